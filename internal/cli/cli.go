@@ -119,13 +119,22 @@ func (s *Subcommand) run(cmd *cobra.Command, args []string) {
 	}
 }
 
-func (s *Subcommand) tryParseConfig() {
+// ReloadConfig parses config file again, using the same options, and returns
+// it. The config of subcommand is not changed.
+func (s *Subcommand) ReloadConfig() (*config.Config, error) {
+	return config.ParseConfig(rootArgs.configPath, s.configOptions()...) //nolint:wrapcheck // not needed
+}
+
+func (s *Subcommand) configOptions() []config.Option {
 	opts := make([]config.Option, 0, 1)
 	if !s.ConfigWithIncludes {
 		opts = append(opts, config.WithoutIncludes())
 	}
+	return opts
+}
 
-	config, err := config.ParseConfig(rootArgs.configPath, opts...)
+func (s *Subcommand) tryParseConfig() {
+	config, err := config.ParseConfig(rootArgs.configPath, s.configOptions()...)
 	s.configErr = err
 	if err != nil {
 		if s.NoRequireConfig {
@@ -138,6 +147,16 @@ func (s *Subcommand) tryParseConfig() {
 	}
 	s.config = config
 	zfs.ZfsBin = config.Global.ZfsBin
+	zfs.ZpoolBin = config.Global.ZpoolBin
+	zfs.DestroyChannelProgram = config.Global.ZfsDestroy.ChannelProgram
+	zfs.DestroyChunk = config.Global.ZfsDestroy.Chunk
+	zfs.PropertiesCacheTTL = config.Global.ZfsPropertiesCache
+	zfs.SetSendBuffers(int(config.Global.RPC.ChunkSize),
+		int(config.Global.RPC.BufferPoolMax))
+	if err := zfs.SetBackend(config.Global.ZfsBackend); err != nil {
+		fmt.Fprintf(os.Stderr, "could not set zfs backend: %s\n", err)
+		os.Exit(1)
+	}
 }
 
 func AddSubcommand(s *Subcommand) {
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+var abortResumeArgs struct {
+	recursive bool
+	dryRun    bool
+	job       string
+}
+
+var AbortResumeCmd = &cli.Subcommand{
+	Use:   "abort-resume [-r] [--dry-run] FILESYSTEM...",
+	Short: "abort resumable state of interrupted receives",
+	Long: `Abort resumable state of interrupted receives.
+
+An interrupted receive leaves receive_resume_token on the receiving
+filesystem and replication resumes from it. If the sender doesn't have the
+snapshot of the token anymore, replication fails until the resumable state is
+aborted. This command aborts it, like zfs recv -A does, so next replication
+starts over from the latest common snapshot. Alternatively, set
+conflict_resolution.invalid_resume_token to "abort" in config of the job.
+`,
+	NoRequireConfig: true,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.MinimumNArgs(1)
+		f := cmd.Flags()
+		f.BoolVarP(&abortResumeArgs.recursive, "recursive", "r", false,
+			"abort resumable state of all children of FILESYSTEM too")
+		f.BoolVar(&abortResumeArgs.dryRun, "dry-run", false,
+			"only print filesystems, which have resumable state")
+		f.StringVar(&abortResumeArgs.job, "job", "",
+			"record aborted states in audit log of `JOB`, if audit_dir is configured")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand, args []string,
+	) error {
+		if job := abortResumeArgs.job; job != "" {
+			c := subcommand.Config()
+			if c == nil {
+				return fmt.Errorf("audit log of job %q: %w", job,
+					subcommand.ConfigParsingError())
+			} else if err := audit.OpenJob(c.Global.AuditDir, job); err != nil {
+				return fmt.Errorf("audit log of job %q: %w", job, err)
+			}
+			defer func() { _ = audit.OpenJob("", job) }()
+			ctx = zfscmd.WithJobID(ctx, job)
+		}
+
+		for _, name := range args {
+			if err := abortResume(ctx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func abortResume(ctx context.Context, name string) error {
+	props := []string{"name", "receive_resume_token"}
+	zfsArgs := []string{"-t", "filesystem,volume"}
+	if abortResumeArgs.recursive {
+		zfsArgs = append(zfsArgs, "-r")
+	}
+	zfsArgs = append(zfsArgs, name)
+
+	cmd := zfs.NewListCmd(ctx, props, zfsArgs)
+	var resumable []string
+	for fields, err := range zfs.ListIter(ctx, props, nil, cmd) {
+		if err != nil {
+			return fmt.Errorf("list filesystems of %q: %w", name, err)
+		} else if fields[1] != "-" && fields[1] != "" {
+			resumable = append(resumable, fields[0])
+		}
+	}
+
+	for _, fs := range resumable {
+		if !abortResumeArgs.dryRun {
+			err := zfs.ZFSRecvClearResumeToken(ctx, fs)
+			audit.Record(ctx, "abort-resume", fs, err)
+			if err != nil {
+				return fmt.Errorf("abort resumable state of %q: %w", fs, err)
+			}
+		}
+		fmt.Printf("aborted resumable state of %s\n", fs)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+var adoptDryRun bool
+
+var AdoptCmd = &cli.Subcommand{
+	Use:   "adopt [--dry-run] JOB",
+	Short: "adopt pre-seeded receiver filesystems into a push or pull job",
+	Long: `Adopt pre-seeded receiver filesystems into a push or pull job.
+
+Receiver filesystems can be seeded by other means, like manual zfs send and
+recv, for instance using a shipped disk. Every filesystem is verified first,
+like zrepl verify does: snapshots with the same name must have the same GUID
+and the same order, and the filesystem must have at least one common snapshot.
+The latest common snapshot of every verified filesystem becomes the replication
+cursor on sender side and the last received snapshot on receiver side, so next
+replication will be incremental from it.
+
+The job must not be running.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+		cmd.Flags().BoolVarP(&adoptDryRun, "dry-run", "n", false,
+			"verify filesystems only, don't change anything")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runAdoptCmd(subcommand.Config(), args[0])
+	},
+}
+
+func runAdoptCmd(config *config.Config, name string) error {
+	req := struct {
+		Name   string
+		DryRun bool
+	}{Name: name, DryRun: adoptDryRun}
+
+	var r report.VerifyReport
+	err := jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointAdopt, &req, &r)
+	if err != nil {
+		return err
+	} else if r.Err != "" {
+		return fmt.Errorf("adopt %s: %s", name, r.Err)
+	}
+	return printVerifyReport("adopt", name, &r)
+}
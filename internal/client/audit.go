@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+)
+
+var AuditCmd = &cli.Subcommand{
+	Use:   "audit",
+	Short: "manage audit logs of endpoint operations",
+	SetupSubcommands: func() []*cli.Subcommand {
+		return []*cli.Subcommand{auditVerifyCmd}
+	},
+}
+
+var auditVerifyCmd = &cli.Subcommand{
+	Use:   "verify [JOB...]",
+	Short: "verify hash chains of audit logs",
+	Long: `Verify hash chains of audit logs.
+
+Verifies audit logs of given jobs or of all configured jobs, which have audit
+logs in global.audit_dir. Any modified, removed or reordered entry breaks the
+hash chain and fails verification.
+`,
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runAuditVerify(subcommand.Config(), args)
+	},
+}
+
+func runAuditVerify(c *config.Config, jobNames []string) error {
+	dir := c.Global.AuditDir
+	if dir == "" {
+		return errors.New("global.audit_dir not configured")
+	}
+
+	explicit := len(jobNames) != 0
+	if !explicit {
+		for i := range c.Jobs {
+			jobNames = append(jobNames, c.Jobs[i].Name())
+		}
+	}
+
+	var failed bool
+	for _, name := range jobNames {
+		n, err := verifyJobAudit(dir, name)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+			continue
+		case err != nil:
+			fmt.Printf("job %q: FAILED: %s\n", name, err)
+			failed = true
+		default:
+			fmt.Printf("job %q: OK, %d entries\n", name, n)
+		}
+	}
+
+	if failed {
+		return errors.New("audit verification failed")
+	}
+	return nil
+}
+
+func verifyJobAudit(dir, jobName string) (int, error) {
+	f, err := os.Open(audit.Filename(dir, jobName))
+	if err != nil {
+		return 0, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	n, err := audit.Verify(f)
+	if err != nil {
+		return n, fmt.Errorf("after %d valid entries: %w", n, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+)
+
+var BwlimitCmd = &cli.Subcommand{
+	Use:   "bwlimit JOB [LIMIT]",
+	Short: "show or change bandwidth limit of a job",
+	Long: `Show or change bandwidth limit of a push or pull job.
+
+LIMIT is bytes per second, like 5MiB. Zero or "unlimited" removes the limit.
+New limit applies immediately to current transfers of the job and to future
+ones, in addition to limits of connect_hosts. It's recorded in the daemon state
+only and doesn't survive daemon restart. Without LIMIT current limit is
+printed.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) { cmd.Args = cobra.RangeArgs(1, 2) },
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runBwlimitCmd(subcommand.Config(), args)
+	},
+}
+
+func runBwlimitCmd(config *config.Config, args []string) error {
+	req := struct {
+		Name  string
+		Set   bool
+		Limit uint64
+	}{Name: args[0]}
+
+	if len(args) > 1 {
+		limit, err := parseBwlimit(args[1])
+		if err != nil {
+			return err
+		}
+		req.Set, req.Limit = true, limit
+	}
+
+	var resp struct{ Limit uint64 }
+	err := jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointBwlimit, &req, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Limit == 0 {
+		fmt.Println("unlimited")
+	} else {
+		fmt.Printf("%d B/s\n", resp.Limit)
+	}
+	return nil
+}
+
+func parseBwlimit(s string) (uint64, error) {
+	if strings.EqualFold(s, "unlimited") {
+		return 0, nil
+	}
+	limit, err := config.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit %q: %w", s, err)
+	}
+	return limit, nil
+}
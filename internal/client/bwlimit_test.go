@@ -0,0 +1,31 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBwlimit(t *testing.T) {
+	tests := []struct {
+		s    string
+		want uint64
+	}{
+		{s: "5MiB", want: 5 << 20},
+		{s: "100k", want: 100 << 10},
+		{s: "0", want: 0},
+		{s: "unlimited", want: 0},
+		{s: "Unlimited", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseBwlimit(tt.s)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := parseBwlimit("fast")
+	require.Error(t, err)
+}
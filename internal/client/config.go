@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+var ConfigCmd = &cli.Subcommand{
+	Use:   "config",
+	Short: "inspect config",
+	SetupSubcommands: func() []*cli.Subcommand {
+		return []*cli.Subcommand{configDumpCmd, configSchemaCmd}
+	},
+}
+
+var configDumpArgs struct {
+	effective bool
+}
+
+var configDumpCmd = &cli.Subcommand{
+	Use:   "dump",
+	Short: "print parsed config as YAML, with defaults applied and secrets redacted",
+
+	ConfigWithIncludes: true,
+
+	SetupFlags: func(f *pflag.FlagSet) {
+		f.BoolVar(&configDumpArgs.effective, "effective", false,
+			"also resolve derived and inherited values, like cron specs of intervals")
+	},
+
+	Run: func(_ context.Context, subcommand *cli.Subcommand, _ []string) error {
+		return dumpConfig(os.Stdout, subcommand.Config(), configDumpArgs.effective)
+	},
+}
+
+func dumpConfig(w io.Writer, c *config.Config, effective bool) error {
+	if effective {
+		c.Effective()
+	}
+	c.RedactSecrets()
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return fmt.Errorf("failed encode to yaml: %w", err)
+	} else if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed encode to yaml: %w", err)
+	}
+	return nil
+}
+
+var configSchemaCmd = &cli.Subcommand{
+	Use:             "schema",
+	Short:           "print JSON Schema of config files",
+	NoRequireConfig: true,
+
+	Run: func(_ context.Context, _ *cli.Subcommand, _ []string) error {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.JSONSchema()); err != nil {
+			return fmt.Errorf("marshal schema: %w", err)
+		}
+		return nil
+	},
+}
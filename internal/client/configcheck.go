@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 	"go.yaml.in/yaml/v4"
@@ -16,8 +17,9 @@ import (
 )
 
 var configcheckArgs struct {
-	format string
-	what   string
+	format     string
+	what       string
+	tlsExpires time.Duration
 }
 
 var ConfigcheckCmd = &cli.Subcommand{
@@ -30,7 +32,9 @@ var ConfigcheckCmd = &cli.Subcommand{
 		f.StringVar(&configcheckArgs.format, "format", "",
 			"dump parsed config object [yaml|json]")
 		f.StringVar(&configcheckArgs.what, "what", "all",
-			"what to print [all|config|jobs|logging]")
+			"what to print [all|config|jobs|logging|tls]")
+		f.DurationVar(&configcheckArgs.tlsExpires, "tls-expires", 30*24*time.Hour,
+			"warn about TLS certificates, which expire within this duration")
 	},
 
 	Run: func(_ context.Context, subcommand *cli.Subcommand, _ []string) error {
@@ -66,6 +70,17 @@ func checkConfig(subcommand *cli.Subcommand) error {
 		}
 	}
 
+	// further: try to load TLS certificates and keys
+	if err := checkTLS(c, time.Now(), configcheckArgs.tlsExpires); err != nil {
+		err := fmt.Errorf("invalid TLS config: %w", err)
+		if configcheckArgs.what == "tls" {
+			return err
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+			hadErr = true
+		}
+	}
+
 	switch configcheckArgs.format {
 	case "":
 	case "json":
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/tlsconf"
+)
+
+// checkTLS loads all configured certificates, keys and CAs, and checks they
+// are valid at now. Warnings, like certificates, which expire within
+// expireWarn, are printed to stderr.
+func checkTLS(c *config.Config, now time.Time, expireWarn time.Duration,
+) error {
+	var errs []error
+	check := func(name string, warnings []string, err error) {
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", name, w)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	for i := range c.Listen {
+		l := &c.Listen[i]
+		name := fmt.Sprintf("listen[%d]", i)
+		if l.TLSCert != "" {
+			host, _, _ := net.SplitHostPort(l.Addr)
+			w, err := tlsconf.CheckKeyPair(l.TLSCert, l.TLSKey, host, now,
+				expireWarn)
+			check(name, w, err)
+		}
+		if l.TLSClientCA != "" {
+			w, err := tlsconf.CheckCA(l.TLSClientCA, now, expireWarn)
+			check(name+".tls_client_ca", w, err)
+		}
+	}
+
+	if h := c.Global.HTTP; h != nil && h.TLSCert != "" {
+		host, _, _ := net.SplitHostPort(h.Listen)
+		w, err := tlsconf.CheckKeyPair(h.TLSCert, h.TLSKey, host, now, expireWarn)
+		check("global.http", w, err)
+	}
+
+	for i := range c.Global.Logging {
+		var outletTLS *config.TCPLoggingOutletTLS
+		switch o := c.Global.Logging[i].Ret.(type) {
+		case *config.TCPLoggingOutlet:
+			outletTLS = o.TLS
+		case *config.SyslogLoggingOutlet:
+			outletTLS = o.TLS
+		}
+		if outletTLS == nil {
+			continue
+		}
+		name := fmt.Sprintf("global.logging[%d].tls", i)
+		w, err := tlsconf.CheckKeyPair(outletTLS.Cert, outletTLS.Key, "", now,
+			expireWarn)
+		check(name, w, err)
+		if outletTLS.CA != "" {
+			w, err := tlsconf.CheckCA(outletTLS.CA, now, expireWarn)
+			check(name+".ca", w, err)
+		}
+	}
+	return errors.Join(errs...)
+}
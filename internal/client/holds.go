@@ -0,0 +1,192 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/endpoint"
+)
+
+var HoldsCmd = &cli.Subcommand{
+	Use:   "holds",
+	Short: "list and release zrepl holds, bookmarks and replication cursors",
+	SetupSubcommands: func() []*cli.Subcommand {
+		return []*cli.Subcommand{holdsCmdList, holdsCmdRelease}
+	},
+}
+
+var holdsArgs struct {
+	filter    zabsFilterFlags
+	olderThan OlderThanFlag
+	json      bool
+	dryRun    bool
+}
+
+func registerHoldsFlags(f *pflag.FlagSet, verb string) {
+	holdsArgs.filter.registerZabsFilterFlags(f, verb)
+	f.Var(&holdsArgs.olderThan, "older-than", fmt.Sprintf(
+		"only %s abstractions created earlier than this duration ago, like 30d or 2w",
+		verb))
+	f.BoolVar(&holdsArgs.json, "json", false, "emit JSON")
+}
+
+var holdsCmdList = &cli.Subcommand{
+	Use:   "list",
+	Short: "list zrepl abstractions with their creation time and job",
+	Example: `  zrepl holds list --job backup --older-than 30d --type step-hold
+  zrepl holds list --fs zroot/data`,
+	NoRequireConfig: true,
+
+	SetupCobra: func(c *cobra.Command) {
+		c.Args = cobra.NoArgs
+		registerHoldsFlags(c.Flags(), "list")
+	},
+
+	Run: func(ctx context.Context, _ *cli.Subcommand, _ []string) error {
+		abs, err := listHolds(ctx)
+		if err != nil {
+			return err
+		} else if holdsArgs.json {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(abs)
+		}
+		return renderHolds(os.Stdout, abs, time.Now())
+	},
+}
+
+var holdsCmdRelease = &cli.Subcommand{
+	Use:   "release",
+	Short: "release holds and destroy bookmarks matching the filter",
+	Long: `Release holds and destroy bookmarks matching the filter.
+
+Releasing the latest replication cursor or last-received hold breaks
+incremental replication of the filesystem. Filter them out by --type, or use
+zrepl zfs-abstraction release-stale, which keeps them.
+`,
+	Example:         "  zrepl holds release --job backup --older-than 30d --type step-hold",
+	NoRequireConfig: true,
+
+	SetupCobra: func(c *cobra.Command) {
+		c.Args = cobra.NoArgs
+		f := c.Flags()
+		registerHoldsFlags(f, "release")
+		f.BoolVar(&holdsArgs.dryRun, "dry-run", false,
+			"only print abstractions, which would be released")
+	},
+
+	Run: func(ctx context.Context, _ *cli.Subcommand, _ []string) error {
+		abs, err := listHolds(ctx)
+		if err != nil {
+			return err
+		}
+		return releaseAbstractions(ctx, abs, holdsArgs.dryRun, holdsArgs.json)
+	},
+}
+
+// listHolds returns abstractions matching command line flags, sorted by
+// filesystem and creation.
+func listHolds(ctx context.Context) ([]endpoint.Abstraction, error) {
+	q, err := holdsArgs.filter.Query()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter specification on command line: %w",
+			err)
+	}
+
+	abs, listErrors, err := endpoint.ListAbstractions(ctx, q)
+	if err != nil {
+		return nil, err
+	} else if len(listErrors) > 0 {
+		color.New(color.FgRed).Fprintf(os.Stderr,
+			"there were errors in listing the abstractions:\n%s\n",
+			endpoint.ListAbstractionsErrors(listErrors))
+		return nil, errors.New("")
+	}
+
+	abs = olderThan(abs, holdsArgs.olderThan.Duration(), time.Now())
+	sortHolds(abs)
+	return abs, nil
+}
+
+// olderThan returns abstractions created earlier than d before now. Zero d
+// returns all abstractions.
+func olderThan(abs []endpoint.Abstraction, d time.Duration, now time.Time,
+) []endpoint.Abstraction {
+	if d == 0 {
+		return abs
+	}
+	before := now.Add(-d)
+	return slices.DeleteFunc(abs, func(a endpoint.Abstraction) bool {
+		return !a.GetFilesystemVersion().Creation.Before(before)
+	})
+}
+
+func sortHolds(abs []endpoint.Abstraction) {
+	slices.SortStableFunc(abs, func(a, b endpoint.Abstraction) int {
+		return cmp.Or(
+			cmp.Compare(a.GetFS(), b.GetFS()),
+			cmp.Compare(a.GetCreateTXG(), b.GetCreateTXG()),
+			cmp.Compare(a.GetType(), b.GetType()))
+	})
+}
+
+func renderHolds(w io.Writer, abs []endpoint.Abstraction, now time.Time,
+) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CREATED\tAGE\tJOB\tTYPE\tNAME")
+	for _, a := range abs {
+		var job string
+		if jobID := a.GetJobID(); jobID != nil {
+			job = jobID.String()
+		}
+		created := a.GetFilesystemVersion().Creation
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			created.Format(time.DateTime),
+			holdAge(now.Sub(created)),
+			cmp.Or(job, "-"), a.GetType(), a.GetFullPath())
+	}
+	return tw.Flush()
+}
+
+// holdAge formats d in the largest whole unit: days, hours or minutes.
+func holdAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// OlderThanFlag is a positive duration, like in config, e.g. 30d.
+type OlderThanFlag struct{ d time.Duration }
+
+func (f *OlderThanFlag) Set(s string) error {
+	d, err := config.ParseDuration(s)
+	if err != nil {
+		return err
+	} else if d <= 0 {
+		return errors.New("duration must be positive")
+	}
+	f.d = d
+	return nil
+}
+
+func (f OlderThanFlag) Type() string            { return "duration" }
+func (f OlderThanFlag) String() string          { return f.d.String() }
+func (f OlderThanFlag) Duration() time.Duration { return f.d }
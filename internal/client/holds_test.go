@@ -0,0 +1,68 @@
+package client
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/endpoint"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestHolds(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	jobID := endpoint.MustMakeJobID("backup")
+	cursor := func(fs string, txg uint64, created time.Time) endpoint.Abstraction {
+		dp, err := zfs.NewDatasetPath(fs)
+		require.NoError(t, err)
+		name, err := endpoint.ReplicationCursorBookmarkName(fs, txg, jobID)
+		require.NoError(t, err)
+		a := endpoint.ReplicationCursorV2Extractor(dp, zfs.FilesystemVersion{
+			Type:      zfs.Bookmark,
+			Name:      name,
+			Guid:      txg,
+			CreateTXG: txg,
+			Creation:  created,
+		})
+		require.NotNil(t, a)
+		return a
+	}
+
+	old := cursor("pool/b", 1, now.Add(-31*24*time.Hour))
+	abs := []endpoint.Abstraction{
+		cursor("pool/b", 2, now.Add(-time.Hour)),
+		old,
+		cursor("pool/a", 3, now.Add(-40*24*time.Hour)),
+	}
+	sortHolds(abs)
+	assert.Equal(t, "pool/a", abs[0].GetFS())
+	assert.Same(t, old, abs[1])
+
+	var b strings.Builder
+	require.NoError(t, renderHolds(&b, abs[:1], now))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"CREATED", "AGE", "JOB", "TYPE", "NAME"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{
+		"2026-09-07", "12:00:00", "40d", "backup",
+		string(endpoint.AbstractionReplicationCursorBookmarkV2),
+		abs[0].GetFullPath(),
+	}, strings.Fields(lines[1]))
+
+	assert.Len(t, olderThan(slices.Clone(abs), 0, now), 3)
+	got := olderThan(slices.Clone(abs), 30*24*time.Hour, now)
+	require.Len(t, got, 2)
+	assert.Same(t, abs[0], got[0])
+	assert.Same(t, old, got[1])
+
+	var f OlderThanFlag
+	require.NoError(t, f.Set("30d"))
+	assert.Equal(t, 30*24*time.Hour, f.Duration())
+	require.Error(t, f.Set("0"))
+	require.Error(t, f.Set("-1d"))
+}
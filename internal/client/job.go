@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+)
+
+var JobCmd = &cli.Subcommand{
+	Use:   "job",
+	Short: "enable or disable jobs of the running daemon, or graph them",
+	SetupSubcommands: func() []*cli.Subcommand {
+		return []*cli.Subcommand{jobEnableCmd, jobDisableCmd, jobGraphCmd}
+	},
+}
+
+var jobEnableCmd = &cli.Subcommand{
+	Use:   "enable JOB",
+	Short: "enable previously disabled job",
+
+	SetupCobra: func(cmd *cobra.Command) { cmd.Args = cobra.ExactArgs(1) },
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runJobCmd(subcommand.Config(), "enable", args[0])
+	},
+}
+
+var jobDisableCmd = &cli.Subcommand{
+	Use:   "disable JOB",
+	Short: "disable job, until it enabled again",
+	Long: `Disable job, until it enabled again.
+
+Disabled job doesn't start from cron or wakeup signal, and its current
+invocation is aborted. Disabled jobs are recorded in the daemon state file
+(global.state_file) and stay disabled after daemon restart.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) { cmd.Args = cobra.ExactArgs(1) },
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runJobCmd(subcommand.Config(), "disable", args[0])
+	},
+}
+
+func runJobCmd(config *config.Config, op, name string) error {
+	req := struct {
+		Op   string
+		Name string
+	}{Op: op, Name: name}
+
+	return jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointJob, &req, nil)
+}
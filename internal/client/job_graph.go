@@ -0,0 +1,414 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+var jobGraphArgs struct {
+	format string
+}
+
+var jobGraphCmd = &cli.Subcommand{
+	Use:   "graph [--format dot|mermaid]",
+	Short: "print graph of configured jobs",
+	Long: `Print graph of configured jobs in DOT or Mermaid format.
+
+Every job is a node with its type, schedule and dataset filter. Edges follow
+the data: push jobs point to their servers or local sink jobs, source jobs and
+servers point to pull jobs, listeners point to sink jobs. Dashed edges chain a
+receiving job to the jobs, which send its received datasets further.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.NoArgs
+		cmd.Flags().StringVar(&jobGraphArgs.format, "format", "dot",
+			"output format: dot or mermaid")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		g, err := newJobGraph(subcommand.Config())
+		if err != nil {
+			return err
+		}
+
+		switch jobGraphArgs.format {
+		case "dot":
+			return g.WriteDot(os.Stdout)
+		case "mermaid":
+			return g.WriteMermaid(os.Stdout)
+		}
+		return fmt.Errorf("unknown format %q, expected dot or mermaid",
+			jobGraphArgs.format)
+	},
+}
+
+// jobGraph is a graph of configured jobs, their listeners and peers.
+type jobGraph struct {
+	nodes []*graphNode
+	byID  map[string]*graphNode
+	edges []graphEdge
+}
+
+type graphNode struct {
+	id    string
+	lines []string
+	peer  bool
+}
+
+type graphEdge struct {
+	from, to string
+	label    string
+	dashed   bool
+}
+
+// jobGraphJob is a configured job with its parsed dataset filter, if it has
+// one.
+type jobGraphJob struct {
+	name   string
+	filter *filters.DatasetFilter
+	rootFS []string
+}
+
+func newJobGraph(c *config.Config) (*jobGraph, error) {
+	g := &jobGraph{byID: make(map[string]*graphNode)}
+	jobs := make([]jobGraphJob, 0, len(c.Jobs))
+	for i := range c.Jobs {
+		j, err := g.addJob(&c.Jobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", c.Jobs[i].Name(), err)
+		}
+		jobs = append(jobs, j)
+	}
+
+	for i := range c.Jobs {
+		g.addTransport(&c.Jobs[i], c.Listen)
+	}
+
+	for _, recv := range jobs {
+		for _, send := range jobs {
+			if send.name != recv.name && chained(recv.rootFS, send.filter) {
+				g.addEdge(graphEdge{
+					from: jobNodeID(recv.name), to: jobNodeID(send.name),
+					label: "sends received", dashed: true,
+				})
+			}
+		}
+	}
+	return g, nil
+}
+
+func jobNodeID(name string) string { return "job:" + name }
+
+func (self *jobGraph) addJob(in *config.JobEnum) (j jobGraphJob, err error) {
+	j.name = in.Name()
+	lines := []string{j.name}
+	var ff config.FilesystemsFilter
+	var df []config.DatasetFilter
+
+	switch v := in.Ret.(type) {
+	case *config.SnapJob:
+		lines = append(lines, "snap")
+		ff, df = v.Filesystems, v.Datasets
+	case *config.PruneJob:
+		lines = append(lines, "prune, cron "+v.Cron)
+		ff, df = v.Filesystems, v.Datasets
+	case *config.PushJob:
+		lines = append(lines, scheduleLine("push", &v.ActiveJob))
+		ff, df = v.Filesystems, v.Datasets
+	case *config.SourceJob:
+		lines = append(lines, "source")
+		ff, df = v.Filesystems, v.Datasets
+	case *config.PullJob:
+		lines = append(lines, scheduleLine("pull", &v.ActiveJob))
+		j.rootFS = []string{v.RootFS}
+		lines = append(lines, "root_fs: "+v.RootFS)
+		if v.Filesystems != nil || v.Datasets != nil {
+			lines = append(lines, filterLines(v.Filesystems, v.Datasets)...)
+		}
+	case *config.SinkJob:
+		lines = append(lines, "sink")
+		j.rootFS = append(j.rootFS, v.RootFS)
+		lines = append(lines, "root_fs: "+v.RootFS)
+		for _, m := range v.RootFSMap {
+			j.rootFS = append(j.rootFS, m.RootFS)
+			lines = append(lines, fmt.Sprintf("root_fs: %s (%s)", m.RootFS,
+				m.Prefix))
+		}
+		for _, sc := range v.StorageClasses {
+			j.rootFS = append(j.rootFS, sc.RootFS)
+			lines = append(lines, fmt.Sprintf("root_fs: %s (class %s)",
+				sc.RootFS, sc.Class))
+		}
+	}
+
+	if ff != nil || df != nil {
+		if j.filter, err = filters.NewFromConfig(ff, df); err != nil {
+			return j, fmt.Errorf("cannot build filesystem filter: %w", err)
+		}
+		lines = append(lines, filterLines(ff, df)...)
+	}
+	self.addNode(&graphNode{id: jobNodeID(j.name), lines: lines})
+	return j, nil
+}
+
+func scheduleLine(typ string, j *config.ActiveJob) string {
+	if spec := j.CronSpec(); spec != "" {
+		return typ + ", cron " + spec
+	}
+	return typ
+}
+
+func filterLines(ff config.FilesystemsFilter, df []config.DatasetFilter,
+) []string {
+	lines := make([]string, 0, len(ff)+len(df))
+	for _, pattern := range slices.Sorted(maps.Keys(ff)) {
+		lines = append(lines, fmt.Sprintf("%s: %v", pattern, ff[pattern]))
+	}
+
+	for _, d := range df {
+		var flags []string
+		if d.Exclude {
+			flags = append(flags, "exclude")
+		}
+		if d.Recursive {
+			flags = append(flags, "recursive")
+		}
+		if d.Shell {
+			flags = append(flags, "shell")
+		}
+		if d.Regex {
+			flags = append(flags, "regex")
+		}
+		line := d.Pattern
+		if line == "" {
+			line = `""`
+		}
+		if len(flags) > 0 {
+			line += " (" + strings.Join(flags, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (self *jobGraph) addTransport(in *config.JobEnum, listen []config.Listen) {
+	name := in.Name()
+	switch v := in.Ret.(type) {
+	case *config.PushJob:
+		peer := self.connectPeer(&v.Connect)
+		self.addEdge(graphEdge{
+			from: jobNodeID(name), to: peer, label: connectLabel(&v.Connect),
+		})
+	case *config.PullJob:
+		peer := self.connectPeer(&v.Connect)
+		self.addEdge(graphEdge{
+			from: peer, to: jobNodeID(name), label: connectLabel(&v.Connect),
+		})
+	case *config.SinkJob:
+		for _, id := range self.listeners(listen) {
+			self.addEdge(graphEdge{
+				from: id, to: jobNodeID(name), label: clientsLabel(v.ClientKeys),
+			})
+		}
+	case *config.SourceJob:
+		for _, id := range self.listeners(listen) {
+			self.addEdge(graphEdge{
+				from: jobNodeID(name), to: id, label: clientsLabel(v.ClientKeys),
+			})
+		}
+	}
+}
+
+// connectPeer returns ID of the node, which c connects to: a local job, or a
+// remote server.
+func (self *jobGraph) connectPeer(c *config.Connect) string {
+	if c.Type == "local" {
+		id := jobNodeID(c.ListenerName)
+		if _, ok := self.byID[id]; !ok {
+			self.addNode(&graphNode{
+				id: id, lines: []string{c.ListenerName, "missing local job"},
+				peer: true,
+			})
+		}
+		return id
+	}
+
+	id := "server:" + c.Server
+	if _, ok := self.byID[id]; !ok {
+		self.addNode(&graphNode{id: id, lines: []string{c.Server}, peer: true})
+	}
+	return id
+}
+
+func connectLabel(c *config.Connect) string {
+	if c.Type == "local" {
+		return "local as " + c.ClientIdentity
+	}
+	return fmt.Sprintf("%s: %s as %s", c.Type, c.ListenerName, c.ClientIdentity)
+}
+
+// listeners returns IDs of nodes of listeners, which serve zfs endpoints.
+func (self *jobGraph) listeners(listen []config.Listen) []string {
+	var ids []string
+	for i := range listen {
+		l := &listen[i]
+		if !l.Zfs {
+			continue
+		}
+
+		addr := l.Addr
+		if addr == "" {
+			addr = "unix:" + l.Unix
+		}
+		id := "listen:" + addr
+		if _, ok := self.byID[id]; !ok {
+			lines := []string{"listen " + addr}
+			if l.TLSCert != "" {
+				lines = append(lines, "tls")
+			}
+			self.addNode(&graphNode{id: id, lines: lines, peer: true})
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func clientsLabel(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return "clients: " + strings.Join(keys, ", ")
+}
+
+func (self *jobGraph) addNode(n *graphNode) {
+	self.nodes = append(self.nodes, n)
+	self.byID[n.id] = n
+}
+
+func (self *jobGraph) addEdge(e graphEdge) { self.edges = append(self.edges, e) }
+
+// chained returns true, if f selects any of rootFS or any dataset below them.
+func chained(rootFS []string, f *filters.DatasetFilter) bool {
+	if f == nil {
+		return false
+	}
+
+	for _, root := range rootFS {
+		p, err := zfs.NewDatasetPath(root)
+		if err != nil {
+			continue
+		}
+		if ok, err := f.Filter(p); err == nil && ok {
+			return true
+		}
+		for name, ok := range f.UserSpecifiedDatasets() {
+			if !ok {
+				continue
+			}
+			child, err := zfs.NewDatasetPath(name)
+			if err != nil {
+				continue
+			}
+			if included, err := f.Filter(child); err == nil && included &&
+				child.HasPrefix(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteDot writes the graph in DOT format of Graphviz.
+func (self *jobGraph) WriteDot(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph zrepl {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, n := range self.nodes {
+		fmt.Fprintf(&sb, "  %s [label=%s", dotQuote(n.id),
+			dotQuote(strings.Join(n.lines, "\n")))
+		if n.peer {
+			sb.WriteString(", shape=ellipse")
+		}
+		sb.WriteString("];\n")
+	}
+
+	for _, e := range self.edges {
+		fmt.Fprintf(&sb, "  %s -> %s", dotQuote(e.from), dotQuote(e.to))
+		var attrs []string
+		if e.label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.label))
+		}
+		if e.dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			sb.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+		}
+		sb.WriteString(";\n")
+	}
+	sb.WriteString("}\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("write dot graph: %w", err)
+	}
+	return nil
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WriteMermaid writes the graph as Mermaid flowchart.
+func (self *jobGraph) WriteMermaid(w io.Writer) error {
+	ids := make(map[string]string, len(self.nodes))
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for i, n := range self.nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.id] = id
+		label := mermaidQuote(strings.Join(n.lines, "\n"))
+		if n.peer {
+			fmt.Fprintf(&sb, "  %s([%s])\n", id, label)
+		} else {
+			fmt.Fprintf(&sb, "  %s[%s]\n", id, label)
+		}
+	}
+
+	for _, e := range self.edges {
+		arrow := "-->"
+		if e.dashed {
+			arrow = "-.->"
+		}
+		if e.label != "" {
+			arrow += "|" + mermaidQuote(e.label) + "|"
+		}
+		fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.from], arrow, ids[e.to])
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("write mermaid graph: %w", err)
+	}
+	return nil
+}
+
+func mermaidQuote(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+	return `"` + r.Replace(s) + `"`
+}
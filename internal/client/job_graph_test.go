@@ -0,0 +1,128 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+const testJobGraphConfig = `
+listen:
+  - addr: "192.168.122.189:8888"
+    tls_cert: "/etc/zrepl/cert.pem"
+    tls_key: "/etc/zrepl/key.pem"
+    zfs: true
+
+jobs:
+  - type: sink
+    name: local_sink
+    root_fs: "storage/sink"
+    client_keys: ["laptop"]
+
+  - type: push
+    name: backup
+    cron: "*/10 * * * *"
+    connect:
+      type: local
+      listener_name: local_sink
+      client_identity: local_backup
+    datasets:
+      - pattern: "system"
+        recursive: true
+      - pattern: "system/tmp"
+        exclude: true
+    snapshotting:
+      type: manual
+    pruning:
+      keep_sender:
+        - type: not_replicated
+      keep_receiver:
+        - type: last_n
+          count: 10
+
+  - type: push
+    name: offsite
+    connect:
+      type: http
+      server: "https://offsite.example.com:8888"
+      listener_name: offsite_sink
+      client_identity: server1
+    filesystems:
+      "storage/sink<": true
+    snapshotting:
+      type: manual
+    pruning:
+      keep_sender:
+        - type: not_replicated
+      keep_receiver:
+        - type: last_n
+          count: 10
+`
+
+func testJobGraph(t *testing.T) *jobGraph {
+	t.Helper()
+	c, err := config.ParseConfigBytes("", []byte(testJobGraphConfig))
+	require.NoError(t, err)
+	g, err := newJobGraph(c)
+	require.NoError(t, err)
+	return g
+}
+
+func TestJobGraph_WriteDot(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, testJobGraph(t).WriteDot(&sb))
+	assert.Equal(t, `digraph zrepl {
+  rankdir=LR;
+  node [shape=box];
+  "job:local_sink" [label="local_sink\nsink\nroot_fs: storage/sink"];
+  "job:backup" [label="backup\npush, cron */10 * * * *\nsystem (recursive)\nsystem/tmp (exclude)"];
+  "job:offsite" [label="offsite\npush\nstorage/sink<: true"];
+  "listen:192.168.122.189:8888" [label="listen 192.168.122.189:8888\ntls", shape=ellipse];
+  "server:https://offsite.example.com:8888" [label="https://offsite.example.com:8888", shape=ellipse];
+  "listen:192.168.122.189:8888" -> "job:local_sink" [label="clients: laptop"];
+  "job:backup" -> "job:local_sink" [label="local as local_backup"];
+  "job:offsite" -> "server:https://offsite.example.com:8888" [label="http: offsite_sink as server1"];
+  "job:local_sink" -> "job:offsite" [label="sends received", style=dashed];
+}
+`, sb.String())
+}
+
+func TestJobGraph_WriteMermaid(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, testJobGraph(t).WriteMermaid(&sb))
+	assert.Equal(t, `flowchart LR
+  n0["local_sink<br/>sink<br/>root_fs: storage/sink"]
+  n1["backup<br/>push, cron */10 * * * *<br/>system (recursive)<br/>system/tmp (exclude)"]
+  n2["offsite<br/>push<br/>storage/sink<: true"]
+  n3(["listen 192.168.122.189:8888<br/>tls"])
+  n4(["https://offsite.example.com:8888"])
+  n3 -->|"clients: laptop"| n0
+  n1 -->|"local as local_backup"| n0
+  n2 -->|"http: offsite_sink as server1"| n4
+  n0 -.->|"sends received"| n2
+`, sb.String())
+}
+
+func TestChained(t *testing.T) {
+	c, err := config.ParseConfigBytes("", []byte(testJobGraphConfig))
+	require.NoError(t, err)
+	g := &jobGraph{byID: make(map[string]*graphNode)}
+
+	backup, err := g.addJob(&c.Jobs[1])
+	require.NoError(t, err)
+	assert.False(t, chained([]string{"storage/sink"}, backup.filter))
+	assert.True(t, chained([]string{"system"}, backup.filter))
+	assert.True(t, chained([]string{"system/usr"}, backup.filter))
+	assert.False(t, chained([]string{"system/tmp"}, backup.filter))
+	assert.False(t, chained([]string{"system"}, nil))
+
+	offsite, err := g.addJob(&c.Jobs[2])
+	require.NoError(t, err)
+	assert.True(t, chained([]string{"storage"}, offsite.filter),
+		"sends datasets below root_fs")
+	assert.False(t, chained([]string{"storage/other"}, offsite.filter))
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
+)
+
+var lifecycleArgs struct {
+	json bool
+}
+
+var LifecycleCmd = &cli.Subcommand{
+	Use:   "lifecycle [--json] DATASET[@SNAPSHOT]",
+	Short: "show what happened to snapshots of a dataset",
+	Long: `Show what happened to snapshots of a dataset.
+
+Prints the journal of a dataset from global.lifecycle_dir: which job created,
+replicated, received, marked for destroy or destroyed its snapshots, and why.
+With @SNAPSHOT it prints only entries of this snapshot.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+		cmd.Flags().BoolVar(&lifecycleArgs.json, "json", false,
+			"print entries as json")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runLifecycle(subcommand.Config(), args[0], os.Stdout)
+	},
+}
+
+func runLifecycle(c *config.Config, name string, w io.Writer) error {
+	dir := c.Global.LifecycleDir
+	if dir == "" {
+		return errors.New("global.lifecycle_dir not configured")
+	}
+
+	fs, snapshot, _ := strings.Cut(name, "@")
+	f, err := os.Open(lifecycle.Filename(dir, fs))
+	if err != nil {
+		return fmt.Errorf("open lifecycle journal: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := lifecycle.Read(f, snapshot)
+	if err != nil {
+		return fmt.Errorf("lifecycle journal of %q: %w", fs, err)
+	}
+
+	if lifecycleArgs.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			return fmt.Errorf("marshal lifecycle entries: %w", err)
+		}
+		return nil
+	}
+	return printLifecycle(w, entries)
+}
+
+func printLifecycle(w io.Writer, entries []lifecycle.Entry) error {
+	var sb strings.Builder
+	for i := range entries {
+		e := &entries[i]
+		fmt.Fprintf(&sb, "%s\t%s\t%s\t@%s", e.Time.Local().Format(time.DateTime),
+			e.Job, e.Event, e.Snapshot)
+		for _, k := range slices.Sorted(maps.Keys(e.Details)) {
+			fmt.Fprintf(&sb, "\t%s=%s", k, e.Details[k])
+		}
+		if e.Error != "" {
+			sb.WriteString("\tFAIL: " + e.Error)
+		}
+		sb.WriteByte('\n')
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("write lifecycle entries: %w", err)
+	}
+	return nil
+}
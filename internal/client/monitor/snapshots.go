@@ -130,12 +130,32 @@ func (self *SnapCheck) jobDatasets(ctx context.Context,
 		datasets, err = self.datasetsFromFilter(ctx, j.Filesystems, j.Datasets)
 	case *config.SnapJob:
 		datasets, err = self.datasetsFromFilter(ctx, j.Filesystems, j.Datasets)
+	case *config.PruneJob:
+		datasets, err = self.datasetsFromFilter(ctx, j.Filesystems, j.Datasets)
 	case *config.SourceJob:
 		datasets, err = self.datasetsFromFilter(ctx, j.Filesystems, j.Datasets)
 	case *config.PullJob:
 		datasets, err = self.datasetsFromRootFs(ctx, j.RootFS, 0)
 	case *config.SinkJob:
 		datasets, err = self.datasetsFromRootFs(ctx, j.RootFS, 1)
+		seen := map[string]struct{}{j.RootFS: {}}
+		roots := make([]string, 0, len(j.RootFSMap)+len(j.StorageClasses))
+		for i := range j.RootFSMap {
+			roots = append(roots, j.RootFSMap[i].RootFS)
+		}
+		for i := range j.StorageClasses {
+			roots = append(roots, j.StorageClasses[i].RootFS)
+		}
+		for i := 0; err == nil && i < len(roots); i++ {
+			rootFS := roots[i]
+			if _, ok := seen[rootFS]; ok {
+				continue
+			}
+			seen[rootFS] = struct{}{}
+			var mapped []*zfs.DatasetPath
+			mapped, err = self.datasetsFromRootFs(ctx, rootFS, 1)
+			datasets = append(datasets, mapped...)
+		}
 	default:
 		err = fmt.Errorf("unknown job type %T", j)
 	}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+)
+
+var pauseArgs struct {
+	stop bool
+}
+
+var PauseCmd = &cli.Subcommand{
+	Use:   "pause [--stop] JOB",
+	Short: "pause replication of a running job",
+	Long: `Pause replication of a running job.
+
+Paused job doesn't start new replication steps, until it resumed. Current
+steps continue, and planned steps and resume tokens are kept, so replication
+continues from the same point after resume. With --stop, active zfs processes
+of the job on this host are suspended with SIGSTOP too. The pause is recorded
+in the daemon state only and doesn't survive daemon restart.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+		cmd.Flags().BoolVar(&pauseArgs.stop, "stop", false,
+			"suspend active zfs processes of the job with SIGSTOP")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runPauseCmd(subcommand.Config(), "pause", args[0], pauseArgs.stop)
+	},
+}
+
+var ResumeCmd = &cli.Subcommand{
+	Use:   "resume JOB",
+	Short: "resume paused replication of a job",
+
+	SetupCobra: func(cmd *cobra.Command) { cmd.Args = cobra.ExactArgs(1) },
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runPauseCmd(subcommand.Config(), "resume", args[0], false)
+	},
+}
+
+func runPauseCmd(config *config.Config, op, name string, stop bool) error {
+	req := struct {
+		Op   string
+		Name string
+		Stop bool
+	}{Op: op, Name: name, Stop: stop}
+
+	return jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointJob, &req, nil)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+)
+
+var pruneArgs struct {
+	dryRun bool
+	json   bool
+}
+
+var PruneCmd = &cli.Subcommand{
+	Use:   "prune --dry-run JOB",
+	Short: "show snapshots, which pruning of a job would destroy",
+	Long: `Show snapshots, which pruning of a job would destroy.
+
+Applies keep rules of the job to current snapshots of every filesystem and
+prints snapshots, which would be destroyed right now, without destroying them.
+Push and pull jobs report both sender and receiver sides. Use it before rolling
+out new keep rules.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+		f := cmd.Flags()
+		f.BoolVar(&pruneArgs.dryRun, "dry-run", false,
+			"don't destroy anything, only show what would be destroyed")
+		f.BoolVar(&pruneArgs.json, "json", false, "print report as json")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		if !pruneArgs.dryRun {
+			return errors.New(
+				"only --dry-run is supported, pruning runs together with the job")
+		}
+		return runPruneCmd(subcommand.Config(), args[0])
+	},
+}
+
+func runPruneCmd(config *config.Config, name string) error {
+	req := struct{ Name string }{Name: name}
+	var sides []*pruner.DryRunReport
+	err := jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointPrune, &req, &sides)
+	if err != nil {
+		return err
+	}
+
+	if pruneArgs.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sides); err != nil {
+			return fmt.Errorf("marshal prune report: %w", err)
+		}
+		return nil
+	}
+	return printPruneDryRun(sides)
+}
+
+func printPruneDryRun(sides []*pruner.DryRunReport) error {
+	var failed int
+	for _, side := range sides {
+		if side.Error != "" {
+			fmt.Printf("%s: FAIL\t%s\n", side.Side, side.Error)
+			failed++
+			continue
+		}
+
+		var destroy int
+		for _, fs := range side.Filesystems {
+			switch {
+			case !fs.SkipReason.NotSkipped():
+				fmt.Printf("%s: skip\t%s\t%s\n", side.Side, fs.Filesystem,
+					fs.SkipReason)
+				continue
+			case fs.Error != "":
+				fmt.Printf("%s: FAIL\t%s\t%s\n", side.Side, fs.Filesystem, fs.Error)
+				failed++
+				continue
+			}
+
+			fmt.Printf("%s: %s\tdestroy %d of %d snapshots\n", side.Side,
+				fs.Filesystem, len(fs.Destroy), fs.SnapshotsCount)
+			for _, s := range fs.Destroy {
+				replicated := ""
+				if s.Replicated {
+					replicated = "\treplicated"
+				}
+				fmt.Printf("\t%s\t%s%s\n", s.Name,
+					s.Date.Local().Format(time.DateTime), replicated)
+			}
+			for _, name := range fs.DestroyBookmarks {
+				fmt.Printf("\t#%s\tbookmark\n", name)
+			}
+			destroy += len(fs.Destroy)
+		}
+		fmt.Printf("%s: would destroy %d snapshots of %d filesystems\n",
+			side.Side, destroy, len(side.Filesystems))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("prune dry run: %d failed", failed)
+	}
+	return nil
+}
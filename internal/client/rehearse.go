@@ -0,0 +1,296 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+var rehearseArgs struct {
+	dryRun bool
+}
+
+var RehearseCmd = &cli.Subcommand{
+	Use:   "rehearse [--dry-run] JOB",
+	Short: "replay latest received snapshots for disaster recovery rehearsal",
+	Long: `Replay latest received snapshots for disaster recovery rehearsal.
+
+For every filesystem received by pull or sink JOB, replays its latest
+snapshots below rehearsal.root_fs of the job, using the full name of the
+received filesystem, like scratch/rehearsal/tank/sink/laptop1/zroot/home.
+Previous rehearsal of the filesystem is destroyed first. Received filesystems
+are never modified.
+
+Method clone clones the latest snapshot and requires rehearsal.root_fs in the
+same pool. Method receive sends the latest rehearsal.count snapshots into a
+duplicate filesystem, which can be in any pool. Replayed filesystems are not
+mounted.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+		cmd.Flags().BoolVar(&rehearseArgs.dryRun, "dry-run", false,
+			"only print what would be replayed")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runRehearse(ctx, subcommand.Config(), args[0])
+	},
+}
+
+// rehearsalJob is a job, which receives filesystems and can rehearse them.
+type rehearsalJob interface {
+	GetRootFS() string
+	GetRootFSMap() []config.RootFSMapping
+	GetStorageClasses() []config.StorageClass
+	GetRehearsal() *config.Rehearsal
+}
+
+func runRehearse(ctx context.Context, c *config.Config, name string) error {
+	j, err := c.Job(name)
+	if err != nil {
+		return err //nolint:wrapcheck // already wrapped
+	}
+
+	rj, ok := j.Ret.(rehearsalJob)
+	if !ok {
+		return fmt.Errorf("job %q is not a pull or sink job", name)
+	}
+	r := rj.GetRehearsal()
+	if r == nil {
+		return fmt.Errorf("job %q has no rehearsal configured", name)
+	}
+
+	roots, err := rehearsalRoots(rj)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", name, err)
+	}
+	rehearsalRoot, err := zfs.NewDatasetPath(r.RootFS)
+	if err != nil {
+		return fmt.Errorf("job %q: invalid rehearsal.root_fs: %w", name, err)
+	} else if err := checkRehearsalRoot(rehearsalRoot, roots, r.Method); err != nil {
+		return fmt.Errorf("job %q: %w", name, err)
+	}
+
+	fss, err := zfs.ZFSListPaths(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot list filesystems: %w", err)
+	}
+
+	var replayed int
+	for _, fs := range receivedBelow(fss, roots) {
+		ok, err := rehearseFilesystem(ctx, r, fs)
+		if err != nil {
+			return fmt.Errorf("rehearse %q: %w", fs.ToString(), err)
+		} else if ok {
+			replayed++
+		}
+	}
+
+	if rehearseArgs.dryRun {
+		fmt.Printf("would replay %d filesystems\n", replayed)
+	} else {
+		fmt.Printf("replayed %d filesystems\n", replayed)
+	}
+	return nil
+}
+
+// rehearsalRoots returns root_fs of the job, root_fs of its root_fs_map and
+// storage_classes.
+func rehearsalRoots(j rehearsalJob) ([]*zfs.DatasetPath, error) {
+	names := []string{j.GetRootFS()}
+	for _, m := range j.GetRootFSMap() {
+		names = append(names, m.RootFS)
+	}
+	for _, sc := range j.GetStorageClasses() {
+		names = append(names, sc.RootFS)
+	}
+
+	roots := make([]*zfs.DatasetPath, 0, len(names))
+	for _, name := range names {
+		p, err := zfs.NewDatasetPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root_fs %q: %w", name, err)
+		}
+		roots = append(roots, p)
+	}
+	return roots, nil
+}
+
+// checkRehearsalRoot returns error, if rehearsal root and receive roots
+// overlap, because rehearsal must not touch received filesystems, or if they
+// are in different pools and method clone is used.
+func checkRehearsalRoot(root *zfs.DatasetPath, roots []*zfs.DatasetPath,
+	method string,
+) error {
+	if root.Length() == 0 {
+		return errors.New("empty rehearsal.root_fs")
+	}
+
+	for _, r := range roots {
+		if root.HasPrefix(r) || r.HasPrefix(root) {
+			return fmt.Errorf("rehearsal.root_fs %q overlaps root_fs %q",
+				root.ToString(), r.ToString())
+		} else if method == "clone" && datasetPool(root) != datasetPool(r) {
+			return fmt.Errorf(
+				"rehearsal method clone requires root_fs %q in pool %q",
+				root.ToString(), datasetPool(r))
+		}
+	}
+	return nil
+}
+
+func datasetPool(p *zfs.DatasetPath) string {
+	pool, _, _ := strings.Cut(p.ToString(), "/")
+	return pool
+}
+
+// receivedBelow returns filesystems of fss, which are roots or below them,
+// sorted by name, so parents are rehearsed before their children.
+func receivedBelow(fss, roots []*zfs.DatasetPath) []*zfs.DatasetPath {
+	var received []*zfs.DatasetPath
+	for _, fs := range fss {
+		if slices.ContainsFunc(roots, fs.HasPrefix) {
+			received = append(received, fs)
+		}
+	}
+	slices.SortFunc(received, func(a, b *zfs.DatasetPath) int {
+		return cmp.Compare(a.ToString(), b.ToString())
+	})
+	return received
+}
+
+// rehearsalTarget returns name of the filesystem, which rehearses fs.
+func rehearsalTarget(root string, fs *zfs.DatasetPath) string {
+	return path.Join(root, fs.ToString())
+}
+
+// latestSnapshots returns count most recent snapshots of snaps, oldest first.
+func latestSnapshots(snaps []zfs.FilesystemVersion, count uint,
+) []zfs.FilesystemVersion {
+	snaps = slices.Clone(snaps)
+	slices.SortFunc(snaps, func(a, b zfs.FilesystemVersion) int {
+		return cmp.Compare(a.CreateTXG, b.CreateTXG)
+	})
+	if n := len(snaps) - int(count); n > 0 {
+		snaps = snaps[n:]
+	}
+	return snaps
+}
+
+func rehearseFilesystem(ctx context.Context, r *config.Rehearsal,
+	fs *zfs.DatasetPath,
+) (bool, error) {
+	ph, err := zfs.ZFSGetFilesystemPlaceholderState(ctx, fs)
+	if err != nil {
+		return false, fmt.Errorf("cannot get placeholder state: %w", err)
+	} else if !ph.FSExists || ph.IsPlaceholder {
+		return false, nil
+	}
+
+	snaps, err := zfs.ZFSListFilesystemVersions(ctx, fs,
+		zfs.ListFilesystemVersionsOptions{Types: zfs.Snapshots})
+	if err != nil {
+		return false, fmt.Errorf("cannot list snapshots: %w", err)
+	}
+
+	count := r.Count
+	if r.Method == "clone" {
+		count = 1
+	}
+	snaps = latestSnapshots(snaps, count)
+	if len(snaps) == 0 {
+		fmt.Printf("skip %s: no snapshots\n", fs.ToString())
+		return false, nil
+	}
+
+	target := rehearsalTarget(r.RootFS, fs)
+	first, last := snaps[0], snaps[len(snaps)-1]
+	fmt.Printf("%s %s %s..%s (%d snapshots) to %s\n", r.Method, fs.ToString(),
+		first.RelName(), last.RelName(), len(snaps), target)
+	if rehearseArgs.dryRun {
+		return true, nil
+	}
+
+	if err := zfs.ZFSDestroyRecursive(ctx, target); err != nil {
+		return false, fmt.Errorf("destroy previous rehearsal: %w", err)
+	}
+	if parent := path.Dir(target); path.Dir(parent) != "." {
+		err := zfs.ZFSCreateParents(ctx, parent,
+			map[string]string{"canmount": "off"})
+		if err != nil {
+			return false, fmt.Errorf("create parent of %q: %w", target, err)
+		}
+	}
+
+	if r.Method == "clone" {
+		if err := zfs.ZFSClone(ctx, last.FullPath(fs.ToString()), target); err != nil {
+			return false, fmt.Errorf("clone %q: %w", last.RelName(), err)
+		}
+		return true, nil
+	}
+
+	if err := rehearsalSendRecv(ctx, fs.ToString(), nil, first, target); err != nil {
+		return false, err
+	} else if len(snaps) > 1 {
+		err := rehearsalSendRecv(ctx, fs.ToString(), &first, last, target)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// rehearsalSendRecv sends snapshot to of fs, incremental from from with all
+// intermediate snapshots, if from isn't nil, and receives it into target.
+func rehearsalSendRecv(ctx context.Context, fs string,
+	from *zfs.FilesystemVersion, to zfs.FilesystemVersion, target string,
+) error {
+	encrypted, err := zfs.ZFSGetEncryptionEnabled(ctx, fs)
+	if err != nil {
+		return fmt.Errorf("cannot check encryption: %w", err)
+	}
+
+	toArg := to.ToSendArgVersion()
+	sendArgs := zfs.ZFSSendArgsUnvalidated{
+		ZFSSendFlags: zfs.ZFSSendFlags{
+			Encrypted: encrypted,
+			Raw:       encrypted,
+			Multi:     from != nil,
+		},
+		FS: fs,
+		To: &toArg,
+	}
+	if from != nil {
+		fromArg := from.ToSendArgVersion()
+		sendArgs.From = &fromArg
+	}
+
+	validated, err := sendArgs.Validate(ctx)
+	if err != nil {
+		return fmt.Errorf("invalid send arguments: %w", err)
+	}
+	stream, err := zfs.ZFSSend(ctx, validated)
+	if err != nil {
+		return fmt.Errorf("send %q: %w", to.RelName(), err)
+	}
+
+	err = zfs.ZFSRecv(ctx, target, &toArg, stream,
+		zfs.RecvOptions{NoMount: true})
+	if err != nil {
+		return fmt.Errorf("receive %q into %q: %w", to.RelName(), target, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func mustDatasetPaths(t *testing.T, names ...string) []*zfs.DatasetPath {
+	t.Helper()
+	paths := make([]*zfs.DatasetPath, len(names))
+	for i, name := range names {
+		p, err := zfs.NewDatasetPath(name)
+		require.NoError(t, err)
+		paths[i] = p
+	}
+	return paths
+}
+
+func TestCheckRehearsalRoot(t *testing.T) {
+	roots := mustDatasetPaths(t, "tank/sink", "fast/sink")
+	tests := []struct {
+		name    string
+		root    string
+		method  string
+		wantErr string
+	}{
+		{name: "receive", root: "scratch/rehearsal", method: "receive"},
+		{
+			name: "below root", root: "tank/sink/rehearsal", method: "receive",
+			wantErr: "overlaps",
+		},
+		{
+			name: "above root", root: "fast", method: "receive",
+			wantErr: "overlaps",
+		},
+		{
+			name: "clone other pool", root: "tank/rehearsal", method: "clone",
+			wantErr: `in pool "fast"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRehearsalRoot(mustDatasetPaths(t, tt.root)[0], roots,
+				tt.method)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	require.NoError(t, checkRehearsalRoot(
+		mustDatasetPaths(t, "tank/rehearsal")[0], roots[:1], "clone"))
+}
+
+func TestReceivedBelow(t *testing.T) {
+	fss := mustDatasetPaths(t, "tank/sink/b/c", "tank", "tank/sink",
+		"tank/sink/b", "tank/sinker", "fast/sink/a", "tank/sink/a-b")
+	roots := mustDatasetPaths(t, "tank/sink", "fast/sink")
+
+	var names []string
+	for _, fs := range receivedBelow(fss, roots) {
+		names = append(names, fs.ToString())
+	}
+	assert.Equal(t, []string{
+		"fast/sink/a", "tank/sink", "tank/sink/a-b", "tank/sink/b",
+		"tank/sink/b/c",
+	}, names)
+
+	assert.Equal(t, "scratch/tank/sink/b",
+		rehearsalTarget("scratch", mustDatasetPaths(t, "tank/sink/b")[0]))
+}
+
+func TestLatestSnapshots(t *testing.T) {
+	snaps := []zfs.FilesystemVersion{
+		{Name: "c", CreateTXG: 30},
+		{Name: "a", CreateTXG: 10},
+		{Name: "b", CreateTXG: 20},
+	}
+
+	names := func(snaps []zfs.FilesystemVersion) (s []string) {
+		for _, v := range snaps {
+			s = append(s, v.Name)
+		}
+		return s
+	}
+	assert.Equal(t, []string{"c"}, names(latestSnapshots(snaps, 1)))
+	assert.Equal(t, []string{"b", "c"}, names(latestSnapshots(snaps, 2)))
+	assert.Equal(t, []string{"a", "b", "c"}, names(latestSnapshots(snaps, 5)))
+	assert.Empty(t, latestSnapshots(nil, 1))
+	assert.Equal(t, "c", snaps[0].Name)
+}
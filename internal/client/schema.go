@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/client/status"
+	"github.com/dsh2dsh/zrepl/internal/daemon/notify"
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/version"
+)
+
+var SchemaCmd = &cli.Subcommand{
+	Use:             "schema [NAME]",
+	Short:           "print JSON Schema of JSON documents produced by zrepl",
+	NoRequireConfig: true,
+	SetupCobra: func(c *cobra.Command) {
+		c.Args = cobra.MaximumNArgs(1)
+		c.ValidArgs = slices.Sorted(maps.Keys(APISchemas()))
+	},
+	Run: func(ctx context.Context, _ *cli.Subcommand, args []string) error {
+		schemas := APISchemas()
+		var v any = schemas
+		if len(args) > 0 {
+			s, ok := schemas[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown schema %q, expected one of: %s", args[0],
+					strings.Join(slices.Sorted(maps.Keys(schemas)), ", "))
+			}
+			v = s
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("marshal schema: %w", err)
+		}
+		return nil
+	},
+}
+
+// APISchemas returns JSON Schemas of JSON documents, which are stable contract
+// for tools consuming them:
+//
+//   - status: output of zrepl status --format json
+//   - verify: response of the daemon to verify and adopt requests
+//   - version: response of the daemon to version requests
+//   - webhook: summary of a job run, posted by webhook notifications without
+//     template
+func APISchemas() map[string]*jsonschema.Schema {
+	return map[string]*jsonschema.Schema{
+		"status":  jsonschema.Reflect("zrepl status", &status.JSONStatus{}),
+		"verify":  jsonschema.Reflect("zrepl verify", &report.VerifyReport{}),
+		"version": jsonschema.Reflect("zrepl version", &version.ZreplVersionInformation{}),
+		"webhook": jsonschema.Reflect("zrepl webhook", &notify.Summary{}),
+	}
+}
+
+// MarshalAPISchemas returns APISchemas as indented JSON.
+func MarshalAPISchemas() ([]byte, error) {
+	b, err := json.MarshalIndent(APISchemas(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal schemas: %w", err)
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,17 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+)
+
+func TestAPISchemas_upToDate(t *testing.T) {
+	b, err := MarshalAPISchemas()
+	require.NoError(t, err)
+	assert.Equal(t, string(b), string(daemon.APISchemaJSON),
+		"schema.json is outdated, run go generate ./internal/daemon")
+}
@@ -16,7 +16,7 @@ var SignalCmd = &cli.Subcommand{
 	Long: `Send a signal to the daemon.
 
 Expected signals:
-  reload   Reload TLS certificates
+  reload   Reload TLS certificates and jobs from config file
   reset    Abort job's current invocation
   shutdown Stop daemon gracefully
   stop     Stop daemon right now
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+)
+
+var skipArgs struct {
+	job   string
+	fs    string
+	d     time.Duration
+	clear bool
+}
+
+var SkipCmd = &cli.Subcommand{
+	Use:   "skip --job JOB --fs FS {--for DURATION | --clear}",
+	Short: "temporary exclude a filesystem from replication",
+	Long: `Temporary exclude a filesystem from replication.
+
+The filesystem is excluded from next runs of a push or pull job, until given
+duration expires. It's recorded in the daemon state and survives daemon
+restarts and config reloads. Skipped filesystems are visible in status.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(0)
+		f := cmd.Flags()
+		f.StringVarP(&skipArgs.job, "job", "j", "", "job name")
+		f.StringVar(&skipArgs.fs, "fs", "", "filesystem name on sender side")
+		f.DurationVar(&skipArgs.d, "for", 0, "skip filesystem for this duration")
+		f.BoolVar(&skipArgs.clear, "clear", false,
+			"include filesystem back into replication")
+		_ = cmd.MarkFlagRequired("job")
+		_ = cmd.MarkFlagRequired("fs")
+		cmd.MarkFlagsOneRequired("for", "clear")
+		cmd.MarkFlagsMutuallyExclusive("for", "clear")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runSkipCmd(subcommand.Config())
+	},
+}
+
+func runSkipCmd(config *config.Config) error {
+	req := struct {
+		Name       string
+		Filesystem string
+		For        time.Duration
+	}{Name: skipArgs.job, Filesystem: skipArgs.fs}
+
+	if !skipArgs.clear {
+		if skipArgs.d <= 0 {
+			return errors.New("--for must be positive")
+		}
+		req.For = skipArgs.d
+	}
+
+	return jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointSkip, &req, nil)
+}
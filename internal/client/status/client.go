@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/client/jsonclient"
 	"github.com/dsh2dsh/zrepl/internal/daemon"
@@ -31,6 +32,25 @@ func (self *Client) Status() (s daemon.Status, err error) {
 	return s, err
 }
 
+// WatchStatus waits until daemon status differs from version, or timeout
+// expired, and returns it.
+func (self *Client) WatchStatus(version uint64, timeout time.Duration,
+) (u daemon.StatusUpdate, err error) {
+	err = self.control.Post(context.Background(),
+		daemon.ControlJobEndpointStatusWatch,
+		struct {
+			Version uint64
+			Timeout time.Duration
+		}{
+			Version: version,
+			Timeout: timeout,
+		}, &u)
+	if err != nil {
+		err = fmt.Errorf("daemon status watch: %w", err)
+	}
+	return u, err
+}
+
 func (self *Client) StatusRaw() ([]byte, error) {
 	var r json.RawMessage
 	err := self.control.Get(context.Background(),
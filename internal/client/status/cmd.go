@@ -12,6 +12,7 @@ import (
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
 	"github.com/dsh2dsh/zrepl/internal/cli"
@@ -21,6 +22,8 @@ import (
 var (
 	selectedJob     string
 	refreshInterval time.Duration
+	outputFormat    string
+	liveMode        bool
 )
 
 var Subcommand = &cli.Subcommand{
@@ -31,7 +34,11 @@ var Subcommand = &cli.Subcommand{
 		cmd.Args = cobra.ExactArgs(0)
 		addSelectedJob(cmd)
 		cmd.Flags().DurationVarP(&refreshInterval, "delay", "d", 1*time.Second,
-			"refresh interval")
+			"minimal refresh interval")
+		cmd.Flags().StringVarP(&outputFormat, "format", "f", "",
+			"output format (tui|text|json), default: tui on a terminal, text otherwise")
+		cmd.Flags().BoolVarP(&liveMode, "live", "l", false,
+			"interactive live-updating mode, same as --format tui")
 	},
 
 	SetupSubcommands: func() []*cli.Subcommand {
@@ -39,6 +46,23 @@ var Subcommand = &cli.Subcommand{
 	},
 
 	Run: func(ctx context.Context, cmd *cli.Subcommand, args []string) error {
+		format, err := statusFormat()
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case "tui":
+		case "text":
+			return withStatusClient(cmd, func(c *Client) error {
+				return dump(c, selectedJob)
+			})
+		case "json":
+			return withStatusClient(cmd, func(c *Client) error {
+				return writeJSON(os.Stdout, c, selectedJob)
+			})
+		}
+
 		return withStatusClient(cmd, func(c *Client) error {
 			model := NewStatusTUI(c).WithInitialJob(selectedJob).
 				WithUpdateEvery(refreshInterval)
@@ -51,6 +75,23 @@ var Subcommand = &cli.Subcommand{
 	},
 }
 
+func statusFormat() (string, error) {
+	switch outputFormat {
+	case "":
+		if liveMode || term.IsTerminal(os.Stdout.Fd()) {
+			return "tui", nil
+		}
+		return "text", nil
+	case "tui", "text", "json":
+		if liveMode && outputFormat != "tui" {
+			return "", fmt.Errorf("--live conflicts with --format %s", outputFormat)
+		}
+		return outputFormat, nil
+	}
+	return "", fmt.Errorf("invalid format %q, must be one of tui, text, json",
+		outputFormat)
+}
+
 var dumpCmd = &cli.Subcommand{
 	Use:   "dump",
 	Short: "output daemon status information as plain text",
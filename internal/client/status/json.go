@@ -0,0 +1,421 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+// JSONVersion is the version of JSON format, produced by `zrepl status
+// --format json`. It changes only on incompatible changes of the format.
+const JSONVersion = 1
+
+// JSONStatus is the stable JSON representation of daemon status. Unlike raw
+// status, it doesn't depend on internal structures of the daemon.
+type JSONStatus struct {
+	Version int       `json:"version"`
+	Jobs    []JSONJob `json:"jobs"`
+}
+
+type JSONJob struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Running  bool      `json:"running"`
+	Duration float64   `json:"duration_seconds"`
+	Error    string    `json:"error,omitempty"`
+	Cron     string    `json:"cron,omitempty"`
+	NextRun  time.Time `json:"next_run,omitzero"`
+	Overlaps uint      `json:"overlaps"`
+	Disabled bool      `json:"disabled,omitempty"`
+
+	Progress JSONProgress `json:"progress"`
+
+	Snapshotting    *JSONSnapshotting `json:"snapshotting,omitempty"`
+	Replication     *JSONReplication  `json:"replication,omitempty"`
+	Verification    *JSONVerification `json:"verification,omitempty"`
+	Skipped         []JSONSkipped     `json:"skipped,omitempty"`
+	Paused          bool              `json:"paused,omitempty"`
+	BandwidthLimit  uint64            `json:"bandwidth_limit,omitempty"`
+	Pruning         *JSONPruning      `json:"pruning,omitempty"`
+	PruningSender   *JSONPruning      `json:"pruning_sender,omitempty"`
+	PruningReceiver *JSONPruning      `json:"pruning_receiver,omitempty"`
+	Clients         []JSONClient      `json:"clients,omitempty"`
+}
+
+// JSONClient are streams received by a sink from one client identity.
+type JSONClient struct {
+	Identity      string    `json:"identity"`
+	BytesReceived uint64    `json:"bytes_received"`
+	Streams       uint64    `json:"streams"`
+	Failures      uint64    `json:"failures"`
+	LastReceive   time.Time `json:"last_receive,omitzero"`
+}
+
+type JSONSkipped struct {
+	Filesystem string    `json:"filesystem"`
+	Until      time.Time `json:"until"`
+}
+
+type JSONProgress struct {
+	Steps         int       `json:"steps"`
+	Step          int       `json:"step"`
+	Expected      uint64    `json:"expected"`
+	Completed     uint64    `json:"completed"`
+	SleepingUntil time.Time `json:"sleeping_until,omitzero"`
+}
+
+type JSONSnapshotting struct {
+	Type        string                   `json:"type"`
+	State       string                   `json:"state,omitempty"`
+	StartedAt   time.Time                `json:"started_at,omitzero"`
+	SleepUntil  time.Time                `json:"sleep_until,omitzero"`
+	Error       string                   `json:"error,omitempty"`
+	Filesystems []JSONSnapshotFilesystem `json:"filesystems"`
+}
+
+type JSONSnapshotFilesystem struct {
+	Name       string    `json:"name"`
+	State      string    `json:"state"`
+	Snapshot   string    `json:"snapshot,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	DoneAt     time.Time `json:"done_at,omitzero"`
+	Hooks      string    `json:"hooks,omitempty"`
+	HooksError bool      `json:"hooks_error"`
+}
+
+type JSONReplication struct {
+	StartedAt       time.Time                   `json:"started_at,omitzero"`
+	FinishedAt      time.Time                   `json:"finished_at,omitzero"`
+	Attempts        int                         `json:"attempts"`
+	State           string                      `json:"state,omitempty"`
+	Error           string                      `json:"error,omitempty"`
+	BytesExpected   uint64                      `json:"bytes_expected"`
+	BytesReplicated uint64                      `json:"bytes_replicated"`
+	Filesystems     []JSONReplicationFilesystem `json:"filesystems"`
+}
+
+type JSONReplicationFilesystem struct {
+	Name            string     `json:"name"`
+	State           string     `json:"state"`
+	BlockedOn       string     `json:"blocked_on,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	CurrentStep     int        `json:"current_step"`
+	BytesExpected   uint64     `json:"bytes_expected"`
+	BytesReplicated uint64     `json:"bytes_replicated"`
+	PlanningMemory  int64      `json:"planning_memory,omitempty"`
+	Steps           []JSONStep `json:"steps"`
+}
+
+type JSONStep struct {
+	From            string `json:"from,omitempty"`
+	To              string `json:"to"`
+	Resumed         bool   `json:"resumed"`
+	BytesExpected   uint64 `json:"bytes_expected"`
+	BytesReplicated uint64 `json:"bytes_replicated"`
+	// Rate is achieved rate of the step in bytes per second.
+	Rate uint64 `json:"rate,omitempty"`
+	// ThrottledSeconds is time the step waited for bandwidth limit.
+	ThrottledSeconds float64 `json:"throttled_seconds,omitempty"`
+}
+
+type JSONVerification struct {
+	StartedAt   time.Time                    `json:"started_at"`
+	FinishedAt  time.Time                    `json:"finished_at,omitzero"`
+	Error       string                       `json:"error,omitempty"`
+	Failed      int                          `json:"failed"`
+	Filesystems []JSONVerificationFilesystem `json:"filesystems"`
+}
+
+type JSONVerificationFilesystem struct {
+	Name         string   `json:"name"`
+	LatestCommon string   `json:"latest_common,omitempty"`
+	Common       int      `json:"common"`
+	Behind       int      `json:"behind"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+type JSONPruning struct {
+	State       string                  `json:"state"`
+	StartedAt   time.Time               `json:"started_at,omitzero"`
+	Error       string                  `json:"error,omitempty"`
+	Filesystems []JSONPruningFilesystem `json:"filesystems"`
+}
+
+type JSONPruningFilesystem struct {
+	Name           string `json:"name"`
+	Completed      bool   `json:"completed"`
+	Snapshots      int    `json:"snapshots"`
+	Destroys       int    `json:"destroys"`
+	PendingDestroy string `json:"pending_destroy,omitempty"`
+	SkipReason     string `json:"skip_reason,omitempty"`
+	Error          string `json:"error,omitempty"`
+
+	BookmarkDestroys int `json:"bookmark_destroys,omitempty"`
+	// Held are names of snapshots, which weren't destroyed because of user
+	// holds.
+	Held []string `json:"held,omitempty"`
+}
+
+// NewJSONStatus converts status of the daemon into JSONStatus. If jobName isn't
+// empty, only this job is included. Internal jobs are never included.
+func NewJSONStatus(s *daemon.Status, jobName string) (*JSONStatus, error) {
+	names := make([]string, 0, len(s.Jobs))
+	for name, j := range s.Jobs {
+		if j.Internal() || j.JobSpecific == nil {
+			continue
+		} else if jobName == "" || name == jobName {
+			names = append(names, name)
+		}
+	}
+	if jobName != "" && len(names) == 0 {
+		return nil, fmt.Errorf("job %q doesn't exists", jobName)
+	}
+	slices.Sort(names)
+
+	r := &JSONStatus{Version: JSONVersion, Jobs: make([]JSONJob, len(names))}
+	for i, name := range names {
+		r.Jobs[i] = newJSONJob(name, s.Jobs[name])
+	}
+	return r, nil
+}
+
+func newJSONJob(name string, s *job.Status) JSONJob {
+	j := JSONJob{
+		Name:     name,
+		Type:     string(s.Type),
+		Error:    s.Error(),
+		Cron:     s.Cron(),
+		NextRun:  s.NextCron,
+		Overlaps: s.Overlaps,
+		Disabled: s.Disabled,
+	}
+
+	d, running := s.Running()
+	j.Running, j.Duration = running, d.Seconds()
+	j.Progress.Steps, j.Progress.Step = s.Steps()
+	j.Progress.Expected, j.Progress.Completed = s.Progress()
+	j.Progress.SleepingUntil = s.SleepingUntil()
+
+	switch v := s.JobSpecific.(type) {
+	case *job.ActiveSideStatus:
+		j.Snapshotting = newJSONSnapshotting(v.Snapshotting)
+		j.Replication = newJSONReplication(v.Replication)
+		j.Verification = newJSONVerification(v.Verify)
+		for _, item := range v.Skipped {
+			j.Skipped = append(j.Skipped, JSONSkipped(item))
+		}
+		j.Paused = v.Paused
+		j.BandwidthLimit = v.BandwidthLimit
+		j.PruningSender = newJSONPruning(v.PruningSender)
+		j.PruningReceiver = newJSONPruning(v.PruningReceiver)
+	case *job.PassiveStatus:
+		j.Snapshotting = newJSONSnapshotting(v.Snapper)
+		j.Clients = newJSONClients(v.Clients)
+	case *job.SnapJobStatus:
+		j.Snapshotting = newJSONSnapshotting(v.Snapshotting)
+		j.Pruning = newJSONPruning(v.Pruning)
+	}
+	return j
+}
+
+func newJSONClients(clients map[string]job.ClientStats) []JSONClient {
+	if len(clients) == 0 {
+		return nil
+	}
+	r := make([]JSONClient, 0, len(clients))
+	for _, name := range slices.Sorted(maps.Keys(clients)) {
+		c := clients[name]
+		r = append(r, JSONClient{
+			Identity:      name,
+			BytesReceived: c.BytesReceived,
+			Streams:       c.Streams,
+			Failures:      c.Failures,
+			LastReceive:   c.LastReceive,
+		})
+	}
+	return r
+}
+
+func newJSONSnapshotting(r *snapper.Report) *JSONSnapshotting {
+	if r == nil {
+		return nil
+	}
+
+	s := &JSONSnapshotting{
+		Type:        string(r.Type),
+		Filesystems: []JSONSnapshotFilesystem{},
+	}
+	p := r.Periodic
+	if p == nil {
+		return s
+	}
+
+	s.State, s.Error = p.State.String(), p.Error
+	s.StartedAt, s.SleepUntil = p.StartedAt, p.SleepUntil
+	s.Filesystems = make([]JSONSnapshotFilesystem, len(p.Progress))
+	for i, fs := range p.Progress {
+		s.Filesystems[i] = JSONSnapshotFilesystem{
+			Name:       fs.Path,
+			State:      fs.State.String(),
+			Snapshot:   fs.SnapName,
+			StartedAt:  fs.StartAt,
+			DoneAt:     fs.DoneAt,
+			Hooks:      fs.Hooks,
+			HooksError: fs.HooksHadError,
+		}
+	}
+	return s
+}
+
+func newJSONReplication(r *report.Report) *JSONReplication {
+	if r == nil {
+		return nil
+	}
+
+	s := &JSONReplication{
+		StartedAt:  r.StartAt,
+		FinishedAt: r.FinishAt,
+		Attempts:   len(r.Attempts),
+		Error:      r.Error(),
+
+		Filesystems: []JSONReplicationFilesystem{},
+	}
+	if len(r.Attempts) == 0 {
+		return s
+	}
+
+	a := r.Attempts[len(r.Attempts)-1]
+	s.State = string(a.State)
+	s.BytesExpected, s.BytesReplicated, _ = a.BytesSum()
+	s.Filesystems = make([]JSONReplicationFilesystem, len(a.Filesystems))
+	for i, fs := range a.Filesystems {
+		s.Filesystems[i] = newJSONReplicationFilesystem(fs)
+	}
+	return s
+}
+
+func newJSONReplicationFilesystem(fs *report.FilesystemReport,
+) JSONReplicationFilesystem {
+	s := JSONReplicationFilesystem{
+		Name:        fs.Info.Name,
+		State:       string(fs.State),
+		CurrentStep: fs.CurrentStep,
+		Steps:       make([]JSONStep, len(fs.Steps)),
+
+		PlanningMemory: fs.Info.PlanningMemory,
+	}
+	if fs.BlockedOn != report.FsBlockedOnNothing {
+		s.BlockedOn = string(fs.BlockedOn)
+	}
+	if err := fs.Error(); err != nil {
+		s.Error = err.Err
+	}
+	s.BytesExpected, s.BytesReplicated, _ = fs.BytesSum()
+
+	for i, step := range fs.Steps {
+		s.Steps[i] = JSONStep{
+			From:            step.Info.From,
+			To:              step.Info.To,
+			Resumed:         step.Info.Resumed,
+			BytesExpected:   step.Info.BytesExpected,
+			BytesReplicated: step.Info.BytesReplicated,
+
+			Rate:             step.Info.Rate(),
+			ThrottledSeconds: step.Info.Throttled.Seconds(),
+		}
+	}
+	return s
+}
+
+func newJSONVerification(r *report.VerifyReport) *JSONVerification {
+	if r == nil {
+		return nil
+	}
+
+	s := &JSONVerification{
+		StartedAt:   r.StartAt,
+		FinishedAt:  r.FinishAt,
+		Error:       r.Err,
+		Failed:      r.Failed(),
+		Filesystems: make([]JSONVerificationFilesystem, len(r.Filesystems)),
+	}
+	for i, fs := range r.Filesystems {
+		s.Filesystems[i] = JSONVerificationFilesystem{
+			Name:         fs.Name,
+			LatestCommon: fs.LatestCommon,
+			Common:       fs.Common,
+			Behind:       fs.Behind,
+			Errors:       fs.Errors,
+		}
+	}
+	return s
+}
+
+func newJSONPruning(r *pruner.Report) *JSONPruning {
+	if r == nil {
+		return nil
+	}
+
+	s := &JSONPruning{
+		State:     r.State,
+		StartedAt: r.StartedAt,
+		Error:     r.Error,
+		Filesystems: make([]JSONPruningFilesystem, 0,
+			len(r.Completed)+len(r.Pending)),
+	}
+	for _, fs := range r.Completed {
+		s.Filesystems = append(s.Filesystems, newJSONPruningFilesystem(&fs, true))
+	}
+	for _, fs := range r.Pending {
+		s.Filesystems = append(s.Filesystems, newJSONPruningFilesystem(&fs, false))
+	}
+	return s
+}
+
+func newJSONPruningFilesystem(fs *pruner.FSReport, completed bool,
+) JSONPruningFilesystem {
+	var held []string
+	for _, s := range fs.Held {
+		held = append(held, s.Name)
+	}
+	return JSONPruningFilesystem{
+		Name:           fs.Filesystem,
+		Completed:      completed,
+		Snapshots:      fs.SnapshotsCount,
+		Destroys:       fs.DestroysCount,
+		PendingDestroy: fs.PendingDestroy,
+		SkipReason:     string(fs.SkipReason),
+		Error:          fs.LastError,
+
+		BookmarkDestroys: fs.BookmarkDestroysCount,
+		Held:             held,
+	}
+}
+
+func writeJSON(w io.Writer, c *Client, jobName string) error {
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+
+	s, err := NewJSONStatus(&status, jobName)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("encode status as json: %w", err)
+	}
+	return nil
+}
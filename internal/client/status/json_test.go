@@ -0,0 +1,92 @@
+package status
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+func TestNewJSONStatus(t *testing.T) {
+	s := &daemon.Status{Jobs: map[string]*job.Status{
+		"_internal": {Type: job.TypeInternal},
+		"b": {
+			Type: job.TypePush,
+			JobSpecific: &job.ActiveSideStatus{
+				Replication: &report.Report{
+					Attempts: []*report.AttemptReport{{
+						State: report.AttemptDone,
+						Filesystems: []*report.FilesystemReport{{
+							Info:  &report.FilesystemInfo{Name: "zroot/a"},
+							State: report.FilesystemDone,
+							Steps: []*report.StepReport{{Info: &report.StepInfo{
+								To:              "@b",
+								BytesExpected:   100,
+								BytesReplicated: 90,
+							}}},
+						}},
+					}},
+				},
+				PruningSender: &pruner.Report{
+					State:     "Done",
+					Completed: []pruner.FSReport{{Filesystem: "zroot/a"}},
+				},
+			},
+		},
+		"a": {Type: job.TypeSnap, JobSpecific: &job.SnapJobStatus{}},
+	}}
+
+	got, err := NewJSONStatus(s, "")
+	require.NoError(t, err)
+	assert.Equal(t, JSONVersion, got.Version)
+	require.Len(t, got.Jobs, 2)
+	assert.Equal(t, "a", got.Jobs[0].Name)
+
+	j := got.Jobs[1]
+	assert.Equal(t, "push", j.Type)
+	require.NotNil(t, j.Replication)
+	assert.Equal(t, "done", j.Replication.State)
+	assert.Equal(t, uint64(90), j.Replication.BytesReplicated)
+	require.Len(t, j.Replication.Filesystems, 1)
+	assert.Equal(t, "zroot/a", j.Replication.Filesystems[0].Name)
+	require.NotNil(t, j.PruningSender)
+	assert.Len(t, j.PruningSender.Filesystems, 1)
+	assert.Nil(t, j.PruningReceiver)
+
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"bytes_replicated":90`)
+
+	got, err = NewJSONStatus(s, "b")
+	require.NoError(t, err)
+	require.Len(t, got.Jobs, 1)
+
+	_, err = NewJSONStatus(s, "c")
+	require.Error(t, err)
+}
+
+func TestNewJSONStatus_sinkClients(t *testing.T) {
+	s := &daemon.Status{Jobs: map[string]*job.Status{
+		"sink": {
+			Type: job.TypeSink,
+			JobSpecific: &job.PassiveStatus{Clients: map[string]job.ClientStats{
+				"foo": {BytesReceived: 100, Streams: 2, Failures: 1},
+				"bar": {BytesReceived: 10, Streams: 1},
+			}},
+		},
+	}}
+
+	got, err := NewJSONStatus(s, "")
+	require.NoError(t, err)
+	require.Len(t, got.Jobs, 1)
+	assert.Equal(t, []JSONClient{
+		{Identity: "bar", BytesReceived: 10, Streams: 1},
+		{Identity: "foo", BytesReceived: 100, Streams: 2, Failures: 1},
+	}, got.Jobs[0].Clients)
+}
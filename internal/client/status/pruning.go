@@ -146,15 +146,23 @@ func (self *JobRender) viewPrunerFsStatus(fs *pruner.FSReport, completed bool,
 			s.Indent.PaddingLeft(s.InactiveFsIcon.GetWidth()))
 	}
 
+	var extra string
+	if fs.BookmarkDestroysCount > 0 {
+		extra = fmt.Sprintf(" and %d bookmarks", fs.BookmarkDestroysCount)
+	}
+	if len(fs.Held) > 0 {
+		extra += fmt.Sprintf(", skipped %d held", len(fs.Held))
+	}
+
 	if completed {
-		return fmt.Sprintf("%s (destroy %d of %d snapshots)",
-			checkMarkDone, fs.DestroysCount, fs.SnapshotsCount)
+		return fmt.Sprintf("%s (destroy %d of %d snapshots%s)",
+			checkMarkDone, fs.DestroysCount, fs.SnapshotsCount, extra)
 	}
 
-	if fs.DestroysCount == 1 {
+	if fs.DestroysCount == 1 && extra == "" {
 		return fmt.Sprintf("%sPending %s", hourglassNotDone, fs.PendingDestroy)
 	}
 
-	return fmt.Sprintf("%sPending (destroy %d of %d snapshots)",
-		hourglassNotDone, fs.DestroysCount, fs.SnapshotsCount)
+	return fmt.Sprintf("%sPending (destroy %d of %d snapshots%s)",
+		hourglassNotDone, fs.DestroysCount, fs.SnapshotsCount, extra)
 }
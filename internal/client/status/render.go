@@ -138,9 +138,12 @@ func (self *JobRender) View() string {
 		self.renderSnap(j.Snapshotting)
 		self.renderPruning("Pruning snapshots:", j.Pruning)
 	case *job.PassiveStatus:
-		if self.job.Type == job.TypeSource {
+		switch self.job.Type {
+		case job.TypeSource:
 			self.renderSnap(j.Snapper)
-		} else {
+		case job.TypeSink:
+			self.viewClients(j.Clients)
+		default:
 			self.viewUnknown()
 		}
 	default:
@@ -158,7 +161,12 @@ func (self *JobRender) viewType() {
 
 	self.jobTimeLine = self.currentLine
 	if t, ok := self.job.Running(); ok {
-		self.printLn("Running: " + t.Truncate(time.Second).String())
+		running := "Running: " + t.Truncate(time.Second).String()
+		if next := self.job.NextCron; !next.IsZero() {
+			running += fmt.Sprintf(" (next run in %s)",
+				time.Until(next).Truncate(time.Second))
+		}
+		self.printLn(running)
 	} else if t := self.job.SleepingUntil(); !t.IsZero() {
 		self.printLn(fmt.Sprintf("Sleep until: %s (%s remaining)",
 			t, time.Until(t).Truncate(time.Second)))
@@ -166,6 +174,24 @@ func (self *JobRender) viewType() {
 		self.printLn("Sleep until: wakeup signal")
 	}
 
+	if self.job.Disabled {
+		self.printLn("Disabled: yes")
+	}
+
+	if st, ok := self.job.JobSpecific.(*job.ActiveSideStatus); ok {
+		if st.Paused {
+			self.printLn("Replication paused: yes")
+		}
+		if st.BandwidthLimit > 0 {
+			self.printLn("Bandwidth limit: " +
+				humanizeFormat(st.BandwidthLimit, true, "%s %sB/s"))
+		}
+	}
+
+	if n := self.job.Overlaps; n > 0 {
+		self.printLn(fmt.Sprintf("Overlapped runs: %d", n))
+	}
+
 	if err := self.job.Error(); err != "" {
 		self.printLn("Last error: " + err)
 	}
@@ -192,6 +218,13 @@ func (self *JobRender) JumpLines() []int { return self.jumpLines }
 
 func (self *JobRender) viewActiveStatus(j *job.ActiveSideStatus) {
 	self.viewReplication(j.Replication)
+	if j.Verify != nil {
+		self.viewVerify(j.Verify)
+	}
+	if len(j.Skipped) > 0 {
+		self.viewSkipped(j.Skipped)
+	}
+
 	self.renderPruning("Pruning Sender:", j.PruningSender)
 	self.renderPruning("Pruning Receiver:", j.PruningReceiver)
 	if self.job.Type == job.TypePush {
@@ -280,6 +280,13 @@ func (self *JobRender) viewFsStatus(fs *report.FilesystemReport) string {
 	} else if curStep != nil && curStep.Info.BytesExpected == 0 {
 		sb.WriteString(" (step lacks size estimation)")
 	}
+
+	if curStep != nil && curStep.Info.Elapsed > 0 {
+		sb.WriteString(humanizeFormat(curStep.Info.Rate(), true, " %s %sB/s"))
+		if t := curStep.Info.Throttled; t > 0 {
+			fmt.Fprintf(&sb, " (throttled %s)", t.Truncate(time.Second))
+		}
+	}
 	return sb.String()
 }
 
@@ -11,10 +11,11 @@ import (
 )
 
 type StatusTUI struct {
-	client *Client
-	status daemon.Status
-	err    error
-	state  tuiState
+	client  *Client
+	status  daemon.Status
+	version uint64
+	err     error
+	state   tuiState
 
 	darkMode   bool
 	initialJob string
@@ -69,9 +70,16 @@ func (self *StatusTUI) Init() tea.Cmd {
 		self.load)
 }
 
+// refreshCmd waits at least updateEvery and after that until daemon status
+// changed.
 func (self *StatusTUI) refreshCmd() tea.Cmd {
+	version := self.version
 	return tea.Tick(self.updateEvery, func(t time.Time) tea.Msg {
-		return self.load()
+		u, err := self.client.WatchStatus(version, time.Minute)
+		if err != nil {
+			return err
+		}
+		return u
 	})
 }
 
@@ -102,6 +110,12 @@ func (self *StatusTUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return self, tea.Quit
 	case daemon.Status:
 		return self, self.handleStatus(msg)
+	case daemon.StatusUpdate:
+		if msg.Version == self.version {
+			return self, self.refreshCmd()
+		}
+		self.version = msg.Version
+		return self, self.handleStatus(*msg.Status)
 	}
 	return self, self.updateState(msg)
 }
@@ -0,0 +1,75 @@
+package status
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+func (self *JobRender) viewVerify(r *report.VerifyReport) {
+	defer self.sectionWithTitle("Verification:")()
+	s := &self.Styles
+	if d, running := r.Running(); running {
+		self.printLn(s.Content.Render(fmt.Sprintf(
+			"Started: %s (lasting %s)", r.StartAt.Round(time.Second),
+			d.Round(time.Second))))
+		return
+	}
+
+	self.printLn(s.Content.Render(fmt.Sprintf(
+		"Last Run: %s (lasted %s)", r.FinishAt.Round(time.Second),
+		r.FinishAt.Sub(r.StartAt).Round(time.Second))))
+	if r.Err != "" {
+		self.printLn(s.Content.Render(self.indentMultiline(
+			"Problem:\n"+r.Err, s.Indent)))
+		return
+	}
+
+	self.printLn(s.Content.Render(fmt.Sprintf("Verified: %d, failed: %d",
+		len(r.Filesystems), r.Failed())))
+	for _, fs := range r.Filesystems {
+		if !fs.Failed() {
+			continue
+		}
+		self.printLn(s.Content.Render(self.indentMultiline(
+			fs.Name+":\n"+strings.Join(fs.Errors, "\n"), s.Indent)))
+	}
+}
+
+func (self *JobRender) viewSkipped(skipped []job.SkippedFilesystem) {
+	defer self.sectionWithTitle("Skipped Filesystems:")()
+	s := &self.Styles
+	for _, item := range skipped {
+		self.printLn(s.Content.Render(fmt.Sprintf("%s until %s (%s left)",
+			item.Filesystem, item.Until.Round(time.Second),
+			time.Until(item.Until).Round(time.Second))))
+	}
+}
+
+func (self *JobRender) viewClients(clients map[string]job.ClientStats) {
+	defer self.sectionWithTitle("Clients:")()
+	s := &self.Styles
+	if len(clients) == 0 {
+		self.printLn(s.Content.Render("No streams received yet"))
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(clients)) {
+		c := clients[name]
+		line := fmt.Sprintf("%s: %s in %d streams", name,
+			humanizeFormat(c.BytesReceived, true, "%s %sB"), c.Streams)
+		if c.Failures > 0 {
+			line += fmt.Sprintf(", %d failed", c.Failures)
+		}
+		if !c.LastReceive.IsZero() {
+			line += fmt.Sprintf(", last %s ago",
+				time.Since(c.LastReceive).Round(time.Second))
+		}
+		self.printLn(s.Content.Render(line))
+	}
+}
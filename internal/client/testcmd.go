@@ -63,6 +63,8 @@ func runTestFilterCmd(ctx context.Context, subcommand *cli.Subcommand, args []st
 		ff, df = j.Filesystems, j.Datasets
 	case *config.SnapJob:
 		ff, df = j.Filesystems, j.Datasets
+	case *config.PruneJob:
+		ff, df = j.Filesystems, j.Datasets
 	default:
 		return fmt.Errorf("job type %T does not have filesystems filter", j)
 	}
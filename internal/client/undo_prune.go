@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/endpoint"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+var undoPruneArgs struct {
+	recursive bool
+	dryRun    bool
+}
+
+var UndoPruneCmd = &cli.Subcommand{
+	Use:   "undo-prune [-r] [--dry-run] DATASET...",
+	Short: "restore receiver snapshots scheduled for delayed destroy",
+	Long: `Restore receiver snapshots scheduled for delayed destroy.
+
+Pruning with receiver_destroy_delay doesn't destroy receiver snapshots at once.
+It marks them by ` + endpoint.DestroyAtProperty + ` user property and destroys
+them after the delay. This command removes the mark from all snapshots of
+DATASET, so they are not destroyed. Fix pruning rules too, or next pruning will
+mark them again.
+`,
+	NoRequireConfig: true,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.MinimumNArgs(1)
+		f := cmd.Flags()
+		f.BoolVarP(&undoPruneArgs.recursive, "recursive", "r", false,
+			"restore snapshots of all children of DATASET too")
+		f.BoolVar(&undoPruneArgs.dryRun, "dry-run", false,
+			"only print snapshots, which would be restored")
+	},
+
+	Run: func(ctx context.Context, _ *cli.Subcommand, args []string) error {
+		for _, name := range args {
+			if err := undoPrune(ctx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func undoPrune(ctx context.Context, name string) error {
+	props := []string{"name", endpoint.DestroyAtProperty}
+	zfsArgs := []string{"-t", "snapshot"}
+	if undoPruneArgs.recursive {
+		zfsArgs = append(zfsArgs, "-r")
+	} else {
+		zfsArgs = append(zfsArgs, "-d", "1")
+	}
+	zfsArgs = append(zfsArgs, name)
+
+	cmd := zfs.NewListCmd(ctx, props, zfsArgs)
+	var marked [][]string
+	for fields, err := range zfs.ListIter(ctx, props, nil, cmd) {
+		if err != nil {
+			return fmt.Errorf("list snapshots of %q: %w", name, err)
+		} else if fields[1] != "-" {
+			marked = append(marked, fields)
+		}
+	}
+
+	for _, fields := range marked {
+		snap, destroyAt := fields[0], fields[1]
+		if !undoPruneArgs.dryRun {
+			err := zfs.ZFSInherit(ctx, snap, endpoint.DestroyAtProperty)
+			if err != nil {
+				return fmt.Errorf("restore %q: %w", snap, err)
+			}
+		}
+		fmt.Printf("restored %s (was scheduled for destroy at %s)\n", snap,
+			destroyAt)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+var VerifyCmd = &cli.Subcommand{
+	Use:   "verify JOB",
+	Short: "verify sender and receiver of a push or pull job",
+	Long: `Verify sender and receiver of a push or pull job.
+
+Compares snapshots of every filesystem on both sides: snapshots with the same
+name must have the same GUID and the same order, and every filesystem must have
+at least one common snapshot. If size verification is enabled for the job, dry
+run size estimates of the latest replication are compared with replicated
+bytes.
+`,
+
+	SetupCobra: func(cmd *cobra.Command) {
+		cmd.Args = cobra.ExactArgs(1)
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand,
+		args []string,
+	) error {
+		return runVerifyCmd(subcommand.Config(), args[0])
+	},
+}
+
+func runVerifyCmd(config *config.Config, name string) error {
+	req := struct{ Name string }{Name: name}
+	var r report.VerifyReport
+	err := jsonRequestResponse(config.Global.Control.SockPath,
+		daemon.ControlJobEndpointVerify, &req, &r)
+	if err != nil {
+		return err
+	} else if r.Err != "" {
+		return fmt.Errorf("verify %s: %s", name, r.Err)
+	}
+
+	return printVerifyReport("verify", name, &r)
+}
+
+func printVerifyReport(action, name string, r *report.VerifyReport) error {
+	for _, fs := range r.Filesystems {
+		if !fs.Failed() {
+			status := "ok"
+			if fs.Adopted {
+				status = "adopted"
+			}
+			fmt.Printf("%s\t%s\t%s (common: %d, behind: %d)\n", status, fs.Name,
+				fs.LatestCommon, fs.Common, fs.Behind)
+			continue
+		}
+		for _, s := range fs.Errors {
+			fmt.Printf("FAIL\t%s\t%s\n", fs.Name, s)
+		}
+	}
+
+	fmt.Printf("verified %d filesystems in %s, %d failed\n",
+		len(r.Filesystems), r.FinishAt.Sub(r.StartAt).Round(time.Millisecond),
+		r.Failed())
+	if n := r.Failed(); n > 0 {
+		return fmt.Errorf("%s %s: %d filesystems failed", action, name, n)
+	}
+	return nil
+}
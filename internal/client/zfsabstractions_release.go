@@ -88,8 +88,17 @@ func doZabsReleaseStale(ctx context.Context, sc *cli.Subcommand, args []string)
 }
 
 func doZabsRelease_Common(ctx context.Context, destroy []endpoint.Abstraction) error {
-	if zabsReleaseFlags.DryRun {
-		if zabsReleaseFlags.Json {
+	return releaseAbstractions(ctx, destroy, zabsReleaseFlags.DryRun,
+		zabsReleaseFlags.Json)
+}
+
+// releaseAbstractions destroys abstractions and prints outcome of every one of
+// them, or only prints them, if dryRun is true.
+func releaseAbstractions(ctx context.Context, destroy []endpoint.Abstraction,
+	dryRun, jsonOut bool,
+) error {
+	if dryRun {
+		if jsonOut {
 			m, err := json.MarshalIndent(destroy, "", "  ")
 			if err != nil {
 				panic(err)
@@ -117,7 +126,7 @@ func doZabsRelease_Common(ctx context.Context, destroy []endpoint.Abstraction) e
 
 	for res := range outcome {
 		hadErr = hadErr || res.DestroyErr != nil
-		if zabsReleaseFlags.Json {
+		if jsonOut {
 			err := enc.Encode(res)
 			if err != nil {
 				colorErr.Fprintf(os.Stderr, "cannot marshal there were errors in destroying the abstractions")
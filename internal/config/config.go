@@ -4,11 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"log/syslog"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/creasty/defaults"
 	"go.yaml.in/yaml/v4"
 
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
 	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
 )
 
@@ -33,6 +36,14 @@ type Config struct {
 	Jobs        []JobEnum `yaml:"jobs" validate:"min=1,dive"`
 	IncludeJobs string    `yaml:"include_jobs" validate:"omitempty,filepath"`
 
+	// Templates are jobs with parameters, which jobs with from_template are
+	// built from, here and in included files.
+	Templates []JobTemplate `yaml:"templates" validate:"dive"`
+
+	// Include is a glob of config files, like conf.d/*.yml, which jobs and keys
+	// are appended to the config.
+	Include string `yaml:"include" validate:"omitempty,filepath"`
+
 	skipIncludes bool
 }
 
@@ -47,7 +58,7 @@ func (c *Config) lateInit(path string) error {
 	if len(c.Global.Logging) == 0 {
 		c.Global.Logging.SetDefaults()
 	} else if c.skipIncludes {
-		return nil
+		return c.resolveSecrets()
 	}
 
 	if keys, err := appendYAML(path, c.IncludeKeys, c.Keys); err != nil {
@@ -61,7 +72,11 @@ func (c *Config) lateInit(path string) error {
 	} else if jobs != nil {
 		c.Jobs = jobs
 	}
-	return nil
+
+	if err := c.include(path); err != nil {
+		return err
+	}
+	return c.resolveSecrets()
 }
 
 func (c *Config) Job(name string) (*JobEnum, error) {
@@ -74,9 +89,12 @@ func (c *Config) Job(name string) (*JobEnum, error) {
 	return nil, fmt.Errorf("job %q not defined in config", name)
 }
 
+// AuthKey is a named key. Instead of Key, KeyCmd can be a shell command, which
+// outputs the key, when the config is loaded.
 type AuthKey struct {
-	Name string `yaml:"name" validate:"required"`
-	Key  string `yaml:"key" validate:"required"`
+	Name   string `yaml:"name" validate:"required"`
+	Key    string `yaml:"key" validate:"required_without=KeyCmd"`
+	KeyCmd string `yaml:"key_cmd"`
 }
 
 type JobEnum struct {
@@ -88,6 +106,8 @@ func (j JobEnum) Name() string {
 	switch v := j.Ret.(type) {
 	case *SnapJob:
 		name = v.Name
+	case *PruneJob:
+		name = v.Name
 	case *PushJob:
 		name = v.Name
 	case *SinkJob:
@@ -107,6 +127,8 @@ func (j JobEnum) MonitorSnapshots() MonitorSnapshots {
 	switch v := j.Ret.(type) {
 	case *SnapJob:
 		m = v.MonitorSnapshots
+	case *PruneJob:
+		m = v.MonitorSnapshots
 	case *PushJob:
 		m = v.MonitorSnapshots
 	case *SinkJob:
@@ -119,6 +141,25 @@ func (j JobEnum) MonitorSnapshots() MonitorSnapshots {
 	return m
 }
 
+// ZfsEnv returns extra environment variables for zfs commands of the job.
+func (j JobEnum) ZfsEnv() map[string]string {
+	switch v := j.Ret.(type) {
+	case *SnapJob:
+		return v.ZfsEnv
+	case *PruneJob:
+		return v.ZfsEnv
+	case *PushJob:
+		return v.ZfsEnv
+	case *SinkJob:
+		return v.ZfsEnv
+	case *PullJob:
+		return v.ZfsEnv
+	case *SourceJob:
+		return v.ZfsEnv
+	}
+	return nil
+}
+
 type ActiveJob struct {
 	Type               string                   `yaml:"type" validate:"required"`
 	Name               string                   `yaml:"name" validate:"required"`
@@ -127,9 +168,14 @@ type ActiveJob struct {
 	Replication        Replication              `yaml:"replication"`
 	ConflictResolution ConflictResolution       `yaml:"conflict_resolution"`
 	MonitorSnapshots   MonitorSnapshots         `yaml:"monitor"`
-	Interval           PositiveDurationOrManual `yaml:"interval"`
+	Interval           PositiveDurationOrManual `yaml:"interval,omitempty"`
 	Cron               string                   `yaml:"cron"`
 	Hooks              JobHooks                 `yaml:"hooks"`
+	PingURL            string                   `yaml:"ping_url" validate:"omitempty,url"`
+	PoolImport         *PoolImport              `yaml:"pool_import"`
+	Blackout           []BlackoutWindow         `yaml:"blackout" validate:"dive"`
+	Overlap            string                   `yaml:"overlap" default:"skip" validate:"required,oneof=skip queue stop"`
+	ZfsEnv             map[string]string        `yaml:"zfs_env" validate:"dive,keys,required,endkeys"`
 }
 
 func (self *ActiveJob) CronSpec() string {
@@ -141,8 +187,18 @@ func (self *ActiveJob) CronSpec() string {
 	return ""
 }
 
+// BlackoutWindow defines a time range, when replication must not run. If To
+// is less or equal to From, the window ends on the next day.
+type BlackoutWindow struct {
+	From string   `yaml:"from" validate:"required"`
+	To   string   `yaml:"to" validate:"required"`
+	Days []string `yaml:"days" validate:"dive,oneof=mon tue wed thu fri sat sun"`
+}
+
 type ConflictResolution struct {
 	InitialReplication string `yaml:"initial_replication" default:"all" validate:"required"`
+	ForeignSnapshots   string `yaml:"foreign_snapshots" default:"fail" validate:"required,oneof=fail ignore destroy"`
+	InvalidResumeToken string `yaml:"invalid_resume_token" default:"fail" validate:"required,oneof=fail abort"`
 }
 
 type MonitorSnapshots struct {
@@ -170,18 +226,59 @@ func (self *MonitorSnapshots) Valid() bool {
 		len(self.Oldest) > 0
 }
 
+// PoolImport imports Pool before the job runs, like a removable backup drive,
+// and exports it after the job finished, if the job imported it. Failed imports
+// are retried Retries times, doubling Interval every time.
+type PoolImport struct {
+	Pool     string        `yaml:"pool" validate:"required"`
+	Args     []string      `yaml:"args" validate:"dive,required"`
+	Export   bool          `yaml:"export" default:"true"`
+	Retries  int           `yaml:"retries" default:"3" validate:"min=0"`
+	Interval time.Duration `yaml:"interval" default:"30s" validate:"gt=0s"`
+}
+
+func (self *PoolImport) UnmarshalYAML(value *yaml.Node) error {
+	type poolImport PoolImport
+	v := (*poolImport)(self)
+	if err := defaults.Set(v); err != nil {
+		return fmt.Errorf("set defaults for %T: %w", self, err)
+	} else if err := value.Decode(v); err != nil {
+		return fmt.Errorf("UnmarshalYAML %T: %w", self, err)
+	}
+	return nil
+}
+
 type JobHooks struct {
 	Pre  *HookCommand `yaml:"pre"`
 	Post *HookCommand `yaml:"post"`
 }
 
+func (self *JobHooks) UnmarshalYAML(value *yaml.Node) error {
+	type jobHooks JobHooks
+	if err := value.Decode((*jobHooks)(self)); err != nil {
+		return fmt.Errorf("UnmarshalYAML %T: %w", self, err)
+	}
+	for _, h := range [...]*HookCommand{self.Pre, self.Post} {
+		if h != nil && h.Type != HookTypeCommand {
+			return fmt.Errorf("job hooks must be of type %q, got %q",
+				HookTypeCommand, h.Type)
+		}
+	}
+	return nil
+}
+
 type PassiveJob struct {
-	Type             string           `yaml:"type" validate:"required"`
-	Name             string           `yaml:"name" validate:"required"`
-	ClientKeys       []string         `yaml:"client_keys" validate:"dive,required"`
-	Pruning          PruningLocal     `yaml:"pruning"`
-	MonitorSnapshots MonitorSnapshots `yaml:"monitor"`
-	Hooks            JobHooks         `yaml:"hooks"`
+	Type             string            `yaml:"type" validate:"required"`
+	Name             string            `yaml:"name" validate:"required"`
+	ClientKeys       []string          `yaml:"client_keys" validate:"dive,required"`
+	Pruning          PruningLocal      `yaml:"pruning"`
+	MonitorSnapshots MonitorSnapshots  `yaml:"monitor"`
+	Hooks            JobHooks          `yaml:"hooks"`
+	Overlap          string            `yaml:"overlap" default:"skip" validate:"required,oneof=skip queue stop"`
+	ZfsEnv           map[string]string `yaml:"zfs_env" validate:"dive,keys,required,endkeys"`
+
+	// Socket marks connections of clients, while they're served by this job.
+	Socket SocketOptions `yaml:"socket"`
 }
 
 type SnapJob struct {
@@ -192,13 +289,34 @@ type SnapJob struct {
 	Filesystems      FilesystemsFilter `yaml:"filesystems" validate:"required_without=Datasets"`
 	Datasets         []DatasetFilter   `yaml:"datasets" validate:"required_without=Filesystems,dive"`
 	MonitorSnapshots MonitorSnapshots  `yaml:"monitor"`
+	PingURL          string            `yaml:"ping_url" validate:"omitempty,url"`
+	Overlap          string            `yaml:"overlap" default:"skip" validate:"required,oneof=skip queue stop"`
+	ZfsEnv           map[string]string `yaml:"zfs_env" validate:"dive,keys,required,endkeys"`
 }
 
+// PruneJob prunes snapshots of filesystems by keep rules on its own schedule,
+// without snapshotting and replication, like snapshots created by other tools.
+type PruneJob struct {
+	Type             string            `yaml:"type" validate:"required"`
+	Name             string            `yaml:"name" validate:"required"`
+	Pruning          PruningLocal      `yaml:"pruning"`
+	Cron             string            `yaml:"cron" validate:"required"`
+	Filesystems      FilesystemsFilter `yaml:"filesystems" validate:"required_without=Datasets"`
+	Datasets         []DatasetFilter   `yaml:"datasets" validate:"required_without=Filesystems,dive"`
+	MonitorSnapshots MonitorSnapshots  `yaml:"monitor"`
+	PingURL          string            `yaml:"ping_url" validate:"omitempty,url"`
+	Overlap          string            `yaml:"overlap" default:"skip" validate:"required,oneof=skip queue stop"`
+	ZfsEnv           map[string]string `yaml:"zfs_env" validate:"dive,keys,required,endkeys"`
+}
+
+// DatasetFilter matches datasets by Pattern: a dataset path, with its children
+// if Recursive, a shell pattern if Shell, or a regular expression if Regex.
 type DatasetFilter struct {
 	Pattern   string `yaml:"pattern"`
 	Exclude   bool   `yaml:"exclude"`
-	Recursive bool   `yaml:"recursive" validate:"excluded_with=Shell"`
-	Shell     bool   `yaml:"shell" validate:"excluded_with=Recursive"`
+	Recursive bool   `yaml:"recursive" validate:"excluded_with=Shell Regex"`
+	Shell     bool   `yaml:"shell" validate:"excluded_with=Recursive Regex"`
+	Regex     bool   `yaml:"regex" validate:"excluded_with=Recursive Shell"`
 }
 
 type SendOptions struct {
@@ -211,8 +329,24 @@ type SendOptions struct {
 	Compressed       bool `yaml:"compressed"`
 	EmbeddedData     bool `yaml:"embedded_data"`
 	Saved            bool `yaml:"saved"`
+	Holds            bool `yaml:"holds"`
+
+	ExecPipe [][]string   `yaml:"execpipe" validate:"dive,required"`
+	Buffer   StreamBuffer `yaml:"buffer"`
 
-	ExecPipe [][]string `yaml:"execpipe" validate:"dive,required"`
+	// StorageClasses tag filesystems with storage classes, which sinks map to
+	// their roots. StorageClassProperty, if not empty, is a user property, which
+	// tags filesystems too and takes precedence over StorageClasses.
+	StorageClasses       []SendStorageClass `yaml:"storage_classes" validate:"dive"`
+	StorageClassProperty string             `yaml:"storage_class_property"`
+}
+
+// SendStorageClass tags filesystems, matched by Filesystems or Datasets, with
+// storage class Class.
+type SendStorageClass struct {
+	Class       string            `yaml:"class" validate:"required"`
+	Filesystems FilesystemsFilter `yaml:"filesystems" validate:"required_without=Datasets"`
+	Datasets    []DatasetFilter   `yaml:"datasets" validate:"required_without=Filesystems,dive"`
 }
 
 type RecvOptions struct {
@@ -220,20 +354,89 @@ type RecvOptions struct {
 	// mechanism for it
 	//
 	// Encrypted bool `yaml:"may_encrypted"`
-	// Future:
-	// Reencrypt bool `yaml:"reencrypt"`
+
+	// Reencrypt encrypts plaintext sends on their initial receive.
+	Reencrypt RecvReencrypt `yaml:"reencrypt"`
 
 	Properties  PropertyRecvOptions    `yaml:"properties"`
 	Placeholder PlaceholderRecvOptions `yaml:"placeholder"`
 
-	ExecPipe [][]string `yaml:"execpipe" validate:"dive,required"`
+	// DatasetProperties are properties of received filesystems, which local
+	// names match their filters. All matching entries apply in order, after
+	// Properties.
+	DatasetProperties []RecvDatasetProperties `yaml:"dataset_properties" validate:"dive"`
+
+	// MountpointCollision defines what to do, if mountpoint of a filesystem
+	// received first time shadows existing content.
+	MountpointCollision string `yaml:"mountpoint_collision" default:"ignore" validate:"required,oneof=ignore none inherit fail"`
+
+	// FreeSpace fails receives before they start, if they don't fit into
+	// available space.
+	FreeSpace RecvFreeSpace `yaml:"free_space"`
+
+	ExecPipe [][]string   `yaml:"execpipe" validate:"dive,required"`
+	Buffer   StreamBuffer `yaml:"buffer"`
+}
+
+// StreamBuffer is an in-memory ring buffer between zfs send or zfs recv and
+// the network. Zero Size means no buffer.
+type StreamBuffer struct {
+	Size Bytes `yaml:"size"`
+}
+
+type RecvDatasetProperties struct {
+	Filesystems FilesystemsFilter   `yaml:"filesystems" validate:"required_without=Datasets"`
+	Datasets    []DatasetFilter     `yaml:"datasets" validate:"required_without=Filesystems,dive"`
+	Properties  PropertyRecvOptions `yaml:"properties"`
+}
+
+type RecvReencrypt struct {
+	Enabled    bool   `yaml:"enabled"`
+	Encryption string `yaml:"encryption" default:"on" validate:"required"`
+	// KeyFormat and KeyLocation are required, if parent of received
+	// filesystems isn't encrypted, else they inherit its key.
+	KeyFormat   string `yaml:"keyformat" validate:"required_with=KeyLocation,omitempty,oneof=raw hex passphrase"`
+	KeyLocation string `yaml:"keylocation" validate:"required_with=KeyFormat"`
+}
+
+// Properties returns properties of initial receives, which encrypt them, or
+// nil, if it's disabled.
+func (self *RecvReencrypt) Properties() map[zfsprop.Property]string {
+	if !self.Enabled {
+		return nil
+	}
+	props := map[zfsprop.Property]string{"encryption": self.Encryption}
+	if self.KeyFormat != "" {
+		props["keyformat"] = self.KeyFormat
+		props["keylocation"] = self.KeyLocation
+	}
+	return props
+}
+
+type RecvFreeSpace struct {
+	Enabled bool `yaml:"enabled"`
+	// Headroom is percent of the pool, which must stay free after the
+	// receive.
+	Headroom uint `yaml:"headroom" default:"10" validate:"lt=100"`
 }
 
 type Replication struct {
-	Protection  ReplicationOptionsProtection  `yaml:"protection"`
-	Concurrency ReplicationOptionsConcurrency `yaml:"concurrency"`
-	Prefix      string                        `yaml:"prefix"`
-	Recursive   bool                          `yaml:"recursive"`
+	Protection    ReplicationOptionsProtection    `yaml:"protection"`
+	Concurrency   ReplicationOptionsConcurrency   `yaml:"concurrency"`
+	VersionsLimit ReplicationOptionsVersionsLimit `yaml:"versions_limit"`
+	Retries       ReplicationOptionsRetries       `yaml:"retries"`
+	Verify        ReplicationOptionsVerify        `yaml:"verify"`
+	Prefix        string                          `yaml:"prefix"`
+	Recursive     bool                            `yaml:"recursive"`
+
+	// Intermediates selects how intermediate snapshots are replicated:
+	// "consolidated" sends them all in one stream (zfs send -I), "stepwise"
+	// sends every snapshot in its own step (zfs send -i).
+	Intermediates string `yaml:"intermediates" default:"consolidated" validate:"required,oneof=consolidated stepwise"`
+
+	// PlanningMemory caps estimated memory of version lists, which the planner
+	// holds at once. Zero means no cap.
+	PlanningMemory Bytes `yaml:"planning_memory"`
 }
 
 type ReplicationOptionsProtection struct {
@@ -244,6 +447,58 @@ type ReplicationOptionsProtection struct {
 type ReplicationOptionsConcurrency struct {
 	Steps         int  `yaml:"steps" default:"1" validate:"min=1"`
 	SizeEstimates uint `yaml:"size_estimates"`
+
+	Auto *ConcurrencyAuto `yaml:"auto"`
+}
+
+// ConcurrencyAuto adjusts number of parallel steps while replication runs. It
+// starts from Steps and every Interval changes it between Min and Max: reduces
+// it, when latency of any of Pools exceeds Latency or the last increase didn't
+// improve throughput, and increases it otherwise.
+type ConcurrencyAuto struct {
+	Min      int           `yaml:"min" default:"1" validate:"min=1"`
+	Max      int           `yaml:"max" validate:"required,gtefield=Min"`
+	Interval time.Duration `yaml:"interval" default:"1m" validate:"gt=0s"`
+	Pools    []string      `yaml:"pools" validate:"dive,required"`
+	Latency  time.Duration `yaml:"latency" default:"50ms" validate:"gt=0s"`
+}
+
+func (self *ConcurrencyAuto) UnmarshalYAML(value *yaml.Node) error {
+	type concurrencyAuto ConcurrencyAuto
+	v := (*concurrencyAuto)(self)
+	if err := defaults.Set(v); err != nil {
+		return fmt.Errorf("set defaults for %T: %w", self, err)
+	} else if err := value.Decode(v); err != nil {
+		return fmt.Errorf("UnmarshalYAML %T: %w", self, err)
+	}
+	return nil
+}
+
+// ReplicationOptionsRetries configures retries of replication attempts after
+// connectivity related errors. Zero MaxAttempts means the default from
+// ZREPL_REPLICATION_MAX_ATTEMPTS.
+type ReplicationOptionsRetries struct {
+	MaxAttempts     int           `yaml:"max_attempts" validate:"min=0"`
+	InitialInterval time.Duration `yaml:"initial_interval" validate:"min=0s"`
+	MaxInterval     time.Duration `yaml:"max_interval" validate:"min=0s"`
+	Multiplier      float64       `yaml:"multiplier" default:"2" validate:"gte=1"`
+}
+
+// ReplicationOptionsVersionsLimit guards the planner against filesystems with
+// huge version lists, like the ones created by foreign snapshot tools.
+type ReplicationOptionsVersionsLimit struct {
+	Max    uint   `yaml:"max"`
+	Action string `yaml:"action" default:"warn" validate:"required,oneof=warn skip truncate"`
+	Keep   uint   `yaml:"keep" validate:"required_if=Action truncate"`
+}
+
+// ReplicationOptionsVerify configures verification of sender and receiver
+// after replication. SizeTolerance is the allowed relative difference between
+// dry run size estimates and replicated bytes.
+type ReplicationOptionsVerify struct {
+	Enabled       bool    `yaml:"enabled"`
+	Size          bool    `yaml:"size"`
+	SizeTolerance float64 `yaml:"size_tolerance" default:"0.1" validate:"gte=0,lte=1"`
 }
 
 type PropertyRecvOptions struct {
@@ -275,11 +530,22 @@ type PullJob struct {
 
 	RootFS string      `yaml:"root_fs" validate:"required"`
 	Recv   RecvOptions `yaml:"recv"`
+
+	// Filesystems and Datasets filter filesystems listed by the sender. Nothing
+	// is filtered, if both are empty.
+	Filesystems FilesystemsFilter `yaml:"filesystems,omitempty"`
+	Datasets    []DatasetFilter   `yaml:"datasets,omitempty" validate:"dive"`
+
+	Rehearsal *Rehearsal `yaml:"rehearsal"`
 }
 
 func (j *PullJob) GetRootFS() string             { return j.RootFS }
+func (j *PullJob) GetRootFSMap() []RootFSMapping { return nil }
 func (j *PullJob) GetAppendClientIdentity() bool { return false }
 func (j *PullJob) GetRecvOptions() *RecvOptions  { return &j.Recv }
+func (j *PullJob) GetRehearsal() *Rehearsal      { return j.Rehearsal }
+
+func (j *PullJob) GetStorageClasses() []StorageClass { return nil }
 
 type PositiveDurationOrManual struct {
 	Interval time.Duration
@@ -309,6 +575,24 @@ func (self *PositiveDurationOrManual) UnmarshalYAML(value *yaml.Node) (err error
 	return nil
 }
 
+func (self PositiveDurationOrManual) MarshalYAML() (any, error) {
+	if self.Manual {
+		return "manual", nil
+	}
+	return formatDuration(self.Interval), nil
+}
+
+func (self PositiveDurationOrManual) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: `positive duration, like 10m or 1d, or "manual"`,
+	}
+}
+
+func (self PositiveDurationOrManual) IsZero() bool {
+	return !self.Manual && self.Interval == 0
+}
+
 func (self *PositiveDurationOrManual) Duration() time.Duration {
 	if self.Manual {
 		return 0
@@ -319,13 +603,62 @@ func (self *PositiveDurationOrManual) Duration() time.Duration {
 type SinkJob struct {
 	PassiveJob `yaml:",inline"`
 
-	RootFS string      `yaml:"root_fs" validate:"required"`
-	Recv   RecvOptions `yaml:"recv"`
+	RootFS    string          `yaml:"root_fs" validate:"required"`
+	RootFSMap []RootFSMapping `yaml:"root_fs_map" validate:"dive"`
+	Recv      RecvOptions     `yaml:"recv"`
+
+	// StorageClasses receive filesystems, which senders tagged with storage
+	// classes, below their own roots.
+	StorageClasses []StorageClass `yaml:"storage_classes" validate:"dive"`
+
+	Rehearsal *Rehearsal `yaml:"rehearsal"`
+}
+
+// Rehearsal configures zrepl rehearse, which replays the latest received
+// snapshots below RootFS, for rehearsing disaster recovery without touching
+// received filesystems. Method clone clones the latest snapshot of every
+// received filesystem, method receive sends the latest Count snapshots into a
+// duplicate filesystem.
+type Rehearsal struct {
+	RootFS string `yaml:"root_fs" validate:"required"`
+	Method string `yaml:"method" default:"receive" validate:"required,oneof=clone receive"`
+	Count  uint   `yaml:"count" default:"1" validate:"min=1"`
+}
+
+func (self *Rehearsal) UnmarshalYAML(value *yaml.Node) error {
+	type rehearsal Rehearsal
+	v := (*rehearsal)(self)
+	if err := defaults.Set(v); err != nil {
+		return fmt.Errorf("set defaults for %T: %w", self, err)
+	} else if err := value.Decode(v); err != nil {
+		return fmt.Errorf("UnmarshalYAML %T: %w", self, err)
+	}
+	return nil
+}
+
+// RootFSMapping receives filesystems of senders, which have Prefix, like a pool
+// name, below RootFS instead of root_fs of the sink job.
+type RootFSMapping struct {
+	Prefix string `yaml:"prefix" validate:"required"`
+	RootFS string `yaml:"root_fs" validate:"required"`
+}
+
+// StorageClass receives filesystems, which senders tagged with Class, below
+// RootFS instead of root_fs of the sink job, with Properties in addition to
+// recv.properties of the sink job.
+type StorageClass struct {
+	Class      string              `yaml:"class" validate:"required"`
+	RootFS     string              `yaml:"root_fs" validate:"required"`
+	Properties PropertyRecvOptions `yaml:"properties"`
 }
 
 func (j *SinkJob) GetRootFS() string             { return j.RootFS }
+func (j *SinkJob) GetRootFSMap() []RootFSMapping { return j.RootFSMap }
 func (j *SinkJob) GetAppendClientIdentity() bool { return true }
 func (j *SinkJob) GetRecvOptions() *RecvOptions  { return &j.Recv }
+func (j *SinkJob) GetRehearsal() *Rehearsal      { return j.Rehearsal }
+
+func (j *SinkJob) GetStorageClasses() []StorageClass { return j.StorageClasses }
 
 type SourceJob struct {
 	PassiveJob `yaml:",inline"`
@@ -335,6 +668,16 @@ type SourceJob struct {
 	Filesystems  FilesystemsFilter `yaml:"filesystems" validate:"required_without=Datasets"`
 	Datasets     []DatasetFilter   `yaml:"datasets" validate:"required_without=Filesystems,dive"`
 	Send         SendOptions       `yaml:"send"`
+
+	// ClientFilesystems restricts filesystems, which a client identity can list
+	// and send, to filesystems passing its filter too. Clients not listed here
+	// can access all filesystems of the job.
+	ClientFilesystems map[string]ClientFilesystems `yaml:"client_filesystems,omitempty" validate:"dive,keys,required,endkeys"`
+}
+
+type ClientFilesystems struct {
+	Filesystems FilesystemsFilter `yaml:"filesystems,omitempty" validate:"required_without=Datasets"`
+	Datasets    []DatasetFilter   `yaml:"datasets,omitempty" validate:"required_without=Filesystems,dive"`
 }
 
 func (j *SourceJob) GetFilesystems() (FilesystemsFilter, []DatasetFilter) {
@@ -352,13 +695,53 @@ type SnapshottingEnum struct {
 type SnapshottingPeriodic struct {
 	Type             string                   `yaml:"type" validate:"required,oneof=cron manual periodic"`
 	Prefix           string                   `yaml:"prefix" validate:"required"`
-	Interval         PositiveDurationOrManual `yaml:"interval"`
+	Interval         PositiveDurationOrManual `yaml:"interval,omitempty"`
 	Cron             string                   `yaml:"cron"`
 	Hooks            []HookCommand            `yaml:"hooks" validate:"dive"`
 	TimestampFormat  string                   `yaml:"timestamp_format" default:"dense" validate:"required"`
 	TimestampLocal   bool                     `yaml:"timestamp_local" default:"true"`
 	Concurrency      uint                     `yaml:"concurrency"`
 	WrittenThreshold uint64                   `yaml:"written_threshold"`
+	// SkipUnchanged skips snapshotting of datasets without changes since the
+	// latest snapshot with Prefix.
+	SkipUnchanged bool `yaml:"skip_unchanged"`
+
+	// NameTemplate is a text/template of snapshot names, like
+	// "{{.Prefix}}{{.FS | base}}_{{.Time.Strftime "%Y-%m-%d"}}". Empty means
+	// Prefix and timestamp.
+	NameTemplate string `yaml:"name_template"`
+
+	// Properties are user properties, set on snapshots at creation, like
+	// "zrepl:job": "{{.JobName}}". Values are text/templates with the same
+	// data as NameTemplate, plus JobName and Snapshot.
+	Properties map[string]string `yaml:"properties" validate:"dive,keys,required,endkeys"`
+
+	// Epoch embeds epoch of snapshot names, kept in state_file, after Prefix.
+	// The epoch is bumped on clock anomalies or restored state_file.
+	Epoch bool `yaml:"epoch"`
+
+	// Jitter delays every snapshotting by a random duration up to it.
+	Jitter time.Duration `yaml:"jitter" validate:"gte=0s"`
+	// Stagger spreads starts of snapshots of filesystems evenly over it.
+	Stagger time.Duration `yaml:"stagger" validate:"gte=0s"`
+	// Atomic creates snapshots of all filesystems of every pool in one
+	// transaction group by zfs channel program.
+	Atomic bool `yaml:"atomic" validate:"excluded_with=Stagger"`
+
+	// Overrides change prefix, cron or hooks for matching filesystems. Every
+	// filesystem belongs to the first matching override.
+	Overrides []SnapshottingOverride `yaml:"overrides" validate:"dive"`
+}
+
+// SnapshottingOverride overrides prefix, cron or hooks of periodic
+// snapshotting for filesystems matched by Filesystems or Datasets. Empty
+// Prefix and Cron and nil Hooks are inherited from snapshotting.
+type SnapshottingOverride struct {
+	Filesystems FilesystemsFilter `yaml:"filesystems" validate:"required_without=Datasets"`
+	Datasets    []DatasetFilter   `yaml:"datasets,omitempty" validate:"dive"`
+	Prefix      string            `yaml:"prefix"`
+	Cron        string            `yaml:"cron"`
+	Hooks       []HookCommand     `yaml:"hooks" validate:"dive"`
 }
 
 func (self *SnapshottingPeriodic) CronSpec() string {
@@ -379,11 +762,27 @@ type PruningSenderReceiver struct {
 	Concurrency  uint          `yaml:"concurrency"`
 	KeepSender   []PruningEnum `yaml:"keep_sender"`
 	KeepReceiver []PruningEnum `yaml:"keep_receiver"`
+	// ReceiverDestroyDelay delays destroys of receiver snapshots. Pruning marks
+	// them and destroys after the delay, unless they were restored by zrepl
+	// undo-prune.
+	ReceiverDestroyDelay time.Duration `yaml:"receiver_destroy_delay" validate:"gte=0s"`
+	// BookmarksMaxAge enables destroying of replication cursor bookmarks on
+	// sender, which are older than the age. The last replication cursor of
+	// every job is always kept.
+	BookmarksMaxAge time.Duration `yaml:"bookmarks_max_age" validate:"gte=0s"`
+	// ReleaseHolds is a regular expression of hold tags, which pruning releases
+	// from snapshots it destroys. Holds of zrepl itself are never released.
+	ReleaseHolds string `yaml:"release_holds"`
+	// KeepMinAge protects snapshots younger than the age from destroying,
+	// whatever keep rules decide.
+	KeepMinAge time.Duration `yaml:"keep_min_age" validate:"gte=0s"`
 }
 
 type PruningLocal struct {
-	Concurrency uint          `yaml:"concurrency"`
-	Keep        []PruningEnum `yaml:"keep"`
+	Concurrency  uint          `yaml:"concurrency"`
+	Keep         []PruningEnum `yaml:"keep"`
+	ReleaseHolds string        `yaml:"release_holds"`
+	KeepMinAge   time.Duration `yaml:"keep_min_age" validate:"gte=0s"`
 }
 
 type LoggingOutletEnumList []LoggingOutletEnum
@@ -409,10 +808,128 @@ var _ defaults.Setter = &LoggingOutletEnumList{}
 type Global struct {
 	RpcTimeout time.Duration `yaml:"rpc_timeout" default:"1m" validate:"gt=0s"`
 	ZfsBin     string        `yaml:"zfs_bin" default:"zfs" validate:"required"`
+	ZpoolBin   string        `yaml:"zpool_bin" default:"zpool" validate:"required"`
+	ZfsDestroy ZfsDestroy    `yaml:"zfs_destroy"`
+	// ZfsBackend runs snapshot, destroy, hold, release and bookmark operations
+	// by zfs commands ("exec") or libzfs_core ("lzc"), if compiled with build
+	// tag "lzc".
+	ZfsBackend string `yaml:"zfs_backend" default:"exec" validate:"required,oneof=exec lzc"`
+	// ZfsPropertiesCache caches results of zfs get during every replication
+	// run for this time. Zero disables the cache.
+	ZfsPropertiesCache time.Duration `yaml:"zfs_properties_cache" validate:"min=0s"`
 
 	Logging    LoggingOutletEnumList  `yaml:"logging" validate:"min=1"`
 	Monitoring []PrometheusMonitoring `yaml:"monitoring" validate:"dive"`
 	Control    GlobalControl          `yaml:"control"`
+	Resources  GlobalResources        `yaml:"resources"`
+	HTTP       *GlobalHTTP            `yaml:"http"`
+	RPC        GlobalRPC              `yaml:"rpc"`
+
+	ConnectHosts  []ConnectHost      `yaml:"connect_hosts" validate:"dive"`
+	Parallel      GlobalParallel     `yaml:"parallel"`
+	Notifications []NotificationEnum `yaml:"notifications" validate:"dive"`
+
+	// StateFile keeps daemon state, which survives its restarts, like disabled
+	// jobs.
+	StateFile string `yaml:"state_file" default:"/var/db/zrepl/state.json" validate:"required,filepath"`
+
+	// AuditDir keeps hash-chained audit logs of mutating endpoint operations,
+	// one log per job. Empty disables audit logs.
+	AuditDir string `yaml:"audit_dir" validate:"omitempty,dirpath"`
+
+	// LifecycleDir keeps journals of actions on snapshots, like created,
+	// replicated and destroyed, one journal per dataset. Empty disables
+	// journals.
+	LifecycleDir string `yaml:"lifecycle_dir" validate:"omitempty,dirpath"`
+
+	AbstractionsGC AbstractionsGC `yaml:"abstractions_gc"`
+}
+
+// AbstractionsGC periodically releases step holds and destroys tentative
+// replication cursor bookmarks, which are stale or belong to jobs, which don't
+// exist anymore. Zero Interval disables it.
+type AbstractionsGC struct {
+	Interval    time.Duration `yaml:"interval" validate:"min=0s"`
+	Concurrency int           `yaml:"concurrency" default:"1" validate:"min=1"`
+}
+
+// ZfsDestroy configures destroying of snapshots by pruning. ChannelProgram
+// destroys them by zfs channel programs in chunks of Chunk snapshots, instead
+// of `zfs destroy fs@a,b,c`.
+type ZfsDestroy struct {
+	ChannelProgram bool `yaml:"channel_program"`
+	Chunk          int  `yaml:"chunk" default:"1000" validate:"min=1"`
+}
+
+// ConnectHost coordinates all jobs, which connect to the same Server. They
+// share Bandwidth (sustained bytes per second) of send and receive streams,
+// which may transfer up to Burst bytes at once, no more than MaxRunning of them
+// replicate at the same time, and their replications start not more often than
+// once in Stagger. Zero values mean no limit, except zero Burst, which means
+// Bandwidth, but no more than 1MiB. BandwidthSchedule changes Bandwidth by time
+// of day, and Bandwidth applies outside of its windows.
+type ConnectHost struct {
+	Server            string            `yaml:"server" validate:"required,url"`
+	Bandwidth         Bytes             `yaml:"bandwidth"`
+	BandwidthSchedule []BandwidthWindow `yaml:"bandwidth_schedule" validate:"dive"`
+	Burst             Bytes             `yaml:"burst"`
+	MaxRunning        int               `yaml:"max_running" validate:"min=0"`
+	Stagger           time.Duration     `yaml:"stagger" validate:"min=0s"`
+}
+
+// BandwidthWindow limits bandwidth by Bandwidth from From to To, local time.
+// If To is less or equal to From, the window ends on the next day. Zero
+// Bandwidth means no limit. The first matching window applies.
+type BandwidthWindow struct {
+	From      string   `yaml:"from" validate:"required"`
+	To        string   `yaml:"to" validate:"required"`
+	Days      []string `yaml:"days" validate:"dive,oneof=mon tue wed thu fri sat sun"`
+	Bandwidth Bytes    `yaml:"bandwidth"`
+}
+
+// GlobalParallel limits number of zfs send and zfs recv processes, which run
+// at the same time, across all jobs. Zero values mean no limit.
+type GlobalParallel struct {
+	Send int `yaml:"send" validate:"min=0"`
+	Recv int `yaml:"recv" validate:"min=0"`
+}
+
+// GlobalHTTP configures a listener, which serves read-only web dashboard.
+// TLSKeyCmd is a shell command, which outputs PEM content of TLSKey.
+type GlobalHTTP struct {
+	Listen    string `yaml:"listen" validate:"required,hostname_port"`
+	TLSCert   string `yaml:"tls_cert" validate:"required_with=TLSKey,omitempty,filepath"`
+	TLSKey    string `yaml:"tls_key" validate:"required_with=TLSCert,omitempty,filepath"`
+	TLSKeyCmd string `yaml:"tls_key_cmd"`
+}
+
+// GlobalRPC tunes replication streams between daemons. ChunkSize is size of
+// reads of zfs send streams and of network buffers. BufferPoolMax limits memory
+// of buffers, which every zfs send stream is read ahead into. Streams fail, if
+// a write of them doesn't complete in WriteTimeout or a read in ReadTimeout.
+// Zero values keep defaults.
+type GlobalRPC struct {
+	ChunkSize     Bytes         `yaml:"chunk_size"`
+	BufferPoolMax Bytes         `yaml:"buffer_pool_max"`
+	WriteTimeout  time.Duration `yaml:"write_timeout" validate:"min=0s"`
+	ReadTimeout   time.Duration `yaml:"read_timeout" validate:"min=0s"`
+}
+
+// GlobalResources limits CPU and memory usage of the daemon. Zero values keep
+// defaults of Go runtime.
+type GlobalResources struct {
+	GoMaxProcs  int         `yaml:"gomaxprocs" validate:"min=0"`
+	GoGC        *int        `yaml:"gogc" validate:"omitempty,min=-1"`
+	GoMemLimit  Bytes       `yaml:"gomemlimit"`
+	RSSWatchdog RSSWatchdog `yaml:"rss_watchdog"`
+}
+
+// RSSWatchdog periodically checks memory usage of the daemon and sheds
+// concurrency, when it reaches ShedRatio of Limit.
+type RSSWatchdog struct {
+	Limit     Bytes         `yaml:"limit"`
+	Interval  time.Duration `yaml:"interval" default:"10s" validate:"gt=0s"`
+	ShedRatio float64       `yaml:"shed_ratio" default:"0.9" validate:"gt=0,lte=1"`
 }
 
 type Connect struct {
@@ -420,6 +937,23 @@ type Connect struct {
 	Server         string `yaml:"server" validate:"required_if=Type http,omitempty,url"`
 	ListenerName   string `yaml:"listener_name" validate:"required"`
 	ClientIdentity string `yaml:"client_identity" validate:"required"`
+	// StreamJournal keeps so many last bytes of every stream sent to the
+	// server, for continuing it after a connection blip. Zero disables it.
+	StreamJournal Bytes `yaml:"stream_journal"`
+
+	// Socket marks connections to the server.
+	Socket SocketOptions `yaml:"socket"`
+}
+
+// SocketOptions marks replication sockets, so network QoS policies and policy
+// routing can match them.
+type SocketOptions struct {
+	// DSCP is a name of DSCP class, like "af21" or "ef", or its number.
+	DSCP string `yaml:"dscp" validate:"excluded_with=TOS"`
+	// TOS is a raw value of TOS byte, or traffic class for IPv6.
+	TOS uint8 `yaml:"tos"`
+	// Mark is SO_MARK of sockets, Linux only.
+	Mark uint32 `yaml:"mark"`
 }
 
 type PruningEnum struct {
@@ -443,6 +977,12 @@ type PruneKeepRegex struct { // FIXME rename to KeepRegex
 	Negate bool   `yaml:"negate"`
 }
 
+type PruneKeepProperty struct {
+	Type     string `yaml:"type" validate:"required"`
+	Property string `yaml:"property" validate:"required"`
+	Value    string `yaml:"value" default:"true"`
+}
+
 type LoggingOutletEnum struct {
 	Ret any `validate:"required"`
 }
@@ -459,6 +999,16 @@ type FileLoggingOutlet struct {
 	LoggingOutletCommon `yaml:",inline"`
 
 	FileName string `yaml:"filename"`
+
+	// MaxSize rotates the file, when it grows bigger. Zero disables rotation.
+	MaxSize Bytes `yaml:"max_size"`
+	// MaxAge removes rotated files older than it. Zero keeps them.
+	MaxAge time.Duration `yaml:"max_age" validate:"gte=0s"`
+	// MaxBackups removes rotated files, except MaxBackups most recent. Zero
+	// keeps them.
+	MaxBackups int `yaml:"max_backups" validate:"min=0"`
+	// Compress rotated files by gzip.
+	Compress bool `yaml:"compress"`
 }
 
 type SyslogLoggingOutlet struct {
@@ -466,6 +1016,12 @@ type SyslogLoggingOutlet struct {
 
 	Facility      SyslogFacility `yaml:"facility" default:"local0" validate:"required"`
 	RetryInterval time.Duration  `yaml:"retry_interval" default:"10s" validate:"gt=0s"`
+
+	// Address of a remote syslog server, which receives RFC 5424 messages. Empty
+	// logs to the local syslog daemon.
+	Address string               `yaml:"address" validate:"omitempty,hostname_port"`
+	Net     string               `yaml:"net" default:"udp" validate:"oneof=udp tcp"`
+	TLS     *TCPLoggingOutletTLS `yaml:"tls"`
 }
 
 type TCPLoggingOutlet struct {
@@ -478,9 +1034,84 @@ type TCPLoggingOutlet struct {
 }
 
 type TCPLoggingOutletTLS struct {
-	CA   string `yaml:"ca" validate:"required"`
-	Cert string `yaml:"cert" validate:"required"`
-	Key  string `yaml:"key" validate:"required"`
+	CA     string `yaml:"ca" validate:"required"`
+	Cert   string `yaml:"cert" validate:"required"`
+	Key    string `yaml:"key" validate:"required_without=KeyCmd"`
+	KeyCmd string `yaml:"key_cmd"`
+}
+
+type NotificationEnum struct {
+	Ret any `validate:"required"`
+}
+
+// NotificationCommon configures, when summaries of job runs are sent: always
+// for failed runs, and for successful runs, if OnSuccess. If StaleAfter is set,
+// it also notifies about replication jobs without successful runs for longer.
+// If Digest is set, runs are batched per job and sent by this cron spec.
+type NotificationCommon struct {
+	Type       string        `yaml:"type" validate:"required"`
+	OnSuccess  bool          `yaml:"on_success"`
+	StaleAfter time.Duration `yaml:"stale_after" validate:"gte=0s"`
+	Digest     string        `yaml:"digest"`
+}
+
+// WebhookNotification posts summaries of job runs to URL. The body is the
+// summary as JSON or Template, executed with the summary.
+type WebhookNotification struct {
+	NotificationCommon `yaml:",inline"`
+
+	URL      string            `yaml:"url" validate:"required,url"`
+	Headers  map[string]string `yaml:"headers" validate:"dive,keys,required,endkeys"`
+	Template string            `yaml:"template"`
+	Timeout  time.Duration     `yaml:"timeout" default:"10s" validate:"gt=0s"`
+}
+
+// EmailNotification mails summaries of job runs through SMTP server Host.
+// Subject and Template are executed with the summary. PasswordCmd is a shell
+// command, which outputs Password.
+type EmailNotification struct {
+	NotificationCommon `yaml:",inline"`
+
+	Host        string        `yaml:"host" validate:"required,hostname_port"`
+	TLS         bool          `yaml:"tls"`
+	Username    string        `yaml:"username"`
+	Password    string        `yaml:"password" validate:"required_with=Username"`
+	PasswordCmd string        `yaml:"password_cmd"`
+	From        string        `yaml:"from" validate:"required,email"`
+	To          []string      `yaml:"to" validate:"min=1,dive,email"`
+	Subject     string        `yaml:"subject"`
+	Template    string        `yaml:"template"`
+	Timeout     time.Duration `yaml:"timeout" default:"30s" validate:"gt=0s"`
+}
+
+// NtfyNotification publishes summaries of job runs to ntfy topic URL.
+// TokenCmd is a shell command, which outputs Token.
+type NtfyNotification struct {
+	NotificationCommon `yaml:",inline"`
+
+	URL      string        `yaml:"url" validate:"required,url"`
+	Token    string        `yaml:"token"`
+	TokenCmd string        `yaml:"token_cmd"`
+	Priority int           `yaml:"priority" validate:"min=0,max=5"`
+	Tags     []string      `yaml:"tags" validate:"dive,required"`
+	Title    string        `yaml:"title"`
+	Template string        `yaml:"template"`
+	Timeout  time.Duration `yaml:"timeout" default:"10s" validate:"gt=0s"`
+}
+
+// GotifyNotification sends summaries of job runs to Gotify server URL as
+// messages of an application with Token. TokenCmd is a shell command, which
+// outputs Token.
+type GotifyNotification struct {
+	NotificationCommon `yaml:",inline"`
+
+	URL      string        `yaml:"url" validate:"required,url"`
+	Token    string        `yaml:"token" validate:"required_without=TokenCmd"`
+	TokenCmd string        `yaml:"token_cmd"`
+	Priority int           `yaml:"priority" default:"5" validate:"min=0"`
+	Title    string        `yaml:"title"`
+	Template string        `yaml:"template"`
+	Timeout  time.Duration `yaml:"timeout" default:"10s" validate:"gt=0s"`
 }
 
 type PrometheusMonitoring struct {
@@ -491,51 +1122,33 @@ type PrometheusMonitoring struct {
 
 type SyslogFacility syslog.Priority
 
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
 func (f *SyslogFacility) UnmarshalJSON(b []byte) error {
 	s := string(b)
-	var level syslog.Priority
-	switch s {
-	case "kern":
-		level = syslog.LOG_KERN
-	case "user":
-		level = syslog.LOG_USER
-	case "mail":
-		level = syslog.LOG_MAIL
-	case "daemon":
-		level = syslog.LOG_DAEMON
-	case "auth":
-		level = syslog.LOG_AUTH
-	case "syslog":
-		level = syslog.LOG_SYSLOG
-	case "lpr":
-		level = syslog.LOG_LPR
-	case "news":
-		level = syslog.LOG_NEWS
-	case "uucp":
-		level = syslog.LOG_UUCP
-	case "cron":
-		level = syslog.LOG_CRON
-	case "authpriv":
-		level = syslog.LOG_AUTHPRIV
-	case "ftp":
-		level = syslog.LOG_FTP
-	case "local0":
-		level = syslog.LOG_LOCAL0
-	case "local1":
-		level = syslog.LOG_LOCAL1
-	case "local2":
-		level = syslog.LOG_LOCAL2
-	case "local3":
-		level = syslog.LOG_LOCAL3
-	case "local4":
-		level = syslog.LOG_LOCAL4
-	case "local5":
-		level = syslog.LOG_LOCAL5
-	case "local6":
-		level = syslog.LOG_LOCAL6
-	case "local7":
-		level = syslog.LOG_LOCAL7
-	default:
+	level, ok := syslogFacilities[s]
+	if !ok {
 		return fmt.Errorf("invalid syslog level: %q", s)
 	}
 	*f = SyslogFacility(level)
@@ -553,14 +1166,48 @@ type GlobalControl struct {
 	SockMode uint32 `yaml:"sockmode" validate:"lte=0o777"`
 }
 
+const (
+	HookTypeCommand         = "command"
+	HookTypeFsfreeze        = "fsfreeze"
+	HookTypeXfsFreeze       = "xfs_freeze"
+	HookTypeMySQLLockTables = "mysql-lock-tables"
+	HookTypePostgres        = "postgres-checkpoint"
+	HookTypeWebhook         = "webhook"
+	HookTypeQemuFreeze      = "qemu-fsfreeze"
+)
+
 type HookCommand struct {
-	Path        string            `yaml:"path" validate:"required"`
+	Type        string            `yaml:"type" default:"command" validate:"oneof=command fsfreeze xfs_freeze mysql-lock-tables postgres-checkpoint webhook qemu-fsfreeze"`
+	Path        string            `yaml:"path" validate:"required_if=Type command"`
+	Mountpoint  string            `yaml:"mountpoint" validate:"required_if=Type fsfreeze,required_if=Type xfs_freeze,excluded_if=Type command,excluded_if=Type mysql-lock-tables,excluded_if=Type postgres-checkpoint,excluded_if=Type webhook,excluded_if=Type qemu-fsfreeze"`
 	Args        []string          `yaml:"args" validate:"dive,required"`
 	Env         map[string]string `yaml:"env" validate:"dive,keys,required,endkeys,required"`
 	Timeout     time.Duration     `yaml:"timeout" default:"1m" validate:"min=0s"`
-	Filesystems FilesystemsFilter `yaml:"filesystems"`
-	Datasets    []DatasetFilter   `yaml:"datasets" validate:"dive"`
+	Filesystems FilesystemsFilter `yaml:"filesystems,omitempty"`
+	Datasets    []DatasetFilter   `yaml:"datasets,omitempty" validate:"dive"`
 	ErrIsFatal  bool              `yaml:"err_is_fatal"`
+
+	// DSN of mysql-lock-tables hook, like "user:password@tcp(host:3306)/db" or
+	// "user@unix(/tmp/mysql.sock)/", or connection string of
+	// postgres-checkpoint hook, like "postgresql://user@host/db".
+	DSN string `yaml:"dsn" validate:"required_if=Type mysql-lock-tables,required_if=Type postgres-checkpoint,excluded_if=Type command,excluded_if=Type fsfreeze,excluded_if=Type xfs_freeze,excluded_if=Type webhook,excluded_if=Type qemu-fsfreeze"`
+	// BackupMode of postgres-checkpoint hook runs pg_backup_start before
+	// snapshotting and pg_backup_stop after it.
+	BackupMode bool `yaml:"backup_mode" validate:"excluded_unless=Type postgres-checkpoint"`
+
+	// URL of webhook hook, which gets a request on pre and post edges. Method
+	// is POST by default. ExpectStatus lists expected status codes of
+	// responses, any 2xx by default.
+	URL          string            `yaml:"url" validate:"required_if=Type webhook,excluded_unless=Type webhook"`
+	Method       string            `yaml:"method" validate:"excluded_unless=Type webhook"`
+	Headers      map[string]string `yaml:"headers" validate:"excluded_unless=Type webhook,dive,keys,required,endkeys"`
+	ExpectStatus []int             `yaml:"expect_status" validate:"excluded_unless=Type webhook,dive,min=100,max=599"`
+
+	// Domain of qemu-fsfreeze hook is a libvirt domain, which guest agent is
+	// reached by virsh, or Socket is a unix socket of qemu-guest-agent of the
+	// VM.
+	Domain string `yaml:"domain" validate:"excluded_unless=Type qemu-fsfreeze"`
+	Socket string `yaml:"socket" validate:"excluded_unless=Type qemu-fsfreeze"`
 }
 
 func (self *HookCommand) UnmarshalYAML(value *yaml.Node) error {
@@ -612,51 +1259,95 @@ func enumUnmarshal(value *yaml.Node, types map[string]any) (any, error) {
 var _ yaml.Unmarshaler = (*JobEnum)(nil)
 
 func (t *JobEnum) UnmarshalYAML(value *yaml.Node) (err error) {
-	t.Ret, err = enumUnmarshal(value, map[string]any{
+	t.Ret, err = enumUnmarshal(value, t.YAMLVariants())
+	return err
+}
+
+func (t JobEnum) MarshalYAML() (any, error) { return t.Ret, nil }
+
+func (t JobEnum) YAMLVariants() map[string]any {
+	return map[string]any{
 		"snap":   new(SnapJob),
+		"prune":  new(PruneJob),
 		"push":   new(PushJob),
 		"sink":   new(SinkJob),
 		"pull":   new(PullJob),
 		"source": new(SourceJob),
-	})
-	return err
+	}
 }
 
 var _ yaml.Unmarshaler = (*PruningEnum)(nil)
 
 func (t *PruningEnum) UnmarshalYAML(value *yaml.Node) (err error) {
-	t.Ret, err = enumUnmarshal(value, map[string]any{
+	t.Ret, err = enumUnmarshal(value, t.YAMLVariants())
+	return err
+}
+
+func (t PruningEnum) MarshalYAML() (any, error) { return t.Ret, nil }
+
+func (t PruningEnum) YAMLVariants() map[string]any {
+	return map[string]any{
 		"not_replicated": new(PruneKeepNotReplicated),
 		"last_n":         new(PruneKeepLastN),
 		"grid":           new(PruneGrid),
 		"regex":          new(PruneKeepRegex),
-	})
-	return err
+		"property":       new(PruneKeepProperty),
+	}
 }
 
 var _ yaml.Unmarshaler = (*SnapshottingEnum)(nil)
 
 func (t *SnapshottingEnum) UnmarshalYAML(value *yaml.Node) (err error) {
-	t.Ret, err = enumUnmarshal(value, map[string]any{
+	t.Ret, err = enumUnmarshal(value, t.YAMLVariants())
+	return err
+}
+
+func (t SnapshottingEnum) MarshalYAML() (any, error) { return t.Ret, nil }
+
+func (t SnapshottingEnum) YAMLVariants() map[string]any {
+	return map[string]any{
 		"periodic": new(SnapshottingPeriodic),
 		"manual":   new(SnapshottingManual),
 		"cron":     new(SnapshottingPeriodic),
-	})
-	return err
+	}
 }
 
 var _ yaml.Unmarshaler = (*LoggingOutletEnum)(nil)
 
 func (t *LoggingOutletEnum) UnmarshalYAML(value *yaml.Node) (err error) {
-	t.Ret, err = enumUnmarshal(value, map[string]any{
+	t.Ret, err = enumUnmarshal(value, t.YAMLVariants())
+	return err
+}
+
+func (t LoggingOutletEnum) MarshalYAML() (any, error) { return t.Ret, nil }
+
+func (t LoggingOutletEnum) YAMLVariants() map[string]any {
+	return map[string]any{
 		"file":   new(FileLoggingOutlet),
 		"stdout": new(FileLoggingOutlet),
 		"syslog": new(SyslogLoggingOutlet),
 		"tcp":    new(TCPLoggingOutlet),
-	})
+	}
+}
+
+var _ yaml.Unmarshaler = (*NotificationEnum)(nil)
+
+func (t *NotificationEnum) UnmarshalYAML(value *yaml.Node) (err error) {
+	t.Ret, err = enumUnmarshal(value, t.YAMLVariants())
 	return err
 }
 
+func (t NotificationEnum) MarshalYAML() (any, error) { return t.Ret, nil }
+
+func (t NotificationEnum) YAMLVariants() map[string]any {
+	return map[string]any{
+		"email":   new(EmailNotification),
+		"gotify":  new(GotifyNotification),
+		"ntfy":    new(NtfyNotification),
+		"webhook": new(WebhookNotification),
+	}
+}
+
 var _ yaml.Unmarshaler = (*SyslogFacility)(nil)
 
 func (t *SyslogFacility) UnmarshalYAML(value *yaml.Node) (err error) {
@@ -666,3 +1357,21 @@ func (t *SyslogFacility) UnmarshalYAML(value *yaml.Node) (err error) {
 	}
 	return t.UnmarshalJSON([]byte(s))
 }
+
+func (t SyslogFacility) YAMLSchema() *jsonschema.Schema {
+	names := slices.Sorted(maps.Keys(syslogFacilities))
+	enum := make([]any, len(names))
+	for i, name := range names {
+		enum[i] = name
+	}
+	return &jsonschema.Schema{Type: "string", Enum: enum}
+}
+
+func (t SyslogFacility) MarshalYAML() (any, error) {
+	for name, level := range syslogFacilities {
+		if SyslogFacility(level) == t {
+			return name, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid syslog facility: %d", t)
+}
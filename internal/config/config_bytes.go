@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+)
+
+// Bytes is a size in bytes, which can be defined like "512", "64K", "100MiB"
+// or "1.5G". All units are powers of 1024.
+type Bytes uint64
+
+func (b Bytes) Uint64() uint64 { return uint64(b) }
+
+var _ yaml.Unmarshaler = (*Bytes)(nil)
+
+func (b *Bytes) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	n, err := parseBytes(s)
+	if err != nil {
+		err := fmt.Errorf("cannot parse value %q: %w", s, err)
+		return &yaml.LoadErrors{
+			Errors: []*yaml.LoadError{
+				yaml.NewLoadError(yaml.ConstructorStage, err.Error(),
+					yaml.Mark{Line: value.Line, Column: value.Column}, err),
+			},
+		}
+	}
+	*b = Bytes(n)
+	return nil
+}
+
+func (b Bytes) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        []string{"integer", "string"},
+		Description: "size in bytes, like 512, 64K or 100MiB",
+	}
+}
+
+// ParseBytes parses sizes like in config, e.g. "5MiB".
+func ParseBytes(s string) (uint64, error) { return parseBytes(s) }
+
+var bytesStringRegex = regexp.MustCompile(
+	`^\s*(\d+(?:\.\d+)?)\s*([kKmMgGtT]?)(?:i?[bB])?\s*$`)
+
+func parseBytes(s string) (uint64, error) {
+	comps := bytesStringRegex.FindStringSubmatch(s)
+	if comps == nil {
+		return 0, fmt.Errorf("must match %s", bytesStringRegex)
+	}
+
+	n, err := strconv.ParseFloat(comps[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %q to float: %w", comps[1], err)
+	}
+
+	var shift int
+	switch strings.ToUpper(comps[2]) {
+	case "K":
+		shift = 10
+	case "M":
+		shift = 20
+	case "G":
+		shift = 30
+	case "T":
+		shift = 40
+	}
+
+	n *= float64(uint64(1) << shift)
+	if n >= math.MaxUint64 {
+		return 0, fmt.Errorf("value %q overflows", s)
+	}
+	return uint64(n), nil
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
 )
 
 type Duration struct{ d time.Duration }
@@ -36,10 +38,24 @@ func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+func (d Duration) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: "duration, like 30s, 10m or 2w",
+	}
+}
+
 type PositiveDuration struct{ d Duration }
 
 func (d PositiveDuration) Duration() time.Duration { return d.d.Duration() }
 
+func (d PositiveDuration) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: "positive duration, like 30s, 10m or 2w",
+	}
+}
+
 var _ yaml.Unmarshaler = (*PositiveDuration)(nil)
 
 func (d *PositiveDuration) UnmarshalYAML(value *yaml.Node) error {
@@ -64,8 +80,36 @@ func parsePositiveDuration(e string) (time.Duration, error) {
 	return d, err
 }
 
+// formatDuration formats d like in config, by the largest unit, which divides
+// it.
+func formatDuration(d time.Duration) string {
+	units := [...]struct {
+		length time.Duration
+		suffix string
+	}{
+		{7 * 24 * time.Hour, "w"},
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	if d == 0 {
+		return "0"
+	}
+	for _, u := range units {
+		if d%u.length == 0 {
+			return strconv.FormatInt(int64(d/u.length), 10) + u.suffix
+		}
+	}
+	return d.String()
+}
+
 var durationStringRegex *regexp.Regexp = regexp.MustCompile(`^\s*([\+-]?\d+)\s*(|s|m|h|d|w)\s*$`)
 
+// ParseDuration parses durations like in config, e.g. "30d" or "2w".
+func ParseDuration(e string) (time.Duration, error) { return parseDuration(e) }
+
 func parseDuration(e string) (d time.Duration, err error) {
 	comps := durationStringRegex.FindStringSubmatch(e)
 	if comps == nil {
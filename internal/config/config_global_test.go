@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/syslog"
 	"testing"
+	"time"
 
 	"github.com/creasty/defaults"
 	"github.com/stretchr/testify/assert"
@@ -62,6 +63,56 @@ global:
 	assert.NotNil(t, (conf.Global.Logging)[3].Ret.(*TCPLoggingOutlet).TLS)
 }
 
+func TestFileLoggingOutlet_rotation(t *testing.T) {
+	conf := testValidGlobalSection(t, `
+global:
+  logging:
+  - type: "file"
+    format: "text"
+    level: "info"
+    filename: "/var/log/zrepl.log"
+    max_size: "100MiB"
+    max_age: "720h"
+    max_backups: 5
+    compress: true
+`)
+	require.Len(t, conf.Global.Logging, 1)
+	o := conf.Global.Logging[0].Ret.(*FileLoggingOutlet)
+	assert.Equal(t, Bytes(100<<20), o.MaxSize)
+	assert.Equal(t, 720*time.Hour, o.MaxAge)
+	assert.Equal(t, 5, o.MaxBackups)
+	assert.True(t, o.Compress)
+}
+
+func TestSyslogLoggingOutlet_remote(t *testing.T) {
+	conf := testValidGlobalSection(t, `
+global:
+  logging:
+  - type: "syslog"
+    format: "text"
+    level: "info"
+  - type: "syslog"
+    format: "json"
+    level: "info"
+    address: "logs.example.com:6514"
+    net: "tcp"
+    tls:
+      ca: "/etc/zrepl/ca.crt"
+      cert: "/etc/zrepl/host.crt"
+      key: "/etc/zrepl/host.key"
+`)
+	require.Len(t, conf.Global.Logging, 2)
+	local := conf.Global.Logging[0].Ret.(*SyslogLoggingOutlet)
+	assert.Empty(t, local.Address)
+	assert.Equal(t, "udp", local.Net)
+
+	remote := conf.Global.Logging[1].Ret.(*SyslogLoggingOutlet)
+	assert.Equal(t, "logs.example.com:6514", remote.Address)
+	assert.Equal(t, "tcp", remote.Net)
+	require.NotNil(t, remote.TLS)
+	assert.Equal(t, "/etc/zrepl/host.crt", remote.TLS.Cert)
+}
+
 func TestDefaultLoggingOutlet(t *testing.T) {
 	conf := testValidGlobalSection(t, "")
 	assert.Len(t, conf.Global.Logging, 1)
@@ -153,3 +204,182 @@ func TestLoggingOutletEnumList_SetDefaults(t *testing.T) {
 		assert.Equal(t, "warn", o.Level)
 	})
 }
+
+func TestConnectHosts(t *testing.T) {
+	conf := testValidGlobalSection(t, `
+global:
+  connect_hosts:
+    - server: "https://backup.example.com:8888"
+      bandwidth: "10MiB"
+      burst: "256KiB"
+      max_running: 1
+      stagger: "5m"
+`)
+	require.Len(t, conf.Global.ConnectHosts, 1)
+	assert.Equal(t, ConnectHost{
+		Server:     "https://backup.example.com:8888",
+		Bandwidth:  10 << 20,
+		Burst:      256 << 10,
+		MaxRunning: 1,
+		Stagger:    5 * time.Minute,
+	}, conf.Global.ConnectHosts[0])
+
+	conf = testValidGlobalSection(t, `
+global:
+  connect_hosts:
+    - server: "https://backup.example.com:8888"
+      bandwidth: "100MiB"
+      bandwidth_schedule:
+        - from: "08:00"
+          to: "18:00"
+          days: ["mon", "fri"]
+          bandwidth: "10MiB"
+        - from: "22:00"
+          to: "06:00"
+`)
+	require.Len(t, conf.Global.ConnectHosts, 1)
+	assert.Equal(t, []BandwidthWindow{
+		{
+			From: "08:00", To: "18:00", Days: []string{"mon", "fri"},
+			Bandwidth: 10 << 20,
+		},
+		{From: "22:00", To: "06:00"},
+	}, conf.Global.ConnectHosts[0].BandwidthSchedule)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  connect_hosts:
+    - bandwidth: "10MiB"
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalParallel(t *testing.T) {
+	conf := testValidGlobalSection(t, `
+global:
+  parallel:
+    send: 2
+    recv: 1
+`)
+	assert.Equal(t, GlobalParallel{Send: 2, Recv: 1}, conf.Global.Parallel)
+
+	conf = testValidGlobalSection(t, "")
+	assert.Equal(t, GlobalParallel{}, conf.Global.Parallel)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  parallel:
+    send: -1
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalAbstractionsGC(t *testing.T) {
+	conf := testValidGlobalSection(t, "")
+	assert.Equal(t, AbstractionsGC{Concurrency: 1}, conf.Global.AbstractionsGC)
+
+	conf = testValidGlobalSection(t, `
+global:
+  abstractions_gc:
+    interval: "1h"
+    concurrency: 4
+`)
+	assert.Equal(t, AbstractionsGC{Interval: time.Hour, Concurrency: 4},
+		conf.Global.AbstractionsGC)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  abstractions_gc:
+    interval: "-1h"
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalZfsDestroy(t *testing.T) {
+	conf := testValidGlobalSection(t, "")
+	assert.Equal(t, ZfsDestroy{Chunk: 1000}, conf.Global.ZfsDestroy)
+
+	conf = testValidGlobalSection(t, `
+global:
+  zfs_destroy:
+    channel_program: true
+    chunk: 500
+`)
+	assert.Equal(t, ZfsDestroy{ChannelProgram: true, Chunk: 500},
+		conf.Global.ZfsDestroy)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  zfs_destroy:
+    chunk: 0
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalZfsBackend(t *testing.T) {
+	conf := testValidGlobalSection(t, "")
+	assert.Equal(t, "exec", conf.Global.ZfsBackend)
+
+	conf = testValidGlobalSection(t, `
+global:
+  zfs_backend: lzc
+`)
+	assert.Equal(t, "lzc", conf.Global.ZfsBackend)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  zfs_backend: libzfs
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalZfsPropertiesCache(t *testing.T) {
+	conf := testValidGlobalSection(t, "")
+	assert.Zero(t, conf.Global.ZfsPropertiesCache)
+
+	conf = testValidGlobalSection(t, `
+global:
+  zfs_properties_cache: 30s
+`)
+	assert.Equal(t, 30*time.Second, conf.Global.ZfsPropertiesCache)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  zfs_properties_cache: -1s
+jobs: []
+`))
+	require.Error(t, err)
+}
+
+func TestGlobalRPC(t *testing.T) {
+	conf := testValidGlobalSection(t, "")
+	assert.Equal(t, GlobalRPC{}, conf.Global.RPC)
+
+	conf = testValidGlobalSection(t, `
+global:
+  rpc:
+    chunk_size: 4MiB
+    buffer_pool_max: 64MiB
+    write_timeout: 1m
+    read_timeout: 2m
+`)
+	assert.Equal(t, GlobalRPC{
+		ChunkSize:     4 << 20,
+		BufferPoolMax: 64 << 20,
+		WriteTimeout:  time.Minute,
+		ReadTimeout:   2 * time.Minute,
+	}, conf.Global.RPC)
+
+	_, err := ParseConfigBytes("", []byte(`
+global:
+  rpc:
+    read_timeout: -1s
+jobs: []
+`))
+	require.Error(t, err)
+}
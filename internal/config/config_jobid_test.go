@@ -1 +1,56 @@
 package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJobID(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobID   string
+		wantErr bool
+	}{
+		{name: "simple", jobID: "foo"},
+		{name: "all allowed chars", jobID: "foo-bar_baz.1:2 3"},
+		{name: "empty", jobID: "", wantErr: true},
+		{name: "slash", jobID: "foo/bar", wantErr: true},
+		{name: "at", jobID: "foo@bar", wantErr: true},
+		{name: "hash", jobID: "foo#bar", wantErr: true},
+		{name: "non ascii", jobID: "föo", wantErr: true},
+		{name: "dot", jobID: ".", wantErr: true},
+		{name: "dotdot", jobID: "..", wantErr: true},
+		{name: "too long", jobID: strings.Repeat("a", 250), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJobID(tt.jobID)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseConfig_invalidJobName(t *testing.T) {
+	_, err := ParseConfigBytes("", []byte(`
+jobs:
+- name: "foo/bar"
+  type: snap
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep:
+    - type: last_n
+      count: 1
+`))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `invalid job name "foo/bar"`)
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneKeepProperty(t *testing.T) {
+	tmpl := `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: foo
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep_sender:
+    - type: property
+      %s
+    keep_receiver:
+    - type: last_n
+      count: 10
+`
+
+	conf := testValidConfig(t, fmt.Sprintf(tmpl, `property: "zrepl:keep"`))
+	push, ok := conf.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	require.Len(t, push.Pruning.KeepSender, 1)
+	assert.Equal(t, &PruneKeepProperty{
+		Type:     "property",
+		Property: "zrepl:keep",
+		Value:    "true",
+	}, push.Pruning.KeepSender[0].Ret)
+
+	conf = testValidConfig(t, fmt.Sprintf(tmpl,
+		"property: \"com.example:pin\"\n      value: \"yes\""))
+	push = conf.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, "yes",
+		push.Pruning.KeepSender[0].Ret.(*PruneKeepProperty).Value)
+
+}
+
+func TestPruningSenderReceiver_receiverDestroyDelay(t *testing.T) {
+	conf := testValidConfig(t, `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: foo
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    receiver_destroy_delay: 24h
+    keep_sender:
+    - type: last_n
+      count: 10
+    keep_receiver:
+    - type: last_n
+      count: 10
+`)
+	push, ok := conf.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, 24*time.Hour, push.Pruning.ReceiverDestroyDelay)
+}
+
+func TestPruningSenderReceiver_bookmarksMaxAge(t *testing.T) {
+	conf := testValidConfig(t, `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: foo
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    bookmarks_max_age: 720h
+    keep_sender:
+    - type: last_n
+      count: 10
+    keep_receiver:
+    - type: last_n
+      count: 10
+`)
+	push, ok := conf.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, 720*time.Hour, push.Pruning.BookmarksMaxAge)
+}
+
+func TestPruning_keepMinAge(t *testing.T) {
+	conf := testValidConfig(t, `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: foo
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep_min_age: 1h
+    keep_sender:
+    - type: last_n
+      count: 10
+    keep_receiver:
+    - type: last_n
+      count: 10
+- name: bar
+  type: snap
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep_min_age: 2h
+    keep:
+    - type: last_n
+      count: 10
+`)
+	push, ok := conf.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, time.Hour, push.Pruning.KeepMinAge)
+
+	snap, ok := conf.Jobs[1].Ret.(*SnapJob)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Hour, snap.Pruning.KeepMinAge)
+}
@@ -72,10 +72,67 @@ jobs:
         testprop2: "test123"
 `
 
+	recv_free_space := `
+  recv:
+    free_space:
+      enabled: true
+`
+
+	recv_free_space_headroom := `
+  recv:
+    free_space:
+      enabled: true
+      headroom: 100
+`
+
+	recv_reencrypt := `
+  recv:
+    reencrypt:
+      enabled: true
+      keyformat: raw
+      keylocation: file:///etc/zrepl/backup.key
+`
+
+	recv_reencrypt_no_keylocation := `
+  recv:
+    reencrypt:
+      enabled: true
+      keyformat: raw
+`
+
+	recv_dataset_properties := `
+  recv:
+    dataset_properties:
+      - datasets:
+          - pattern: zreplplatformtest/hosts
+            recursive: true
+        properties:
+          override:
+            mountpoint: none
+      - filesystems: { "zreplplatformtest/hosts/*/var": true }
+        properties:
+          override:
+            readonly: "on"
+`
+
+	recv_dataset_properties_no_filter := `
+  recv:
+    dataset_properties:
+      - properties:
+          override:
+            readonly: "on"
+`
+
 	recv_empty := `
   recv: {}
 `
 
+	recv_buffer := `
+  recv:
+    buffer:
+      size: 64M
+`
+
 	recv_not_specified := `
 `
 
@@ -119,6 +176,49 @@ jobs:
 		testValidConfig(t, fill(recv_properties_empty))
 	})
 
+	t.Run("recv_free_space", func(t *testing.T) {
+		c := testValidConfig(t, fill(recv_free_space))
+		assert.Equal(t, RecvFreeSpace{Enabled: true, Headroom: 10},
+			c.Jobs[0].Ret.(*PullJob).Recv.FreeSpace)
+
+		_, err := testConfig(t, fill(recv_free_space_headroom))
+		require.Error(t, err)
+	})
+
+	t.Run("recv_reencrypt", func(t *testing.T) {
+		c := testValidConfig(t, fill(recv_reencrypt))
+		reencrypt := c.Jobs[0].Ret.(*PullJob).Recv.Reencrypt
+		assert.Equal(t, map[zfsprop.Property]string{
+			"encryption":  "on",
+			"keyformat":   "raw",
+			"keylocation": "file:///etc/zrepl/backup.key",
+		}, reencrypt.Properties())
+
+		c = testValidConfig(t, fill(recv_empty))
+		assert.Nil(t, c.Jobs[0].Ret.(*PullJob).Recv.Reencrypt.Properties())
+
+		_, err := testConfig(t, fill(recv_reencrypt_no_keylocation))
+		require.Error(t, err)
+	})
+
+	t.Run("recv_dataset_properties", func(t *testing.T) {
+		c := testValidConfig(t, fill(recv_dataset_properties))
+		dp := c.Jobs[0].Ret.(*PullJob).Recv.DatasetProperties
+		require.Len(t, dp, 2)
+		assert.Equal(t, "zreplplatformtest/hosts", dp[0].Datasets[0].Pattern)
+		assert.Equal(t, "none", dp[0].Properties.Override["mountpoint"])
+		assert.True(t, dp[1].Filesystems["zreplplatformtest/hosts/*/var"])
+		assert.Equal(t, "on", dp[1].Properties.Override["readonly"])
+
+		_, err := testConfig(t, fill(recv_dataset_properties_no_filter))
+		require.Error(t, err)
+	})
+
+	t.Run("recv_buffer", func(t *testing.T) {
+		c := testValidConfig(t, fill(recv_buffer))
+		assert.Equal(t, Bytes(64<<20), c.Jobs[0].Ret.(*PullJob).Recv.Buffer.Size)
+	})
+
 	t.Run("recv_empty", func(t *testing.T) {
 		c := testValidConfig(t, fill(recv_empty))
 		assert.NotNil(t, c)
@@ -79,6 +79,35 @@ jobs:
 	send_not_specified := `
 `
 
+	holds_true := `
+  send:
+    holds: true
+`
+
+	buffer := `
+  send:
+    buffer:
+      size: 256 MiB
+`
+
+	storage_classes := `
+  send:
+    storage_class_property: "zrepl:storage_class"
+    storage_classes:
+      - class: "archive"
+        datasets:
+          - pattern: "zroot/media"
+            recursive: true
+      - class: "fast"
+        filesystems: {"zroot/db<": true}
+`
+
+	storage_class_without_filter := `
+  send:
+    storage_classes:
+      - class: "archive"
+`
+
 	fill := func(s string) string { return fmt.Sprintf(tmpl, s) }
 
 	t.Run("encrypted_false", func(t *testing.T) {
@@ -138,6 +167,45 @@ jobs:
 		assert.True(t, encrypted)
 	})
 
+	t.Run("holds_true", func(t *testing.T) {
+		c := testValidConfig(t, fill(holds_true))
+		assert.True(t, c.Jobs[0].Ret.(*PushJob).Send.Holds)
+
+		c = testValidConfig(t, fill(send_empty))
+		assert.False(t, c.Jobs[0].Ret.(*PushJob).Send.Holds)
+	})
+
+	t.Run("buffer", func(t *testing.T) {
+		c := testValidConfig(t, fill(buffer))
+		assert.Equal(t, Bytes(256<<20), c.Jobs[0].Ret.(*PushJob).Send.Buffer.Size)
+
+		c = testValidConfig(t, fill(send_empty))
+		assert.Zero(t, c.Jobs[0].Ret.(*PushJob).Send.Buffer.Size)
+	})
+
+	t.Run("storage_classes", func(t *testing.T) {
+		c := testValidConfig(t, fill(storage_classes))
+		send := c.Jobs[0].Ret.(*PushJob).Send
+		assert.Equal(t, "zrepl:storage_class", send.StorageClassProperty)
+		assert.Equal(t, []SendStorageClass{
+			{
+				Class: "archive",
+				Datasets: []DatasetFilter{
+					{Pattern: "zroot/media", Recursive: true},
+				},
+			},
+			{
+				Class:       "fast",
+				Filesystems: FilesystemsFilter{"zroot/db<": true},
+			},
+		}, send.StorageClasses)
+	})
+
+	t.Run("storage_class_without_filter", func(t *testing.T) {
+		_, err := testConfig(t, fill(storage_class_without_filter))
+		assert.Error(t, err)
+	})
+
 	t.Run("send_not_specified", func(t *testing.T) {
 		c := testValidConfig(t, fill(send_not_specified))
 		assert.NotNil(t, c)
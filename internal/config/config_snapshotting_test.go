@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSnapshotting(t *testing.T) {
@@ -56,6 +57,79 @@ jobs:
     interval: 1d
 `
 
+	skipUnchanged := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    skip_unchanged: true
+`
+
+	nameTemplate := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    name_template: '{{.Prefix}}{{.FS | base}}_{{.Time.Strftime "%Y-%m-%d"}}'
+`
+
+	properties := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    properties:
+      "zrepl:job": "{{.JobName}}"
+`
+
+	overrides := `
+  snapshotting:
+    type: cron
+    prefix: zrepl_
+    cron: "0 * * * *"
+    overrides:
+    - filesystems: { "zroot/mail<": true }
+      prefix: zrepl_mail_
+      cron: "*/5 * * * *"
+    - datasets:
+      - pattern: zroot/db
+      hooks: []
+`
+
+	epoch := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    epoch: true
+`
+
+	jitter := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 15m
+    jitter: 30s
+    stagger: 5s
+`
+
+	atomic := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 15m
+    atomic: true
+`
+
+	atomicStagger := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 15m
+    atomic: true
+    stagger: 5s
+`
+
 	intervalManual := `
   snapshotting:
     type: periodic
@@ -76,6 +150,102 @@ jobs:
     - type: command
       path: /tmp/path/to/command
       filesystems: { "zroot<": true, "<": false }
+    - type: fsfreeze
+      mountpoint: /var/lib/mysql
+      timeout: 30s
+      datasets:
+      - pattern: zroot/mysql
+    - type: mysql-lock-tables
+      dsn: "root@unix(/tmp/mysql.sock)/"
+      datasets:
+      - pattern: zroot/mysql
+    - type: postgres-checkpoint
+      dsn: "postgresql://postgres@/postgres?host=/tmp"
+      backup_mode: true
+      datasets:
+      - pattern: zroot/postgres
+    - type: webhook
+      url: "https://app.example.com/quiesce"
+      method: PUT
+      headers:
+        Authorization: "Bearer secret"
+      expect_status: [200, 204]
+      timeout: 10s
+    - type: qemu-fsfreeze
+      domain: vm1
+      datasets:
+      - pattern: zroot/vm/vm1
+        recursive: true
+    - type: qemu-fsfreeze
+      socket: /var/run/qemu/vm2.qga
+      datasets:
+      - pattern: zroot/vm/vm2
+        recursive: true
+`
+
+	webhookNoURL := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: webhook
+      method: POST
+`
+
+	urlCommand := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: command
+      path: /tmp/path/to/command
+      url: "https://app.example.com/quiesce"
+`
+
+	domainCommand := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: command
+      path: /tmp/path/to/command
+      domain: vm1
+`
+
+	mysqlNoDSN := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: mysql-lock-tables
+      datasets:
+      - pattern: zroot/mysql
+`
+
+	backupModeCommand := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: command
+      path: /tmp/path/to/command
+      backup_mode: true
+`
+
+	freezeNoMountpoint := `
+  snapshotting:
+    type: periodic
+    prefix: zrepl_
+    interval: 10m
+    hooks:
+    - type: xfs_freeze
+      datasets:
+      - pattern: zroot/mysql
 `
 
 	fillSnapshotting := func(s string) string { return fmt.Sprintf(tmpl, s) }
@@ -112,6 +282,66 @@ jobs:
 		assert.Equal(t, "human", snp.TimestampFormat)
 	})
 
+	t.Run("skip_unchanged", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(skipUnchanged))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.True(t, snp.SkipUnchanged)
+
+		c = testValidConfig(t, fillSnapshotting(periodic))
+		snp = c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.False(t, snp.SkipUnchanged)
+	})
+
+	t.Run("name_template", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(nameTemplate))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.Equal(t,
+			`{{.Prefix}}{{.FS | base}}_{{.Time.Strftime "%Y-%m-%d"}}`,
+			snp.NameTemplate)
+	})
+
+	t.Run("properties", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(properties))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.Equal(t, map[string]string{"zrepl:job": "{{.JobName}}"},
+			snp.Properties)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(overrides))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		require.Len(t, snp.Overrides, 2)
+		assert.True(t, snp.Overrides[0].Filesystems["zroot/mail<"])
+		assert.Equal(t, "zrepl_mail_", snp.Overrides[0].Prefix)
+		assert.Equal(t, "*/5 * * * *", snp.Overrides[0].Cron)
+		assert.Nil(t, snp.Overrides[0].Hooks)
+		assert.Equal(t, "zroot/db", snp.Overrides[1].Datasets[0].Pattern)
+		assert.NotNil(t, snp.Overrides[1].Hooks)
+		assert.Empty(t, snp.Overrides[1].Hooks)
+	})
+
+	t.Run("epoch", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(epoch))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.True(t, snp.Epoch)
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(jitter))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.Equal(t, 30*time.Second, snp.Jitter)
+		assert.Equal(t, 5*time.Second, snp.Stagger)
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		c = testValidConfig(t, fillSnapshotting(atomic))
+		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
+		assert.True(t, snp.Atomic)
+
+		_, err := testConfig(t, fillSnapshotting(atomicStagger))
+		require.Error(t, err)
+	})
+
 	t.Run("interval_manual", func(t *testing.T) {
 		c = testValidConfig(t, fillSnapshotting(intervalManual))
 		snp := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic)
@@ -126,6 +356,56 @@ jobs:
 		hs := c.Jobs[0].Ret.(*PushJob).Snapshotting.Ret.(*SnapshottingPeriodic).Hooks
 		assert.True(t, hs[0].Filesystems["<"])
 		assert.True(t, hs[1].Filesystems["zroot<"])
+		assert.Equal(t, HookTypeCommand, hs[1].Type)
+		assert.Equal(t, HookTypeFsfreeze, hs[2].Type)
+		assert.Equal(t, "/var/lib/mysql", hs[2].Mountpoint)
+		assert.Equal(t, 30*time.Second, hs[2].Timeout)
+		assert.Equal(t, HookTypeMySQLLockTables, hs[3].Type)
+		assert.Equal(t, "root@unix(/tmp/mysql.sock)/", hs[3].DSN)
+		assert.Equal(t, time.Minute, hs[3].Timeout)
+		assert.Equal(t, HookTypePostgres, hs[4].Type)
+		assert.Equal(t, "postgresql://postgres@/postgres?host=/tmp", hs[4].DSN)
+		assert.True(t, hs[4].BackupMode)
+		assert.Equal(t, HookTypeWebhook, hs[5].Type)
+		assert.Equal(t, "https://app.example.com/quiesce", hs[5].URL)
+		assert.Equal(t, "PUT", hs[5].Method)
+		assert.Equal(t, map[string]string{"Authorization": "Bearer secret"},
+			hs[5].Headers)
+		assert.Equal(t, []int{200, 204}, hs[5].ExpectStatus)
+		assert.Equal(t, 10*time.Second, hs[5].Timeout)
+		assert.Equal(t, HookTypeQemuFreeze, hs[6].Type)
+		assert.Equal(t, "vm1", hs[6].Domain)
+		assert.Equal(t, "/var/run/qemu/vm2.qga", hs[7].Socket)
+	})
+
+	t.Run("webhook without url", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(webhookNoURL))
+		assert.Error(t, err)
+	})
+
+	t.Run("url of command", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(urlCommand))
+		assert.Error(t, err)
+	})
+
+	t.Run("domain of command", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(domainCommand))
+		assert.Error(t, err)
+	})
+
+	t.Run("mysql without dsn", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(mysqlNoDSN))
+		assert.Error(t, err)
+	})
+
+	t.Run("backup_mode of command", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(backupModeCommand))
+		assert.Error(t, err)
+	})
+
+	t.Run("freeze without mountpoint", func(t *testing.T) {
+		_, err := testConfig(t, fillSnapshotting(freezeNoMountpoint))
+		assert.Error(t, err)
 	})
 }
 
@@ -182,3 +462,36 @@ jobs:
 		assert.Equal(t, "dense", snp.TimestampFormat) // default was set correctly
 	})
 }
+
+func TestJobHooks_commandOnly(t *testing.T) {
+	tmpl := `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: foo
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep_sender:
+    - type: last_n
+      count: 10
+    keep_receiver:
+    - type: last_n
+      count: 10
+  hooks:
+    pre:
+      %s
+`
+
+	c := testValidConfig(t, fmt.Sprintf(tmpl, "path: /tmp/path/to/command"))
+	pre := c.Jobs[0].Ret.(*PushJob).Hooks.Pre
+	assert.Equal(t, HookTypeCommand, pre.Type)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl,
+		"{type: fsfreeze, mountpoint: /var/lib/mysql}"))
+	assert.ErrorContains(t, err, "job hooks must be of type")
+}
@@ -8,9 +8,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
 )
 
 func TestSampleConfigsAreParsedWithoutErrors(t *testing.T) {
@@ -248,3 +251,425 @@ jobs:
 	require.NotNil(t, job)
 	assert.True(t, job.Send.Raw)
 }
+
+func TestReplication_VersionsLimit(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+    replication:
+      versions_limit:
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, `        max: 1000`))
+	job := c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, uint(1000), job.Replication.VersionsLimit.Max)
+	assert.Equal(t, "warn", job.Replication.VersionsLimit.Action)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `        action: "truncate"`))
+	require.Error(t, err)
+
+	_, err = testConfig(t, fmt.Sprintf(tmpl, `        action: "foo"`))
+	require.Error(t, err)
+}
+
+func TestReplication_Verify(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+    replication:
+      verify:
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, `        enabled: true`))
+	job := c.Jobs[0].Ret.(*PushJob)
+	assert.True(t, job.Replication.Verify.Enabled)
+	assert.False(t, job.Replication.Verify.Size)
+	assert.InDelta(t, 0.1, job.Replication.Verify.SizeTolerance, 0)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `        size_tolerance: 2`))
+	require.Error(t, err)
+}
+
+func TestReplication_ConcurrencyAuto(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+    replication:
+      concurrency:
+        steps: 2
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	job := c.Jobs[0].Ret.(*PushJob)
+	assert.Nil(t, job.Replication.Concurrency.Auto)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+        auto:
+          max: 8
+          pools: ["zroot"]`))
+	job = c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, &ConcurrencyAuto{
+		Min:      1,
+		Max:      8,
+		Interval: time.Minute,
+		Pools:    []string{"zroot"},
+		Latency:  50 * time.Millisecond,
+	}, job.Replication.Concurrency.Auto)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+        auto:
+          min: 4
+          max: 2`))
+	require.Error(t, err)
+}
+
+func TestPoolImport(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	assert.Nil(t, c.Jobs[0].Ret.(*PushJob).PoolImport)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    pool_import:
+      pool: "backup"`))
+	assert.Equal(t, &PoolImport{
+		Pool:     "backup",
+		Export:   true,
+		Retries:  3,
+		Interval: 30 * time.Second,
+	}, c.Jobs[0].Ret.(*PushJob).PoolImport)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    pool_import:
+      pool: "backup"
+      args: ["-d", "/dev/gpt"]
+      export: false
+      retries: 0`))
+	assert.Equal(t, &PoolImport{
+		Pool:     "backup",
+		Args:     []string{"-d", "/dev/gpt"},
+		Retries:  0,
+		Interval: 30 * time.Second,
+	}, c.Jobs[0].Ret.(*PushJob).PoolImport)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    pool_import:
+      export: true`))
+	require.Error(t, err)
+}
+
+func TestNotifications(t *testing.T) {
+	c := testValidConfig(t, `
+global:
+  notifications:
+    - type: "webhook"
+      url: "https://hooks.example.com/zrepl"
+    - type: "webhook"
+      url: "https://mattermost.example.com/hooks/xxx"
+      on_success: true
+      headers:
+        Authorization: "Bearer secret"
+      template: '{"text": {{ json .Job }}}'
+      timeout: "30s"
+    - type: "email"
+      host: "smtp.example.com:587"
+      username: "zrepl"
+      password: "secret"
+      from: "zrepl@example.com"
+      to: ["admin@example.com"]
+    - type: "ntfy"
+      url: "https://ntfy.sh/zrepl"
+      stale_after: "24h"
+    - type: "gotify"
+      url: "https://gotify.example.com"
+      token: "secret"
+      digest: "0 8 * * 1"
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`)
+	require.Len(t, c.Global.Notifications, 5)
+	assert.Equal(t, &WebhookNotification{
+		NotificationCommon: NotificationCommon{Type: "webhook"},
+		URL:                "https://hooks.example.com/zrepl",
+		Timeout:            10 * time.Second,
+	}, c.Global.Notifications[0].Ret)
+	assert.Equal(t, &WebhookNotification{
+		NotificationCommon: NotificationCommon{
+			Type:      "webhook",
+			OnSuccess: true,
+		},
+		URL:      "https://mattermost.example.com/hooks/xxx",
+		Headers:  map[string]string{"Authorization": "Bearer secret"},
+		Template: `{"text": {{ json .Job }}}`,
+		Timeout:  30 * time.Second,
+	}, c.Global.Notifications[1].Ret)
+	assert.Equal(t, &EmailNotification{
+		NotificationCommon: NotificationCommon{Type: "email"},
+		Host:               "smtp.example.com:587",
+		Username:           "zrepl",
+		Password:           "secret",
+		From:               "zrepl@example.com",
+		To:                 []string{"admin@example.com"},
+		Timeout:            30 * time.Second,
+	}, c.Global.Notifications[2].Ret)
+	assert.Equal(t, &NtfyNotification{
+		NotificationCommon: NotificationCommon{
+			Type:       "ntfy",
+			StaleAfter: 24 * time.Hour,
+		},
+		URL:     "https://ntfy.sh/zrepl",
+		Timeout: 10 * time.Second,
+	}, c.Global.Notifications[3].Ret)
+	assert.Equal(t, &GotifyNotification{
+		NotificationCommon: NotificationCommon{
+			Type:   "gotify",
+			Digest: "0 8 * * 1",
+		},
+		URL:      "https://gotify.example.com",
+		Token:    "secret",
+		Priority: 5,
+		Timeout:  10 * time.Second,
+	}, c.Global.Notifications[4].Ret)
+
+	_, err := testConfig(t, `
+global:
+  notifications:
+    - type: "email"
+      host: "smtp.example.com:587"
+      from: "zrepl@example.com"
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`)
+	require.Error(t, err)
+
+	_, err = testConfig(t, `
+global:
+  notifications:
+    - type: "webhook"
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`)
+	require.Error(t, err)
+}
+
+func TestSinkJob_RootFSMap(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "sink"
+    root_fs: "tank/sink"
+    client_keys: ["bar"]
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	assert.Empty(t, c.Jobs[0].Ret.(*SinkJob).RootFSMap)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    root_fs_map:
+      - prefix: "zroot"
+        root_fs: "fast/sink"
+      - prefix: "data/media"
+        root_fs: "slow/sink"`))
+	assert.Equal(t, []RootFSMapping{
+		{Prefix: "zroot", RootFS: "fast/sink"},
+		{Prefix: "data/media", RootFS: "slow/sink"},
+	}, c.Jobs[0].Ret.(*SinkJob).RootFSMap)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    root_fs_map:
+      - prefix: "zroot"`))
+	require.Error(t, err)
+}
+
+func TestSinkJob_StorageClasses(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "sink"
+    root_fs: "tank/sink"
+    client_keys: ["bar"]
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	assert.Empty(t, c.Jobs[0].Ret.(*SinkJob).StorageClasses)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    storage_classes:
+      - class: "fast"
+        root_fs: "fast/sink"
+      - class: "archive"
+        root_fs: "slow/sink"
+        properties:
+          override:
+            recordsize: "1M"
+            compression: "zstd-19"`))
+	assert.Equal(t, []StorageClass{
+		{Class: "fast", RootFS: "fast/sink"},
+		{
+			Class:  "archive",
+			RootFS: "slow/sink",
+			Properties: PropertyRecvOptions{
+				Override: map[zfsprop.Property]string{
+					"recordsize":  "1M",
+					"compression": "zstd-19",
+				},
+			},
+		},
+	}, c.Jobs[0].Ret.(*SinkJob).StorageClasses)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    storage_classes:
+      - class: "fast"`))
+	require.Error(t, err)
+}
+
+func TestSinkJob_Rehearsal(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "sink"
+    root_fs: "tank/sink"
+    client_keys: ["bar"]
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	assert.Nil(t, c.Jobs[0].Ret.(*SinkJob).Rehearsal)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    rehearsal:
+      root_fs: "scratch/rehearsal"`))
+	assert.Equal(t, &Rehearsal{
+		RootFS: "scratch/rehearsal", Method: "receive", Count: 1,
+	}, c.Jobs[0].Ret.(*SinkJob).Rehearsal)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    rehearsal:
+      root_fs: "tank/rehearsal"
+      method: "clone"
+      count: 3`))
+	assert.Equal(t, &Rehearsal{
+		RootFS: "tank/rehearsal", Method: "clone", Count: 3,
+	}, c.Jobs[0].Ret.(*SinkJob).Rehearsal)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    rehearsal:
+      root_fs: "scratch/rehearsal"
+      method: "rsync"`))
+	require.Error(t, err)
+}
+
+func TestReplication_Intermediates(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	job := c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, "consolidated", job.Replication.Intermediates)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    replication:
+      intermediates: "stepwise"`))
+	job = c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, "stepwise", job.Replication.Intermediates)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    replication:
+      intermediates: "foo"`))
+	require.Error(t, err)
+}
+
+func TestConflictResolution_InvalidResumeToken(t *testing.T) {
+	const tmpl = `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "foo"
+      client_identity: "bar"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+%s
+`
+	c := testValidConfig(t, fmt.Sprintf(tmpl, ""))
+	job := c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, "fail", job.ConflictResolution.InvalidResumeToken)
+
+	c = testValidConfig(t, fmt.Sprintf(tmpl, `
+    conflict_resolution:
+      invalid_resume_token: "abort"`))
+	job = c.Jobs[0].Ret.(*PushJob)
+	assert.Equal(t, "abort", job.ConflictResolution.InvalidResumeToken)
+
+	_, err := testConfig(t, fmt.Sprintf(tmpl, `
+    conflict_resolution:
+      invalid_resume_token: "foo"`))
+	require.Error(t, err)
+}
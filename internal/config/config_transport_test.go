@@ -75,6 +75,28 @@ jobs:
       client_identity: "client"
 			`,
 		},
+		{
+			Name:        "http_with_socket",
+			ExpectError: false,
+			Connect: `
+			type: "http"
+			server: "https://server1.foo.bar:8888"
+      listener_name: "job"
+      client_identity: "client"
+			socket: {dscp: "af21", mark: 7}
+			`,
+		},
+		{
+			Name:        "socket_with_dscp_and_tos",
+			ExpectError: true,
+			Connect: `
+			type: "http"
+			server: "https://server1.foo.bar:8888"
+      listener_name: "job"
+      client_identity: "client"
+			socket: {dscp: "af21", tos: 72}
+			`,
+		},
 	}
 
 	for _, tc := range testTable {
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParsePostgresConninfo returns keyword/value pairs of connection string s of
+// libpq, like libpq parses them: keyword = value, where value may be
+// single-quoted and backslash escapes the next character.
+func ParsePostgresConninfo(s string) ([][2]string, error) {
+	var params [][2]string
+	skipSpace := func(i int) int {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := skipSpace(0); i < len(s); i = skipSpace(i) {
+		start := i
+		for i < len(s) && s[i] != '=' && !isSpace(s[i]) {
+			i++
+		}
+		key := s[start:i]
+		if i = skipSpace(i); i == len(s) || s[i] != '=' {
+			return nil, fmt.Errorf("missing \"=\" after %q", key)
+		}
+		i = skipSpace(i + 1)
+
+		var value strings.Builder
+		quoted := i < len(s) && s[i] == '\''
+		if quoted {
+			i++
+		}
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			} else if quoted && c == '\'' {
+				quoted = false
+				i++
+				break
+			} else if !quoted && isSpace(c) {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if quoted {
+			return nil, fmt.Errorf("unterminated quoted value of %q", key)
+		}
+		params = append(params, [2]string{key, value.String()})
+	}
+	return params, nil
+}
+
+// FormatPostgresConninfo returns connection string of libpq with params.
+// Values are quoted, if they're empty or contain spaces, quotes or
+// backslashes.
+func FormatPostgresConninfo(params [][2]string) string {
+	var sb strings.Builder
+	for _, kv := range params {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(kv[0])
+		sb.WriteByte('=')
+		if v := kv[1]; v != "" && !strings.ContainsAny(v, " \t\n\r'\\") {
+			sb.WriteString(v)
+			continue
+		}
+		sb.WriteByte('\'')
+		sb.WriteString(conninfoEscaper.Replace(kv[1]))
+		sb.WriteByte('\'')
+	}
+	return sb.String()
+}
+
+var conninfoEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePostgresConninfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    [][2]string
+		format  string
+		wantErr bool
+	}{
+		{
+			name:   "plain",
+			s:      "host=/tmp dbname=app",
+			want:   [][2]string{{"host", "/tmp"}, {"dbname", "app"}},
+			format: "host=/tmp dbname=app",
+		},
+		{
+			name: "spaced and quoted",
+			s:    ` user = 'my user'  password='it\'s a \\secret' dbname=\'db `,
+			want: [][2]string{
+				{"user", "my user"}, {"password", `it's a \secret`}, {"dbname", "'db"},
+			},
+			format: `user='my user' password='it\'s a \\secret' dbname='\'db'`,
+		},
+		{
+			name:   "empty value",
+			s:      "password='' dbname=app",
+			want:   [][2]string{{"password", ""}, {"dbname", "app"}},
+			format: "password='' dbname=app",
+		},
+		{name: "empty", s: "  "},
+		{name: "missing equal sign", s: "dbname app", wantErr: true},
+		{name: "database name", s: "app", wantErr: true},
+		{name: "unterminated quote", s: "password='secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := ParsePostgresConninfo(tt.s)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, params)
+			assert.Equal(t, tt.format, FormatPostgresConninfo(params))
+		})
+	}
+}
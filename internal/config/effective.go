@@ -0,0 +1,66 @@
+package config
+
+import (
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+)
+
+// Effective fills derived and inherited fields of the config by values, which
+// zrepl actually uses: cron specs of intervals, prefixes, cron specs and hooks
+// of snapshotting overrides, and defaults of zero values, which come from
+// environment variables. Includes are cleared, because included jobs and keys
+// are already in the config.
+func (c *Config) Effective() {
+	c.IncludeKeys, c.IncludeJobs, c.Include = "", "", ""
+	if c.Global.RPC.ChunkSize == 0 {
+		c.Global.RPC.ChunkSize = Bytes(env.Values.ZFSSendReadSize)
+	}
+
+	for i := range c.Jobs {
+		switch v := c.Jobs[i].Ret.(type) {
+		case *SnapJob:
+			v.Snapshotting.effective()
+		case *PushJob:
+			v.ActiveJob.effective()
+			v.Snapshotting.effective()
+		case *PullJob:
+			v.ActiveJob.effective()
+		case *SourceJob:
+			v.Snapshotting.effective()
+		}
+	}
+}
+
+func (self *ActiveJob) effective() {
+	if !self.Interval.Manual {
+		self.Cron, self.Interval = self.CronSpec(), PositiveDurationOrManual{}
+	}
+
+	retries := &self.Replication.Retries
+	if retries.MaxAttempts == 0 {
+		retries.MaxAttempts = env.Values.ReplicationMaxAttempts
+	}
+}
+
+func (self *SnapshottingEnum) effective() {
+	s, ok := self.Ret.(*SnapshottingPeriodic)
+	if !ok {
+		return
+	}
+
+	if !s.Interval.Manual {
+		s.Cron, s.Interval = s.CronSpec(), PositiveDurationOrManual{}
+	}
+
+	for i := range s.Overrides {
+		o := &s.Overrides[i]
+		if o.Prefix == "" {
+			o.Prefix = s.Prefix
+		}
+		if o.Cron == "" {
+			o.Cron = s.Cron
+		}
+		if o.Hooks == nil {
+			o.Hooks = s.Hooks
+		}
+	}
+}
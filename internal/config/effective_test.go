@@ -0,0 +1,98 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestConfig_Effective(t *testing.T) {
+	c := testValidConfig(t, `
+jobs:
+  - name: "foo"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "bar"
+      client_identity: "foo"
+    interval: "10m"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "periodic"
+      prefix: "zrepl_"
+      interval: "1h"
+      overrides:
+        - filesystems:
+            "pool/db": true
+          prefix: "db_"
+        - filesystems:
+            "pool/tmp": true
+    pruning:
+      keep_sender:
+        - type: "grid"
+          grid: "1x1h(keep=all) | 24x1h | 14x1d"
+          regex: "^zrepl_"
+`)
+	c.Effective()
+
+	push, ok := c.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, "@every 10m0s", push.Cron)
+	assert.Zero(t, push.Interval)
+	assert.Equal(t, 3, push.Replication.Retries.MaxAttempts)
+
+	snap, ok := push.Snapshotting.Ret.(*SnapshottingPeriodic)
+	require.True(t, ok)
+	assert.Equal(t, "@every 1h0m0s", snap.Cron)
+	require.Len(t, snap.Overrides, 2)
+	assert.Equal(t, "db_", snap.Overrides[0].Prefix)
+	assert.Equal(t, snap.Cron, snap.Overrides[0].Cron)
+	assert.Equal(t, "zrepl_", snap.Overrides[1].Prefix)
+
+	b, err := yaml.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "grid: 1x1h(keep=all) | 24x1h | 14x1d\n")
+}
+
+func TestConfig_Effective_samples(t *testing.T) {
+	paths, err := filepath.Glob("./samples/*.yml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, p := range paths {
+		t.Run(p, func(t *testing.T) {
+			c, err := ParseConfig(p)
+			require.NoError(t, err)
+			c.Effective()
+			b, err := yaml.Marshal(c)
+			require.NoError(t, err)
+
+			c2, err := ParseConfigBytes("", b)
+			require.NoError(t, err, string(b))
+			c2.Effective()
+			b2, err := yaml.Marshal(c2)
+			require.NoError(t, err)
+			assert.Equal(t, string(b), string(b2))
+		})
+	}
+}
+
+func TestConfig_RedactSecrets(t *testing.T) {
+	c := testValidConfig(t, `
+global:
+  http:
+    listen: ":8080"
+    tls_cert: "/etc/zrepl/cert.pem"
+    tls_key: "/etc/zrepl/key.pem"
+keys:
+  - name: "foo"
+    key: "secret"
+`+secretCmdJobs)
+	c.RedactSecrets()
+	assert.Equal(t, redactedSecret, c.Keys[0].Key)
+	assert.Equal(t, "/etc/zrepl/key.pem", c.Global.HTTP.TLSKey)
+}
@@ -19,6 +19,17 @@ var Values = struct {
 	ReplicationReconnectHardTimeout time.Duration `env:"ZREPL_REPLICATION_RECONNECT_HARD_FAIL_TIMEOUT"`
 
 	ZFSMaxHoldTagLen int `env:"ZREPL_ZFS_MAX_HOLD_TAG_LEN"`
+
+	ZFSListCacheTTL time.Duration `env:"ZREPL_ZFS_LIST_CACHE_TTL"`
+	ZFSJSONDisable  bool          `env:"ZREPL_ZFS_JSON_DISABLE"`
+
+	ZFSSendPipeSize  int  `env:"ZREPL_ZFS_SEND_PIPE_SIZE"`
+	ZFSSendReadSize  int  `env:"ZREPL_ZFS_SEND_READ_SIZE"`
+	ZFSSendReadahead int  `env:"ZREPL_ZFS_SEND_READAHEAD"`
+	ZFSSpliceDisable bool `env:"ZREPL_ZFS_SPLICE_DISABLE"`
+
+	StreamJournalRetries int           `env:"ZREPL_STREAM_JOURNAL_RETRIES"`
+	StreamJournalGrace   time.Duration `env:"ZREPL_STREAM_JOURNAL_GRACE"`
 }{
 	PrunerRetryInterval:             10 * time.Second,
 	ReplicationMaxAttempts:          3,
@@ -27,6 +38,13 @@ var Values = struct {
 
 	// 256 include NULL byte, from module/zfs/dsl_userhold.c
 	ZFSMaxHoldTagLen: 256 - 1,
+
+	ZFSListCacheTTL: 5 * time.Second,
+
+	ZFSSendReadSize: 1 << 20,
+
+	StreamJournalRetries: 3,
+	StreamJournalGrace:   10 * time.Second,
 }
 
 func Parse() error {
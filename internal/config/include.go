@@ -13,17 +13,11 @@ import (
 func appendYAML[T []E, E any](base, pattern string, target T) (T, error) {
 	if pattern == "" {
 		return nil, nil
-	} else if !filepath.IsAbs(pattern) && base != "" {
-		pattern = filepath.Join(filepath.Dir(base), pattern)
 	}
 
-	matches, err := filepath.Glob(pattern)
+	matches, err := globInclude(base, pattern)
 	if err != nil {
-		return nil, fmt.Errorf("failed glob %q: %w", pattern, err)
-	} else if matches == nil && !hasMeta(pattern) {
-		if _, err := os.Lstat(pattern); err != nil {
-			return nil, fmt.Errorf("failed include %q: %w", pattern, err)
-		}
+		return nil, err
 	}
 
 	for _, name := range matches {
@@ -43,6 +37,119 @@ func appendYAML[T []E, E any](base, pattern string, target T) (T, error) {
 	return target, nil
 }
 
+// globInclude returns files matching pattern. Relative pattern is relative to
+// directory of config file base. A pattern without magic characters must match
+// an existing file.
+func globInclude(base, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) && base != "" {
+		pattern = filepath.Join(filepath.Dir(base), pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed glob %q: %w", pattern, err)
+	} else if matches == nil && !hasMeta(pattern) {
+		if _, err := os.Lstat(pattern); err != nil {
+			return nil, fmt.Errorf("failed include %q: %w", pattern, err)
+		}
+	}
+	return matches, nil
+}
+
+// includeFragment is a config file, included by Include. It defines jobs and
+// keys only, which are appended to jobs and keys of the config.
+type includeFragment struct {
+	Keys []AuthKey `yaml:"keys"`
+	Jobs []JobEnum `yaml:"jobs"`
+}
+
+// include appends jobs and keys of files, matching c.Include, in lexical
+// order. Job names must be unique across the config and all included files.
+func (c *Config) include(path string) error {
+	if c.Include == "" {
+		return nil
+	}
+
+	matches, err := globInclude(path, c.Include)
+	if err != nil {
+		return err
+	}
+
+	templates, err := jobTemplates(c.Templates)
+	if err != nil {
+		return err
+	}
+
+	jobFiles := make(map[string]string, len(c.Jobs))
+	for i := range c.Jobs {
+		jobFiles[c.Jobs[i].Name()] = path
+	}
+
+	for _, name := range matches {
+		frag, err := readIncludeFragment(name, templates)
+		if err != nil {
+			return err
+		}
+		for i := range frag.Jobs {
+			jobName := frag.Jobs[i].Name()
+			if prev, ok := jobFiles[jobName]; ok {
+				return fmt.Errorf("include %q: duplicate job name %q, defined in %q",
+					name, jobName, prev)
+			}
+			jobFiles[jobName] = name
+		}
+		c.Keys = append(c.Keys, frag.Keys...)
+		c.Jobs = append(c.Jobs, frag.Jobs...)
+	}
+	return nil
+}
+
+func readIncludeFragment(name string, templates map[string]*JobTemplate,
+) (*includeFragment, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("include %q: yaml unmarshal: %w", name, err)
+	} else if err := resolveIncludeRaw(name, &root); err != nil {
+		return nil, fmt.Errorf("include %q: %w", name, err)
+	} else if err := checkFragmentKeys(&root); err != nil {
+		return nil, fmt.Errorf("include %q: %w", name, err)
+	} else if doc := documentMapping(&root); doc != nil {
+		if err := expandJobs(doc, templates); err != nil {
+			return nil, fmt.Errorf("include %q: %w", name, err)
+		}
+	}
+
+	frag := new(includeFragment)
+	if err := root.Decode(frag); err != nil {
+		return nil, fmt.Errorf("include %q: yaml unmarshal: %w", name, err)
+	}
+	return frag, nil
+}
+
+// checkFragmentKeys returns error, if document root has other top level keys,
+// than jobs and keys.
+func checkFragmentKeys(root *yaml.Node) error {
+	doc := documentMapping(root)
+	if doc == nil {
+		return nil
+	}
+
+	for i := 0; i < len(doc.Content); i += 2 {
+		switch key := doc.Content[i]; key.Value {
+		case "jobs", "keys":
+		default:
+			return fmt.Errorf("line %d: only jobs and keys can be included, got %q",
+				key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
 // hasMeta reports whether path contains any of the magic characters recognized
 // by Match.
 //
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// includeRawTag tags scalars, which are paths to files with PEM material, like
+// certificates and keys. Such scalars are replaced by content of the files.
+const includeRawTag = "!include_raw"
+
+// resolveIncludeRaw replaces every scalar of node, tagged by !include_raw, by
+// content of the file. Relative paths are relative to directory of config file
+// base.
+func resolveIncludeRaw(base string, node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == includeRawTag {
+		b, err := readIncludeRaw(base, node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", node.Line, includeRawTag, err)
+		}
+		node.Tag, node.Value = "!!str", string(b)
+		return nil
+	}
+
+	for _, n := range node.Content {
+		if err := resolveIncludeRaw(base, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readIncludeRaw returns content of PEM file name. It refuses files with
+// private keys, readable by others.
+func readIncludeRaw(base, name string) ([]byte, error) {
+	if name == "" {
+		return nil, errors.New("empty path")
+	} else if !filepath.IsAbs(name) && base != "" {
+		name = filepath.Join(filepath.Dir(base), name)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", name, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %q: %w", name, err)
+	} else if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%q is not a regular file", name)
+	}
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", name, err)
+	}
+
+	hasKey, err := pemHasPrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", name, err)
+	} else if hasKey && fi.Mode().Perm()&0o004 != 0 {
+		return nil, fmt.Errorf(
+			"%q contains a private key and is world-readable (mode %s)",
+			name, fi.Mode().Perm())
+	}
+	return b, nil
+}
+
+// pemHasPrivateKey returns true if PEM content b has a private key block. It
+// returns error, if b has no PEM blocks.
+func pemHasPrivateKey(b []byte) (bool, error) {
+	var found, hasKey bool
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		}
+		found = true
+		hasKey = hasKey || strings.Contains(block.Type, "PRIVATE KEY")
+	}
+
+	if !found {
+		return false, errors.New("no PEM data found")
+	}
+	return hasKey, nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const includeRawConfig = `
+global:
+  http:
+    listen: ":8080"
+    tls_cert: !include_raw "cert.pem"
+    tls_key: !include_raw "key.pem"
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`
+
+func writeIncludeRaw(t *testing.T, keyMode os.FileMode) (string, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	certPEM := string(pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}))
+	keyPEM := string(pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"),
+		[]byte(certPEM), 0o644))
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, []byte(keyPEM), 0o600))
+	require.NoError(t, os.Chmod(keyFile, keyMode))
+
+	path := filepath.Join(dir, "zrepl.yml")
+	require.NoError(t, os.WriteFile(path, []byte(includeRawConfig), 0o600))
+	return path, certPEM, keyPEM
+}
+
+func TestIncludeRaw(t *testing.T) {
+	path, certPEM, keyPEM := writeIncludeRaw(t, 0o600)
+	c, err := ParseConfig(path)
+	require.NoError(t, err)
+	require.NotNil(t, c.Global.HTTP)
+	assert.Equal(t, certPEM, c.Global.HTTP.TLSCert)
+	assert.Equal(t, keyPEM, c.Global.HTTP.TLSKey)
+}
+
+func TestIncludeRaw_worldReadableKey(t *testing.T) {
+	path, _, _ := writeIncludeRaw(t, 0o644)
+	_, err := ParseConfig(path)
+	require.ErrorContains(t, err, "world-readable")
+}
+
+func TestIncludeRaw_errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("garbage"), 0o600))
+
+	tests := []struct {
+		name string
+		file string
+		err  string
+	}{
+		{name: "not PEM", file: notPEM, err: "no PEM data found"},
+		{name: "not exists", file: filepath.Join(dir, "x.pem"), err: "open"},
+		{name: "directory", file: dir, err: "not a regular file"},
+		{name: "empty", file: "", err: "empty path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testConfig(t, `
+global:
+  http:
+    listen: ":8080"
+    tls_cert: !include_raw "`+tt.file+`"
+    tls_key: "key.pem"
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +35,64 @@ func TestAppendYAML_jobs(t *testing.T) {
 	assert.Equal(t, "bar", c.Jobs[0].Name())
 	assert.Equal(t, "foo", c.Jobs[1].Name())
 }
+
+func TestConfig_include(t *testing.T) {
+	c, err := ParseConfig("testdata/include.yaml")
+	require.NoError(t, err)
+	require.Len(t, c.Jobs, 3)
+	assert.Equal(t, "bar", c.Jobs[0].Name())
+	assert.Equal(t, "foo", c.Jobs[1].Name())
+	assert.Equal(t, "qux", c.Jobs[2].Name())
+	require.Len(t, c.Keys, 1)
+	assert.Equal(t, "test", c.Keys[0].Name)
+}
+
+func TestConfig_include_duplicate(t *testing.T) {
+	dir := t.TempDir()
+	job := `
+jobs:
+  - name: "foo"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+    pruning:
+      keep:
+        - type: "last_n"
+          count: 10
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yml"),
+		[]byte(job), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yml"),
+		[]byte(job), 0o600))
+
+	_, err := ParseConfigBytes(filepath.Join(dir, "zrepl.yml"),
+		[]byte(`include: "*.yml"`))
+	require.ErrorContains(t, err, `duplicate job name "foo"`)
+	require.ErrorContains(t, err, filepath.Join(dir, "a.yml"))
+}
+
+func TestConfig_include_onlyJobsAndKeys(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yml"),
+		[]byte("global:\n  zfs_bin: /sbin/zfs\n"), 0o600))
+
+	_, err := ParseConfigBytes(filepath.Join(dir, "zrepl.yml"),
+		[]byte(`include: "*.yml"`))
+	require.ErrorContains(t, err, "only jobs and keys can be included")
+}
+
+func TestConfig_include_notExist(t *testing.T) {
+	_, err := ParseConfigBytes("testdata/zrepl.yml",
+		[]byte(`include: "notexists.yml"`))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// empty conf.d is fine
+	c, err := ParseConfigBytes("testdata/include_jobs.yaml", []byte(`
+include: "notexists.d/*.yml"
+include_jobs: "jobs.d/*.yaml"
+`))
+	require.NoError(t, err)
+	assert.Len(t, c.Jobs, 1)
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+)
+
+// Prefixes of on-disk artifacts, which include job name. They must be the same
+// as in package endpoint.
+const (
+	jobIDStepHoldPrefix         = "zrepl_STEP_J_"
+	jobIDLastReceivedHoldPrefix = "zrepl_last_received_J_"
+	jobIDCursorBookmarkPrefix   = "zrepl_CURSORTENTATIVE__G_0000000000000000_J_"
+
+	// jobIDMaxEntityNameLen is the max length of a ZFS entity name, like
+	// "pool#bookmark".
+	jobIDMaxEntityNameLen = 256 - 1
+)
+
+var jobIDValidChar = regexp.MustCompile(`^[0-9a-zA-Z-_\.: ]+$`)
+
+// ValidateJobID returns an error, if s can't be used as a job name. Job name
+// becomes part of hold tags and bookmark names, created by the daemon, so it
+// must be valid before the daemon creates any of them. It does the same checks,
+// as endpoint.MakeJobID, which can't be used here, because endpoint imports
+// config.
+func ValidateJobID(s string) error {
+	switch {
+	case s == "":
+		return errors.New("must not be empty string")
+	case !jobIDValidChar.MatchString(s):
+		return fmt.Errorf(
+			"must only contain alphanumeric chars and any in %q", "-_.: ")
+	case s == "." || s == "..":
+		return fmt.Errorf("must not be %q", s)
+	}
+
+	maxHoldTagLen := env.Values.ZFSMaxHoldTagLen
+	for _, prefix := range [...]string{
+		jobIDStepHoldPrefix, jobIDLastReceivedHoldPrefix,
+	} {
+		if len(prefix)+len(s) > maxHoldTagLen {
+			return fmt.Errorf("hold tag %q exceeds max length of %d",
+				prefix+s, maxHoldTagLen)
+		}
+	}
+
+	// the same sample filesystem, as endpoint.MakeJobID uses
+	const fsLen = len("pool/ds#")
+	if n := len(jobIDCursorBookmarkPrefix) + len(s); n+fsLen >
+		jobIDMaxEntityNameLen {
+		return fmt.Errorf("replication cursor bookmark exceeds max length of %d",
+			jobIDMaxEntityNameLen)
+	}
+	return nil
+}
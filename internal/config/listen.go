@@ -8,8 +8,18 @@ type Listen struct {
 
 	TLSCert string `yaml:"tls_cert" validate:"required_with=TLSKey,omitempty,filepath"`
 	TLSKey  string `yaml:"tls_key" validate:"omitempty,filepath"`
+	// TLSKeyCmd is a shell command, which outputs PEM content of TLSKey.
+	TLSKeyCmd string `yaml:"tls_key_cmd"`
 
-	Control bool `yaml:"control" validate:"required_without_all=Metrics Zfs"`
-	Metrics bool `yaml:"metrics" validate:"required_without_all=Control Zfs"`
-	Zfs     bool `yaml:"zfs" validate:"required_without_all=Control Metrics"`
+	// TLSClientCA enables mTLS: every client must present a certificate, signed
+	// by this CA.
+	TLSClientCA string `yaml:"tls_client_ca" validate:"excluded_without=TLSCert,omitempty,filepath"`
+	// ControlKeys are names of keys, which authorize clients of control
+	// endpoints by bearer token.
+	ControlKeys []string `yaml:"control_keys,omitempty" validate:"excluded_without=Control,dive,required"`
+
+	Control bool `yaml:"control" validate:"required_without_all=Metrics Zfs Web"`
+	Metrics bool `yaml:"metrics" validate:"required_without_all=Control Zfs Web"`
+	Zfs     bool `yaml:"zfs" validate:"required_without_all=Control Metrics Web"`
+	Web     bool `yaml:"web" validate:"required_without_all=Control Metrics Zfs"`
 }
@@ -100,6 +100,42 @@ func TestListen_Validator(t *testing.T) {
 				Metrics: true,
 			},
 		},
+		{
+			name: "with tls_client_ca",
+			listen: Listen{
+				Addr:        "127.0.0.1:80",
+				TLSCert:     "/notexists",
+				TLSKey:      "/notexists",
+				TLSClientCA: "/notexists",
+				Control:     true,
+			},
+		},
+		{
+			name: "with tls_client_ca without tls_cert",
+			listen: Listen{
+				Addr:        "127.0.0.1:80",
+				TLSClientCA: "/notexists",
+				Control:     true,
+			},
+			invalid: true,
+		},
+		{
+			name: "with control_keys",
+			listen: Listen{
+				Addr:        "127.0.0.1:80",
+				Control:     true,
+				ControlKeys: []string{"ops"},
+			},
+		},
+		{
+			name: "with control_keys without control",
+			listen: Listen{
+				Addr:        "127.0.0.1:80",
+				Metrics:     true,
+				ControlKeys: []string{"ops"},
+			},
+			invalid: true,
+		},
 		{
 			name: "with zfs",
 			listen: Listen{
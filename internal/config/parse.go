@@ -46,9 +46,18 @@ func ParseConfig(path string, opts ...Option) (*Config, error) {
 func ParseConfigBytes(path string, bytes []byte, opts ...Option,
 ) (*Config, error) {
 	c := New(opts...)
+	var root yaml.Node
 	if err := defaults.Set(c); err != nil {
 		return nil, fmt.Errorf("init config with defaults: %w", err)
-	} else if err := yaml.Unmarshal(bytes, &c); err != nil {
+	} else if err := yaml.Unmarshal(bytes, &root); err != nil {
+		return nil, fmt.Errorf("config unmarshal: %w", err)
+	} else if err := resolveIncludeRaw(path, &root); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	} else if err := resolveTemplates(&root); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	} else if root.Kind == 0 {
+		return nil, errors.New("There was no yaml document in the file")
+	} else if err := root.Decode(&c); err != nil {
 		return nil, fmt.Errorf("config unmarshal: %w", err)
 	} else if c == nil {
 		return nil, errors.New("There was no yaml document in the file")
@@ -92,7 +101,9 @@ func validateJobNames(config *Config) error {
 	seen := make(map[string]struct{}, len(config.Jobs))
 	for _, job := range config.Jobs {
 		name := job.Name()
-		if _, ok := seen[name]; ok {
+		if err := ValidateJobID(name); err != nil {
+			return fmt.Errorf("invalid job name %q: %w", name, err)
+		} else if _, ok := seen[name]; ok {
 			return fmt.Errorf("duplicate job name %q", name)
 		}
 		seen[name] = struct{}{}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
 )
 
 type RetentionIntervalList []RetentionInterval
@@ -52,6 +54,34 @@ func (t *RetentionIntervalList) UnmarshalYAML(value *yaml.Node) (err error) {
 	return nil
 }
 
+func (t RetentionIntervalList) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: "retention grid, like 1x1h(keep=all) | 24x1h | 14x1d",
+	}
+}
+
+func (t RetentionIntervalList) MarshalYAML() (any, error) {
+	specs := make([]string, 0, len(t))
+	for i := 0; i < len(t); {
+		n := 1
+		for i+n < len(t) && t[i+n] == t[i] {
+			n++
+		}
+		spec := strconv.Itoa(n) + "x" + formatDuration(t[i].length)
+		switch t[i].keepCount {
+		case 1:
+		case RetentionGridKeepCountAll:
+			spec += "(keep=all)"
+		default:
+			spec += "(keep=" + strconv.Itoa(t[i].keepCount) + ")"
+		}
+		specs = append(specs, spec)
+		i += n
+	}
+	return strings.Join(specs, " | "), nil
+}
+
 var retentionStringIntervalRegex *regexp.Regexp = regexp.MustCompile(`^\s*(\d+)\s*x\s*([^\(]+)\s*(\((.*)\))?\s*$`)
 
 func parseRetentionGridIntervalString(e string) (intervals []RetentionInterval, err error) {
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+)
+
+// JSONSchema returns JSON Schema of config files, for validating them and for
+// autocompletion in editors.
+func JSONSchema() *jsonschema.Schema {
+	s := jsonschema.ReflectYAML("zrepl.yml", &Config{})
+	// Jobs can be included from other files only.
+	if c, ok := s.Defs["config.Config"]; ok {
+		c.Properties["jobs"].MinItems = nil
+	}
+	// Jobs can be built from templates.
+	if jobs, ok := s.Defs["config.JobEnum"]; ok {
+		jobs.AnyOf = append(jobs.AnyOf, jobFromTemplate{}.YAMLSchema())
+	}
+	return s
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+)
+
+func TestJSONSchema(t *testing.T) {
+	s := JSONSchema()
+	assert.Equal(t, jsonschema.Draft, s.Schema)
+	assert.Equal(t, "#/$defs/config.Config", s.Ref)
+
+	c := s.Defs["config.Config"]
+	require.NotNil(t, c)
+	assert.Equal(t, &jsonschema.Schema{
+		Type:  "array",
+		Items: &jsonschema.Schema{Ref: "#/$defs/config.JobEnum"},
+	}, c.Properties["jobs"])
+
+	g := s.Defs["config.Global"]
+	require.NotNil(t, g)
+	assert.Equal(t, "1m", g.Properties["rpc_timeout"].Default)
+	assert.Equal(t, []any{"exec", "lzc"}, g.Properties["zfs_backend"].Enum)
+
+	jobs := s.Defs["config.JobEnum"]
+	require.NotNil(t, jobs)
+	require.Len(t, jobs.AnyOf, 7)
+	assert.Equal(t, "#/$defs/config.PruneJob", jobs.AnyOf[0].AllOf[0].Ref)
+	assert.Equal(t, "prune", jobs.AnyOf[0].AllOf[1].Properties["type"].Const)
+	assert.Equal(t, []string{"from_template"}, jobs.AnyOf[6].Required)
+}
+
+func TestJSONSchema_samples(t *testing.T) {
+	s := JSONSchema()
+	paths, err := filepath.Glob("./samples/*.yml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, p := range paths {
+		t.Run(p, func(t *testing.T) {
+			b, err := os.ReadFile(p)
+			require.NoError(t, err)
+			var v any
+			require.NoError(t, yaml.Unmarshal(b, &v))
+			require.NoError(t, validateSchema(s, s, v, ""))
+		})
+	}
+
+	var v any
+	require.NoError(t, yaml.Unmarshal([]byte(`
+jobs:
+  - name: "foo"
+    type: "snap"
+    snapshotting:
+      type: "hourly"
+`), &v))
+	require.ErrorContains(t, validateSchema(s, s, v, ""), ".jobs[0]: no match")
+}
+
+// validateSchema validates v by subset of JSON Schema, which JSONSchema
+// generates.
+func validateSchema(root, s *jsonschema.Schema, v any, path string) error {
+	if s.Ref != "" {
+		return validateSchema(root,
+			root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")], v, path)
+	}
+
+	for _, sub := range s.AllOf {
+		if err := validateSchema(root, sub, v, path); err != nil {
+			return err
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		var errs []string
+		for _, sub := range s.AnyOf {
+			err := validateSchema(root, sub, v, path)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: no match: %s", path, strings.Join(errs, "; "))
+		}
+	}
+
+	if s.Const != nil && s.Const != v {
+		return fmt.Errorf("%s: %v is not %v", path, v, s.Const)
+	} else if len(s.Enum) > 0 && !slices.Contains(s.Enum, v) {
+		return fmt.Errorf("%s: %v is not one of %v", path, v, s.Enum)
+	} else if err := validateType(s, v, path); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: %q is required", path, name)
+			}
+		}
+		for name, item := range v {
+			sub := s.Properties[name]
+			if sub == nil {
+				sub = s.AdditionalProperties
+			}
+			if sub == nil {
+				continue
+			}
+			if err := validateSchema(root, sub, item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: less than %d items", path, *s.MinItems)
+		} else if s.Items == nil {
+			break
+		}
+		for i, item := range v {
+			err := validateSchema(root, s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateType(s *jsonschema.Schema, v any, path string) error {
+	var types []string
+	switch t := s.Type.(type) {
+	case nil:
+		return nil
+	case string:
+		types = []string{t}
+	case []string:
+		types = t
+	}
+
+	var got string
+	switch v.(type) {
+	case nil:
+		got = "null"
+	case bool:
+		got = "boolean"
+	case int, int64, uint64:
+		got = "integer"
+	case float64:
+		got = "number"
+	case string:
+		got = "string"
+	case []any:
+		got = "array"
+	case map[string]any:
+		got = "object"
+	}
+
+	if slices.Contains(types, got) ||
+		(got == "integer" && slices.Contains(types, "number")) {
+		return nil
+	}
+	return fmt.Errorf("%s: %s is not %v", path, got, types)
+}
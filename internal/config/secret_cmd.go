@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretCmdTimeout limits run time of every command, which outputs a secret.
+const secretCmdTimeout = time.Minute
+
+// resolveSecrets replaces empty secrets of the config, like keys, passwords
+// and tokens, by output of commands, configured for them.
+func (c *Config) resolveSecrets() error {
+	return c.eachSecret(func(secret *string, cmd string, _ bool) error {
+		return secretFromCmd(secret, cmd)
+	})
+}
+
+// RedactSecrets replaces secrets of the config by a placeholder, so the
+// config can be printed. TLS keys are replaced only if they are PEM content,
+// not paths. Passwords of DSNs of hooks and values of all HTTP headers, which
+// usually carry credentials, are replaced too, including these of templates.
+func (c *Config) RedactSecrets() {
+	_ = c.eachSecret(func(secret *string, _ string, tls bool) error {
+		if *secret != "" && (!tls || isPEM(*secret)) {
+			*secret = redactedSecret
+		}
+		return nil
+	})
+
+	c.eachHook(func(h *HookCommand) {
+		h.DSN = h.RedactedDSN()
+		redactHeaders(h.Headers)
+	})
+
+	for i := range c.Global.Notifications {
+		if v, ok := c.Global.Notifications[i].Ret.(*WebhookNotification); ok {
+			redactHeaders(v.Headers)
+		}
+	}
+
+	for i := range c.Templates {
+		c.Templates[i].redactSecrets()
+	}
+}
+
+const (
+	redactedSecret = "<redacted>"
+	// redactedPassword replaces passwords of DSNs, like [url.URL.Redacted].
+	redactedPassword = "xxxxx"
+)
+
+func redactHeaders(headers map[string]string) {
+	for k := range headers {
+		headers[k] = redactedSecret
+	}
+}
+
+// eachHook calls fn for every hook of jobs of the config.
+func (c *Config) eachHook(fn func(h *HookCommand)) {
+	for i := range c.Jobs {
+		var hooks *JobHooks
+		var snapshotting *SnapshottingEnum
+		switch v := c.Jobs[i].Ret.(type) {
+		case *SnapJob:
+			snapshotting = &v.Snapshotting
+		case *PushJob:
+			hooks, snapshotting = &v.Hooks, &v.Snapshotting
+		case *PullJob:
+			hooks = &v.Hooks
+		case *SinkJob:
+			hooks = &v.Hooks
+		case *SourceJob:
+			hooks, snapshotting = &v.Hooks, &v.Snapshotting
+		}
+
+		if hooks != nil {
+			for _, h := range [...]*HookCommand{hooks.Pre, hooks.Post} {
+				if h != nil {
+					fn(h)
+				}
+			}
+		}
+
+		if snapshotting == nil {
+			continue
+		} else if s, ok := snapshotting.Ret.(*SnapshottingPeriodic); ok {
+			for j := range s.Hooks {
+				fn(&s.Hooks[j])
+			}
+			for j := range s.Overrides {
+				for k := range s.Overrides[j].Hooks {
+					fn(&s.Overrides[j].Hooks[k])
+				}
+			}
+		}
+	}
+}
+
+// RedactedDSN returns DSN of the hook without password, for printing.
+func (self *HookCommand) RedactedDSN() string {
+	if self.Type == HookTypeMySQLLockTables {
+		return redactMySQLDSN(self.DSN)
+	}
+	return redactPostgresDSN(self.DSN)
+}
+
+// redactMySQLDSN redacts password of dsn in format of go-sql-driver/mysql:
+// [user[:password]@][net[(addr)]]/dbname.
+func redactMySQLDSN(dsn string) string {
+	i := strings.LastIndexByte(dsn, '/')
+	if i < 0 {
+		return dsn
+	}
+	at := strings.LastIndexByte(dsn[:i], '@')
+	if at < 0 {
+		return dsn
+	} else if user, _, ok := strings.Cut(dsn[:at], ":"); ok {
+		return user + ":" + redactedPassword + dsn[at:]
+	}
+	return dsn
+}
+
+// redactPostgresDSN redacts password of postgres connection URI or connection
+// string of keyword=value pairs. Unparsable dsn, which looks like it has a
+// password, is replaced completely.
+func redactPostgresDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", redactedPassword)
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+
+	params, err := ParsePostgresConninfo(dsn)
+	if err != nil {
+		if strings.Contains(dsn, "password") {
+			return redactedPassword
+		}
+		return dsn
+	}
+
+	var redacted bool
+	for i := range params {
+		if params[i][0] == "password" {
+			params[i][1], redacted = redactedPassword, true
+		}
+	}
+	if !redacted {
+		return dsn
+	}
+	return FormatPostgresConninfo(params)
+}
+
+func isPEM(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN ")
+}
+
+// eachSecret calls fn for every secret of the config with the command, which
+// outputs it. tls is true for TLS keys.
+func (c *Config) eachSecret(fn func(secret *string, cmd string, tls bool) error,
+) error {
+	for i := range c.Keys {
+		k := &c.Keys[i]
+		if err := fn(&k.Key, k.KeyCmd, false); err != nil {
+			return fmt.Errorf("key %q: %w", k.Name, err)
+		}
+	}
+
+	for i := range c.Listen {
+		l := &c.Listen[i]
+		if err := fn(&l.TLSKey, l.TLSKeyCmd, true); err != nil {
+			return fmt.Errorf("listen %d: tls_key: %w", i, err)
+		}
+	}
+
+	if h := c.Global.HTTP; h != nil {
+		if err := fn(&h.TLSKey, h.TLSKeyCmd, true); err != nil {
+			return fmt.Errorf("global http: tls_key: %w", err)
+		}
+	}
+
+	for i := range c.Global.Logging {
+		var tls *TCPLoggingOutletTLS
+		switch v := c.Global.Logging[i].Ret.(type) {
+		case *SyslogLoggingOutlet:
+			tls = v.TLS
+		case *TCPLoggingOutlet:
+			tls = v.TLS
+		}
+		if tls == nil {
+			continue
+		} else if err := fn(&tls.Key, tls.KeyCmd, true); err != nil {
+			return fmt.Errorf("logging outlet %d: tls key: %w", i, err)
+		}
+	}
+
+	for i := range c.Global.Notifications {
+		var err error
+		switch v := c.Global.Notifications[i].Ret.(type) {
+		case *EmailNotification:
+			err = fn(&v.Password, v.PasswordCmd, false)
+		case *NtfyNotification:
+			err = fn(&v.Token, v.TokenCmd, false)
+		case *GotifyNotification:
+			err = fn(&v.Token, v.TokenCmd, false)
+		}
+		if err != nil {
+			return fmt.Errorf("notification %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// secretFromCmd sets secret to output of shell command cmd, without trailing
+// newlines. It does nothing if cmd is empty.
+func secretFromCmd(secret *string, cmd string) error {
+	if cmd == "" {
+		return nil
+	} else if *secret != "" {
+		return errors.New("both secret and command are configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "/bin/sh", "-c", cmd).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) != 0 {
+			return fmt.Errorf("command %q: %w: %s", cmd, err,
+				bytes.TrimSpace(exitErr.Stderr))
+		}
+		return fmt.Errorf("command %q: %w", cmd, err)
+	}
+
+	s := strings.TrimRight(string(out), "\r\n")
+	if s == "" {
+		return fmt.Errorf("command %q: empty output", cmd)
+	}
+	*secret = s
+	return nil
+}
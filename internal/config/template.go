@@ -0,0 +1,296 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.yaml.in/yaml/v4"
+
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+)
+
+// JobTemplate is a job, which jobs with from_template are built from. Its
+// scalars can have parameters, like ${server}, which are replaced by params of
+// the jobs. Name is the name of the template, not of the jobs.
+type JobTemplate struct {
+	Name string `yaml:"name" validate:"required"`
+
+	node *yaml.Node
+}
+
+var _ yaml.Unmarshaler = (*JobTemplate)(nil)
+
+func (self *JobTemplate) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: template must be a mapping", value.Line)
+	} else if name := mappingValue(value, "name"); name != nil {
+		self.Name = name.Value
+	}
+	self.node = value
+	return nil
+}
+
+func (self JobTemplate) MarshalYAML() (any, error) { return self.node, nil }
+
+func (self JobTemplate) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "object",
+		Description: "job with parameters like ${server}",
+		Properties: map[string]*jsonschema.Schema{
+			"name": {Type: "string"},
+		},
+		Required: []string{"name"},
+	}
+}
+
+// jobFromTemplate is a job, built from template FromTemplate with Params.
+type jobFromTemplate struct {
+	FromTemplate string            `yaml:"from_template"`
+	Params       map[string]string `yaml:"params"`
+}
+
+func (jobFromTemplate) YAMLSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"from_template": {Type: "string"},
+			"params": {
+				Type:                 "object",
+				AdditionalProperties: &jsonschema.Schema{Type: "string"},
+			},
+		},
+		Required: []string{"from_template"},
+	}
+}
+
+// templateParam matches parameters of templates, like ${server}. $${server} is
+// an escaped literal ${server}.
+var templateParam = regexp.MustCompile(`\$?\$\{(\w*)\}`)
+
+// resolveTemplates replaces jobs of config document root, which have
+// from_template, by jobs, built from templates of the config.
+func resolveTemplates(root *yaml.Node) error {
+	doc := documentMapping(root)
+	if doc == nil {
+		return nil
+	}
+
+	var list []JobTemplate
+	if node := mappingValue(doc, "templates"); node != nil {
+		if err := node.Decode(&list); err != nil {
+			return fmt.Errorf("templates: %w", err)
+		}
+	}
+
+	templates, err := jobTemplates(list)
+	if err != nil {
+		return err
+	}
+	return expandJobs(doc, templates)
+}
+
+// jobTemplates returns templates by their names.
+func jobTemplates(list []JobTemplate) (map[string]*JobTemplate, error) {
+	templates := make(map[string]*JobTemplate, len(list))
+	for i := range list {
+		t := &list[i]
+		if t.Name == "" {
+			return nil, fmt.Errorf("template #%d: name required", i+1)
+		} else if _, ok := templates[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate template name %q", t.Name)
+		}
+		templates[t.Name] = t
+	}
+	return templates, nil
+}
+
+// expandJobs replaces jobs of mapping doc, which have from_template, by jobs,
+// built from templates.
+func expandJobs(doc *yaml.Node, templates map[string]*JobTemplate) error {
+	jobs := mappingValue(doc, "jobs")
+	if jobs == nil || jobs.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	for i, job := range jobs.Content {
+		if job.Kind != yaml.MappingNode ||
+			mappingValue(job, "from_template") == nil {
+			continue
+		}
+
+		var ref jobFromTemplate
+		if err := job.Decode(&ref); err != nil {
+			return fmt.Errorf("job #%d: %w", i+1, err)
+		}
+
+		t, ok := templates[ref.FromTemplate]
+		if !ok {
+			return fmt.Errorf("job #%d: line %d: unknown template %q",
+				i+1, job.Line, ref.FromTemplate)
+		}
+
+		expanded, err := t.expand(job, ref.Params)
+		if err != nil {
+			return fmt.Errorf("job #%d: template %q: %w", i+1, t.Name, err)
+		}
+		jobs.Content[i] = expanded
+	}
+	return nil
+}
+
+// expand returns a copy of the template with params, which fields of job
+// override.
+func (self *JobTemplate) expand(job *yaml.Node, params map[string]string,
+) (*yaml.Node, error) {
+	node := copyNode(self.node)
+	used := make(map[string]struct{}, len(params))
+	if err := substituteParams(node, params, used); err != nil {
+		return nil, err
+	}
+
+	for name := range params {
+		if _, ok := used[name]; !ok {
+			return nil, fmt.Errorf("unused parameter %q", name)
+		}
+	}
+
+	content := make([]*yaml.Node, 0, len(node.Content)+len(job.Content))
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value != "name" {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+
+	for i := 0; i < len(job.Content); i += 2 {
+		key, value := job.Content[i], job.Content[i+1]
+		switch key.Value {
+		case "from_template", "params":
+			continue
+		}
+		if j := mappingIndex(content, key.Value); j >= 0 {
+			content[j+1] = value
+		} else {
+			content = append(content, key, value)
+		}
+	}
+	node.Content = content
+	return node, nil
+}
+
+// substituteParams replaces parameters in all scalars of node by params and
+// adds names of replaced parameters to used.
+func substituteParams(node *yaml.Node, params map[string]string,
+	used map[string]struct{},
+) error {
+	if node.Kind == yaml.ScalarNode {
+		var errs []error
+		s := templateParam.ReplaceAllStringFunc(node.Value, func(m string) string {
+			if m[1] == '$' {
+				return m[1:]
+			}
+			name := templateParam.FindStringSubmatch(m)[1]
+			v, ok := params[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("line %d: missing parameter %q",
+					node.Line, name))
+				return m
+			}
+			used[name] = struct{}{}
+			return v
+		})
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		} else if s != node.Value {
+			node.Value = s
+			if node.Style == 0 {
+				// Resolve type of plain scalar by its new value.
+				node.Tag = ""
+			}
+		}
+		return nil
+	}
+
+	for _, n := range node.Content {
+		if err := substituteParams(n, params, used); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, n := range node.Content {
+		c.Content[i] = copyNode(n)
+	}
+	return &c
+}
+
+// documentMapping returns top level mapping of document root, or nil.
+func documentMapping(root *yaml.Node) *yaml.Node {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// mappingValue returns value of key in mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(node.Content, key); i >= 0 {
+		return node.Content[i+1]
+	}
+	return nil
+}
+
+// mappingIndex returns index of key in content of mapping node, or -1.
+func mappingIndex(content []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// redactSecrets redacts passwords of DSNs and values of headers of hooks of
+// the template, like [Config.RedactSecrets] does for jobs.
+func (self *JobTemplate) redactSecrets() {
+	if self.node != nil {
+		self.node = copyNode(self.node)
+		redactNodeSecrets(self.node)
+	}
+}
+
+func redactNodeSecrets(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			switch node.Content[i].Value {
+			case "dsn":
+				if value.Kind == yaml.ScalarNode {
+					h := HookCommand{DSN: value.Value}
+					if typ := mappingValue(node, "type"); typ != nil {
+						h.Type = typ.Value
+					}
+					value.Value = h.RedactedDSN()
+				}
+			case "headers":
+				if value.Kind == yaml.MappingNode {
+					for j := 1; j < len(value.Content); j += 2 {
+						value.Content[j].Value = redactedSecret
+					}
+				}
+			}
+		}
+	}
+
+	for _, n := range node.Content {
+		redactNodeSecrets(n)
+	}
+}
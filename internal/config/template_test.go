@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const templatesConfig = `
+templates:
+  - name: "offsite"
+    type: "push"
+    connect:
+      type: "http"
+      server: "https://${host}:8888"
+      listener_name: "sink"
+      client_identity: "${host}"
+    filesystems:
+      "${pool}<": true
+    snapshotting:
+      type: "manual"
+    replication:
+      concurrency:
+        steps: ${steps}
+    hooks:
+      pre:
+        path: "/usr/local/bin/hook"
+        args: ["$${HOME}"]
+`
+
+func TestTemplates(t *testing.T) {
+	c := testValidConfig(t, templatesConfig+`
+jobs:
+  - name: "foo"
+    from_template: "offsite"
+    params:
+      host: "a.example.com"
+      pool: "zroot"
+      steps: "2"
+  - name: "bar"
+    from_template: "offsite"
+    params:
+      host: "b.example.com"
+      pool: "tank"
+      steps: "1"
+    filesystems:
+      "tank/data<": true
+`)
+
+	require.Len(t, c.Templates, 1)
+	assert.Equal(t, "offsite", c.Templates[0].Name)
+	require.Len(t, c.Jobs, 2)
+
+	foo, ok := c.Jobs[0].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, "foo", foo.Name)
+	assert.Equal(t, "https://a.example.com:8888", foo.Connect.Server)
+	assert.Equal(t, "a.example.com", foo.Connect.ClientIdentity)
+	assert.Equal(t, FilesystemsFilter{"zroot<": true}, foo.Filesystems)
+	assert.Equal(t, 2, foo.Replication.Concurrency.Steps)
+	require.NotNil(t, foo.Hooks.Pre)
+	assert.Equal(t, []string{"${HOME}"}, foo.Hooks.Pre.Args)
+
+	bar, ok := c.Jobs[1].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, "bar", bar.Name)
+	assert.Equal(t, "https://b.example.com:8888", bar.Connect.Server)
+	assert.Equal(t, FilesystemsFilter{"tank/data<": true}, bar.Filesystems)
+	assert.Equal(t, 1, bar.Replication.Concurrency.Steps)
+}
+
+func TestTemplates_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		job  string
+		err  string
+	}{
+		{
+			name: "unknown template",
+			job:  `{name: "foo", from_template: "onsite"}`,
+			err:  `unknown template "onsite"`,
+		},
+		{
+			name: "missing parameter",
+			job: `{name: "foo", from_template: "offsite",
+              params: {host: "a", pool: "zroot"}}`,
+			err: `missing parameter "steps"`,
+		},
+		{
+			name: "unused parameter",
+			job: `{name: "foo", from_template: "offsite",
+              params: {host: "a", pool: "zroot", steps: "1", port: "22"}}`,
+			err: `unused parameter "port"`,
+		},
+		{
+			name: "invalid job",
+			job: `{name: "foo", from_template: "offsite",
+              params: {host: "a", pool: "zroot", steps: "0"}}`,
+			err: "steps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testConfig(t, templatesConfig+"jobs: ["+tt.job+"]\n")
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	_, err := testConfig(t, templatesConfig+`
+  - name: "offsite"
+    type: "snap"
+jobs: [{name: "foo", from_template: "offsite"}]
+`)
+	require.ErrorContains(t, err, `duplicate template name "offsite"`)
+}
+
+func TestTemplates_include(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zrepl.yml")
+	require.NoError(t, os.WriteFile(path, []byte(templatesConfig+`
+include: "conf.d/*.yml"
+jobs:
+  - name: "snap"
+    type: "snap"
+    filesystems:
+      "<": true
+    snapshotting:
+      type: "manual"
+`), 0o600))
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf.d"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "foo.yml"),
+		[]byte(`
+jobs:
+  - name: "foo"
+    from_template: "offsite"
+    params: {host: "a.example.com", pool: "zroot", steps: "1"}
+`), 0o600))
+
+	c, err := ParseConfig(path)
+	require.NoError(t, err)
+	require.Len(t, c.Jobs, 2)
+	foo, ok := c.Jobs[1].Ret.(*PushJob)
+	require.True(t, ok)
+	assert.Equal(t, "https://a.example.com:8888", foo.Connect.Server)
+}
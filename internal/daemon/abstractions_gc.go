@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/endpoint"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+)
+
+func newAbstractionsGC(in *config.AbstractionsGC, jobNames func() []string,
+) *abstractionsGC {
+	return &abstractionsGC{
+		interval:    in.Interval,
+		concurrency: in.Concurrency,
+		jobNames:    jobNames,
+
+		promDestroyed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "zrepl",
+			Subsystem: "daemon",
+			Name:      "abstractions_gc_destroyed_total",
+			Help:      "number of stale step holds and bookmarks destroyed by abstractions gc",
+		}),
+		promErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "zrepl",
+			Subsystem: "daemon",
+			Name:      "abstractions_gc_errors_total",
+			Help:      "number of failed passes and destroys of abstractions gc",
+		}),
+	}
+}
+
+// abstractionsGC is an internal job, which periodically releases step holds
+// and destroys tentative replication cursor bookmarks, left by completed steps
+// or by jobs, which don't exist anymore.
+type abstractionsGC struct {
+	interval    time.Duration
+	concurrency int
+	jobNames    func() []string
+
+	mu     sync.Mutex
+	report AbstractionsGCReport
+
+	promDestroyed prometheus.Counter
+	promErrors    prometheus.Counter
+}
+
+// AbstractionsGCReport is the result of the last pass of abstractions gc.
+type AbstractionsGCReport struct {
+	Interval  time.Duration
+	LastRun   time.Time `json:",omitzero"`
+	Duration  time.Duration
+	NextRun   time.Time `json:",omitzero"`
+	Destroyed []string  `json:",omitempty"`
+	Errors    []string  `json:",omitempty"`
+}
+
+var _ job.Internal = (*abstractionsGC)(nil)
+
+func (self *abstractionsGC) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(self.promDestroyed, self.promErrors)
+}
+
+func (self *abstractionsGC) Run(ctx context.Context) error {
+	log := logging.GetLogger(ctx, logging.SubsysJob).With(
+		slog.Duration("interval", self.interval))
+	log.Info("start abstractions gc")
+
+	t := time.NewTicker(self.interval)
+	defer t.Stop()
+	self.setNextRun(time.Now().Add(self.interval))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		self.pass(ctx, log)
+		self.setNextRun(time.Now().Add(self.interval))
+	}
+}
+
+func (self *abstractionsGC) pass(ctx context.Context, log *slog.Logger) {
+	r := AbstractionsGCReport{Interval: self.interval, LastRun: time.Now()}
+	defer func() {
+		r.Duration = time.Since(r.LastRun)
+		self.mu.Lock()
+		self.report = r
+		self.mu.Unlock()
+	}()
+
+	stale, err := self.listStale(ctx, log)
+	if err != nil {
+		self.promErrors.Inc()
+		logger.WithError(log, err, "failed list stale abstractions")
+		r.Errors = append(r.Errors, err.Error())
+		return
+	}
+
+	for res := range endpoint.BatchDestroy(ctx, stale) {
+		l := log.With(slog.String("abstraction", res.Abstraction.String()))
+		if res.DestroyErr != nil {
+			self.promErrors.Inc()
+			logger.WithError(l, res.DestroyErr, "failed destroy stale abstraction")
+			r.Errors = append(r.Errors, res.DestroyErr.Error())
+			continue
+		}
+		self.promDestroyed.Inc()
+		l.Info("destroyed stale abstraction")
+		r.Destroyed = append(r.Destroyed, res.Abstraction.String())
+	}
+
+	log.With(
+		slog.Int("destroyed", len(r.Destroyed)),
+		slog.Int("errors", len(r.Errors)),
+	).Info("abstractions gc pass finished")
+}
+
+func (self *abstractionsGC) listStale(ctx context.Context, log *slog.Logger,
+) ([]endpoint.Abstraction, error) {
+	jobs, err := self.jobIDs()
+	if err != nil {
+		return nil, err
+	}
+	log.With(slog.Int("jobs", len(jobs))).Info("start abstractions gc pass")
+	return endpoint.ListStaleSteps(ctx, jobs, self.concurrency)
+}
+
+func (self *abstractionsGC) jobIDs() ([]endpoint.JobID, error) {
+	names := self.jobNames()
+	jobs := make([]endpoint.JobID, len(names))
+	for i, name := range names {
+		jobID, err := endpoint.MakeJobID(name)
+		if err != nil {
+			// abstractions of this job would look orphaned
+			return nil, fmt.Errorf("invalid job name %q: %w", name, err)
+		}
+		jobs[i] = jobID
+	}
+	return jobs, nil
+}
+
+func (self *abstractionsGC) setNextRun(t time.Time) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.report.Interval = self.interval
+	self.report.NextRun = t
+}
+
+// Report returns a copy of the report of the last pass.
+func (self *abstractionsGC) Report() *AbstractionsGCReport {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	r := self.report
+	return &r
+}
@@ -6,25 +6,45 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
 	"github.com/dsh2dsh/zrepl/internal/daemon/middleware"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
 	"github.com/dsh2dsh/zrepl/internal/version"
 	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
 const (
-	ControlJobEndpointSignal  = "/signal"
-	ControlJobEndpointStatus  = "/status"
-	ControlJobEndpointVersion = "/version"
+	ControlJobEndpointSignal = "/signal"
+	ControlJobEndpointStatus = "/status"
+	// ControlJobEndpointStatusWatch long-polls status: it responds when status
+	// differs from the version, the client already has, or on timeout.
+	ControlJobEndpointStatusWatch = "/status/watch"
+	ControlJobEndpointVersion     = "/version"
+	ControlJobEndpointVerify      = "/verify"
+	ControlJobEndpointSkip        = "/skip"
+	ControlJobEndpointBwlimit     = "/bwlimit"
+	ControlJobEndpointAdopt       = "/adopt"
+	ControlJobEndpointJob         = "/job"
+	// ControlJobEndpointPrune reports snapshots, which pruning of a job would
+	// destroy right now, without destroying them.
+	ControlJobEndpointPrune = "/prune"
+	// ControlJobEndpointSchema returns JSON Schemas of JSON documents, which are
+	// stable contract for tools consuming them.
+	ControlJobEndpointSchema = "/schema"
 )
 
 func newControlJob(jobs *jobs) *controlJob {
-	return &controlJob{jobs: jobs}
+	j := &controlJob{jobs: jobs}
+	j.watcher = newStatusWatcher(j.status)
+	return j
 }
 
 type controlJob struct {
-	jobs *jobs
+	jobs    *jobs
+	watcher *statusWatcher
 }
 
 func (j *controlJob) Endpoints(mux *http.ServeMux, m ...middleware.Middleware,
@@ -35,8 +55,32 @@ func (j *controlJob) Endpoints(mux *http.ServeMux, m ...middleware.Middleware,
 	mux.Handle(ControlJobEndpointStatus, middleware.Append(m,
 		middleware.JsonResponder(j.status)))
 
+	mux.Handle(ControlJobEndpointStatusWatch, middleware.Append(m,
+		middleware.JsonRequestResponder(j.watcher.watch)))
+
 	mux.Handle(ControlJobEndpointSignal, middleware.Append(m,
 		middleware.JsonRequestResponder(j.signal)))
+
+	mux.Handle(ControlJobEndpointVerify, middleware.Append(m,
+		middleware.JsonRequestResponder(j.verify)))
+
+	mux.Handle(ControlJobEndpointAdopt, middleware.Append(m,
+		middleware.JsonRequestResponder(j.adopt)))
+
+	mux.Handle(ControlJobEndpointSkip, middleware.Append(m,
+		middleware.JsonRequestResponder(j.skip)))
+
+	mux.Handle(ControlJobEndpointBwlimit, middleware.Append(m,
+		middleware.JsonRequestResponder(j.bwlimit)))
+
+	mux.Handle(ControlJobEndpointJob, middleware.Append(m,
+		middleware.JsonRequestResponder(j.job)))
+
+	mux.Handle(ControlJobEndpointPrune, middleware.Append(m,
+		middleware.JsonRequestResponder(j.prune)))
+
+	mux.Handle(ControlJobEndpointSchema, middleware.Append(m,
+		middleware.JsonResponder(j.schema)))
 }
 
 func (j *controlJob) version(_ context.Context) (
@@ -52,11 +96,121 @@ func (j *controlJob) status(_ context.Context) (*Status, error) {
 		Global: GlobalStatus{
 			ZFSCmds:   zfscmd.GetReport(),
 			OsEnviron: os.Environ(),
+
+			AbstractionsGC: j.jobs.abstractionsGCReport(),
 		},
 	}
 	return s, nil
 }
 
+type verifyRequest struct {
+	Name string
+}
+
+func (j *controlJob) verify(ctx context.Context, req *verifyRequest,
+) (*report.VerifyReport, error) {
+	logging.FromContext(ctx).With(slog.String("name", req.Name)).
+		Info("verify job")
+	return j.jobs.verify(ctx, req.Name)
+}
+
+type adoptRequest struct {
+	Name   string
+	DryRun bool
+}
+
+func (j *controlJob) adopt(ctx context.Context, req *adoptRequest,
+) (*report.VerifyReport, error) {
+	logging.FromContext(ctx).With(
+		slog.String("name", req.Name),
+		slog.Bool("dry_run", req.DryRun),
+	).Info("adopt filesystems")
+	return j.jobs.adopt(ctx, req.Name, req.DryRun)
+}
+
+type pruneRequest struct {
+	Name string
+}
+
+func (j *controlJob) prune(ctx context.Context, req *pruneRequest,
+) (*[]*pruner.DryRunReport, error) {
+	logging.FromContext(ctx).With(slog.String("name", req.Name)).
+		Info("prune dry run")
+	sides, err := j.jobs.pruneDryRun(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &sides, nil
+}
+
+type skipRequest struct {
+	Name       string
+	Filesystem string
+	For        time.Duration
+}
+
+func (j *controlJob) skip(ctx context.Context, req *skipRequest,
+) (*struct{}, error) {
+	logging.FromContext(ctx).With(
+		slog.String("name", req.Name),
+		slog.String("filesystem", req.Filesystem),
+		slog.Duration("for", req.For),
+	).Info("skip filesystem")
+	return nil, j.jobs.skip(req.Name, req.Filesystem, req.For)
+}
+
+type bwlimitRequest struct {
+	Name  string
+	Set   bool
+	Limit uint64
+}
+
+type bwlimitResponse struct {
+	Limit uint64
+}
+
+func (j *controlJob) bwlimit(ctx context.Context, req *bwlimitRequest,
+) (*bwlimitResponse, error) {
+	if req.Set {
+		logging.FromContext(ctx).With(
+			slog.String("name", req.Name),
+			slog.Uint64("limit", req.Limit),
+		).Info("set bandwidth limit")
+	}
+	limit, err := j.jobs.bwlimit(req.Name, req.Set, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &bwlimitResponse{Limit: limit}, nil
+}
+
+type jobRequest struct {
+	Op   string
+	Name string
+	Stop bool
+}
+
+func (j *controlJob) job(ctx context.Context, req *jobRequest,
+) (*struct{}, error) {
+	logging.FromContext(ctx).With(
+		slog.String("op", req.Op),
+		slog.String("name", req.Name),
+		slog.Bool("stop", req.Stop),
+	).Info("got job request")
+
+	switch req.Op {
+	case "enable":
+		return nil, j.jobs.enable(req.Name, true)
+	case "disable":
+		return nil, j.jobs.enable(req.Name, false)
+	case "pause":
+		return nil, j.jobs.pause(req.Name, req.Stop)
+	case "resume":
+		return nil, j.jobs.resume(req.Name)
+	}
+	return nil, fmt.Errorf("invalid operation %q", req.Op)
+}
+
 type signalRequest struct {
 	Op   string
 	Name string
@@ -11,11 +11,20 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/config"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job"
 	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/daemon/notify"
+	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
 	"github.com/dsh2dsh/zrepl/internal/logger"
 	"github.com/dsh2dsh/zrepl/internal/version"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
-func Run(ctx context.Context, conf *config.Config) error {
+// Run starts the daemon with conf. On SIGHUP or reload signal it reloads
+// config using loadConfig.
+func Run(ctx context.Context, conf *config.Config,
+	loadConfig func() (*config.Config, error),
+) error {
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -28,19 +37,50 @@ func Run(ctx context.Context, conf *config.Config) error {
 	if err != nil {
 		return fmt.Errorf("daemon: cannot build jobs from config: %w", err)
 	}
+	for i := range conf.Jobs {
+		j := &conf.Jobs[i]
+		zfscmd.SetJobEnv(j.Name(), j.ZfsEnv())
+		if err := audit.OpenJob(conf.Global.AuditDir, j.Name()); err != nil {
+			return fmt.Errorf("daemon: job %q: %w", j.Name(), err)
+		}
+	}
+	if err := lifecycle.SetDir(conf.Global.LifecycleDir); err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
 
 	log := logger.NewLogger(outlets)
 	slog.SetDefault(log)
 	log.Info(version.NewZreplVersionInformation().String())
 	ctx = logging.WithLogger(ctx, log)
+	applyResources(log, &conf.Global.Resources)
+	applyParallel(log, &conf.Global.Parallel)
 
 	log.Info("starting daemon")
-	jobs := newJobs(ctx, cancel)
+	state, err := loadDaemonState(conf.Global.StateFile)
+	if err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+	snapper.SetEpochs(state)
+
+	notifiers, err := notify.FromConfig(conf.Global.Notifications)
+	if err != nil {
+		return fmt.Errorf("daemon: cannot build notifications: %w", err)
+	}
+
+	jobs := newJobs(ctx, cancel).WithState(state).WithNotify(notifiers)
 	// start regular jobs
 	jobs.startCronJobs(confJobs)
+	jobs.watchNotify(confJobs)
+	if gc := &conf.Global.AbstractionsGC; gc.Interval > 0 {
+		jobs.startAbstractionsGC(gc)
+	}
 	if err := startServer(ctx, conf, jobs, outlets, connector); err != nil {
 		return fmt.Errorf("daemon: %w", err)
 	}
+	if w := &conf.Global.Resources.RSSWatchdog; w.Limit > 0 {
+		jobs.startInternal(newRSSWatchdog(w))
+	}
+	jobs.OnReload(newConfigReloader(jobs, conf, connector, loadConfig).Reload)
 
 	waitDone(ctx, jobs)
 	return nil
@@ -52,7 +92,10 @@ func startServer(ctx context.Context, conf *config.Config, jobs *jobs,
 	log := logging.FromContext(ctx)
 	server := newServerJob(log,
 		newControlJob(jobs),
-		newZfsJob(connecter, conf.Keys).WithTimeout(conf.Global.RpcTimeout))
+		newZfsJob(connecter, conf.Keys).WithTimeout(conf.Global.RpcTimeout).
+			WithStreamTimeouts(conf.Global.RPC.ReadTimeout,
+				conf.Global.RPC.WriteTimeout)).
+		WithKeys(conf.Keys)
 
 	var hasControl, hasMetrics bool
 	for i := range conf.Listen {
@@ -68,6 +111,10 @@ func startServer(ctx context.Context, conf *config.Config, jobs *jobs,
 		return err
 	}
 
+	if err := globalHTTP(server, conf); err != nil {
+		return err
+	}
+
 	if has, err := defaultMetrics(hasMetrics, server, conf); err != nil {
 		return err
 	} else if has {
@@ -100,6 +147,25 @@ func defaultControl(exists bool, api *serverJob, conf *config.Config) error {
 	return nil
 }
 
+func globalHTTP(api *serverJob, conf *config.Config) error {
+	c := conf.Global.HTTP
+	if c == nil {
+		return nil
+	}
+
+	listen := config.Listen{
+		Addr:    c.Listen,
+		TLSCert: c.TLSCert,
+		TLSKey:  c.TLSKey,
+		Web:     true,
+	}
+
+	if err := api.AddServer(&listen); err != nil {
+		return fmt.Errorf("add web server from global.http: %w", err)
+	}
+	return nil
+}
+
 func defaultMetrics(exists bool, api *serverJob, conf *config.Config,
 ) (bool, error) {
 	if exists {
@@ -4,6 +4,8 @@ import (
 	"cmp"
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"regexp/syntax"
 	"strings"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
@@ -16,6 +18,7 @@ func NewItem(in config.DatasetFilter) (*filterItem, error) {
 		mapping:      !in.Exclude,
 		recursive:    in.Recursive,
 		shellPattern: in.Shell,
+		regexPattern: in.Regex,
 	}
 	if err := item.init(); err != nil {
 		return nil, err
@@ -29,7 +32,11 @@ type filterItem struct {
 	recursive bool
 
 	shellPattern bool
-	shellPath    *zfs.DatasetPath
+	regexPattern bool
+	regex        *regexp.Regexp
+	// basePath is a dataset, which all datasets matching shell pattern or regex
+	// are below.
+	basePath *zfs.DatasetPath
 
 	path *zfs.DatasetPath
 }
@@ -37,6 +44,8 @@ type filterItem struct {
 func (self *filterItem) init() error {
 	if self.shellPattern {
 		return self.initShellPattern()
+	} else if self.regexPattern {
+		return self.initRegex()
 	} else if self.path != nil {
 		return nil
 	}
@@ -70,10 +79,54 @@ func (self *filterItem) initShellPattern() error {
 			"failed extract dataset path %q from shell pattern %q: %w",
 			base, self.pattern, err)
 	}
-	self.shellPath = path
+	self.basePath = path
 	return nil
 }
 
+func (self *filterItem) initRegex() error {
+	re, err := regexp.Compile(self.pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %w", self.pattern, err)
+	}
+	self.regex = re
+
+	base := regexBaseDir(self.pattern)
+	if base == "" {
+		return nil
+	}
+
+	path, err := zfs.NewDatasetPath(base)
+	if err != nil {
+		return fmt.Errorf(
+			"failed extract dataset path %q from regex %q: %w",
+			base, self.pattern, err)
+	}
+	self.basePath = path
+	return nil
+}
+
+// regexBaseDir returns parent dataset of literal prefix of regex pattern,
+// anchored by ^, like "tank/vm" of "^tank/vm/(prod|staging)/".
+func regexBaseDir(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat || len(re.Sub) < 2 {
+		return ""
+	}
+
+	begin, literal := re.Sub[0], re.Sub[1]
+	if begin.Op != syntax.OpBeginText || literal.Op != syntax.OpLiteral ||
+		literal.Flags&syntax.FoldCase != 0 {
+		return ""
+	}
+
+	prefix := string(literal.Rune)
+	n := strings.LastIndex(prefix, "/")
+	if n < 0 {
+		return ""
+	}
+	return prefix[:n]
+}
+
 func shellBaseDir(pattern string) string {
 	n := strings.IndexAny(pattern, `*?[\`)
 	if n < 0 {
@@ -102,6 +155,11 @@ func (self *filterItem) DatasetPath() *zfs.DatasetPath { return self.path }
 
 func (self *filterItem) Mapping() bool { return self.mapping }
 
+// Pattern returns true, if the item is a shell pattern or a regex.
+func (self *filterItem) Pattern() bool {
+	return self.shellPattern || self.regexPattern
+}
+
 func (self *filterItem) Clone() *filterItem {
 	cloned := *self
 	return &cloned
@@ -116,16 +174,21 @@ func (self *filterItem) Match(p *zfs.DatasetPath) (bool, error) {
 				p.ToString(), self.pattern, err)
 		}
 		return matched, nil
+	case self.regex != nil:
+		return self.regex.MatchString(p.ToString()), nil
 	case self.recursive:
 		return p.HasPrefix(self.path), nil
 	}
 	return self.path.Equal(p), nil
 }
 
+// CompatCompare orders entries of filesystems map from less specific to more
+// specific, because the last matching entry wins. Regex entries go after all
+// other entries, in lexical order of their patterns.
 func (self *filterItem) CompatCompare(b *filterItem) int {
 	switch {
 	case self.path == nil && b.path == nil:
-		return 0
+		return strings.Compare(self.pattern, b.pattern)
 	case self.path != nil && b.path == nil:
 		return -1
 	case self.path == nil && b.path != nil:
@@ -153,11 +216,11 @@ func (self *filterItem) ParentFilesystem() *zfs.DatasetPath {
 	}
 
 	path := self.path
-	if self.shellPattern {
-		path = self.shellPath
+	if self.shellPattern || self.regexPattern {
+		path = self.basePath
 	}
 
-	if path.Empty() {
+	if path == nil || path.Empty() {
 		return nil
 	}
 	return path
@@ -165,8 +228,8 @@ func (self *filterItem) ParentFilesystem() *zfs.DatasetPath {
 
 func (self *filterItem) HasPrefix(prefix *zfs.DatasetPath) bool {
 	switch {
-	case self.shellPath != nil:
-		return self.shellPath.HasPrefix(prefix)
+	case self.basePath != nil:
+		return self.basePath.HasPrefix(prefix)
 	case self.path != nil:
 		return self.path.HasPrefix(prefix)
 	}
@@ -59,6 +59,28 @@ func Test_shellBaseDir(t *testing.T) {
 	}
 }
 
+func Test_regexBaseDir(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "^tank/vm/(prod|staging)/", want: "tank/vm"},
+		{pattern: "^tank/vm", want: "tank"},
+		{pattern: "^tank/vm/prod$", want: "tank/vm"},
+		{pattern: "^tank"},
+		{pattern: "tank/vm/"},
+		{pattern: "^(tank|zroot)/vm/"},
+		{pattern: "(?i)^tank/vm/"},
+		{pattern: "^tank/vm/.*/cache$", want: "tank/vm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, regexBaseDir(tt.pattern))
+		})
+	}
+}
+
 func Test_filterItem_ParentFilesystem(t *testing.T) {
 	tests := []struct {
 		name string
@@ -3,6 +3,7 @@ package filters
 import (
 	"fmt"
 	"iter"
+	"maps"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -14,6 +15,10 @@ import (
 const (
 	filterResultOk   = "ok"
 	filterResultOmit = "!"
+
+	// regexPrefix marks patterns of filesystems, which are regular
+	// expressions, like "~ ^tank/vm/(prod|staging)/".
+	regexPrefix = "~"
 )
 
 func NoFilter() (*DatasetFilter, error) {
@@ -43,6 +48,9 @@ func New(size int) *DatasetFilter {
 type DatasetFilter struct {
 	entries     []*filterItem
 	parentPaths []*zfs.DatasetPath
+	// unbounded is true, if an entry can match datasets below any pool, like
+	// a regex without literal prefix.
+	unbounded bool
 }
 
 func (self *DatasetFilter) AddList(in []config.DatasetFilter) error {
@@ -70,11 +78,16 @@ func (self *DatasetFilter) appendParent(e *filterItem) {
 
 	if p := e.ParentFilesystem(); p != nil {
 		self.parentPaths = append(self.parentPaths, p)
+	} else if e.Mapping() && e.Pattern() {
+		self.unbounded = true
 	}
 }
 
 func (self *DatasetFilter) addMap(in map[string]bool) error {
-	for pathPattern, accept := range in {
+	// sorted keys keep order of entries, which CompatSort compares as equal,
+	// the same from run to run.
+	for _, pathPattern := range slices.Sorted(maps.Keys(in)) {
+		accept := in[pathPattern]
 		if err := self.addCompat(pathPattern, accept); err != nil {
 			return fmt.Errorf(
 				"invalid mapping entry [%q: %v]: %w", pathPattern, accept, err)
@@ -85,6 +98,19 @@ func (self *DatasetFilter) addMap(in map[string]bool) error {
 }
 
 func (self *DatasetFilter) addCompat(pathPattern string, mapping bool) error {
+	if re, ok := strings.CutPrefix(pathPattern, regexPrefix); ok {
+		entry, err := NewItem(config.DatasetFilter{
+			Pattern: strings.TrimSpace(re),
+			Exclude: !mapping,
+			Regex:   true,
+		})
+		if err != nil {
+			return err
+		}
+		self.append(entry)
+		return nil
+	}
+
 	// assert path glob adheres to spec
 	const subTreeSep = "<"
 	pathStr, pattern, found := strings.Cut(pathPattern, subTreeSep)
@@ -142,6 +168,7 @@ func (self *DatasetFilter) CompatSort() {
 
 	clear(self.parentPaths)
 	self.parentPaths = self.parentPaths[:0]
+	self.unbounded = false
 	for _, e := range self.entries {
 		self.appendParent(e)
 	}
@@ -191,6 +218,10 @@ func (self *DatasetFilter) SingleRecursiveDataset() *zfs.DatasetPath {
 }
 
 func (self *DatasetFilter) TopFilesystems() (int, iter.Seq[string]) {
+	if self.unbounded {
+		return 0, func(yield func(string) bool) {}
+	}
+
 	fn := func(yield func(string) bool) {
 		for _, p := range self.parentPaths {
 			if !yield(p.ToString()) {
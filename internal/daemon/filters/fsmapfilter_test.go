@@ -125,6 +125,41 @@ func TestDatasetFilter_Filter_filesystems(t *testing.T) {
 				"test/app/2/cache": "test/app",
 			},
 		},
+		{
+			name: "with regex",
+			filesystems: config.FilesystemsFilter{
+				"tank/vm<":                      false,
+				"~ ^tank/vm/(prod|staging)/":    true,
+				"~ ^tank/vm/[^/]+/[^/]+/cache$": false,
+			},
+			topPaths: []string{"tank/vm"},
+			checkPass: map[string]bool{
+				"tank/vm":                  false,
+				"tank/vm/prod":             false,
+				"tank/vm/prod/db":          true,
+				"tank/vm/staging/web":      true,
+				"tank/vm/staging/web/data": true,
+				"tank/vm/dev/db":           false,
+				"tank/vm/prod/db/cache":    false,
+			},
+		},
+		{
+			name: "unanchored regex",
+			filesystems: config.FilesystemsFilter{
+				"tank/vm<": true,
+				"~ /cache": true,
+			},
+			checkPass: map[string]bool{
+				"tank/vm/db":    true,
+				"zroot/cache":   true,
+				"zroot/foo":     false,
+				"tank/vm/cache": true,
+			},
+			recursiveRoot: map[string]string{
+				"tank/vm/db":    "tank/vm",
+				"tank/vm/cache": "tank/vm",
+			},
+		},
 		{
 			name:        "match all",
 			filesystems: config.FilesystemsFilter{"<": true},
@@ -177,6 +212,56 @@ func TestDatasetFilter_Filter_filesystems(t *testing.T) {
 	}
 }
 
+func TestDatasetFilter_Filter_conflictingRegex(t *testing.T) {
+	tests := []struct {
+		name        string
+		filesystems config.FilesystemsFilter
+		checkPass   map[string]bool
+	}{
+		{
+			name: "more specific excludes",
+			filesystems: config.FilesystemsFilter{
+				"~ ^tank/":    true,
+				"~ ^tank/tmp": false,
+			},
+			checkPass: map[string]bool{
+				"tank/a":     true,
+				"tank/tmp":   false,
+				"tank/tmp/a": false,
+			},
+		},
+		{
+			name: "more specific includes",
+			filesystems: config.FilesystemsFilter{
+				"~ ^tank/":    false,
+				"~ ^tank/tmp": true,
+			},
+			checkPass: map[string]bool{
+				"tank/a":     false,
+				"tank/tmp":   true,
+				"tank/tmp/a": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// iteration order of maps is random, so results must not depend on it
+			for range 20 {
+				f, err := NewFromConfig(tt.filesystems, nil)
+				require.NoError(t, err)
+				for p, want := range tt.checkPass {
+					dp, err := zfs.NewDatasetPath(p)
+					require.NoError(t, err)
+					pass, err := f.Filter(dp)
+					require.NoError(t, err)
+					assert.Equal(t, want, pass, p)
+				}
+			}
+		})
+	}
+}
+
 func TestNoFilter(t *testing.T) {
 	f, err := NoFilter()
 	require.NoError(t, err)
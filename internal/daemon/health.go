@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dsh2dsh/cron/v3"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/middleware"
+)
+
+const (
+	// EndpointHealthz reports liveness of the daemon: it's running and its
+	// scheduler isn't stuck.
+	EndpointHealthz = "/healthz"
+	// EndpointReadyz reports readiness of the daemon: it's alive, started all
+	// jobs, isn't stopping and none of jobs is in fatal state.
+	EndpointReadyz = "/readyz"
+)
+
+const (
+	// healthCronLag is how long a cron entry may stay overdue, before the
+	// scheduler is considered stuck.
+	healthCronLag = time.Minute
+	// healthCronTimeout is how long to wait for entries of the scheduler, before
+	// it's considered stuck.
+	healthCronTimeout = 5 * time.Second
+)
+
+// HealthStatus is the response of health endpoints. Checks contains failed
+// checks with their reasons.
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (j *controlJob) healthEndpoints(mux *http.ServeMux,
+	m ...middleware.Middleware,
+) {
+	mux.Handle(EndpointHealthz, middleware.AppendHandler(m,
+		healthHandler(j.jobs.liveness)))
+	mux.Handle(EndpointReadyz, middleware.AppendHandler(m,
+		healthHandler(j.jobs.readiness)))
+}
+
+// healthHandler responds with 200 OK, if check finds no problems, or with 503
+// Service Unavailable and found problems.
+func healthHandler(check func(now time.Time) map[string]string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := HealthStatus{Status: "ok", Checks: check(time.Now())}
+		code := http.StatusOK
+		if len(s.Checks) > 0 {
+			s.Status, code = "fail", http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&s)
+	}
+}
+
+// liveness returns problems, which make the daemon not alive: it's stopping,
+// or its scheduler is stuck.
+func (self *jobs) liveness(now time.Time) map[string]string {
+	problems := make(map[string]string)
+	if self.ctx.Err() != nil {
+		problems["daemon"] = "stopping: " + context.Cause(self.ctx).Error()
+		return problems
+	} else if self.graceful.Err() != nil {
+		// cron already stopped and its entries aren't updated anymore.
+		return problems
+	}
+
+	entries, ok := self.cronEntries(healthCronTimeout)
+	if !ok {
+		problems["scheduler"] = fmt.Sprintf("cron doesn't respond in %s",
+			healthCronTimeout)
+		return problems
+	}
+
+	for _, e := range entries {
+		if e.Next.IsZero() {
+			continue
+		} else if lag := now.Sub(e.Next); lag > healthCronLag {
+			problems["scheduler"] = fmt.Sprintf("cron is %s behind",
+				lag.Truncate(time.Second))
+			break
+		}
+	}
+	return problems
+}
+
+// cronEntries returns entries of the scheduler and true, or false if the
+// scheduler doesn't respond in timeout.
+func (self *jobs) cronEntries(timeout time.Duration) ([]cron.Entry, bool) {
+	ch := make(chan []cron.Entry, 1)
+	go func() { ch <- self.cron.Entries() }()
+	select {
+	case entries := <-ch:
+		return entries, true
+	case <-time.After(timeout):
+		return nil, false
+	}
+}
+
+// readiness returns problems of liveness and problems, which make the daemon
+// not ready: it hasn't started jobs yet, it's stopping gracefully, or some
+// jobs are in fatal state.
+func (self *jobs) readiness(now time.Time) map[string]string {
+	problems := self.liveness(now)
+	if _, ok := problems["daemon"]; !ok {
+		switch {
+		case self.graceful.Err() != nil:
+			problems["daemon"] = "stopping gracefully"
+		case !self.started.Load():
+			problems["daemon"] = "starting"
+		}
+	}
+
+	self.jobsMu.RLock()
+	defer self.jobsMu.RUnlock()
+	for name, p := range self.jobs {
+		if err := p.Fatal(); err != nil {
+			problems["job "+name] = err.Error()
+		}
+	}
+	return problems
+}
@@ -0,0 +1,32 @@
+//go:build freebsd
+
+package hooks
+
+import (
+	"fmt"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+// UFSSnapshotName is the name of UFS snapshot created by fsfreeze hook inside
+// .snap directory of the mountpoint.
+const UFSSnapshotName = "zrepl"
+
+// FreeBSD can't freeze a filesystem, so fsfreeze hook creates a UFS snapshot
+// instead, replacing the previous one. The zvol snapshot contains this
+// consistent UFS snapshot, which can be mounted with mdconfig(8) after restore.
+// Nothing to thaw.
+func freezeCommands(hookType, mountpoint string) (freeze, thaw []string,
+	err error,
+) {
+	switch hookType {
+	case config.HookTypeFsfreeze:
+		freeze = []string{
+			"/bin/sh", "-c", `rm -f "$1" && mksnap_ffs "$1"`, "fsfreeze",
+			mountpoint + "/.snap/" + UFSSnapshotName,
+		}
+	default:
+		err = fmt.Errorf("unsupported freeze hook type: %q", hookType)
+	}
+	return freeze, thaw, err
+}
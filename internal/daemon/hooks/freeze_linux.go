@@ -0,0 +1,25 @@
+//go:build linux
+
+package hooks
+
+import (
+	"fmt"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func freezeCommands(hookType, mountpoint string) (freeze, thaw []string,
+	err error,
+) {
+	switch hookType {
+	case config.HookTypeFsfreeze:
+		freeze = []string{"fsfreeze", "--freeze", mountpoint}
+		thaw = []string{"fsfreeze", "--unfreeze", mountpoint}
+	case config.HookTypeXfsFreeze:
+		freeze = []string{"xfs_freeze", "-f", mountpoint}
+		thaw = []string{"xfs_freeze", "-u", mountpoint}
+	default:
+		err = fmt.Errorf("unsupported freeze hook type: %q", hookType)
+	}
+	return freeze, thaw, err
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !freebsd
+
+package hooks
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func freezeCommands(hookType, mountpoint string) (freeze, thaw []string,
+	err error,
+) {
+	return nil, nil, fmt.Errorf("%s hook: unsupported platform %q", hookType,
+		runtime.GOOS)
+}
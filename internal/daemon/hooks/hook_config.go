@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
 func ListFromConfig(in []config.HookCommand) (List, error) {
 	hl := make(List, len(in))
-	for i, h := range in {
-		h, err := NewCommandHook(&h)
+	for i := range in {
+		h, err := newFromConfig(&in[i])
 		if err != nil {
 			return nil, fmt.Errorf("create hook #%d: %w", i+1, err)
 		}
@@ -19,13 +20,39 @@ func ListFromConfig(in []config.HookCommand) (List, error) {
 	return hl, nil
 }
 
-type List []*CommandHook
+func newFromConfig(in *config.HookCommand) (FilteredHook, error) {
+	switch in.Type {
+	case config.HookTypeFsfreeze, config.HookTypeXfsFreeze:
+		return NewFreezeHook(in)
+	case config.HookTypeMySQLLockTables:
+		return NewMySQLLockHook(in)
+	case config.HookTypePostgres:
+		return NewPostgresHook(in)
+	case config.HookTypeWebhook:
+		return NewWebhookHook(in)
+	case config.HookTypeQemuFreeze:
+		return NewQemuFreezeHook(in)
+	default:
+		return NewCommandHook(in)
+	}
+}
+
+// FilteredHook is a [Hook], which runs for filesystems matched by its filter
+// only.
+type FilteredHook interface {
+	Hook
+	Filesystems() *filters.DatasetFilter
+}
+
+type List []FilteredHook
 
-func (self List) Slice() []*CommandHook { return []*CommandHook(self) }
+func (self List) Slice() []FilteredHook { return []FilteredHook(self) }
 
 func (self List) WithCombinedOutput() List {
 	for _, h := range self {
-		h.WithCombinedOutput()
+		if h, ok := h.(*CommandHook); ok {
+			h.WithCombinedOutput()
+		}
 	}
 	return self
 }
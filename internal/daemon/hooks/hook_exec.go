@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -147,6 +149,17 @@ func NewPlan(hooks List, phase Phase, cb *CallbackHook, extra map[string]string,
 	return plan, nil
 }
 
+// hookEnv returns extra env of the plan with timing of the phase, which started
+// at started, for a hook, which starts at now.
+func (p *Plan) hookEnv(started, now time.Time) map[string]string {
+	env := make(map[string]string, len(p.env)+2)
+	maps.Copy(env, p.env)
+	env[EnvPhaseStart] = strconv.FormatInt(started.Unix(), 10)
+	env[EnvPhaseElapsed] = strconv.FormatFloat(now.Sub(started).Seconds(), 'f',
+		3, 64)
+	return env
+}
+
 type PlanReport []Step
 
 func (p *Plan) Report() PlanReport {
@@ -197,10 +210,11 @@ func (p *Plan) Run(ctx context.Context, dryRun bool) {
 		f()
 	}
 
+	started := time.Now()
 	runHook := func(s *Step, ctx context.Context, edge Edge) HookReport {
 		w(func() { s.Status = StepExec })
 		begin := time.Now()
-		r := s.Hook.Run(ctx, edge, p.phase, dryRun, p.env)
+		r := s.Hook.Run(ctx, edge, p.phase, dryRun, p.hookEnv(started, begin))
 		end := time.Now()
 		w(func() {
 			s.Report = r
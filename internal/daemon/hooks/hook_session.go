@@ -0,0 +1,209 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+)
+
+// sharedSession is a session of SQL client, shared by concurrent snapshots.
+// It's started by the first of them and closed after the last one, or after
+// safety timeout, even if the post edge never runs or hangs.
+type sharedSession struct {
+	name    string
+	timeout time.Duration
+	start   func(ctx context.Context) (*sqlSession, error)
+	// stop are statements, which close the session.
+	stop string
+
+	mu      sync.Mutex
+	session *sqlSession
+	refs    int
+	timer   *time.Timer
+}
+
+// acquire starts the session, if it isn't started yet by concurrent snapshots.
+func (self *sharedSession) acquire(ctx context.Context) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.refs > 0 {
+		self.refs++
+		return nil
+	}
+
+	getLogger(ctx).Info("\"" + self.name + "\"")
+	s, err := self.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	self.session, self.refs = s, 1
+	closeCtx := context.WithoutCancel(ctx)
+	var timer *time.Timer
+	timer = time.AfterFunc(self.timeout, func() { self.expire(closeCtx, timer) })
+	self.timer = timer
+	return nil
+}
+
+// release closes the session after the last concurrent snapshot.
+func (self *sharedSession) release() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.refs == 0 {
+		return fmt.Errorf("already done after safety timeout %s", self.timeout)
+	} else if self.refs--; self.refs > 0 {
+		return nil
+	}
+
+	self.timer.Stop()
+	s := self.session
+	self.session, self.timer = nil, nil
+	return s.Close(self.stop, self.timeout)
+}
+
+func (self *sharedSession) expire(ctx context.Context, timer *time.Timer) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.timer != timer {
+		return
+	}
+	s := self.session
+	self.session, self.refs, self.timer = nil, 0, nil
+
+	l := getLogger(ctx).With(slog.String("hook", self.name))
+	l.With(slog.Duration("timeout", self.timeout)).
+		Warn("close session after safety timeout")
+	if err := s.Close(self.stop, self.timeout); err != nil {
+		logger.WithError(l, err, "close session after safety timeout")
+	}
+}
+
+// sqlSession is a running SQL client, like mysql or psql, which keeps its
+// connection to the database until its stdin closed.
+type sqlSession struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+// startSQLSession starts SQL client path with args and env added to
+// environment, writes statements to it and waits until it outputs mark, but no
+// longer than timeout.
+func startSQLSession(ctx context.Context, path string, args, env []string,
+	statements, mark string, timeout time.Duration,
+) (*sqlSession, error) {
+	// Not CommandContext, because the session outlives ctx of the pre edge.
+	s := &sqlSession{name: filepath.Base(path), cmd: exec.Command(path, args...)}
+	if len(env) != 0 {
+		s.cmd.Env = append(os.Environ(), env...)
+	}
+	s.cmd.Stderr = &s.stderr
+
+	stdin, err := s.cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin of %q: %w", path, err)
+	}
+	s.stdin = stdin
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout of %q: %w", path, err)
+	} else if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %q: %w", path, err)
+	}
+
+	marked := make(chan error, 1)
+	go func() {
+		marked <- s.waitMark(stdout, mark)
+		// The client must not block on writing to stdout.
+		_, _ = io.Copy(io.Discard, stdout)
+	}()
+
+	if _, err = io.WriteString(stdin, statements); err != nil {
+		return nil, s.kill(fmt.Errorf("write to %q: %w", path, err))
+	}
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err = <-marked:
+	case <-t.C:
+		err = fmt.Errorf("timed out after %s", timeout)
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+	if err != nil {
+		return nil, s.kill(err)
+	}
+	return s, nil
+}
+
+// waitMark reads lines from r until mark.
+func (self *sqlSession) waitMark(r io.Reader, mark string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == mark {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read output of %s: %w", self.name, err)
+	}
+	return errors.New(self.name + " exited before " + mark)
+}
+
+// Close writes statements, closes stdin and waits until the client exited, but
+// no longer than timeout.
+func (self *sqlSession) Close(statements string, timeout time.Duration) error {
+	_, err := io.WriteString(self.stdin, statements)
+	if err != nil {
+		return self.kill(fmt.Errorf("write to %s: %w", self.name, err))
+	}
+	_ = self.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- self.cmd.Wait() }()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err = <-done:
+	case <-t.C:
+		_ = self.cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("%s didn't exit in %s", self.name, timeout)
+	}
+	return self.wrapError(err)
+}
+
+// kill kills the client, which closes its connection, and returns err with its
+// stderr.
+func (self *sqlSession) kill(err error) error {
+	_ = self.stdin.Close()
+	_ = self.cmd.Process.Kill()
+	_ = self.cmd.Wait()
+	return self.wrapError(err)
+}
+
+func (self *sqlSession) wrapError(err error) error {
+	if err == nil {
+		return nil
+	} else if stderr := strings.TrimSpace(self.stderr.String()); stderr != "" {
+		return fmt.Errorf("%w: %s", err, stderr)
+	}
+	return err
+}
@@ -18,6 +18,14 @@ const (
 	EnvFS       = "ZREPL_FS"
 	EnvSnapshot = "ZREPL_SNAPNAME"
 	EnvTimeout  = "ZREPL_TIMEOUT"
+	EnvJob      = "ZREPL_JOB"
+	// EnvPrevSnapshot is name of the previous snapshot of the filesystem with
+	// the same prefix, empty if there is none.
+	EnvPrevSnapshot = "ZREPL_PREV_SNAPSHOT"
+	// EnvPhaseStart is unix time, when the plan started to run hooks.
+	EnvPhaseStart = "ZREPL_PHASE_START"
+	// EnvPhaseElapsed is seconds since EnvPhaseStart, when the hook started.
+	EnvPhaseElapsed = "ZREPL_PHASE_ELAPSED"
 )
 
 func NewCommandHook(in *config.HookCommand) (*CommandHook, error) {
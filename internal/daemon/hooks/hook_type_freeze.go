@@ -0,0 +1,244 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+)
+
+// NewFreezeHook returns a hook, which freezes a filesystem mounted from a zvol
+// on its pre edge and thaws it on its post edge, so the zvol snapshot contains
+// a consistent filesystem.
+func NewFreezeHook(in *config.HookCommand) (*FreezeHook, error) {
+	if !filepath.IsAbs(in.Mountpoint) {
+		return nil, fmt.Errorf("mountpoint must be absolute: %q", in.Mountpoint)
+	} else if in.Timeout <= 0 {
+		return nil, errors.New("freeze hook requires positive timeout")
+	}
+
+	freeze, thaw, err := freezeCommands(in.Type, in.Mountpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FreezeHook{
+		errIsFatal: in.ErrIsFatal,
+		hookType:   in.Type,
+		mountpoint: filepath.Clean(in.Mountpoint),
+		timeout:    in.Timeout,
+		freezeCmd:  freeze,
+		thawCmd:    thaw,
+	}
+
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filesystem filter: %w", err)
+	}
+	r.filter = filter
+	return r, nil
+}
+
+type FreezeHook struct {
+	filter     *filters.DatasetFilter
+	errIsFatal bool
+	hookType   string
+	mountpoint string
+	timeout    time.Duration
+
+	freezeCmd []string
+	thawCmd   []string
+}
+
+func (self *FreezeHook) Filesystems() *filters.DatasetFilter {
+	return self.filter
+}
+
+func (self *FreezeHook) ErrIsFatal() bool { return self.errIsFatal }
+
+func (self *FreezeHook) String() string {
+	return self.hookType + " " + self.mountpoint
+}
+
+func (self *FreezeHook) Run(ctx context.Context, edge Edge, phase Phase,
+	dryRun bool, extra map[string]string,
+) HookReport {
+	report := &FreezeHookReport{Hook: self.String(), Edge: edge}
+	if dryRun {
+		return report
+	}
+
+	switch edge {
+	case Pre:
+		report.Err = frozen.Freeze(ctx, self)
+	case Post:
+		report.Err = frozen.Thaw(ctx, self)
+	}
+	return report
+}
+
+func (self *FreezeHook) freezeKey() string { return self.mountpoint }
+
+func (self *FreezeHook) freezeTimeout() time.Duration { return self.timeout }
+
+func (self *FreezeHook) freeze(ctx context.Context) error {
+	return self.run(ctx, self.freezeCmd)
+}
+
+func (self *FreezeHook) thaw(ctx context.Context) error {
+	return self.run(ctx, self.thawCmd)
+}
+
+func (self *FreezeHook) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+	return NewCommand(args[0], args[1:]...).WithTimeout(self.timeout).Run(ctx)
+}
+
+type FreezeHookReport struct {
+	Hook string
+	Edge Edge
+	Err  error
+}
+
+func (r *FreezeHookReport) String() string {
+	action := "freeze"
+	if r.Edge == Post {
+		action = "thaw"
+	}
+	if r.HadError() {
+		return fmt.Sprintf("%s hook %q failed: %s", action, r.Hook, r.Err)
+	}
+	return fmt.Sprintf("%s hook %q", action, r.Hook)
+}
+
+func (r *FreezeHookReport) HadError() bool { return r.Err != nil }
+
+func (r *FreezeHookReport) Error() string {
+	if r.Err == nil {
+		return ""
+	}
+	return r.String()
+}
+
+// freezer freezes and thaws something, identified by its freezeKey, like a
+// mounted filesystem or a guest.
+type freezer interface {
+	freezeKey() string
+	freezeTimeout() time.Duration
+	freeze(ctx context.Context) error
+	thaw(ctx context.Context) error
+}
+
+// frozen tracks all filesystems and guests frozen by zrepl, so concurrent
+// snapshots of zvols, which share a mountpoint or a guest, freeze it once and
+// thaw it after the last snapshot.
+var frozen = frozenMounts{mounts: make(map[string]*frozenMount)}
+
+type frozenMounts struct {
+	mu     sync.Mutex
+	mounts map[string]*frozenMount
+}
+
+func (self *frozenMounts) mount(mountpoint string) *frozenMount {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	m, ok := self.mounts[mountpoint]
+	if !ok {
+		m = &frozenMount{}
+		self.mounts[mountpoint] = m
+	}
+	return m
+}
+
+func (self *frozenMounts) Freeze(ctx context.Context, h freezer) error {
+	return self.mount(h.freezeKey()).Freeze(ctx, h)
+}
+
+func (self *frozenMounts) Thaw(ctx context.Context, h freezer) error {
+	return self.mount(h.freezeKey()).Thaw(ctx, h)
+}
+
+type frozenMount struct {
+	mu    sync.Mutex
+	hook  freezer
+	refs  int
+	timer *time.Timer
+}
+
+func (self *frozenMount) Freeze(ctx context.Context, h freezer) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.refs > 0 {
+		self.refs++
+		return nil
+	}
+
+	// Thaw in background context, because it must happen even if ctx canceled.
+	thawCtx := context.WithoutCancel(ctx)
+	if err := h.freeze(ctx); err != nil {
+		// The freeze command could be killed by timeout after it froze the
+		// filesystem, so always try to thaw it.
+		if err := h.thaw(thawCtx); err != nil {
+			logger.WithError(getLogger(ctx), err, "thaw after failed freeze")
+		}
+		return fmt.Errorf("freeze %q: %w", h.freezeKey(), err)
+	}
+
+	self.hook, self.refs = h, 1
+	// Always thaw, even if the post edge never runs or hangs.
+	var timer *time.Timer
+	timer = time.AfterFunc(h.freezeTimeout(),
+		func() { self.expire(thawCtx, timer) })
+	self.timer = timer
+	return nil
+}
+
+func (self *frozenMount) Thaw(ctx context.Context, h freezer) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.refs == 0 {
+		return fmt.Errorf("%q already thawed after safety timeout %s",
+			h.freezeKey(), h.freezeTimeout())
+	} else if self.refs--; self.refs > 0 {
+		return nil
+	}
+
+	self.timer.Stop()
+	hook := self.hook
+	self.hook, self.timer = nil, nil
+	if err := hook.thaw(context.WithoutCancel(ctx)); err != nil {
+		return fmt.Errorf("thaw %q: %w", h.freezeKey(), err)
+	}
+	return nil
+}
+
+func (self *frozenMount) expire(ctx context.Context, timer *time.Timer) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.timer != timer {
+		return
+	}
+	h := self.hook
+	self.hook, self.refs, self.timer = nil, 0, nil
+
+	l := getLogger(ctx).With(slog.String("frozen", h.freezeKey()))
+	l.With(slog.Duration("timeout", h.freezeTimeout())).
+		Warn("thaw after safety timeout")
+	if err := h.thaw(ctx); err != nil {
+		logger.WithError(l, err, "thaw after safety timeout")
+	}
+}
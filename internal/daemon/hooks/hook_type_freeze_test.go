@@ -0,0 +1,90 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFreezeHook(t *testing.T, timeout time.Duration,
+) (*FreezeHook, func() string) {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "log")
+	appendCmd := func(s string) []string {
+		return []string{"/bin/sh", "-c", `echo "$1" >> "$2"`, "sh", s, log}
+	}
+
+	h := &FreezeHook{
+		hookType:   "fsfreeze",
+		mountpoint: t.TempDir(),
+		timeout:    timeout,
+		freezeCmd:  appendCmd("freeze"),
+		thawCmd:    appendCmd("thaw"),
+	}
+	return h, func() string {
+		b, err := os.ReadFile(log)
+		if os.IsNotExist(err) {
+			return ""
+		}
+		require.NoError(t, err)
+		return string(b)
+	}
+}
+
+func TestFreezeHook_Run(t *testing.T) {
+	h, log := newTestFreezeHook(t, time.Minute)
+	ctx := t.Context()
+
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, "freeze\n", log())
+
+	require.False(t, h.Run(ctx, Post, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, "freeze\n", log(), "still frozen by another snapshot")
+	require.False(t, h.Run(ctx, Post, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, "freeze\nthaw\n", log())
+}
+
+func TestFreezeHook_Run_dryRun(t *testing.T) {
+	h, log := newTestFreezeHook(t, time.Minute)
+	ctx := t.Context()
+
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, true, nil).HadError())
+	require.False(t, h.Run(ctx, Post, PhaseTesting, true, nil).HadError())
+	assert.Empty(t, log())
+}
+
+func TestFreezeHook_safetyTimeout(t *testing.T) {
+	h, log := newTestFreezeHook(t, 100*time.Millisecond)
+	ctx := t.Context()
+
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	assert.Eventually(t, func() bool { return log() == "freeze\nthaw\n" },
+		time.Second, 10*time.Millisecond)
+
+	r := h.Run(ctx, Post, PhaseTesting, false, nil)
+	require.True(t, r.HadError())
+	assert.ErrorContains(t, r, "already thawed after safety timeout")
+	assert.Equal(t, "freeze\nthaw\n", log())
+}
+
+func TestFreezeHook_thawAfterFailedFreeze(t *testing.T) {
+	h, log := newTestFreezeHook(t, time.Minute)
+	h.freezeCmd = []string{"/bin/sh", "-c", "exit 1"}
+	ctx := t.Context()
+
+	r := h.Run(ctx, Pre, PhaseTesting, false, nil)
+	require.True(t, r.HadError())
+	assert.Equal(t, "thaw\n", log())
+
+	h.freezeCmd = []string{"/bin/sh", "-c", "true"}
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	require.False(t, h.Run(context.Background(), Post, PhaseTesting, false,
+		nil).HadError())
+	assert.Equal(t, "thaw\nthaw\n", log())
+}
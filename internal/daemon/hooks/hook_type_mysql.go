@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+)
+
+const (
+	mysqlDefaultPath = "mysql"
+	mysqlLockedMark  = "zrepl_tables_locked"
+)
+
+// NewMySQLLockHook returns a hook, which runs FLUSH TABLES WITH READ LOCK on
+// its pre edge and holds the lock by a persistent connection of mysql client
+// until its post edge, so the snapshot contains consistent tables.
+func NewMySQLLockHook(in *config.HookCommand) (*MySQLLockHook, error) {
+	if in.Timeout <= 0 {
+		return nil, errors.New("mysql-lock-tables hook requires positive timeout")
+	}
+
+	args, password, err := mysqlArgs(in.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	path := in.Path
+	if path == "" {
+		path = mysqlDefaultPath
+	}
+
+	r := &MySQLLockHook{
+		errIsFatal: in.ErrIsFatal,
+		path:       path,
+		args:       args,
+		password:   password,
+	}
+	r.sharedSession = sharedSession{
+		name:    r.String(),
+		timeout: in.Timeout,
+		start:   r.startSession,
+		stop:    "UNLOCK TABLES;\n",
+	}
+
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filesystem filter: %w", err)
+	}
+	r.filter = filter
+	return r, nil
+}
+
+// mysqlArgs returns arguments of mysql client and password from dsn in format
+// of go-sql-driver/mysql: [user[:password]@][tcp[(addr)]|unix[(path)]]/[dbname].
+func mysqlArgs(dsn string) (args []string, password string, err error) {
+	i := strings.LastIndexByte(dsn, '/')
+	if i < 0 {
+		return nil, "", errors.New("invalid dsn: missing the slash separating the database name")
+	}
+	dsn, dbName := dsn[:i], dsn[i+1:]
+	dbName, _, _ = strings.Cut(dbName, "?")
+
+	args = []string{"--batch", "--skip-column-names", "--unbuffered"}
+	if i := strings.LastIndexByte(dsn, '@'); i >= 0 {
+		user, pass, _ := strings.Cut(dsn[:i], ":")
+		if user != "" {
+			args = append(args, "--user="+user)
+		}
+		dsn, password = dsn[i+1:], pass
+	}
+
+	proto, addr, _ := strings.Cut(dsn, "(")
+	if addr != "" {
+		var ok bool
+		if addr, ok = strings.CutSuffix(addr, ")"); !ok {
+			return nil, "", fmt.Errorf("invalid dsn: unclosed address of %q", proto)
+		}
+	}
+
+	switch proto {
+	case "":
+	case "tcp":
+		if addr == "" {
+			break
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, ""
+		}
+		args = append(args, "--protocol=TCP", "--host="+host)
+		if port != "" {
+			args = append(args, "--port="+port)
+		}
+	case "unix":
+		args = append(args, "--protocol=SOCKET")
+		if addr != "" {
+			args = append(args, "--socket="+addr)
+		}
+	default:
+		return nil, "", fmt.Errorf("invalid dsn: unsupported protocol %q", proto)
+	}
+
+	if dbName != "" {
+		args = append(args, "--database="+dbName)
+	}
+	return args, password, nil
+}
+
+type MySQLLockHook struct {
+	sharedSession
+
+	filter     *filters.DatasetFilter
+	errIsFatal bool
+	path       string
+	args       []string
+	password   string
+}
+
+func (self *MySQLLockHook) Filesystems() *filters.DatasetFilter {
+	return self.filter
+}
+
+func (self *MySQLLockHook) ErrIsFatal() bool { return self.errIsFatal }
+
+func (self *MySQLLockHook) String() string {
+	return config.HookTypeMySQLLockTables + " " +
+		strings.Join(append([]string{self.path}, self.args...), " ")
+}
+
+func (self *MySQLLockHook) Run(ctx context.Context, edge Edge, phase Phase,
+	dryRun bool, extra map[string]string,
+) HookReport {
+	report := &MySQLLockHookReport{Hook: self.String(), Edge: edge}
+	if dryRun {
+		return report
+	}
+
+	switch edge {
+	case Pre:
+		if err := self.acquire(ctx); err != nil {
+			report.Err = fmt.Errorf("lock tables: %w", err)
+		}
+	case Post:
+		if err := self.release(); err != nil {
+			report.Err = fmt.Errorf("unlock tables: %w", err)
+		}
+	}
+	return report
+}
+
+// startSession starts mysql client and locks tables. They're locked, until
+// the client exited.
+func (self *MySQLLockHook) startSession(ctx context.Context,
+) (*sqlSession, error) {
+	var env []string
+	if self.password != "" {
+		env = []string{"MYSQL_PWD=" + self.password}
+	}
+	return startSQLSession(ctx, self.path, self.args, env,
+		"FLUSH TABLES WITH READ LOCK;\nSELECT '"+mysqlLockedMark+"';\n",
+		mysqlLockedMark, self.timeout)
+}
+
+type MySQLLockHookReport struct {
+	Hook string
+	Edge Edge
+	Err  error
+}
+
+func (r *MySQLLockHookReport) String() string {
+	action := "lock tables"
+	if r.Edge == Post {
+		action = "unlock tables"
+	}
+	if r.HadError() {
+		return fmt.Sprintf("%s hook %q failed: %s", action, r.Hook, r.Err)
+	}
+	return fmt.Sprintf("%s hook %q", action, r.Hook)
+}
+
+func (r *MySQLLockHookReport) HadError() bool { return r.Err != nil }
+
+func (r *MySQLLockHookReport) Error() string {
+	if r.Err == nil {
+		return ""
+	}
+	return r.String()
+}
@@ -0,0 +1,131 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestMySQLArgs(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		args     []string
+		password string
+		wantErr  bool
+	}{
+		{dsn: "/"},
+		{
+			dsn: "root:secret@tcp(db.example.com:3307)/app?timeout=5s",
+			args: []string{
+				"--user=root", "--protocol=TCP", "--host=db.example.com",
+				"--port=3307", "--database=app",
+			},
+			password: "secret",
+		},
+		{
+			dsn:  "backup@unix(/var/run/mysqld/mysqld.sock)/",
+			args: []string{"--user=backup", "--protocol=SOCKET", "--socket=/var/run/mysqld/mysqld.sock"},
+		},
+		{dsn: "root@tcp(localhost)/", args: []string{"--user=root", "--protocol=TCP", "--host=localhost"}},
+		{dsn: "root@localhost", wantErr: true},
+		{dsn: "root@udp(localhost)/", wantErr: true},
+		{dsn: "root@tcp(localhost/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			args, password, err := mysqlArgs(tt.dsn)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t,
+				append([]string{"--batch", "--skip-column-names", "--unbuffered"},
+					tt.args...), args)
+			assert.Equal(t, tt.password, password)
+		})
+	}
+}
+
+// newTestMySQLLockHook returns hook, which runs a fake mysql client, and a
+// function, which returns statements received by it.
+func newTestMySQLLockHook(t *testing.T, timeout time.Duration,
+) (*MySQLLockHook, func() string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	mysql := filepath.Join(dir, "mysql")
+	require.NoError(t, os.WriteFile(mysql, []byte(`#!/bin/sh
+while read -r line; do
+  echo "$line" >> "`+log+`"
+  case "$line" in
+    SELECT*) echo "`+mysqlLockedMark+`" ;;
+  esac
+done
+`), 0o755))
+
+	h, err := NewMySQLLockHook(&config.HookCommand{
+		Type:    config.HookTypeMySQLLockTables,
+		Path:    mysql,
+		DSN:     "root:secret@unix(/tmp/mysql.sock)/",
+		Timeout: timeout,
+	})
+	require.NoError(t, err)
+	return h, func() string {
+		b, err := os.ReadFile(log)
+		if os.IsNotExist(err) {
+			return ""
+		}
+		require.NoError(t, err)
+		return string(b)
+	}
+}
+
+func TestMySQLLockHook_Run(t *testing.T) {
+	h, log := newTestMySQLLockHook(t, time.Minute)
+	ctx := t.Context()
+	const locked = "FLUSH TABLES WITH READ LOCK;\nSELECT '" + mysqlLockedMark +
+		"';\n"
+
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, locked, log())
+
+	require.False(t, h.Run(ctx, Post, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, locked, log(), "still locked by another snapshot")
+	require.False(t, h.Run(ctx, Post, PhaseTesting, false, nil).HadError())
+	assert.Equal(t, locked+"UNLOCK TABLES;\n", log())
+}
+
+func TestMySQLLockHook_Run_expire(t *testing.T) {
+	h, log := newTestMySQLLockHook(t, 100*time.Millisecond)
+	ctx := t.Context()
+
+	require.False(t, h.Run(ctx, Pre, PhaseTesting, false, nil).HadError())
+	assert.Eventually(t, func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.refs == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, log(), "UNLOCK TABLES;\n")
+	assert.True(t, h.Run(ctx, Post, PhaseTesting, false, nil).HadError())
+}
+
+func TestMySQLLockHook_Run_failed(t *testing.T) {
+	h, err := NewMySQLLockHook(&config.HookCommand{
+		Type:    config.HookTypeMySQLLockTables,
+		Path:    "/bin/false",
+		DSN:     "/",
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	assert.True(t, h.Run(t.Context(), Pre, PhaseTesting, false, nil).HadError())
+	assert.Zero(t, h.refs)
+}
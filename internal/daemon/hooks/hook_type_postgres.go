@@ -0,0 +1,221 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+)
+
+const (
+	postgresDefaultPath = "psql"
+	postgresBackupMark  = "zrepl_backup_started"
+	postgresBackupLabel = "zrepl"
+)
+
+// NewPostgresHook returns a hook, which runs CHECKPOINT on its pre edge, so
+// recovery of the snapshot replays less WAL. In backup mode it runs
+// pg_backup_start instead, which checkpoints too, and holds the backup by a
+// persistent connection of psql until its post edge, which runs
+// pg_backup_stop.
+func NewPostgresHook(in *config.HookCommand) (*PostgresHook, error) {
+	if in.Timeout <= 0 {
+		return nil, errors.New("postgres-checkpoint hook requires positive timeout")
+	}
+
+	dsn, password, err := postgresDSN(in.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	path := in.Path
+	if path == "" {
+		path = postgresDefaultPath
+	}
+
+	r := &PostgresHook{
+		errIsFatal: in.ErrIsFatal,
+		path:       path,
+		args: []string{
+			"--no-psqlrc", "--quiet", "--no-align", "--tuples-only",
+			"--set=ON_ERROR_STOP=1", "--dbname=" + dsn,
+		},
+		dsn:        in.RedactedDSN(),
+		backupMode: in.BackupMode,
+	}
+	if password != "" {
+		r.env = []string{"PGPASSWORD=" + password}
+	}
+	r.sharedSession = sharedSession{
+		name:    r.String(),
+		timeout: in.Timeout,
+		start:   r.startSession,
+		stop:    "SELECT pg_backup_stop(wait_for_archive => false);\n",
+	}
+
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filesystem filter: %w", err)
+	}
+	r.filter = filter
+	return r, nil
+}
+
+type PostgresHook struct {
+	sharedSession
+
+	filter     *filters.DatasetFilter
+	errIsFatal bool
+	path       string
+	args       []string
+	env        []string
+	dsn        string
+	backupMode bool
+}
+
+// postgresDSN returns dsn without password and the password, which psql gets
+// by PGPASSWORD, so it isn't visible in arguments of the process. dsn is a
+// postgresql:// URI, a keyword/value connection string or just a database
+// name, which is returned unchanged, like psql accepts it.
+func postgresDSN(dsn string) (string, string, error) {
+	if !strings.Contains(dsn, "=") && !strings.Contains(dsn, "://") {
+		return dsn, "", nil
+	} else if u, err := url.Parse(dsn); err == nil &&
+		(u.Scheme == "postgresql" || u.Scheme == "postgres") {
+		var password string
+		if u.User != nil {
+			password, _ = u.User.Password()
+			if name := u.User.Username(); name != "" {
+				u.User = url.User(name)
+			} else {
+				u.User = nil
+			}
+		}
+		if q := u.Query(); q.Has("password") {
+			password = q.Get("password")
+			q.Del("password")
+			u.RawQuery = q.Encode()
+		}
+		return u.String(), password, nil
+	}
+
+	params, err := config.ParsePostgresConninfo(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	var password string
+	params = slices.DeleteFunc(params, func(kv [2]string) bool {
+		if kv[0] == "password" {
+			password = kv[1]
+			return true
+		}
+		return false
+	})
+	return config.FormatPostgresConninfo(params), password, nil
+}
+
+func (self *PostgresHook) Filesystems() *filters.DatasetFilter {
+	return self.filter
+}
+
+func (self *PostgresHook) ErrIsFatal() bool { return self.errIsFatal }
+
+func (self *PostgresHook) String() string {
+	return config.HookTypePostgres + " " + self.path + " " + self.dsn
+}
+
+func (self *PostgresHook) Run(ctx context.Context, edge Edge, phase Phase,
+	dryRun bool, extra map[string]string,
+) HookReport {
+	report := &PostgresHookReport{Hook: self.String(), Action: "checkpoint"}
+	switch {
+	case self.backupMode && edge == Pre:
+		report.Action = "start backup"
+	case self.backupMode && edge == Post:
+		report.Action = "stop backup"
+	case edge == Post:
+		report.Action = ""
+	}
+	if dryRun || report.Action == "" {
+		return report
+	}
+
+	var err error
+	switch {
+	case !self.backupMode:
+		err = self.checkpoint(ctx)
+	case edge == Pre:
+		err = self.acquire(ctx)
+	case edge == Post:
+		err = self.release()
+	}
+	if err != nil {
+		report.Err = fmt.Errorf("%s: %w", report.Action, err)
+	}
+	return report
+}
+
+// checkpoint runs CHECKPOINT by psql.
+func (self *PostgresHook) checkpoint(ctx context.Context) error {
+	getLogger(ctx).Info("\"" + self.String() + "\"")
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, self.path,
+		append(self.args, "--command=CHECKPOINT")...)
+	if len(self.env) != 0 {
+		cmd.Env = append(os.Environ(), self.env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			return fmt.Errorf("%q: %w: %s", self.path, err, s)
+		}
+		return fmt.Errorf("%q: %w", self.path, err)
+	}
+	return nil
+}
+
+// startSession starts psql and backup. The backup is in progress, until
+// pg_backup_stop, or until the client exited, which aborts it.
+func (self *PostgresHook) startSession(ctx context.Context,
+) (*sqlSession, error) {
+	return startSQLSession(ctx, self.path, self.args, self.env,
+		"SELECT pg_backup_start('"+postgresBackupLabel+"', fast => true);\n"+
+			"SELECT '"+postgresBackupMark+"';\n",
+		postgresBackupMark, self.timeout)
+}
+
+type PostgresHookReport struct {
+	Hook   string
+	Action string
+	Err    error
+}
+
+func (r *PostgresHookReport) String() string {
+	if r.HadError() {
+		return fmt.Sprintf("%s hook %q failed: %s", r.Action, r.Hook, r.Err)
+	} else if r.Action == "" {
+		return fmt.Sprintf("hook %q", r.Hook)
+	}
+	return fmt.Sprintf("%s hook %q", r.Action, r.Hook)
+}
+
+func (r *PostgresHookReport) HadError() bool { return r.Err != nil }
+
+func (r *PostgresHookReport) Error() string {
+	if r.Err == nil {
+		return ""
+	}
+	return r.String()
+}
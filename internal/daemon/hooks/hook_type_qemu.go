@@ -0,0 +1,183 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+)
+
+// NewQemuFreezeHook returns a hook, which freezes filesystems of a running VM
+// by qemu-guest-agent on its pre edge and thaws them on its post edge, so
+// snapshots of zvols of the VM contain consistent filesystems. The guest agent
+// is reached by virsh with libvirt domain or directly by its unix socket.
+func NewQemuFreezeHook(in *config.HookCommand) (*QemuFreezeHook, error) {
+	switch {
+	case in.Timeout <= 0:
+		return nil, errors.New("qemu freeze hook requires positive timeout")
+	case in.Domain == "" && in.Socket == "":
+		return nil, errors.New("qemu freeze hook requires domain or socket")
+	case in.Domain != "" && in.Socket != "":
+		return nil, errors.New(
+			"qemu freeze hook requires either domain or socket, not both")
+	case in.Socket != "" && !filepath.IsAbs(in.Socket):
+		return nil, fmt.Errorf("socket must be absolute: %q", in.Socket)
+	}
+
+	r := &QemuFreezeHook{
+		errIsFatal: in.ErrIsFatal,
+		domain:     in.Domain,
+		timeout:    in.Timeout,
+	}
+	if in.Socket != "" {
+		r.socket = filepath.Clean(in.Socket)
+	}
+
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filesystem filter: %w", err)
+	}
+	r.filter = filter
+	return r, nil
+}
+
+type QemuFreezeHook struct {
+	filter     *filters.DatasetFilter
+	errIsFatal bool
+	domain     string
+	socket     string
+	timeout    time.Duration
+}
+
+func (self *QemuFreezeHook) Filesystems() *filters.DatasetFilter {
+	return self.filter
+}
+
+func (self *QemuFreezeHook) ErrIsFatal() bool { return self.errIsFatal }
+
+func (self *QemuFreezeHook) String() string {
+	return config.HookTypeQemuFreeze + " " + self.freezeKey()
+}
+
+func (self *QemuFreezeHook) Run(ctx context.Context, edge Edge, phase Phase,
+	dryRun bool, extra map[string]string,
+) HookReport {
+	report := &FreezeHookReport{Hook: self.String(), Edge: edge}
+	if dryRun {
+		return report
+	}
+
+	switch edge {
+	case Pre:
+		report.Err = frozen.Freeze(ctx, self)
+	case Post:
+		report.Err = frozen.Thaw(ctx, self)
+	}
+	return report
+}
+
+func (self *QemuFreezeHook) freezeKey() string {
+	if self.domain != "" {
+		return "domain:" + self.domain
+	}
+	return "socket:" + self.socket
+}
+
+func (self *QemuFreezeHook) freezeTimeout() time.Duration {
+	return self.timeout
+}
+
+func (self *QemuFreezeHook) freeze(ctx context.Context) error {
+	return self.execute(ctx, "guest-fsfreeze-freeze")
+}
+
+func (self *QemuFreezeHook) thaw(ctx context.Context) error {
+	return self.execute(ctx, "guest-fsfreeze-thaw")
+}
+
+func (self *QemuFreezeHook) execute(ctx context.Context, cmd string) error {
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+
+	if self.domain != "" {
+		b, err := json.Marshal(&guestAgentRequest{Execute: cmd})
+		if err != nil {
+			return fmt.Errorf("marshal %q: %w", cmd, err)
+		}
+		timeout := strconv.FormatFloat(self.timeout.Seconds(), 'f', 0, 64)
+		return NewCommand("virsh", "qemu-agent-command", "--timeout", timeout,
+			self.domain, string(b)).WithTimeout(self.timeout).Run(ctx)
+	}
+	return guestAgentExecute(ctx, self.socket, cmd)
+}
+
+type guestAgentRequest struct {
+	Execute   string `json:"execute"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+type guestAgentResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+}
+
+// guestAgentExecute executes cmd by qemu-guest-agent listening on socket. It
+// synchronizes with the agent by guest-sync first, skipping stale responses
+// of previous clients.
+func guestAgentExecute(ctx context.Context, socket, cmd string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return fmt.Errorf("connect to guest agent: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("set deadline of guest agent: %w", err)
+		}
+	}
+
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+	id := rand.Int32()
+	err = enc.Encode(&guestAgentRequest{
+		Execute:   "guest-sync",
+		Arguments: map[string]int32{"id": id},
+	})
+	if err != nil {
+		return fmt.Errorf("guest-sync: %w", err)
+	}
+
+	for {
+		var resp guestAgentResponse
+		if err := dec.Decode(&resp); err != nil {
+			return fmt.Errorf("guest-sync: read response: %w", err)
+		}
+		var synced int32
+		if json.Unmarshal(resp.Return, &synced) == nil && synced == id {
+			break
+		}
+	}
+
+	if err := enc.Encode(&guestAgentRequest{Execute: cmd}); err != nil {
+		return fmt.Errorf("%s: %w", cmd, err)
+	}
+
+	var resp guestAgentResponse
+	if err := dec.Decode(&resp); err != nil {
+		return fmt.Errorf("%s: read response: %w", cmd, err)
+	} else if resp.Error != nil {
+		return fmt.Errorf("%s: %s: %s", cmd, resp.Error.Class, resp.Error.Desc)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package hooks
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+// testGuestAgent is a fake qemu-guest-agent, which records executed commands
+// and fails commands listed in fail.
+type testGuestAgent struct {
+	mu       sync.Mutex
+	executed []string
+	fail     map[string]bool
+}
+
+func newTestGuestAgent(t *testing.T) (*testGuestAgent, string) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "qga.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	agent := &testGuestAgent{fail: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.serve(conn)
+		}
+	}()
+	return agent, socket
+}
+
+func (self *testGuestAgent) serve(conn net.Conn) {
+	defer conn.Close()
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+	// A stale response of a previous client.
+	_ = enc.Encode(map[string]any{"return": 0})
+	for {
+		var req struct {
+			Execute   string         `json:"execute"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		self.mu.Lock()
+		failed := self.fail[req.Execute]
+		if req.Execute != "guest-sync" {
+			self.executed = append(self.executed, req.Execute)
+		}
+		self.mu.Unlock()
+
+		switch {
+		case req.Execute == "guest-sync":
+			_ = enc.Encode(map[string]any{"return": req.Arguments["id"]})
+		case failed:
+			_ = enc.Encode(map[string]any{"error": map[string]string{
+				"class": "GenericError", "desc": "failed",
+			}})
+		default:
+			_ = enc.Encode(map[string]any{"return": 1})
+		}
+	}
+}
+
+func (self *testGuestAgent) Executed() []string {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.executed
+}
+
+func TestQemuFreezeHook_Run(t *testing.T) {
+	agent, socket := newTestGuestAgent(t)
+	h, err := NewQemuFreezeHook(&config.HookCommand{
+		Type:    config.HookTypeQemuFreeze,
+		Socket:  socket,
+		Timeout: time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "qemu-fsfreeze socket:"+socket, h.String())
+	ctx := t.Context()
+
+	require.False(t, h.Run(ctx, Pre, PhaseSnapshot, true, nil).HadError())
+	assert.Empty(t, agent.Executed())
+
+	r := h.Run(ctx, Pre, PhaseSnapshot, false, nil)
+	require.False(t, r.HadError(), r.Error())
+	require.False(t, h.Run(ctx, Pre, PhaseSnapshot, false, nil).HadError())
+	assert.Equal(t, []string{"guest-fsfreeze-freeze"}, agent.Executed())
+
+	require.False(t, h.Run(ctx, Post, PhaseSnapshot, false, nil).HadError())
+	r = h.Run(ctx, Post, PhaseSnapshot, false, nil)
+	require.False(t, r.HadError(), r.Error())
+	assert.Equal(t, []string{"guest-fsfreeze-freeze", "guest-fsfreeze-thaw"},
+		agent.Executed())
+}
+
+func TestQemuFreezeHook_thawAfterFailedFreeze(t *testing.T) {
+	agent, socket := newTestGuestAgent(t)
+	agent.fail["guest-fsfreeze-freeze"] = true
+	h, err := NewQemuFreezeHook(&config.HookCommand{
+		Type:    config.HookTypeQemuFreeze,
+		Socket:  socket,
+		Timeout: time.Minute,
+	})
+	require.NoError(t, err)
+
+	r := h.Run(t.Context(), Pre, PhaseSnapshot, false, nil)
+	require.True(t, r.HadError())
+	assert.ErrorContains(t, r, "guest-fsfreeze-freeze: GenericError: failed")
+	assert.Equal(t, []string{"guest-fsfreeze-freeze", "guest-fsfreeze-thaw"},
+		agent.Executed())
+}
+
+func TestNewQemuFreezeHook_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      config.HookCommand
+		wantErr string
+	}{
+		{
+			name:    "without timeout",
+			in:      config.HookCommand{Domain: "vm1"},
+			wantErr: "positive timeout",
+		},
+		{
+			name:    "without domain and socket",
+			in:      config.HookCommand{Timeout: time.Minute},
+			wantErr: "requires domain or socket",
+		},
+		{
+			name: "domain and socket",
+			in: config.HookCommand{
+				Domain: "vm1", Socket: "/tmp/qga.sock", Timeout: time.Minute,
+			},
+			wantErr: "not both",
+		},
+		{
+			name:    "relative socket",
+			in:      config.HookCommand{Socket: "qga.sock", Timeout: time.Minute},
+			wantErr: "socket must be absolute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.Type = config.HookTypeQemuFreeze
+			_, err := NewQemuFreezeHook(&tt.in)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/util/httppost"
+)
+
+// NewWebhookHook returns a hook, which sends a request to URL on its pre and
+// post edges, like an endpoint of an application, which quiesces it. Body of
+// POST and PUT requests is a JSON object with the same variables, which
+// command hooks get in their env.
+func NewWebhookHook(in *config.HookCommand) (*WebhookHook, error) {
+	if in.Timeout <= 0 {
+		return nil, errors.New("webhook hook requires positive timeout")
+	}
+
+	u, err := url.Parse(in.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url %q: scheme must be http or https",
+			u.Redacted())
+	}
+
+	method := strings.ToUpper(in.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	r := &WebhookHook{
+		errIsFatal:   in.ErrIsFatal,
+		url:          u,
+		method:       method,
+		headers:      in.Headers,
+		expectStatus: in.ExpectStatus,
+		timeout:      in.Timeout,
+		client:       &http.Client{Timeout: in.Timeout},
+	}
+
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filesystem filter: %w", err)
+	}
+	r.filter = filter
+	return r, nil
+}
+
+type WebhookHook struct {
+	filter       *filters.DatasetFilter
+	errIsFatal   bool
+	url          *url.URL
+	method       string
+	headers      map[string]string
+	expectStatus []int
+	timeout      time.Duration
+
+	client *http.Client
+}
+
+func (self *WebhookHook) Filesystems() *filters.DatasetFilter {
+	return self.filter
+}
+
+func (self *WebhookHook) ErrIsFatal() bool { return self.errIsFatal }
+
+func (self *WebhookHook) String() string {
+	return config.HookTypeWebhook + " " + self.method + " " +
+		self.url.Redacted()
+}
+
+func (self *WebhookHook) Run(ctx context.Context, edge Edge, phase Phase,
+	dryRun bool, extra map[string]string,
+) HookReport {
+	report := &WebhookHookReport{Hook: self.String(), Edge: edge}
+	if dryRun {
+		return report
+	}
+
+	getLogger(ctx).Info("\"" + self.String() + "\"")
+	report.Status, report.Err = self.request(ctx,
+		self.payload(edge, phase, extra))
+	return report
+}
+
+// payload returns variables of the request, like env of command hooks.
+func (self *WebhookHook) payload(edge Edge, phase Phase,
+	extra map[string]string,
+) map[string]string {
+	payload := make(map[string]string, len(extra)+2)
+	maps.Copy(payload, extra)
+	payload[EnvType] = strings.ToLower(edge.StringForPhase(phase))
+	payload[EnvTimeout] = fmt.Sprintf("%.f", math.Floor(self.timeout.Seconds()))
+	return payload
+}
+
+func (self *WebhookHook) request(ctx context.Context,
+	payload map[string]string,
+) (int, error) {
+	var body io.Reader
+	if self.method == http.MethodPost || self.method == http.MethodPut {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("marshal payload: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, self.method, self.url.String(),
+		body)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	} else if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Zrepl-Hook-Type", payload[EnvType])
+	for k, v := range self.headers {
+		req.Header.Set(k, v)
+	}
+
+	return httppost.Do(self.client, req, self.expected)
+}
+
+// expected returns true, if status is in expectStatus, or it's 2xx, if
+// expectStatus is empty.
+func (self *WebhookHook) expected(status int) bool {
+	if len(self.expectStatus) == 0 {
+		return httppost.Is2xx(status)
+	}
+	return slices.Contains(self.expectStatus, status)
+}
+
+type WebhookHookReport struct {
+	Hook   string
+	Edge   Edge
+	Status int
+	Err    error
+}
+
+func (r *WebhookHookReport) String() string {
+	if r.HadError() {
+		return fmt.Sprintf("%s webhook %q failed: %s", r.Edge, r.Hook, r.Err)
+	} else if r.Status != 0 {
+		return fmt.Sprintf("%s webhook %q: %d", r.Edge, r.Hook, r.Status)
+	}
+	return fmt.Sprintf("%s webhook %q", r.Edge, r.Hook)
+}
+
+func (r *WebhookHookReport) HadError() bool { return r.Err != nil }
+
+func (r *WebhookHookReport) Error() string {
+	if r.Err == nil {
+		return ""
+	}
+	return r.String()
+}
@@ -0,0 +1,122 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+type webhookRequest struct {
+	Method  string
+	Header  http.Header
+	Payload map[string]string
+}
+
+// newTestWebhook returns a server, which responds with status, and requests
+// received by it.
+func newTestWebhook(t *testing.T, status int) (*httptest.Server,
+	*[]webhookRequest,
+) {
+	t.Helper()
+	var reqs []webhookRequest
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			req := webhookRequest{Method: r.Method, Header: r.Header}
+			if r.ContentLength > 0 {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req.Payload))
+			}
+			reqs = append(reqs, req)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("quiesced\n"))
+		}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestWebhookHook(t *testing.T) {
+	srv, reqs := newTestWebhook(t, http.StatusOK)
+	h, err := NewWebhookHook(&config.HookCommand{
+		Type:    config.HookTypeWebhook,
+		URL:     srv.URL + "/quiesce",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Timeout: time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "webhook POST "+srv.URL+"/quiesce", h.String())
+
+	ctx := context.Background()
+	extra := map[string]string{
+		EnvFS:       "zroot/app",
+		EnvSnapshot: "zrepl_1",
+		EnvJob:      "snap",
+	}
+
+	r := h.Run(ctx, Pre, PhaseSnapshot, true, extra)
+	require.False(t, r.HadError(), r.Error())
+	assert.Empty(t, *reqs)
+
+	r = h.Run(ctx, Pre, PhaseSnapshot, false, extra)
+	require.False(t, r.HadError(), r.Error())
+	r = h.Run(ctx, Post, PhaseSnapshot, false, extra)
+	require.False(t, r.HadError(), r.Error())
+
+	require.Len(t, *reqs, 2)
+	req := (*reqs)[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "pre_snapshot", req.Header.Get("X-Zrepl-Hook-Type"))
+	assert.Equal(t, map[string]string{
+		EnvFS:       "zroot/app",
+		EnvSnapshot: "zrepl_1",
+		EnvJob:      "snap",
+		EnvType:     "pre_snapshot",
+		EnvTimeout:  "60",
+	}, req.Payload)
+	assert.Equal(t, "post_snapshot", (*reqs)[1].Payload[EnvType])
+}
+
+func TestWebhookHook_status(t *testing.T) {
+	srv, reqs := newTestWebhook(t, http.StatusServiceUnavailable)
+	h, err := NewWebhookHook(&config.HookCommand{
+		Type:    config.HookTypeWebhook,
+		URL:     srv.URL,
+		Method:  "get",
+		Timeout: time.Minute,
+	})
+	require.NoError(t, err)
+
+	r := h.Run(context.Background(), Pre, PhaseSnapshot, false, nil)
+	require.True(t, r.HadError())
+	assert.Contains(t, r.Error(), `unexpected status "503 Service Unavailable"`)
+	assert.Contains(t, r.Error(), "quiesced")
+	require.Len(t, *reqs, 1)
+	assert.Equal(t, http.MethodGet, (*reqs)[0].Method)
+	assert.Nil(t, (*reqs)[0].Payload)
+
+	h.expectStatus = []int{http.StatusServiceUnavailable}
+	r = h.Run(context.Background(), Pre, PhaseSnapshot, false, nil)
+	require.False(t, r.HadError(), r.Error())
+}
+
+func TestNewWebhookHook_errors(t *testing.T) {
+	_, err := NewWebhookHook(&config.HookCommand{
+		Type: config.HookTypeWebhook, URL: "http://localhost",
+	})
+	require.ErrorContains(t, err, "positive timeout")
+
+	_, err = NewWebhookHook(&config.HookCommand{
+		Type:    config.HookTypeWebhook,
+		URL:     "ftp://localhost",
+		Timeout: time.Minute,
+	})
+	require.ErrorContains(t, err, "scheme must be http or https")
+}
@@ -149,6 +149,24 @@ jobs:
 				},
 			},
 		},
+		{
+			Name:           "check_env_extra",
+			Config:         []string{`{type: command, path: {{.WorkDir}}/test/test-report-env-extra.sh, filesystems: {"<": true}}`},
+			ExpectHadError: false,
+			ExpectStepReports: []expectStep{
+				{
+					ExpectedEdge: hooks.Pre,
+					ExpectStatus: hooks.StepOk,
+					OutputTest:   regexpTest(`TEST pre_testing job=testjob prev=zrepl_prev start=\d+ elapsed=\d+\.\d{3}`),
+				},
+				{ExpectedEdge: hooks.Callback, ExpectStatus: hooks.StepOk},
+				{
+					ExpectedEdge: hooks.Post,
+					ExpectStatus: hooks.StepOk,
+					OutputTest:   regexpTest(`TEST post_testing job=testjob prev=zrepl_prev start=\d+ elapsed=\d+\.\d{3}`),
+				},
+			},
+		},
 
 		{
 			Name:                  "nonfatal_pre_error_continues",
@@ -403,8 +421,10 @@ jobs:
 	})
 
 	hookEnvExtra := map[string]string{
-		hooks.EnvFS:       fs.ToString(),
-		hooks.EnvSnapshot: testSnapshotName,
+		hooks.EnvFS:           fs.ToString(),
+		hooks.EnvSnapshot:     testSnapshotName,
+		hooks.EnvJob:          "testjob",
+		hooks.EnvPrevSnapshot: "zrepl_prev",
 	}
 
 	for _, tt := range testTable {
@@ -0,0 +1,3 @@
+#!/bin/sh -eu
+
+echo "TEST $ZREPL_HOOKTYPE job=$ZREPL_JOB prev=$ZREPL_PREV_SNAPSHOT start=$ZREPL_PHASE_START elapsed=$ZREPL_PHASE_ELAPSED"
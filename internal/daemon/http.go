@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
 	"net"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/dsh2dsh/zrepl/internal/tlsconf"
 )
 
 type server struct {
@@ -16,19 +19,22 @@ type server struct {
 
 	listener net.Listener
 
-	certFile string
-	keyFile  string
+	certFile     string
+	keyFile      string
+	clientCAFile string
 
-	cert *tls.Certificate
-	mu   sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	mu        sync.RWMutex
 }
 
 func (self *server) Clone() *server {
 	return &server{
 		Server: self.Server,
 
-		certFile: self.certFile,
-		keyFile:  self.keyFile,
+		certFile:     self.certFile,
+		keyFile:      self.keyFile,
+		clientCAFile: self.clientCAFile,
 	}
 }
 
@@ -108,6 +114,25 @@ func (self *server) initTLSConfig() {
 		self.TLSConfig = new(tls.Config)
 	}
 	self.TLSConfig.GetCertificate = self.certificate
+	if self.clientCAFile != "" {
+		self.TLSConfig.GetConfigForClient = self.configForClient
+	}
+}
+
+// configForClient returns a clone of server's TLS config, which requires
+// client certificates, signed by currently loaded client CA.
+func (self *server) configForClient(*tls.ClientHelloInfo) (*tls.Config,
+	error,
+) {
+	self.mu.RLock()
+	clientCAs := self.clientCAs
+	self.mu.RUnlock()
+
+	c := self.TLSConfig.Clone()
+	c.GetConfigForClient = nil
+	c.ClientAuth = tls.RequireAndVerifyClientCert
+	c.ClientCAs = clientCAs
+	return c, nil
 }
 
 func (self *server) certificate(*tls.ClientHelloInfo) (*tls.Certificate,
@@ -127,19 +152,32 @@ func (self *server) LoadCert(log *slog.Logger) error {
 	if self.certFile == "" {
 		return nil
 	}
+	certName, keyName := tlsconf.Describe(self.certFile),
+		tlsconf.Describe(self.keyFile)
 	log.With(
-		slog.String("cert", self.certFile),
-		slog.String("key", self.keyFile),
+		slog.String("cert", certName),
+		slog.String("key", keyName),
 	).Info("load certificate")
 
-	cert, err := tls.LoadX509KeyPair(self.certFile, self.keyFile)
+	cert, err := tlsconf.LoadX509KeyPair(self.certFile, self.keyFile)
 	if err != nil {
 		return fmt.Errorf("failed load cert from %q, %q: %w",
-			self.certFile, self.keyFile, err)
+			certName, keyName, err)
+	}
+
+	var clientCAs *x509.CertPool
+	if self.clientCAFile != "" {
+		caName := tlsconf.Describe(self.clientCAFile)
+		log.With(slog.String("client_ca", caName)).Info("load client CA")
+		clientCAs, err = tlsconf.ParseCAFile(self.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed load client CA from %q: %w", caName, err)
+		}
 	}
 
 	self.mu.Lock()
 	self.cert = &cert
+	self.clientCAs = clientCAs
 	self.mu.Unlock()
 	return nil
 }
@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_configForClient(t *testing.T) {
+	clientCAs := x509.NewCertPool()
+	s := &server{
+		Server: &http.Server{TLSConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS13,
+			CipherSuites: []uint16{tls.TLS_AES_128_GCM_SHA256},
+			NextProtos:   []string{"http/1.1"},
+		}},
+		clientCAFile: "ca.crt",
+		cert:         new(tls.Certificate),
+		clientCAs:    clientCAs,
+	}
+	s.initTLSConfig()
+
+	c, err := s.configForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), c.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_AES_128_GCM_SHA256}, c.CipherSuites)
+	assert.Equal(t, []string{"http/1.1"}, c.NextProtos)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, c.ClientAuth)
+	assert.Same(t, clientCAs, c.ClientCAs)
+	assert.NotNil(t, c.GetCertificate)
+	assert.Nil(t, c.GetConfigForClient)
+	assert.Nil(t, s.TLSConfig.ClientCAs, "base config unchanged")
+}
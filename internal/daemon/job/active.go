@@ -2,10 +2,12 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/dsh2dsh/cron/v3"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/dsh2dsh/zrepl/internal/config"
 	"github.com/dsh2dsh/zrepl/internal/config/env"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job/signal"
 	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
 	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
@@ -21,6 +24,9 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/replication/driver"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic"
 	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/util/bandwidth"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
 type ActiveSide struct {
@@ -29,6 +35,12 @@ type ActiveSide struct {
 	connected Connected
 
 	replicationDriverConfig driver.Config
+	stepsTuner              *stepsTuner
+	blackout                blackout
+	verify                  config.ReplicationOptionsVerify
+	skipped                 skipList
+	paused                  pauseGate
+	bwlimit                 bwLimit
 
 	prunerFactory *pruner.PrunerFactory
 
@@ -37,12 +49,22 @@ type ActiveSide struct {
 	promBytesReplicated   *prometheus.CounterVec   // labels: filesystem
 	promReplicationErrors prometheus.Gauge
 	promLastSuccessful    prometheus.Gauge
+	promVerifyErrors      prometheus.Gauge
+	promFsLastSuccess     *prometheus.GaugeVec // labels: filesystem
+	promPruneLastSuccess  *prometheus.GaugeVec // labels: prune_side
+	promStepBytes         prometheus.Histogram
+	promStepSecs          prometheus.Histogram
+	promStepThroughput    prometheus.Histogram
 
 	tasksMtx sync.Mutex
 	tasks    activeSideTasks
 
-	preHook  *Hook
-	postHook *Hook
+	preHook    *Hook
+	postHook   *Hook
+	ping       *ping
+	poolImport *poolImport
+
+	overlap OverlapPolicy
 }
 
 var _ Job = (*ActiveSide)(nil)
@@ -53,6 +75,7 @@ const (
 	ActiveSideDone ActiveSideState = iota
 	ActiveSideSnapshot
 	ActiveSideReplicating
+	ActiveSideVerifying
 	ActiveSidePruneSender
 	ActiveSidePruneReceiver
 )
@@ -63,6 +86,8 @@ func (self ActiveSideState) String() string {
 		return "ActiveSideSnapshot"
 	case ActiveSideReplicating:
 		return "ActiveSideReplicating"
+	case ActiveSideVerifying:
+		return "ActiveSideVerifying"
 	case ActiveSidePruneSender:
 		return "ActiveSidePruneSender"
 	case ActiveSidePruneReceiver:
@@ -82,6 +107,10 @@ type activeSideTasks struct {
 	// ActiveSidePruneReceiver, ActiveSideDone
 	replicationReport driver.ReportFunc
 
+	// valid for state ActiveSideVerifying, ActiveSidePruneSender,
+	// ActiveSidePruneReceiver, ActiveSideDone
+	verifyReport *report.VerifyReport
+
 	// valid for state ActiveSidePruneSender, ActiveSidePruneReceiver,
 	// ActiveSideDone
 	prunerSender, prunerReceiver *pruner.Pruner
@@ -102,6 +131,7 @@ func (a *ActiveSide) updateTasks(u func(*activeSideTasks)) activeSideTasks {
 type activeMode interface {
 	ConnectEndpoints(ctx context.Context, cn Connected)
 	DisconnectEndpoints()
+	NewEndpoints(cn Connected) (logic.Sender, logic.Receiver)
 	SenderReceiver() (logic.Sender, logic.Receiver)
 	Type() Type
 	PlannerPolicy() logic.PlannerPolicy
@@ -138,15 +168,31 @@ func modePushFromConfig(g *config.Global, in *config.PushJob,
 		return nil, fmt.Errorf("field `conflict_resolution`: %w", err)
 	}
 
+	versionsLimit, err := logic.VersionsLimitFromConfig(
+		&in.Replication.VersionsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("field `replication.versions_limit`: %w", err)
+	}
+
+	intermediates, err := logic.IntermediatesFromConfig(
+		in.Replication.Intermediates)
+	if err != nil {
+		return nil, fmt.Errorf("field `replication.intermediates`: %w", err)
+	}
+
 	m.plannerPolicy = &logic.PlannerPolicy{
 		ConflictResolution: conflictResolution,
 		ReplicationConfig:  replicationConfig,
+		VersionsLimit:      versionsLimit,
+		Intermediates:      intermediates,
+		PlanningMemory:     int64(in.Replication.PlanningMemory),
 	}
 	if err := m.plannerPolicy.Validate(); err != nil {
 		return nil, fmt.Errorf("cannot build planner policy: %w", err)
 	}
 
-	m.snapper, err = snapper.FromConfig(g, m.senderConfig.FSF, in.Snapshotting)
+	m.snapper, err = snapper.FromConfig(g, in.Name, m.senderConfig.FSF,
+		in.Snapshotting)
 	if err != nil {
 		return nil, fmt.Errorf("cannot build snapper: %w", err)
 	}
@@ -195,11 +241,19 @@ func (m *modePush) ConnectEndpoints(ctx context.Context, cn Connected) {
 	).Info("connect to receiver")
 
 	m.receiver = cn.Endpoint()
-	m.sender = endpoint.NewSender(*m.senderConfig).
+	m.sender = m.newSender()
+}
+
+func (m *modePush) newSender() *endpoint.Sender {
+	return endpoint.NewSender(*m.senderConfig).
 		WithDrySendConcurrency(m.drySendConcurrency).
 		WithPruneConcurrency(m.pruneConcurrency)
 }
 
+func (m *modePush) NewEndpoints(cn Connected) (logic.Sender, logic.Receiver) {
+	return m.newSender(), cn.Endpoint()
+}
+
 func (m *modePush) DisconnectEndpoints() {
 	m.setupMtx.Lock()
 	defer m.setupMtx.Unlock()
@@ -244,6 +298,7 @@ type modePull struct {
 	receiver       *endpoint.Receiver
 	receiverConfig endpoint.ReceiverConfig
 	sender         Endpoint
+	fsf            *filters.DatasetFilter
 	plannerPolicy  *logic.PlannerPolicy
 	cronSpec       string
 
@@ -264,9 +319,24 @@ func (m *modePull) ConnectEndpoints(ctx context.Context, cn Connected) {
 		slog.String("from", cn.Name()),
 	).Info("connect to sender")
 
-	m.receiver = endpoint.NewReceiver(m.receiverConfig).
+	m.receiver = m.newReceiver()
+	m.sender = m.newSender(cn)
+}
+
+func (m *modePull) newSender(cn Connected) Endpoint {
+	if m.fsf == nil {
+		return cn.Endpoint()
+	}
+	return NewFilteredSender(cn.Endpoint(), m.fsf)
+}
+
+func (m *modePull) newReceiver() *endpoint.Receiver {
+	return endpoint.NewReceiver(m.receiverConfig).
 		WithPruneConcurrency(m.pruneConcurrency)
-	m.sender = cn.Endpoint()
+}
+
+func (m *modePull) NewEndpoints(cn Connected) (logic.Sender, logic.Receiver) {
+	return m.newSender(cn), m.newReceiver()
 }
 
 func (m *modePull) DisconnectEndpoints() {
@@ -326,14 +396,36 @@ func modePullFromConfig(in *config.PullJob, jobID endpoint.JobID,
 		return nil, fmt.Errorf("field `conflict_resolution`: %w", err)
 	}
 
+	versionsLimit, err := logic.VersionsLimitFromConfig(
+		&in.Replication.VersionsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("field `replication.versions_limit`: %w", err)
+	}
+
+	intermediates, err := logic.IntermediatesFromConfig(
+		in.Replication.Intermediates)
+	if err != nil {
+		return nil, fmt.Errorf("field `replication.intermediates`: %w", err)
+	}
+
 	m.plannerPolicy = &logic.PlannerPolicy{
 		ConflictResolution: conflictResolution,
 		ReplicationConfig:  replicationConfig,
+		VersionsLimit:      versionsLimit,
+		Intermediates:      intermediates,
+		PlanningMemory:     int64(in.Replication.PlanningMemory),
 	}
 	if err := m.plannerPolicy.Validate(); err != nil {
 		return nil, fmt.Errorf("cannot build planner policy: %w", err)
 	}
 
+	if in.Filesystems != nil || in.Datasets != nil {
+		m.fsf, err = filters.NewFromConfig(in.Filesystems, in.Datasets)
+		if err != nil {
+			return nil, fmt.Errorf("cannot build filesystem filter: %w", err)
+		}
+	}
+
 	m.receiverConfig, err = buildReceiverConfig(in, jobID)
 	if err != nil {
 		return nil, err
@@ -341,14 +433,23 @@ func modePullFromConfig(in *config.PullJob, jobID endpoint.JobID,
 	return m, nil
 }
 
-func replicationDriverConfigFromConfig(in *config.Replication) (driver.Config,
-	error,
-) {
+func replicationDriverConfigFromConfig(in *config.Replication, b blackout,
+) (driver.Config, error) {
 	c := driver.Config{
 		StepQueueConcurrency:     in.Concurrency.Steps,
 		MaxAttempts:              env.Values.ReplicationMaxAttempts,
 		Prefix:                   in.Prefix,
 		ReconnectHardFailTimeout: env.Values.ReplicationReconnectHardTimeout,
+
+		RetryInterval:    in.Retries.InitialInterval,
+		RetryMaxInterval: in.Retries.MaxInterval,
+		RetryMultiplier:  in.Retries.Multiplier,
+	}
+	if in.Retries.MaxAttempts > 0 {
+		c.MaxAttempts = in.Retries.MaxAttempts
+	}
+	if len(b) > 0 {
+		c.Blackout = b.Until
 	}
 	return c, c.Validate()
 }
@@ -406,6 +507,49 @@ func activeSide(g *config.Global, in *config.ActiveJob, configJob any,
 		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
 	})
 
+	j.promVerifyErrors = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "replication",
+		Name:        "verify_errors",
+		Help:        "number of filesystems that failed verification after the latest replication, or -1 if verification failed before enumerating the filesystems",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+	})
+
+	j.promFsLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "replication",
+		Name:        "last_success_timestamp_seconds",
+		Help:        "timestamp of last successful replication per filesystem",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+	}, []string{"filesystem"})
+
+	j.promStepBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "replication",
+		Name:        "step_bytes",
+		Help:        "number of bytes replicated by a step",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+		Buckets:     prometheus.ExponentialBuckets(1<<20, 4, 10), // 1MiB..256GiB
+	})
+
+	j.promStepSecs = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "replication",
+		Name:        "step_duration_seconds",
+		Help:        "seconds spent by a step",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+		Buckets:     prometheus.ExponentialBuckets(1, 3, 10), // 1s..5.5h
+	})
+
+	j.promStepThroughput = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "replication",
+		Name:        "step_throughput_bytes_per_second",
+		Help:        "bytes per second replicated by a step",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+		Buckets:     prometheus.ExponentialBuckets(64<<10, 2, 14), // 64KiB..512MiB
+	})
+
 	j.promPruneSecs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace:   "zrepl",
 		Subsystem:   "pruning",
@@ -414,22 +558,45 @@ func activeSide(g *config.Global, in *config.ActiveJob, configJob any,
 		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
 	}, []string{"prune_side"})
 
+	j.promPruneLastSuccess = newPromPruneLastSuccess(j.name.String())
+
 	j.prunerFactory, err = pruner.NewPrunerFactory(in.Pruning, j.promPruneSecs)
 	if err != nil {
 		return nil, err
 	}
 
-	j.replicationDriverConfig, err = replicationDriverConfigFromConfig(&in.Replication)
+	if j.overlap, err = overlapFromConfig(in.Overlap); err != nil {
+		return nil, fmt.Errorf("field `overlap`: %w", err)
+	}
+
+	if j.blackout, err = blackoutFromConfig(in.Blackout); err != nil {
+		return nil, fmt.Errorf("field `blackout`: %w", err)
+	}
+
+	j.replicationDriverConfig, err = replicationDriverConfigFromConfig(
+		&in.Replication, j.blackout)
 	if err != nil {
 		return nil, fmt.Errorf("cannot build replication driver config: %w", err)
 	}
 
+	j.stepsTuner = newStepsTuner(in.Replication.Concurrency.Steps,
+		in.Replication.Concurrency.Auto)
+	if j.stepsTuner != nil {
+		j.replicationDriverConfig.Concurrency = j.stepsTuner.Concurrency
+	}
+
+	j.replicationDriverConfig.Paused = j.paused.Paused
+
+	j.verify = in.Replication.Verify
+
 	if in.Hooks.Pre != nil {
 		j.preHook = NewHookFromConfig(in.Hooks.Pre)
 	}
 	if in.Hooks.Post != nil {
 		j.postHook = NewHookFromConfig(in.Hooks.Post).WithPostHook(true)
 	}
+	j.ping = newPing(in.PingURL)
+	j.poolImport = newPoolImport(in.PoolImport)
 
 	if j.connected, err = connecter.FromConfig(&in.Connect); err != nil {
 		return nil, fmt.Errorf("cannot build connect: %w", err)
@@ -437,20 +604,81 @@ func activeSide(g *config.Global, in *config.ActiveJob, configJob any,
 	return j, nil
 }
 
+func newPromPruneLastSuccess(jobName string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "prune",
+		Name:        "last_success_timestamp_seconds",
+		Help:        "timestamp of last successful pruning",
+		ConstLabels: prometheus.Labels{"zrepl_job": jobName},
+	}, []string{"prune_side"})
+}
+
 func (j *ActiveSide) RegisterMetrics(registerer prometheus.Registerer) {
 	registerer.MustRegister(j.promRepStateSecs)
 	registerer.MustRegister(j.promPruneSecs)
 	registerer.MustRegister(j.promBytesReplicated)
 	registerer.MustRegister(j.promReplicationErrors)
 	registerer.MustRegister(j.promLastSuccessful)
+	registerer.MustRegister(j.promVerifyErrors)
+	registerer.MustRegister(j.promFsLastSuccess)
+	registerer.MustRegister(j.promPruneLastSuccess)
+	registerer.MustRegister(j.promStepBytes)
+	registerer.MustRegister(j.promStepSecs)
+	registerer.MustRegister(j.promStepThroughput)
 }
 
 func (j *ActiveSide) Name() string { return j.name.String() }
 
+// Skip excludes filesystem fs from replication until until. Zero until
+// includes it back.
+func (j *ActiveSide) Skip(fs string, until time.Time) {
+	j.skipped.Skip(fs, until)
+}
+
+// SetBandwidthLimit limits bandwidth of replication to rate bytes per second,
+// including current transfers. Zero rate removes the limit.
+func (j *ActiveSide) SetBandwidthLimit(rate uint64) { j.bwlimit.Set(rate) }
+
+// BandwidthLimit returns current limit, set by SetBandwidthLimit.
+func (j *ActiveSide) BandwidthLimit() uint64 { return j.bwlimit.Rate() }
+
+// Pause stops dispatching of new replication steps, until Resume. If stopZFS is
+// true, it also suspends active zfs processes of the job with SIGSTOP.
+func (j *ActiveSide) Pause(stopZFS bool) error {
+	if !j.paused.Pause(stopZFS) {
+		return errors.New("replication already paused")
+	} else if !stopZFS {
+		return nil
+	}
+
+	if _, err := zfscmd.SignalJob(j.Name(), syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("suspend zfs processes: %w", err)
+	}
+	return nil
+}
+
+// Resume continues replication, paused by Pause.
+func (j *ActiveSide) Resume() error {
+	ok, stopped := j.paused.Resume()
+	if !ok {
+		return errors.New("replication not paused")
+	} else if !stopped {
+		return nil
+	}
+
+	if _, err := zfscmd.SignalJob(j.Name(), syscall.SIGCONT); err != nil {
+		return fmt.Errorf("continue zfs processes: %w", err)
+	}
+	return nil
+}
+
 func (j *ActiveSide) Cron() string { return j.mode.Cron() }
 
 func (j *ActiveSide) Runnable() bool { return j.mode.Runnable() }
 
+func (j *ActiveSide) Overlap() OverlapPolicy { return j.overlap }
+
 func (j *ActiveSide) Status() *Status {
 	tasks := j.updateTasks(nil)
 	activeStatus := &ActiveSideStatus{
@@ -467,6 +695,11 @@ func (j *ActiveSide) Status() *Status {
 		activeStatus.Replication = tasks.replicationReport()
 	}
 
+	activeStatus.Verify = tasks.verifyReport
+	activeStatus.Skipped = j.skipped.Report()
+	activeStatus.Paused = j.paused.Paused() != nil
+	activeStatus.BandwidthLimit = j.bwlimit.Rate()
+
 	if tasks.prunerSender != nil {
 		activeStatus.PruningSender = tasks.prunerSender.Report()
 	}
@@ -476,6 +709,7 @@ func (j *ActiveSide) Status() *Status {
 	}
 
 	return &Status{
+		JobID:       j.name,
 		CanWakeup:   true,
 		Type:        j.mode.Type(),
 		JobSpecific: activeStatus,
@@ -488,8 +722,12 @@ type ActiveSideStatus struct {
 	Err       string
 
 	Replication                    *report.Report
+	Verify                         *report.VerifyReport
 	PruningSender, PruningReceiver *pruner.Report
 	Snapshotting                   *snapper.Report
+	Skipped                        []SkippedFilesystem `json:",omitempty"`
+	Paused                         bool                `json:",omitempty"`
+	BandwidthLimit                 uint64              `json:",omitempty"`
 }
 
 func (self *ActiveSideStatus) Error() string {
@@ -509,6 +747,12 @@ func (self *ActiveSideStatus) Error() string {
 		}
 	}
 
+	if v := self.Verify; v != nil {
+		if s := v.Error(); s != "" {
+			return "verify: " + s
+		}
+	}
+
 	if prun := self.PruningSender; prun != nil {
 		if prun.Error != "" {
 			return prun.Error
@@ -660,18 +904,30 @@ func (j *ActiveSide) Run(ctx context.Context) error {
 	j.mode.ConnectEndpoints(ctx, j.connected)
 	defer j.mode.DisconnectEndpoints()
 
+	exportPool := func() error { return nil }
 	steps := []func(context.Context) error{
 		func(context.Context) error { return j.before(ctx) },
+		func(ctx context.Context) (err error) {
+			exportPool, err = j.importPool(ctx)
+			return err
+		},
 		j.snapshot,
 		func(context.Context) error { return j.replicate(ctx) },
+		j.verifyReplication,
 		j.pruneSender,
 		j.pruneReceiver,
 		func(context.Context) error { return j.afterPruning(ctx) },
 	}
 
+	begin := j.ping.Start(ctx)
 	if j.activeSteps(signal.GracefulFrom(ctx), steps) {
 		log.Info("task completed")
 	}
+	if err := exportPool(); err != nil {
+		logger.WithError(log, err, "failed export pool")
+		j.updateTasks(func(tasks *activeSideTasks) { tasks.err = err })
+	}
+	j.ping.Finish(ctx, j, begin)
 	return nil
 }
 
@@ -709,6 +965,16 @@ func (j *ActiveSide) before(ctx context.Context) error {
 	return nil
 }
 
+func (j *ActiveSide) importPool(ctx context.Context) (func() error, error) {
+	exportPool, err := j.poolImport.Import(ctx)
+	if err != nil {
+		logger.WithError(GetLogger(ctx), err, "failed import pool")
+		j.updateTasks(func(tasks *activeSideTasks) { tasks.err = err })
+		return exportPool, err
+	}
+	return exportPool, nil
+}
+
 func (j *ActiveSide) runLocalPreHook(ctx context.Context) error {
 	h := j.preHook
 	if h == nil {
@@ -748,13 +1014,33 @@ func (j *ActiveSide) snapshot(ctx context.Context) error {
 }
 
 func (j *ActiveSide) replicate(ctx context.Context) error {
+	log := GetLogger(ctx)
+	if until := j.blackout.Until(time.Now()); !until.IsZero() {
+		log.With(slog.Time("until", until)).
+			Info("skip replication inside of blackout window")
+		return nil
+	}
+
+	release, err := j.connected.Schedule(ctx)
+	if err != nil {
+		return fmt.Errorf("wait for connect host schedule: %w", err)
+	}
+	defer release()
+
 	if err := j.runRemotePreHook(ctx); err != nil {
 		return err
 	}
-
-	log := GetLogger(ctx)
 	log.Info("start replication")
 
+	if j.stepsTuner != nil {
+		tunerCtx, stopTuner := context.WithCancel(ctx)
+		defer stopTuner()
+		go j.stepsTuner.Run(tunerCtx, j.bytesReplicated)
+	}
+
+	// one replication run gets the same properties of datasets many times
+	ctx = zfs.WithPropertiesCache(ctx)
+	ctx = bandwidth.WithLimiter(ctx, j.bwlimit.Limiter())
 	var repWait driver.WaitFunc
 	p := j.planner()
 	j.updateTasks(func(tasks *activeSideTasks) {
@@ -770,12 +1056,25 @@ func (j *ActiveSide) replicate(ctx context.Context) error {
 	if numErrors == 0 {
 		j.promLastSuccessful.SetToCurrentTime()
 	}
+	for _, name := range replicationReport.SucceededFilesystems() {
+		j.promFsLastSuccess.WithLabelValues(name).SetToCurrentTime()
+	}
 	log.Info("finished replication")
 
 	j.runRemotePostHook(ctx)
 	return nil
 }
 
+// bytesReplicated returns number of bytes replicated by current attempt of
+// replication.
+func (j *ActiveSide) bytesReplicated() uint64 {
+	if r := j.updateTasks(nil).replicationReport; r != nil {
+		_, replicated := r().Progress()
+		return replicated
+	}
+	return 0
+}
+
 func (j *ActiveSide) runRemotePreHook(ctx context.Context) error {
 	log := GetLogger(ctx)
 	log.Info("run remote pre hook")
@@ -810,7 +1109,73 @@ func (j *ActiveSide) planner() *logic.Planner {
 	sender, receiver := j.mode.SenderReceiver()
 	p := logic.NewPlanner(j.promRepStateSecs, j.promBytesReplicated,
 		sender, receiver, j.mode.PlannerPolicy())
-	return p
+	return p.WithSkip(j.skipped.Skipped).WithStepMetrics(&logic.StepMetrics{
+		Bytes:      j.promStepBytes,
+		Duration:   j.promStepSecs,
+		Throughput: j.promStepThroughput,
+	})
+}
+
+func (j *ActiveSide) verifyReplication(ctx context.Context) error {
+	if !j.verify.Enabled {
+		return nil
+	}
+
+	tasks := j.updateTasks(func(tasks *activeSideTasks) {
+		tasks.state = ActiveSideVerifying
+	})
+	if tasks.replicationReport == nil {
+		return nil
+	}
+
+	sender, receiver := j.mode.SenderReceiver()
+	r := j.doVerify(ctx, sender, receiver, tasks.replicationReport())
+	j.updateTasks(func(tasks *activeSideTasks) { tasks.verifyReport = r })
+	return nil
+}
+
+func (j *ActiveSide) doVerify(ctx context.Context, sender logic.Sender,
+	receiver logic.Receiver, replication *report.Report,
+) *report.VerifyReport {
+	p := logic.NewPlanner(nil, nil, sender, receiver, j.mode.PlannerPolicy())
+	r := p.Verify(ctx)
+	r.JobID = j.Name()
+	if j.verify.Size {
+		r.CheckSizes(replication, j.verify.SizeTolerance)
+	}
+	j.promVerifyErrors.Set(float64(r.Failed()))
+	return r
+}
+
+// Verify compares sender and receiver of this job right now, without running
+// replication. Sizes are checked against the latest replication, if any.
+func (j *ActiveSide) Verify(ctx context.Context) *report.VerifyReport {
+	var replication *report.Report
+	if tasks := j.updateTasks(nil); tasks.replicationReport != nil {
+		replication = tasks.replicationReport()
+	}
+	sender, receiver := j.mode.NewEndpoints(j.connected)
+	return j.doVerify(ctx, sender, receiver, replication)
+}
+
+// Adopt adopts receiver filesystems, seeded by other means, like manual zfs
+// send and recv, so next replication of them will be incremental.
+func (j *ActiveSide) Adopt(ctx context.Context, dryRun bool,
+) *report.VerifyReport {
+	sender, receiver := j.mode.NewEndpoints(j.connected)
+	p := logic.NewPlanner(nil, nil, sender, receiver, j.mode.PlannerPolicy())
+	r := p.Adopt(ctx, dryRun)
+	r.JobID = j.Name()
+	return r
+}
+
+// PruneDryRun reports snapshots, which keep rules of sender and receiver would
+// destroy right now.
+func (j *ActiveSide) PruneDryRun(ctx context.Context) []*pruner.DryRunReport {
+	sender, receiver := j.mode.NewEndpoints(j.connected)
+	senderPruner := j.prunerFactory.BuildSenderPruner(ctx, sender, sender)
+	receiverPruner := j.prunerFactory.BuildReceiverPruner(ctx, receiver, sender)
+	return []*pruner.DryRunReport{senderPruner.DryRun(), receiverPruner.DryRun()}
 }
 
 func (j *ActiveSide) pruneSender(ctx context.Context) error {
@@ -828,6 +1193,9 @@ func (j *ActiveSide) pruneSender(ctx context.Context) error {
 
 	begin := time.Now()
 	tasks.prunerSender.Prune()
+	if tasks.prunerSender.Report().Succeeded() {
+		j.promPruneLastSuccess.WithLabelValues("sender").SetToCurrentTime()
+	}
 	log.With(slog.Duration("duration", time.Since(begin))).
 		Info("finished pruning sender")
 	return nil
@@ -847,6 +1215,9 @@ func (j *ActiveSide) pruneReceiver(ctx context.Context) error {
 
 	begin := time.Now()
 	tasks.prunerReceiver.Prune()
+	if tasks.prunerReceiver.Report().Succeeded() {
+		j.promPruneLastSuccess.WithLabelValues("receiver").SetToCurrentTime()
+	}
 	log.With(slog.Duration("duration", time.Since(begin))).
 		Info("finished pruning receiver")
 	return nil
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func bandwidthScheduleFromConfig(otherwise uint64,
+	in []config.BandwidthWindow,
+) (*bandwidthSchedule, error) {
+	s := &bandwidthSchedule{
+		windows:   make([]bandwidthWindow, len(in)),
+		otherwise: otherwise,
+	}
+	for i := range in {
+		w := &in[i]
+		err := s.windows[i].init(&config.BlackoutWindow{
+			From: w.From, To: w.To, Days: w.Days,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("window #%d: %w", i, err)
+		}
+		s.windows[i].rate = w.Bandwidth.Uint64()
+	}
+	return s, nil
+}
+
+// bandwidthSchedule changes bandwidth by time of day.
+type bandwidthSchedule struct {
+	windows   []bandwidthWindow
+	otherwise uint64
+}
+
+type bandwidthWindow struct {
+	blackoutWindow
+	rate uint64
+}
+
+// Rate returns bandwidth of the first window, which contains t, or bandwidth
+// outside of windows. Zero means no limit.
+func (self *bandwidthSchedule) Rate(t time.Time) uint64 {
+	for i := range self.windows {
+		if _, ok := self.windows[i].end(t); ok {
+			return self.windows[i].rate
+		}
+	}
+	return self.otherwise
+}
@@ -0,0 +1,54 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestBandwidthSchedule_Rate(t *testing.T) {
+	s, err := bandwidthScheduleFromConfig(100, []config.BandwidthWindow{
+		{
+			From: "08:00", To: "18:00",
+			Days:      []string{"mon", "tue", "wed", "thu", "fri"},
+			Bandwidth: 10,
+		},
+		{From: "12:00", To: "13:00", Bandwidth: 20},
+		{From: "22:00", To: "06:00"},
+	})
+	require.NoError(t, err)
+
+	// 2024-05-06 is monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want uint64
+	}{
+		{name: "otherwise", t: at(6, 7, 59), want: 100},
+		{name: "office hours", t: at(6, 8, 0), want: 10},
+		{name: "first window wins", t: at(6, 12, 30), want: 10},
+		{name: "weekend lunch", t: at(11, 12, 30), want: 20},
+		{name: "weekend", t: at(11, 9, 0), want: 100},
+		{name: "night unlimited", t: at(6, 23, 0)},
+		{name: "after midnight", t: at(7, 5, 59)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Rate(tt.t))
+		})
+	}
+
+	_, err = bandwidthScheduleFromConfig(0, []config.BandwidthWindow{
+		{From: "08:00", To: "6pm"},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func blackoutFromConfig(in []config.BlackoutWindow) (blackout, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	windows := make(blackout, len(in))
+	for i := range in {
+		if err := windows[i].init(&in[i]); err != nil {
+			return nil, fmt.Errorf("blackout window #%d: %w", i, err)
+		}
+	}
+	return windows, nil
+}
+
+// blackout is a list of time windows, when replication must not run.
+type blackout []blackoutWindow
+
+// Until returns the end of blackout, which contains t, or zero time if t is
+// outside of any window. Adjacent and overlapping windows are merged.
+func (self blackout) Until(t time.Time) time.Time {
+	var until time.Time
+	for range len(self) {
+		end, ok := self.end(t)
+		if !ok {
+			break
+		}
+		until, t = end, end
+	}
+	return until
+}
+
+func (self blackout) end(t time.Time) (end time.Time, ok bool) {
+	for i := range self {
+		if e, ok2 := self[i].end(t); ok2 && e.After(end) {
+			end, ok = e, true
+		}
+	}
+	return end, ok
+}
+
+type blackoutWindow struct {
+	from, to int // minutes since midnight
+	days     [7]bool
+	anyDay   bool
+}
+
+func (self *blackoutWindow) init(in *config.BlackoutWindow) (err error) {
+	if self.from, err = parseTimeOfDay(in.From); err != nil {
+		return fmt.Errorf("field `from`: %w", err)
+	} else if self.to, err = parseTimeOfDay(in.To); err != nil {
+		return fmt.Errorf("field `to`: %w", err)
+	}
+
+	self.anyDay = len(in.Days) == 0
+	for _, s := range in.Days {
+		d, ok := weekdays[s]
+		if !ok {
+			return fmt.Errorf("field `days`: unknown day %q", s)
+		}
+		self.days[d] = true
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("parse %q as HH:MM: %w", s, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// end returns the end of this window, if it contains t. The day restriction
+// applies to the day the window begins.
+func (self *blackoutWindow) end(t time.Time) (time.Time, bool) {
+	for _, day := range [...]time.Time{t.AddDate(0, 0, -1), t} {
+		if !self.anyDay && !self.days[day.Weekday()] {
+			continue
+		}
+		begin := timeOfDay(day, self.from)
+		end := timeOfDay(day, self.to)
+		if self.to <= self.from {
+			end = timeOfDay(day.AddDate(0, 0, 1), self.to)
+		}
+		if !t.Before(begin) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func timeOfDay(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0,
+		day.Location())
+}
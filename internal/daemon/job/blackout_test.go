@@ -0,0 +1,55 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestBlackout_Until(t *testing.T) {
+	b, err := blackoutFromConfig([]config.BlackoutWindow{
+		{From: "08:00", To: "18:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}},
+		{From: "18:00", To: "19:30", Days: []string{"fri"}},
+		{From: "23:00", To: "01:00"},
+	})
+	require.NoError(t, err)
+
+	// 2024-05-06 is monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{name: "before window", t: at(6, 7, 59)},
+		{name: "inside window", t: at(6, 8, 0), want: at(6, 18, 0)},
+		{name: "window end", t: at(6, 18, 0)},
+		{name: "weekend", t: at(11, 12, 0)},
+		{name: "merged windows", t: at(10, 17, 0), want: at(10, 19, 30)},
+		{name: "overnight before midnight", t: at(7, 23, 30), want: at(8, 1, 0)},
+		{name: "overnight after midnight", t: at(8, 0, 30), want: at(8, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, b.Until(tt.t))
+		})
+	}
+}
+
+func TestBlackout_invalid(t *testing.T) {
+	_, err := blackoutFromConfig([]config.BlackoutWindow{
+		{From: "8am", To: "18:00"},
+	})
+	require.Error(t, err)
+
+	var b blackout
+	assert.True(t, b.Until(time.Now()).IsZero())
+}
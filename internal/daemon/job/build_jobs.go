@@ -8,7 +8,11 @@ import (
 
 func JobsFromConfig(c *config.Config) ([]Job, *Connecter, error) {
 	jobs := make([]Job, len(c.Jobs))
-	connecter := NewConnecter(c.Keys).WithTimeout(c.Global.RpcTimeout)
+	connecter, err := NewConnecter(c.Keys).WithTimeout(c.Global.RpcTimeout).
+		WithRPC(&c.Global.RPC).WithHosts(c.Global.ConnectHosts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("global connect_hosts: %w", err)
+	}
 
 	for i := range c.Jobs {
 		j, err := buildJob(&c.Global, c.Jobs[i], connecter)
@@ -52,6 +56,11 @@ func buildJob(c *config.Global, in config.JobEnum, connecter *Connecter,
 		if err != nil {
 			return cannotBuildJob(err, v.Name)
 		}
+	case *config.PruneJob:
+		j, err = pruneJobFromConfig(v)
+		if err != nil {
+			return cannotBuildJob(err, v.Name)
+		}
 	case *config.PushJob:
 		j, err = activeSide(c, &v.ActiveJob, v, connecter)
 		if err != nil {
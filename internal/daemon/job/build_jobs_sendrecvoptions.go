@@ -36,8 +36,17 @@ func buildSenderConfig(in SendingJobConfig, jobID endpoint.JobID) (*endpoint.Sen
 		SendCompressed:       sendOpts.Compressed,
 		SendEmbeddedData:     sendOpts.EmbeddedData,
 		SendSaved:            sendOpts.Saved,
+		SendHolds:            sendOpts.Holds,
+
+		StorageClassProperty: sendOpts.StorageClassProperty,
 
 		ExecPipe: sendOpts.ExecPipe,
+		Buffer:   int(sendOpts.Buffer.Size),
+	}
+
+	sc.StorageClasses, err = buildSendStorageClasses(sendOpts.StorageClasses)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := sc.Validate(); err != nil {
@@ -47,8 +56,29 @@ func buildSenderConfig(in SendingJobConfig, jobID endpoint.JobID) (*endpoint.Sen
 	return sc, nil
 }
 
+func buildSendStorageClasses(in []config.SendStorageClass,
+) ([]endpoint.SendStorageClass, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	classes := make([]endpoint.SendStorageClass, len(in))
+	for i := range in {
+		fsf, err := filters.NewFromConfig(in[i].Filesystems, in[i].Datasets)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot build filesystem filter of storage class %q: %w",
+				in[i].Class, err)
+		}
+		classes[i] = endpoint.SendStorageClass{Name: in[i].Class, FSF: fsf}
+	}
+	return classes, nil
+}
+
 type ReceivingJobConfig interface {
 	GetRootFS() string
+	GetRootFSMap() []config.RootFSMapping
+	GetStorageClasses() []config.StorageClass
 	GetAppendClientIdentity() bool
 	GetRecvOptions() *config.RecvOptions
 }
@@ -63,7 +93,21 @@ func buildReceiverConfig(in ReceivingJobConfig, jobID endpoint.JobID,
 		return rc, errors.New("root_fs must not be empty")
 	}
 
+	rootMap, err := buildRootMap(in.GetRootFSMap())
+	if err != nil {
+		return rc, err
+	}
+
+	storageClasses, err := buildStorageClasses(in.GetStorageClasses())
+	if err != nil {
+		return rc, err
+	}
+
 	recvOpts := in.GetRecvOptions()
+	datasetProperties, err := buildDatasetProperties(recvOpts.DatasetProperties)
+	if err != nil {
+		return rc, err
+	}
 
 	placeholderEncryption, err := endpoint.
 		PlaceholderCreationEncryptionPropertyString(
@@ -83,12 +127,23 @@ func buildReceiverConfig(in ReceivingJobConfig, jobID endpoint.JobID,
 		JobID:                      jobID,
 		RootWithoutClientComponent: rootFs,
 		AppendClientIdentity:       in.GetAppendClientIdentity(),
+		RootMap:                    rootMap,
+		StorageClasses:             storageClasses,
 
 		InheritProperties:     recvOpts.Properties.Inherit,
 		OverrideProperties:    recvOpts.Properties.Override,
+		ReencryptProperties:   recvOpts.Reencrypt.Properties(),
+		DatasetProperties:     datasetProperties,
 		PlaceholderEncryption: placeholderEncryption,
+		MountpointCollision: endpoint.MountpointCollision(
+			recvOpts.MountpointCollision),
+		FreeSpace: endpoint.FreeSpace{
+			Enabled:  recvOpts.FreeSpace.Enabled,
+			Headroom: recvOpts.FreeSpace.Headroom,
+		},
 
 		ExecPipe: recvOpts.ExecPipe,
+		Buffer:   int(recvOpts.Buffer.Size),
 	}
 
 	if err = rc.Validate(); err != nil {
@@ -96,3 +151,74 @@ func buildReceiverConfig(in ReceivingJobConfig, jobID endpoint.JobID,
 	}
 	return rc, err
 }
+
+func buildRootMap(in []config.RootFSMapping) ([]endpoint.RootMapping, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	rootMap := make([]endpoint.RootMapping, len(in))
+	for i := range in {
+		prefix, err := zfs.NewDatasetPath(in[i].Prefix)
+		if err != nil || prefix.Length() <= 0 {
+			return nil, fmt.Errorf(
+				"root_fs_map prefix %q is not a valid zfs filesystem path",
+				in[i].Prefix)
+		}
+		rootFs, err := zfs.NewDatasetPath(in[i].RootFS)
+		if err != nil || rootFs.Length() <= 0 {
+			return nil, fmt.Errorf(
+				"root_fs_map root_fs %q is not a valid zfs filesystem path",
+				in[i].RootFS)
+		}
+		rootMap[i] = endpoint.RootMapping{Prefix: prefix, Root: rootFs}
+	}
+	return rootMap, nil
+}
+
+func buildStorageClasses(in []config.StorageClass,
+) ([]endpoint.StorageClass, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	classes := make([]endpoint.StorageClass, len(in))
+	for i := range in {
+		rootFs, err := zfs.NewDatasetPath(in[i].RootFS)
+		if err != nil || rootFs.Length() <= 0 {
+			return nil, fmt.Errorf(
+				"storage class %q root_fs %q is not a valid zfs filesystem path",
+				in[i].Class, in[i].RootFS)
+		}
+		classes[i] = endpoint.StorageClass{
+			Name:               in[i].Class,
+			Root:               rootFs,
+			InheritProperties:  in[i].Properties.Inherit,
+			OverrideProperties: in[i].Properties.Override,
+		}
+	}
+	return classes, nil
+}
+
+func buildDatasetProperties(in []config.RecvDatasetProperties,
+) ([]endpoint.DatasetProperties, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	props := make([]endpoint.DatasetProperties, len(in))
+	for i := range in {
+		fsf, err := filters.NewFromConfig(in[i].Filesystems, in[i].Datasets)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"cannot build filesystem filter of dataset properties #%d: %w",
+				i+1, err)
+		}
+		props[i] = endpoint.DatasetProperties{
+			FSF:                fsf,
+			InheritProperties:  in[i].Properties.Inherit,
+			OverrideProperties: in[i].Properties.Override,
+		}
+	}
+	return props, nil
+}
@@ -131,19 +131,19 @@ jobs:
 			c := cases[i]
 
 			conf, err := config.ParseConfigBytes("", []byte(fill(c.jobName)))
-			require.NoError(t, err, "not expecting yaml-config to know about job ids")
-			require.NotNil(t, conf)
-			jobs, _, err := JobsFromConfig(conf)
-
-			if c.valid {
-				require.NoError(t, err)
-				require.Len(t, jobs, 2)
-				assert.Equal(t, c.jobName, jobs[0].Name())
-			} else {
+			if !c.valid {
 				t.Logf("error: %s", err)
-				require.Error(t, err)
-				assert.Nil(t, jobs)
+				require.Error(t, err, "expecting yaml-config to validate job ids")
+				assert.Nil(t, conf)
+				return
 			}
+			require.NoError(t, err)
+			require.NotNil(t, conf)
+
+			jobs, _, err := JobsFromConfig(conf)
+			require.NoError(t, err)
+			require.Len(t, jobs, 2)
+			assert.Equal(t, c.jobName, jobs[0].Name())
 		})
 	}
 }
@@ -327,3 +327,107 @@ jobs:
 		})
 	}
 }
+
+func TestPruneJob(t *testing.T) {
+	conf, err := config.ParseConfigBytes("", []byte(`
+jobs:
+- name: foo
+  type: prune
+  cron: "15 * * * *"
+  filesystems: {"<": true}
+  pruning:
+    keep:
+    - type: last_n
+      count: 10
+`))
+	require.NoError(t, err)
+
+	jobs, _, err := JobsFromConfig(conf)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	j, ok := jobs[0].(*PruneJob)
+	require.True(t, ok)
+	assert.Equal(t, "15 * * * *", j.Cron())
+	assert.False(t, j.Runnable())
+	assert.Equal(t, TypePrune, j.Status().Type)
+	assert.Implements(t, (*PruneDryRunner)(nil), j)
+}
+
+func TestPruneJob_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+	}{
+		{
+			name: "without cron",
+			conf: `
+jobs:
+- name: foo
+  type: prune
+  filesystems: {"<": true}
+  pruning:
+    keep:
+    - type: last_n
+      count: 10
+`,
+		},
+		{
+			name: "not_replicated",
+			conf: `
+jobs:
+- name: foo
+  type: prune
+  cron: "15 * * * *"
+  filesystems: {"<": true}
+  pruning:
+    keep:
+    - type: not_replicated
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := config.ParseConfigBytes("", []byte(tt.conf))
+			if err == nil {
+				_, _, err = JobsFromConfig(conf)
+			}
+			t.Log(err)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSourceJob_clientFilesystems(t *testing.T) {
+	conf, err := config.ParseConfigBytes("", []byte(`
+jobs:
+- name: foo
+  type: source
+  filesystems: {"tank<": true}
+  snapshotting:
+    type: manual
+  client_keys: ["a", "b"]
+  client_filesystems:
+    a:
+      filesystems: {"tank/a<": true}
+`))
+	require.NoError(t, err)
+
+	jobs, _, err := JobsFromConfig(conf)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	j, ok := jobs[0].(*PassiveSide)
+	require.True(t, ok)
+	m, ok := j.mode.(*modeSource)
+	require.True(t, ok)
+	require.Contains(t, m.clientFilters, "a")
+	assert.NotNil(t, m.clientFilters["a"])
+	assert.NotContains(t, m.clientFilters, "b")
+
+	conf.Jobs[0].Ret.(*config.SourceJob).ClientFilesystems["c"] = config.
+		ClientFilesystems{Filesystems: config.FilesystemsFilter{"tank/c<": true}}
+	_, _, err = JobsFromConfig(conf)
+	require.ErrorContains(t, err, `client "c" not in client_keys`)
+}
@@ -0,0 +1,41 @@
+package job
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/util/bandwidth"
+)
+
+// BandwidthLimiter is a job, which can limit bandwidth of its replication at
+// runtime.
+type BandwidthLimiter interface {
+	SetBandwidthLimit(rate uint64)
+	BandwidthLimit() uint64
+}
+
+// bwLimit is a bandwidth limit of a job, changed at runtime. Zero rate means no
+// limit.
+type bwLimit struct {
+	rate atomic.Uint64
+
+	once    sync.Once
+	limiter *bandwidth.Limiter
+}
+
+// Set changes the limit to rate bytes per second. Transfers, which already use
+// the limiter, switch to new rate on their next read.
+func (self *bwLimit) Set(rate uint64) { self.rate.Store(rate) }
+
+// Rate returns current limit in bytes per second.
+func (self *bwLimit) Rate() uint64 { return self.rate.Load() }
+
+// Limiter returns [bandwidth.Limiter], which limits transfers by current rate.
+func (self *bwLimit) Limiter() *bandwidth.Limiter {
+	self.once.Do(func() {
+		self.limiter = bandwidth.NewScheduledLimiter(
+			func(time.Time) uint64 { return self.rate.Load() }, 0)
+	})
+	return self.limiter
+}
@@ -0,0 +1,35 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBwLimit(t *testing.T) {
+	var l bwLimit
+	assert.Zero(t, l.Rate())
+	limiter := l.Limiter()
+	require.NotNil(t, limiter)
+	assert.Same(t, limiter, l.Limiter())
+
+	ctx := context.Background()
+	started := time.Now()
+	require.NoError(t, limiter.Wait(ctx, 10<<20))
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+
+	l.Set(1 << 20)
+	assert.Equal(t, uint64(1<<20), l.Rate())
+	started = time.Now()
+	require.NoError(t, limiter.Wait(ctx, 1<<20))
+	require.NoError(t, limiter.Wait(ctx, 100<<10))
+	assert.Greater(t, time.Since(started), 50*time.Millisecond)
+
+	l.Set(0)
+	started = time.Now()
+	require.NoError(t, limiter.Wait(ctx, 10<<20))
+	assert.Less(t, time.Since(started), 100*time.Millisecond)
+}
@@ -2,12 +2,20 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/url"
 	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/client/jsonclient"
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+	"github.com/dsh2dsh/zrepl/internal/logger"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/util/bandwidth"
+	"github.com/dsh2dsh/zrepl/internal/util/journal"
+	"github.com/dsh2dsh/zrepl/internal/util/timeoutio"
 )
 
 const (
@@ -17,6 +25,7 @@ const (
 	EpWaitForConnectivity
 
 	EpReceive
+	EpAdopt
 
 	EpSend
 	EpSendDry
@@ -35,7 +44,8 @@ var allEndpoints = [numEndpoints]string{
 	"/zfs/destroy/",   // epListFilesystemVersions
 	"/zfs/health/",    // epWaitForConnectivity
 
-	"/zfs/recv/", // epReceive
+	"/zfs/recv/",  // epReceive
+	"/zfs/adopt/", // epAdopt
 
 	"/zfs/send/",    // epSend
 	"/zfs/drysend/", // epSendDry
@@ -68,6 +78,10 @@ type Client struct {
 	endpoints  []string
 
 	timeout time.Duration
+	limiter *bandwidth.Limiter
+	journal int
+
+	readTimeout, writeTimeout time.Duration
 }
 
 var _ Endpoint = (*Client)(nil)
@@ -79,6 +93,26 @@ func (self *Client) WithTimeout(d time.Duration) *Client {
 	return self
 }
 
+// WithLimiter limits bandwidth of send and receive streams by l.
+func (self *Client) WithLimiter(l *bandwidth.Limiter) *Client {
+	self.limiter = l
+	return self
+}
+
+// WithStreamTimeouts fails send streams, which reads block longer than read,
+// and receive streams, which can't be written for longer than write.
+func (self *Client) WithStreamTimeouts(read, write time.Duration) *Client {
+	self.readTimeout, self.writeTimeout = read, write
+	return self
+}
+
+// WithJournal keeps size last bytes of every stream sent by Receive, for
+// continuing it after a broken request.
+func (self *Client) WithJournal(size int) *Client {
+	self.journal = size
+	return self
+}
+
 func (self *Client) endpoint(i int) string { return self.endpoints[i] }
 
 func (self *Client) json() *jsonclient.Client { return self.jsonClient }
@@ -139,22 +173,87 @@ func (self *Client) Receive(ctx context.Context, req *pdu.ReceiveReq,
 	receive io.ReadCloser,
 ) error {
 	defer receive.Close()
+	receive = self.limiter.Reader(ctx, receive)
+	if self.writeTimeout > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		receive = timeoutio.NewSource(receive, self.writeTimeout, cancel)
+	}
+
 	ep := self.endpoint(EpReceive)
+	if self.journal > 0 {
+		return self.receiveJournaled(ctx, ep, req, receive)
+	}
+
 	if err := self.json().PostStream(ctx, ep, req, nil, receive); err != nil {
 		return fmt.Errorf("endpoint %q: %w", ep, err)
 	}
 	return nil
 }
 
+// receiveJournaled sends the stream again from the oldest kept byte, if the
+// request broke because of transport error. Receiver skips bytes it already
+// has.
+func (self *Client) receiveJournaled(ctx context.Context, ep string,
+	req *pdu.ReceiveReq, receive io.Reader,
+) error {
+	jreq := *req
+	jreq.StreamSession = journal.NewSessionID()
+	replay := journal.NewReplay(receive, self.journal)
+
+	for attempt := 0; ; attempt++ {
+		r := replay.Rewind()
+		jreq.StreamOffset = r.Offset()
+		err := self.json().PostStream(ctx, ep, &jreq, nil, r)
+		if err == nil {
+			return nil
+		} else if _, ok := errors.AsType[*url.Error](err); !ok ||
+			attempt >= env.Values.StreamJournalRetries || ctx.Err() != nil {
+			return fmt.Errorf("endpoint %q: %w", ep, err)
+		}
+
+		logger.WithError(GetLogger(ctx).With(
+			slog.String("fs", req.Filesystem),
+			slog.String("session", jreq.StreamSession),
+			slog.Int("attempt", attempt+1),
+		), err, "stream broken, continue it")
+
+		t := time.NewTimer(time.Duration(attempt+1) * 100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return context.Cause(ctx)
+		case <-t.C:
+		}
+	}
+}
+
 func (self *Client) Send(ctx context.Context, req *pdu.SendReq,
 ) (*pdu.SendRes, io.ReadCloser, error) {
 	ep := self.endpoint(EpSend)
+	ctx, cancel := context.WithCancelCause(ctx)
 	resp := new(pdu.SendRes)
 	r, err := self.json().PostResponseStream(ctx, ep, req, resp)
 	if err != nil {
+		cancel(nil)
 		return nil, nil, fmt.Errorf("endpoint %q: %w", ep, err)
 	}
-	return resp, r, nil
+	r = timeoutio.NewReader(&cancelReadCloser{ReadCloser: r, cancel: cancel},
+		self.readTimeout, cancel)
+	return resp, self.limiter.Reader(ctx, r), nil
+}
+
+// cancelReadCloser cancels context of a stream, when it's closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+
+	cancel context.CancelCauseFunc
+}
+
+func (self *cancelReadCloser) Close() error {
+	defer self.cancel(nil)
+	return self.ReadCloser.Close() //nolint:wrapcheck // not needed
 }
 
 func (self *Client) SendDry(ctx context.Context, req *pdu.SendDryReq,
@@ -183,35 +282,33 @@ func (self *Client) SendCompleted(ctx context.Context,
 	return nil
 }
 
+func (self *Client) Adopt(ctx context.Context, req *pdu.AdoptReq) error {
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+
+	ep := self.endpoint(EpAdopt)
+	if err := self.json().Post(ctx, ep, req, nil); err != nil {
+		return fmt.Errorf("endpoint %q: %w", ep, err)
+	}
+	return nil
+}
+
 func (self *Client) ReplicationCursor(ctx context.Context,
 	req *pdu.ReplicationCursorReq,
 ) (*pdu.ReplicationCursorRes, error) {
 	ctx, cancel := context.WithTimeout(ctx, self.timeout)
 	defer cancel()
 
-	resp := struct {
-		Result struct {
-			Guid     uint64
-			Notexist bool
-		}
-	}{}
-
+	var resp pdu.ReplicationCursorRes
 	ep := self.endpoint(EpReplicationCursor)
 	if err := self.json().Post(ctx, ep, req, &resp); err != nil {
 		return nil, fmt.Errorf("endpoint %q: %w", ep, err)
 	}
 
-	if resp.Result.Notexist {
-		return &pdu.ReplicationCursorRes{
-			Result: &pdu.ReplicationCursorRes_Result{
-				Notexist: resp.Result.Notexist,
-			},
-		}, nil
+	if resp.Result == nil {
+		resp.Result = &pdu.ReplicationCursorRes_Result{}
 	}
-
-	return &pdu.ReplicationCursorRes{
-		Result: &pdu.ReplicationCursorRes_Result{Guid: resp.Result.Guid},
-	}, nil
+	return &resp, nil
 }
 
 func (self *Client) PreHook(ctx context.Context) error {
@@ -0,0 +1,123 @@
+package job
+
+import (
+	"context"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/util/bytecounter"
+)
+
+// ClientStats are received streams of one client identity, accounted by a
+// sink since start of the daemon.
+type ClientStats struct {
+	BytesReceived uint64
+	Streams       uint64
+	Failures      uint64
+	LastReceive   time.Time `json:",omitzero"`
+}
+
+func newClientAccounting(jobName string) *clientAccounting {
+	labels := prometheus.Labels{"zrepl_job": jobName}
+	return &clientAccounting{
+		clients: make(map[string]*ClientStats),
+
+		promBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "zrepl",
+			Subsystem:   "sink",
+			Name:        "received_bytes_total",
+			Help:        "number of bytes received from a client identity",
+			ConstLabels: labels,
+		}, []string{"client_identity"}),
+
+		promStreams: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "zrepl",
+			Subsystem:   "sink",
+			Name:        "received_streams_total",
+			Help:        "number of receive streams from a client identity",
+			ConstLabels: labels,
+		}, []string{"client_identity"}),
+
+		promFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "zrepl",
+			Subsystem:   "sink",
+			Name:        "receive_failures_total",
+			Help:        "number of failed receive streams from a client identity",
+			ConstLabels: labels,
+		}, []string{"client_identity"}),
+	}
+}
+
+// clientAccounting aggregates received bytes, streams and failures per client
+// identity of a sink.
+type clientAccounting struct {
+	mu      sync.Mutex
+	clients map[string]*ClientStats
+
+	promBytes    *prometheus.CounterVec // labels: client_identity
+	promStreams  *prometheus.CounterVec // labels: client_identity
+	promFailures *prometheus.CounterVec // labels: client_identity
+}
+
+func (self *clientAccounting) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(self.promBytes)
+	registerer.MustRegister(self.promStreams)
+	registerer.MustRegister(self.promFailures)
+}
+
+func (self *clientAccounting) Observe(clientIdentity string, bytes uint64,
+	err error,
+) {
+	self.promBytes.WithLabelValues(clientIdentity).Add(float64(bytes))
+	self.promStreams.WithLabelValues(clientIdentity).Inc()
+	if err != nil {
+		self.promFailures.WithLabelValues(clientIdentity).Inc()
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	stats, ok := self.clients[clientIdentity]
+	if !ok {
+		stats = new(ClientStats)
+		self.clients[clientIdentity] = stats
+	}
+	stats.BytesReceived += bytes
+	stats.Streams++
+	if err != nil {
+		stats.Failures++
+	}
+	stats.LastReceive = time.Now()
+}
+
+// Clients returns a copy of stats of every client identity.
+func (self *clientAccounting) Clients() map[string]ClientStats {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	clients := make(map[string]ClientStats, len(self.clients))
+	for name, stats := range maps.All(self.clients) {
+		clients[name] = *stats
+	}
+	return clients
+}
+
+// accountingEndpoint accounts every Receive into clientAccounting.
+type accountingEndpoint struct {
+	Endpoint
+
+	clientIdentity string
+	accounting     *clientAccounting
+}
+
+func (self *accountingEndpoint) Receive(ctx context.Context,
+	req *pdu.ReceiveReq, r io.ReadCloser,
+) error {
+	cr := bytecounter.NewReadCloser(r)
+	err := self.Endpoint.Receive(ctx, req, cr)
+	self.accounting.Observe(self.clientIdentity, cr.Count(), err)
+	return err
+}
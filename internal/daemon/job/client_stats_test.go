@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+type receiveEndpoint struct {
+	Endpoint
+	err error
+}
+
+func (self *receiveEndpoint) Receive(_ context.Context, _ *pdu.ReceiveReq,
+	r io.ReadCloser,
+) error {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	return self.err
+}
+
+func TestAccountingEndpoint_Receive(t *testing.T) {
+	accounting := newClientAccounting("sink")
+	reg := prometheus.NewRegistry()
+	accounting.RegisterMetrics(reg)
+
+	receive := func(identity, data string, err error) {
+		ep := &accountingEndpoint{
+			Endpoint:       &receiveEndpoint{err: err},
+			clientIdentity: identity,
+			accounting:     accounting,
+		}
+		r := io.NopCloser(strings.NewReader(data))
+		assert.ErrorIs(t, ep.Receive(t.Context(), &pdu.ReceiveReq{}, r), err)
+	}
+
+	receive("foo", "12345", nil)
+	receive("foo", "123", errors.New("recv failed"))
+	receive("bar", "1", nil)
+
+	clients := accounting.Clients()
+	require.Len(t, clients, 2)
+	foo := clients["foo"]
+	assert.Equal(t, uint64(8), foo.BytesReceived)
+	assert.Equal(t, uint64(2), foo.Streams)
+	assert.Equal(t, uint64(1), foo.Failures)
+	assert.False(t, foo.LastReceive.IsZero())
+
+	bar := clients["bar"]
+	assert.Equal(t, uint64(1), bar.BytesReceived)
+	assert.Equal(t, uint64(1), bar.Streams)
+	assert.Zero(t, bar.Failures)
+
+	assert.InDelta(t, 8, testutil.ToFloat64(
+		accounting.promBytes.WithLabelValues("foo")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(
+		accounting.promFailures.WithLabelValues("foo")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(
+		accounting.promStreams.WithLabelValues("bar")), 0)
+}
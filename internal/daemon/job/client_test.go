@@ -3,9 +3,13 @@ package job
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,6 +17,7 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/client/jsonclient"
 	"github.com/dsh2dsh/zrepl/internal/daemon/middleware"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/util/journal"
 )
 
 func TestClient_Receive(t *testing.T) {
@@ -40,6 +45,68 @@ func TestClient_Receive(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestClient_Receive_journal(t *testing.T) {
+	testReq := pdu.ReceiveReq{Filesystem: "test/dataset"}
+	testStream := bytes.Repeat([]byte("foo\nbar\n"), 100_000)
+	sessions := journal.NewSessions(time.Second)
+
+	var got bytes.Buffer
+	fn := func(ctx context.Context, req *pdu.ReceiveReq, r io.ReadCloser) error {
+		assert.NotEmpty(t, req.StreamSession)
+		return sessions.Receive(ctx, req.StreamSession, req.StreamOffset, r,
+			func(_ context.Context, r io.ReadCloser) error {
+				defer r.Close()
+				_, err := io.Copy(&got, r)
+				return err
+			})
+	}
+
+	ts := httptest.NewServer(middleware.Append(
+		nil, middleware.JsonRequestStream(fn)))
+	defer ts.Close()
+
+	transport := &breakOnce{RoundTripper: http.DefaultTransport, n: 100_000}
+	jsonClient, err := jsonclient.New(ts.URL,
+		jsonclient.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err)
+	client := NewClient("test", jsonClient).WithJournal(1 << 20)
+	err = client.Receive(t.Context(), &testReq,
+		io.NopCloser(bytes.NewReader(testStream)))
+	require.NoError(t, err)
+	assert.True(t, transport.broken.Load())
+	assert.Equal(t, testStream, got.Bytes())
+}
+
+// breakOnce breaks body of the first request after n bytes.
+type breakOnce struct {
+	http.RoundTripper
+	n      int
+	broken atomic.Bool
+}
+
+func (self *breakOnce) RoundTrip(req *http.Request) (*http.Response, error) {
+	if self.broken.CompareAndSwap(false, true) {
+		req.Body = &breakingBody{ReadCloser: req.Body, n: self.n}
+	}
+	return self.RoundTripper.RoundTrip(req) //nolint:wrapcheck // test
+}
+
+type breakingBody struct {
+	io.ReadCloser
+	n int
+}
+
+func (self *breakingBody) Read(p []byte) (int, error) {
+	if self.n <= 0 {
+		return 0, errors.New("connection reset")
+	} else if len(p) > self.n {
+		p = p[:self.n]
+	}
+	n, err := self.ReadCloser.Read(p)
+	self.n -= n
+	return n, err //nolint:wrapcheck // test
+}
+
 func TestClient_Send(t *testing.T) {
 	testReq := pdu.SendReq{Filesystem: "test/dataset"}
 	testStream := []byte("foo\nbar\n")
@@ -0,0 +1,114 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/bandwidth"
+)
+
+// connectHostKey returns a key, which identifies the same host in different
+// server URLs.
+func connectHostKey(server string) string {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return server
+	}
+	return u.Host
+}
+
+func newConnectHost(in *config.ConnectHost) (*connectHost, error) {
+	limiter, err := connectHostLimiter(in)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &connectHost{limiter: limiter, stagger: in.Stagger}
+	if in.MaxRunning > 0 {
+		h.running = make(chan struct{}, in.MaxRunning)
+	}
+	return h, nil
+}
+
+func connectHostLimiter(in *config.ConnectHost) (*bandwidth.Limiter, error) {
+	if len(in.BandwidthSchedule) == 0 {
+		return bandwidth.NewLimiter(in.Bandwidth.Uint64(), in.Burst.Uint64()), nil
+	}
+
+	s, err := bandwidthScheduleFromConfig(in.Bandwidth.Uint64(),
+		in.BandwidthSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("field `bandwidth_schedule`: %w", err)
+	}
+	return bandwidth.NewScheduledLimiter(s.Rate, in.Burst.Uint64()), nil
+}
+
+// connectHost coordinates all jobs, connected to the same host. It shares
+// bandwidth between them and schedules their replications.
+type connectHost struct {
+	limiter *bandwidth.Limiter
+	running chan struct{}
+	stagger time.Duration
+
+	mu        sync.Mutex
+	nextStart time.Time
+}
+
+// Limiter returns bandwidth limiter of the host or nil, if the host has no
+// bandwidth limit.
+func (self *connectHost) Limiter() *bandwidth.Limiter {
+	if self == nil {
+		return nil
+	}
+	return self.limiter
+}
+
+// Schedule waits for a free slot and stagger interval after previous start.
+// Returned function must be called, when the job finished its replication.
+func (self *connectHost) Schedule(ctx context.Context) (func(), error) {
+	if self == nil {
+		return func() {}, nil
+	}
+
+	release := func() {}
+	if self.running != nil {
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case self.running <- struct{}{}:
+		}
+		release = func() { <-self.running }
+	}
+
+	if d := self.reserveStart(); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			release()
+			return nil, context.Cause(ctx)
+		case <-t.C:
+		}
+	}
+	return release, nil
+}
+
+func (self *connectHost) reserveStart() time.Duration {
+	if self.stagger == 0 {
+		return 0
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	now := time.Now()
+	startAt := now
+	if self.nextStart.After(now) {
+		startAt = self.nextStart
+	}
+	self.nextStart = startAt.Add(self.stagger)
+	return startAt.Sub(now)
+}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestConnectHostKey(t *testing.T) {
+	assert.Equal(t, "example.com:8888",
+		connectHostKey("https://example.com:8888"))
+	assert.Equal(t, "example.com:8888",
+		connectHostKey("http://example.com:8888/"))
+	assert.Equal(t, "foo", connectHostKey("foo"))
+}
+
+func TestConnectHost_Schedule(t *testing.T) {
+	var nilHost *connectHost
+	release, err := nilHost.Schedule(t.Context())
+	require.NoError(t, err)
+	release()
+	assert.Nil(t, nilHost.Limiter())
+
+	h, err := newConnectHost(&config.ConnectHost{MaxRunning: 1})
+	require.NoError(t, err)
+	assert.Nil(t, h.Limiter())
+	release, err = h.Schedule(t.Context())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = h.Schedule(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = h.Schedule(t.Context())
+	require.NoError(t, err)
+	release()
+}
+
+func TestConnectHost_stagger(t *testing.T) {
+	h, err := newConnectHost(&config.ConnectHost{Stagger: time.Hour})
+	require.NoError(t, err)
+	assert.Zero(t, h.reserveStart())
+	assert.InDelta(t, time.Hour, h.reserveStart(), float64(time.Second))
+	assert.InDelta(t, 2*time.Hour, h.reserveStart(), float64(time.Second))
+}
+
+func TestConnectHost_bandwidthSchedule(t *testing.T) {
+	h, err := newConnectHost(&config.ConnectHost{
+		Bandwidth: 10 << 20,
+		BandwidthSchedule: []config.BandwidthWindow{
+			{From: "08:00", To: "18:00", Bandwidth: 1 << 20},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, h.Limiter())
+
+	_, err = newConnectHost(&config.ConnectHost{
+		BandwidthSchedule: []config.BandwidthWindow{
+			{From: "8am", To: "18:00", Bandwidth: 1 << 20},
+		},
+	})
+	require.Error(t, err)
+}
@@ -14,6 +14,10 @@ type Connected interface {
 
 	PreHook(ctx context.Context) error
 	PostHook(ctx context.Context) error
+
+	// Schedule waits until the job can start replication. Returned function
+	// must be called, when replication finished.
+	Schedule(ctx context.Context) (func(), error)
 }
 
 func newLocalConnected(listenerName, clientIdentity string,
@@ -64,6 +68,10 @@ func (self *localConnected) PostHook(ctx context.Context) error {
 	return self.job().PostHook(self.hookContext(ctx), self.clientIdentity)
 }
 
+func (self *localConnected) Schedule(context.Context) (func(), error) {
+	return func() {}, nil
+}
+
 func newServerConnected(name string, client *Client) *serverConnected {
 	return &serverConnected{name: name, client: client}
 }
@@ -71,6 +79,12 @@ func newServerConnected(name string, client *Client) *serverConnected {
 type serverConnected struct {
 	name   string
 	client *Client
+	host   *connectHost
+}
+
+func (self *serverConnected) WithHost(h *connectHost) *serverConnected {
+	self.host = h
+	return self
 }
 
 var _ Connected = (*serverConnected)(nil)
@@ -86,3 +100,7 @@ func (self *serverConnected) PreHook(ctx context.Context) error {
 func (self *serverConnected) PostHook(ctx context.Context) error {
 	return self.client.PostHook(ctx)
 }
+
+func (self *serverConnected) Schedule(ctx context.Context) (func(), error) {
+	return self.host.Schedule(ctx)
+}
@@ -3,22 +3,23 @@ package job
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/client/jsonclient"
 	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/qos"
 )
 
 func NewConnecter(keys []config.AuthKey) *Connecter {
 	cn := &Connecter{
-		jobs: make(map[string]*PassiveSide, 1),
+		jobs: &passiveJobs{items: make(map[string]*PassiveSide, 1)},
 		keys: make(map[string]config.AuthKey, len(keys)),
 
-		httpClient: &http.Client{
-			Transport: &http.Transport{IdleConnTimeout: 30 * time.Second},
-		},
-		timeout: time.Minute,
+		httpClient: newHTTPClient(nil, 0),
+		timeout:    time.Minute,
 
 		requiredJobs: make([]string, 0, 1),
 	}
@@ -30,11 +31,17 @@ func NewConnecter(keys []config.AuthKey) *Connecter {
 }
 
 type Connecter struct {
-	jobs map[string]*PassiveSide
+	jobs *passiveJobs
 	keys map[string]config.AuthKey
 
+	// httpClient is shared by jobs without socket options, every job with
+	// them has its own client.
 	httpClient *http.Client
 	timeout    time.Duration
+	hosts      map[string]*connectHost
+
+	bufferSize                int
+	readTimeout, writeTimeout time.Duration
 
 	requiredJobs []string
 }
@@ -46,18 +53,76 @@ func (self *Connecter) WithTimeout(d time.Duration) *Connecter {
 	return self
 }
 
+// WithRPC configures network buffers of clients and timeouts of their
+// streams.
+func (self *Connecter) WithRPC(in *config.GlobalRPC) *Connecter {
+	self.bufferSize = int(in.ChunkSize)
+	self.readTimeout, self.writeTimeout = in.ReadTimeout, in.WriteTimeout
+	self.httpClient = self.newHTTPClient(nil)
+	return self
+}
+
+// WithHosts configures coordination of jobs, connected to the same hosts.
+func (self *Connecter) WithHosts(hosts []config.ConnectHost) (*Connecter,
+	error,
+) {
+	self.hosts = make(map[string]*connectHost, len(hosts))
+	for i := range hosts {
+		h := &hosts[i]
+		host, err := newConnectHost(h)
+		if err != nil {
+			return nil, fmt.Errorf("connect host %q: %w", h.Server, err)
+		}
+		self.hosts[connectHostKey(h.Server)] = host
+	}
+	return self, nil
+}
+
 func (self *Connecter) AddJob(listnerName string, j *PassiveSide) {
-	self.jobs[listnerName] = j
+	self.jobs.mu.Lock()
+	defer self.jobs.mu.Unlock()
+	self.jobs.items[listnerName] = j
 }
 
-func (self *Connecter) Job(name string) *PassiveSide { return self.jobs[name] }
+func (self *Connecter) Job(name string) *PassiveSide {
+	self.jobs.mu.RLock()
+	defer self.jobs.mu.RUnlock()
+	return self.jobs.items[name]
+}
+
+// Replace makes self and next share the same set of passive jobs, which is
+// replaced by passive jobs from jobs. It's used on config reload, when jobs
+// built by next and jobs kept from self must see each other.
+func (self *Connecter) Replace(next *Connecter, jobs []Job) {
+	items := make(map[string]*PassiveSide, len(jobs))
+	for _, j := range jobs {
+		if p, ok := j.(*PassiveSide); ok {
+			items[p.Name()] = p
+		}
+	}
+
+	self.jobs.mu.Lock()
+	defer self.jobs.mu.Unlock()
+	self.jobs.items = items
+	next.jobs = self.jobs
+}
 
 func (self *Connecter) FromConfig(in *config.Connect) (Connected, error) {
 	switch {
 	case in.Type == "local":
 		return self.newLocal(in.ListenerName, in.ClientIdentity), nil
 	case in.Server != "":
-		return self.newServer(in.Server, in.ListenerName, in.ClientIdentity)
+		socket, err := socketOptionsFromConfig(&in.Socket)
+		if err != nil {
+			return nil, fmt.Errorf("field `socket`: %w", err)
+		}
+		cn, err := self.newServer(in.Server, in.ListenerName, in.ClientIdentity,
+			socket)
+		if err != nil {
+			return nil, err
+		}
+		cn.client.WithJournal(int(in.StreamJournal))
+		return cn, nil
 	}
 	return nil, fmt.Errorf("unknown type %q", in.Type)
 }
@@ -66,10 +131,11 @@ func (self *Connecter) newLocal(listenerName, clientIdentity string,
 ) *localConnected {
 	self.requiredJobs = append(self.requiredJobs, listenerName)
 	return newLocalConnected(listenerName, clientIdentity,
-		func(name string) *PassiveSide { return self.jobs[name] })
+		func(name string) *PassiveSide { return self.Job(name) })
 }
 
 func (self *Connecter) newServer(server, listenerName, clientIdentity string,
+	socket qos.Options,
 ) (*serverConnected, error) {
 	authKey, ok := self.keys[clientIdentity]
 	if !ok {
@@ -80,8 +146,13 @@ func (self *Connecter) newServer(server, listenerName, clientIdentity string,
 	authValue := "Bearer " + authKey.Key
 	name := listenerName + "@" + server
 
+	httpClient := self.httpClient
+	if !socket.Empty() {
+		httpClient = self.newHTTPClient(&net.Dialer{Control: socket.Control})
+	}
+
 	jsonClient, err := jsonclient.New(server,
-		jsonclient.WithHTTPClient(self.httpClient),
+		jsonclient.WithHTTPClient(httpClient),
 		jsonclient.WithRequestEditorFn(
 			func(_ context.Context, req *http.Request) error {
 				req.Header.Set("Authorization", authValue)
@@ -91,8 +162,11 @@ func (self *Connecter) newServer(server, listenerName, clientIdentity string,
 		return nil, fmt.Errorf("build jsonclient for %q: %w", name, err)
 	}
 
-	client := NewClient(listenerName, jsonClient).WithTimeout(self.timeout)
-	cn := newServerConnected(name, client)
+	host := self.hosts[connectHostKey(server)]
+	client := NewClient(listenerName, jsonClient).WithTimeout(self.timeout).
+		WithLimiter(host.Limiter()).
+		WithStreamTimeouts(self.readTimeout, self.writeTimeout)
+	cn := newServerConnected(name, client).WithHost(host)
 	return cn, nil
 }
 
@@ -104,3 +178,39 @@ func (self *Connecter) Validate() error {
 	}
 	return nil
 }
+
+func (self *Connecter) newHTTPClient(dialer *net.Dialer) *http.Client {
+	return newHTTPClient(dialer, self.bufferSize)
+}
+
+// newHTTPClient returns http.Client, which dials connections by dialer, or by
+// default dialer, if dialer is nil. Positive bufferSize changes sizes of read
+// and write buffers of connections.
+func newHTTPClient(dialer *net.Dialer, bufferSize int) *http.Client {
+	t := &http.Transport{
+		IdleConnTimeout: 30 * time.Second,
+		ReadBufferSize:  bufferSize,
+		WriteBufferSize: bufferSize,
+	}
+	if dialer != nil {
+		t.DialContext = dialer.DialContext
+	}
+	return &http.Client{Transport: t}
+}
+
+func socketOptionsFromConfig(in *config.SocketOptions) (qos.Options, error) {
+	opts := qos.Options{TOS: in.TOS, Mark: in.Mark}
+	if in.DSCP != "" {
+		tos, err := qos.ParseDSCP(in.DSCP)
+		if err != nil {
+			return opts, err //nolint:wrapcheck // already wrapped
+		}
+		opts.TOS = tos
+	}
+	return opts, opts.Validate() //nolint:wrapcheck // already wrapped
+}
+
+type passiveJobs struct {
+	items map[string]*PassiveSide
+	mu    sync.RWMutex
+}
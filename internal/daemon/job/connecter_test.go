@@ -0,0 +1,61 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestConnecter_Replace(t *testing.T) {
+	const cstr = `
+jobs:
+- name: foo
+  type: push
+  connect:
+    type: local
+    listener_name: "zdisk"
+    client_identity: bar
+  filesystems: {"<": true}
+  snapshotting:
+    type: manual
+  pruning:
+    keep_sender:
+    - type: last_n
+      count: 10
+    keep_receiver:
+    - type: last_n
+      count: 10
+
+- name: "zdisk"
+  type: "sink"
+  root_fs: "zdisk/zrepl"
+  serve:
+    type: "local"
+    listener_name: "zdisk"
+`
+
+	c, err := config.ParseConfigBytes("", []byte(cstr))
+	require.NoError(t, err)
+
+	prevJobs, prev, err := JobsFromConfig(c)
+	require.NoError(t, err)
+	require.Len(t, prevJobs, 2)
+
+	nextJobs, next, err := JobsFromConfig(c)
+	require.NoError(t, err)
+	require.Len(t, nextJobs, 2)
+	assert.NotSame(t, prevJobs[1], next.Job("zdisk"))
+
+	// keep the sink job from the previous config
+	nextJobs[1] = prevJobs[1]
+	prev.Replace(next, nextJobs)
+	assert.Same(t, prevJobs[1], prev.Job("zdisk"))
+	assert.Same(t, prevJobs[1], next.Job("zdisk"))
+
+	prev.Replace(next, nextJobs[:1])
+	assert.Nil(t, prev.Job("zdisk"))
+	assert.Nil(t, next.Job("zdisk"))
+}
@@ -2,10 +2,13 @@ package job
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
 type Endpoint interface {
@@ -37,3 +40,44 @@ func (self *SenderOnce) ListFilesystems(context.Context,
 ) (*pdu.ListFilesystemRes, error) {
 	return self.listFilesystemsOnce()
 }
+
+// NewFilteredSender returns endpoint, which lists only filesystems of endpoint
+// passing fsf. They are sent one by one, not as a part of recursive send,
+// because the filter can exclude children of a recursively sent filesystem.
+func NewFilteredSender(endpoint Endpoint, fsf *filters.DatasetFilter,
+) *FilteredSender {
+	return &FilteredSender{Endpoint: endpoint, fsf: fsf}
+}
+
+type FilteredSender struct {
+	Endpoint
+
+	fsf *filters.DatasetFilter
+}
+
+var _ Endpoint = (*FilteredSender)(nil)
+
+func (self *FilteredSender) ListFilesystems(ctx context.Context,
+) (*pdu.ListFilesystemRes, error) {
+	resp, err := self.Endpoint.ListFilesystems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filesystems := make([]*pdu.Filesystem, 0, len(resp.Filesystems))
+	for _, fs := range resp.Filesystems {
+		dp, err := zfs.NewDatasetPath(fs.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filesystem %q: %w", fs.Path, err)
+		}
+		pass, err := self.fsf.Filter(dp)
+		if err != nil {
+			return nil, fmt.Errorf("filter filesystem %q: %w", fs.Path, err)
+		} else if pass {
+			fs.Replicate, fs.Exclude, fs.Replicated = false, "", false
+			filesystems = append(filesystems, fs)
+		}
+	}
+	resp.Filesystems = filesystems
+	return resp, nil
+}
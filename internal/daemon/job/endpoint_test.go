@@ -0,0 +1,84 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+type listEndpoint struct {
+	Endpoint
+	paths     []string
+	recursive bool
+}
+
+func (self *listEndpoint) ListFilesystems(context.Context,
+) (*pdu.ListFilesystemRes, error) {
+	resp := &pdu.ListFilesystemRes{
+		Filesystems: make([]*pdu.Filesystem, len(self.paths)),
+	}
+	for i, p := range self.paths {
+		resp.Filesystems[i] = &pdu.Filesystem{Path: p}
+		if self.recursive {
+			// like endpoint.Sender lists filesystems with recursive policy
+			resp.Filesystems[i].Replicate = i == 0
+			resp.Filesystems[i].Replicated = i > 0
+		}
+	}
+	return resp, nil
+}
+
+func TestFilteredSender_ListFilesystems(t *testing.T) {
+	fsf, err := filters.NewFromConfig(config.FilesystemsFilter{
+		"tank/vm<":                   false,
+		"~ ^tank/vm/(prod|staging)/": true,
+		"zroot/home<":                true,
+	}, nil)
+	require.NoError(t, err)
+
+	ep := NewFilteredSender(&listEndpoint{paths: []string{
+		"tank", "tank/vm", "tank/vm/prod/db", "tank/vm/dev/db",
+		"tank/vm/staging/web", "zroot", "zroot/home", "zroot/home/user",
+	}}, fsf)
+
+	resp, err := ep.ListFilesystems(t.Context())
+	require.NoError(t, err)
+
+	paths := make([]string, len(resp.Filesystems))
+	for i, fs := range resp.Filesystems {
+		paths[i] = fs.Path
+	}
+	assert.Equal(t, []string{
+		"tank/vm/prod/db", "tank/vm/staging/web", "zroot/home", "zroot/home/user",
+	}, paths)
+}
+
+func TestFilteredSender_ListFilesystems_recursive(t *testing.T) {
+	fsf, err := filters.NewFromConfig(config.FilesystemsFilter{
+		"zroot<":          true,
+		"zroot/tmp<":      false,
+		"zroot/home/user": true,
+	}, nil)
+	require.NoError(t, err)
+
+	ep := NewFilteredSender(&listEndpoint{
+		paths:     []string{"zroot", "zroot/home", "zroot/home/user", "zroot/tmp"},
+		recursive: true,
+	}, fsf)
+
+	resp, err := ep.ListFilesystems(t.Context())
+	require.NoError(t, err)
+	require.Len(t, resp.Filesystems, 3)
+	for _, fs := range resp.Filesystems {
+		assert.NotEqual(t, "zroot/tmp", fs.Path)
+		assert.False(t, fs.Replicate, "%s must not be sent recursively", fs.Path)
+		assert.False(t, fs.Replicated, "%s must be sent itself", fs.Path)
+		assert.Empty(t, fs.Exclude, fs.Path)
+	}
+}
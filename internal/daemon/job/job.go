@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
 	"github.com/dsh2dsh/zrepl/internal/endpoint"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
 )
 
 func GetLogger(ctx context.Context) *slog.Logger {
@@ -22,6 +25,23 @@ type Internal interface {
 	RegisterMetrics(registerer prometheus.Registerer)
 }
 
+// Verifier is a job, which can compare its sender and receiver on demand.
+type Verifier interface {
+	Verify(ctx context.Context) *report.VerifyReport
+}
+
+// Adopter is a job, which can adopt receiver filesystems, seeded by other
+// means, like manual zfs send and recv.
+type Adopter interface {
+	Adopt(ctx context.Context, dryRun bool) *report.VerifyReport
+}
+
+// PruneDryRunner is a job, which can report snapshots, its keep rules would
+// destroy right now, without destroying them.
+type PruneDryRunner interface {
+	PruneDryRun(ctx context.Context) []*pruner.DryRunReport
+}
+
 type Job interface {
 	Internal
 
@@ -30,6 +50,44 @@ type Job interface {
 	SenderConfig() *endpoint.SenderConfig
 	Runnable() bool
 	Cron() string
+	Overlap() OverlapPolicy
+}
+
+// OverlapPolicy defines what to do, when the next cron trigger arrives, while
+// the job is still running.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the next run.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue starts the next run right after current run finished.
+	OverlapQueue
+	// OverlapStop stops current run and starts the next run.
+	OverlapStop
+)
+
+func overlapFromConfig(s string) (OverlapPolicy, error) {
+	switch s {
+	case "", "skip":
+		return OverlapSkip, nil
+	case "queue":
+		return OverlapQueue, nil
+	case "stop":
+		return OverlapStop, nil
+	}
+	return OverlapSkip, fmt.Errorf("%q is not in {skip,queue,stop}", s)
+}
+
+func (self OverlapPolicy) String() string {
+	switch self {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapStop:
+		return "stop"
+	}
+	return "OverlapPolicy(" + strconv.Itoa(int(self)) + ")"
 }
 
 type Type string
@@ -37,6 +95,7 @@ type Type string
 const (
 	TypeInternal Type = "internal"
 	TypeSnap     Type = "snap"
+	TypePrune    Type = "prune"
 	TypePush     Type = "push"
 	TypeSink     Type = "sink"
 	TypePull     Type = "pull"
@@ -44,9 +103,16 @@ const (
 )
 
 type Status struct {
+	// JobID is the validated name of the job, as used in hold tags and
+	// bookmark names.
+	JobID     endpoint.JobID
 	Err       string
 	NextCron  time.Time
 	CanWakeup bool
+	// Overlaps counts cron triggers arrived while the job was still running.
+	Overlaps uint
+	// Disabled is true if the job was disabled by zrepl job disable.
+	Disabled bool `json:",omitempty"`
 
 	Type        Type
 	JobSpecific JobStatus
@@ -70,7 +136,7 @@ func (s *Status) UnmarshalJSON(b []byte) error {
 	switch st.Type {
 	case TypeInternal:
 		// internal jobs do not report specifics
-	case TypeSnap:
+	case TypeSnap, TypePrune:
 		s.JobSpecific = new(SnapJobStatus)
 	case TypePull, TypePush:
 		s.JobSpecific = new(ActiveSideStatus)
@@ -4,15 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job/signal"
 	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
 	"github.com/dsh2dsh/zrepl/internal/endpoint"
 	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/qos"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -27,9 +31,15 @@ type PassiveSide struct {
 	name endpoint.JobID
 
 	clientKeys map[string]struct{}
+	// clients accounts received streams per client identity of sinks, nil for
+	// sources.
+	clients *clientAccounting
 
 	preHook  *Hook
 	postHook *Hook
+
+	overlap OverlapPolicy
+	socket  qos.Options
 }
 
 var _ Job = (*PassiveSide)(nil)
@@ -94,16 +104,43 @@ func modeSourceFromConfig(g *config.Global, in *config.SourceJob,
 		return nil, fmt.Errorf("send options: %w", err)
 	}
 
-	m.snapper, err = snapper.FromConfig(g, m.senderConfig.FSF, in.Snapshotting)
+	m.snapper, err = snapper.FromConfig(g, in.Name, m.senderConfig.FSF,
+		in.Snapshotting)
 	if err != nil {
 		return nil, fmt.Errorf("cannot build snapper: %w", err)
 	}
+
+	if m.clientFilters, err = clientFiltersFromConfig(in); err != nil {
+		return nil, fmt.Errorf("field `client_filesystems`: %w", err)
+	}
 	return m, nil
 }
 
+func clientFiltersFromConfig(in *config.SourceJob,
+) (map[string]*filters.DatasetFilter, error) {
+	clientFilters := make(map[string]*filters.DatasetFilter,
+		len(in.ClientFilesystems))
+	clients := slices.Sorted(maps.Keys(in.ClientFilesystems))
+	for _, clientIdentity := range clients {
+		c := in.ClientFilesystems[clientIdentity]
+		if len(in.ClientKeys) != 0 &&
+			!slices.Contains(in.ClientKeys, clientIdentity) {
+			return nil, fmt.Errorf("client %q not in client_keys", clientIdentity)
+		}
+		fsf, err := filters.NewFromConfig(c.Filesystems, c.Datasets)
+		if err != nil {
+			return nil, fmt.Errorf("client %q: %w", clientIdentity, err)
+		}
+		clientFilters[clientIdentity] = fsf
+	}
+	return clientFilters, nil
+}
+
 type modeSource struct {
 	senderConfig *endpoint.SenderConfig
 	snapper      snapper.Snapper
+	// clientFilters restrict filesystems of senderConfig per client identity.
+	clientFilters map[string]*filters.DatasetFilter
 
 	drySendConcurrency int
 	pruneConcurrency   int
@@ -115,6 +152,7 @@ func (m *modeSource) Type() Type { return TypeSource }
 
 func (m *modeSource) Endpoint(clientIdentity string) Endpoint {
 	return endpoint.NewSender(*m.senderConfig).
+		WithClientFilter(m.clientFilters[clientIdentity]).
 		WithDrySendConcurrency(m.drySendConcurrency).
 		WithPruneConcurrency(m.pruneConcurrency)
 }
@@ -151,6 +189,7 @@ func passiveSideFromConfig(g *config.Global, in *config.PassiveJob,
 	switch v := configJob.(type) {
 	case *config.SinkJob:
 		s.mode, err = modeSinkFromConfig(v, s.name) // shadow
+		s.clients = newClientAccounting(s.Name())
 	case *config.SourceJob:
 		s.mode, err = modeSourceFromConfig(g, v, s.name) // shadow
 	}
@@ -158,6 +197,14 @@ func passiveSideFromConfig(g *config.Global, in *config.PassiveJob,
 		return nil, err // no wrapping necessary
 	}
 
+	if s.overlap, err = overlapFromConfig(in.Overlap); err != nil {
+		return nil, fmt.Errorf("field `overlap`: %w", err)
+	}
+
+	if s.socket, err = socketOptionsFromConfig(&in.Socket); err != nil {
+		return nil, fmt.Errorf("field `socket`: %w", err)
+	}
+
 	if in.Hooks.Pre != nil {
 		s.preHook = NewHookFromConfig(in.Hooks.Pre)
 	}
@@ -175,12 +222,26 @@ func (j *PassiveSide) Cron() string { return j.mode.Cron() }
 
 func (j *PassiveSide) Runnable() bool { return j.mode.Runnable() }
 
+func (j *PassiveSide) Overlap() OverlapPolicy { return j.overlap }
+
+// SocketOptions returns marks of connections served by this job.
+func (j *PassiveSide) SocketOptions() qos.Options { return j.socket }
+
 func (s *PassiveSide) Status() *Status {
+	if s.clients != nil {
+		return &Status{
+			JobID:       s.name,
+			Type:        s.mode.Type(),
+			JobSpecific: &PassiveStatus{Clients: s.clients.Clients()},
+		}
+	}
+
 	snapperReport := s.mode.Report()
 	if snapperReport == nil || snapperReport.Type == snapper.TypeManual {
 		return nil
 	}
 	return &Status{
+		JobID:       s.name,
 		Type:        s.mode.Type(),
 		JobSpecific: &PassiveStatus{Snapper: snapperReport},
 	}
@@ -188,6 +249,8 @@ func (s *PassiveSide) Status() *Status {
 
 type PassiveStatus struct {
 	Snapper *snapper.Report
+	// Clients are received streams per client identity of a sink.
+	Clients map[string]ClientStats `json:",omitempty"`
 }
 
 func (self *PassiveStatus) Error() string {
@@ -248,7 +311,15 @@ func (j *PassiveSide) SenderConfig() *endpoint.SenderConfig {
 }
 
 func (j *PassiveSide) Endpoint(clientIdentity string) Endpoint {
-	return j.mode.Endpoint(clientIdentity)
+	ep := j.mode.Endpoint(clientIdentity)
+	if j.clients == nil {
+		return ep
+	}
+	return &accountingEndpoint{
+		Endpoint:       ep,
+		clientIdentity: clientIdentity,
+		accounting:     j.clients,
+	}
 }
 
 func (j *PassiveSide) KnownClient(clientIdentity string) bool {
@@ -259,7 +330,11 @@ func (j *PassiveSide) KnownClient(clientIdentity string) bool {
 	return ok
 }
 
-func (*PassiveSide) RegisterMetrics(registerer prometheus.Registerer) {}
+func (j *PassiveSide) RegisterMetrics(registerer prometheus.Registerer) {
+	if j.clients != nil {
+		j.clients.RegisterMetrics(registerer)
+	}
+}
 
 func (j *PassiveSide) Run(ctx context.Context) error {
 	j.mode.Run(signal.GracefulFrom(ctx))
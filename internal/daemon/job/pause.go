@@ -0,0 +1,59 @@
+package job
+
+import "sync"
+
+// Pauser is a job, which can pause replication and resume it later, without
+// losing its plan and resume tokens.
+type Pauser interface {
+	// Pause stops dispatching of new replication steps. If stopZFS is true, it
+	// also suspends active zfs processes of the job with SIGSTOP.
+	Pause(stopZFS bool) error
+	// Resume continues paused replication.
+	Resume() error
+}
+
+// pauseGate blocks replication steps while paused.
+type pauseGate struct {
+	resumed chan struct{} // nil if not paused, closed on resume
+	stopped bool
+	mu      sync.Mutex
+}
+
+// Pause pauses the gate and returns true, or returns false if the gate is
+// paused already. stopped records zfs processes were suspended.
+func (self *pauseGate) Pause(stopped bool) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.resumed != nil {
+		return false
+	}
+	self.resumed, self.stopped = make(chan struct{}), stopped
+	return true
+}
+
+// Resume resumes the paused gate and returns true, or returns false if the
+// gate isn't paused. stopped is true if zfs processes were suspended by Pause.
+func (self *pauseGate) Resume() (ok, stopped bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.resumed == nil {
+		return false, false
+	}
+	close(self.resumed)
+	stopped = self.stopped
+	self.resumed, self.stopped = nil, false
+	return true, stopped
+}
+
+// Paused returns a channel, which is closed when the gate resumed, or nil if
+// the gate isn't paused.
+func (self *pauseGate) Paused() <-chan struct{} {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.resumed == nil {
+		return nil
+	}
+	return self.resumed
+}
@@ -0,0 +1,42 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseGate(t *testing.T) {
+	var g pauseGate
+	assert.Nil(t, g.Paused())
+	ok, _ := g.Resume()
+	assert.False(t, ok)
+
+	require.True(t, g.Pause(true))
+	assert.False(t, g.Pause(false))
+	resumed := g.Paused()
+	require.NotNil(t, resumed)
+
+	select {
+	case <-resumed:
+		t.Fatal("resumed before Resume")
+	default:
+	}
+
+	ok, stopped := g.Resume()
+	assert.True(t, ok)
+	assert.True(t, stopped)
+	assert.Nil(t, g.Paused())
+
+	select {
+	case <-resumed:
+	default:
+		t.Fatal("not resumed after Resume")
+	}
+
+	require.True(t, g.Pause(false))
+	ok, stopped = g.Resume()
+	assert.True(t, ok)
+	assert.False(t, stopped)
+}
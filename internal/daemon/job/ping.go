@@ -0,0 +1,81 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/job/signal"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/httppost"
+)
+
+const pingTimeout = 10 * time.Second
+
+// newPing returns a pinger of dead man's switch service, like healthchecks.io,
+// or nil, if url is empty.
+func newPing(url string) *ping {
+	if url == "" {
+		return nil
+	}
+	return &ping{url: strings.TrimSuffix(url, "/"), client: http.DefaultClient}
+}
+
+// ping reports every run of the job: url/start when the run starts, url when
+// it finished successfully, and url/fail when it finished with error. Finish
+// pings have a summary of the run as body.
+type ping struct {
+	url    string
+	client *http.Client
+}
+
+// Start pings the service and returns the time of start.
+func (self *ping) Start(ctx context.Context) time.Time {
+	begin := time.Now()
+	if self != nil {
+		self.send(ctx, self.url+"/start", "")
+	}
+	return begin
+}
+
+// Finish pings the service with the summary of the job's run, started at
+// begin.
+func (self *ping) Finish(ctx context.Context, j Job, begin time.Time) {
+	if self == nil {
+		return
+	}
+
+	var errMsg string
+	if st := j.Status(); st != nil {
+		errMsg = st.Error()
+	}
+	if graceful := signal.GracefulFrom(ctx); errMsg == "" && graceful.Err() != nil {
+		errMsg = context.Cause(graceful).Error()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "job: %s\n", j.Name())
+	fmt.Fprintf(&b, "started: %s\n", begin.Format(time.RFC3339))
+	fmt.Fprintf(&b, "duration: %s\n", time.Since(begin).Truncate(time.Second))
+
+	url := self.url
+	if errMsg != "" {
+		fmt.Fprintf(&b, "error: %s\n", errMsg)
+		url += "/fail"
+	}
+	self.send(ctx, url, b.String())
+}
+
+func (self *ping) send(ctx context.Context, url, body string) {
+	log := GetLogger(ctx).With(slog.String("url", url))
+	err := httppost.Post(ctx, self.client, pingTimeout, url, []byte(body),
+		map[string]string{"Content-Type": "text/plain; charset=utf-8"})
+	if err != nil {
+		logger.WithError(log, err, "failed ping")
+		return
+	}
+	log.Debug("ping sent")
+}
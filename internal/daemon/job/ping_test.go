@@ -0,0 +1,72 @@
+package job
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+)
+
+type pingJob struct {
+	Job
+
+	status *Status
+}
+
+func (self *pingJob) Name() string { return "foo" }
+
+func (self *pingJob) Status() *Status { return self.status }
+
+func TestPing(t *testing.T) {
+	type request struct {
+		path string
+		body string
+	}
+	var requests []request
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			requests = append(requests, request{path: r.URL.Path, body: string(b)})
+		}))
+	defer srv.Close()
+
+	assert.Nil(t, newPing(""))
+	var nilPing *ping
+	nilPing.Finish(t.Context(), nil, nilPing.Start(t.Context()))
+
+	p := newPing(srv.URL + "/uuid/")
+	ctx := t.Context()
+	j := &pingJob{status: &Status{JobSpecific: &SnapJobStatus{}}}
+
+	begin := p.Start(ctx)
+	p.Finish(ctx, j, begin)
+	require.Len(t, requests, 2)
+	assert.Equal(t, request{path: "/uuid/start"}, requests[0])
+	assert.Equal(t, "/uuid", requests[1].path)
+	assert.Contains(t, requests[1].body, "job: foo\n")
+	assert.NotContains(t, requests[1].body, "error:")
+
+	j.status.JobSpecific = &SnapJobStatus{
+		Pruning: &pruner.Report{Error: "prune failed"},
+	}
+	p.Finish(ctx, j, begin.Add(-time.Minute))
+	require.Len(t, requests, 3)
+	assert.Equal(t, "/uuid/fail", requests[2].path)
+	assert.Contains(t, requests[2].body, "duration: 1m0s\n")
+	assert.Contains(t, requests[2].body, "error: prune failed\n")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	p.Start(canceled)
+	require.Len(t, requests, 4, "must ping even with canceled context")
+}
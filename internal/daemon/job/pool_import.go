@@ -0,0 +1,99 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// newPoolImport returns importer of a pool, like a removable backup drive, or
+// nil if in is nil.
+func newPoolImport(in *config.PoolImport) *poolImport {
+	if in == nil {
+		return nil
+	}
+	return &poolImport{
+		pool:     in.Pool,
+		args:     in.Args,
+		export:   in.Export,
+		retries:  in.Retries,
+		interval: in.Interval,
+
+		imported:   zfs.ZpoolImported,
+		importPool: zfs.ZpoolImport,
+		exportPool: zfs.ZpoolExport,
+	}
+}
+
+type poolImport struct {
+	pool     string
+	args     []string
+	export   bool
+	retries  int
+	interval time.Duration
+
+	imported   func(ctx context.Context, pool string) (bool, error)
+	importPool func(ctx context.Context, pool string, args ...string) error
+	exportPool func(ctx context.Context, pool string) error
+}
+
+// Import imports the pool, retrying failed imports with backoff. It returns a
+// function, which exports the pool, if it was imported by this call and export
+// configured, or does nothing otherwise.
+func (self *poolImport) Import(ctx context.Context) (func() error, error) {
+	noop := func() error { return nil }
+	if self == nil {
+		return noop, nil
+	}
+
+	log := GetLogger(ctx).With(slog.String("pool", self.pool))
+	if ok, err := self.imported(ctx, self.pool); err != nil {
+		return noop, fmt.Errorf("pool import %q: %w", self.pool, err)
+	} else if ok {
+		log.Info("pool already imported")
+		return noop, nil
+	}
+
+	interval := self.interval
+	for attempt := 0; ; attempt++ {
+		log.With(slog.Int("attempt", attempt)).Info("import pool")
+		err := self.importPool(ctx, self.pool, self.args...)
+		if err == nil {
+			break
+		} else if attempt >= self.retries {
+			return noop, fmt.Errorf("pool import %q: %w", self.pool, err)
+		}
+
+		logger.WithError(log.With(slog.Duration("retry_in", interval)), err,
+			"failed import pool")
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return noop, fmt.Errorf("pool import %q: %w", self.pool,
+				context.Cause(ctx))
+		case <-t.C:
+		}
+		interval *= 2
+	}
+
+	if !self.export {
+		return noop, nil
+	}
+	return func() error { return self.Export(ctx) }, nil
+}
+
+// Export exports the pool. The job can be stopped already, but the pool must
+// be exported anyway, so the drive can be removed.
+func (self *poolImport) Export(ctx context.Context) error {
+	GetLogger(ctx).With(slog.String("pool", self.pool)).Info("export pool")
+	if err := self.exportPool(context.WithoutCancel(ctx), self.pool); err != nil {
+		return fmt.Errorf("pool export %q: %w", self.pool, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func newTestPoolImport(imported bool, failImports int) (*poolImport, *[]string) {
+	var calls []string
+	p := newPoolImport(&config.PoolImport{
+		Pool:     "backup",
+		Args:     []string{"-d", "/dev/gpt"},
+		Export:   true,
+		Retries:  2,
+		Interval: time.Millisecond,
+	})
+
+	p.imported = func(ctx context.Context, pool string) (bool, error) {
+		calls = append(calls, "imported "+pool)
+		return imported, nil
+	}
+	p.importPool = func(ctx context.Context, pool string, args ...string) error {
+		calls = append(calls, "import "+pool)
+		if failImports > 0 {
+			failImports--
+			return errors.New("no such pool")
+		}
+		return nil
+	}
+	p.exportPool = func(ctx context.Context, pool string) error {
+		calls = append(calls, "export "+pool)
+		return nil
+	}
+	return p, &calls
+}
+
+func TestPoolImport(t *testing.T) {
+	var nilImport *poolImport
+	exportPool, err := nilImport.Import(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, exportPool())
+
+	p, calls := newTestPoolImport(false, 2)
+	exportPool, err = p.Import(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, exportPool())
+	assert.Equal(t, []string{
+		"imported backup",
+		"import backup", "import backup", "import backup",
+		"export backup",
+	}, *calls)
+
+	p, calls = newTestPoolImport(false, 3)
+	_, err = p.Import(t.Context())
+	require.ErrorContains(t, err, "no such pool")
+	assert.Len(t, *calls, 4)
+
+	p, calls = newTestPoolImport(true, 0)
+	exportPool, err = p.Import(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, exportPool())
+	assert.Equal(t, []string{"imported backup"}, *calls,
+		"must not export pool, which it didn't import")
+
+	p, calls = newTestPoolImport(false, 0)
+	p.export = false
+	exportPool, err = p.Import(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, exportPool())
+	assert.Equal(t, []string{"imported backup", "import backup"}, *calls)
+
+	p, _ = newTestPoolImport(false, 1)
+	p.interval = time.Hour
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = p.Import(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,128 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job/signal"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/endpoint"
+)
+
+func pruneJobFromConfig(in *config.PruneJob) (j *PruneJob, err error) {
+	j = &PruneJob{cron: in.Cron, pruneConcurrency: int(in.Pruning.Concurrency)}
+	j.fsfilter, err = filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build filesystem filter: %w", err)
+	}
+
+	j.name, err = endpoint.MakeJobID(in.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job name: %w", err)
+	}
+	if j.overlap, err = overlapFromConfig(in.Overlap); err != nil {
+		return nil, fmt.Errorf("field `overlap`: %w", err)
+	}
+	j.ping = newPing(in.PingURL)
+	j.promPruneSecs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   "zrepl",
+		Subsystem:   "pruning",
+		Name:        "time",
+		Help:        "seconds spent in pruner",
+		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
+	}, []string{"prune_side"})
+	j.promPruneLastSuccess = newPromPruneLastSuccess(j.name.String())
+	j.prunerFactory, err = pruner.NewLocalPrunerFactory(
+		in.Pruning, j.promPruneSecs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build prune job pruning rules: %w", err)
+	}
+	return j, nil
+}
+
+// PruneJob prunes snapshots of filesystems by keep rules on its own cron
+// schedule. It doesn't create snapshots and doesn't replicate.
+type PruneJob struct {
+	name     endpoint.JobID
+	fsfilter *filters.DatasetFilter
+	cron     string
+
+	prunerFactory *pruner.LocalPrunerFactory
+
+	promPruneSecs        *prometheus.HistogramVec // labels: prune_side
+	promPruneLastSuccess *prometheus.GaugeVec     // labels: prune_side
+
+	prunerMtx sync.Mutex
+	pruner    *pruner.Pruner
+
+	pruneConcurrency int
+	overlap          OverlapPolicy
+	ping             *ping
+}
+
+var _ Job = (*PruneJob)(nil)
+
+func (j *PruneJob) Name() string { return j.name.String() }
+
+func (j *PruneJob) Type() Type { return TypePrune }
+
+func (j *PruneJob) Cron() string { return j.cron }
+
+func (j *PruneJob) Runnable() bool { return false }
+
+func (j *PruneJob) Overlap() OverlapPolicy { return j.overlap }
+
+func (j *PruneJob) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(j.promPruneSecs)
+	registerer.MustRegister(j.promPruneLastSuccess)
+}
+
+func (j *PruneJob) Status() *Status {
+	pruneStatus := &SnapJobStatus{}
+	j.prunerMtx.Lock()
+	if j.pruner != nil {
+		pruneStatus.Pruning = j.pruner.Report()
+	}
+	j.prunerMtx.Unlock()
+	return &Status{JobID: j.name, Type: j.Type(), JobSpecific: pruneStatus}
+}
+
+func (j *PruneJob) SenderConfig() *endpoint.SenderConfig { return nil }
+
+func (j *PruneJob) Run(ctx context.Context) error {
+	log := GetLogger(ctx)
+	defer log.Info("job exiting")
+	begin := j.ping.Start(ctx)
+	defer j.ping.Finish(ctx, j, begin)
+	ctx = signal.GracefulFrom(ctx)
+
+	pruner := j.buildPruner(ctx)
+	j.prunerMtx.Lock()
+	j.pruner = pruner
+	j.prunerMtx.Unlock()
+
+	log.With(slog.Int("concurrency", pruner.Concurrency())).
+		Info("start pruning")
+	pruner.Prune()
+	if pruner.Report().Succeeded() {
+		j.promPruneLastSuccess.WithLabelValues("local").SetToCurrentTime()
+	}
+	log.Info("finished pruning")
+	return nil
+}
+
+// PruneDryRun reports snapshots, which keep rules would destroy right now.
+func (j *PruneJob) PruneDryRun(ctx context.Context) []*pruner.DryRunReport {
+	return []*pruner.DryRunReport{j.buildPruner(ctx).DryRun()}
+}
+
+func (j *PruneJob) buildPruner(ctx context.Context) *pruner.Pruner {
+	return buildLocalPruner(ctx, j.prunerFactory, j.name, j.fsfilter,
+		j.pruneConcurrency)
+}
@@ -0,0 +1,77 @@
+package job
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Skipper is a job, which can exclude filesystems from replication for some
+// time.
+type Skipper interface {
+	Skip(fs string, until time.Time)
+}
+
+// SkippedFilesystem describes a filesystem, excluded from replication until
+// Until.
+type SkippedFilesystem struct {
+	Filesystem string
+	Until      time.Time
+}
+
+// skipList is a list of filesystems, temporary excluded from replication.
+type skipList struct {
+	items map[string]time.Time
+	mu    sync.Mutex
+}
+
+// Skip excludes fs until until. Zero or past until removes fs from the list.
+func (self *skipList) Skip(fs string, until time.Time) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if !time.Now().Before(until) {
+		delete(self.items, fs)
+		return
+	} else if self.items == nil {
+		self.items = make(map[string]time.Time)
+	}
+	self.items[fs] = until
+}
+
+// Skipped returns true if fs is excluded now.
+func (self *skipList) Skipped(fs string) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	until, ok := self.items[fs]
+	if !ok {
+		return false
+	} else if time.Now().Before(until) {
+		return true
+	}
+	delete(self.items, fs)
+	return false
+}
+
+// Report returns not expired items of the list, sorted by filesystem name.
+func (self *skipList) Report() []SkippedFilesystem {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	now := time.Now()
+	r := make([]SkippedFilesystem, 0, len(self.items))
+	for fs, until := range self.items {
+		if now.Before(until) {
+			r = append(r, SkippedFilesystem{Filesystem: fs, Until: until})
+		} else {
+			delete(self.items, fs)
+		}
+	}
+
+	slices.SortFunc(r, func(a, b SkippedFilesystem) int {
+		return cmp.Compare(a.Filesystem, b.Filesystem)
+	})
+	return r
+}
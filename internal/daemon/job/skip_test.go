@@ -0,0 +1,36 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipList(t *testing.T) {
+	var l skipList
+	assert.False(t, l.Skipped("zroot/a"))
+	assert.Empty(t, l.Report())
+
+	now := time.Now()
+	l.Skip("zroot/b", now.Add(time.Hour))
+	l.Skip("zroot/a", now.Add(time.Hour))
+	l.Skip("zroot/c", now.Add(time.Nanosecond))
+	l.Skip("zroot/d", now.Add(-time.Hour))
+	time.Sleep(time.Millisecond)
+
+	assert.True(t, l.Skipped("zroot/a"))
+	assert.False(t, l.Skipped("zroot/c"))
+	assert.False(t, l.Skipped("zroot/d"))
+
+	r := l.Report()
+	require.Len(t, r, 2)
+	assert.Equal(t, "zroot/a", r[0].Filesystem)
+	assert.Equal(t, "zroot/b", r[1].Filesystem)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), r[0].Until, time.Minute)
+
+	l.Skip("zroot/a", time.Time{})
+	assert.False(t, l.Skipped("zroot/a"))
+	assert.Len(t, l.Report(), 1)
+}
@@ -30,13 +30,18 @@ func snapJobFromConfig(g *config.Global, in *config.SnapJob) (j *SnapJob,
 	}
 	j.fsfilter = fsf
 
-	if j.snapper, err = snapper.FromConfig(g, fsf, in.Snapshotting); err != nil {
+	j.snapper, err = snapper.FromConfig(g, in.Name, fsf, in.Snapshotting)
+	if err != nil {
 		return nil, fmt.Errorf("cannot build snapper: %w", err)
 	}
 	j.name, err = endpoint.MakeJobID(in.Name)
 	if err != nil {
 		return nil, fmt.Errorf("invalid job name: %w", err)
 	}
+	if j.overlap, err = overlapFromConfig(in.Overlap); err != nil {
+		return nil, fmt.Errorf("field `overlap`: %w", err)
+	}
+	j.ping = newPing(in.PingURL)
 	j.promPruneSecs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace:   "zrepl",
 		Subsystem:   "pruning",
@@ -44,6 +49,7 @@ func snapJobFromConfig(g *config.Global, in *config.SnapJob) (j *SnapJob,
 		Help:        "seconds spent in pruner",
 		ConstLabels: prometheus.Labels{"zrepl_job": j.name.String()},
 	}, []string{"prune_side"})
+	j.promPruneLastSuccess = newPromPruneLastSuccess(j.name.String())
 	j.prunerFactory, err = pruner.NewLocalPrunerFactory(
 		in.Pruning, j.promPruneSecs)
 	if err != nil {
@@ -59,12 +65,15 @@ type SnapJob struct {
 
 	prunerFactory *pruner.LocalPrunerFactory
 
-	promPruneSecs *prometheus.HistogramVec // labels: prune_side
+	promPruneSecs        *prometheus.HistogramVec // labels: prune_side
+	promPruneLastSuccess *prometheus.GaugeVec     // labels: prune_side
 
 	prunerMtx sync.Mutex
 	pruner    *pruner.Pruner
 
 	pruneConcurrency int
+	overlap          OverlapPolicy
+	ping             *ping
 }
 
 var _ Job = (*SnapJob)(nil)
@@ -77,8 +86,11 @@ func (j *SnapJob) Cron() string { return j.snapper.Cron() }
 
 func (j *SnapJob) Runnable() bool { return j.snapper.Runnable() }
 
+func (j *SnapJob) Overlap() OverlapPolicy { return j.overlap }
+
 func (j *SnapJob) RegisterMetrics(registerer prometheus.Registerer) {
 	registerer.MustRegister(j.promPruneSecs)
+	registerer.MustRegister(j.promPruneLastSuccess)
 }
 
 func (j *SnapJob) Status() *Status {
@@ -92,7 +104,7 @@ func (j *SnapJob) Status() *Status {
 	r := j.snapper.Report()
 	snapStatus.Snapshotting = &r
 
-	st := &Status{Type: j.Type(), JobSpecific: snapStatus}
+	st := &Status{JobID: j.name, Type: j.Type(), JobSpecific: snapStatus}
 	if r.Periodic != nil {
 		st.CanWakeup = true
 	}
@@ -197,6 +209,8 @@ func (j *SnapJob) SenderConfig() *endpoint.SenderConfig { return nil }
 func (j *SnapJob) Run(ctx context.Context) error {
 	log := GetLogger(ctx)
 	defer log.Info("job exiting")
+	begin := j.ping.Start(ctx)
+	defer j.ping.Finish(ctx, j, begin)
 	ctx = signal.GracefulFrom(ctx)
 
 	j.snapper.Run(ctx)
@@ -210,17 +224,7 @@ func (j *SnapJob) Run(ctx context.Context) error {
 }
 
 func (j *SnapJob) prune(ctx context.Context) {
-	sender := endpoint.NewSender(endpoint.SenderConfig{
-		JobID: j.name,
-		FSF:   j.fsfilter,
-		// FIXME the following config fields are irrelevant for SnapJob
-		// because the endpoint is only used as pruner.Target.
-		// However, the implementation requires them to be set.
-		Encrypt: true,
-	}).WithPruneConcurrency(j.pruneConcurrency)
-
-	localSender := NewLocalSender(ctx, sender)
-	pruner := j.prunerFactory.BuildLocalPruner(ctx, localSender, localSender)
+	pruner := j.buildPruner(ctx)
 	j.prunerMtx.Lock()
 	j.pruner = pruner
 	j.prunerMtx.Unlock()
@@ -229,9 +233,38 @@ func (j *SnapJob) prune(ctx context.Context) {
 	log.With(slog.Int("concurrency", j.pruner.Concurrency())).
 		Info("start pruning")
 	j.pruner.Prune()
+	if j.pruner.Report().Succeeded() {
+		j.promPruneLastSuccess.WithLabelValues("local").SetToCurrentTime()
+	}
 	log.Info("finished pruning")
 }
 
+// PruneDryRun reports snapshots, which keep rules would destroy right now.
+func (j *SnapJob) PruneDryRun(ctx context.Context) []*pruner.DryRunReport {
+	return []*pruner.DryRunReport{j.buildPruner(ctx).DryRun()}
+}
+
+func (j *SnapJob) buildPruner(ctx context.Context) *pruner.Pruner {
+	return buildLocalPruner(ctx, j.prunerFactory, j.name, j.fsfilter,
+		j.pruneConcurrency)
+}
+
+func buildLocalPruner(ctx context.Context, f *pruner.LocalPrunerFactory,
+	jobID endpoint.JobID, fsf *filters.DatasetFilter, concurrency int,
+) *pruner.Pruner {
+	sender := endpoint.NewSender(endpoint.SenderConfig{
+		JobID: jobID,
+		FSF:   fsf,
+		// FIXME the following config fields are irrelevant for local jobs
+		// because the endpoint is only used as pruner.Target.
+		// However, the implementation requires them to be set.
+		Encrypt: true,
+	}).WithPruneConcurrency(concurrency)
+
+	localSender := NewLocalSender(ctx, sender)
+	return f.BuildLocalPruner(ctx, localSender, localSender)
+}
+
 // Adaptor that implements pruner.History around a pruner.Target.
 // The ReplicationCursor method is Get-op only and always returns
 // the filesystem's most recent version's GUID.
@@ -261,7 +294,31 @@ var (
 func (self *LocalSender) ReplicationCursor(ctx context.Context,
 	req *pdu.ReplicationCursorReq,
 ) (*pdu.ReplicationCursorRes, error) {
-	fsvReq := &pdu.ListFilesystemVersionsReq{Filesystem: req.Filesystem}
+	if len(req.Filesystems) == 0 {
+		result, err := self.replicationCursor(ctx, req.Filesystem)
+		if err != nil {
+			return nil, err
+		}
+		return &pdu.ReplicationCursorRes{Result: result}, nil
+	}
+
+	resp := &pdu.ReplicationCursorRes{
+		Results: make(map[string]*pdu.ReplicationCursorRes_Result,
+			len(req.Filesystems)),
+	}
+	for _, fs := range req.Filesystems {
+		result, err := self.replicationCursor(ctx, fs)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results[fs] = result
+	}
+	return resp, nil
+}
+
+func (self *LocalSender) replicationCursor(ctx context.Context, fs string,
+) (*pdu.ReplicationCursorRes_Result, error) {
+	fsvReq := &pdu.ListFilesystemVersionsReq{Filesystem: fs}
 	res, err := self.ListFilesystemVersions(ctx, fsvReq)
 	if err != nil {
 		return nil, err
@@ -269,17 +326,12 @@ func (self *LocalSender) ReplicationCursor(ctx context.Context,
 
 	fsvs := res.Versions
 	if len(fsvs) == 0 {
-		return &pdu.ReplicationCursorRes{
-			Result: &pdu.ReplicationCursorRes_Result{Notexist: true},
-		}, nil
+		return &pdu.ReplicationCursorRes_Result{Notexist: true}, nil
 	}
 
 	// always return must recent version
 	mostRecent := slices.MaxFunc(fsvs, func(a, b *pdu.FilesystemVersion) int {
 		return cmp.Compare(a.CreateTXG, b.CreateTXG)
 	})
-
-	return &pdu.ReplicationCursorRes{
-		Result: &pdu.ReplicationCursorRes_Result{Guid: mostRecent.GetGuid()},
-	}, nil
+	return &pdu.ReplicationCursorRes_Result{Guid: mostRecent.GetGuid()}, nil
 }
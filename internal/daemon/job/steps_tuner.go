@@ -0,0 +1,131 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// After the last increase didn't improve throughput, don't increase
+// concurrency for this number of intervals.
+const stepsTunerHold = 3
+
+// newStepsTuner returns tuner of parallel steps, which starts from steps, or
+// nil if in is nil.
+func newStepsTuner(steps int, in *config.ConcurrencyAuto) *stepsTuner {
+	if in == nil {
+		return nil
+	}
+
+	t := &stepsTuner{
+		min:      in.Min,
+		max:      in.Max,
+		interval: in.Interval,
+		pools:    in.Pools,
+		latency:  in.Latency,
+
+		poolLatency: zfs.ZpoolLatency,
+	}
+	t.current.Store(int64(min(max(steps, t.min), t.max)))
+	return t
+}
+
+// stepsTuner adjusts number of parallel steps between min and max, using
+// latency of pools and replication throughput.
+type stepsTuner struct {
+	min, max int
+	interval time.Duration
+	pools    []string
+	latency  time.Duration
+
+	poolLatency func(ctx context.Context, pools []string) (time.Duration,
+		error)
+
+	current atomic.Int64
+
+	prevThroughput uint64
+	lastChange     int
+	hold           int
+}
+
+// Concurrency returns current number of parallel steps.
+func (self *stepsTuner) Concurrency() int { return int(self.current.Load()) }
+
+// Run adjusts concurrency every interval, until ctx is done. replicated returns
+// number of bytes replicated so far.
+func (self *stepsTuner) Run(ctx context.Context, replicated func() uint64) {
+	log := GetLogger(ctx)
+	ticker := time.NewTicker(self.interval)
+	defer ticker.Stop()
+
+	prevBytes := replicated()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		bytes := replicated()
+		if bytes < prevBytes {
+			// next attempt of replication started from zero
+			prevBytes = 0
+		}
+		throughput := uint64(float64(bytes-prevBytes) / self.interval.Seconds())
+		prevBytes = bytes
+
+		var latency time.Duration
+		if len(self.pools) > 0 {
+			var err error
+			latency, err = self.poolLatency(ctx, self.pools)
+			if err != nil {
+				logger.WithError(log, err, "cannot sample pools latency")
+			}
+		}
+
+		prev := self.Concurrency()
+		if next := self.adjust(latency, throughput); next != prev {
+			log.With(
+				slog.Int("concurrency", next),
+				slog.Int("prev", prev),
+				slog.Duration("latency", latency),
+				slog.Uint64("throughput", throughput),
+			).Info("adjust concurrency of steps")
+		}
+	}
+}
+
+// adjust changes concurrency using sampled latency of pools and throughput in
+// bytes per second, and returns new concurrency.
+func (self *stepsTuner) adjust(latency time.Duration, throughput uint64) int {
+	cur := self.Concurrency()
+	next := cur
+	switch {
+	case latency > self.latency:
+		next--
+	case throughput == 0:
+		// nothing replicated, nothing to learn
+	case self.lastChange > 0 &&
+		float64(throughput) < float64(self.prevThroughput)*1.05:
+		// the last increase didn't improve throughput
+		next--
+		self.hold = stepsTunerHold
+	case self.hold > 0:
+		self.hold--
+	default:
+		next++
+	}
+
+	next = min(max(next, self.min), self.max)
+	self.lastChange = next - cur
+	if throughput > 0 {
+		self.prevThroughput = throughput
+	}
+	self.current.Store(int64(next))
+	return next
+}
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestStepsTuner_adjust(t *testing.T) {
+	assert.Nil(t, newStepsTuner(1, nil))
+
+	tuner := newStepsTuner(10, &config.ConcurrencyAuto{
+		Min:     1,
+		Max:     4,
+		Latency: 50 * time.Millisecond,
+	})
+	assert.Equal(t, 4, tuner.Concurrency(), "must start inside of bounds")
+
+	// high latency
+	assert.Equal(t, 3, tuner.adjust(100*time.Millisecond, 1000))
+	assert.Equal(t, 2, tuner.adjust(100*time.Millisecond, 1000))
+
+	// throughput grows
+	assert.Equal(t, 3, tuner.adjust(time.Millisecond, 1000))
+	assert.Equal(t, 4, tuner.adjust(time.Millisecond, 2000))
+	assert.Equal(t, 4, tuner.adjust(time.Millisecond, 3000), "max reached")
+
+	// nothing replicated
+	assert.Equal(t, 4, tuner.adjust(time.Millisecond, 0))
+
+	// the last increase didn't help
+	tuner.current.Store(3)
+	assert.Equal(t, 4, tuner.adjust(time.Millisecond, 3000))
+	assert.Equal(t, 3, tuner.adjust(time.Millisecond, 3000))
+	for range stepsTunerHold {
+		assert.Equal(t, 3, tuner.adjust(time.Millisecond, 3000))
+	}
+	assert.Equal(t, 4, tuner.adjust(time.Millisecond, 3000))
+
+	// min reached
+	tuner.current.Store(1)
+	assert.Equal(t, 1, tuner.adjust(time.Second, 3000))
+}
@@ -5,17 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dsh2dsh/cron/v3"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/dsh2dsh/zrepl/internal/config"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job/signal"
 	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/daemon/notify"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
 	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
 	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
@@ -52,20 +62,35 @@ type jobs struct {
 	log  *slog.Logger
 
 	jobs         map[string]*props
+	jobsMu       sync.RWMutex
+	state        *daemonState
 	internalJobs []job.Internal
 	reloaders    []func()
+	notify       *notify.Notifiers
+	gc           *abstractionsGC
+
+	// started is true, when all jobs started.
+	started atomic.Bool
 }
 
 type props struct {
-	job    job.Job
-	cronId cron.EntryID
-
-	mu     sync.Mutex
-	wakeup context.CancelCauseFunc
-	reset  context.CancelCauseFunc
-
-	wakeupBusy int
-	err        error
+	job     job.Job
+	cronId  cron.EntryID
+	metrics *jobMetrics
+
+	mu      sync.Mutex
+	wakeup  context.CancelCauseFunc
+	reset   context.CancelCauseFunc
+	done    chan struct{}
+	removed bool
+
+	overlaps uint
+	queued   bool
+	err      error
+
+	// fatal is an error, which the job can't recover from without
+	// reconfiguration, like invalid cron spec.
+	fatal error
 }
 
 func (self *props) Context(ctx context.Context) context.Context {
@@ -75,34 +100,82 @@ func (self *props) Context(ctx context.Context) context.Context {
 	defer self.mu.Unlock()
 	self.wakeup = wakeupStop
 	ctx, self.reset = context.WithCancelCause(ctx)
+	self.done = make(chan struct{})
 	return ctx
 }
 
-func (self *props) Stop() {
+// Stop marks the job as not running and returns true, if the next run was
+// queued while it was running.
+func (self *props) Stop() (queued bool) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	self.wakeup(nil)
 	self.wakeup = nil
 	self.reset(nil)
 	self.reset = nil
+	close(self.done)
+	queued, self.queued = self.queued && !self.removed, false
+	return queued
+}
+
+// Remove marks the job as removed, so it will not be started again, and aborts
+// its current invocation. It returns a channel, which is closed when the job
+// stopped, or nil if the job isn't running.
+func (self *props) Remove(cause error) <-chan struct{} {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.removed = true
+	if !self.running() {
+		return nil
+	}
+	self.reset(cause)
+	return self.done
 }
 
-func (self *props) Wakeup(cause error, wakeupBusy bool) bool {
+func (self *props) Wakeup(cause error) bool {
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	if !self.running() {
 		return false
-	} else if wakeupBusy {
-		self.wakeupBusy++
-		self.err = fmt.Errorf("job frequency is too high; was skipped %d times",
-			self.wakeupBusy)
 	}
 	self.wakeup(cause)
 	return true
 }
 
+// Overlap applies overlap policy of the job, if it's still running, and
+// returns true. Otherwise it returns false and the job must be started.
+func (self *props) Overlap(cause error) (bool, job.OverlapPolicy) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	policy := self.job.Overlap()
+	if !self.running() {
+		return false, policy
+	}
+
+	self.overlaps++
+	metricJobOverlaps.WithLabelValues(self.job.Name(), policy.String()).Inc()
+	switch policy {
+	case job.OverlapQueue:
+		self.queued = true
+	case job.OverlapStop:
+		self.queued = true
+		self.reset(cause)
+	default:
+		self.err = fmt.Errorf("job frequency is too high; was skipped %d times",
+			self.overlaps)
+		self.wakeup(cause)
+	}
+	return true, policy
+}
+
 func (self *props) running() bool { return self.reset != nil }
 
+func (self *props) Running() bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.running()
+}
+
 func (self *props) Reset(cause error) bool {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -113,6 +186,13 @@ func (self *props) Reset(cause error) bool {
 	return true
 }
 
+// Fatal returns an error, which the job can't recover from, or nil.
+func (self *props) Fatal() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.fatal
+}
+
 func (self *props) PreRun() job.Job {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -120,6 +200,12 @@ func (self *props) PreRun() job.Job {
 	return self.job
 }
 
+// WithState configures persistent state of jobs.
+func (self *jobs) WithState(s *daemonState) *jobs {
+	self.state = s
+	return self
+}
+
 func (self *jobs) Cancel() {
 	self.log.Info("stop all jobs")
 	self.cancel()
@@ -146,7 +232,23 @@ func (self *jobs) Shutdown() {
 	self.gracefulStop(errors.New("graceful stop"))
 }
 
+func (self *jobs) job(name string) (*props, bool) {
+	self.jobsMu.RLock()
+	defer self.jobsMu.RUnlock()
+	j, ok := self.jobs[name]
+	return j, ok
+}
+
+// names returns names of all configured jobs.
+func (self *jobs) names() []string {
+	self.jobsMu.RLock()
+	defer self.jobsMu.RUnlock()
+	return slices.Collect(maps.Keys(self.jobs))
+}
+
 func (self *jobs) status() map[string]*job.Status {
+	self.jobsMu.RLock()
+	defer self.jobsMu.RUnlock()
 	ret := make(map[string]*job.Status, len(self.jobs))
 	for name, j := range self.jobs {
 		if s := j.job.Status(); s != nil {
@@ -164,19 +266,25 @@ func (self *jobs) updateStatus(j *props, s *job.Status) *job.Status {
 		entry := self.cron.Entry(j.cronId)
 		s.NextCron = entry.Next
 	}
+	s.Overlaps = j.overlaps
+	s.Disabled = self.state.Disabled(j.job.Name())
 	return s
 }
 
 func (self *jobs) wakeup(name string) error {
-	j, ok := self.jobs[name]
+	j, ok := self.job(name)
 	if !ok {
 		return fmt.Errorf("job does not exist: %s", name)
 	}
 
+	if self.state.Disabled(name) {
+		return fmt.Errorf("job disabled: %s", name)
+	}
+
 	log := job.GetLogger(self.ctx).With(
 		slog.String(logging.JobField, name))
 	log.Info("wakeup job from signal")
-	if j.Wakeup(errors.New("wakeup from signal"), false) {
+	if j.Wakeup(errors.New("wakeup from signal")) {
 		return nil
 	}
 
@@ -185,8 +293,134 @@ func (self *jobs) wakeup(name string) error {
 	return nil
 }
 
+func (self *jobs) verify(ctx context.Context, name string,
+) (*report.VerifyReport, error) {
+	j, ok := self.job(name)
+	if !ok {
+		return nil, fmt.Errorf("job does not exist: %s", name)
+	}
+	v, ok := j.job.(job.Verifier)
+	if !ok {
+		return nil, fmt.Errorf("job doesn't support verification: %s", name)
+	}
+	return v.Verify(logging.With(ctx, slog.String(logging.JobField, name))), nil
+}
+
+func (self *jobs) adopt(ctx context.Context, name string, dryRun bool,
+) (*report.VerifyReport, error) {
+	j, ok := self.job(name)
+	if !ok {
+		return nil, fmt.Errorf("job does not exist: %s", name)
+	}
+	a, ok := j.job.(job.Adopter)
+	if !ok {
+		return nil, fmt.Errorf("job doesn't support adoption: %s", name)
+	} else if j.Running() {
+		return nil, fmt.Errorf("job is running: %s", name)
+	}
+	ctx = logging.With(ctx, slog.String(logging.JobField, name))
+	return a.Adopt(ctx, dryRun), nil
+}
+
+func (self *jobs) pruneDryRun(ctx context.Context, name string,
+) ([]*pruner.DryRunReport, error) {
+	j, ok := self.job(name)
+	if !ok {
+		return nil, fmt.Errorf("job does not exist: %s", name)
+	}
+	p, ok := j.job.(job.PruneDryRunner)
+	if !ok {
+		return nil, fmt.Errorf("job doesn't support pruning: %s", name)
+	}
+	ctx = logging.With(ctx, slog.String(logging.JobField, name))
+	return p.PruneDryRun(ctx), nil
+}
+
+func (self *jobs) skip(name, fs string, d time.Duration) error {
+	j, ok := self.job(name)
+	if !ok {
+		return fmt.Errorf("job does not exist: %s", name)
+	}
+	s, ok := j.job.(job.Skipper)
+	if !ok {
+		return fmt.Errorf("job doesn't support skipping filesystems: %s", name)
+	} else if _, err := zfs.NewDatasetPath(fs); err != nil {
+		return fmt.Errorf("invalid filesystem %q: %w", fs, err)
+	}
+
+	var until time.Time
+	if d > 0 {
+		until = time.Now().Add(d)
+	}
+	if err := self.state.SetSkipped(name, fs, until); err != nil {
+		return err
+	}
+	s.Skip(fs, until)
+	return nil
+}
+
+// bwlimit returns bandwidth limit of job name. If set is true, it changes the
+// limit to limit bytes per second first.
+func (self *jobs) bwlimit(name string, set bool, limit uint64) (uint64, error) {
+	j, ok := self.job(name)
+	if !ok {
+		return 0, fmt.Errorf("job does not exist: %s", name)
+	}
+	l, ok := j.job.(job.BandwidthLimiter)
+	if !ok {
+		return 0, fmt.Errorf("job doesn't support bandwidth limit: %s", name)
+	} else if set {
+		l.SetBandwidthLimit(limit)
+	}
+	return l.BandwidthLimit(), nil
+}
+
+// enable enables or disables job name. Disabled job doesn't start until it
+// enabled again, and its current invocation is aborted.
+func (self *jobs) enable(name string, enable bool) error {
+	j, ok := self.job(name)
+	if !ok {
+		return fmt.Errorf("job does not exist: %s", name)
+	} else if err := self.state.SetDisabled(name, !enable); err != nil {
+		return err
+	} else if !enable {
+		j.Reset(errors.New("job disabled"))
+	}
+	return nil
+}
+
+// pause pauses replication of job name. If stopZFS is true, active zfs
+// processes of the job are suspended too.
+func (self *jobs) pause(name string, stopZFS bool) error {
+	p, err := self.pauser(name)
+	if err != nil {
+		return err
+	}
+	return p.Pause(stopZFS)
+}
+
+func (self *jobs) resume(name string) error {
+	p, err := self.pauser(name)
+	if err != nil {
+		return err
+	}
+	return p.Resume()
+}
+
+func (self *jobs) pauser(name string) (job.Pauser, error) {
+	j, ok := self.job(name)
+	if !ok {
+		return nil, fmt.Errorf("job does not exist: %s", name)
+	}
+	p, ok := j.job.(job.Pauser)
+	if !ok {
+		return nil, fmt.Errorf("job doesn't support pausing: %s", name)
+	}
+	return p, nil
+}
+
 func (self *jobs) reset(name string) error {
-	j, ok := self.jobs[name]
+	j, ok := self.job(name)
 	if !ok {
 		return fmt.Errorf("job does not exist: %s", name)
 	} else if !j.Reset(errors.New("reset signal")) {
@@ -206,24 +440,63 @@ func (self *jobs) startCronJobs(confJobs []job.Job) {
 				context.Cause(self.ctx), "break starting jobs")
 			break
 		}
-		p := &props{job: j}
-		self.jobs[name] = p
-		j.RegisterMetrics(prometheus.DefaultRegisterer)
-		log := log.With(slog.String(logging.JobField, name))
-		if j.Runnable() {
-			self.runJob(p, log)
+		p := self.addJob(j)
+		if self.startJob(p, log.With(slog.String(logging.JobField, name))) {
 			runCount++
-		} else {
-			log.With(slog.Bool("runnable", false)).Info("job initialized")
 		}
-		self.registerCron(p, log)
 	}
 
 	self.cron.Start()
+	self.started.Store(true)
 	self.log.With(slog.Int("count", len(self.jobs)), slog.Int("run", runCount)).
 		Info("started jobs")
 }
 
+func (self *jobs) addJob(j job.Job) *props {
+	if s, ok := j.(job.Skipper); ok {
+		for fs, until := range self.state.Skipped(j.Name()) {
+			s.Skip(fs, until)
+		}
+	}
+	p := &props{job: j, metrics: newJobMetrics(prometheus.DefaultRegisterer)}
+	self.jobsMu.Lock()
+	self.jobs[j.Name()] = p
+	self.jobsMu.Unlock()
+	j.RegisterMetrics(p.metrics)
+	return p
+}
+
+func (self *jobs) startJob(p *props, log *slog.Logger) (run bool) {
+	if self.state.Disabled(p.job.Name()) {
+		log.Info("job disabled")
+	} else if p.job.Runnable() {
+		self.runJob(p, log)
+		run = true
+	} else {
+		log.With(slog.Bool("runnable", false)).Info("job initialized")
+	}
+	self.registerCron(p, log)
+	return
+}
+
+// removeJob stops and removes job name. It returns a channel, which is closed
+// when the job stopped, or nil if the job wasn't running.
+func (self *jobs) removeJob(name string, cause error) <-chan struct{} {
+	self.jobsMu.Lock()
+	p, ok := self.jobs[name]
+	delete(self.jobs, name)
+	self.jobsMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if p.cronId > 0 {
+		self.cron.Remove(p.cronId)
+	}
+	p.metrics.Reset()
+	return p.Remove(cause)
+}
+
 func (self *jobs) mustCheckJobName(s string) {
 	if strings.HasPrefix(s, "_") {
 		panic("internal job name used for non-internal job " + s)
@@ -234,10 +507,18 @@ func (self *jobs) mustCheckJobName(s string) {
 }
 
 func (self *jobs) runJob(p *props, log *slog.Logger) {
-	fn := self.makeStartFunc(self.context(p), p.PreRun(), log)
+	ctx := self.context(p)
+	fn := self.makeStartFunc(ctx, p.PreRun(), log)
 	self.g.Go(func() error {
-		defer p.Stop()
-		return fn()
+		begin := time.Now()
+		err := fn()
+		self.notifyRun(ctx, p.job, begin, err)
+		if p.Stop() && self.graceful.Err() == nil &&
+			!self.state.Disabled(p.job.Name()) {
+			log.Info("start queued job")
+			self.runJob(p, log)
+		}
+		return err
 	})
 }
 
@@ -290,19 +571,30 @@ func (self *jobs) registerCron(p *props, log *slog.Logger) {
 	log = log.With(slog.String("cron", cronSpec))
 
 	log.Info("register cron job")
-	id, err := self.cron.AddFunc(cronSpec, func() {
-		self.handleCron(p, log)
-	})
+	schedule, err := snapper.ParseCron(cronSpec)
 	if err != nil {
 		logger.WithError(log, err, "failed add cron job")
+		p.mu.Lock()
+		p.fatal = fmt.Errorf("failed add cron job %q: %w", cronSpec, err)
+		p.mu.Unlock()
+		return
 	}
-	p.cronId = id
+	p.cronId = self.cron.Schedule(schedule, cron.FuncJob(func() {
+		self.handleCron(p, log)
+	}))
 }
 
 func (self *jobs) handleCron(j *props, log *slog.Logger) {
+	if self.state.Disabled(j.job.Name()) {
+		log.Info("skip disabled job from cron")
+		return
+	}
+
 	log.Info("start job from cron")
-	if j.Wakeup(errors.New("wakeup from cron"), true) {
-		log.Warn("job took longer than its interval")
+	running, policy := j.Overlap(errors.New("wakeup from cron"))
+	if running {
+		log.With(slog.String("overlap", policy.String())).
+			Warn("job took longer than its interval")
 		return
 	}
 	self.runJob(j, log)
@@ -315,6 +607,22 @@ func (self *jobs) startInternal(j job.Internal) {
 	self.internalJobs = append(self.internalJobs, j)
 }
 
+// startAbstractionsGC starts internal job, which periodically destroys stale
+// abstractions of jobs.
+func (self *jobs) startAbstractionsGC(in *config.AbstractionsGC) {
+	self.gc = newAbstractionsGC(in, self.names)
+	self.startInternal(self.gc)
+}
+
+// abstractionsGCReport returns report of abstractions gc or nil, if it's
+// disabled.
+func (self *jobs) abstractionsGCReport() *AbstractionsGCReport {
+	if self.gc == nil {
+		return nil
+	}
+	return self.gc.Report()
+}
+
 func (self *jobs) Reload() {
 	self.log.Info("reloading")
 	for _, fn := range self.reloaders {
@@ -141,45 +141,70 @@ func parseLevel(s string) (l slog.Level, err error) {
 }
 
 func parseTCPOutlet(in *config.TCPLoggingOutlet, formatter *SlogFormatter,
-) (_ *TCPOutlet, err error) {
-	var tlsConfig *tls.Config
-	if in.TLS != nil {
-		tlsConfig, err = func(m *config.TCPLoggingOutletTLS, host string,
-		) (*tls.Config, error) {
-			clientCert, err := tls.LoadX509KeyPair(m.Cert, m.Key)
-			if err != nil {
-				return nil, fmt.Errorf("cannot load client cert: %w", err)
-			}
-
-			var rootCAs *x509.CertPool
-			if m.CA == "" {
-				if rootCAs, err = x509.SystemCertPool(); err != nil {
-					return nil, fmt.Errorf("cannot open system cert pool: %w", err)
-				}
-			} else {
-				rootCAs, err = tlsconf.ParseCAFile(m.CA)
-				if err != nil {
-					return nil, fmt.Errorf("cannot parse CA cert: %w", err)
-				}
-			}
-			if rootCAs == nil {
-				panic("invariant violated")
-			}
-			return tlsconf.ClientAuthClient(host, rootCAs, clientCert)
-		}(in.TLS, in.Address)
-		if err != nil {
-			return nil, errors.New("cannot not parse TLS config in field 'tls'")
-		}
+) (*TCPOutlet, error) {
+	tlsConfig, err := parseOutletTLS(in.TLS, in.Address)
+	if err != nil {
+		return nil, err
 	}
-
 	o := NewTCPOutlet(formatter.WithLogMetadata(true), in.Net, in.Address,
 		tlsConfig, in.RetryInterval)
 	return o, nil
 }
 
+func parseOutletTLS(m *config.TCPLoggingOutletTLS, host string,
+) (*tls.Config, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	clientCert, err := tlsconf.LoadX509KeyPair(m.Cert, m.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client cert: %w", err)
+	}
+
+	var rootCAs *x509.CertPool
+	if m.CA == "" {
+		if rootCAs, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("cannot open system cert pool: %w", err)
+		}
+	} else {
+		rootCAs, err = tlsconf.ParseCAFile(m.CA)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse CA cert: %w", err)
+		}
+	}
+	if rootCAs == nil {
+		panic("invariant violated")
+	}
+
+	tlsConfig, err := tlsconf.ClientAuthClient(host, rootCAs, clientCert)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse TLS config in field 'tls': %w",
+			err)
+	}
+	return tlsConfig, nil
+}
+
 func parseSyslogOutlet(in *config.SyslogLoggingOutlet, formatter *SlogFormatter,
-) (*SyslogOutlet, error) {
-	o := NewSyslogOutlet(formatter, syslog.Priority(in.Facility),
-		in.RetryInterval)
+) (slog.Handler, error) {
+	if in.Address == "" {
+		o := NewSyslogOutlet(formatter, syslog.Priority(in.Facility),
+			in.RetryInterval)
+		return o, nil
+	}
+
+	switch {
+	case in.Net != "udp" && in.Net != "tcp":
+		return nil, fmt.Errorf("invalid syslog net %q, must be udp or tcp", in.Net)
+	case in.TLS != nil && in.Net == "udp":
+		return nil, errors.New("syslog over TLS requires tcp net")
+	}
+
+	tlsConfig, err := parseOutletTLS(in.TLS, in.Address)
+	if err != nil {
+		return nil, err
+	}
+	o := NewRemoteSyslogOutlet(formatter, syslog.Priority(in.Facility),
+		in.Net, in.Address, tlsConfig, in.RetryInterval)
 	return o, nil
 }
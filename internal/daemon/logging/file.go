@@ -7,6 +7,7 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"sync"
 	"syscall"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
@@ -14,7 +15,18 @@ import (
 
 func parseFileOutlet(in *config.FileLoggingOutlet, formatter *SlogFormatter,
 ) (*FileOutlet, error) {
-	return NewFileOutlet(in.FileName, formatter)
+	rotate, err := rotateFromConfig(in)
+	if err != nil {
+		return nil, err
+	} else if in.FileName == "" {
+		return NewFileOutlet("", formatter)
+	}
+
+	f, err := NewLogFile(in.FileName, WithRotate(rotate))
+	if err != nil {
+		return nil, err
+	}
+	return new(FileOutlet).WithFormatter(formatter).WithWriter(f), nil
 }
 
 func NewFileOutlet(filename string, formatter *SlogFormatter,
@@ -73,24 +85,49 @@ func (self *FileOutlet) WithGroup(name string) slog.Handler {
 
 // --------------------------------------------------
 
-func NewLogFile(filename string) (f *logFile, err error) {
+func NewLogFile(filename string, opts ...LogFileOption) (f *logFile,
+	err error,
+) {
 	f = &logFile{filename: filename}
+	for _, fn := range opts {
+		fn(f)
+	}
 	if err := f.Open(); err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
+type LogFileOption func(f *logFile)
+
+// WithRotate configures rotation of the log file.
+func WithRotate(r Rotate) LogFileOption {
+	return func(f *logFile) { f.rotate = r }
+}
+
 type logFile struct {
 	f        *os.File
 	filename string
+	size     int64
+	rotate   Rotate
+
+	mu sync.Mutex
+	// millMu serializes compression and removal of rotated files.
+	millMu sync.Mutex
 }
 
 func (self *logFile) Write(p []byte) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
 	if err := self.reopenIfNotExists(); err != nil {
 		return 0, fmt.Errorf("reopen file %q: %w", self.filename, err)
+	} else if err := self.rotateIfNeeded(len(p)); err != nil {
+		return 0, fmt.Errorf("rotate file %q: %w", self.filename, err)
 	}
+
 	n, err := self.f.Write(p)
+	self.size += int64(n)
 	if err != nil {
 		return n, fmt.Errorf("write to %q: %w", self.filename, err)
 	}
@@ -133,6 +170,12 @@ func (self *logFile) Open() error {
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
-	self.f = f
+
+	finfo, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat of %q: %w", self.filename, err)
+	}
+	self.f, self.size = f, finfo.Size()
 	return nil
 }
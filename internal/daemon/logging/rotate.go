@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+// rotateTimeFormat is the suffix of rotated files, like
+// zrepl.log.2006-01-02T15-04-05.000, always in UTC.
+const rotateTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// Rotate configures rotation of log files. Zero values disable it.
+type Rotate struct {
+	// MaxSize rotates the file, when it grows bigger.
+	MaxSize int64
+	// MaxAge removes rotated files older than it.
+	MaxAge time.Duration
+	// MaxBackups removes rotated files, except MaxBackups most recent.
+	MaxBackups int
+	// Compress rotated files by gzip.
+	Compress bool
+}
+
+func rotateFromConfig(in *config.FileLoggingOutlet) (Rotate, error) {
+	switch {
+	case in.MaxSize > 0 && in.FileName == "":
+		return Rotate{}, errors.New("max_size requires filename")
+	case in.MaxAge < 0:
+		return Rotate{}, fmt.Errorf("negative max_age: %s", in.MaxAge)
+	case in.MaxBackups < 0:
+		return Rotate{}, fmt.Errorf("negative max_backups: %d", in.MaxBackups)
+	}
+	return Rotate{
+		MaxSize:    int64(in.MaxSize.Uint64()),
+		MaxAge:     in.MaxAge,
+		MaxBackups: in.MaxBackups,
+		Compress:   in.Compress,
+	}, nil
+}
+
+// rotateIfNeeded rotates the file, if writing n more bytes makes it bigger,
+// than MaxSize. Rotated files are compressed and removed in background.
+//
+// caller must hold lock mu
+func (self *logFile) rotateIfNeeded(n int) error {
+	maxSize := self.rotate.MaxSize
+	if maxSize <= 0 || self.size == 0 || self.size+int64(n) <= maxSize {
+		return nil
+	}
+
+	if err := self.f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	backup := self.filename + "." + time.Now().UTC().Format(rotateTimeFormat)
+	if err := os.Rename(self.filename, backup); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	} else if err := self.Open(); err != nil {
+		return err
+	}
+
+	r := &self.rotate
+	if r.MaxAge == 0 && r.MaxBackups == 0 && !r.Compress {
+		return nil
+	}
+	go func() {
+		if err := self.mill(time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "zrepl: rotate %q: %s\n", self.filename, err)
+		}
+	}()
+	return nil
+}
+
+// mill compresses rotated files and removes old ones.
+func (self *logFile) mill(now time.Time) error {
+	self.millMu.Lock()
+	defer self.millMu.Unlock()
+
+	backups, err := self.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, b := range backups {
+		switch {
+		case self.rotate.MaxBackups > 0 && i >= self.rotate.MaxBackups,
+			self.rotate.MaxAge > 0 && now.Sub(b.t) > self.rotate.MaxAge:
+			if err := os.Remove(b.name); err != nil {
+				errs = append(errs, err)
+			}
+		case self.rotate.Compress && !strings.HasSuffix(b.name, compressSuffix):
+			if err := compressFile(b.name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type logBackup struct {
+	name string
+	t    time.Time
+}
+
+// backups returns rotated files, most recent first.
+func (self *logFile) backups() ([]logBackup, error) {
+	dir, base := filepath.Split(self.filename)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	prefix := base + "."
+	var backups []logBackup
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix)
+		t, err := time.Parse(rotateTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{name: filepath.Join(dir, name), t: t})
+	}
+
+	slices.SortFunc(backups, func(a, b logBackup) int { return b.t.Compare(a.t) })
+	return backups, nil
+}
+
+// compressFile compresses name into name.gz and removes name.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	gzName := name + compressSuffix
+	dst, err := os.OpenFile(gzName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(gzName)
+		return fmt.Errorf("compress %q: %w", name, err)
+	}
+	return os.Remove(name)
+}
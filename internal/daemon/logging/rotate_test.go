@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestLogFile_rotate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "zrepl.log")
+	f, err := NewLogFile(filename, WithRotate(Rotate{MaxSize: 10}))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("12345678\n"))
+	require.NoError(t, err)
+	backups, err := f.backups()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	_, err = f.Write([]byte("abc\n"))
+	require.NoError(t, err)
+	backups, err = f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	b, err := os.ReadFile(backups[0].name)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(b))
+	b, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "abc\n", string(b))
+}
+
+func TestLogFile_mill(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "zrepl.log")
+	now := time.Now().UTC()
+	backup := func(d time.Duration) string {
+		return filename + "." + now.Add(-d).Format(rotateTimeFormat)
+	}
+
+	names := []string{
+		backup(time.Minute), backup(time.Hour), backup(2 * time.Hour),
+		backup(48 * time.Hour),
+	}
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(name, []byte(name), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filename+".unrelated", nil, 0o644))
+
+	f, err := NewLogFile(filename, WithRotate(Rotate{
+		MaxAge:     24 * time.Hour,
+		MaxBackups: 2,
+		Compress:   true,
+	}))
+	require.NoError(t, err)
+	require.NoError(t, f.mill(now))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var got []string
+	for _, e := range entries {
+		got = append(got, filepath.Join(dir, e.Name()))
+	}
+	assert.ElementsMatch(t, []string{
+		filename, filename + ".unrelated",
+		names[0] + compressSuffix, names[1] + compressSuffix,
+	}, got)
+
+	r, err := os.Open(names[0] + compressSuffix)
+	require.NoError(t, err)
+	defer r.Close()
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, names[0], string(b))
+}
+
+func TestRotateFromConfig(t *testing.T) {
+	r, err := rotateFromConfig(&config.FileLoggingOutlet{
+		FileName:   "zrepl.log",
+		MaxSize:    config.Bytes(1 << 20),
+		MaxAge:     time.Hour,
+		MaxBackups: 3,
+		Compress:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Rotate{
+		MaxSize: 1 << 20, MaxAge: time.Hour, MaxBackups: 3, Compress: true,
+	}, r)
+
+	_, err = rotateFromConfig(&config.FileLoggingOutlet{MaxSize: 1})
+	require.ErrorContains(t, err, "filename")
+	_, err = rotateFromConfig(&config.FileLoggingOutlet{MaxBackups: -1})
+	require.Error(t, err)
+	_, err = rotateFromConfig(&config.FileLoggingOutlet{MaxAge: -time.Second})
+	require.Error(t, err)
+}
@@ -63,12 +63,21 @@ func (self *SlogFormatter) WithHideFields(fields []string) *SlogFormatter {
 	return self
 }
 
+func (self *SlogFormatter) hideFields(fields ...string) {
+	if self.hide == nil {
+		self.hide = make(map[string]struct{}, len(fields))
+	}
+	for _, field := range fields {
+		self.hide[field] = struct{}{}
+	}
+}
+
 func (self *SlogFormatter) WithJsonHandler() *SlogFormatter {
 	self.json = true
 	self.h = slog.NewJSONHandler(self.b, &slog.HandlerOptions{
 		AddSource:   self.addSource,
 		Level:       self.minLevel,
-		ReplaceAttr: self.replaceHiddenAttr,
+		ReplaceAttr: self.replaceJsonAttr,
 	})
 	return self
 }
@@ -106,6 +115,19 @@ func (self *SlogFormatter) replaceTextAttr(groups []string, a slog.Attr,
 	return self.replaceHiddenAttr(groups, a)
 }
 
+func (self *SlogFormatter) replaceJsonAttr(groups []string, a slog.Attr,
+) slog.Attr {
+	if len(groups) == 0 {
+		switch {
+		case a.Key == slog.TimeKey && !self.logTime:
+			return slog.Attr{}
+		case a.Key == slog.LevelKey && !self.logLevel:
+			return slog.Attr{}
+		}
+	}
+	return self.replaceHiddenAttr(groups, a)
+}
+
 func (self *SlogFormatter) replaceHiddenAttr(_ []string, a slog.Attr) slog.Attr {
 	if self.hiddenField(a.Key) {
 		return slog.Attr{}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestSlogFormatter_json(t *testing.T) {
+	tests := []struct {
+		name   string
+		common config.LoggingOutletCommon
+		want   map[string]any
+	}{
+		{
+			name:   "with time",
+			common: config.LoggingOutletCommon{Format: "json", Time: true},
+			want: map[string]any{
+				"level": "INFO",
+				"msg":   "first line\nsecond line",
+				"job":   "foo",
+				"n":     float64(1),
+				"span":  "abc",
+				"fs":    map[string]any{"name": "zroot/foo"},
+			},
+		},
+		{
+			name: "without time and hidden field",
+			common: config.LoggingOutletCommon{
+				Format:     "json",
+				HideFields: []string{"span"},
+			},
+			want: map[string]any{
+				"level": "INFO",
+				"msg":   "first line\nsecond line",
+				"job":   "foo",
+				"n":     float64(1),
+				"fs":    map[string]any{"name": "zroot/foo"},
+			},
+		},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseLogFormat(tt.common)
+			require.NoError(t, err)
+			h := f.WithAttrs([]slog.Attr{slog.String("job", "foo")})
+
+			r := slog.NewRecord(now, slog.LevelInfo, "first line\nsecond line", 0)
+			r.AddAttrs(slog.Int("n", 1), slog.String("span", "abc"),
+				slog.Group("fs", slog.String("name", "zroot/foo")))
+
+			var b bytes.Buffer
+			require.NoError(t, h.(*SlogFormatter).Write(&b, r))
+			require.NoError(t, h.(*SlogFormatter).Write(&b, r))
+
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			require.Len(t, lines, 2, "one JSON object per line")
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+			if tt.common.Time {
+				require.Contains(t, got, "time")
+				ts, err := time.Parse(time.RFC3339Nano, got["time"].(string))
+				require.NoError(t, err)
+				assert.True(t, now.Equal(ts))
+				delete(got, "time")
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
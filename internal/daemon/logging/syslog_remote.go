@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"log/syslog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// rfc5424Time is RFC 3339 time with at most 6 digits of fractional seconds,
+	// allowed by RFC 5424.
+	rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+	// sdID identifies structured data of zrepl messages. 32473 is the private
+	// enterprise number reserved for documentation by RFC 5612.
+	sdID = "zrepl@32473"
+)
+
+// sdFields are the fields sent as RFC 5424 structured data instead of message
+// text.
+var sdFields = [...]string{JobField, SubsysField, "fs"}
+
+// NewRemoteSyslogOutlet returns a handler, which sends RFC 5424 messages to a
+// remote syslog server. Messages sent over tcp are framed by octet counting
+// from RFC 6587.
+func NewRemoteSyslogOutlet(f *SlogFormatter, facility syslog.Priority,
+	network, address string, tlsConfig *tls.Config,
+	retryInterval time.Duration,
+) *RemoteSyslogOutlet {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	sd := make([]string, 0, len(sdFields))
+	for _, name := range sdFields {
+		if !f.hiddenField(name) {
+			sd = append(sd, name)
+		}
+	}
+	f.hideFields(sd...)
+
+	return &RemoteSyslogOutlet{
+		formatter: f.WithLogMetadata(false),
+		facility:  facility,
+		hostname:  hostname,
+		pid:       strconv.Itoa(os.Getpid()),
+		sdFields:  sd,
+		framed:    network != "udp",
+		conn:      newConnWriter(network, address, tlsConfig, retryInterval),
+	}
+}
+
+type RemoteSyslogOutlet struct {
+	formatter Formatter
+	facility  syslog.Priority
+	hostname  string
+	pid       string
+	framed    bool
+
+	sdFields []string
+	sd       []slog.Attr
+	grouped  bool
+
+	conn *connWriter
+}
+
+var _ slog.Handler = (*RemoteSyslogOutlet)(nil)
+
+func (self *RemoteSyslogOutlet) Enabled(ctx context.Context, level slog.Level,
+) bool {
+	return self.formatter.Enabled(ctx, level)
+}
+
+func (self *RemoteSyslogOutlet) Handle(_ context.Context, r slog.Record) error {
+	sd := self.sd
+	if !self.grouped {
+		r.Attrs(func(a slog.Attr) bool {
+			if self.isSDField(a.Key) {
+				sd = append(slices.Clip(sd), a)
+			}
+			return true
+		})
+	}
+
+	msg := new(bytes.Buffer)
+	self.writeHeader(msg, r, sd)
+	err := self.formatter.FormatWithCallback(r, func(b []byte) error {
+		msg.Write(b)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !self.framed {
+		return self.conn.Send(msg)
+	}
+	frame := bytes.NewBuffer(make([]byte, 0, msg.Len()+8))
+	frame.WriteString(strconv.Itoa(msg.Len()))
+	frame.WriteByte(' ')
+	frame.Write(msg.Bytes())
+	return self.conn.Send(frame)
+}
+
+// writeHeader writes RFC 5424 header and structured data:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID PARAM="VALUE"...]
+func (self *RemoteSyslogOutlet) writeHeader(b *bytes.Buffer, r slog.Record,
+	sd []slog.Attr,
+) {
+	pri := int(self.facility&^0x07) | int(syslogSeverity(r.Level))
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(pri))
+	b.WriteString(">1 ")
+	if r.Time.IsZero() {
+		b.WriteByte('-')
+	} else {
+		b.WriteString(r.Time.Format(rfc5424Time))
+	}
+	b.WriteByte(' ')
+	b.WriteString(self.hostname)
+	b.WriteString(" zrepl ")
+	b.WriteString(self.pid)
+	b.WriteString(" - ")
+
+	if len(sd) == 0 {
+		b.WriteString("- ")
+		return
+	}
+	b.WriteString("[" + sdID)
+	for _, a := range sd {
+		b.WriteByte(' ')
+		b.WriteString(a.Key)
+		b.WriteString(`="`)
+		sdEscaper.WriteString(b, a.Value.Resolve().String())
+		b.WriteByte('"')
+	}
+	b.WriteString("] ")
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func syslogSeverity(l slog.Level) syslog.Priority {
+	switch {
+	case l < slog.LevelInfo:
+		return syslog.LOG_DEBUG
+	case l < slog.LevelWarn:
+		return syslog.LOG_INFO
+	case l < slog.LevelError:
+		return syslog.LOG_WARNING
+	}
+	return syslog.LOG_ERR
+}
+
+func (self *RemoteSyslogOutlet) isSDField(name string) bool {
+	return slices.Contains(self.sdFields, name)
+}
+
+func (self *RemoteSyslogOutlet) WithAttrs(attrs []slog.Attr) slog.Handler {
+	o := *self
+	o.formatter = self.formatter.WithAttrs(attrs)
+	if !self.grouped {
+		for _, a := range attrs {
+			if self.isSDField(a.Key) {
+				o.sd = append(slices.Clip(o.sd), a)
+			}
+		}
+	}
+	return &o
+}
+
+func (self *RemoteSyslogOutlet) WithGroup(name string) slog.Handler {
+	o := *self
+	o.formatter = self.formatter.WithGroup(name)
+	o.grouped = true
+	return &o
+}
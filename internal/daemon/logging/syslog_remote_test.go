@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func newTestRemoteSyslog(t *testing.T, network, address string,
+) *slog.Logger {
+	t.Helper()
+	f, err := parseLogFormat(config.LoggingOutletCommon{
+		Format:     "text",
+		HideFields: []string{"span"},
+	})
+	require.NoError(t, err)
+	o := NewRemoteSyslogOutlet(f, syslog.LOG_LOCAL0, network, address, nil,
+		time.Second)
+	t.Cleanup(o.conn.Close)
+	return slog.New(o)
+}
+
+func TestRemoteSyslogOutlet_udp(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	log := newTestRemoteSyslog(t, "udp", pc.LocalAddr().String())
+	log.With(slog.String(JobField, "foo"), slog.String("span", "abc")).
+		Warn("hello", slog.String("fs", `zroot/"a]`), slog.Int("n", 1))
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	hostname, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+	// local0 (16) * 8 + warning (4)
+	re := regexp.MustCompile(`^<132>1 \S+ ` + regexp.QuoteMeta(hostname) +
+		` zrepl ` + pid + ` - \[zrepl@32473 job="foo" fs="zroot/\\"a\\]"\] ` +
+		`hello n=1$`)
+	assert.Regexp(t, re, string(b[:n]))
+}
+
+func TestRemoteSyslogOutlet_tcp(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	log := newTestRemoteSyslog(t, "tcp", l.Addr().String())
+	log.Info("first")
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	r := bufio.NewReader(conn)
+	readFrame := func() string {
+		lenStr, err := r.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(lenStr[:len(lenStr)-1])
+		require.NoError(t, err)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(r, msg)
+		require.NoError(t, err)
+		return string(msg)
+	}
+
+	assert.Regexp(t, `^<134>1 .* - - first$`, readFrame())
+	log.Error("second")
+	assert.Regexp(t, `^<131>1 .* - - second$`, readFrame())
+}
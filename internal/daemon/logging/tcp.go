@@ -15,6 +15,25 @@ import (
 func NewTCPOutlet(formatter Formatter, network, address string,
 	tlsConfig *tls.Config, retryInterval time.Duration,
 ) *TCPOutlet {
+	return &TCPOutlet{
+		formatter: formatter,
+		conn:      newConnWriter(network, address, tlsConfig, retryInterval),
+	}
+}
+
+type TCPOutlet struct {
+	formatter Formatter
+	conn      *connWriter
+}
+
+var _ slog.Handler = (*TCPOutlet)(nil)
+
+// FIXME: use this method
+func (h *TCPOutlet) Close() { h.conn.Close() }
+
+func newConnWriter(network, address string, tlsConfig *tls.Config,
+	retryInterval time.Duration,
+) *connWriter {
 	connect := func(ctx context.Context) (conn net.Conn, err error) {
 		deadl, ok := ctx.Deadline()
 		if !ok {
@@ -28,22 +47,25 @@ func NewTCPOutlet(formatter Formatter, network, address string,
 		} else {
 			conn, err = dialer.DialContext(ctx, network, address)
 		}
-		return conn, fmt.Errorf("daemon/logging: %w", err)
+		if err != nil {
+			return nil, fmt.Errorf("daemon/logging: %w", err)
+		}
+		return conn, nil
 	}
 
 	// allow one message in flight while previous is in io.Copy()
 	entryChan := make(chan *bytes.Buffer, 1)
-	o := &TCPOutlet{
-		formatter: formatter,
+	w := &connWriter{
 		connect:   connect,
 		entryChan: entryChan,
 	}
-	go o.outLoop(retryInterval)
-	return o
+	go w.outLoop(retryInterval)
+	return w
 }
 
-type TCPOutlet struct {
-	formatter Formatter
+// connWriter writes log entries to a network connection in background and
+// reconnects after errors.
+type connWriter struct {
 	// Specifies how much time must pass between a connection error and a
 	// reconnection attempt. Log entries written to the outlet during this time
 	// interval are silently dropped.
@@ -51,12 +73,9 @@ type TCPOutlet struct {
 	entryChan chan *bytes.Buffer
 }
 
-var _ slog.Handler = (*TCPOutlet)(nil)
-
-// FIXME: use this method
-func (h *TCPOutlet) Close() { close(h.entryChan) }
+func (h *connWriter) Close() { close(h.entryChan) }
 
-func (h *TCPOutlet) outLoop(retryInterval time.Duration) {
+func (h *connWriter) outLoop(retryInterval time.Duration) {
 	var retry time.Time
 	var conn net.Conn
 	for msg := range h.entryChan {
@@ -84,6 +103,15 @@ func (h *TCPOutlet) outLoop(retryInterval time.Duration) {
 	}
 }
 
+func (h *connWriter) Send(buf *bytes.Buffer) error {
+	select {
+	case h.entryChan <- buf:
+		return nil
+	default:
+		return errors.New("connection broken or not fast enough")
+	}
+}
+
 func (self *TCPOutlet) Enabled(ctx context.Context, level slog.Level) bool {
 	return self.formatter.Enabled(ctx, level)
 }
@@ -94,12 +122,7 @@ func (self *TCPOutlet) Handle(_ context.Context, r slog.Record) error {
 		return err
 	}
 
-	select {
-	case self.entryChan <- buf:
-		return nil
-	default:
-		return errors.New("connection broken or not fast enough")
-	}
+	return self.conn.Send(buf)
 }
 
 func (self *TCPOutlet) WithAttrs(attrs []slog.Attr) slog.Handler {
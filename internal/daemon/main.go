@@ -13,6 +13,6 @@ var DaemonCmd = &cli.Subcommand{
 	ConfigWithIncludes: true,
 
 	Run: func(ctx context.Context, subcommand *cli.Subcommand, args []string) error {
-		return Run(ctx, subcommand.Config())
+		return Run(ctx, subcommand.Config(), subcommand.ReloadConfig)
 	},
 }
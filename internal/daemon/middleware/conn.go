@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/qos"
+)
+
+type ctxKeyConn struct{}
+
+var connKey ctxKeyConn = struct{}{}
+
+// WithConn returns ctx with connection conn of requests. It's intended for
+// [http.Server.ConnContext].
+func WithConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey, conn)
+}
+
+func ConnFrom(ctx context.Context) net.Conn {
+	if ctx == nil {
+		return nil
+	}
+	if conn, ok := ctx.Value(connKey).(net.Conn); ok {
+		return conn
+	}
+	return nil
+}
+
+// MarkConn marks connection of requests with socket options of their job,
+// returned by options. The connection keeps marks for next requests, until
+// another job marks it.
+func MarkConn(options func(jobName string) qos.Options) Middleware {
+	fn := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if conn := ConnFrom(ctx); conn != nil {
+				err := options(JobNameFrom(ctx)).Apply(conn)
+				if err != nil {
+					logger.WithError(getLogger(r), err, "cannot mark connection")
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	return fn
+}
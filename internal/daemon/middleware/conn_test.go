@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/util/qos"
+)
+
+func TestMarkConn(t *testing.T) {
+	const testJobName = "test"
+	var marked string
+
+	mux := http.NewServeMux()
+	mux.Handle("/zfs/datasets/{job}", AppendHandler([]Middleware{
+		ExtractJobName("job", func(name string) bool {
+			return name == testJobName
+		}),
+		MarkConn(func(jobName string) qos.Options {
+			marked = jobName
+			return qos.Options{TOS: 0x48}
+		}),
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotNil(t, ConnFrom(r.Context()))
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	ts := httptest.NewUnstartedServer(mux)
+	ts.Config.ConnContext = WithConn
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/zfs/datasets/" + testJobName)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, testJobName, marked)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// StreamTimeouts fails streams, which don't progress: every read of request
+// body must complete in read and every write of response in write. Zero
+// durations mean no limit.
+func StreamTimeouts(read, write time.Duration) Middleware {
+	fn := func(next http.Handler) http.Handler {
+		if read <= 0 && write <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			if read > 0 {
+				r.Body = &deadlineBody{ReadCloser: r.Body, rc: rc, d: read}
+				defer func() { _ = rc.SetReadDeadline(time.Time{}) }()
+			}
+			if write > 0 {
+				w = &deadlineWriter{ResponseWriter: w, rc: rc, d: write}
+				defer func() { _ = rc.SetWriteDeadline(time.Time{}) }()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	return fn
+}
+
+type deadlineBody struct {
+	io.ReadCloser
+
+	rc *http.ResponseController
+	d  time.Duration
+}
+
+func (self *deadlineBody) Read(p []byte) (int, error) {
+	_ = self.rc.SetReadDeadline(time.Now().Add(self.d))
+	return self.ReadCloser.Read(p) //nolint:wrapcheck // not needed
+}
+
+type deadlineWriter struct {
+	http.ResponseWriter
+
+	rc *http.ResponseController
+	d  time.Duration
+}
+
+func (self *deadlineWriter) Write(p []byte) (int, error) {
+	_ = self.rc.SetWriteDeadline(time.Now().Add(self.d))
+	return self.ResponseWriter.Write(p) //nolint:wrapcheck // not needed
+}
+
+func (self *deadlineWriter) Unwrap() http.ResponseWriter {
+	return self.ResponseWriter
+}
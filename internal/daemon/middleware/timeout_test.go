@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTimeouts(t *testing.T) {
+	var readErr error
+	h := AppendHandler([]Middleware{
+		StreamTimeouts(50*time.Millisecond, 50*time.Millisecond),
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "text/plain", strings.NewReader("foobar"))
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.NoError(t, readErr)
+	assert.Equal(t, "ok", string(b))
+
+	// stalled request body
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() { _, _ = pw.Write([]byte("foo")) }()
+	resp, err = http.Post(ts.URL, "text/plain", pr)
+	if err == nil {
+		resp.Body.Close()
+	}
+	require.Error(t, readErr)
+	assert.ErrorContains(t, readErr, "timeout")
+}
+
+func TestStreamTimeouts_zero(t *testing.T) {
+	h := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	next := StreamTimeouts(0, 0)(h)
+	assert.NotNil(t, next)
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/daemon/notify"
+)
+
+// WithNotify configures notifications about finished runs of jobs.
+func (self *jobs) WithNotify(n *notify.Notifiers) *jobs {
+	self.notify = n
+	return self
+}
+
+// notifyRun sends summary of the job's run, started at begin and finished with
+// err. Only jobs, which run from cron or wakeup, have runs. Passive jobs serve
+// their clients until they stopped.
+func (self *jobs) notifyRun(ctx context.Context, j job.Job, begin time.Time,
+	err error,
+) {
+	if self.notify == nil {
+		return
+	}
+
+	st := j.Status()
+	switch st.Type {
+	case job.TypePush, job.TypePull:
+		self.notify.Watch(j.Name(), string(st.Type), begin)
+	case job.TypeSnap, job.TypePrune:
+	default:
+		return
+	}
+
+	switch {
+	case err != nil:
+	case ctx.Err() != nil:
+		err = context.Cause(ctx)
+	case self.graceful.Err() != nil:
+		err = context.Cause(self.graceful)
+	}
+	self.notify.Notify(ctx, runSummary(j.Name(), st, begin, err))
+}
+
+// runSummary returns summary of the job's run, using its status st. stopped
+// is the error, which stopped the run, if its status has no error.
+func runSummary(name string, st *job.Status, begin time.Time, stopped error,
+) *notify.Summary {
+	s := &notify.Summary{
+		Job:       name,
+		Type:      string(st.Type),
+		Error:     st.Error(),
+		StartedAt: begin,
+		Duration:  time.Since(begin),
+	}
+	if s.Error == "" && stopped != nil {
+		s.Error = stopped.Error()
+	}
+	s.Success = s.Error == ""
+
+	active, ok := st.JobSpecific.(*job.ActiveSideStatus)
+	if !ok || active.Replication == nil || len(active.Replication.Attempts) == 0 {
+		return s
+	}
+
+	attempts := active.Replication.Attempts
+	attempt := attempts[len(attempts)-1]
+	_, s.Filesystems = attempt.FilesystemsProgress()
+	_, s.Bytes, _ = attempt.BytesSum()
+	for _, fs := range attempt.Filesystems {
+		if err := fs.Error(); err != nil {
+			s.Errors = append(s.Errors, fs.Info.Name+": "+err.Err)
+		}
+	}
+	return s
+}
+
+// watchNotify starts watching replication jobs for stale successful runs and
+// sending digests, if any notification wants it.
+func (self *jobs) watchNotify(confJobs []job.Job) {
+	hasStale, hasDigest := self.notify.HasStale(), self.notify.HasDigest()
+	if !hasStale && !hasDigest {
+		return
+	}
+
+	if hasStale {
+		now := time.Now()
+		for _, j := range confJobs {
+			st := j.Status()
+			if st == nil {
+				continue
+			}
+			switch t := st.Type; t {
+			case job.TypePush, job.TypePull:
+				self.notify.Watch(j.Name(), string(t), now)
+			}
+		}
+	}
+	self.startInternal(&notifyWatcher{notify: self.notify, interval: time.Minute})
+}
+
+// notifyWatcher is an internal job, which periodically notifies about
+// replication jobs without successful runs for too long, and sends digests.
+type notifyWatcher struct {
+	notify   *notify.Notifiers
+	interval time.Duration
+}
+
+var _ job.Internal = (*notifyWatcher)(nil)
+
+func (self *notifyWatcher) RegisterMetrics(prometheus.Registerer) {}
+
+func (self *notifyWatcher) Run(ctx context.Context) error {
+	logging.GetLogger(ctx, logging.SubsysJob).With(
+		slog.Duration("interval", self.interval)).Info("start notify watcher")
+
+	t := time.NewTicker(self.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-t.C:
+			self.notify.CheckStale(ctx, now)
+			self.notify.SendDigests(ctx, now)
+		}
+	}
+}
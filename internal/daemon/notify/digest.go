@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/dsh2dsh/cron/v3"
+)
+
+// newDigest returns [digest], which sends batched runs by cron spec.
+func newDigest(spec string, now time.Time) (*digest, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("digest %q: %w", spec, err)
+	}
+	return &digest{
+		schedule: schedule,
+		next:     schedule.Next(now),
+		runs:     make(map[string][]*Summary),
+	}, nil
+}
+
+// digest batches summaries of runs per job, until its schedule fires.
+type digest struct {
+	schedule cron.Schedule
+	next     time.Time
+	runs     map[string][]*Summary // by job name
+}
+
+func (self *digest) add(s *Summary) {
+	self.runs[s.Job] = append(self.runs[s.Job], s)
+}
+
+// flush returns digests of all batched jobs, sorted by job name, if it's time
+// to send them, and starts new batch. Digests without failed runs are returned
+// only if onSuccess.
+func (self *digest) flush(now time.Time, onSuccess bool) []*Summary {
+	if now.Before(self.next) {
+		return nil
+	}
+	self.next = self.schedule.Next(now)
+
+	var digests []*Summary
+	for _, name := range slices.Sorted(maps.Keys(self.runs)) {
+		if d := newDigestSummary(self.runs[name]); !d.Success || onSuccess {
+			digests = append(digests, d)
+		}
+	}
+	clear(self.runs)
+	return digests
+}
+
+// newDigestSummary returns digest of runs of the same job. Filesystems, Bytes
+// and Duration are sums of all runs, and Errors are unique errors of failed
+// runs.
+func newDigestSummary(runs []*Summary) *Summary {
+	first := runs[0]
+	s := &Summary{
+		Job:       first.Job,
+		Type:      first.Type,
+		Digest:    true,
+		Runs:      runs,
+		StartedAt: first.StartedAt,
+	}
+
+	seen := make(map[string]struct{})
+	addError := func(err string) {
+		if _, ok := seen[err]; !ok {
+			seen[err] = struct{}{}
+			s.Errors = append(s.Errors, err)
+		}
+	}
+
+	for _, r := range runs {
+		s.Filesystems += r.Filesystems
+		s.Bytes += r.Bytes
+		s.Duration += r.Duration
+		if r.Success {
+			continue
+		} else if r.Error != "" {
+			addError(r.Error)
+		}
+		for _, err := range r.Errors {
+			addError(err)
+		}
+	}
+
+	if failed := s.Failed(); failed > 0 {
+		s.Error = fmt.Sprintf("%d of %d runs failed", failed, len(runs))
+	} else {
+		s.Success = true
+	}
+	return s
+}
+
+// HasDigest returns true, if any notifier batches runs into digests.
+func (self *Notifiers) HasDigest() bool {
+	if self == nil {
+		return false
+	}
+	for i := range self.items {
+		if self.items[i].digest != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SendDigests sends batched runs of every notifier, which digest schedule
+// fired at now.
+func (self *Notifiers) SendDigests(ctx context.Context, now time.Time) {
+	if self == nil {
+		return
+	}
+
+	digests := make([][]*Summary, len(self.items))
+	self.mu.Lock()
+	for i := range self.items {
+		n := &self.items[i]
+		if n.digest != nil {
+			digests[i] = n.digest.flush(now, n.onSuccess)
+		}
+	}
+	self.mu.Unlock()
+
+	for i, summaries := range digests {
+		for _, s := range summaries {
+			self.items[i].send(ctx, s)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestNotifiers_SendDigests(t *testing.T) {
+	srv, reqs, bodies := newTestServer(t, http.StatusOK)
+	n, err := FromConfig([]config.NotificationEnum{
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{
+				Type:   "webhook",
+				Digest: "0 8 * * *",
+			},
+			URL: srv.URL,
+			Template: `{{ .Job }} {{ .Digest }} {{ len .Runs }} {{ .Failed }} ` +
+				`{{ .Bytes }} {{ .Error }} {{ .Errors }}`,
+			Timeout: time.Second,
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, n.HasDigest())
+	assert.False(t, n.HasStale())
+
+	ctx := context.Background()
+	start := time.Now()
+	n.Notify(ctx, &Summary{Job: "foo", Success: true, Bytes: 1, StartedAt: start})
+	n.Notify(ctx, &Summary{
+		Job: "foo", Error: "failed", Errors: []string{"zroot/a: failed"},
+		Bytes: 2, StartedAt: start,
+	})
+	n.Notify(ctx, &Summary{
+		Job: "foo", Error: "failed", Bytes: 4, StartedAt: start,
+	})
+	n.Notify(ctx, &Summary{Job: "bar", Success: true, StartedAt: start})
+	assert.Empty(t, *reqs, "runs must be batched")
+
+	n.SendDigests(ctx, start)
+	assert.Empty(t, *reqs, "too early")
+
+	n.SendDigests(ctx, start.Add(25*time.Hour))
+	require.Len(t, *reqs, 1, "successful digest of bar must be skipped")
+	assert.Equal(t, "foo true 3 2 7 2 of 3 runs failed [failed zroot/a: failed]",
+		(*bodies)[0])
+
+	n.SendDigests(ctx, start.Add(50*time.Hour))
+	assert.Len(t, *reqs, 1, "batch must be empty after sending")
+}
+
+func TestNotifiers_invalidDigest(t *testing.T) {
+	_, err := FromConfig([]config.NotificationEnum{
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{
+				Type:   "webhook",
+				Digest: "daily",
+			},
+			URL:     "http://localhost",
+			Timeout: time.Second,
+		}},
+	})
+	require.Error(t, err)
+}
+
+func TestTextMessage_digest(t *testing.T) {
+	m, err := newTextMessage("", "")
+	require.NoError(t, err)
+
+	runs := []*Summary{testSummary(), {Job: "zroot-to-backup", Success: true}}
+	title, body, err := m.render(newDigestSummary(runs))
+	require.NoError(t, err)
+	assert.Equal(t, "zrepl: job zroot-to-backup digest: 2 runs, 1 failed", title)
+	assert.Contains(t, body, "Runs:        2 (1 failed)")
+	assert.Contains(t, body, "Error: 1 of 2 runs failed")
+}
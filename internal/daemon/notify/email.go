@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+// NewEmail returns [Email], configured by in.
+func NewEmail(in *config.EmailNotification) (*Email, error) {
+	msg, err := newTextMessage(in.Subject, in.Template)
+	if err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+
+	e := &Email{
+		host:     in.Host,
+		tls:      in.TLS,
+		username: in.Username,
+		password: in.Password,
+		from:     in.From,
+		to:       in.To,
+		msg:      msg,
+		timeout:  in.Timeout,
+	}
+	e.send = e.sendMail
+	return e, nil
+}
+
+// Email mails summaries of job runs through a SMTP server.
+type Email struct {
+	host     string
+	tls      bool
+	username string
+	password string
+	from     string
+	to       []string
+	msg      *textMessage
+	timeout  time.Duration
+
+	send func(ctx context.Context, msg []byte) error
+}
+
+func (self *Email) Notify(ctx context.Context, s *Summary) error {
+	msg, err := self.message(s)
+	if err != nil {
+		return err
+	}
+
+	// the job can be stopped already, but the email must be sent
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), self.timeout)
+	defer cancel()
+	return self.send(ctx, msg)
+}
+
+func (self *Email) message(s *Summary) ([]byte, error) {
+	subject, body, err := self.msg.render(s)
+	if err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", self.from)
+	header("To", strings.Join(self.to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+
+	for line := range strings.Lines(body) {
+		b.WriteString(strings.TrimRight(line, "\r\n"))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes(), nil
+}
+
+func (self *Email) sendMail(ctx context.Context, msg []byte) error {
+	c, err := self.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := self.auth(c); err != nil {
+		return err
+	}
+
+	if err := c.Mail(self.from); err != nil {
+		return fmt.Errorf("email: MAIL FROM: %w", err)
+	}
+	for _, to := range self.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("email: RCPT TO %q: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("email: DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("email: write message: %w", err)
+	} else if err := w.Close(); err != nil {
+		return fmt.Errorf("email: finish message: %w", err)
+	}
+
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("email: QUIT: %w", err)
+	}
+	return nil
+}
+
+func (self *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	serverName, _, err := net.SplitHostPort(self.host)
+	if err != nil {
+		return nil, fmt.Errorf("email: parse host %q: %w", self.host, err)
+	}
+
+	var conn net.Conn
+	if self.tls {
+		d := tls.Dialer{Config: &tls.Config{ServerName: serverName}}
+		conn, err = d.DialContext(ctx, "tcp", self.host)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", self.host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("email: connect to %q: %w", self.host, err)
+	}
+
+	// net/smtp knows nothing about contexts
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, serverName)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("email: new client: %w", err)
+	}
+
+	if !self.tls {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			err := c.StartTLS(&tls.Config{ServerName: serverName})
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("email: STARTTLS: %w", err)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (self *Email) auth(c *smtp.Client) error {
+	if self.username == "" {
+		return nil
+	}
+
+	serverName, _, _ := net.SplitHostPort(self.host)
+	auth := smtp.PlainAuth("", self.username, self.password, serverName)
+	if err := c.Auth(auth); err != nil {
+		return fmt.Errorf("email: AUTH: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func newTestEmail(t *testing.T, in *config.EmailNotification) (*Email,
+	*[]string,
+) {
+	in.Host = "smtp.example.com:25"
+	in.From = "zrepl@example.com"
+	in.To = []string{"admin@example.com", "ops@example.com"}
+	in.Timeout = time.Second
+
+	e, err := NewEmail(in)
+	require.NoError(t, err)
+
+	var sent []string
+	e.send = func(ctx context.Context, msg []byte) error {
+		sent = append(sent, string(msg))
+		return nil
+	}
+	return e, &sent
+}
+
+func TestEmail_default(t *testing.T) {
+	e, sent := newTestEmail(t, &config.EmailNotification{})
+	require.NoError(t, e.Notify(t.Context(), testSummary()))
+	require.Len(t, *sent, 1)
+
+	header, body, ok := strings.Cut((*sent)[0], "\r\n\r\n")
+	require.True(t, ok)
+	assert.Contains(t, header, "From: zrepl@example.com\r\n")
+	assert.Contains(t, header, "To: admin@example.com, ops@example.com\r\n")
+	assert.Contains(t, header, "Subject: zrepl: job zroot-to-backup failed\r\n")
+	assert.Contains(t, body, "Job:         zroot-to-backup (push)\r\n")
+	assert.Contains(t, body, "Duration:    1m30s\r\n")
+	assert.Contains(t, body, "Error: \"quoted\" failure\r\n")
+	assert.Contains(t, body, "Filesystem errors:\r\n  zroot/foo: failed\r\n")
+	assert.NotContains(t, strings.ReplaceAll(body, "\r\n", ""), "\n")
+}
+
+func TestEmail_template(t *testing.T) {
+	e, sent := newTestEmail(t, &config.EmailNotification{
+		Subject:  "[backup] {{ .Job }}",
+		Template: "{{ .Job }}: {{ .Error }}",
+	})
+	require.NoError(t, e.Notify(t.Context(), testSummary()))
+	require.Len(t, *sent, 1)
+
+	header, body, ok := strings.Cut((*sent)[0], "\r\n\r\n")
+	require.True(t, ok)
+	assert.Contains(t, header, "Subject: [backup] zroot-to-backup\r\n")
+	assert.Equal(t, "zroot-to-backup: \"quoted\" failure\r\n", body)
+
+	_, err := NewEmail(&config.EmailNotification{Subject: "{{ .Job"})
+	require.Error(t, err)
+	_, err = NewEmail(&config.EmailNotification{Template: "{{ .Job"})
+	require.Error(t, err)
+}
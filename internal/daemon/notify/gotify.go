@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/httppost"
+)
+
+// NewGotify returns [Gotify], configured by in.
+func NewGotify(in *config.GotifyNotification) (*Gotify, error) {
+	msg, err := newTextMessage(in.Title, in.Template)
+	if err != nil {
+		return nil, fmt.Errorf("gotify: %w", err)
+	}
+
+	u, err := url.JoinPath(in.URL, "message")
+	if err != nil {
+		return nil, fmt.Errorf("gotify: build url from %q: %w", in.URL, err)
+	}
+
+	return &Gotify{
+		url:      u,
+		token:    in.Token,
+		priority: in.Priority,
+		msg:      msg,
+		timeout:  in.Timeout,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// Gotify sends summaries of job runs as messages of a Gotify application.
+type Gotify struct {
+	url      string
+	token    string
+	priority int
+	msg      *textMessage
+	timeout  time.Duration
+	client   *http.Client
+}
+
+func (self *Gotify) Notify(ctx context.Context, s *Summary) error {
+	title, message, err := self.msg.render(s)
+	if err != nil {
+		return fmt.Errorf("gotify: %w", err)
+	}
+
+	body, err := json.Marshal(struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}{title, message, self.priority})
+	if err != nil {
+		return fmt.Errorf("gotify: marshal message: %w", err)
+	}
+
+	err = httppost.Post(ctx, self.client, self.timeout, self.url, body,
+		map[string]string{
+			"Content-Type": "application/json",
+			"X-Gotify-Key": self.token,
+		})
+	if err != nil {
+		return fmt.Errorf("gotify: %w", err)
+	}
+	return nil
+}
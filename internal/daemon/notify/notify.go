@@ -0,0 +1,228 @@
+// Package notify sends summaries of job runs to external services, like
+// webhooks of Slack or Mattermost, push notifications of ntfy or Gotify, or by
+// email.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/jsonschema"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+)
+
+// Summary of a job run.
+type Summary struct {
+	Job     string `json:"job"`
+	Type    string `json:"type"`
+	Success bool   `json:"success"`
+	// Stale means the job has no successful runs for too long. It isn't a
+	// summary of a run.
+	Stale bool `json:"stale,omitempty"`
+	// Digest means it's a summary of batched Runs.
+	Digest bool       `json:"digest,omitempty"`
+	Runs   []*Summary `json:"runs,omitempty"`
+	// Error is the error of the run, if it failed.
+	Error string `json:"error,omitempty"`
+	// Errors are errors of filesystems.
+	Errors []string `json:"errors,omitempty"`
+
+	// Filesystems is number of replicated filesystems.
+	Filesystems int `json:"filesystems"`
+	// Bytes is number of replicated bytes.
+	Bytes uint64 `json:"bytes"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"-"`
+}
+
+var _ json.Marshaler = (*Summary)(nil)
+
+// MarshalJSON marshals Duration as seconds, because nanoseconds are useless
+// for receivers.
+func (self *Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	b, err := json.Marshal(struct {
+		*summary
+		Duration float64 `json:"duration_seconds"`
+	}{(*summary)(self), self.Duration.Seconds()})
+	if err != nil {
+		return nil, fmt.Errorf("marshal summary: %w", err)
+	}
+	return b, nil
+}
+
+var _ jsonschema.Extender = (*Summary)(nil)
+
+// JSONSchemaExtend adds duration_seconds, added by MarshalJSON.
+func (self *Summary) JSONSchemaExtend(s *jsonschema.Schema) {
+	s.Properties["duration_seconds"] = &jsonschema.Schema{Type: "number"}
+	s.Required = append(s.Required, "duration_seconds")
+}
+
+// Failed returns number of failed Runs of a digest.
+func (self *Summary) Failed() (n int) {
+	for _, r := range self.Runs {
+		if !r.Success {
+			n++
+		}
+	}
+	return n
+}
+
+var templateFuncs = template.FuncMap{
+	// json returns v as JSON, so strings can be safely embedded into JSON
+	// payloads.
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("json: %w", err)
+		}
+		return string(b), nil
+	},
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, s *Summary) error
+}
+
+// FromConfig returns all configured notifiers or nil, if nothing configured.
+func FromConfig(in []config.NotificationEnum) (*Notifiers, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	n := &Notifiers{
+		items:       make([]notifier, len(in)),
+		jobs:        make(map[string]*watchedJob),
+		staleNotify: make(map[staleKey]struct{}),
+	}
+	for i := range in {
+		item, err := notifierFromConfig(&in[i])
+		if err != nil {
+			return nil, fmt.Errorf("notification #%d: %w", i, err)
+		}
+		if spec := commonConfig(&in[i]).Digest; spec != "" {
+			if item.digest, err = newDigest(spec, time.Now()); err != nil {
+				return nil, fmt.Errorf("notification #%d: %w", i, err)
+			}
+		}
+		n.items[i] = item
+	}
+	return n, nil
+}
+
+func notifierFromConfig(in *config.NotificationEnum) (notifier, error) {
+	switch v := in.Ret.(type) {
+	case *config.EmailNotification:
+		n, err := NewEmail(v)
+		if err != nil {
+			return notifier{}, err
+		}
+		return newNotifier(n, &v.NotificationCommon), nil
+	case *config.GotifyNotification:
+		n, err := NewGotify(v)
+		if err != nil {
+			return notifier{}, err
+		}
+		return newNotifier(n, &v.NotificationCommon), nil
+	case *config.NtfyNotification:
+		n, err := NewNtfy(v)
+		if err != nil {
+			return notifier{}, err
+		}
+		return newNotifier(n, &v.NotificationCommon), nil
+	case *config.WebhookNotification:
+		n, err := NewWebhook(v)
+		if err != nil {
+			return notifier{}, err
+		}
+		return newNotifier(n, &v.NotificationCommon), nil
+	default:
+		return notifier{}, fmt.Errorf("unknown notification type: %T", v)
+	}
+}
+
+func commonConfig(in *config.NotificationEnum) *config.NotificationCommon {
+	switch v := in.Ret.(type) {
+	case *config.EmailNotification:
+		return &v.NotificationCommon
+	case *config.GotifyNotification:
+		return &v.NotificationCommon
+	case *config.NtfyNotification:
+		return &v.NotificationCommon
+	case *config.WebhookNotification:
+		return &v.NotificationCommon
+	}
+	return &config.NotificationCommon{}
+}
+
+func newNotifier(n Notifier, in *config.NotificationCommon) notifier {
+	return notifier{
+		Notifier:   n,
+		name:       in.Type,
+		onSuccess:  in.OnSuccess,
+		staleAfter: in.StaleAfter,
+	}
+}
+
+type notifier struct {
+	Notifier
+
+	name       string
+	onSuccess  bool
+	staleAfter time.Duration
+	digest     *digest // nil, if runs are sent immediately
+}
+
+// Notifiers sends summaries to all configured notifiers.
+type Notifiers struct {
+	items []notifier
+
+	mu   sync.Mutex
+	jobs map[string]*watchedJob
+	// staleNotify contains stale jobs, already notified by a notifier.
+	staleNotify map[staleKey]struct{}
+}
+
+// Notify sends s to every notifier, which wants it. Errors are logged, because
+// the job already finished and nothing can be done.
+func (self *Notifiers) Notify(ctx context.Context, s *Summary) {
+	if self == nil {
+		return
+	}
+
+	if s.Success {
+		self.succeeded(s.Job, s.StartedAt.Add(s.Duration))
+	}
+
+	for i := range self.items {
+		n := &self.items[i]
+		if n.digest != nil {
+			self.mu.Lock()
+			n.digest.add(s)
+			self.mu.Unlock()
+			continue
+		} else if s.Success && !n.onSuccess {
+			continue
+		}
+		n.send(ctx, s)
+	}
+}
+
+func (self *notifier) send(ctx context.Context, s *Summary) {
+	l := logging.GetLogger(ctx, logging.SubsysJob).With(
+		slog.String("notification", self.name))
+	if err := self.Notify(ctx, s); err != nil {
+		logger.WithError(l, err, "failed send notification")
+		return
+	}
+	l.Debug("notification sent")
+}
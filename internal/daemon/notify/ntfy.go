@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/httppost"
+)
+
+// NewNtfy returns [Ntfy], configured by in.
+func NewNtfy(in *config.NtfyNotification) (*Ntfy, error) {
+	msg, err := newTextMessage(in.Title, in.Template)
+	if err != nil {
+		return nil, fmt.Errorf("ntfy: %w", err)
+	}
+
+	return &Ntfy{
+		url:      in.URL,
+		token:    in.Token,
+		priority: in.Priority,
+		tags:     strings.Join(in.Tags, ","),
+		msg:      msg,
+		timeout:  in.Timeout,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// Ntfy publishes summaries of job runs to a ntfy topic.
+type Ntfy struct {
+	url      string
+	token    string
+	priority int
+	tags     string
+	msg      *textMessage
+	timeout  time.Duration
+	client   *http.Client
+}
+
+func (self *Ntfy) Notify(ctx context.Context, s *Summary) error {
+	title, body, err := self.msg.render(s)
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "text/plain; charset=utf-8",
+		"Title":        mime.QEncoding.Encode("utf-8", title),
+	}
+	if self.priority > 0 {
+		headers["Priority"] = strconv.Itoa(self.priority)
+	}
+	if self.tags != "" {
+		headers["Tags"] = self.tags
+	}
+	if self.token != "" {
+		headers["Authorization"] = "Bearer " + self.token
+	}
+
+	err = httppost.Post(ctx, self.client, self.timeout, self.url, []byte(body),
+		headers)
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestNtfy(t *testing.T) {
+	srv, reqs, bodies := newTestServer(t, http.StatusOK)
+	n, err := NewNtfy(&config.NtfyNotification{
+		URL:      srv.URL + "/zrepl",
+		Token:    "secret",
+		Priority: 4,
+		Tags:     []string{"warning", "backup"},
+		Template: "{{ .Job }}: {{ .Error }}",
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(t.Context(), testSummary()))
+
+	require.Len(t, *reqs, 1)
+	r := (*reqs)[0]
+	assert.Equal(t, "/zrepl", r.URL.Path)
+	assert.Equal(t, "zrepl: job zroot-to-backup failed", r.Header.Get("Title"))
+	assert.Equal(t, "4", r.Header.Get("Priority"))
+	assert.Equal(t, "warning,backup", r.Header.Get("Tags"))
+	assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+	assert.Equal(t, `zroot-to-backup: "quoted" failure`, (*bodies)[0])
+}
+
+func TestGotify(t *testing.T) {
+	srv, reqs, bodies := newTestServer(t, http.StatusOK)
+	n, err := NewGotify(&config.GotifyNotification{
+		URL:      srv.URL,
+		Token:    "secret",
+		Priority: 8,
+		Title:    "backup {{ .Job }}",
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(t.Context(), testSummary()))
+
+	require.Len(t, *reqs, 1)
+	r := (*reqs)[0]
+	assert.Equal(t, "/message", r.URL.Path)
+	assert.Equal(t, "secret", r.Header.Get("X-Gotify-Key"))
+
+	var msg struct {
+		Title    string
+		Message  string
+		Priority int
+	}
+	require.NoError(t, json.Unmarshal([]byte((*bodies)[0]), &msg))
+	assert.Equal(t, "backup zroot-to-backup", msg.Title)
+	assert.Contains(t, msg.Message, "Result:      failed\n")
+	assert.Equal(t, 8, msg.Priority)
+}
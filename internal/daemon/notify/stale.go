@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type watchedJob struct {
+	typ         string
+	lastSuccess time.Time
+}
+
+type staleKey struct {
+	job      string
+	notifier int
+}
+
+// HasStale returns true, if any notifier wants notifications about stale jobs.
+func (self *Notifiers) HasStale() bool {
+	if self == nil {
+		return false
+	}
+	for i := range self.items {
+		if self.items[i].staleAfter > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch starts watching job for stale successful runs. Until the first
+// successful run, the job is considered successful at since. Already watched
+// jobs are not changed.
+func (self *Notifiers) Watch(job, typ string, since time.Time) {
+	if self == nil {
+		return
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if _, ok := self.jobs[job]; !ok {
+		self.jobs[job] = &watchedJob{typ: typ, lastSuccess: since}
+	}
+}
+
+// Forget stops watching job, like removed by config reload.
+func (self *Notifiers) Forget(job string) {
+	if self == nil {
+		return
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	delete(self.jobs, job)
+	for k := range self.staleNotify {
+		if k.job == job {
+			delete(self.staleNotify, k)
+		}
+	}
+}
+
+func (self *Notifiers) succeeded(job string, at time.Time) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	j, ok := self.jobs[job]
+	if !ok {
+		return
+	}
+	j.lastSuccess = at
+	for i := range self.items {
+		delete(self.staleNotify, staleKey{job: job, notifier: i})
+	}
+}
+
+// CheckStale notifies about watched jobs, which have no successful runs longer
+// than stale_after of a notifier. Every notifier notifies once, until the job
+// succeeds again.
+func (self *Notifiers) CheckStale(ctx context.Context, now time.Time) {
+	if self == nil {
+		return
+	}
+
+	type staleJob struct {
+		notifier int
+		summary  *Summary
+	}
+	var stale []staleJob
+
+	self.mu.Lock()
+	for name, j := range self.jobs {
+		for i := range self.items {
+			n := &self.items[i]
+			key := staleKey{job: name, notifier: i}
+			if n.staleAfter <= 0 || now.Sub(j.lastSuccess) < n.staleAfter {
+				continue
+			} else if _, ok := self.staleNotify[key]; ok {
+				continue
+			}
+			self.staleNotify[key] = struct{}{}
+			stale = append(stale, staleJob{
+				notifier: i,
+				summary: &Summary{
+					Job:   name,
+					Type:  j.typ,
+					Stale: true,
+					Error: fmt.Sprintf(
+						"no successful replication since %s (%s)",
+						j.lastSuccess.Format(time.RFC3339),
+						now.Sub(j.lastSuccess).Truncate(time.Second)),
+				},
+			})
+		}
+	}
+	self.mu.Unlock()
+
+	for _, s := range stale {
+		self.items[s.notifier].send(ctx, s.summary)
+	}
+}
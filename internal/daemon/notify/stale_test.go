@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestNotifiers_CheckStale(t *testing.T) {
+	srv, reqs, bodies := newTestServer(t, http.StatusOK)
+	n, err := FromConfig([]config.NotificationEnum{
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{
+				Type:       "webhook",
+				StaleAfter: time.Hour,
+			},
+			URL:      srv.URL,
+			Template: "{{ .Job }} {{ .Stale }}",
+			Timeout:  time.Second,
+		}},
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{Type: "webhook"},
+			URL:                srv.URL,
+			Timeout:            time.Second,
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, n.HasStale())
+
+	ctx := context.Background()
+	start := time.Now()
+	n.Watch("foo", "push", start)
+	n.CheckStale(ctx, start.Add(time.Hour-time.Second))
+	assert.Empty(t, *reqs)
+
+	n.CheckStale(ctx, start.Add(time.Hour))
+	require.Len(t, *reqs, 1)
+	assert.Equal(t, "foo true", (*bodies)[0])
+
+	// notified once
+	n.CheckStale(ctx, start.Add(2*time.Hour))
+	assert.Len(t, *reqs, 1)
+
+	// success resets it
+	n.Notify(ctx, &Summary{
+		Job: "foo", Success: true, StartedAt: start.Add(2 * time.Hour),
+	})
+	assert.Len(t, *reqs, 1)
+	n.CheckStale(ctx, start.Add(3*time.Hour-time.Second))
+	assert.Len(t, *reqs, 1)
+	n.CheckStale(ctx, start.Add(3*time.Hour))
+	assert.Len(t, *reqs, 2)
+
+	n.Forget("foo")
+	n.CheckStale(ctx, start.Add(5*time.Hour))
+	assert.Len(t, *reqs, 2)
+}
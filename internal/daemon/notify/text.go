@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultTitle = `zrepl: job {{ .Job }} ` +
+		`{{ if .Digest }}digest: {{ len .Runs }} runs, {{ .Failed }} failed` +
+		`{{ else if .Stale }}stale{{ else if .Success }}succeeded` +
+		`{{ else }}failed{{ end }}`
+
+	defaultMessage = `Job:         {{ .Job }} ({{ .Type }})
+Result:      {{ if .Stale }}stale{{ else if .Success }}succeeded{{ else }}failed{{ end }}
+{{- if .Digest }}
+Runs:        {{ len .Runs }} ({{ .Failed }} failed)
+{{- end }}
+{{- if not .Stale }}
+Started at:  {{ .StartedAt.Format "2006-01-02 15:04:05 MST" }}
+Duration:    {{ .Duration }}
+Filesystems: {{ .Filesystems }}
+Bytes:       {{ .Bytes }}
+{{- end }}
+{{- with .Error }}
+
+Error: {{ . }}
+{{- end }}
+{{- with .Errors }}
+
+Filesystem errors:
+{{- range . }}
+  {{ . }}
+{{- end }}
+{{- end }}
+`
+)
+
+// newTextMessage returns [textMessage] with title and body templates. Empty
+// templates are replaced by default ones.
+func newTextMessage(title, body string) (*textMessage, error) {
+	if title == "" {
+		title = defaultTitle
+	}
+	titleTmpl, err := template.New("title").Funcs(templateFuncs).Parse(title)
+	if err != nil {
+		return nil, fmt.Errorf("parse title template: %w", err)
+	}
+
+	if body == "" {
+		body = defaultMessage
+	}
+	bodyTmpl, err := template.New("message").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse message template: %w", err)
+	}
+	return &textMessage{title: titleTmpl, body: bodyTmpl}, nil
+}
+
+// textMessage renders human readable title and body of a summary, for
+// notifiers like email or push notifications.
+type textMessage struct {
+	title *template.Template
+	body  *template.Template
+}
+
+func (self *textMessage) render(s *Summary) (title, body string, err error) {
+	var b strings.Builder
+	if err := self.title.Execute(&b, s); err != nil {
+		return "", "", fmt.Errorf("execute title template: %w", err)
+	}
+	title = strings.TrimSpace(b.String())
+
+	var buf bytes.Buffer
+	if err := self.body.Execute(&buf, s); err != nil {
+		return "", "", fmt.Errorf("execute message template: %w", err)
+	}
+	return title, buf.String(), nil
+}
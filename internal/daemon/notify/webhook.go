@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/httppost"
+)
+
+// NewWebhook returns [Webhook], configured by in.
+func NewWebhook(in *config.WebhookNotification) (*Webhook, error) {
+	w := &Webhook{
+		url:     in.URL,
+		headers: in.Headers,
+		timeout: in.Timeout,
+		client:  http.DefaultClient,
+	}
+
+	if in.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(templateFuncs).
+			Parse(in.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse webhook template: %w", err)
+		}
+		w.template = tmpl
+	}
+	return w, nil
+}
+
+// Webhook posts summaries of job runs to a URL, as JSON or using a template.
+type Webhook struct {
+	url      string
+	headers  map[string]string
+	template *template.Template
+	timeout  time.Duration
+	client   *http.Client
+}
+
+func (self *Webhook) Notify(ctx context.Context, s *Summary) error {
+	body, err := self.body(s)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(self.headers)+1)
+	headers["Content-Type"] = "application/json"
+	maps.Copy(headers, self.headers)
+
+	err = httppost.Post(ctx, self.client, self.timeout, self.url, body, headers)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+func (self *Webhook) body(s *Summary) ([]byte, error) {
+	if self.template == nil {
+		b, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: marshal summary: %w", err)
+		}
+		return b, nil
+	}
+
+	var b bytes.Buffer
+	if err := self.template.Execute(&b, s); err != nil {
+		return nil, fmt.Errorf("webhook: execute template: %w", err)
+	}
+	return b.Bytes(), nil
+}
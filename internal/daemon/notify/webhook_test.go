@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func newTestServer(t *testing.T, status int) (*httptest.Server, *[]*http.Request,
+	*[]string,
+) {
+	var reqs []*http.Request
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			reqs = append(reqs, r)
+			bodies = append(bodies, string(b))
+			w.WriteHeader(status)
+		}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs, &bodies
+}
+
+func testSummary() *Summary {
+	return &Summary{
+		Job:         "zroot-to-backup",
+		Type:        "push",
+		Error:       `"quoted" failure`,
+		Errors:      []string{"zroot/foo: failed"},
+		Filesystems: 2,
+		Bytes:       1024,
+		StartedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:    90 * time.Second,
+	}
+}
+
+func TestWebhook_json(t *testing.T) {
+	srv, reqs, bodies := newTestServer(t, http.StatusOK)
+	w, err := NewWebhook(&config.WebhookNotification{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Notify(t.Context(), testSummary()))
+
+	require.Len(t, *reqs, 1)
+	r := (*reqs)[0]
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte((*bodies)[0]), &got))
+	assert.Equal(t, map[string]any{
+		"job":              "zroot-to-backup",
+		"type":             "push",
+		"success":          false,
+		"error":            `"quoted" failure`,
+		"errors":           []any{"zroot/foo: failed"},
+		"filesystems":      float64(2),
+		"bytes":            float64(1024),
+		"started_at":       "2026-01-02T03:04:05Z",
+		"duration_seconds": float64(90),
+	}, got)
+}
+
+func TestWebhook_template(t *testing.T) {
+	srv, _, bodies := newTestServer(t, http.StatusOK)
+	w, err := NewWebhook(&config.WebhookNotification{
+		URL:      srv.URL,
+		Template: `{"text": {{ json (printf "%s: %s" .Job .Error) }}}`,
+		Timeout:  time.Second,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Notify(t.Context(), testSummary()))
+	require.Len(t, *bodies, 1)
+	assert.JSONEq(t, `{"text": "zroot-to-backup: \"quoted\" failure"}`,
+		(*bodies)[0])
+
+	_, err = NewWebhook(&config.WebhookNotification{Template: "{{ .Job"})
+	require.Error(t, err)
+}
+
+func TestWebhook_errorStatus(t *testing.T) {
+	srv, _, _ := newTestServer(t, http.StatusInternalServerError)
+	w, err := NewWebhook(&config.WebhookNotification{
+		URL:     srv.URL,
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	require.Error(t, w.Notify(t.Context(), testSummary()))
+}
+
+func TestNotifiers_onSuccess(t *testing.T) {
+	srv, reqs, _ := newTestServer(t, http.StatusOK)
+	n, err := FromConfig([]config.NotificationEnum{
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{Type: "webhook"},
+			URL:                srv.URL,
+			Timeout:            time.Second,
+		}},
+		{Ret: &config.WebhookNotification{
+			NotificationCommon: config.NotificationCommon{
+				Type:      "webhook",
+				OnSuccess: true,
+			},
+			URL:     srv.URL,
+			Timeout: time.Second,
+		}},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	n.Notify(ctx, &Summary{Job: "foo", Success: true})
+	assert.Len(t, *reqs, 1)
+	n.Notify(ctx, &Summary{Job: "foo", Error: "failed"})
+	assert.Len(t, *reqs, 3)
+
+	n, err = FromConfig(nil)
+	require.NoError(t, err)
+	assert.Nil(t, n)
+	n.Notify(ctx, &Summary{Job: "foo"})
+}
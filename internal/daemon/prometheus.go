@@ -18,7 +18,10 @@ import (
 
 const endpointMetrics = "/metrics"
 
-var metricLogEntries *prometheus.CounterVec
+var (
+	metricLogEntries  *prometheus.CounterVec
+	metricJobOverlaps *prometheus.CounterVec
+)
 
 func init() {
 	metricLogEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -27,6 +30,13 @@ func init() {
 		Name:      "log_entries",
 		Help:      "number of log entries per job task and level",
 	}, []string{"zrepl_job", "level"})
+
+	metricJobOverlaps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "zrepl",
+		Subsystem: "daemon",
+		Name:      "job_overlaps",
+		Help:      "number of cron triggers arrived while the job was still running",
+	}, []string{"zrepl_job", "overlap"})
 }
 
 func mustRegisterMetrics(registerer prometheus.Registerer) {
@@ -36,6 +46,7 @@ func mustRegisterMetrics(registerer prometheus.Registerer) {
 	endpoint.RegisterMetrics(registerer)
 
 	registerer.MustRegister(metricLogEntries)
+	registerer.MustRegister(metricJobOverlaps)
 	if err := zfs.PrometheusRegister(registerer); err != nil {
 		panic(err)
 	}
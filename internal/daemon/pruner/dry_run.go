@@ -0,0 +1,66 @@
+package pruner
+
+// DryRunReport lists snapshots, which keep rules of one side would destroy
+// right now.
+type DryRunReport struct {
+	// Side is "sender", "receiver" or "local".
+	Side        string
+	Error       string `json:",omitempty"`
+	Filesystems []DryRunFSReport
+}
+
+type DryRunFSReport struct {
+	Filesystem     string
+	SkipReason     FSSkipReason `json:",omitempty"`
+	Error          string       `json:",omitempty"`
+	SnapshotsCount int
+	Destroy        []SnapshotReport
+
+	// DestroyBookmarks are names of zrepl bookmarks, which are older than
+	// bookmarks_max_age. Sender still keeps the last replication cursor of every
+	// job.
+	DestroyBookmarks []string `json:",omitempty"`
+}
+
+// DryRun plans pruning like Prune, but doesn't destroy anything. It returns
+// snapshots, which would be destroyed.
+func (p *Pruner) DryRun() *DryRunReport {
+	side, _ := p.args.ctx.Value(contextKeyPruneSide).(string)
+	r := &DryRunReport{Side: side}
+	if p.args.empty() {
+		return r
+	}
+
+	pfss, err := planFilesystems(&p.args)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	r.Filesystems = make([]DryRunFSReport, 0, len(pfss))
+	for _, pfs := range pfss {
+		r.Filesystems = append(r.Filesystems, pfs.DryRunReport())
+	}
+	return r
+}
+
+func (f *fs) DryRunReport() DryRunFSReport {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	r := DryRunFSReport{
+		Filesystem:     f.path,
+		SkipReason:     f.skipReason,
+		SnapshotsCount: len(f.snaps),
+		Destroy:        make([]SnapshotReport, len(f.destroy)),
+
+		DestroyBookmarks: f.destroyBookmarks,
+	}
+	if f.planErr != nil {
+		r.Error = f.planErr.Error()
+	}
+	for i, s := range f.destroy {
+		r.Destroy[i] = s.(*snapshot).Report()
+	}
+	return r
+}
@@ -0,0 +1,95 @@
+package pruner
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/pruning"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+type dryRunTarget struct {
+	versions  map[string][]*pdu.FilesystemVersion
+	destroyed bool
+}
+
+func (self *dryRunTarget) ListFilesystems(context.Context,
+) (*pdu.ListFilesystemRes, error) {
+	return &pdu.ListFilesystemRes{Filesystems: []*pdu.Filesystem{
+		{Path: "zroot/foo"},
+		{Path: "zroot/placeholder", IsPlaceholder: true},
+	}}, nil
+}
+
+func (self *dryRunTarget) ListFilesystemVersions(_ context.Context,
+	req *pdu.ListFilesystemVersionsReq,
+) (*pdu.ListFilesystemVersionsRes, error) {
+	return &pdu.ListFilesystemVersionsRes{
+		Versions: self.versions[req.Filesystem],
+	}, nil
+}
+
+func (self *dryRunTarget) DestroySnapshots(context.Context,
+	*pdu.DestroySnapshotsReq,
+) (*pdu.DestroySnapshotsRes, error) {
+	self.destroyed = true
+	return &pdu.DestroySnapshotsRes{}, nil
+}
+
+func (self *dryRunTarget) ReplicationCursor(context.Context,
+	*pdu.ReplicationCursorReq,
+) (*pdu.ReplicationCursorRes, error) {
+	return &pdu.ReplicationCursorRes{}, nil
+}
+
+func TestPruner_DryRun(t *testing.T) {
+	creation := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	versions := make([]*pdu.FilesystemVersion, 5)
+	for i := range versions {
+		versions[i] = &pdu.FilesystemVersion{
+			Name:      "zrepl_" + strconv.Itoa(i+1),
+			Guid:      uint64(i + 1),
+			CreateTXG: uint64(i + 1),
+			Creation: pdu.FilesystemVersionCreation(
+				creation.Add(time.Duration(i) * time.Hour)),
+		}
+	}
+	target := &dryRunTarget{
+		versions: map[string][]*pdu.FilesystemVersion{"zroot/foo": versions},
+	}
+
+	keepLast, err := pruning.NewKeepLastN(2, "")
+	require.NoError(t, err)
+	ctx := context.WithValue(t.Context(), contextKeyPruneSide, "local")
+	p := &Pruner{args: args{
+		ctx:    ctx,
+		target: target,
+		sender: target,
+		rules:  []pruning.KeepRule{keepLast},
+	}}
+
+	r := p.DryRun()
+	assert.False(t, target.destroyed)
+	assert.Equal(t, "local", r.Side)
+	assert.Empty(t, r.Error)
+	require.Len(t, r.Filesystems, 2)
+
+	fs := r.Filesystems[0]
+	assert.Equal(t, "zroot/foo", fs.Filesystem)
+	assert.True(t, fs.SkipReason.NotSkipped())
+	assert.Equal(t, 5, fs.SnapshotsCount)
+	names := make([]string, len(fs.Destroy))
+	for i := range fs.Destroy {
+		names[i] = fs.Destroy[i].Name
+	}
+	assert.Equal(t, []string{"zrepl_1", "zrepl_2", "zrepl_3"}, names)
+	assert.True(t, creation.Equal(fs.Destroy[0].Date))
+
+	assert.Equal(t, "zroot/placeholder", r.Filesystems[1].Filesystem)
+	assert.Equal(t, FSSkipReason(SkipPlaceholder), r.Filesystems[1].SkipReason)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,6 +22,11 @@ func NewLocalPrunerFactory(in config.PruningLocal,
 	if err != nil {
 		return nil, fmt.Errorf("cannot build pruning rules: %w", err)
 	}
+	rules = pruning.WithMinAge(rules, in.KeepMinAge)
+
+	if err := validReleaseHolds(in.ReleaseHolds); err != nil {
+		return nil, err
+	}
 
 	for _, r := range in.Keep {
 		if _, ok := r.Ret.(*config.PruneKeepNotReplicated); ok {
@@ -33,6 +40,8 @@ func NewLocalPrunerFactory(in config.PruningLocal,
 	f := &LocalPrunerFactory{
 		concurrency:   int(in.Concurrency),
 		keepRules:     rules,
+		releaseHolds:  in.ReleaseHolds,
+		reason:        keepReason("keep", in.Keep, in.KeepMinAge),
 		promPruneSecs: promPruneSecs,
 
 		retryWait: env.Values.PrunerRetryInterval,
@@ -43,10 +52,48 @@ func NewLocalPrunerFactory(in config.PruningLocal,
 type LocalPrunerFactory struct {
 	concurrency   int
 	keepRules     []pruning.KeepRule
+	releaseHolds  string
+	reason        string
 	retryWait     time.Duration
 	promPruneSecs *prometheus.HistogramVec
 }
 
+func validReleaseHolds(s string) error {
+	if s == "" {
+		return nil
+	} else if _, err := regexp.Compile(s); err != nil {
+		return fmt.Errorf("invalid release_holds regex %q: %w", s, err)
+	}
+	return nil
+}
+
+// keepReason describes keep rules in from config field name, because pruner
+// destroys snapshots, which none of them keeps.
+func keepReason(name string, in []config.PruningEnum, minAge time.Duration,
+) string {
+	types := make([]string, 0, len(in))
+	for i := range in {
+		switch v := in[i].Ret.(type) {
+		case *config.PruneKeepNotReplicated:
+			types = append(types, v.Type)
+		case *config.PruneKeepLastN:
+			types = append(types, v.Type)
+		case *config.PruneGrid:
+			types = append(types, v.Type)
+		case *config.PruneKeepRegex:
+			types = append(types, v.Type)
+		case *config.PruneKeepProperty:
+			types = append(types, v.Type)
+		}
+	}
+
+	reason := name + ": " + strings.Join(types, ", ")
+	if minAge > 0 && len(in) > 0 {
+		reason += "; keep_min_age: " + minAge.String()
+	}
+	return reason
+}
+
 func (f *LocalPrunerFactory) BuildLocalPruner(ctx context.Context,
 	target Target, history Sender,
 ) *Pruner {
@@ -59,6 +106,9 @@ func (f *LocalPrunerFactory) BuildLocalPruner(ctx context.Context,
 			rules:       f.keepRules,
 			retryWait:   f.retryWait,
 
+			releaseHolds: f.releaseHolds,
+			reason:       f.reason,
+
 			// considerSnapAtCursorReplicated is not relevant for local pruning
 			considerSnapAtCursorReplicated: false,
 
@@ -82,6 +132,10 @@ func NewPrunerFactory(in config.PruningSenderReceiver,
 		return nil, fmt.Errorf("cannot build sender pruning rules: %w", err)
 	}
 
+	if err := validReleaseHolds(in.ReleaseHolds); err != nil {
+		return nil, err
+	}
+
 	var considerSnapAtCursorReplicated bool
 	for _, r := range in.KeepSender {
 		if knr, ok := r.Ret.(*config.PruneKeepNotReplicated); ok {
@@ -92,13 +146,20 @@ func NewPrunerFactory(in config.PruningSenderReceiver,
 
 	f := &PrunerFactory{
 		concurrency:   int(in.Concurrency),
-		senderRules:   keepRulesSender,
-		receiverRules: keepRulesReceiver,
+		senderRules:   pruning.WithMinAge(keepRulesSender, in.KeepMinAge),
+		receiverRules: pruning.WithMinAge(keepRulesReceiver, in.KeepMinAge),
 		promPruneSecs: promPruneSecs,
 
 		retryWait: env.Values.PrunerRetryInterval,
 
 		considerSnapAtCursorReplicated: considerSnapAtCursorReplicated,
+		receiverDestroyDelay:           in.ReceiverDestroyDelay,
+		bookmarksMaxAge:                in.BookmarksMaxAge,
+		releaseHolds:                   in.ReleaseHolds,
+		senderReason: keepReason("keep_sender", in.KeepSender,
+			in.KeepMinAge),
+		receiverReason: keepReason("keep_receiver", in.KeepReceiver,
+			in.KeepMinAge),
 	}
 	return f, nil
 }
@@ -109,6 +170,11 @@ type PrunerFactory struct {
 	receiverRules                  []pruning.KeepRule
 	retryWait                      time.Duration
 	considerSnapAtCursorReplicated bool
+	receiverDestroyDelay           time.Duration
+	bookmarksMaxAge                time.Duration
+	releaseHolds                   string
+	senderReason                   string
+	receiverReason                 string
 	promPruneSecs                  *prometheus.HistogramVec
 }
 
@@ -125,6 +191,9 @@ func (f *PrunerFactory) BuildSenderPruner(ctx context.Context, target Target,
 			retryWait:   f.retryWait,
 
 			considerSnapAtCursorReplicated: f.considerSnapAtCursorReplicated,
+			bookmarksMaxAge:                f.bookmarksMaxAge,
+			releaseHolds:                   f.releaseHolds,
+			reason:                         f.senderReason,
 
 			promPruneSecs: f.promPruneSecs.WithLabelValues("sender"),
 		},
@@ -146,6 +215,9 @@ func (f *PrunerFactory) BuildReceiverPruner(ctx context.Context, target Target,
 			retryWait:   f.retryWait,
 
 			considerSnapAtCursorReplicated: false, // senseless here anyways
+			destroyDelay:                   f.receiverDestroyDelay,
+			releaseHolds:                   f.releaseHolds,
+			reason:                         f.receiverReason,
 
 			promPruneSecs: f.promPruneSecs.WithLabelValues("receiver"),
 		},
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/logger"
 	"github.com/dsh2dsh/zrepl/internal/pruning"
@@ -31,6 +33,12 @@ type fs struct {
 	// (type snapshot)
 	destroyList  []string
 	destroyCount int
+	// snapshots from snaps, which keep rules destroy
+	destroy []pruning.Snapshot
+	// names of zrepl bookmarks older than bookmarks max age
+	destroyBookmarks []string
+	// snapshots, which weren't destroyed because of user holds
+	held []pdu.HeldSnapshot
 
 	mtx sync.RWMutex
 
@@ -69,14 +77,25 @@ func (f *fs) Report() FSReport {
 
 	r.SnapshotsCount = len(f.snaps)
 	r.DestroysCount = f.destroyCount
+	r.BookmarkDestroysCount = len(f.destroyBookmarks)
+	r.Held = f.held
 	if len(f.destroyList) != 0 {
 		r.PendingDestroy = f.destroyList[0]
 	}
 	return r
 }
 
+func (f *fs) setHeld(held []pdu.HeldSnapshot) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.held = held
+}
+
+// Build plans pruning of filesystem tfs. If needsReplicated, it uses
+// replication cursor from cursors, which were fetched by one batched request,
+// or asks sender about it, if cursors has no result for tfs.
 func (self *fs) Build(a *args, tfs *pdu.Filesystem, target Target,
-	sender Sender, needsReplicated bool,
+	sender Sender, needsReplicated bool, cursors *pdu.ReplicationCursorRes,
 ) error {
 	ctx := a.ctx
 	l := GetLogger(ctx).With(slog.String("fs", tfs.Path))
@@ -90,7 +109,10 @@ func (self *fs) Build(a *args, tfs *pdu.Filesystem, target Target,
 			message+": plan error, skipping filesystem")
 	}
 
-	req := pdu.ListFilesystemVersionsReq{Filesystem: tfs.Path}
+	req := pdu.ListFilesystemVersionsReq{
+		Filesystem: tfs.Path,
+		Properties: pruning.RulesProperties(a.rules),
+	}
 	tfsvsres, err := target.ListFilesystemVersions(ctx, &req)
 	if err != nil {
 		pfsPlanErrAndLog(err, "cannot list filesystem versions")
@@ -110,12 +132,16 @@ func (self *fs) Build(a *args, tfs *pdu.Filesystem, target Target,
 	var cursorGuid uint64
 	var beforeCursor bool
 	if needsReplicated {
-		req := pdu.ReplicationCursorReq{Filesystem: tfs.Path}
-		resp, err := sender.ReplicationCursor(ctx, &req)
-		if err != nil {
-			pfsPlanErrAndLog(err, "cannot get replication cursor bookmark")
-			return nil
-		} else if resp.GetNotexist() {
+		resp := cursors.Lookup(tfs.Path)
+		if resp == nil {
+			req := pdu.ReplicationCursorReq{Filesystem: tfs.Path}
+			resp, err = sender.ReplicationCursor(ctx, &req)
+			if err != nil {
+				pfsPlanErrAndLog(err, "cannot get replication cursor bookmark")
+				return nil
+			}
+		}
+		if resp.GetNotexist() {
 			err := errors.New(
 				"replication cursor bookmark does not exist (one successful replication is required before pruning works)")
 			pfsPlanErrAndLog(err, "")
@@ -155,11 +181,42 @@ func (self *fs) Build(a *args, tfs *pdu.Filesystem, target Target,
 
 	// Apply prune rules
 	destroy := pruning.PruneSnapshots(ctx, self.snaps, a.rules)
+	self.destroy = destroy
 	self.destroyCount = len(destroy)
 	self.destroyList = snapshotRanges(self.snaps, destroy)
+
+	if a.bookmarksMaxAge > 0 {
+		bookmarks, err := oldBookmarks(tfsvs, time.Now().Add(-a.bookmarksMaxAge))
+		if err != nil {
+			pfsPlanErrAndLog(err, "fs version with invalid creation date")
+			return nil
+		}
+		self.destroyBookmarks = bookmarks
+	}
 	return nil
 }
 
+// oldBookmarks returns names of zrepl bookmarks from versions, which were
+// created before t. Sender decides, which of them are really zrepl
+// abstractions and can be destroyed.
+func oldBookmarks(versions []*pdu.FilesystemVersion, t time.Time,
+) ([]string, error) {
+	var names []string
+	for _, v := range versions {
+		if v.Type != pdu.FilesystemVersion_Bookmark ||
+			!strings.HasPrefix(v.Name, "zrepl_") {
+			continue
+		}
+		creation, err := v.CreationAsTime()
+		if err != nil {
+			return nil, fmt.Errorf("#%s: %w", v.Name, err)
+		} else if creation.Before(t) {
+			names = append(names, v.Name)
+		}
+	}
+	return names, nil
+}
+
 func snapshotRanges(snapshots, destroy []pruning.Snapshot) []string {
 	names := make([]string, 0, len(destroy))
 	var lastIdx int
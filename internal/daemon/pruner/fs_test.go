@@ -1,9 +1,13 @@
 package pruner
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dsh2dsh/zrepl/internal/pruning"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
@@ -88,3 +92,83 @@ func Test_snapshotRanges(t *testing.T) {
 		})
 	}
 }
+
+type cursorSender struct {
+	Sender
+
+	req *pdu.ReplicationCursorReq
+	err error
+}
+
+func (self *cursorSender) ReplicationCursor(_ context.Context,
+	req *pdu.ReplicationCursorReq,
+) (*pdu.ReplicationCursorRes, error) {
+	self.req = req
+	if self.err != nil {
+		return nil, self.err
+	}
+	resp := &pdu.ReplicationCursorRes{
+		Results: make(map[string]*pdu.ReplicationCursorRes_Result),
+	}
+	for i, fs := range req.Filesystems {
+		resp.Results[fs] = &pdu.ReplicationCursorRes_Result{Guid: uint64(i + 1)}
+	}
+	return resp, nil
+}
+
+func Test_replicationCursors(t *testing.T) {
+	sfss := map[string]*pdu.Filesystem{
+		"pool/a": {Path: "pool/a"},
+		"pool/b": {Path: "pool/b"},
+	}
+	tfss := []*pdu.Filesystem{
+		{Path: "pool/a"},
+		{Path: "pool/b", IsPlaceholder: true},
+		{Path: "pool/c"},
+	}
+
+	ctx := context.WithValue(t.Context(), contextKeyPruneSide, "sender")
+	sender := &cursorSender{}
+	cursors := replicationCursors(ctx, sender, sfss, tfss)
+	require.NotNil(t, cursors)
+	assert.Equal(t, []string{"pool/a"}, sender.req.Filesystems)
+	assert.Equal(t, uint64(1), cursors.Lookup("pool/a").GetGuid())
+	assert.Nil(t, cursors.Lookup("pool/c"))
+
+	sender = &cursorSender{err: errors.New("not supported")}
+	assert.Nil(t, replicationCursors(ctx, sender, sfss, tfss))
+
+	sender = &cursorSender{}
+	assert.Nil(t, replicationCursors(ctx, sender, sfss, tfss[1:]))
+	assert.Nil(t, sender.req)
+}
+
+func Test_oldBookmarks(t *testing.T) {
+	now := time.Now()
+	version := func(typ pdu.FilesystemVersion_VersionType, name string,
+		age time.Duration,
+	) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type:     typ,
+			Name:     name,
+			Creation: now.Add(-age).Format(time.RFC3339),
+		}
+	}
+
+	versions := []*pdu.FilesystemVersion{
+		version(pdu.FilesystemVersion_Bookmark, "zrepl_CURSOR_old", 48*time.Hour),
+		version(pdu.FilesystemVersion_Bookmark, "zrepl_CURSOR_new", time.Hour),
+		version(pdu.FilesystemVersion_Bookmark, "manual", 48*time.Hour),
+		version(pdu.FilesystemVersion_Snapshot, "zrepl_snap", 48*time.Hour),
+	}
+	names, err := oldBookmarks(versions, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zrepl_CURSOR_old"}, names)
+
+	versions = append(versions,
+		&pdu.FilesystemVersion{
+			Type: pdu.FilesystemVersion_Bookmark, Name: "zrepl_invalid",
+		})
+	_, err = oldBookmarks(versions, now)
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/logger"
 	"github.com/dsh2dsh/zrepl/internal/pruning"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/util/pressure"
 )
 
 // The sender in the replication setup. The pruner uses the Sender to determine
@@ -65,9 +66,19 @@ type args struct {
 	rules                          []pruning.KeepRule
 	retryWait                      time.Duration
 	considerSnapAtCursorReplicated bool
+	destroyDelay                   time.Duration
+	bookmarksMaxAge                time.Duration
+	releaseHolds                   string
+	reason                         string
 	promPruneSecs                  prometheus.Observer
 }
 
+// empty returns true, if there is nothing to prune: no keep rules and no
+// bookmarks max age configured.
+func (self *args) empty() bool {
+	return len(self.rules) == 0 && self.bookmarksMaxAge <= 0
+}
+
 func (self *args) Concurrency() int {
 	if self.concurrency < 1 {
 		return runtime.GOMAXPROCS(0)
@@ -95,7 +106,7 @@ type updater func(func(*Pruner))
 func (p *Pruner) Concurrency() int { return p.args.Concurrency() }
 
 func (p *Pruner) Prune() {
-	if len(p.args.rules) == 0 {
+	if p.args.empty() {
 		l := GetLogger(p.args.ctx)
 		l.Info("skip pruning, because no keep rules configured")
 		p.state = Done
@@ -144,56 +155,17 @@ func (p *Pruner) State() State {
 }
 
 func doOneAttempt(a *args, u updater) {
-	ctx, target, sender := a.ctx, a.target, a.sender
-	sfss, tfss, err := listFilesystems(ctx, sender, target)
+	pfss, err := planFilesystems(a)
 	if err != nil {
 		u(func(p *Pruner) { p.state, p.err = PlanErr, err })
 		return
 	}
 
-	pfss := make([]*fs, len(tfss))
-	needsReplicated := containsNotReplicated(a.rules)
-	g := new(errgroup.Group)
-	g.SetLimit(a.Concurrency())
-
-	for i, tfs := range tfss {
-		if ctx.Err() != nil {
-			break
-		}
-		l := GetLogger(ctx).With(slog.String("fs", tfs.Path))
-		l.Debug("plan filesystem")
-
-		pfs := &fs{path: tfs.Path}
-		pfss[i] = pfs
-
-		if tfs.GetIsPlaceholder() {
-			pfs.skipReason = SkipPlaceholder
-			l.With(slog.String("skip_reason", string(pfs.skipReason))).
-				Debug("skipping filesystem")
-			continue
-		} else if sfs := sfss[tfs.GetPath()]; sfs == nil {
-			pfs.skipReason = SkipNoCorrespondenceOnSender
-			l.With(
-				slog.String("skip_reason", string(pfs.skipReason)),
-				slog.String("sfs", sfs.GetPath()),
-			).Debug("skipping filesystem")
-			continue
-		}
-
-		g.Go(func() error {
-			return pfs.Build(a, tfs, target, sender, needsReplicated)
-		})
-	}
-
-	if err := g.Wait(); err != nil {
-		u(func(p *Pruner) { p.state, p.err = PlanErr, err })
-		return
-	} else if ctx.Err() != nil {
-		u(func(p *Pruner) { p.state, p.err = PlanErr, context.Cause(ctx) })
-		return
+	req := pdu.DestroySnapshotsReq{
+		DestroyDelay: a.destroyDelay,
+		ReleaseHolds: a.releaseHolds,
+		Reason:       a.reason,
 	}
-
-	req := pdu.DestroySnapshotsReq{}
 	u(func(p *Pruner) {
 		makeExecQueue(a.ctx, p, pfss, &req)
 		p.state = Exec
@@ -204,7 +176,7 @@ func doOneAttempt(a *args, u updater) {
 		return
 	}
 
-	resp, err := target.DestroySnapshots(a.ctx, &req)
+	resp, err := a.target.DestroySnapshots(a.ctx, &req)
 	if err != nil {
 		u(func(p *Pruner) { p.state, p.err = ExecErr, err })
 		return
@@ -245,6 +217,60 @@ func doOneAttempt(a *args, u updater) {
 	})
 }
 
+// planFilesystems applies keep rules to snapshots of every target filesystem.
+func planFilesystems(a *args) ([]*fs, error) {
+	ctx, target, sender := a.ctx, a.target, a.sender
+	sfss, tfss, err := listFilesystems(ctx, sender, target)
+	if err != nil {
+		return nil, err
+	}
+
+	pfss := make([]*fs, len(tfss))
+	needsReplicated := containsNotReplicated(a.rules)
+	var cursors *pdu.ReplicationCursorRes
+	if needsReplicated {
+		cursors = replicationCursors(ctx, sender, sfss, tfss)
+	}
+	g := new(errgroup.Group)
+	g.SetLimit(pressure.Concurrency(a.Concurrency()))
+
+	for i, tfs := range tfss {
+		if ctx.Err() != nil {
+			break
+		}
+		l := GetLogger(ctx).With(slog.String("fs", tfs.Path))
+		l.Debug("plan filesystem")
+
+		pfs := &fs{path: tfs.Path}
+		pfss[i] = pfs
+
+		if tfs.GetIsPlaceholder() {
+			pfs.skipReason = SkipPlaceholder
+			l.With(slog.String("skip_reason", string(pfs.skipReason))).
+				Debug("skipping filesystem")
+			continue
+		} else if sfs := sfss[tfs.GetPath()]; sfs == nil {
+			pfs.skipReason = SkipNoCorrespondenceOnSender
+			l.With(
+				slog.String("skip_reason", string(pfs.skipReason)),
+				slog.String("sfs", sfs.GetPath()),
+			).Debug("skipping filesystem")
+			continue
+		}
+
+		g.Go(func() error {
+			return pfs.Build(a, tfs, target, sender, needsReplicated, cursors)
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err //nolint:wrapcheck // it's our error
+	} else if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return pfss, nil
+}
+
 func listFilesystems(ctx context.Context, sender Sender, target Target,
 ) (sfss map[string]*pdu.Filesystem, tfss []*pdu.Filesystem, _ error) {
 	g := new(errgroup.Group)
@@ -275,6 +301,35 @@ func listFilesystems(ctx context.Context, sender Sender, target Target,
 	return sfss, tfss, nil
 }
 
+// replicationCursors asks sender about replication cursors of all filesystems,
+// which exist on both sides, using one request. It returns nil on error, and
+// every filesystem will ask sender about its replication cursor separately.
+func replicationCursors(ctx context.Context, sender Sender,
+	sfss map[string]*pdu.Filesystem, tfss []*pdu.Filesystem,
+) *pdu.ReplicationCursorRes {
+	req := pdu.ReplicationCursorReq{
+		Filesystems: make([]string, 0, len(tfss)),
+	}
+	for _, tfs := range tfss {
+		if !tfs.GetIsPlaceholder() && sfss[tfs.GetPath()] != nil {
+			req.Filesystems = append(req.Filesystems, tfs.GetPath())
+		}
+	}
+	if len(req.Filesystems) == 0 {
+		return nil
+	}
+
+	l := GetLogger(ctx).With(slog.Int("count", len(req.Filesystems)))
+	l.Debug("get replication cursors")
+	resp, err := sender.ReplicationCursor(ctx, &req)
+	if err != nil {
+		logger.WithError(l, err,
+			"cannot get replication cursors, fallback to one request per filesystem")
+		return nil
+	}
+	return resp
+}
+
 func containsNotReplicated(rules []pruning.KeepRule) bool {
 	i := slices.IndexFunc(rules, func(r pruning.KeepRule) bool {
 		_, ok := r.(*pruning.KeepNotReplicated)
@@ -298,7 +353,8 @@ func makeExecQueue(ctx context.Context, p *Pruner, pfss []*fs,
 	l := GetLogger(ctx)
 
 	for _, pfs := range pfss {
-		if !pfs.skipReason.NotSkipped() || len(pfs.destroyList) == 0 {
+		if !pfs.skipReason.NotSkipped() ||
+			len(pfs.destroyList) == 0 && len(pfs.destroyBookmarks) == 0 {
 			continue
 		}
 		destroyList := make([]string, len(pfs.destroyList))
@@ -310,9 +366,16 @@ func makeExecQueue(ctx context.Context, p *Pruner, pfss []*fs,
 				slog.String("destroy_snap", destroyName),
 			).Debug("policy destroys snapshot")
 		}
+		for _, name := range pfs.destroyBookmarks {
+			l.With(
+				slog.String("fs", pfs.path),
+				slog.String("destroy_bookmark", name),
+			).Debug("policy destroys bookmark")
+		}
 		req.Filesystems = append(req.Filesystems, pdu.DestroySnapshots{
 			Filesystem: pfs.path,
 			Snapshots:  destroyList,
+			Bookmarks:  pfs.destroyBookmarks,
 		})
 		p.execQueue.Put(pfs, nil, false)
 	}
@@ -329,15 +392,27 @@ func checkOneAttemptExec(a *args, u updater, pfs *fs,
 		return
 	}
 
+	var held []pdu.HeldSnapshot
+	results := make([]*pdu.DestroySnapshotRes, 0, len(destroyed.Results))
+	for i := range destroyed.Results {
+		failed := &destroyed.Results[i]
+		held = append(held, failed.Held...)
+		if failed.Error != "" || len(failed.Held) == 0 {
+			results = append(results, failed)
+		}
+	}
+
 	var err error
-	if len(destroyed.Results) > 0 {
-		names := make([]string, len(destroyed.Results))
-		pairs := make([]string, len(destroyed.Results))
-		lastMsg, allSame := destroyed.Results[0].Error, true
-		for i := range destroyed.Results {
-			failed := &destroyed.Results[i]
+	if len(results) > 0 {
+		names := make([]string, len(results))
+		pairs := make([]string, len(results))
+		lastMsg, allSame := results[0].Error, true
+		for i, failed := range results {
 			allSame = allSame && failed.Error == lastMsg
-			names[i] = "@" + failed.Name
+			names[i] = failed.Name
+			if !strings.HasPrefix(names[i], "#") {
+				names[i] = "@" + names[i]
+			}
 			pairs[i] = fmt.Sprintf("(%s: %s)", names[i], failed.Error)
 		}
 		if allSame {
@@ -348,7 +423,17 @@ func checkOneAttemptExec(a *args, u updater, pfs *fs,
 		}
 	}
 
-	u(func(p *Pruner) { p.execQueue.Put(pfs, err, err == nil) })
+	u(func(p *Pruner) {
+		pfs.setHeld(held)
+		p.execQueue.Put(pfs, err, err == nil)
+	})
+	for _, s := range held {
+		GetLogger(a.ctx).With(
+			slog.String("fs", pfs.path),
+			slog.String("snap", s.Name),
+			slog.String("tags", strings.Join(s.Tags, ",")),
+		).Warn("skip snapshot, it's held")
+	}
 	if err != nil {
 		logger.WithError(GetLogger(a.ctx), err,
 			"target could not destroy snapshots")
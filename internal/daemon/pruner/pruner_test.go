@@ -0,0 +1,60 @@
+package pruner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func TestCheckOneAttemptExec_held(t *testing.T) {
+	held := pdu.HeldSnapshot{Name: "snap2", Tags: []string{"backup"}}
+	tests := []struct {
+		name      string
+		results   []pdu.DestroySnapshotRes
+		wantError string
+	}{
+		{
+			name: "held only",
+			results: []pdu.DestroySnapshotRes{
+				{Name: "snap1%snap3", Held: []pdu.HeldSnapshot{held}},
+			},
+		},
+		{
+			name: "held and failed",
+			results: []pdu.DestroySnapshotRes{
+				{
+					Name:  "snap1%snap3",
+					Error: "snap1: dataset is busy",
+					Held:  []pdu.HeldSnapshot{held},
+				},
+				{Name: "bar", Error: "dataset is busy"},
+			},
+			wantError: "destroys failed: (@snap1%snap3: snap1: dataset is busy), (@bar: dataset is busy)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pruner{execQueue: newExecQueue(1)}
+			u := func(f func(*Pruner)) { f(p) }
+			a := &args{
+				ctx: context.WithValue(t.Context(), contextKeyPruneSide, "sender"),
+			}
+			pfs := &fs{path: "pool/a"}
+
+			checkOneAttemptExec(a, u, pfs, &pdu.DestroyedSnapshots{
+				Filesystem: pfs.path,
+				Results:    tt.results,
+			})
+
+			r := p.Report()
+			require.Len(t, r.Completed, 1)
+			assert.Equal(t, tt.wantError, r.Completed[0].LastError)
+			assert.Equal(t, []pdu.HeldSnapshot{held}, r.Completed[0].Held)
+		})
+	}
+}
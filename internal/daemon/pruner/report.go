@@ -1,6 +1,10 @@
 package pruner
 
-import "time"
+import (
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
 
 type Report struct {
 	State              string
@@ -16,6 +20,10 @@ type FSReport struct {
 	PendingDestroy string
 	SkipReason     FSSkipReason
 	LastError      string
+
+	BookmarkDestroysCount int
+	// Held are snapshots, which weren't destroyed because of user holds.
+	Held []pdu.HeldSnapshot `json:",omitempty"`
 }
 
 type SnapshotReport struct {
@@ -55,3 +63,6 @@ func (self *Report) Progress() (expected, completed uint64) {
 	}
 	return expected, completed
 }
+
+// Succeeded returns true if pruning finished without errors.
+func (self *Report) Succeeded() bool { return self.State == Done.String() }
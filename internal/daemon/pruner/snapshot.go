@@ -26,3 +26,8 @@ func (self *snapshot) Report() SnapshotReport {
 func (self *snapshot) Name() string     { return self.fsv.Name }
 func (self *snapshot) Replicated() bool { return self.replicated }
 func (self *snapshot) Date() time.Time  { return self.date }
+
+func (self *snapshot) Property(name string) (string, bool) {
+	v, ok := self.fsv.Properties[name]
+	return v, ok
+}
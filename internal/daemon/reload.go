@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+// newConfigReloader returns reloader of jobs from config file. On every reload
+// it re-reads config using load, stops removed jobs, starts new ones and
+// restarts changed ones. Unchanged jobs are not touched.
+func newConfigReloader(jobs *jobs, conf *config.Config,
+	connecter *job.Connecter, load func() (*config.Config, error),
+) *configReloader {
+	return &configReloader{
+		jobs:      jobs,
+		conf:      conf,
+		connecter: connecter,
+		load:      load,
+	}
+}
+
+type configReloader struct {
+	jobs      *jobs
+	conf      *config.Config
+	connecter *job.Connecter
+	load      func() (*config.Config, error)
+}
+
+func (self *configReloader) Reload() {
+	log := self.jobs.log
+	if self.jobs.graceful.Err() != nil {
+		log.Info("skip reloading config of stopping daemon")
+		return
+	}
+
+	log.Info("reloading config")
+	conf, err := self.load()
+	if err != nil {
+		logger.WithError(log, err, "failed reload config, keep running jobs")
+		return
+	}
+
+	if err := self.apply(conf); err != nil {
+		logger.WithError(log, err, "failed apply config, keep running jobs")
+		return
+	}
+	self.conf = conf
+}
+
+func (self *configReloader) apply(conf *config.Config) error {
+	log := self.jobs.log
+	if !reflect.DeepEqual(self.conf.Global, conf.Global) ||
+		!reflect.DeepEqual(self.conf.Listen, conf.Listen) {
+		log.Warn("changes of global and listen require daemon restart")
+	}
+
+	nextJobs, connecter, err := job.JobsFromConfig(conf)
+	if err != nil {
+		return fmt.Errorf("cannot build jobs from config: %w", err)
+	}
+
+	prevConf := make(map[string]*config.JobEnum, len(self.conf.Jobs))
+	for i := range self.conf.Jobs {
+		j := &self.conf.Jobs[i]
+		prevConf[j.Name()] = j
+	}
+
+	started := make([]job.Job, 0, len(nextJobs))
+	for i, j := range nextJobs {
+		name := j.Name()
+		prev, ok := prevConf[name]
+		delete(prevConf, name)
+		if ok && reflect.DeepEqual(prev.Ret, conf.Jobs[i].Ret) {
+			if p, ok := self.jobs.job(name); ok {
+				nextJobs[i] = p.job
+				continue
+			}
+		}
+		started = append(started, j)
+	}
+	self.connecter.Replace(connecter, nextJobs)
+
+	cause := errors.New("job changed by config reload")
+	for name := range prevConf {
+		log.With(slog.String(logging.JobField, name)).Info("remove job")
+		self.jobs.removeJob(name, cause)
+		zfscmd.SetJobEnv(name, nil)
+		_ = audit.OpenJob("", name)
+		self.jobs.notify.Forget(name)
+	}
+
+	for i := range conf.Jobs {
+		j := &conf.Jobs[i]
+		zfscmd.SetJobEnv(j.Name(), j.ZfsEnv())
+	}
+
+	for _, j := range started {
+		if err := audit.OpenJob(self.conf.Global.AuditDir, j.Name()); err != nil {
+			logger.WithError(log.With(slog.String(logging.JobField, j.Name())),
+				err, "failed open audit log")
+		}
+	}
+
+	for _, j := range started {
+		self.jobs.replaceJob(j, cause)
+	}
+
+	log.With(
+		slog.Int("count", len(nextJobs)),
+		slog.Int("started", len(started)),
+		slog.Int("removed", len(prevConf)),
+	).Info("config reloaded")
+	return nil
+}
+
+// replaceJob stops job with the same name, if exists, and starts j after it
+// stopped.
+func (self *jobs) replaceJob(j job.Job, cause error) {
+	name := j.Name()
+	log := job.GetLogger(self.ctx).With(slog.String(logging.JobField, name))
+	done := self.removeJob(name, cause)
+	p := self.addJob(j)
+	if done == nil {
+		log.Info("start job")
+		self.startJob(p, log)
+		return
+	}
+
+	log.Info("wait for previous job stopped")
+	self.g.Go(func() error {
+		<-done
+		if self.graceful.Err() != nil {
+			return nil
+		}
+		log.Info("start job")
+		self.startJob(p, log)
+		return nil
+	})
+}
+
+// newJobMetrics returns [prometheus.Registerer], which remembers registered
+// collectors, so they can be unregistered, when the job removed.
+func newJobMetrics(registerer prometheus.Registerer) *jobMetrics {
+	return &jobMetrics{Registerer: registerer}
+}
+
+type jobMetrics struct {
+	prometheus.Registerer
+
+	collectors []prometheus.Collector
+	mu         sync.Mutex
+}
+
+func (self *jobMetrics) Register(c prometheus.Collector) error {
+	if err := self.Registerer.Register(c); err != nil {
+		return err //nolint:wrapcheck // not needed
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.collectors = append(self.collectors, c)
+	return nil
+}
+
+func (self *jobMetrics) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := self.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Reset unregisters all collectors, registered by the job.
+func (self *jobMetrics) Reset() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for _, c := range self.collectors {
+		self.Registerer.Unregister(c)
+	}
+	self.collectors = nil
+}
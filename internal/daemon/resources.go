@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/pressure"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func applyResources(log *slog.Logger, in *config.GlobalResources) {
+	if in.GoMaxProcs > 0 {
+		prev := runtime.GOMAXPROCS(in.GoMaxProcs)
+		log.With(slog.Int("gomaxprocs", in.GoMaxProcs), slog.Int("prev", prev)).
+			Info("set GOMAXPROCS")
+	}
+
+	if in.GoGC != nil {
+		prev := debug.SetGCPercent(*in.GoGC)
+		log.With(slog.Int("gogc", *in.GoGC), slog.Int("prev", prev)).
+			Info("set GOGC")
+	}
+
+	if limit := in.GoMemLimit.Uint64(); limit > 0 {
+		prev := debug.SetMemoryLimit(int64(limit))
+		log.With(slog.Uint64("gomemlimit", limit), slog.Int64("prev", prev)).
+			Info("set GOMEMLIMIT")
+	}
+}
+
+func applyParallel(log *slog.Logger, in *config.GlobalParallel) {
+	zfs.SetParallel(in.Send, in.Recv)
+	if in.Send > 0 || in.Recv > 0 {
+		log.With(slog.Int("send", in.Send), slog.Int("recv", in.Recv)).
+			Info("limit parallel zfs send/recv")
+	}
+}
+
+func newRSSWatchdog(in *config.RSSWatchdog) *rssWatchdog {
+	return &rssWatchdog{
+		limit:    in.Limit.Uint64(),
+		interval: in.Interval,
+		shedAt:   uint64(float64(in.Limit.Uint64()) * in.ShedRatio),
+		rss:      processRSS,
+
+		promRSS: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "zrepl",
+			Subsystem: "daemon",
+			Name:      "rss_bytes",
+			Help:      "resident set size of the daemon",
+		}),
+		promShedding: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "zrepl",
+			Subsystem: "daemon",
+			Name:      "shedding",
+			Help:      "1 if the daemon sheds concurrency because of memory pressure",
+		}),
+	}
+}
+
+// rssWatchdog is an internal job, which sheds concurrency of the daemon, when
+// its memory usage approaches configured limit.
+type rssWatchdog struct {
+	limit    uint64
+	interval time.Duration
+	shedAt   uint64
+	rss      func() (uint64, error)
+
+	promRSS      prometheus.Gauge
+	promShedding prometheus.Gauge
+}
+
+var _ job.Internal = (*rssWatchdog)(nil)
+
+func (self *rssWatchdog) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(self.promRSS, self.promShedding)
+}
+
+func (self *rssWatchdog) Run(ctx context.Context) error {
+	log := logging.GetLogger(ctx, logging.SubsysJob).With(
+		slog.Uint64("limit", self.limit), slog.Uint64("shed_at", self.shedAt))
+	log.Info("start rss watchdog")
+
+	t := time.NewTicker(self.interval)
+	defer t.Stop()
+	for {
+		self.check(log)
+		select {
+		case <-ctx.Done():
+			pressure.Set(false)
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (self *rssWatchdog) check(log *slog.Logger) {
+	rss, err := self.rss()
+	if err != nil {
+		logger.WithError(log, err,
+			"use memory obtained by Go runtime instead of resident set size")
+		rss = goRSS()
+	}
+	self.promRSS.Set(float64(rss))
+
+	high := rss >= self.shedAt
+	if prev := pressure.Set(high); prev == high {
+		return
+	}
+
+	log = log.With(slog.Uint64("rss", rss))
+	if high {
+		self.promShedding.Set(1)
+		log.Warn("memory usage approaches limit, shed concurrency")
+		debug.FreeOSMemory()
+	} else {
+		self.promShedding.Set(0)
+		log.Info("memory usage is below limit, restore concurrency")
+	}
+}
+
+// goRSS returns memory, which Go runtime obtained from the OS and didn't
+// release yet. It's used, if resident set size can't be read.
+func goRSS() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	var total, released uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		total = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		released = samples[1].Value.Uint64()
+	}
+	return total - released
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/util/pressure"
+)
+
+func TestRSSWatchdog_check(t *testing.T) {
+	w := newRSSWatchdog(&config.RSSWatchdog{
+		Limit: 1000, Interval: time.Second, ShedRatio: 0.9,
+	})
+	var rss uint64
+	w.rss = func() (uint64, error) { return rss, nil }
+	t.Cleanup(func() { pressure.Set(false) })
+
+	rss = 500
+	w.check(slog.Default())
+	assert.False(t, pressure.High())
+
+	rss = 950
+	w.check(slog.Default())
+	assert.True(t, pressure.High(), "shed concurrency")
+	w.check(slog.Default())
+	assert.True(t, pressure.High(), "still high")
+
+	rss = 800
+	w.check(slog.Default())
+	assert.False(t, pressure.High(), "restore concurrency, when RSS dropped")
+}
+
+func TestProcessRSS(t *testing.T) {
+	rss, err := processRSS()
+	require.NoError(t, err)
+	assert.Positive(t, rss)
+}
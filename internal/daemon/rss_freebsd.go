@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Offset of ki_rssize in struct kinfo_proc of sys/user.h: 8 pointers and 192
+// bytes of ints, shorts, sigsets and groups before it, followed by vm_size_t
+// ki_size. Both ki_size and ki_rssize have size of a pointer.
+const (
+	kinfoPtrSize   = int(unsafe.Sizeof(uintptr(0)))
+	kinfoRSSOffset = 192 + 9*kinfoPtrSize
+)
+
+// processRSS returns current resident set size of the daemon from ki_rssize of
+// kern.proc.pid sysctl.
+func processRSS() (uint64, error) {
+	b, err := unix.SysctlRaw("kern.proc.pid", os.Getpid())
+	if err != nil {
+		return 0, fmt.Errorf("sysctl kern.proc.pid: %w", err)
+	}
+	pages, err := kinfoRSSize(b)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+// kinfoRSSize returns ki_rssize of struct kinfo_proc b in pages.
+func kinfoRSSize(b []byte) (uint64, error) {
+	if len(b) < 4 {
+		return 0, fmt.Errorf("short kinfo_proc: %d bytes", len(b))
+	} else if size := int(binary.NativeEndian.Uint32(b)); size != len(b) ||
+		size < kinfoRSSOffset+kinfoPtrSize {
+		return 0, fmt.Errorf("unexpected kinfo_proc: ki_structsize=%d, len=%d",
+			size, len(b))
+	}
+
+	if kinfoPtrSize == 8 {
+		return binary.NativeEndian.Uint64(b[kinfoRSSOffset:]), nil
+	}
+	return uint64(binary.NativeEndian.Uint32(b[kinfoRSSOffset:])), nil
+}
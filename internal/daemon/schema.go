@@ -0,0 +1,21 @@
+package daemon
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+)
+
+//go:generate go run schema_gen.go schema.json
+
+// APISchemaJSON contains JSON Schemas of JSON documents, which are stable
+// contract for tools consuming them, keyed by name. It's generated from types
+// of these documents.
+//
+//go:embed schema.json
+var APISchemaJSON []byte
+
+func (j *controlJob) schema(_ context.Context) (*json.RawMessage, error) {
+	b := json.RawMessage(APISchemaJSON)
+	return &b, nil
+}
@@ -0,0 +1,20 @@
+//go:build ignore
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dsh2dsh/zrepl/internal/client"
+)
+
+func main() {
+	b, err := client.MarshalAPISchemas()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(os.Args[1], b, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -62,6 +63,7 @@ type serverJob struct {
 	controlJob *controlJob
 	hasMetrics bool
 	zfsJob     *zfsJob
+	keys       []config.AuthKey
 }
 
 var _ job.Internal = (*serverJob)(nil)
@@ -73,12 +75,24 @@ func (self *serverJob) init() *serverJob {
 		middleware.RequestLogger(
 			// don't log requests to status endpoint, too spammy
 			middleware.WithCustomLevel(ControlJobEndpointStatus, slog.LevelDebug),
-			middleware.WithCustomLevel("/metrics", slog.LevelDebug)),
+			middleware.WithCustomLevel(ControlJobEndpointStatusWatch,
+				slog.LevelDebug),
+			middleware.WithCustomLevel("/metrics", slog.LevelDebug),
+			middleware.WithCustomLevel(EndpointHealthz, slog.LevelDebug),
+			middleware.WithCustomLevel(EndpointReadyz, slog.LevelDebug),
+			middleware.WithCustomLevel("/api/status", slog.LevelDebug),
+			middleware.WithCustomLevel("/", slog.LevelDebug)),
 		self.prometheus,
 	}
 	return self
 }
 
+// WithKeys configures keys, which control_keys of listeners refer to.
+func (self *serverJob) WithKeys(keys []config.AuthKey) *serverJob {
+	self.keys = keys
+	return self
+}
+
 func (self *serverJob) RegisterMetrics(registerer prometheus.Registerer) {
 	registerer.MustRegister(self.reqBegin, self.reqFinished)
 	if self.hasMetrics {
@@ -87,24 +101,36 @@ func (self *serverJob) RegisterMetrics(registerer prometheus.Registerer) {
 }
 
 func (self *serverJob) AddServer(c *config.Listen) error {
-	self.log.With(
+	log := self.log.With(
 		slog.String("addr", c.Addr),
 		slog.String("unix", c.Unix),
 		slog.Bool("control", c.Control),
 		slog.Bool("metrics", c.Metrics),
 		slog.Bool("zfs", c.Zfs),
-	).Info("adding listener")
+		slog.Bool("web", c.Web),
+	)
+	log.Info("adding listener")
+
+	controlKeys, err := self.controlKeys(c.ControlKeys)
+	if err != nil {
+		return fmt.Errorf("add server: %w", err)
+	} else if c.Control && c.Addr != "" && len(controlKeys) == 0 &&
+		c.TLSClientCA == "" {
+		log.Warn("control endpoints listen on network without authentication")
+	}
 
 	s := &server{
 		Server: &http.Server{
 			Addr:    c.Addr,
-			Handler: self.mux(c),
+			Handler: self.mux(c, controlKeys),
 
 			ReadHeaderTimeout: 10 * time.Second,
 			IdleTimeout:       30 * time.Second,
+			ConnContext:       middleware.WithConn,
 		},
-		certFile: c.TLSCert,
-		keyFile:  c.TLSKey,
+		certFile:     c.TLSCert,
+		keyFile:      c.TLSKey,
+		clientCAFile: c.TLSClientCA,
 	}
 
 	if c.Unix != "" {
@@ -121,18 +147,41 @@ func (self *serverJob) AddServer(c *config.Listen) error {
 	return nil
 }
 
-func (self *serverJob) mux(c *config.Listen) *http.ServeMux {
+func (self *serverJob) controlKeys(names []string) ([]config.AuthKey, error) {
+	keys := make([]config.AuthKey, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(self.keys,
+			func(k config.AuthKey) bool { return k.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("control key %q not found in keys", name)
+		}
+		keys = append(keys, self.keys[i])
+	}
+	return keys, nil
+}
+
+func (self *serverJob) mux(c *config.Listen, controlKeys []config.AuthKey,
+) *http.ServeMux {
 	mux := http.NewServeMux()
 	if c.Control {
-		self.controlJob.Endpoints(mux, self.middlewares...)
+		m := self.middlewares
+		if len(controlKeys) > 0 {
+			m = append(slices.Clip(m),
+				middleware.CheckClientIdentity(controlKeys))
+		}
+		self.controlJob.Endpoints(mux, m...)
 	}
 	if c.Metrics {
 		self.hasMetrics = true
 		metricsEndpoints(mux, self.middlewares...)
+		self.controlJob.healthEndpoints(mux, self.middlewares...)
 	}
 	if c.Zfs {
 		self.zfsJob.Endpoints(mux, self.prometheus)
 	}
+	if c.Web {
+		self.controlJob.webEndpoints(mux, self.middlewares...)
+	}
 	return mux
 }
 
@@ -0,0 +1,123 @@
+package snapper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// newSnapshotBatch returns batch of n filesystems, which creates their
+// snapshots at once.
+func newSnapshotBatch(n int) *snapshotBatch {
+	return &snapshotBatch{
+		pending: n,
+		left:    make(map[*zfs.DatasetPath]struct{}, n),
+		errs:    make(map[*zfs.DatasetPath]error, n),
+		done:    make(chan struct{}),
+
+		createAtomic: zfs.ZFSSnapshotAtomic,
+		create:       zfs.ZFSSnapshot,
+	}
+}
+
+// snapshotBatch creates snapshots of all filesystems of a plan at once. Every
+// snapshot waits, until all other filesystems reached their snapshots too, or
+// left the batch, because their pre hooks failed. So all pre hooks run before
+// and all post hooks run after snapshots of the whole batch. Snapshots of
+// every pool are created in one txg by channel program, or one by one, if zfs
+// doesn't support channel programs.
+type snapshotBatch struct {
+	mu      sync.Mutex
+	pending int
+	snaps   []zfs.AtomicSnapshot
+	left    map[*zfs.DatasetPath]struct{}
+	errs    map[*zfs.DatasetPath]error
+	done    chan struct{}
+
+	createAtomic func(ctx context.Context, pool string,
+		snaps []zfs.AtomicSnapshot) error
+	create func(ctx context.Context, fs *zfs.DatasetPath, name string,
+		recursive bool, props map[string]string) error
+}
+
+// Snapshot adds snapshot s into the batch, waits until snapshots of the batch
+// are created and returns error of s.
+func (self *snapshotBatch) Snapshot(ctx context.Context, s zfs.AtomicSnapshot,
+) error {
+	self.mu.Lock()
+	self.snaps = append(self.snaps, s)
+	self.left[s.FS] = struct{}{}
+	self.pending--
+	last := self.pending == 0
+	self.mu.Unlock()
+
+	if last {
+		self.createSnapshots(ctx)
+	}
+
+	select {
+	case <-self.done:
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+	return self.errs[s.FS]
+}
+
+// Leave removes fs from the batch, if it didn't add its snapshot.
+func (self *snapshotBatch) Leave(ctx context.Context, fs *zfs.DatasetPath) {
+	self.mu.Lock()
+	if _, ok := self.left[fs]; ok {
+		self.mu.Unlock()
+		return
+	}
+	self.left[fs] = struct{}{}
+	self.pending--
+	last := self.pending == 0
+	self.mu.Unlock()
+
+	if last {
+		self.createSnapshots(ctx)
+	}
+}
+
+func (self *snapshotBatch) createSnapshots(ctx context.Context) {
+	defer close(self.done)
+	pools := make(map[string][]zfs.AtomicSnapshot, 1)
+	for _, s := range self.snaps {
+		pool, _, _ := strings.Cut(s.FS.ToString(), "/")
+		pools[pool] = append(pools[pool], s)
+	}
+
+	for pool, snaps := range pools {
+		log := getLogger(ctx).With(slog.String("pool", pool),
+			slog.Int("snapshots", len(snaps)))
+		log.Debug("create snapshots atomically")
+		err := self.createAtomic(ctx, pool, snaps)
+		if errors.Is(err, zfs.ErrChannelProgramsNotSupported) {
+			logger.WithError(log, err, "").Warn(
+				"fallback to snapshots one by one")
+			for _, s := range snaps {
+				self.errs[s.FS] = self.create(ctx, s.FS, s.Name, s.Recursive,
+					s.Props)
+			}
+			continue
+		} else if err != nil {
+			logger.WithError(log, err, "cannot create snapshots atomically")
+		}
+
+		partial, _ := errors.AsType[*zfs.SnapshotAtomicError](err)
+		for i := range snaps {
+			s := &snaps[i]
+			if partial != nil && partial.IsCreated(s) {
+				self.errs[s.FS] = nil
+			} else {
+				self.errs[s.FS] = err
+			}
+		}
+	}
+}
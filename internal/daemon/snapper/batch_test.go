@@ -0,0 +1,133 @@
+package snapper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestSnapshotBatch(t *testing.T) {
+	var fss []*zfs.DatasetPath
+	for _, name := range []string{"pool/a", "pool/b", "tank/c", "tank/d"} {
+		p, err := zfs.NewDatasetPath(name)
+		require.NoError(t, err)
+		fss = append(fss, p)
+	}
+
+	tests := []struct {
+		name       string
+		atomicErr  error
+		wantAtomic map[string]int
+		wantSingle int
+		wantErr    error
+		// wantFailed are filesystems with errors, if only some of them failed.
+		wantFailed []bool
+	}{
+		{
+			name:       "atomic",
+			wantAtomic: map[string]int{"pool": 2, "tank": 1},
+		},
+		{
+			name:       "fallback",
+			atomicErr:  zfs.ErrChannelProgramsNotSupported,
+			wantAtomic: map[string]int{"pool": 2, "tank": 1},
+			wantSingle: 3,
+		},
+		{
+			name: "partial",
+			atomicErr: &zfs.SnapshotAtomicError{
+				Snapshot: "pool/b@snap",
+				Err:      syscall.ENOSPC,
+				Created:  map[string]bool{"pool/a@snap": true},
+			},
+			wantAtomic: map[string]int{"pool": 2, "tank": 1},
+			wantFailed: []bool{false, true, true},
+		},
+		{
+			name:       "error",
+			atomicErr:  errors.New("test error"),
+			wantAtomic: map[string]int{"pool": 2, "tank": 1},
+			wantErr:    errors.New("test error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			gotAtomic := make(map[string]int)
+			var gotSingle int
+
+			b := newSnapshotBatch(len(fss))
+			b.createAtomic = func(ctx context.Context, pool string,
+				snaps []zfs.AtomicSnapshot,
+			) error {
+				mu.Lock()
+				defer mu.Unlock()
+				gotAtomic[pool] += len(snaps)
+				return tt.atomicErr
+			}
+			b.create = func(ctx context.Context, fs *zfs.DatasetPath, name string,
+				recursive bool, props map[string]string,
+			) error {
+				mu.Lock()
+				defer mu.Unlock()
+				gotSingle++
+				return nil
+			}
+
+			ctx := t.Context()
+			var wg sync.WaitGroup
+			errs := make([]error, len(fss))
+			for i, fs := range fss {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer b.Leave(ctx, fs)
+					if fs.ToString() == "tank/d" {
+						return
+					}
+					errs[i] = b.Snapshot(ctx, zfs.AtomicSnapshot{FS: fs, Name: "snap"})
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, tt.wantAtomic, gotAtomic)
+			assert.Equal(t, tt.wantSingle, gotSingle)
+			for i, err := range errs[:3] {
+				if tt.wantFailed != nil {
+					if tt.wantFailed[i] {
+						require.ErrorIs(t, err, tt.atomicErr, fss[i].ToString())
+					} else {
+						require.NoError(t, err, fss[i].ToString())
+					}
+				} else if tt.wantErr != nil {
+					require.EqualError(t, err, tt.wantErr.Error(), fss[i].ToString())
+				} else {
+					require.NoError(t, err, fss[i].ToString())
+				}
+			}
+			require.NoError(t, errs[3])
+		})
+	}
+}
+
+func TestSnapshotBatch_empty(t *testing.T) {
+	fs, err := zfs.NewDatasetPath("pool/a")
+	require.NoError(t, err)
+
+	b := newSnapshotBatch(1)
+	b.Leave(t.Context(), fs)
+	b.Leave(t.Context(), fs)
+	select {
+	case <-b.done:
+	default:
+		t.Fatal("batch not done")
+	}
+}
@@ -0,0 +1,60 @@
+package snapper
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/cron/v3"
+)
+
+// cronSep separates cron specs of snapshotting with overrides.
+const cronSep = "; "
+
+// ParseCron parses cron spec of a job. It's a standard cron spec or a list of
+// them, separated by ";", for snapshotting with overrides. Returned schedule
+// fires at every time of any of them.
+func ParseCron(spec string) (cron.Schedule, error) {
+	specs := strings.Split(spec, ";")
+	schedules := make(anySchedule, 0, len(specs))
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		schedule, err := cron.ParseStandard(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse cron spec %q: %w", s, err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if len(schedules) == 1 {
+		return schedules[0], nil
+	}
+	return schedules, nil
+}
+
+// joinCron returns cron spec, which fires at every time of any of specs.
+// Duplicates and empty specs are ignored.
+func joinCron(specs ...string) string {
+	joined := make([]string, 0, len(specs))
+	for _, s := range specs {
+		if s != "" && !slices.Contains(joined, s) {
+			joined = append(joined, s)
+		}
+	}
+	return strings.Join(joined, cronSep)
+}
+
+// anySchedule is a schedule, which fires at every time of any of its
+// schedules.
+type anySchedule []cron.Schedule
+
+func (self anySchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, s := range self {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
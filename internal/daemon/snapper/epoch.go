@@ -0,0 +1,154 @@
+package snapper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// Epochs keeps epoch of snapshot names, which survives restarts of the daemon.
+// The epoch is embedded in names of snapshots and bumped on clock anomalies
+// or restored state of the daemon, so new names don't collide with existing
+// ones.
+type Epochs interface {
+	// Epoch returns the epoch at now. It's bumped and bumped is true, if now is
+	// before the latest time seen by the daemon, because the clock went
+	// backwards.
+	Epoch(now time.Time) (epoch uint64, bumped bool, err error)
+	// BumpEpoch bumps the epoch above seen, if it isn't already, and returns the
+	// epoch. bumped is true, if it was bumped.
+	BumpEpoch(seen uint64) (epoch uint64, bumped bool, err error)
+}
+
+var epochs = struct {
+	mu sync.Mutex
+	e  Epochs
+}{}
+
+// SetEpochs sets persistent epochs of snapshot names. Without them epoch is
+// always 0.
+func SetEpochs(e Epochs) {
+	epochs.mu.Lock()
+	epochs.e = e
+	epochs.mu.Unlock()
+}
+
+func getEpochs() Epochs {
+	epochs.mu.Lock()
+	defer epochs.mu.Unlock()
+	return epochs.e
+}
+
+// currentEpoch returns the epoch at now.
+func currentEpoch(ctx context.Context, now time.Time) (uint64, error) {
+	e := getEpochs()
+	if e == nil {
+		return 0, nil
+	}
+
+	epoch, bumped, err := e.Epoch(now)
+	if err != nil {
+		return 0, fmt.Errorf("get epoch of snapshot names: %w", err)
+	} else if bumped {
+		getLogger(ctx).With(slog.Uint64("epoch", epoch)).
+			Warn("bumped epoch of snapshot names, because clock went backwards")
+	}
+	return epoch, nil
+}
+
+// bumpEpoch bumps the epoch above seen and logs reason, if it was bumped. It
+// returns the epoch.
+func bumpEpoch(ctx context.Context, seen uint64, reason string) (uint64, error) {
+	e := getEpochs()
+	if e == nil {
+		return 0, nil
+	}
+
+	epoch, bumped, err := e.BumpEpoch(seen)
+	if err != nil {
+		return 0, fmt.Errorf("bump epoch of snapshot names: %w", err)
+	} else if bumped {
+		getLogger(ctx).With(slog.Uint64("epoch", epoch)).
+			Warn("bumped epoch of snapshot names, because " + reason)
+	}
+	return epoch, nil
+}
+
+// epochName returns name of epoch in snapshot names.
+func epochName(epoch uint64) string {
+	return "e" + strconv.FormatUint(epoch, 10)
+}
+
+// parseEpoch returns epoch of snapshot name with prefix, like
+// "zrepl_e3_20240305_070809_UTC", and true, or false if name has no epoch
+// after prefix.
+func parseEpoch(name, prefix string) (uint64, bool) {
+	s, ok := strings.CutPrefix(name, prefix+"e")
+	if !ok {
+		return 0, false
+	}
+	s, _, ok = strings.Cut(s, "_")
+	if !ok {
+		return 0, false
+	}
+	epoch, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return epoch, true
+}
+
+// checkEpoch bumps the epoch, if snapshots of fss with prefix have a later
+// epoch, because state of the daemon was restored from backup, or were taken in
+// the future, because the clock went backwards.
+func checkEpoch(ctx context.Context, fss []*zfs.DatasetPath, prefix string,
+	now time.Time,
+) error {
+	if getEpochs() == nil {
+		return nil
+	}
+
+	current, err := currentEpoch(ctx, now)
+	if err != nil {
+		return err
+	}
+	var future bool
+
+	for _, fs := range fss {
+		l := getLogger(ctx).With(slog.String("fs", fs.ToString()))
+		fsvs, err := zfs.ZFSListFilesystemVersions(ctx, fs,
+			zfs.ListFilesystemVersionsOptions{
+				Types:           zfs.Snapshots,
+				ShortnamePrefix: prefix,
+			})
+		if err != nil {
+			logger.WithError(l, err, "cannot check epoch of snapshots")
+			continue
+		}
+
+		for i := range fsvs {
+			v := &fsvs[i]
+			seen, ok := parseEpoch(v.Name, prefix)
+			switch {
+			case ok && seen >= current:
+				current, err = bumpEpoch(ctx, seen, fmt.Sprintf(
+					"snapshot %q has a later epoch", v.ToAbsPath(fs)))
+			case !future && v.Creation.After(now):
+				future = true
+				current, err = bumpEpoch(ctx, current, fmt.Sprintf(
+					"snapshot %q is from the future", v.ToAbsPath(fs)))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
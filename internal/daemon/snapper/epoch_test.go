@@ -0,0 +1,96 @@
+package snapper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEpochs struct {
+	epoch    uint64
+	lastSeen time.Time
+}
+
+func (self *testEpochs) Epoch(now time.Time) (uint64, bool, error) {
+	bumped := now.Before(self.lastSeen)
+	if bumped {
+		self.epoch++
+	}
+	self.lastSeen = now
+	return self.epoch, bumped, nil
+}
+
+func (self *testEpochs) BumpEpoch(seen uint64) (uint64, bool, error) {
+	if self.epoch > seen {
+		return self.epoch, false, nil
+	}
+	self.epoch = seen + 1
+	return self.epoch, true, nil
+}
+
+func TestEpoch(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+
+	epoch, err := currentEpoch(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, epoch)
+
+	e := &testEpochs{}
+	SetEpochs(e)
+	defer SetEpochs(nil)
+
+	epoch, err = currentEpoch(ctx, now)
+	require.NoError(t, err)
+	assert.Zero(t, epoch)
+
+	epoch, err = currentEpoch(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), epoch)
+
+	epoch, err = bumpEpoch(ctx, 3, "restored")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), epoch)
+
+	epoch, err = bumpEpoch(ctx, 2, "restored")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), epoch)
+
+	args := planArgs{prefix: "zrepl_", timestampFormat: "dense", epoch: true}
+	name, err := args.snapName(now, epoch, "zroot/home")
+	require.NoError(t, err)
+	assert.Equal(t, "zrepl_e4_20240305_070809_UTC", name)
+
+	tmpl, err := newNameTemplate("{{.Prefix}}{{.Epoch}}-{{.Time}}")
+	require.NoError(t, err)
+	args.nameTemplate = tmpl
+	name, err = args.snapName(now, epoch, "zroot/home")
+	require.NoError(t, err)
+	assert.Equal(t, "zrepl_4-20240305_070809_UTC", name)
+}
+
+func TestParseEpoch(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   uint64
+		wantOk bool
+	}{
+		{name: "zrepl_e3_20240305_070809_UTC", want: 3, wantOk: true},
+		{name: "zrepl_e12_1709622489", want: 12, wantOk: true},
+		{name: "zrepl_20240305_070809_UTC"},
+		{name: "zrepl_e_20240305_070809_UTC"},
+		{name: "zrepl_ex_20240305"},
+		{name: "foo_e3_20240305"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			epoch, ok := parseEpoch(tt.name, "zrepl_")
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, epoch)
+		})
+	}
+}
@@ -2,18 +2,20 @@ package snapper
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/dsh2dsh/zrepl/internal/daemon/hooks"
 	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
 	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/pressure"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -21,20 +23,52 @@ type planArgs struct {
 	prefix          string
 	timestampFormat string
 	timestampLocal  bool
-	hooks           hooks.List
-	concurrency     int
+	nameTemplate    *template.Template
+	// properties are templates of user properties, set on snapshots at
+	// creation.
+	properties  map[string]*template.Template
+	jobName     string
+	hooks       hooks.List
+	concurrency int
+	// epoch embeds epoch of snapshot names after prefix.
+	epoch bool
+	// stagger spreads starts of snapshots of filesystems evenly over the
+	// duration.
+	stagger time.Duration
+	// atomic creates snapshots of all filesystems at once, after all pre hooks.
+	atomic bool
 }
 
 type plan struct {
 	args  planArgs
 	snaps map[*zfs.DatasetPath]*progress
+	// fsArgs are args of filesystems, which can be changed by overrides.
+	fsArgs map[*zfs.DatasetPath]*planArgs
 
+	hookLists      []hooks.List
 	hookMatchCount map[hooks.Hook]int
+
+	// epoch of snapshot names.
+	epoch uint64
+	// batch creates snapshots atomically, if enabled.
+	batch *snapshotBatch
 }
 
 func makePlan(args planArgs, fss []*zfs.DatasetPath) *plan {
-	p := &plan{args: args}
-	return p.init(fss)
+	p := newPlan(args)
+	p.add(&p.args, fss)
+	return p
+}
+
+// newPlan returns empty plan, which uses concurrency of args. Filesystems are
+// added by add.
+func newPlan(args planArgs) *plan {
+	return &plan{
+		args:           args,
+		snaps:          make(map[*zfs.DatasetPath]*progress, 1),
+		fsArgs:         make(map[*zfs.DatasetPath]*planArgs, 1),
+		hookMatchCount: make(map[hooks.Hook]int, len(args.hooks)),
+	}
 }
 
 type SnapState uint
@@ -60,73 +94,144 @@ func (self SnapState) String() string {
 	return "SnapState(" + strconv.FormatInt(int64(self), 10) + ")"
 }
 
-func (self *plan) init(paths []*zfs.DatasetPath) *plan {
-	self.makeHooksCount()
-	self.makeSnapsProgress(paths)
-	return self
-}
-
-func (self *plan) makeHooksCount() {
-	self.hookMatchCount = make(map[hooks.Hook]int, len(self.args.hooks))
-	for _, h := range self.args.hooks {
+// add adds paths to the plan, which are snapshotted with args.
+func (self *plan) add(args *planArgs, paths []*zfs.DatasetPath) {
+	self.hookLists = append(self.hookLists, args.hooks)
+	for _, h := range args.hooks {
 		self.hookMatchCount[h] = 0
 	}
-}
 
-func (self *plan) makeSnapsProgress(paths []*zfs.DatasetPath) {
-	self.snaps = make(map[*zfs.DatasetPath]*progress, 1)
 	for _, fs := range paths {
 		parent := fs.RecursiveParent()
 		if parent == nil || parent.HasExcluded() {
 			self.snaps[fs] = NewProgress()
+			self.fsArgs[fs] = args
 		}
 	}
 }
 
-func (self *plan) snapName() string {
-	return fmt.Sprintf("%s%s", self.args.prefix,
-		self.formatNow(self.args.timestampFormat, self.args.timestampLocal))
+func (self *plan) argsOf(fs *zfs.DatasetPath) *planArgs {
+	if args, ok := self.fsArgs[fs]; ok {
+		return args
+	}
+	return &self.args
+}
+
+// snapName returns name of snapshot of fs and its properties.
+func (self *plan) snapName(fs *zfs.DatasetPath) (string, map[string]string,
+	error,
+) {
+	args, now := self.argsOf(fs), time.Now()
+	name, err := args.snapName(now, self.epoch, fs.ToString())
+	if err != nil {
+		return "", nil, err
+	}
+	props, err := args.snapProperties(now, self.epoch, fs.ToString(), name)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, props, nil
+}
+
+// check returns error, if snapshot name or properties can't be made from
+// args.
+func (self *planArgs) check() error {
+	name, err := self.snapName(time.Now(), 0, "pool/fs")
+	if err != nil {
+		return err
+	}
+	_, err = self.snapProperties(time.Now(), 0, "pool/fs", name)
+	return err
 }
 
-func (self *plan) formatNow(format string, localTime bool) string {
-	now := time.Now()
-	if !localTime {
+// snapName returns name of snapshot of fs taken at now in epoch, by
+// name_template, if configured, or by prefix, epoch, if enabled, and timestamp.
+func (self *planArgs) snapName(now time.Time, epoch uint64, fs string,
+) (string, error) {
+	if !self.timestampLocal {
 		now = now.UTC()
 	}
 
-	switch strings.ToLower(format) {
-	case "dense":
-		format = "20060102_150405_MST"
-	case "human":
-		format = "2006-01-02_15:04:05"
-	case "iso-8601":
-		format = "2006-01-02T15:04:05.000Z"
-	case "unix-seconds":
-		return strconv.FormatInt(now.Unix(), 10)
+	if self.nameTemplate == nil {
+		prefix := self.prefix
+		if self.epoch {
+			prefix += epochName(epoch) + "_"
+		}
+		return prefix + formatTime(now, self.timestampFormat), nil
 	}
-	return now.Format(format)
+	return executeNameTemplate(self.nameTemplate,
+		self.nameData(now, epoch, fs))
+}
+
+func (self *planArgs) nameData(now time.Time, epoch uint64, fs string,
+) *nameData {
+	return &nameData{
+		Prefix: self.prefix,
+		Time:   snapTime{Time: now, format: self.timestampFormat},
+		Job:    self.jobName,
+		FS:     fs,
+		Epoch:  epoch,
+	}
+}
+
+// snapProperties returns user properties of snapshot snapName of fs, taken at
+// now in epoch.
+func (self *planArgs) snapProperties(now time.Time, epoch uint64, fs,
+	snapName string,
+) (map[string]string, error) {
+	if len(self.properties) == 0 {
+		return nil, nil
+	} else if !self.timestampLocal {
+		now = now.UTC()
+	}
+	return executePropertyTemplates(self.properties, &propertyData{
+		nameData: *self.nameData(now, epoch, fs),
+		JobName:  self.jobName,
+		Snapshot: snapName,
+	})
 }
 
 func (self *plan) execute(ctx context.Context, dryRun bool) bool {
 	var anyFsHadErr bool
 	var g errgroup.Group
-	g.SetLimit(self.args.concurrency)
+	if self.args.atomic {
+		// every snapshot waits for all others, so they must run at once.
+		self.batch = newSnapshotBatch(len(self.snaps))
+	} else {
+		g.SetLimit(pressure.Concurrency(self.args.concurrency))
+	}
 
-	// TODO channel programs -> allow a little jitter?
+	begin, i := time.Now(), 0
 	for fs, progress := range self.snaps {
-		snapName := self.snapName()
+		if !dryRun && !self.staggerWait(ctx, begin, i) {
+			anyFsHadErr = true
+			break
+		}
+		i++
+
+		snapName, props, err := self.snapName(fs)
+		if err != nil {
+			logger.WithError(getLogger(ctx).With(slog.String("fs", fs.ToString())),
+				err, "cannot make snapshot name")
+			anyFsHadErr = true
+			progress.StateError()
+			self.leaveBatch(ctx, fs)
+			continue
+		}
 		ctx := logging.With(ctx, slog.String("fs", fs.ToString()),
 			slog.Bool("recursive", fs.RecursiveSnapshot()),
 			slog.String("snap", snapName))
 
-		hookPlan := self.hookPlan(ctx, fs, snapName)
+		hookPlan := self.hookPlan(ctx, fs, snapName, props)
 		if hookPlan == nil {
 			anyFsHadErr = true
 			progress.StateError()
+			self.leaveBatch(ctx, fs)
 			continue
 		}
 
 		g.Go(func() error {
+			defer self.leaveBatch(ctx, fs)
 			return progress.CreateSnapshot(ctx, dryRun, snapName, hookPlan)
 		})
 	}
@@ -138,10 +243,37 @@ func (self *plan) execute(ctx context.Context, dryRun bool) bool {
 	return !anyFsHadErr
 }
 
+// leaveBatch removes fs from the batch of atomic snapshots, if it didn't reach
+// its snapshot.
+func (self *plan) leaveBatch(ctx context.Context, fs *zfs.DatasetPath) {
+	if self.batch != nil {
+		self.batch.Leave(ctx, fs)
+	}
+}
+
+// staggerWait waits until start of i-th snapshot, spread evenly over stagger
+// from begin. It returns false, if ctx is done.
+func (self *plan) staggerWait(ctx context.Context, begin time.Time, i int,
+) bool {
+	if self.args.stagger <= 0 || i == 0 {
+		return true
+	}
+
+	d := self.args.stagger * time.Duration(i) / time.Duration(len(self.snaps))
+	t := time.NewTimer(time.Until(begin.Add(d)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		return false
+	}
+	return true
+}
+
 func (self *plan) hookPlan(ctx context.Context, fs *zfs.DatasetPath,
-	snapName string,
+	snapName string, props map[string]string,
 ) *hooks.Plan {
-	filteredHooks, err := self.args.hooks.CopyFilteredForFilesystem(fs)
+	filteredHooks, err := self.argsOf(fs).hooks.CopyFilteredForFilesystem(fs)
 	if err != nil {
 		logger.WithError(getLogger(ctx), err, "unexpected filter error")
 		return nil
@@ -151,13 +283,18 @@ func (self *plan) hookPlan(ctx context.Context, fs *zfs.DatasetPath,
 
 	jobCallback := hooks.NewCallbackHookForFilesystem("snapshot", fs,
 		func(ctx context.Context) error {
-			return createSnapshot(ctx, fs, snapName)
+			if self.batch != nil {
+				return self.batchSnapshot(ctx, fs, snapName, props)
+			}
+			return createSnapshot(ctx, fs, snapName, props)
 		})
 
 	hookPlan, err := hooks.NewPlan(filteredHooks, hooks.PhaseSnapshot,
 		jobCallback, map[string]string{
-			hooks.EnvFS:       fs.ToString(),
-			hooks.EnvSnapshot: snapName,
+			hooks.EnvFS:           fs.ToString(),
+			hooks.EnvSnapshot:     snapName,
+			hooks.EnvJob:          self.argsOf(fs).jobName,
+			hooks.EnvPrevSnapshot: self.prevSnapshot(ctx, fs, filteredHooks),
 		})
 	if err != nil {
 		logger.WithError(getLogger(ctx), err, "cannot create job hook plan")
@@ -166,11 +303,53 @@ func (self *plan) hookPlan(ctx context.Context, fs *zfs.DatasetPath,
 	return hookPlan
 }
 
+// prevSnapshot returns name of the latest snapshot of fs with the same prefix,
+// if fs has hooks, which may need it, or empty string.
+func (self *plan) prevSnapshot(ctx context.Context, fs *zfs.DatasetPath,
+	filteredHooks hooks.List,
+) string {
+	if len(filteredHooks) == 0 {
+		return ""
+	}
+
+	prev, err := latestSnapshot(ctx, fs, self.argsOf(fs).prefix)
+	if err != nil {
+		logger.WithError(getLogger(ctx), err, "cannot find previous snapshot")
+		return ""
+	} else if prev == nil {
+		return ""
+	}
+	return prev.Name
+}
+
 func createSnapshot(ctx context.Context, fs *zfs.DatasetPath, snapName string,
+	props map[string]string,
 ) error {
 	l := getLogger(ctx)
 	l.Debug("create snapshot")
-	err := zfs.ZFSSnapshot(ctx, fs, snapName, fs.RecursiveSnapshot())
+	err := zfs.ZFSSnapshot(ctx, fs, snapName, fs.RecursiveSnapshot(), props)
+	recordCreated(ctx, fs, snapName, err)
+	if err != nil {
+		logger.WithError(l, err, "cannot create snapshot")
+		return err
+	}
+	return nil
+}
+
+// batchSnapshot adds snapshot of fs into the batch and waits until all
+// snapshots of the batch are created.
+func (self *plan) batchSnapshot(ctx context.Context, fs *zfs.DatasetPath,
+	snapName string, props map[string]string,
+) error {
+	l := getLogger(ctx)
+	l.Debug("wait for atomic snapshot")
+	err := self.batch.Snapshot(ctx, zfs.AtomicSnapshot{
+		FS:        fs,
+		Name:      snapName,
+		Recursive: fs.RecursiveSnapshot(),
+		Props:     props,
+	})
+	recordCreated(ctx, fs, snapName, err)
 	if err != nil {
 		logger.WithError(l, err, "cannot create snapshot")
 		return err
@@ -178,6 +357,17 @@ func createSnapshot(ctx context.Context, fs *zfs.DatasetPath, snapName string,
 	return nil
 }
 
+func recordCreated(ctx context.Context, fs *zfs.DatasetPath, snapName string,
+	err error,
+) {
+	if fs.RecursiveSnapshot() {
+		lifecycle.Record(ctx, lifecycle.Created, fs.ToString(), snapName, err,
+			"recursive", "true")
+	} else {
+		lifecycle.Record(ctx, lifecycle.Created, fs.ToString(), snapName, err)
+	}
+}
+
 func (self *plan) countHooks(filteredHooks hooks.List) {
 	for _, h := range filteredHooks {
 		self.hookMatchCount[h]++
@@ -185,14 +375,19 @@ func (self *plan) countHooks(filteredHooks hooks.List) {
 }
 
 func (self *plan) logUnmatchedHooks(ctx context.Context) {
-	argsHooks := self.args.hooks.Slice()
 	l := getLogger(ctx)
 	for h, cnt := range self.hookMatchCount {
 		if cnt != 0 {
 			continue
 		}
-		hookIdx := slices.IndexFunc(argsHooks,
-			func(h2 *hooks.CommandHook) bool { return h2 == h })
+		var hookIdx int
+		for _, list := range self.hookLists {
+			hookIdx = slices.IndexFunc(list.Slice(),
+				func(h2 hooks.FilteredHook) bool { return h2 == h })
+			if hookIdx >= 0 {
+				break
+			}
+		}
 		l.With(slog.String("hook", h.String()),
 			slog.Int("hook_number", hookIdx+1)).
 			Warn("hook did not match any snapshotted filesystems")
@@ -0,0 +1,33 @@
+package snapper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestPlan_staggerWait(t *testing.T) {
+	fss := make([]*zfs.DatasetPath, 0, 4)
+	for _, name := range []string{"pool/a", "pool/b", "pool/c", "pool/d"} {
+		p, err := zfs.NewDatasetPath(name)
+		require.NoError(t, err)
+		fss = append(fss, p)
+	}
+	p := makePlan(planArgs{stagger: 40 * time.Millisecond}, fss)
+
+	ctx := context.Background()
+	begin := time.Now()
+	assert.True(t, p.staggerWait(ctx, begin, 0))
+	assert.Less(t, time.Since(begin), 10*time.Millisecond)
+	assert.True(t, p.staggerWait(ctx, begin, 2))
+	assert.GreaterOrEqual(t, time.Since(begin), 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, p.staggerWait(ctx, time.Now(), 3))
+}
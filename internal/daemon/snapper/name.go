@@ -0,0 +1,152 @@
+package snapper
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// newNameTemplate parses name_template of snapshots. Empty text returns nil
+// template, which means prefix and timestamp.
+func newNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New("name_template").Option("missingkey=error").
+		Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse name_template: %w", err)
+	}
+	return t, nil
+}
+
+// templateFuncs are functions of name_template and templates of properties.
+var templateFuncs = template.FuncMap{
+	"base": path.Base,
+	"replace": func(old, repl, s string) string {
+		return strings.ReplaceAll(s, old, repl)
+	},
+	"strftime": func(layout string, t snapTime) string {
+		return t.Strftime(layout)
+	},
+}
+
+// nameData is data of name_template.
+type nameData struct {
+	Prefix string
+	Time   snapTime
+	Job    string
+	FS     string
+	Epoch  uint64
+}
+
+// snapTime is time of a snapshot, which prints itself by timestamp_format.
+type snapTime struct {
+	time.Time
+
+	format string
+}
+
+func (self snapTime) String() string { return formatTime(self.Time, self.format) }
+
+// Strftime formats time by strftime(3) layout, like "%Y-%m-%d_%H%M".
+func (self snapTime) Strftime(layout string) string {
+	return strftime(self.Time, layout)
+}
+
+func executeNameTemplate(t *template.Template, data *nameData,
+) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("execute name_template: %w", err)
+	}
+
+	name := sb.String()
+	switch {
+	case !strings.HasPrefix(name, data.Prefix):
+		return "", fmt.Errorf("name_template: %q doesn't start with prefix %q",
+			name, data.Prefix)
+	case name == data.Prefix:
+		return "", fmt.Errorf("name_template: %q is the prefix only", name)
+	}
+
+	err := zfs.EntityNamecheck("pool@"+name, zfs.EntityTypeSnapshot)
+	if err != nil {
+		return "", fmt.Errorf("name_template: invalid snapshot name %q: %w",
+			name, err)
+	}
+	return name, nil
+}
+
+func formatTime(t time.Time, format string) string {
+	switch strings.ToLower(format) {
+	case "dense":
+		format = "20060102_150405_MST"
+	case "human":
+		format = "2006-01-02_15:04:05"
+	case "iso-8601":
+		format = "2006-01-02T15:04:05.000Z"
+	case "unix-seconds":
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(format)
+}
+
+// strftimeLayouts maps strftime(3) conversions to layouts of package time.
+var strftimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'F': "2006-01-02",
+	'h': "Jan",
+	'H': "15",
+	'I': "03",
+	'j': "002",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'S': "05",
+	'T': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// strftime formats t by strftime(3) layout. Besides conversions of
+// strftimeLayouts it supports %s (unix seconds) and %%. Unknown conversions are
+// kept as is.
+func strftime(t time.Time, layout string) string {
+	var sb strings.Builder
+	for i := 0; i < len(layout); i++ {
+		c := layout[i]
+		if c != '%' || i+1 == len(layout) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = layout[i]; c {
+		case '%':
+			sb.WriteByte('%')
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		default:
+			if l, ok := strftimeLayouts[c]; ok {
+				sb.WriteString(t.Format(l))
+			} else {
+				sb.WriteByte('%')
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
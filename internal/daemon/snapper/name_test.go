@@ -0,0 +1,91 @@
+package snapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanArgs_snapName(t *testing.T) {
+	now := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{name: "without template", want: "zrepl_20240305_070809_UTC"},
+		{
+			name:     "prefix and time",
+			template: "{{.Prefix}}{{.Time}}",
+			want:     "zrepl_20240305_070809_UTC",
+		},
+		{
+			name:     "legacy",
+			template: `{{.Prefix}}{{.FS | base}}_{{.Time.Strftime "%Y-%m-%d-%H%M"}}`,
+			want:     "zrepl_home_2024-03-05-0708",
+		},
+		{
+			name:     "pipelines",
+			template: `{{.Prefix}}{{.Job}}_{{.FS | replace "/" "-"}}_{{.Time | strftime "%j.%s"}}`,
+			want:     "zrepl_backup_zroot-usr-home_065.1709622489",
+		},
+		{
+			name:     "go layout",
+			template: `{{.Prefix}}{{.Time.Format "2006.01.02"}}`,
+			want:     "zrepl_2024.03.05",
+		},
+		{
+			name:     "without prefix",
+			template: "auto_{{.Time}}",
+			wantErr:  "doesn't start with prefix",
+		},
+		{
+			name:     "prefix only",
+			template: "{{.Prefix}}",
+			wantErr:  "prefix only",
+		},
+		{
+			name:     "invalid name",
+			template: "{{.Prefix}}{{.FS}}",
+			wantErr:  "invalid snapshot name",
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Prefix}}{{.Host}}",
+			wantErr:  "execute name_template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newNameTemplate(tt.template)
+			require.NoError(t, err)
+			args := planArgs{
+				prefix:          "zrepl_",
+				timestampFormat: "dense",
+				nameTemplate:    tmpl,
+				jobName:         "backup",
+			}
+
+			name, err := args.snapName(now, 0, "zroot/usr/home")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+
+	_, err := newNameTemplate("{{.Prefix")
+	require.ErrorContains(t, err, "parse name_template")
+}
+
+func TestStrftime(t *testing.T) {
+	now := time.Date(2024, 3, 5, 17, 8, 9, 0, time.UTC)
+	assert.Equal(t, "Tue Mar 2024-03-05 05PM 17:08:09 +0000 UTC %q %",
+		strftime(now, "%a %b %F %I%p %T %z %Z %q %"))
+}
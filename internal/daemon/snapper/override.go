@@ -0,0 +1,168 @@
+package snapper
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dsh2dsh/cron/v3"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/daemon/hooks"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// group is a group of filesystems of periodic snapshotting with its own
+// prefix, cron or hooks. The last group of a snapper is its base group, which
+// contains filesystems not matched by overrides.
+type group struct {
+	// filter of filesystems of the group, nil for the base group.
+	filter   *filters.DatasetFilter
+	planArgs planArgs
+	// schedule of the group, nil means every invocation.
+	schedule cron.Schedule
+	// last is the last invocation, which snapshotted the group.
+	last time.Time
+}
+
+// groupsFromConfig returns groups of overrides and the base group with base
+// args, and cron spec of the snapper, which fires for all of them.
+func groupsFromConfig(base planArgs, in *config.SnapshottingPeriodic,
+	now time.Time,
+) ([]*group, string, error) {
+	groups := make([]*group, 0, len(in.Overrides)+1)
+	specs := []string{in.CronSpec()}
+	var scheduled bool
+
+	for i := range in.Overrides {
+		o := &in.Overrides[i]
+		g, err := overrideFromConfig(base, o)
+		if err != nil {
+			return nil, "", fmt.Errorf("override #%d: %w", i+1, err)
+		}
+		if o.Cron != "" {
+			if in.Cron == "" {
+				return nil, "", fmt.Errorf(
+					"override #%d: cron requires cron of snapshotting", i+1)
+			}
+			g.schedule, err = parseSpecSchedule(o.Cron)
+			if err != nil {
+				return nil, "", fmt.Errorf("override #%d: %w", i+1, err)
+			}
+			g.last = now
+			specs = append(specs, o.Cron)
+			scheduled = true
+		}
+		groups = append(groups, g)
+	}
+
+	baseGroup := &group{planArgs: base}
+	if scheduled {
+		schedule, err := parseSpecSchedule(in.Cron)
+		if err != nil {
+			return nil, "", err
+		}
+		baseGroup.schedule, baseGroup.last = schedule, now
+		// overrides without cron share schedule of the base group.
+		for _, g := range groups {
+			if g.schedule == nil {
+				g.schedule, g.last = schedule, now
+			}
+		}
+	}
+	return append(groups, baseGroup), joinCron(specs...), nil
+}
+
+func overrideFromConfig(base planArgs, in *config.SnapshottingOverride,
+) (*group, error) {
+	filter, err := filters.NewFromConfig(in.Filesystems, in.Datasets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filesystems: %w", err)
+	}
+
+	g := &group{filter: filter, planArgs: base}
+	if in.Prefix != "" {
+		g.planArgs.prefix = in.Prefix
+	}
+	if in.Hooks != nil {
+		hookList, err := hooks.ListFromConfig(in.Hooks)
+		if err != nil {
+			return nil, fmt.Errorf("hook config error: %w", err)
+		}
+		g.planArgs.hooks = hookList
+	}
+
+	if err := g.planArgs.check(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// parseSpecSchedule parses cron spec of a group. Schedules like "@every 1h"
+// fire relative to their registration, not to wall clock, and can't be
+// matched with invocations of the job.
+func parseSpecSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse cron spec %q: %w", spec, err)
+	} else if _, ok := schedule.(*cron.SpecSchedule); !ok {
+		return nil, fmt.Errorf("cron spec %q of overrides must be wall clock based",
+			spec)
+	}
+	return schedule, nil
+}
+
+// groupFilesystems returns filesystems of fss by groups. Every filesystem
+// belongs to the first matching group.
+func groupFilesystems(groups []*group, fss []*zfs.DatasetPath,
+) ([][]*zfs.DatasetPath, error) {
+	grouped := make([][]*zfs.DatasetPath, len(groups))
+	for _, fs := range fss {
+		for i, g := range groups {
+			if g.filter == nil {
+				grouped[i] = append(grouped[i], fs)
+				break
+			}
+			ok, err := g.filter.Filter(fs)
+			if err != nil {
+				return nil, fmt.Errorf("filter %q: %w", fs.ToString(), err)
+			} else if ok {
+				grouped[i] = append(grouped[i], fs)
+				break
+			}
+		}
+	}
+	return grouped, nil
+}
+
+// dueGroups returns which of groups are due at now and marks them snapshotted
+// at now. If none of them is due, all of them are, because the job was woken
+// up explicitly.
+func dueGroups(groups []*group, now time.Time) []bool {
+	due := make([]bool, len(groups))
+	var anyDue bool
+	for i, g := range groups {
+		due[i] = g.due(now)
+		anyDue = anyDue || due[i]
+	}
+
+	for i, g := range groups {
+		if !anyDue {
+			due[i] = true
+		}
+		if due[i] {
+			g.last = now
+		}
+	}
+	return due
+}
+
+// due returns true, if the group is scheduled between its last invocation and
+// now. A cron job may start a little bit earlier, than scheduled.
+func (self *group) due(now time.Time) bool {
+	if self.schedule == nil {
+		return true
+	}
+	next := self.schedule.Next(self.last)
+	return !next.IsZero() && !next.After(now.Add(time.Second))
+}
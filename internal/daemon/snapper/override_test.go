@@ -0,0 +1,118 @@
+package snapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestGroupsFromConfig(t *testing.T) {
+	now := time.Date(2024, 3, 5, 7, 2, 0, 0, time.Local)
+	in := &config.SnapshottingPeriodic{
+		Type:            "cron",
+		Prefix:          "zrepl_",
+		Cron:            "0 * * * *",
+		TimestampFormat: "dense",
+		Overrides: []config.SnapshottingOverride{
+			{
+				Filesystems: config.FilesystemsFilter{"pool/mail<": true},
+				Prefix:      "zrepl_mail_",
+				Cron:        "*/5 * * * *",
+			},
+			{
+				Filesystems: config.FilesystemsFilter{"pool/db": true},
+				Hooks:       []config.HookCommand{},
+			},
+		},
+	}
+	base := planArgs{prefix: in.Prefix, timestampFormat: in.TimestampFormat}
+
+	groups, cronSpec, err := groupsFromConfig(base, in, now)
+	require.NoError(t, err)
+	assert.Equal(t, "0 * * * *; */5 * * * *", cronSpec)
+	require.Len(t, groups, 3)
+	assert.Equal(t, "zrepl_mail_", groups[0].planArgs.prefix)
+	assert.Equal(t, "zrepl_", groups[1].planArgs.prefix)
+	assert.Nil(t, groups[2].filter)
+
+	fss := make([]*zfs.DatasetPath, 0, 4)
+	for _, name := range []string{"pool/db", "pool/home", "pool/mail", "pool/mail/a"} {
+		p, err := zfs.NewDatasetPath(name)
+		require.NoError(t, err)
+		fss = append(fss, p)
+	}
+	grouped, err := groupFilesystems(groups, fss)
+	require.NoError(t, err)
+	assert.Equal(t, []*zfs.DatasetPath{fss[2], fss[3]}, grouped[0])
+	assert.Equal(t, []*zfs.DatasetPath{fss[0]}, grouped[1])
+	assert.Equal(t, []*zfs.DatasetPath{fss[1]}, grouped[2])
+
+	assert.Equal(t, []bool{true, false, false},
+		dueGroups(groups, now.Add(3*time.Minute)))
+	assert.Equal(t, []bool{true, false, false},
+		dueGroups(groups, now.Add(8*time.Minute)))
+	assert.Equal(t, []bool{true, true, true},
+		dueGroups(groups, now.Add(58*time.Minute)))
+	// explicit wakeup snapshots all groups
+	assert.Equal(t, []bool{true, true, true},
+		dueGroups(groups, now.Add(59*time.Minute)))
+}
+
+func TestGroupsFromConfig_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      config.SnapshottingPeriodic
+		wantErr string
+	}{
+		{
+			name: "override cron without cron",
+			in: config.SnapshottingPeriodic{
+				Prefix: "zrepl_",
+				Overrides: []config.SnapshottingOverride{{
+					Filesystems: config.FilesystemsFilter{"pool/mail": true},
+					Cron:        "*/5 * * * *",
+				}},
+			},
+			wantErr: "cron requires cron of snapshotting",
+		},
+		{
+			name: "override cron every",
+			in: config.SnapshottingPeriodic{
+				Prefix: "zrepl_",
+				Cron:   "0 * * * *",
+				Overrides: []config.SnapshottingOverride{{
+					Filesystems: config.FilesystemsFilter{"pool/mail": true},
+					Cron:        "@every 5m",
+				}},
+			},
+			wantErr: "must be wall clock based",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := groupsFromConfig(planArgs{prefix: tt.in.Prefix}, &tt.in,
+				time.Now())
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	schedule, err := ParseCron("0 * * * *; */5 * * * *")
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 5, 7, 2, 0, 0, time.Local)
+	assert.Equal(t, now.Add(3*time.Minute), schedule.Next(now))
+
+	schedule, err = ParseCron("0 * * * *")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(58*time.Minute), schedule.Next(now))
+
+	_, err = ParseCron("0 * * * *; foo")
+	require.Error(t, err)
+}
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"runtime"
 	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dsh2dsh/cron/v3"
@@ -25,7 +27,7 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
-func periodicFromConfig(fsf *filters.DatasetFilter,
+func periodicFromConfig(jobName string, fsf *filters.DatasetFilter,
 	in *config.SnapshottingPeriodic,
 ) (*Periodic, error) {
 	if in.Prefix == "" {
@@ -49,6 +51,16 @@ func periodicFromConfig(fsf *filters.DatasetFilter,
 		return nil, fmt.Errorf("hook config error: %w", err)
 	}
 
+	nameTemplate, err := newNameTemplate(in.NameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	properties, err := newPropertyTemplates(in.Properties)
+	if err != nil {
+		return nil, err
+	}
+
 	concurrency := int(in.Concurrency)
 	if concurrency < 1 {
 		concurrency = runtime.GOMAXPROCS(0)
@@ -63,16 +75,35 @@ func periodicFromConfig(fsf *filters.DatasetFilter,
 				prefix:          in.Prefix,
 				timestampFormat: in.TimestampFormat,
 				timestampLocal:  in.TimestampLocal,
+				nameTemplate:    nameTemplate,
+				properties:      properties,
+				jobName:         jobName,
 				hooks:           hookList,
 				concurrency:     concurrency,
+				epoch:           in.Epoch,
+				stagger:         in.Stagger,
+				atomic:          in.Atomic,
 			},
 			writtenThreshold: in.WrittenThreshold,
+			skipUnchanged:    in.SkipUnchanged,
+			jitter:           in.Jitter,
+			epochChecked:     new(atomic.Bool),
 			// ctx and log is set in Run()
 		},
 
 		state:     Stopped,
 		nextState: Planning,
 	}
+
+	if err := s.args.planArgs.check(); err != nil {
+		return nil, err
+	}
+
+	groups, cronSpec, err := groupsFromConfig(s.args.planArgs, in, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.args.groups, s.cronSpec = groups, cronSpec
 	return s.init(), nil
 }
 
@@ -82,6 +113,14 @@ type periodicArgs struct {
 	fsf              *filters.DatasetFilter
 	planArgs         planArgs
 	writtenThreshold uint64
+	skipUnchanged    bool
+	// jitter is max random delay of snapshotting.
+	jitter time.Duration
+
+	// groups of filesystems by overrides, the last one is the base group.
+	groups []*group
+	// epochChecked is true, when epoch of existing snapshots was checked.
+	epochChecked *atomic.Bool
 }
 
 type Periodic struct {
@@ -203,7 +242,7 @@ func periodicStateSyncUp(a periodicArgs, u updater) state {
 		return onErr(err, u)
 	}
 
-	syncPoint, err := findSyncPoint(a.ctx, fss, a.planArgs.prefix, a.interval)
+	syncPoint, err := a.findSyncPoint(fss)
 	if err != nil {
 		return onErr(err, u)
 	}
@@ -231,28 +270,93 @@ func periodicStateSyncUp(a periodicArgs, u updater) state {
 	return u(func(self *Periodic) { self.state = Planning }).sf()
 }
 
+// sleepJitter sleeps a random duration up to jitter, so snapshots of many jobs
+// with the same schedule don't start at the same second. It returns false, if
+// ctx is done. Wakeup signal stops sleeping.
+func (a *periodicArgs) sleepJitter() bool {
+	if a.jitter <= 0 {
+		return true
+	}
+
+	d := rand.N(a.jitter)
+	getLogger(a.ctx).With(slog.Duration("duration", d.Truncate(time.Millisecond))).
+		Info("jitter snapshotting")
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-signal.WakeupFrom(a.ctx).Done():
+	case <-a.ctx.Done():
+		return false
+	}
+	return true
+}
+
+// findSyncPoint returns the earliest sync point of groups of fss.
+func (a *periodicArgs) findSyncPoint(fss []*zfs.DatasetPath) (time.Time, error) {
+	grouped, err := groupFilesystems(a.groups, fss)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var syncPoint time.Time
+	for i, g := range a.groups {
+		if len(grouped[i]) == 0 {
+			continue
+		}
+		groupSyncPoint, err := findSyncPoint(a.ctx, grouped[i],
+			g.planArgs.prefix, a.interval)
+		if err != nil {
+			return time.Time{}, err
+		} else if syncPoint.IsZero() || groupSyncPoint.Before(syncPoint) {
+			syncPoint = groupSyncPoint
+		}
+	}
+	if syncPoint.IsZero() {
+		return time.Now(), nil
+	}
+	return syncPoint, nil
+}
+
 func periodicStatePlan(a periodicArgs, u updater) state {
 	u(func(self *Periodic) { self.lastInvocation = time.Now() })
+	if !a.sleepJitter() {
+		return onMainCtxDone(a.ctx, u)
+	}
+
+	now := time.Now()
 
 	fss, err := zfs.ZFSListMapping(a.ctx, a.fsf)
 	if err != nil {
 		return onErr(err, u)
 	}
 
-	if a.writtenThreshold != 0 {
-		log := getLogger(a.ctx)
-		fss = slices.DeleteFunc(fss, func(p *zfs.DatasetPath) bool {
-			if p.Written() < a.writtenThreshold {
-				log.Info("skip snapshotting, because 'written' below threshold",
-					slog.String("fs", p.ToString()),
-					slog.Uint64("written", p.Written()),
-					slog.Uint64("threshold", a.writtenThreshold))
-				return true
-			}
-			return false
-		})
+	grouped, err := groupFilesystems(a.groups, fss)
+	if err != nil {
+		return onErr(err, u)
+	}
+
+	p := newPlan(a.planArgs)
+	if a.planArgs.epoch {
+		if p.epoch, err = a.checkEpoch(grouped, now); err != nil {
+			return onErr(err, u)
+		}
+	}
+
+	for i, due := range dueGroups(a.groups, now) {
+		g := a.groups[i]
+		if !due {
+			getLogger(a.ctx).With(slog.String("prefix", g.planArgs.prefix),
+				slog.Int("count", len(grouped[i]))).
+				Info("skip snapshotting of filesystems, because their cron isn't due")
+			continue
+		}
+		fss := skipWritten(a.ctx, grouped[i], a.writtenThreshold)
+		if a.skipUnchanged {
+			fss = skipUnchanged(a.ctx, fss, g.planArgs.prefix)
+		}
+		p.add(&g.planArgs, fss)
 	}
-	p := makePlan(a.planArgs, fss)
 
 	return u(func(self *Periodic) {
 		self.state = Snapshotting
@@ -279,6 +383,101 @@ func periodicStateSnapshot(a periodicArgs, u updater) state {
 	}).sf()
 }
 
+// checkEpoch checks epoch of existing snapshots of grouped filesystems, if it
+// wasn't checked yet, and returns the epoch at now.
+func (a *periodicArgs) checkEpoch(grouped [][]*zfs.DatasetPath, now time.Time,
+) (uint64, error) {
+	if !a.epochChecked.Load() {
+		for i, g := range a.groups {
+			err := checkEpoch(a.ctx, grouped[i], g.planArgs.prefix, now)
+			if err != nil {
+				return 0, err
+			}
+		}
+		a.epochChecked.Store(true)
+	}
+	return currentEpoch(a.ctx, now)
+}
+
+// skipWritten returns fss without datasets, which property written is below
+// threshold.
+func skipWritten(ctx context.Context, fss []*zfs.DatasetPath, threshold uint64,
+) []*zfs.DatasetPath {
+	if threshold == 0 {
+		return fss
+	}
+
+	log := getLogger(ctx)
+	return slices.DeleteFunc(fss, func(p *zfs.DatasetPath) bool {
+		if p.Written() < threshold {
+			log.Info("skip snapshotting, because 'written' below threshold",
+				slog.String("fs", p.ToString()),
+				slog.Uint64("written", p.Written()),
+				slog.Uint64("threshold", threshold))
+			return true
+		}
+		return false
+	})
+}
+
+// skipUnchanged returns fss without datasets, which have no changes since
+// their latest snapshot with prefix.
+//
+// Property written counts changes since the latest snapshot of any prefix, so
+// datasets with written > 0 have changes since the latest snapshot with prefix
+// too. Only datasets with written = 0 are checked with written@snapshot,
+// because a snapshot of another tool could be taken after the latest snapshot
+// with prefix.
+func skipUnchanged(ctx context.Context, fss []*zfs.DatasetPath, prefix string,
+) []*zfs.DatasetPath {
+	log := getLogger(ctx)
+	return slices.DeleteFunc(fss, func(p *zfs.DatasetPath) bool {
+		if p.Written() != 0 {
+			return false
+		}
+		l := log.With(slog.String("fs", p.ToString()))
+
+		latest, err := latestSnapshot(ctx, p, prefix)
+		if err != nil {
+			logger.WithError(l, err, "cannot check changes, snapshot anyway")
+			return false
+		} else if latest == nil {
+			return false
+		}
+
+		written, err := zfs.ZFSGetWrittenSince(ctx, p.ToString(), latest.Name)
+		if err != nil {
+			logger.WithError(l, err, "cannot check changes, snapshot anyway")
+			return false
+		} else if written != 0 {
+			return false
+		}
+		l.With(slog.String("snapshot", latest.Name)).
+			Info("skip snapshotting, because no changes since latest snapshot")
+		return true
+	})
+}
+
+// latestSnapshot returns the latest snapshot of d with prefix or nil.
+func latestSnapshot(ctx context.Context, d *zfs.DatasetPath, prefix string,
+) (*zfs.FilesystemVersion, error) {
+	fsvs, err := zfs.ZFSListFilesystemVersions(ctx, d,
+		zfs.ListFilesystemVersionsOptions{
+			Types:           zfs.Snapshots,
+			ShortnamePrefix: prefix,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("list filesystem versions: %w", err)
+	} else if len(fsvs) == 0 {
+		return nil, nil
+	}
+
+	latest := slices.MaxFunc(fsvs, func(a, b zfs.FilesystemVersion) int {
+		return cmp.Compare(a.CreateTXG, b.CreateTXG)
+	})
+	return &latest, nil
+}
+
 // see docs/snapshotting.rst
 func findSyncPoint(ctx context.Context, fss []*zfs.DatasetPath, prefix string,
 	interval time.Duration,
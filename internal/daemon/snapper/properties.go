@@ -0,0 +1,65 @@
+package snapper
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+// newPropertyTemplates parses values of user properties, which are set on
+// snapshots at creation. Every value is a text/template, like "{{.JobName}}".
+func newPropertyTemplates(in map[string]string,
+) (map[string]*template.Template, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	templates := make(map[string]*template.Template, len(in))
+	for name, text := range in {
+		if err := zfsprop.Property(name).Validate(); err != nil {
+			return nil, fmt.Errorf("property %q: %w", name, err)
+		} else if !strings.Contains(name, ":") {
+			return nil, fmt.Errorf(
+				"property %q: only user properties, like \"zrepl:job\", are allowed",
+				name)
+		}
+
+		t, err := template.New(name).Option("missingkey=error").
+			Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parse template of property %q: %w", name, err)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+// propertyData is data of templates of properties.
+type propertyData struct {
+	nameData
+
+	JobName  string
+	Snapshot string
+}
+
+// executePropertyTemplates returns values of properties of snapshot snapName.
+func executePropertyTemplates(templates map[string]*template.Template,
+	data *propertyData,
+) (map[string]string, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	props := make(map[string]string, len(templates))
+	for name, t := range templates {
+		var sb strings.Builder
+		if err := t.Execute(&sb, data); err != nil {
+			return nil, fmt.Errorf("execute template of property %q: %w", name,
+				err)
+		}
+		props[name] = sb.String()
+	}
+	return props, nil
+}
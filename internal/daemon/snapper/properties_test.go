@@ -0,0 +1,75 @@
+package snapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanArgs_snapProperties(t *testing.T) {
+	props, err := newPropertyTemplates(map[string]string{
+		"zrepl:job":      "{{.JobName}}",
+		"zrepl:snapshot": "{{.FS}}@{{.Snapshot}}",
+		"zrepl:date":     `{{.Time.Strftime "%Y-%m-%d"}}`,
+	})
+	require.NoError(t, err)
+
+	args := planArgs{
+		prefix:          "zrepl_",
+		timestampFormat: "dense",
+		jobName:         "backup",
+		properties:      props,
+	}
+	now := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	got, err := args.snapProperties(now, 0, "zroot/home",
+		"zrepl_20240305_070809_UTC")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"zrepl:job":      "backup",
+		"zrepl:snapshot": "zroot/home@zrepl_20240305_070809_UTC",
+		"zrepl:date":     "2024-03-05",
+	}, got)
+
+	args.properties = nil
+	got, err = args.snapProperties(now, 0, "zroot/home", "zrepl_1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestNewPropertyTemplates_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		props   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "native property",
+			props:   map[string]string{"compression": "off"},
+			wantErr: "only user properties",
+		},
+		{
+			name:    "invalid name",
+			props:   map[string]string{"zrepl job": "x"},
+			wantErr: `property "zrepl job"`,
+		},
+		{
+			name:    "parse",
+			props:   map[string]string{"zrepl:job": "{{.JobName"},
+			wantErr: "parse template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newPropertyTemplates(tt.props)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	props, err := newPropertyTemplates(map[string]string{"zrepl:x": "{{.Host}}"})
+	require.NoError(t, err)
+	args := planArgs{prefix: "zrepl_", timestampFormat: "dense", properties: props}
+	require.ErrorContains(t, args.check(), "execute template of property")
+}
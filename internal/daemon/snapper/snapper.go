@@ -74,12 +74,12 @@ func (self *Report) Progress() (uint64, uint64) {
 	return 0, 0
 }
 
-func FromConfig(g *config.Global, fsf *filters.DatasetFilter,
+func FromConfig(g *config.Global, jobName string, fsf *filters.DatasetFilter,
 	in config.SnapshottingEnum,
 ) (Snapper, error) {
 	switch v := in.Ret.(type) {
 	case *config.SnapshottingPeriodic:
-		return periodicFromConfig(fsf, v)
+		return periodicFromConfig(jobName, fsf, v)
 	case *config.SnapshottingManual:
 		return &manual{}, nil
 	default:
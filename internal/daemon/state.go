@@ -0,0 +1,216 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/snapper"
+)
+
+// loadDaemonState reads daemon state from path. Missing file means empty
+// state.
+func loadDaemonState(path string) (*daemonState, error) {
+	s := &daemonState{path: path, disabled: make(map[string]struct{})}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read daemon state: %w", err)
+	}
+
+	var stored storedDaemonState
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("unmarshal daemon state from %q: %w", path, err)
+	}
+	for _, name := range stored.Disabled {
+		s.disabled[name] = struct{}{}
+	}
+	s.epoch, s.lastSeen = stored.Epoch, stored.LastSeen
+	s.skipped = stored.Skipped
+	return s, nil
+}
+
+// daemonState is the state of the daemon, which survives its restarts.
+type daemonState struct {
+	path     string
+	disabled map[string]struct{}
+	mu       sync.Mutex
+
+	epoch    uint64
+	lastSeen time.Time
+
+	// skipped are filesystems of jobs, temporary excluded from replication by
+	// zrepl skip, with time, when they're included back.
+	skipped map[string]map[string]time.Time
+}
+
+type storedDaemonState struct {
+	Disabled []string `json:"disabled,omitempty"`
+	// Epoch of snapshot names.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// LastSeen is the latest time, when epoch was requested.
+	LastSeen time.Time `json:"last_seen,omitzero"`
+	// Skipped filesystems of jobs and their expiration time.
+	Skipped map[string]map[string]time.Time `json:"skipped,omitempty"`
+}
+
+// Disabled returns true if job name was disabled by zrepl job disable.
+func (self *daemonState) Disabled(name string) bool {
+	if self == nil {
+		return false
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	_, ok := self.disabled[name]
+	return ok
+}
+
+// SetDisabled disables or enables job name and saves the state.
+func (self *daemonState) SetDisabled(name string, disabled bool) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if _, ok := self.disabled[name]; ok == disabled {
+		return nil
+	} else if disabled {
+		self.disabled[name] = struct{}{}
+	} else {
+		delete(self.disabled, name)
+	}
+	return self.save()
+}
+
+// Skipped returns not expired skipped filesystems of job name with their
+// expiration time.
+func (self *daemonState) Skipped(name string) map[string]time.Time {
+	if self == nil {
+		return nil
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	now := time.Now()
+	skipped := make(map[string]time.Time, len(self.skipped[name]))
+	for fs, until := range self.skipped[name] {
+		if now.Before(until) {
+			skipped[fs] = until
+		}
+	}
+	return skipped
+}
+
+// SetSkipped skips filesystem fs of job name until until and saves the state.
+// Zero or past until removes fs.
+func (self *daemonState) SetSkipped(name, fs string, until time.Time) error {
+	if self == nil {
+		return nil
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if time.Now().Before(until) {
+		if self.skipped == nil {
+			self.skipped = make(map[string]map[string]time.Time)
+		}
+		if self.skipped[name] == nil {
+			self.skipped[name] = make(map[string]time.Time)
+		}
+		self.skipped[name][fs] = until
+	} else if _, ok := self.skipped[name][fs]; ok {
+		delete(self.skipped[name], fs)
+	} else {
+		return nil
+	}
+	return self.save()
+}
+
+// pruneSkipped removes expired skipped filesystems.
+func (self *daemonState) pruneSkipped(now time.Time) {
+	for name, filesystems := range self.skipped {
+		for fs, until := range filesystems {
+			if !now.Before(until) {
+				delete(filesystems, fs)
+			}
+		}
+		if len(filesystems) == 0 {
+			delete(self.skipped, name)
+		}
+	}
+}
+
+// epochClockSkew is how far the clock can go backwards without bumping the
+// epoch. Snapshot names have seconds resolution at most.
+const epochClockSkew = time.Second
+
+var _ snapper.Epochs = (*daemonState)(nil)
+
+// Epoch returns epoch of snapshot names at now and saves now as the latest seen
+// time. The epoch is bumped, if now is before the latest seen time, because the
+// clock went backwards.
+func (self *daemonState) Epoch(now time.Time) (uint64, bool, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	var bumped bool
+	if now.Add(epochClockSkew).Before(self.lastSeen) {
+		self.epoch++
+		bumped = true
+	}
+	if bumped || now.After(self.lastSeen) {
+		self.lastSeen = now
+		if err := self.save(); err != nil {
+			return 0, false, err
+		}
+	}
+	return self.epoch, bumped, nil
+}
+
+// BumpEpoch bumps epoch of snapshot names above seen, if it isn't already, and
+// saves the state.
+func (self *daemonState) BumpEpoch(seen uint64) (uint64, bool, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.epoch > seen {
+		return self.epoch, false, nil
+	}
+	self.epoch = seen + 1
+	if err := self.save(); err != nil {
+		return 0, false, err
+	}
+	return self.epoch, true, nil
+}
+
+func (self *daemonState) save() error {
+	self.pruneSkipped(time.Now())
+	stored := storedDaemonState{
+		Disabled: make([]string, 0, len(self.disabled)),
+		Epoch:    self.epoch,
+		LastSeen: self.lastSeen,
+		Skipped:  self.skipped,
+	}
+	for name := range self.disabled {
+		stored.Disabled = append(stored.Disabled, name)
+	}
+	slices.Sort(stored.Disabled)
+
+	b, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("marshal daemon state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(self.path), 0o755); err != nil {
+		return fmt.Errorf("save daemon state: %w", err)
+	}
+	tmp := self.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("save daemon state: %w", err)
+	} else if err := os.Rename(tmp, self.path); err != nil {
+		return fmt.Errorf("save daemon state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonState_skipped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := loadDaemonState(path)
+	require.NoError(t, err)
+	assert.Empty(t, s.Skipped("job1"))
+
+	until := time.Now().Add(24 * time.Hour).Round(0)
+	require.NoError(t, s.SetSkipped("job1", "zroot/a", until))
+	require.NoError(t, s.SetSkipped("job1", "zroot/b", until))
+	require.NoError(t, s.SetSkipped("job2", "zroot/c", until))
+
+	// restart of the daemon
+	s, err = loadDaemonState(path)
+	require.NoError(t, err)
+	assert.Len(t, s.Skipped("job1"), 2)
+	assert.True(t, until.Equal(s.Skipped("job1")["zroot/a"]))
+	assert.Len(t, s.Skipped("job2"), 1)
+
+	require.NoError(t, s.SetSkipped("job1", "zroot/b", time.Time{}))
+	s, err = loadDaemonState(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zroot/a"},
+		slices.Sorted(maps.Keys(s.Skipped("job1"))))
+}
+
+func TestDaemonState_skippedExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := loadDaemonState(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetSkipped("job1", "zroot/a",
+		time.Now().Add(10*time.Millisecond)))
+	require.NoError(t, s.SetSkipped("job2", "zroot/b", time.Now().Add(time.Hour)))
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, s.Skipped("job1"), "expired")
+
+	// any save prunes expired filesystems
+	require.NoError(t, s.SetDisabled("job3", true))
+	s, err = loadDaemonState(path)
+	require.NoError(t, err)
+	assert.NotContains(t, s.skipped, "job1")
+	assert.Len(t, s.Skipped("job2"), 1)
+}
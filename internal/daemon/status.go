@@ -13,6 +13,8 @@ type Status struct {
 type GlobalStatus struct {
 	ZFSCmds   *zfscmd.Report
 	OsEnviron []string
+	// AbstractionsGC is the report of abstractions gc, if it's enabled.
+	AbstractionsGC *AbstractionsGCReport `json:",omitempty"`
 }
 
 func (self *Status) JobCounts() (running, withErr int) {
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	statusWatchInterval   = time.Second
+	statusWatchMaxTimeout = time.Minute
+)
+
+// StatusUpdate is a response of ControlJobEndpointStatusWatch. Version changes
+// every time Status changes.
+type StatusUpdate struct {
+	Version uint64
+	Status  *Status
+}
+
+type statusWatchRequest struct {
+	// Version of the status, the client already has. Zero means the client has
+	// nothing yet.
+	Version uint64
+	// Timeout of waiting for a new version.
+	Timeout time.Duration
+}
+
+func newStatusWatcher(status func(ctx context.Context) (*Status, error),
+) *statusWatcher {
+	return &statusWatcher{
+		status:   status,
+		interval: statusWatchInterval,
+		changed:  make(chan struct{}),
+	}
+}
+
+// statusWatcher builds the status once per interval, while somebody watches
+// it, and wakes up all watchers, when it changed. So any number of watchers
+// costs the same, as a single one.
+type statusWatcher struct {
+	status   func(ctx context.Context) (*Status, error)
+	interval time.Duration
+
+	mu       sync.Mutex
+	watchers int
+	running  bool
+
+	version uint64
+	current *Status
+	encoded []byte
+	changed chan struct{}
+	// stale is true, when nobody watched the status for some time and current
+	// status is out of date.
+	stale bool
+}
+
+func (self *statusWatcher) watch(ctx context.Context, req *statusWatchRequest,
+) (*StatusUpdate, error) {
+	self.subscribe(ctx)
+	defer self.unsubscribe()
+
+	update, changed, err := self.update(ctx)
+	if err != nil {
+		return nil, err
+	} else if update.Version != req.Version {
+		return update, nil
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 || timeout > statusWatchMaxTimeout {
+		timeout = statusWatchMaxTimeout
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	case <-changed:
+	}
+	update, _, err = self.update(ctx)
+	return update, err
+}
+
+func (self *statusWatcher) subscribe(ctx context.Context) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.watchers++
+	if !self.running {
+		self.running = true
+		go self.run(context.WithoutCancel(ctx))
+	}
+}
+
+func (self *statusWatcher) unsubscribe() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.watchers--
+}
+
+func (self *statusWatcher) run(ctx context.Context) {
+	t := time.NewTicker(self.interval)
+	defer t.Stop()
+
+	for range t.C {
+		self.mu.Lock()
+		if self.watchers == 0 {
+			self.running = false
+			self.stale = true
+			self.mu.Unlock()
+			return
+		}
+		self.mu.Unlock()
+		_ = self.refresh(ctx)
+	}
+}
+
+// update returns current status. It builds the status, if there is no status
+// yet or it's stale.
+func (self *statusWatcher) update(ctx context.Context,
+) (*StatusUpdate, <-chan struct{}, error) {
+	self.mu.Lock()
+	outdated := self.current == nil || self.stale
+	self.mu.Unlock()
+
+	if outdated {
+		if err := self.refresh(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	update := &StatusUpdate{Version: self.version, Status: self.current}
+	return update, self.changed, nil
+}
+
+func (self *statusWatcher) refresh(ctx context.Context) error {
+	s, err := self.status(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err //nolint:wrapcheck // not needed
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.stale = false
+	if self.current != nil && bytes.Equal(b, self.encoded) {
+		return nil
+	}
+	self.version++
+	self.current, self.encoded = s, b
+	close(self.changed)
+	self.changed = make(chan struct{})
+	return nil
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+	"github.com/dsh2dsh/zrepl/internal/daemon/middleware"
+	"github.com/dsh2dsh/zrepl/internal/daemon/pruner"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/version"
+)
+
+const (
+	webEndpointIndex  = "/{$}"
+	webEndpointStatus = "GET /api/status"
+)
+
+//go:embed web/index.html
+var webIndexHTML string
+
+var webIndexTmpl = template.Must(template.New("index").Funcs(template.FuncMap{
+	"bytes": webBytes,
+	"time":  webTime,
+}).Parse(webIndexHTML))
+
+// webEndpoints registers read-only web dashboard. It uses the same data, the
+// control socket exposes, but never accepts any signals.
+func (j *controlJob) webEndpoints(mux *http.ServeMux,
+	m ...middleware.Middleware,
+) {
+	mux.Handle(webEndpointIndex, middleware.AppendHandler(m,
+		http.HandlerFunc(j.webIndex)))
+
+	mux.Handle(webEndpointStatus, middleware.Append(m,
+		middleware.JsonResponder(j.status)))
+}
+
+func (j *controlJob) webIndex(w http.ResponseWriter, r *http.Request) {
+	s, err := j.status(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var b bytes.Buffer
+	if err := webIndexTmpl.Execute(&b, newWebPage(s)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = b.WriteTo(w)
+}
+
+type webPage struct {
+	Version string
+	Now     time.Time
+	Jobs    []*webJob
+}
+
+type webJob struct {
+	Name     string
+	Type     job.Type
+	State    string
+	Err      string
+	NextCron time.Time
+
+	Replication *report.AttemptReport
+	Filesystems []webFilesystem
+	Verify      *report.VerifyReport
+	Skipped     []job.SkippedFilesystem
+	Pruning     []webPruning
+}
+
+type webFilesystem struct {
+	Name       string
+	State      report.FilesystemState
+	Step       *report.StepInfo
+	Expected   uint64
+	Replicated uint64
+	Err        string
+}
+
+type webPruning struct {
+	Side   string
+	Report *pruner.Report
+}
+
+func newWebPage(s *Status) *webPage {
+	page := &webPage{
+		Version: version.NewZreplVersionInformation().Version,
+		Now:     time.Now(),
+		Jobs:    make([]*webJob, 0, len(s.Jobs)),
+	}
+
+	for name, st := range s.Jobs {
+		if st.Internal() || st.JobSpecific == nil {
+			continue
+		}
+		page.Jobs = append(page.Jobs, newWebJob(name, st))
+	}
+	slices.SortFunc(page.Jobs, func(a, b *webJob) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return page
+}
+
+func newWebJob(name string, s *job.Status) *webJob {
+	j := &webJob{
+		Name:     name,
+		Type:     s.Type,
+		State:    "idle",
+		Err:      s.Error(),
+		NextCron: s.SleepingUntil(),
+	}
+
+	if d, ok := s.Running(); ok {
+		j.State = "running " + d.Truncate(time.Second).String()
+	} else if s.Disabled {
+		j.State = "disabled"
+	}
+
+	switch st := s.JobSpecific.(type) {
+	case *job.ActiveSideStatus:
+		if r := st.Replication; r != nil && len(r.Attempts) > 0 {
+			j.setReplication(r.Attempts[len(r.Attempts)-1])
+		}
+		j.Verify = st.Verify
+		j.Skipped = st.Skipped
+		j.addPruning("sender", st.PruningSender)
+		j.addPruning("receiver", st.PruningReceiver)
+	case *job.SnapJobStatus:
+		j.addPruning("local", st.Pruning)
+	}
+	return j
+}
+
+func (self *webJob) setReplication(r *report.AttemptReport) {
+	self.Replication = r
+	self.Filesystems = make([]webFilesystem, len(r.Filesystems))
+	for i, fs := range r.Filesystems {
+		item := &self.Filesystems[i]
+		item.Name = fs.Info.Name
+		item.State = fs.State
+		if step := fs.Step(); step != nil {
+			item.Step = step.Info
+		}
+		item.Expected, item.Replicated, _ = fs.BytesSum()
+		if err := fs.Error(); err != nil {
+			item.Err = err.Error()
+		}
+	}
+	slices.SortFunc(self.Filesystems, func(a, b webFilesystem) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
+
+func (self *webJob) addPruning(side string, r *pruner.Report) {
+	if r != nil {
+		self.Pruning = append(self.Pruning, webPruning{Side: side, Report: r})
+	}
+}
+
+func webBytes(v uint64) string {
+	const unit = 1024
+	if v < unit {
+		return strconv.FormatUint(v, 10) + " B"
+	}
+	div, exp := uint64(unit), 0
+	for n := v / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(v)/float64(div), "KMGTPE"[exp])
+}
+
+func webTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
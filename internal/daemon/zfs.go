@@ -10,21 +10,31 @@ import (
 	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/config/env"
 	"github.com/dsh2dsh/zrepl/internal/daemon/job"
 	"github.com/dsh2dsh/zrepl/internal/daemon/middleware"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/util/journal"
+	"github.com/dsh2dsh/zrepl/internal/util/qos"
 )
 
 func newZfsJob(connecter *job.Connecter, keys []config.AuthKey) *zfsJob {
-	j := &zfsJob{connecter: connecter, timeout: time.Minute}
+	j := &zfsJob{
+		connecter: connecter,
+		sessions:  journal.NewSessions(env.Values.StreamJournalGrace),
+		timeout:   time.Minute,
+	}
 	return j.init(keys)
 }
 
 type zfsJob struct {
 	connecter   *job.Connecter
 	middlewares []middleware.Middleware
+	sessions    *journal.Sessions
 
 	timeout time.Duration
+
+	readTimeout, writeTimeout time.Duration
 }
 
 func (self *zfsJob) init(keys []config.AuthKey) *zfsJob {
@@ -35,10 +45,18 @@ func (self *zfsJob) init(keys []config.AuthKey) *zfsJob {
 			return self.connecter.Job(name) != nil
 		}),
 		middleware.CheckClientIdentity(keys),
+		middleware.MarkConn(self.socketOptions),
 	}
 	return self
 }
 
+func (self *zfsJob) socketOptions(jobName string) qos.Options {
+	if j := self.connecter.Job(jobName); j != nil {
+		return j.SocketOptions()
+	}
+	return qos.Options{}
+}
+
 func (self *zfsJob) WithTimeout(d time.Duration) *zfsJob {
 	if d > 0 {
 		self.timeout = d
@@ -46,6 +64,13 @@ func (self *zfsJob) WithTimeout(d time.Duration) *zfsJob {
 	return self
 }
 
+// WithStreamTimeouts fails receive streams, which reads block longer than read,
+// and send streams, which writes block longer than write.
+func (self *zfsJob) WithStreamTimeouts(read, write time.Duration) *zfsJob {
+	self.readTimeout, self.writeTimeout = read, write
+	return self
+}
+
 func (self *zfsJob) Endpoints(mux *http.ServeMux, m ...middleware.Middleware) {
 	ep := job.EndpointNames("{job}")
 	m = slices.Concat(m, self.middlewares)
@@ -67,10 +92,14 @@ func (self *zfsJob) Endpoints(mux *http.ServeMux, m ...middleware.Middleware) {
 	mux.Handle(ep[job.EpWaitForConnectivity],
 		middleware.AppendHandler(m, http.HandlerFunc(self.healthCheck)))
 
-	mux.Handle(ep[job.EpReceive], middleware.Append(m,
+	streamTimeouts := middleware.StreamTimeouts(self.readTimeout,
+		self.writeTimeout)
+	mux.Handle(ep[job.EpReceive], middleware.Append(m, streamTimeouts,
 		middleware.JsonRequestStream(self.receive)))
+	mux.Handle(ep[job.EpAdopt], middleware.Append(m,
+		middleware.JsonRequestResponder(self.adopt)))
 
-	mux.Handle(ep[job.EpSend], middleware.Append(m,
+	mux.Handle(ep[job.EpSend], middleware.Append(m, streamTimeouts,
 		middleware.JsonRequestResponseStream(self.send)))
 	mux.Handle(ep[job.EpSendDry], middleware.Append(m,
 		middleware.JsonRequestResponder(self.sendDry)))
@@ -189,7 +218,18 @@ func (self *zfsJob) receive(ctx context.Context, req *pdu.ReceiveReq,
 		return err
 	}
 
-	if err = ep.Receive(ctx, req, r); err != nil {
+	if req.StreamSession != "" {
+		id := middleware.ClientIdentityFrom(ctx) + "/" +
+			middleware.JobNameFrom(ctx) + "/" + req.StreamSession
+		err = self.sessions.Receive(ctx, id, req.StreamOffset, r,
+			func(ctx context.Context, r io.ReadCloser) error {
+				return ep.Receive(ctx, req, r)
+			})
+	} else {
+		err = ep.Receive(ctx, req, r)
+	}
+
+	if err != nil {
 		return fmt.Errorf("create snapshot %q on %q: %w",
 			req.To.Name, req.Filesystem, err)
 	}
@@ -248,6 +288,22 @@ func (self *zfsJob) sendCompleted(ctx context.Context,
 	return nil, nil
 }
 
+func (self *zfsJob) adopt(ctx context.Context, req *pdu.AdoptReq,
+) (*struct{}, error) {
+	ep, err := self.endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, self.timeout)
+	defer cancel()
+
+	if err := ep.Adopt(ctx, req); err != nil {
+		return nil, fmt.Errorf("adopt %q: %w", req.GetFilesystem(), err)
+	}
+	return nil, nil
+}
+
 func (self *zfsJob) replicationCursor(ctx context.Context,
 	req *pdu.ReplicationCursorReq,
 ) (*pdu.ReplicationCursorRes, error) {
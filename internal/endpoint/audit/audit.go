@@ -0,0 +1,169 @@
+// Package audit keeps append-only, hash-chained logs of mutating endpoint
+// operations, like recv, destroy, hold and release, one log per job.
+//
+// Every entry contains the hash of the previous entry and its own hash, which
+// covers the entry and the previous hash. Any modified, removed or reordered
+// entry breaks the chain, which is detected by [Verify].
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry of audit log.
+type Entry struct {
+	Seq     uint64            `json:"seq"`
+	Time    time.Time         `json:"time"`
+	Job     string            `json:"job"`
+	Op      string            `json:"op"`
+	Target  string            `json:"target"`
+	Details map[string]string `json:"details,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Prev    string            `json:"prev"`
+	Hash    string            `json:"hash,omitempty"`
+}
+
+// sum returns hash of the entry, without its Hash.
+func (self *Entry) sum() (string, error) {
+	e := *self
+	e.Hash = ""
+	b, err := json.Marshal(&e)
+	if err != nil {
+		return "", fmt.Errorf("marshal audit entry: %w", err)
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Open opens audit log at path for appending, creating it if not exists.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+
+	l := &Log{f: f}
+	if err := l.readLast(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %q: %w", path, err)
+	}
+	return l, nil
+}
+
+// Log is an audit log of one job.
+type Log struct {
+	f   *os.File
+	mu  sync.Mutex
+	seq uint64
+	// last is the hash of the last entry.
+	last string
+}
+
+func (self *Log) readLast() error {
+	var last Entry
+	s := bufio.NewScanner(self.f)
+	s.Buffer(nil, maxEntrySize)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(s.Bytes(), &last); err != nil {
+			return fmt.Errorf("parse entry: %w", err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	self.seq, self.last = last.Seq, last.Hash
+	return nil
+}
+
+const maxEntrySize = 1 << 20
+
+// Append chains e to the last entry and writes it to the log.
+func (self *Log) Append(e Entry) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	e.Seq, e.Prev = self.seq+1, self.last
+	hash, err := e.sum()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	b, err := json.Marshal(&e)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+	if _, err := self.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write audit entry: %w", err)
+	} else if err := self.f.Sync(); err != nil {
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+	self.seq, self.last = e.Seq, e.Hash
+	return nil
+}
+
+func (self *Log) Close() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if err := self.f.Close(); err != nil {
+		return fmt.Errorf("close audit log: %w", err)
+	}
+	return nil
+}
+
+// ErrBrokenChain means entries of audit log were modified, removed or
+// reordered.
+var ErrBrokenChain = errors.New("broken hash chain")
+
+// Verify reads audit log from r and verifies its hash chain. It returns number
+// of verified entries.
+func Verify(r io.Reader) (n int, err error) {
+	var seq uint64
+	var prev string
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxEntrySize)
+	for line := 1; s.Scan(); line++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return n, fmt.Errorf("line %d: parse entry: %w", line, err)
+		}
+
+		switch hash, err := e.sum(); {
+		case err != nil:
+			return n, fmt.Errorf("line %d: %w", line, err)
+		case e.Seq != seq+1:
+			return n, fmt.Errorf("line %d: %w: seq %d after %d", line,
+				ErrBrokenChain, e.Seq, seq)
+		case e.Prev != prev:
+			return n, fmt.Errorf("line %d: %w: prev hash mismatch", line,
+				ErrBrokenChain)
+		case e.Hash != hash:
+			return n, fmt.Errorf("line %d: %w: hash mismatch", line,
+				ErrBrokenChain)
+		}
+		seq, prev = e.Seq, e.Hash
+		n++
+	}
+
+	if err := s.Err(); err != nil {
+		return n, fmt.Errorf("read audit log: %w", err)
+	}
+	return n, nil
+}
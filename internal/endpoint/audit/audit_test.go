@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+func TestLog_AppendVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+	l, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Entry{Job: "job", Op: "recv", Target: "a@1"}))
+	require.NoError(t, l.Append(Entry{
+		Job: "job", Op: "hold", Target: "a@1",
+		Details: map[string]string{"tag": "zrepl_job"},
+	}))
+	require.NoError(t, l.Close())
+
+	// reopened log continues the chain
+	l, err = Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Entry{
+		Job: "job", Op: "destroy", Target: "a@0",
+		Error: "dataset is busy",
+	}))
+	require.NoError(t, l.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	n, err := Verify(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	lines := strings.SplitAfter(string(b), "\n")
+	require.Len(t, lines, 4)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "modified",
+			content: lines[0] +
+				strings.Replace(lines[1], `"hold"`, `"release"`, 1) + lines[2],
+		},
+		{name: "removed", content: lines[0] + lines[2]},
+		{name: "reordered", content: lines[1] + lines[0] + lines[2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tt.content))
+			require.ErrorIs(t, err, ErrBrokenChain)
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	const jobName = "TestRecord"
+	dir := t.TempDir()
+	require.NoError(t, OpenJob(dir, jobName))
+	defer func() { require.NoError(t, OpenJob("", jobName)) }()
+
+	ctx := zfscmd.WithJobID(context.Background(), jobName)
+	Record(ctx, "release", "a@1", errors.New("no such tag"), "tag", "foo")
+	// other jobs aren't recorded
+	Record(context.Background(), "destroy", "a@1", nil)
+
+	b, err := os.ReadFile(Filename(dir, jobName))
+	require.NoError(t, err)
+	n, err := Verify(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Contains(t, string(b), `"op":"release"`)
+	assert.Contains(t, string(b), `"details":{"tag":"foo"}`)
+	assert.Contains(t, string(b), `"error":"no such tag"`)
+}
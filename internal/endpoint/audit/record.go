@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+var jobLogs = struct {
+	mtx  sync.RWMutex
+	logs map[string]*Log
+}{logs: make(map[string]*Log)}
+
+// Filename returns path of audit log of job jobName in directory dir.
+func Filename(dir, jobName string) string {
+	return filepath.Join(dir, jobName+".log")
+}
+
+// OpenJob opens audit log of job jobName in directory dir and records
+// operations of this job into it. Empty dir closes audit log of the job.
+func OpenJob(dir, jobName string) error {
+	var l *Log
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create audit dir: %w", err)
+		}
+		var err error
+		if l, err = Open(Filename(dir, jobName)); err != nil {
+			return err
+		}
+	}
+
+	jobLogs.mtx.Lock()
+	defer jobLogs.mtx.Unlock()
+	if prev, ok := jobLogs.logs[jobName]; ok {
+		_ = prev.Close()
+		delete(jobLogs.logs, jobName)
+	}
+	if l != nil {
+		jobLogs.logs[jobName] = l
+	}
+	return nil
+}
+
+func jobLog(jobName string) *Log {
+	jobLogs.mtx.RLock()
+	defer jobLogs.mtx.RUnlock()
+	return jobLogs.logs[jobName]
+}
+
+// Record appends operation op on target with its result err to audit log of
+// the job from ctx, if the job has it. details are pairs of keys and values.
+func Record(ctx context.Context, op, target string, err error,
+	details ...string,
+) {
+	job := zfscmd.GetJobID(ctx)
+	l := jobLog(job)
+	if l == nil {
+		return
+	}
+
+	e := Entry{Time: time.Now(), Job: job, Op: op, Target: target}
+	if len(details) > 0 {
+		e.Details = make(map[string]string, len(details)/2)
+		for i := 0; i+1 < len(details); i += 2 {
+			e.Details[details[i]] = details[i+1]
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if err := l.Append(e); err != nil {
+		logger.WithError(
+			logging.GetLogger(ctx, logging.SubsysEndpoint).With(
+				slog.String("op", op), slog.String("target", target)),
+			err, "failed record audit entry")
+	}
+}
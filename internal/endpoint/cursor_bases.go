@@ -0,0 +1,146 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// keepCursorBases removes from snapNames every snapshot, which is the
+// incremental base of the last replication cursor of any job, not only of the
+// job, which prunes fs. Such a snapshot is needed by that job for its next
+// incremental send and destroying it, because of retention rules of another
+// job, breaks its incremental chain.
+//
+// Ranges like "a%b" are split around kept snapshots.
+func keepCursorBases(ctx context.Context, fs string, snapNames []string,
+) ([]string, error) {
+	bases, err := cursorBases(ctx, fs)
+	if err != nil {
+		return nil, err
+	} else if len(bases) == 0 {
+		return snapNames, nil
+	}
+
+	dp, err := zfs.NewDatasetPath(fs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filesystem %q: %w", fs, err)
+	}
+	snaps, err := zfs.ZFSListFilesystemVersions(ctx, dp,
+		zfs.ListFilesystemVersionsOptions{Types: zfs.Snapshots})
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots of %q: %w", fs, err)
+	}
+
+	names, kept := withoutCursorBases(snaps, snapNames, bases)
+	for _, s := range kept {
+		getLogger(ctx).With(
+			slog.String("fs", fs),
+			slog.String("snap", s.Name),
+			slog.String("job", bases[s.Guid]),
+		).Info("keep snapshot, it's incremental base of replication cursor")
+	}
+	return names, nil
+}
+
+// cursorBases returns GUIDs of snapshots, which the last replication cursor of
+// every job on fs points to, mapped to the job name.
+func cursorBases(ctx context.Context, fs string) (map[uint64]string, error) {
+	abs, absErr, err := ListAbstractions(ctx, ListZFSHoldsAndBookmarksQuery{
+		FS: ListZFSHoldsAndBookmarksQueryFilesystemFilter{FS: &fs},
+		What: AbstractionTypeSet{
+			AbstractionReplicationCursorBookmarkV1: true,
+			AbstractionReplicationCursorBookmarkV2: true,
+		},
+		Concurrency: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list replication cursors of %q: %w", fs, err)
+	} else if len(absErr) > 0 {
+		return nil, ListAbstractionsErrors(absErr)
+	}
+
+	last := lastCursors(abs)
+	bases := make(map[uint64]string, len(last))
+	for job, v := range last {
+		bases[v.Guid] = job
+	}
+	return bases, nil
+}
+
+// lastCursors returns the last replication cursor of every job from abs,
+// mapped to the job name. V1 replication cursor has empty job name. Other
+// abstractions are ignored.
+func lastCursors(abs []Abstraction) map[string]zfs.FilesystemVersion {
+	last := make(map[string]zfs.FilesystemVersion, len(abs))
+	for _, a := range abs {
+		switch a.GetType() {
+		case AbstractionReplicationCursorBookmarkV1,
+			AbstractionReplicationCursorBookmarkV2:
+		default:
+			continue
+		}
+		var job string
+		if jobID := a.GetJobID(); jobID != nil {
+			job = jobID.String()
+		}
+		v := a.GetFilesystemVersion()
+		if cur, ok := last[job]; !ok || v.CreateTXG > cur.CreateTXG {
+			last[job] = v
+		}
+	}
+	return last
+}
+
+// withoutCursorBases returns snapNames without snapshots from bases and the
+// removed snapshots. snaps must be sorted by createtxg.
+func withoutCursorBases(snaps []zfs.FilesystemVersion, snapNames []string,
+	bases map[uint64]string,
+) (names []string, kept []zfs.FilesystemVersion) {
+	index := make(map[string]int, len(snaps))
+	for i := range snaps {
+		index[snaps[i].Name] = i
+	}
+
+	names = make([]string, 0, len(snapNames))
+	for _, name := range snapNames {
+		from, to, isRange := strings.Cut(name, "%")
+		i, ok1 := index[from]
+		j, ok2 := i, true
+		if isRange {
+			j, ok2 = index[to]
+		}
+		if !ok1 || !ok2 || j < i {
+			// Let zfs destroy report unknown snapshots.
+			names = append(names, name)
+			continue
+		}
+
+		first := -1
+		for k := i; k <= j; k++ {
+			if _, ok := bases[snaps[k].Guid]; ok {
+				kept = append(kept, snaps[k])
+				names = appendRange(names, snaps, first, k-1)
+				first = -1
+			} else if first < 0 {
+				first = k
+			}
+		}
+		names = appendRange(names, snaps, first, j)
+	}
+	return names, kept
+}
+
+func appendRange(names []string, snaps []zfs.FilesystemVersion, i, j int,
+) []string {
+	switch {
+	case i < 0 || j < i:
+		return names
+	case i == j:
+		return append(names, snaps[i].Name)
+	}
+	return append(names, snaps[i].Name+"%"+snaps[j].Name)
+}
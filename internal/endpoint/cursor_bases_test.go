@@ -0,0 +1,87 @@
+package endpoint
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestWithoutCursorBases(t *testing.T) {
+	snaps := make([]zfs.FilesystemVersion, 10)
+	for i := range snaps {
+		snaps[i] = zfs.FilesystemVersion{
+			Type:      zfs.Snapshot,
+			Name:      strconv.Itoa(i + 1),
+			Guid:      uint64(i + 1),
+			CreateTXG: uint64(i + 1),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		snapNames []string
+		bases     []uint64
+		want      []string
+		wantKept  []string
+	}{
+		{
+			name:      "no bases",
+			snapNames: []string{"1%5", "7"},
+			want:      []string{"1%5", "7"},
+		},
+		{
+			name:      "single",
+			snapNames: []string{"1", "3", "7"},
+			bases:     []uint64{3},
+			want:      []string{"1", "7"},
+			wantKept:  []string{"3"},
+		},
+		{
+			name:      "inside range",
+			snapNames: []string{"1%10"},
+			bases:     []uint64{4, 8},
+			want:      []string{"1%3", "5%7", "9%10"},
+			wantKept:  []string{"4", "8"},
+		},
+		{
+			name:      "range bounds",
+			snapNames: []string{"1%3", "5%8"},
+			bases:     []uint64{1, 8},
+			want:      []string{"2%3", "5%7"},
+			wantKept:  []string{"1", "8"},
+		},
+		{
+			name:      "single left",
+			snapNames: []string{"1%3"},
+			bases:     []uint64{2, 3},
+			want:      []string{"1"},
+			wantKept:  []string{"2", "3"},
+		},
+		{
+			name:      "unknown snapshot",
+			snapNames: []string{"foo", "1%foo"},
+			bases:     []uint64{1},
+			want:      []string{"foo", "1%foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bases := make(map[uint64]string, len(tt.bases))
+			for _, guid := range tt.bases {
+				bases[guid] = "job"
+			}
+			names, kept := withoutCursorBases(snaps, tt.snapNames, bases)
+			assert.Equal(t, tt.want, names)
+
+			var keptNames []string
+			for _, s := range kept {
+				keptNames = append(keptNames, s.Name)
+			}
+			assert.Equal(t, tt.wantKept, keptNames)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// DestroyAtProperty marks snapshots, which pruning scheduled for destroy. Its
+// value is RFC 3339 time, after which pruning destroys the snapshot. zrepl
+// undo-prune removes it.
+const DestroyAtProperty = "zrepl:destroy_at"
+
+// delayDestroys returns destroyFilter, which marks snapshots by
+// DestroyAtProperty instead of destroying them, and allows to destroy only
+// snapshots, which were marked at least delay ago.
+func delayDestroys(delay time.Duration) destroyFilter {
+	return func(ctx context.Context, fs string, snapNames []string,
+	) ([]string, error) {
+		dp, err := zfs.NewDatasetPath(fs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filesystem %q: %w", fs, err)
+		}
+		snaps, err := zfs.ZFSListFilesystemVersions(ctx, dp,
+			zfs.ListFilesystemVersionsOptions{
+				Types:      zfs.Snapshots,
+				Properties: []string{DestroyAtProperty},
+			})
+		if err != nil {
+			return nil, fmt.Errorf("list snapshots of %q: %w", fs, err)
+		}
+
+		now := time.Now()
+		destroyAt := now.Add(delay).Format(time.RFC3339)
+		requested, unknown := expandSnapshotNames(snaps, snapNames)
+		// Let zfs destroy report unknown snapshots.
+		names := unknown
+		for _, s := range requested {
+			l := getLogger(ctx).With(slog.String("fs", fs),
+				slog.String("snap", s.Name))
+			if at, ok := snapshotDestroyAt(l, s); ok {
+				if !now.Before(at) {
+					names = append(names, s.Name)
+				}
+				continue
+			}
+			err := zfs.ZFSSetPath(ctx, s.FullPath(fs),
+				map[string]string{DestroyAtProperty: destroyAt})
+			lifecycle.Record(ctx, lifecycle.Marked, fs, s.Name, err,
+				"destroy_at", destroyAt)
+			if err != nil {
+				return nil, fmt.Errorf("mark %q for destroy: %w", s.FullPath(fs), err)
+			}
+			l.With(slog.String("destroy_at", destroyAt)).
+				Info("snapshot marked for delayed destroy")
+		}
+		return names, nil
+	}
+}
+
+func snapshotDestroyAt(l *slog.Logger, s zfs.FilesystemVersion,
+) (time.Time, bool) {
+	v, ok := s.Properties[DestroyAtProperty]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		l.With(slog.String("value", v), slog.String("error", err.Error())).
+			Warn("mark again snapshot with invalid " + DestroyAtProperty)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// expandSnapshotNames returns snapshots named by snapNames, with ranges like
+// "a%b" expanded, and names of unknown snapshots or ranges. snaps must be
+// sorted by createtxg.
+func expandSnapshotNames(snaps []zfs.FilesystemVersion, snapNames []string,
+) (expanded []zfs.FilesystemVersion, unknown []string) {
+	index := make(map[string]int, len(snaps))
+	for i := range snaps {
+		index[snaps[i].Name] = i
+	}
+
+	for _, name := range snapNames {
+		from, to, isRange := strings.Cut(name, "%")
+		i, ok1 := index[from]
+		j, ok2 := i, true
+		if isRange {
+			j, ok2 = index[to]
+		}
+		if !ok1 || !ok2 || j < i {
+			unknown = append(unknown, name)
+			continue
+		}
+		expanded = append(expanded, snaps[i:j+1]...)
+	}
+	return expanded, unknown
+}
@@ -0,0 +1,52 @@
+package endpoint
+
+import (
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestExpandSnapshotNames(t *testing.T) {
+	snaps := make([]zfs.FilesystemVersion, 5)
+	for i := range snaps {
+		snaps[i] = zfs.FilesystemVersion{
+			Type: zfs.Snapshot,
+			Name: strconv.Itoa(i + 1),
+		}
+	}
+
+	expanded, unknown := expandSnapshotNames(snaps,
+		[]string{"1", "3%4", "foo", "2%bar", "4%2"})
+	names := make([]string, len(expanded))
+	for i := range expanded {
+		names[i] = expanded[i].Name
+	}
+	assert.Equal(t, []string{"1", "3", "4"}, names)
+	assert.Equal(t, []string{"foo", "2%bar", "4%2"}, unknown)
+}
+
+func TestSnapshotDestroyAt(t *testing.T) {
+	l := slog.New(slog.DiscardHandler)
+	now := time.Now().Truncate(time.Second)
+
+	_, ok := snapshotDestroyAt(l, zfs.FilesystemVersion{})
+	assert.False(t, ok)
+
+	_, ok = snapshotDestroyAt(l, zfs.FilesystemVersion{
+		Properties: map[string]string{DestroyAtProperty: "tomorrow"},
+	})
+	assert.False(t, ok)
+
+	at, ok := snapshotDestroyAt(l, zfs.FilesystemVersion{
+		Properties: map[string]string{
+			DestroyAtProperty: now.Format(time.RFC3339),
+		},
+	})
+	assert.True(t, ok)
+	assert.True(t, now.Equal(at))
+}
@@ -3,19 +3,39 @@ package endpoint
 import (
 	"context"
 	"errors"
+	"fmt"
 	"iter"
 	"log/slog"
+	"regexp"
 	"runtime"
+	"strings"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
+// destroyFilter returns snapshots of fs, which are allowed to be destroyed.
+type destroyFilter func(ctx context.Context, fs string, snapNames []string,
+) ([]string, error)
+
 func destroySnapshots(ctx context.Context, concurrency, fsCount int,
-	reqs iter.Seq2[*pdu.DestroySnapshots, error],
+	reqs iter.Seq2[*pdu.DestroySnapshots, error], filter destroyFilter,
+	releaseHolds string,
 ) (*pdu.DestroySnapshotsRes, error) {
+	var releaseRe *regexp.Regexp
+	if releaseHolds != "" {
+		re, err := regexp.Compile(releaseHolds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release holds regex %q: %w",
+				releaseHolds, err)
+		}
+		releaseRe = re
+	}
+
 	var g errgroup.Group
 	if concurrency < 1 {
 		concurrency = runtime.GOMAXPROCS(0)
@@ -39,10 +59,27 @@ func destroySnapshots(ctx context.Context, concurrency, fsCount int,
 			continue
 		}
 		g.Go(func() error {
-			failed, err := destroyOneSnapshots(ctx, r.LocalPath(), r.Snapshots)
+			snapNames := r.Snapshots
+			if filter != nil {
+				names, err := filter(ctx, r.LocalPath(), snapNames)
+				if err != nil {
+					destroyed.Error = err.Error()
+					return nil
+				}
+				snapNames = names
+			}
+			failed, err := destroyOneSnapshots(ctx, r.LocalPath(), snapNames,
+				releaseRe)
+			if err != nil {
+				destroyed.Error = err.Error()
+				return nil
+			}
+			failedBookmarks, err := destroyBookmarks(ctx, r.LocalPath(),
+				r.Bookmarks)
 			if err != nil {
 				destroyed.Error = err.Error()
-			} else if len(failed) != 0 {
+			}
+			if failed = append(failed, failedBookmarks...); len(failed) != 0 {
 				destroyed.Results = failed
 			}
 			return nil
@@ -56,7 +93,11 @@ func destroySnapshots(ctx context.Context, concurrency, fsCount int,
 }
 
 func destroyOneSnapshots(ctx context.Context, lp string, snapNames []string,
+	releaseHolds *regexp.Regexp,
 ) (destroyed []pdu.DestroySnapshotRes, _ error) {
+	if len(snapNames) == 0 {
+		return nil, nil
+	}
 	destroy := make([]zfs.DestroySnapOp, len(snapNames))
 	for i, name := range snapNames {
 		destroy[i].Name = name
@@ -69,18 +110,35 @@ func destroyOneSnapshots(ctx context.Context, lp string, snapNames []string,
 
 	zfs.ZFSDestroyFilesystemVersions(ctx, lp, destroy)
 	for i := range destroy {
-		if err := destroy[i].Err; err != nil {
-			destroyed = append(destroyed, pdu.DestroySnapshotRes{
-				Name: destroy[i].Name,
-			})
-			failed := &destroyed[len(destroyed)-1]
-			de, ok := errors.AsType[*zfs.DestroySnapshotsError](err)
-			if ok && len(de.Reason) == 1 {
-				failed.Error = de.Reason[0]
-			} else {
-				failed.Error = err.Error()
+		audit.Record(ctx, "destroy", lp+"@"+destroy[i].Name, destroy[i].Err)
+		lifecycle.Record(ctx, lifecycle.Destroyed, lp, destroy[i].Name,
+			destroy[i].Err)
+		err := destroy[i].Err
+		if err == nil {
+			continue
+		}
+
+		failed := pdu.DestroySnapshotRes{Name: destroy[i].Name}
+		de, ok := errors.AsType[*zfs.DestroySnapshotsError](err)
+		if ok && len(de.Reason) == 1 {
+			failed.Error = de.Reason[0]
+		} else {
+			failed.Error = err.Error()
+		}
+
+		if ok {
+			held, errs := checkHolds(ctx, lp, de, releaseHolds)
+			failed.Held = held
+			switch {
+			case len(errs) == 0:
+				failed.Error = ""
+			case len(errs) < len(de.Undestroyable):
+				failed.Error = strings.Join(errs, ", ")
 			}
 		}
+		if failed.Error != "" || len(failed.Held) != 0 {
+			destroyed = append(destroyed, failed)
+		}
 	}
 	return destroyed, nil
 }
@@ -0,0 +1,80 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+// destroyBookmarks destroys bookmarks of fs from bmNames, which are zrepl
+// replication cursors or tentative replication cursors, except the last
+// replication cursor of every job. Other bookmarks are never destroyed.
+//
+// It returns failed destroys with names prefixed by '#'.
+func destroyBookmarks(ctx context.Context, fs string, bmNames []string,
+) ([]pdu.DestroySnapshotRes, error) {
+	if len(bmNames) == 0 {
+		return nil, nil
+	}
+
+	abs, absErr, err := ListAbstractions(ctx, ListZFSHoldsAndBookmarksQuery{
+		FS: ListZFSHoldsAndBookmarksQueryFilesystemFilter{FS: &fs},
+		What: AbstractionTypeSet{
+			AbstractionReplicationCursorBookmarkV1:        true,
+			AbstractionReplicationCursorBookmarkV2:        true,
+			AbstractionTentativeReplicationCursorBookmark: true,
+		},
+		Concurrency: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list zrepl bookmarks of %q: %w", fs, err)
+	} else if len(absErr) > 0 {
+		return nil, ListAbstractionsErrors(absErr)
+	}
+
+	l := getLogger(ctx).With(slog.String("fs", fs))
+	destroy := destroyableBookmarks(abs, bmNames)
+	l.With(
+		slog.Int("count", len(destroy)),
+		slog.Int("requested", len(bmNames)),
+	).Debug("destroying bookmarks")
+
+	var failed []pdu.DestroySnapshotRes
+	for _, a := range destroy {
+		name := a.GetFilesystemVersion().Name
+		if err := a.Destroy(ctx); err != nil {
+			failed = append(failed, pdu.DestroySnapshotRes{
+				Name: "#" + name, Error: err.Error(),
+			})
+			continue
+		}
+		l.With(slog.String("bookmark", name)).Info("destroyed bookmark")
+	}
+	return failed, nil
+}
+
+// destroyableBookmarks returns abstractions from abs, which bmNames refer to,
+// without the last replication cursor of every job.
+func destroyableBookmarks(abs []Abstraction, bmNames []string) []Abstraction {
+	byName := make(map[string]Abstraction, len(abs))
+	for _, a := range abs {
+		byName[a.GetFilesystemVersion().Name] = a
+	}
+
+	keep := make(map[string]struct{})
+	for _, v := range lastCursors(abs) {
+		keep[v.Name] = struct{}{}
+	}
+
+	destroy := make([]Abstraction, 0, len(bmNames))
+	for _, name := range bmNames {
+		if _, ok := keep[name]; ok {
+			continue
+		} else if a, ok := byName[name]; ok {
+			destroy = append(destroy, a)
+		}
+	}
+	return destroy
+}
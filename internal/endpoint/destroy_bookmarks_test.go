@@ -0,0 +1,56 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestDestroyableBookmarks(t *testing.T) {
+	const fs = "pool/a"
+	dp, err := zfs.NewDatasetPath(fs)
+	require.NoError(t, err)
+
+	jobA, jobB := MustMakeJobID("a"), MustMakeJobID("b")
+	bookmark := func(namer func(string, uint64, JobID) (string, error),
+		extractor BookmarkExtractor, guid uint64, jobID JobID,
+	) Abstraction {
+		name, err := namer(fs, guid, jobID)
+		require.NoError(t, err)
+		a := extractor(dp, zfs.FilesystemVersion{
+			Type:      zfs.Bookmark,
+			Name:      name,
+			Guid:      guid,
+			CreateTXG: guid,
+		})
+		require.NotNil(t, a)
+		return a
+	}
+
+	abs := []Abstraction{
+		bookmark(ReplicationCursorBookmarkName, ReplicationCursorV2Extractor,
+			1, jobA),
+		bookmark(ReplicationCursorBookmarkName, ReplicationCursorV2Extractor,
+			2, jobA),
+		bookmark(TentativeReplicationCursorBookmarkName,
+			TentativeReplicationCursorExtractor, 3, jobA),
+		bookmark(ReplicationCursorBookmarkName, ReplicationCursorV2Extractor,
+			1, jobB),
+	}
+
+	names := make([]string, 0, len(abs)+1)
+	for _, a := range abs {
+		names = append(names, a.GetFilesystemVersion().Name)
+	}
+	names = append(names, "foo")
+
+	destroy := destroyableBookmarks(abs, names)
+	require.Len(t, destroy, 2)
+	assert.Same(t, abs[0], destroy[0])
+	assert.Same(t, abs[2], destroy[1])
+
+	assert.Empty(t, destroyableBookmarks(abs, nil))
+}
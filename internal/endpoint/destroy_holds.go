@@ -0,0 +1,87 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// zfs destroy fails with this reason, if the snapshot has user holds.
+const datasetBusy = "dataset is busy"
+
+// checkHolds looks for user holds of every snapshot, which zfs destroy
+// reported as busy. If releaseHolds isn't nil and matches every tag of a
+// snapshot, it releases the holds and destroys the snapshot again.
+//
+// It returns snapshots, which are still held, and errors of other
+// undestroyable snapshots like "name: reason".
+func checkHolds(ctx context.Context, fs string, de *zfs.DestroySnapshotsError,
+	releaseHolds *regexp.Regexp,
+) (held []pdu.HeldSnapshot, errs []string) {
+	for i, name := range de.Undestroyable {
+		reason := de.Reason[i]
+		if reason != datasetBusy {
+			errs = append(errs, name+": "+reason)
+			continue
+		}
+
+		tags, err := zfs.ZFSHolds(ctx, fs, name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s (%s)", name, reason, err))
+			continue
+		} else if len(tags) == 0 {
+			errs = append(errs, name+": "+reason)
+			continue
+		}
+
+		if releasableHolds(tags, releaseHolds) {
+			if err := releaseAndDestroy(ctx, fs, name, tags); err != nil {
+				errs = append(errs, name+": "+err.Error())
+			}
+			continue
+		}
+		held = append(held, pdu.HeldSnapshot{Name: name, Tags: tags})
+	}
+	return held, errs
+}
+
+// releasableHolds returns true if re matches every tag and no tag belongs to
+// zrepl.
+func releasableHolds(tags []string, re *regexp.Regexp) bool {
+	if re == nil {
+		return false
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "zrepl_") || !re.MatchString(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func releaseAndDestroy(ctx context.Context, fs, name string, tags []string,
+) error {
+	path := fs + "@" + name
+	l := getLogger(ctx).With(slog.String("snapshot", path))
+	for _, tag := range tags {
+		err := zfs.ZFSRelease(ctx, tag, path)
+		audit.Record(ctx, "release", path, err, "tag", tag)
+		if err != nil {
+			return err //nolint:wrapcheck // already wrapped
+		}
+		l.With(slog.String("tag", tag)).Info("released hold")
+	}
+
+	err := zfs.ZFSDestroy(ctx, path)
+	audit.Record(ctx, "destroy", path, err)
+	lifecycle.Record(ctx, lifecycle.Destroyed, fs, name, err,
+		"released", strings.Join(tags, ","))
+	return err
+}
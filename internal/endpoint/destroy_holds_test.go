@@ -0,0 +1,17 @@
+package endpoint
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleasableHolds(t *testing.T) {
+	re := regexp.MustCompile(`^backup-`)
+	assert.False(t, releasableHolds([]string{"backup-1"}, nil))
+	assert.True(t, releasableHolds([]string{"backup-1", "backup-2"}, re))
+	assert.False(t, releasableHolds([]string{"backup-1", "other"}, re))
+	assert.False(t, releasableHolds([]string{"zrepl_STEP_J_foo"},
+		regexp.MustCompile(`.*`)))
+}
@@ -13,12 +13,15 @@ import (
 	"path"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/endpoint/lifecycle"
 	"github.com/dsh2dsh/zrepl/internal/logger"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
 	"github.com/dsh2dsh/zrepl/internal/util/chainlock"
@@ -40,8 +43,18 @@ type SenderConfig struct {
 	SendCompressed       bool
 	SendEmbeddedData     bool
 	SendSaved            bool
+	// SendHolds sends user holds of snapshots with the stream (zfs send -h).
+	SendHolds bool
+
+	// StorageClasses tag filesystems with storage classes, which receivers map
+	// to their roots. User property StorageClassProperty, if not empty, tags
+	// them too and takes precedence over StorageClasses.
+	StorageClasses       []SendStorageClass
+	StorageClassProperty string
 
 	ExecPipe [][]string
+	// Buffer is size of in-memory ring buffer between zfs send and the network.
+	Buffer int
 }
 
 func (c *SenderConfig) Validate() error {
@@ -58,6 +71,9 @@ type Sender struct {
 	jobId    JobID
 	config   SenderConfig
 
+	// clientFilter restricts filesystems of FSFilter for current client.
+	clientFilter *filters.DatasetFilter
+
 	drySendConcurrency int
 	pruneConcurrency   int
 }
@@ -85,6 +101,15 @@ func (s *Sender) WithPruneConcurrency(n int) *Sender {
 	return s
 }
 
+// WithClientFilter restricts filesystems, which the client can list and send,
+// to filesystems passing fsf too. Recursive sends aren't allowed for such
+// client, because they can include filesystems not passing fsf, so listed
+// filesystems are sent one by one.
+func (s *Sender) WithClientFilter(fsf *filters.DatasetFilter) *Sender {
+	s.clientFilter = fsf
+	return s
+}
+
 func (s *Sender) filterCheckFS(fs string) (*zfs.DatasetPath, error) {
 	dp, err := zfs.NewDatasetPath(fs)
 	if err != nil {
@@ -100,11 +125,30 @@ func (s *Sender) filterCheckFS(fs string) (*zfs.DatasetPath, error) {
 	if !pass {
 		return nil, fmt.Errorf("endpoint does not allow access to filesystem %s", fs)
 	}
+
+	if s.clientFilter != nil {
+		if pass, err = s.clientFilter.Filter(dp); err != nil {
+			return nil, err
+		} else if !pass {
+			return nil, fmt.Errorf(
+				"client is not allowed to access filesystem %s", fs)
+		}
+	}
 	return dp, nil
 }
 
 func (s *Sender) ListFilesystems(ctx context.Context) (*pdu.ListFilesystemRes,
 	error,
+) {
+	res, err := s.listFilesystems(ctx)
+	if err != nil || s.clientFilter == nil {
+		return res, err
+	}
+	return s.clientFilesystems(res)
+}
+
+func (s *Sender) listFilesystems(ctx context.Context) (*pdu.ListFilesystemRes,
+	error,
 ) {
 	if root := s.FSFilter.SingleRecursiveDataset(); root != nil {
 		return s.listFilesystemsRecursive(ctx, root)
@@ -132,10 +176,37 @@ func (s *Sender) ListFilesystems(ctx context.Context) (*pdu.ListFilesystemRes,
 		}
 	}
 
+	if err := s.storageClasses(ctx, rfss); err != nil {
+		return nil, err
+	}
+
 	res := &pdu.ListFilesystemRes{Filesystems: rfss}
 	return res, nil
 }
 
+// clientFilesystems returns filesystems of res, which pass client filter. They
+// are sent one by one, not as a part of recursive send.
+func (s *Sender) clientFilesystems(res *pdu.ListFilesystemRes,
+) (*pdu.ListFilesystemRes, error) {
+	filesystems := make([]*pdu.Filesystem, 0, len(res.Filesystems))
+	for _, fs := range res.Filesystems {
+		dp, err := zfs.NewDatasetPath(fs.Path)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := s.clientFilter.Filter(dp)
+		if err != nil {
+			return nil, err
+		} else if !pass {
+			continue
+		}
+		fs.Replicate, fs.Exclude, fs.Replicated = false, "", false
+		filesystems = append(filesystems, fs)
+	}
+	res.Filesystems = filesystems
+	return res, nil
+}
+
 func (s *Sender) listFilesystemsRecursive(ctx context.Context,
 	root *zfs.DatasetPath,
 ) (*pdu.ListFilesystemRes, error) {
@@ -143,6 +214,8 @@ func (s *Sender) listFilesystemsRecursive(ctx context.Context,
 		zfs.PlaceholderPropertyName)
 	if err != nil {
 		return nil, err
+	} else if err := s.storageClasses(ctx, res.Filesystems); err != nil {
+		return nil, err
 	} else if len(res.Filesystems) < 2 {
 		return res, nil
 	}
@@ -204,7 +277,20 @@ func (s *Sender) ListFilesystemVersions(ctx context.Context, r *pdu.ListFilesyst
 	if err != nil {
 		return nil, err
 	}
-	fsvs, err := zfs.ZFSListFilesystemVersions(ctx, lp, zfs.ListFilesystemVersionsOptions{})
+	return listFilesystemVersions(ctx, lp, r.Properties)
+}
+
+func listFilesystemVersions(ctx context.Context, lp *zfs.DatasetPath,
+	props []string,
+) (*pdu.ListFilesystemVersionsRes, error) {
+	for _, name := range props {
+		if !strings.Contains(name, ":") {
+			return nil, fmt.Errorf("not a user property: %q", name)
+		}
+	}
+
+	fsvs, err := zfs.ZFSListFilesystemVersions(ctx, lp,
+		zfs.ListFilesystemVersionsOptions{Properties: props})
 	if err != nil {
 		return nil, err
 	}
@@ -212,8 +298,7 @@ func (s *Sender) ListFilesystemVersions(ctx context.Context, r *pdu.ListFilesyst
 	for i := range fsvs {
 		rfsvs[i] = pdu.FilesystemVersionFromZFS(&fsvs[i])
 	}
-	res := &pdu.ListFilesystemVersionsRes{Versions: rfsvs}
-	return res, nil
+	return &pdu.ListFilesystemVersionsRes{Versions: rfsvs}, nil
 }
 
 func uncheckedSendArgsFromPDU(fsv *pdu.FilesystemVersion) *zfs.ZFSSendArgVersion {
@@ -239,6 +324,9 @@ func (s *Sender) sendMakeArgs(ctx context.Context, r *pdu.SendReq) (sendArgs zfs
 	_, err := s.filterCheckFS(r.Filesystem)
 	if err != nil {
 		return sendArgs, err
+	} else if r.Replicate && s.clientFilter != nil {
+		return sendArgs, fmt.Errorf(
+			"client is not allowed to send filesystem %s recursively", r.Filesystem)
 	}
 
 	sendArgsUnvalidated := zfs.ZFSSendArgsUnvalidated{
@@ -255,10 +343,12 @@ func (s *Sender) sendMakeArgs(ctx context.Context, r *pdu.SendReq) (sendArgs zfs
 			Compressed:       s.config.SendCompressed,
 			EmbeddedData:     s.config.SendEmbeddedData,
 			Saved:            s.config.SendSaved,
+			Holds:            s.config.SendHolds,
 			Multi:            r.Multi,
 			Replicate:        r.Replicate,
 			Exclude:          r.Exclude,
 		},
+		Buffer: s.config.Buffer,
 	}
 
 	sendArgs, err = sendArgsUnvalidated.Validate(ctx)
@@ -406,6 +496,11 @@ func (s *Sender) Send(ctx context.Context, r *pdu.SendReq) (*pdu.SendRes,
 			s.jobId, sendArgs.FS, destroyTypes, keep, check)
 	}()
 
+	encrypted, err := sendsEncrypted(ctx, &sendArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var sendStream io.ReadCloser
 	sendStream, err = zfs.ZFSSend(ctx, sendArgs, s.config.ExecPipe...)
 	if err != nil {
@@ -414,7 +509,11 @@ func (s *Sender) Send(ctx context.Context, r *pdu.SendReq) (*pdu.SendRes,
 		return nil, nil, fmt.Errorf("zfs send failed: %w", err)
 	}
 
-	res := &pdu.SendRes{UsedResumeToken: r.ResumeToken != ""}
+	res := &pdu.SendRes{
+		UsedResumeToken: r.ResumeToken != "",
+		Encrypted:       encrypted,
+		Holds:           sendArgs.Holds && r.ResumeToken == "",
+	}
 	return res, sendStream, nil
 }
 
@@ -531,12 +630,19 @@ func (s *Sender) SendCompleted(ctx context.Context, r *pdu.SendCompletedReq,
 	}
 	abstractionsCacheSingleton.TryBatchDestroy(ctx, s.jobId, fs, destroyTypes, keep, nil)
 
+	if from != nil {
+		lifecycle.Record(ctx, lifecycle.Replicated, fs, to.Name, nil,
+			"from", from.Name)
+	} else {
+		lifecycle.Record(ctx, lifecycle.Replicated, fs, to.Name, nil)
+	}
 	return nil
 }
 
 func (s *Sender) DestroySnapshots(ctx context.Context,
 	req *pdu.DestroySnapshotsReq,
 ) (*pdu.DestroySnapshotsRes, error) {
+	ctx = lifecycle.WithReason(ctx, req.Reason)
 	iter := func(yield func(*pdu.DestroySnapshots, error) bool) {
 		for i := range req.Filesystems {
 			r := &req.Filesystems[i]
@@ -546,7 +652,8 @@ func (s *Sender) DestroySnapshots(ctx context.Context,
 			}
 		}
 	}
-	return destroySnapshots(ctx, s.pruneConcurrency, len(req.Filesystems), iter)
+	return destroySnapshots(ctx, s.pruneConcurrency, len(req.Filesystems), iter,
+		keepCursorBases, req.ReleaseHolds)
 }
 
 func (*Sender) WaitForConnectivity(ctx context.Context) error { return nil }
@@ -554,19 +661,61 @@ func (*Sender) WaitForConnectivity(ctx context.Context) error { return nil }
 func (s *Sender) ReplicationCursor(ctx context.Context,
 	req *pdu.ReplicationCursorReq,
 ) (*pdu.ReplicationCursorRes, error) {
-	dp, err := s.filterCheckFS(req.Filesystem)
+	if len(req.Filesystems) > 0 {
+		return s.replicationCursors(ctx, req.Filesystems)
+	}
+
+	result, err := s.replicationCursor(ctx, req.Filesystem)
 	if err != nil {
 		return nil, err
 	}
+	return &pdu.ReplicationCursorRes{Result: result}, nil
+}
 
-	cursor, err := GetMostRecentReplicationCursorOfJob(ctx, dp.ToString(), s.jobId)
+// replicationCursors looks up replication cursors of all filesystems fss
+// concurrently and returns them in one response, so the caller doesn't need a
+// round trip per filesystem.
+func (s *Sender) replicationCursors(ctx context.Context, fss []string,
+) (*pdu.ReplicationCursorRes, error) {
+	results := make([]*pdu.ReplicationCursorRes_Result, len(fss))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for i, fs := range fss {
+		g.Go(func() (err error) {
+			results[i], err = s.replicationCursor(ctx, fs)
+			if err != nil {
+				return fmt.Errorf("replication cursor %q: %w", fs, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err //nolint:wrapcheck // it's our error
+	}
+
+	resp := &pdu.ReplicationCursorRes{
+		Results: make(map[string]*pdu.ReplicationCursorRes_Result, len(fss)),
+	}
+	for i, fs := range fss {
+		resp.Results[fs] = results[i]
+	}
+	return resp, nil
+}
+
+func (s *Sender) replicationCursor(ctx context.Context, fs string,
+) (*pdu.ReplicationCursorRes_Result, error) {
+	dp, err := s.filterCheckFS(fs)
 	if err != nil {
 		return nil, err
 	}
-	if cursor == nil {
-		return &pdu.ReplicationCursorRes{Result: &pdu.ReplicationCursorRes_Result{Notexist: true}}, nil
+
+	cursor, err := GetMostRecentReplicationCursorOfJob(ctx, dp.ToString(), s.jobId)
+	if err != nil {
+		return nil, err
+	} else if cursor == nil {
+		return &pdu.ReplicationCursorRes_Result{Notexist: true}, nil
 	}
-	return &pdu.ReplicationCursorRes{Result: &pdu.ReplicationCursorRes_Result{Guid: cursor.Guid}}, nil
+	return &pdu.ReplicationCursorRes_Result{Guid: cursor.Guid}, nil
 }
 
 func (*Sender) Receive(ctx context.Context, r *pdu.ReceiveReq, _ io.ReadCloser,
@@ -574,6 +723,10 @@ func (*Sender) Receive(ctx context.Context, r *pdu.ReceiveReq, _ io.ReadCloser,
 	return errors.New("sender does not implement Receive()")
 }
 
+func (*Sender) Adopt(context.Context, *pdu.AdoptReq) error {
+	return errors.New("sender does not implement Adopt()")
+}
+
 // NOTE: when adding members to this struct, remember
 // to add them to `ReceiverConfig.copyIn()`
 type ReceiverConfig struct {
@@ -581,13 +734,25 @@ type ReceiverConfig struct {
 
 	RootWithoutClientComponent *zfs.DatasetPath
 	AppendClientIdentity       bool
+	RootMap                    []RootMapping
+	StorageClasses             []StorageClass
 
 	InheritProperties  []zfsprop.Property
 	OverrideProperties map[zfsprop.Property]string
+	// ReencryptProperties, like encryption=on, are set on initial receives of
+	// plaintext sends, which are encrypted by receiver.
+	ReencryptProperties map[zfsprop.Property]string
+	// DatasetProperties apply to received filesystems, which local names match
+	// them, after InheritProperties and OverrideProperties.
+	DatasetProperties []DatasetProperties
 
 	PlaceholderEncryption PlaceholderCreationEncryptionProperty
+	MountpointCollision   MountpointCollision
+	FreeSpace             FreeSpace
 
 	ExecPipe [][]string
+	// Buffer is size of in-memory ring buffer between the network and zfs recv.
+	Buffer int
 }
 
 //go:generate enumer -type=PlaceholderCreationEncryptionProperty -transform=kebab -trimprefix=PlaceholderCreationEncryptionProperty
@@ -603,6 +768,20 @@ const (
 func (c *ReceiverConfig) copyIn() {
 	c.RootWithoutClientComponent = c.RootWithoutClientComponent.Copy()
 
+	rootMap := make([]RootMapping, len(c.RootMap))
+	copy(rootMap, c.RootMap)
+	for i := range rootMap {
+		rootMap[i].copyIn()
+	}
+	c.RootMap = rootMap
+
+	storageClasses := make([]StorageClass, len(c.StorageClasses))
+	copy(storageClasses, c.StorageClasses)
+	for i := range storageClasses {
+		storageClasses[i].copyIn()
+	}
+	c.StorageClasses = storageClasses
+
 	pInherit := make([]zfsprop.Property, len(c.InheritProperties))
 	copy(pInherit, c.InheritProperties)
 	c.InheritProperties = pInherit
@@ -610,6 +789,12 @@ func (c *ReceiverConfig) copyIn() {
 	pOverride := make(map[zfsprop.Property]string, len(c.OverrideProperties))
 	maps.Copy(pOverride, c.OverrideProperties)
 	c.OverrideProperties = pOverride
+	c.ReencryptProperties = maps.Clone(c.ReencryptProperties)
+
+	c.DatasetProperties = slices.Clone(c.DatasetProperties)
+	for i := range c.DatasetProperties {
+		c.DatasetProperties[i].copyIn()
+	}
 }
 
 func (c *ReceiverConfig) Validate() error {
@@ -629,10 +814,43 @@ func (c *ReceiverConfig) Validate() error {
 		}
 	}
 
+	for prop := range c.ReencryptProperties {
+		if err := prop.Validate(); err != nil {
+			return fmt.Errorf("reencrypt property %q: %w", prop, err)
+		}
+	}
+
+	for i := range c.DatasetProperties {
+		if err := c.DatasetProperties[i].Validate(); err != nil {
+			return fmt.Errorf("dataset properties #%d: %w", i+1, err)
+		}
+	}
+
 	if c.RootWithoutClientComponent.Length() <= 0 {
 		return errors.New("RootWithoutClientComponent must not be an empty dataset path")
 	}
 
+	for i := range c.RootMap {
+		if err := c.RootMap[i].Validate(); err != nil {
+			return fmt.Errorf("root map #%d: %w", i, err)
+		}
+	}
+
+	for i := range c.StorageClasses {
+		if err := c.StorageClasses[i].Validate(); err != nil {
+			return fmt.Errorf("storage class #%d: %w", i, err)
+		} else if c.storageClass(c.StorageClasses[i].Name) != &c.StorageClasses[i] {
+			return fmt.Errorf("storage class #%d: duplicate name %q", i,
+				c.StorageClasses[i].Name)
+		}
+	}
+
+	if err := c.MountpointCollision.Validate(); err != nil {
+		return fmt.Errorf("mountpoint collision: %w", err)
+	} else if err := c.FreeSpace.Validate(); err != nil {
+		return err
+	}
+
 	if !c.PlaceholderEncryption.IsAPlaceholderCreationEncryptionProperty() {
 		return errors.New("`PlaceholderEncryption` field is invalid")
 	}
@@ -672,9 +890,11 @@ func (s *Receiver) WithPruneConcurrency(n int) *Receiver {
 	return s
 }
 
-func (s *Receiver) clientRootFromCtx(ctx context.Context) *zfs.DatasetPath {
+func (s *Receiver) clientRootFromCtx(ctx context.Context,
+	root *zfs.DatasetPath,
+) *zfs.DatasetPath {
 	if !s.conf.AppendClientIdentity {
-		return s.conf.RootWithoutClientComponent.Copy()
+		return root.Copy()
 	}
 
 	var clientIdentity string
@@ -689,8 +909,7 @@ func (s *Receiver) clientRootFromCtx(ctx context.Context) *zfs.DatasetPath {
 		clientIdentity = identity
 	}
 
-	clientRoot, err := ClientRoot(s.conf.RootWithoutClientComponent,
-		clientIdentity)
+	clientRoot, err := ClientRoot(root, clientIdentity)
 	if err != nil {
 		err = fmt.Errorf(
 			"ClientIdentityContextKey must have been validated before invoking Receiver: %w",
@@ -720,8 +939,7 @@ const receiveResumeToken = "receive_resume_token"
 func (s *Receiver) ListFilesystems(ctx context.Context) (*pdu.ListFilesystemRes,
 	error,
 ) {
-	return listFilesystemsRecursive(ctx, s.clientRootFromCtx(ctx), false,
-		zfs.PlaceholderPropertyName, receiveResumeToken)
+	return s.listFilesystems(ctx)
 }
 
 func listFilesystemsRecursive(ctx context.Context, root *zfs.DatasetPath,
@@ -807,22 +1025,11 @@ func makeFilesystems(ctx context.Context, root *zfs.DatasetPath,
 func (s *Receiver) ListFilesystemVersions(ctx context.Context,
 	req *pdu.ListFilesystemVersionsReq,
 ) (*pdu.ListFilesystemVersionsRes, error) {
-	lp, err := mapToLocal(s.clientRootFromCtx(ctx), req.GetFilesystem())
+	_, lp, err := s.mapToLocal(ctx, req.GetFilesystem())
 	if err != nil {
 		return nil, err
 	}
-	// TODO share following code with sender
-
-	fsvs, err := zfs.ZFSListFilesystemVersions(ctx, lp, zfs.ListFilesystemVersionsOptions{})
-	if err != nil {
-		return nil, err
-	}
-
-	rfsvs := make([]*pdu.FilesystemVersion, len(fsvs))
-	for i := range fsvs {
-		rfsvs[i] = pdu.FilesystemVersionFromZFS(&fsvs[i])
-	}
-	return &pdu.ListFilesystemVersionsRes{Versions: rfsvs}, nil
+	return listFilesystemVersions(ctx, lp, req.Properties)
 }
 
 func mapToLocal(root *zfs.DatasetPath, fs string) (*zfs.DatasetPath, error) {
@@ -888,17 +1095,20 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 	defer receive.Close()
 	getLogger(ctx).Debug("incoming Receive")
 
-	root := s.clientRootFromCtx(ctx)
-	lp, err := mapToLocal(root, req.Filesystem)
+	rootFS, lp, storageClass, err := s.locate(ctx, req.Filesystem,
+		req.StorageClass)
 	if err != nil {
 		return fmt.Errorf("`Filesystem` invalid: %w", err)
 	}
+	root := s.clientRootFromCtx(ctx, rootFS)
 
 	to := uncheckedSendArgsFromPDU(req.GetTo())
 	if to == nil {
 		return errors.New("`To` must not be nil")
 	} else if !to.IsSnapshot() {
 		return errors.New("`To` must be a snapshot")
+	} else if req.Encrypted && s.conf.Reencrypting() {
+		return ErrReencryptRaw
 	}
 
 	// create placeholder parent filesystems as appropriate
@@ -940,13 +1150,13 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 			}
 
 			if !ph.FSExists {
-				if s.conf.RootWithoutClientComponent.HasPrefix(v.Path) {
+				if rootFS.HasPrefix(v.Path) {
 					if v.Path.Length() == 1 {
 						visitErr = fmt.Errorf("pool %q not imported",
 							v.Path.ToString())
 					} else {
 						visitErr = fmt.Errorf("root_fs %q does not exist",
-							s.conf.RootWithoutClientComponent.ToString())
+							rootFS.ToString())
 					}
 					logger.WithError(l, visitErr,
 						"placeholders are only created automatically below root_fs")
@@ -1009,8 +1219,13 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 
 	recvOpts := zfs.RecvOptions{
 		SavePartialRecvState: true,
-		InheritProperties:    s.conf.InheritProperties,
-		OverrideProperties:   s.conf.OverrideProperties,
+		Buffer:               s.conf.Buffer,
+	}
+	inherit, override := s.conf.recvProperties(storageClass)
+	recvOpts.InheritProperties, recvOpts.OverrideProperties, err = s.conf.
+		datasetProperties(lp, inherit, override)
+	if err != nil {
+		return err
 	}
 
 	var clearPlaceholderProperty bool
@@ -1019,6 +1234,19 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 		clearPlaceholderProperty = true
 	}
 
+	if err := s.conf.FreeSpace.Check(ctx, lp, ph.FSExists,
+		req.ExpectedSize); err != nil {
+		return err
+	}
+
+	firstRecv := !ph.FSExists || ph.IsPlaceholder
+	if firstRecv {
+		recvOpts.OverrideProperties = s.conf.reencryptProperties(
+			recvOpts.OverrideProperties)
+	}
+	checkMountpoint := firstRecv && s.conf.MountpointCollision.Enabled()
+	recvOpts.NoMount = checkMountpoint
+
 	if clearPlaceholderProperty {
 		log.Info("clearing placeholder property")
 		if err := zfs.ZFSSetPlaceholder(ctx, lp, false); err != nil {
@@ -1029,7 +1257,9 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 
 	if req.ClearResumeToken && ph.FSExists {
 		log.Info("clearing resume token")
-		if err := zfs.ZFSRecvClearResumeToken(ctx, lp.ToString()); err != nil {
+		err := zfs.ZFSRecvClearResumeToken(ctx, lp.ToString())
+		audit.Record(ctx, "abort-resume", lp.ToString(), err)
+		if err != nil {
 			return fmt.Errorf("cannot clear resume token: %w", err)
 		}
 	}
@@ -1040,6 +1270,10 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 	snapFullPath := to.FullPath(lp.ToString())
 	err = zfs.ZFSRecv(ctx, lp.ToString(), to, receive, recvOpts,
 		s.conf.ExecPipe...)
+	audit.Record(ctx, "recv", snapFullPath, err,
+		"rollback", strconv.FormatBool(recvOpts.RollbackAndForceRecv))
+	lifecycle.Record(ctx, lifecycle.Received, lp.ToString(), to.RelName, err,
+		"from", req.Filesystem)
 	if err != nil {
 		logger.WithError(
 			log.With(slog.String("opts", fmt.Sprintf("%#v", recvOpts))),
@@ -1058,6 +1292,18 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 		return fmt.Errorf("%s: %w", msg, err)
 	}
 
+	if checkMountpoint {
+		if err := s.conf.MountpointCollision.Resolve(ctx, log, lp); err != nil {
+			return err
+		}
+	}
+
+	if req.Holds {
+		if err := releaseSentHolds(ctx, lp.ToString(), toRecvd); err != nil {
+			return err
+		}
+	}
+
 	replicationGuaranteeOptions, err := replicationGuaranteeOptionsFromPDU(
 		req.GetReplicationConfig().Protection)
 	if err != nil {
@@ -1099,23 +1345,103 @@ func (s *Receiver) Receive(ctx context.Context, req *pdu.ReceiveReq,
 	return nil
 }
 
+// Adopt makes snapshot To of a filesystem, received by other means, like
+// manual zfs send and recv, the latest received snapshot of this job. It
+// verifies the snapshot exists and has the same GUID, clears placeholder
+// property of the filesystem and creates last-received hold on the snapshot.
+func (s *Receiver) Adopt(ctx context.Context, req *pdu.AdoptReq) error {
+	_, lp, err := s.mapToLocal(ctx, req.GetFilesystem())
+	if err != nil {
+		return fmt.Errorf("`Filesystem` invalid: %w", err)
+	}
+
+	to := uncheckedSendArgsFromPDU(req.GetTo())
+	if to == nil {
+		return errors.New("`To` must not be nil")
+	} else if !to.IsSnapshot() {
+		return errors.New("`To` must be a snapshot")
+	}
+
+	log := getLogger(ctx).With(
+		slog.String("proto_fs", req.GetFilesystem()),
+		slog.String("local_fs", lp.ToString()),
+		slog.String("to", to.RelName))
+
+	toRecvd, err := to.ValidateExistsAndGetVersion(ctx, lp.ToString())
+	if err != nil {
+		return fmt.Errorf("validate `To` exists: %w", err)
+	}
+
+	ph, err := zfs.ZFSGetFilesystemPlaceholderState(ctx, lp)
+	if err != nil {
+		return fmt.Errorf("cannot get placeholder state: %w", err)
+	} else if ph.IsPlaceholder {
+		log.Info("clearing placeholder property")
+		if err := zfs.ZFSSetPlaceholder(ctx, lp, false); err != nil {
+			return fmt.Errorf("cannot clear placeholder property: %w", err)
+		}
+	}
+
+	replicationConfig := req.GetReplicationConfig()
+	if replicationConfig == nil {
+		return errors.New("`ReplicationConfig` must not be nil")
+	}
+	replicationGuaranteeOptions, err := replicationGuaranteeOptionsFromPDU(
+		replicationConfig.Protection)
+	if err != nil {
+		return err
+	}
+	liveAbs, err := replicationGuaranteeOptions.Strategy(true).
+		ReceiverPostRecv(ctx, s.conf.JobID, lp.ToString(), toRecvd)
+	if err != nil {
+		return err
+	}
+	for _, a := range liveAbs {
+		if a != nil {
+			abstractionsCacheSingleton.Put(a)
+		}
+	}
+	keep := func(a Abstraction) (keep bool) {
+		for _, k := range liveAbs {
+			keep = keep || AbstractionEquals(a, k)
+		}
+		return keep
+	}
+	destroyTypes := AbstractionTypeSet{
+		AbstractionLastReceivedHold: true,
+	}
+	abstractionsCacheSingleton.TryBatchDestroy(ctx, s.conf.JobID,
+		lp.ToString(), destroyTypes, keep, nil)
+
+	log.Info("adopted filesystem")
+	return nil
+}
+
 func (s *Receiver) DestroySnapshots(ctx context.Context,
 	req *pdu.DestroySnapshotsReq,
 ) (*pdu.DestroySnapshotsRes, error) {
-	clientRoot := s.clientRootFromCtx(ctx)
+	ctx = lifecycle.WithReason(ctx, req.Reason)
 	iter := func(yield func(*pdu.DestroySnapshots, error) bool) {
 		for i := range req.Filesystems {
 			r := &req.Filesystems[i]
-			lp, err := mapToLocal(clientRoot, r.Filesystem)
+			_, lp, err := s.mapToLocal(ctx, r.Filesystem)
 			if err == nil {
 				r.SetLocalPath(lp.ToString())
+				if len(r.Bookmarks) != 0 {
+					err = errors.New("receiver doesn't destroy bookmarks")
+				}
 			}
 			if !yield(r, err) {
 				return
 			}
 		}
 	}
-	return destroySnapshots(ctx, s.pruneConcurrency, len(req.Filesystems), iter)
+	var filter destroyFilter
+	if req.DestroyDelay > 0 {
+		filter = delayDestroys(req.DestroyDelay)
+	}
+	return destroySnapshots(ctx, s.pruneConcurrency, len(req.Filesystems), iter,
+		filter, req.ReleaseHolds)
 }
 
 func (*Receiver) SendCompleted(context.Context, *pdu.SendCompletedReq) error {
@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func newTestClientFilterSender(t *testing.T) *Sender {
+	fsf, err := filters.NewFromConfig(config.FilesystemsFilter{
+		"tank<":        true,
+		"tank/secret<": false,
+	}, nil)
+	require.NoError(t, err)
+
+	clientFilter, err := filters.NewFromConfig(config.FilesystemsFilter{
+		"tank/vm<": true,
+		"tank/db":  true,
+	}, nil)
+	require.NoError(t, err)
+
+	return NewSender(SenderConfig{FSF: fsf, JobID: MustMakeJobID("source")}).
+		WithClientFilter(clientFilter)
+}
+
+func TestSender_WithClientFilter_filterCheckFS(t *testing.T) {
+	s := newTestClientFilterSender(t)
+	tests := []struct {
+		fs      string
+		wantErr bool
+	}{
+		{fs: "tank/vm"},
+		{fs: "tank/vm/a"},
+		{fs: "tank/db"},
+		{fs: "tank", wantErr: true},
+		{fs: "tank/db/log", wantErr: true},
+		{fs: "tank/home", wantErr: true},
+		{fs: "tank/secret", wantErr: true},
+		{fs: "zroot/vm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fs, func(t *testing.T) {
+			dp, err := s.filterCheckFS(tt.fs)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.fs, dp.ToString())
+		})
+	}
+}
+
+func TestSender_WithClientFilter_clientFilesystems(t *testing.T) {
+	s := newTestClientFilterSender(t)
+	res, err := s.clientFilesystems(&pdu.ListFilesystemRes{
+		Filesystems: []*pdu.Filesystem{
+			{Path: "tank", Replicate: true, Exclude: "tank/secret"},
+			{Path: "tank/db", Replicated: true},
+			{Path: "tank/home", Replicated: true},
+			{Path: "tank/vm", Replicated: true},
+			{Path: "tank/vm/a", Replicated: true},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*pdu.Filesystem{
+		{Path: "tank/db"},
+		{Path: "tank/vm"},
+		{Path: "tank/vm/a"},
+	}, res.Filesystems)
+}
+
+func TestSender_WithClientFilter_sendMakeArgs(t *testing.T) {
+	s := newTestClientFilterSender(t)
+	_, err := s.sendMakeArgs(t.Context(), &pdu.SendReq{
+		Filesystem: "tank/vm",
+		Replicate:  true,
+	})
+	require.ErrorContains(t, err, "recursively")
+
+	_, err = s.sendMakeArgs(t.Context(), &pdu.SendReq{Filesystem: "tank/home"})
+	require.ErrorContains(t, err, "not allowed")
+}
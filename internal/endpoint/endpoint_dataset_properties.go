@@ -0,0 +1,82 @@
+package endpoint
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+// DatasetProperties are properties for receiving filesystems, which local
+// names pass FSF.
+type DatasetProperties struct {
+	FSF                *filters.DatasetFilter
+	InheritProperties  []zfsprop.Property
+	OverrideProperties map[zfsprop.Property]string
+}
+
+func (self *DatasetProperties) copyIn() {
+	self.InheritProperties = slices.Clone(self.InheritProperties)
+	self.OverrideProperties = maps.Clone(self.OverrideProperties)
+}
+
+func (self *DatasetProperties) Validate() error {
+	if self.FSF == nil {
+		return errors.New("filesystem filter must not be nil")
+	}
+	for _, prop := range self.InheritProperties {
+		if err := prop.Validate(); err != nil {
+			return fmt.Errorf("inherit property %q: %w", prop, err)
+		}
+	}
+	for prop := range self.OverrideProperties {
+		if err := prop.Validate(); err != nil {
+			return fmt.Errorf("override property %q: %w", prop, err)
+		}
+	}
+	return nil
+}
+
+// datasetProperties applies properties of all DatasetProperties, which match
+// local filesystem lp, in order, to inherit and override properties. A
+// property inherited by a later entry isn't overridden anymore, and vice versa.
+func (c *ReceiverConfig) datasetProperties(lp *zfs.DatasetPath,
+	inherit []zfsprop.Property, override map[zfsprop.Property]string,
+) ([]zfsprop.Property, map[zfsprop.Property]string, error) {
+	cloned := false
+	for i := range c.DatasetProperties {
+		dp := &c.DatasetProperties[i]
+		if ok, err := dp.FSF.Filter(lp); err != nil {
+			return nil, nil, fmt.Errorf(
+				"dataset properties #%d: filter %q: %w", i+1, lp.ToString(), err)
+		} else if !ok {
+			continue
+		}
+
+		if !cloned {
+			inherit, override = slices.Clone(inherit), maps.Clone(override)
+			if override == nil {
+				override = make(map[zfsprop.Property]string,
+					len(dp.OverrideProperties))
+			}
+			cloned = true
+		}
+
+		for _, prop := range dp.InheritProperties {
+			delete(override, prop)
+			if !slices.Contains(inherit, prop) {
+				inherit = append(inherit, prop)
+			}
+		}
+		for prop, value := range dp.OverrideProperties {
+			inherit = slices.DeleteFunc(inherit,
+				func(p zfsprop.Property) bool { return p == prop })
+			override[prop] = value
+		}
+	}
+	return inherit, override, nil
+}
@@ -0,0 +1,87 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+func TestReceiverConfig_datasetProperties(t *testing.T) {
+	hosts, err := filters.NewFromConfig(nil, []config.DatasetFilter{
+		{Pattern: "backup/hosts", Recursive: true},
+	})
+	require.NoError(t, err)
+	vars, err := filters.NewFromConfig(nil, []config.DatasetFilter{
+		{Pattern: "backup/hosts/*/var", Shell: true},
+	})
+	require.NoError(t, err)
+
+	c := ReceiverConfig{
+		DatasetProperties: []DatasetProperties{
+			{
+				FSF:                hosts,
+				InheritProperties:  []zfsprop.Property{"readonly"},
+				OverrideProperties: map[zfsprop.Property]string{"mountpoint": "none"},
+			},
+			{
+				FSF:                vars,
+				OverrideProperties: map[zfsprop.Property]string{"readonly": "on"},
+			},
+		},
+	}
+	inherit := []zfsprop.Property{"canmount"}
+	override := map[zfsprop.Property]string{"compression": "lz4"}
+
+	tests := []struct {
+		name         string
+		fs           string
+		wantInherit  []zfsprop.Property
+		wantOverride map[zfsprop.Property]string
+	}{
+		{
+			name:         "not matched",
+			fs:           "backup/other",
+			wantInherit:  inherit,
+			wantOverride: override,
+		},
+		{
+			name:        "hosts",
+			fs:          "backup/hosts/foo",
+			wantInherit: []zfsprop.Property{"canmount", "readonly"},
+			wantOverride: map[zfsprop.Property]string{
+				"compression": "lz4",
+				"mountpoint":  "none",
+			},
+		},
+		{
+			name:        "var of hosts",
+			fs:          "backup/hosts/foo/var",
+			wantInherit: []zfsprop.Property{"canmount"},
+			wantOverride: map[zfsprop.Property]string{
+				"compression": "lz4",
+				"mountpoint":  "none",
+				"readonly":    "on",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotInherit, gotOverride, err := c.datasetProperties(
+				mustDatasetPath(t, tt.fs), inherit, override)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantInherit, gotInherit)
+			assert.Equal(t, tt.wantOverride, gotOverride)
+		})
+	}
+	assert.Equal(t, []zfsprop.Property{"canmount"}, inherit)
+	assert.Equal(t, map[zfsprop.Property]string{"compression": "lz4"}, override)
+
+	c.DatasetProperties[0].FSF = nil
+	require.ErrorContains(t, c.DatasetProperties[0].Validate(), "must not be nil")
+}
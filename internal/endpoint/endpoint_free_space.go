@@ -0,0 +1,103 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// FreeSpace is a gate of receives, which fails a receive before it starts, if
+// expected size of its stream doesn't fit into available space of the target
+// dataset, or leaves less than Headroom percent of its pool free.
+type FreeSpace struct {
+	Enabled  bool
+	Headroom uint
+}
+
+func (self FreeSpace) Validate() error {
+	if self.Headroom >= 100 {
+		return fmt.Errorf("free space headroom %d%% must be less than 100%%",
+			self.Headroom)
+	}
+	return nil
+}
+
+// Check checks space for a stream of expected size, received into fs. fs
+// may not exist, then available space of its nearest existing parent is used.
+// Unknown expected size (zero) always passes.
+func (self FreeSpace) Check(ctx context.Context, fs *zfs.DatasetPath,
+	exists bool, expected uint64,
+) error {
+	if !self.Enabled || expected == 0 {
+		return nil
+	}
+
+	name := fs.ToString()
+	target := name
+	if !exists {
+		target = path.Dir(name)
+	}
+	avail, _, err := spaceOf(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	pool, _, _ := strings.Cut(name, "/")
+	poolAvail, poolUsed, err := spaceOf(ctx, pool)
+	if err != nil {
+		return err
+	}
+	return self.check(name, expected, avail, poolAvail, poolUsed)
+}
+
+func (self FreeSpace) check(fs string, expected, avail, poolAvail,
+	poolUsed uint64,
+) error {
+	headroom := (poolAvail + poolUsed) / 100 * uint64(self.Headroom)
+	if expected > avail || expected+headroom > poolAvail {
+		return &InsufficientSpaceError{
+			Filesystem: fs,
+			Expected:   expected,
+			Available:  min(avail, poolAvail),
+			Headroom:   headroom,
+		}
+	}
+	return nil
+}
+
+func spaceOf(ctx context.Context, fs string) (avail, used uint64, err error) {
+	props, err := zfs.ZFSGetRawAnySource(ctx, fs,
+		[]string{"available", "used"})
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot get available space of %q: %w", fs, err)
+	}
+
+	avail, err = strconv.ParseUint(props.Get("available"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse available space of %q: %w", fs, err)
+	}
+	used, err = strconv.ParseUint(props.Get("used"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse used space of %q: %w", fs, err)
+	}
+	return avail, used, nil
+}
+
+// InsufficientSpaceError is returned by [FreeSpace.Check], if a stream doesn't
+// fit into available space.
+type InsufficientSpaceError struct {
+	Filesystem string
+	Expected   uint64
+	Available  uint64
+	Headroom   uint64
+}
+
+func (self *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf(
+		"insufficient space to receive %q: expected %d bytes, available %d bytes, headroom %d bytes",
+		self.Filesystem, self.Expected, self.Available, self.Headroom)
+}
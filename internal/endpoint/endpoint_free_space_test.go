@@ -0,0 +1,74 @@
+package endpoint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSpace_check(t *testing.T) {
+	const gb = 1 << 30
+	tests := []struct {
+		name      string
+		headroom  uint
+		expected  uint64
+		avail     uint64
+		poolAvail uint64
+		wantErr   bool
+	}{
+		{
+			name:      "fits",
+			headroom:  10,
+			expected:  10 * gb,
+			avail:     50 * gb,
+			poolAvail: 50 * gb,
+		},
+		{
+			name:      "without headroom",
+			expected:  50 * gb,
+			avail:     50 * gb,
+			poolAvail: 50 * gb,
+		},
+		{
+			name:      "eats headroom",
+			headroom:  10,
+			expected:  45 * gb,
+			avail:     50 * gb,
+			poolAvail: 50 * gb,
+			wantErr:   true,
+		},
+		{
+			name:      "quota",
+			expected:  10 * gb,
+			avail:     5 * gb,
+			poolAvail: 50 * gb,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := FreeSpace{Enabled: true, Headroom: tt.headroom}
+			err := fs.check("zroot/sink/foo", tt.expected, tt.avail,
+				tt.poolAvail, 100*gb-tt.poolAvail)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			spaceErr, ok := errors.AsType[*InsufficientSpaceError](err)
+			require.True(t, ok, err)
+			assert.Equal(t, "zroot/sink/foo", spaceErr.Filesystem)
+			assert.Equal(t, tt.expected, spaceErr.Expected)
+			assert.Equal(t, uint64(tt.headroom)*gb, spaceErr.Headroom)
+			assert.ErrorContains(t, err, "insufficient space")
+		})
+	}
+}
+
+func TestFreeSpace_Check_disabled(t *testing.T) {
+	require.NoError(t, FreeSpace{}.Check(t.Context(), nil, false, 1<<30))
+	require.NoError(t, FreeSpace{Enabled: true}.Check(t.Context(), nil, false, 0))
+	require.Error(t, FreeSpace{Headroom: 100}.Validate())
+}
@@ -43,6 +43,8 @@ func replicationGuaranteeKindFromPDU(in pdu.ReplicationGuaranteeKind) (k Replica
 		return ReplicationGuaranteeKindIncremental, nil
 	case pdu.ReplicationGuaranteeKind_GuaranteeResumability:
 		return ReplicationGuaranteeKindResumability, nil
+	case pdu.ReplicationGuaranteeKind_GuaranteeBookmarks:
+		return ReplicationGuaranteeKindBookmarks, nil
 
 	case pdu.ReplicationGuaranteeKind_GuaranteeInvalid:
 		fallthrough
@@ -66,6 +68,7 @@ const (
 	ReplicationGuaranteeKindResumability ReplicationGuaranteeKind = 1 << iota
 	ReplicationGuaranteeKindIncremental
 	ReplicationGuaranteeKindNone
+	ReplicationGuaranteeKindBookmarks
 )
 
 type ReplicationGuaranteeStrategy interface {
@@ -83,6 +86,8 @@ func ReplicationGuaranteeFromKind(k ReplicationGuaranteeKind) ReplicationGuarant
 		return ReplicationGuaranteeIncremental{}
 	case ReplicationGuaranteeKindResumability:
 		return ReplicationGuaranteeResumability{}
+	case ReplicationGuaranteeKindBookmarks:
+		return ReplicationGuaranteeBookmarks{}
 	default:
 		panic(fmt.Sprintf("unreachable: %q %T", k, k))
 	}
@@ -191,6 +196,52 @@ func (g ReplicationGuaranteeResumability) SenderPostRecvConfirmed(ctx context.Co
 	return senderPostRecvConfirmedCommon(ctx, jid, fs, to)
 }
 
+// ReplicationGuaranteeBookmarks never holds snapshots on sender. It protects
+// `from` and `to` by tentative replication cursor bookmarks only, so sender
+// pruning can destroy replicated snapshots at once, and the planner sends
+// incrementally from the replication cursor.
+type ReplicationGuaranteeBookmarks struct{}
+
+func (g ReplicationGuaranteeBookmarks) String() string { return "bookmarks" }
+
+func (g ReplicationGuaranteeBookmarks) Kind() ReplicationGuaranteeKind {
+	return ReplicationGuaranteeKindBookmarks
+}
+
+func (g ReplicationGuaranteeBookmarks) SenderPreSend(ctx context.Context, jid JobID, sendArgs *zfs.ZFSSendArgsValidated) (keep []Abstraction, err error) {
+	if from := sendArgs.FromVersion; from != nil {
+		if from.Type == zfs.Bookmark {
+			getLogger(ctx).With(
+				slog.String("replication_guarantee", g.String()),
+				slog.String("fromVersion", from.FullPath(sendArgs.FS)),
+			).Debug("`from` is a bookmark already")
+		} else {
+			from, err := CreateTentativeReplicationCursor(ctx, sendArgs.FS, *from,
+				jid)
+			if err != nil {
+				return nil, err
+			}
+			keep = append(keep, from)
+		}
+	}
+
+	to, err := CreateTentativeReplicationCursor(ctx, sendArgs.FS,
+		sendArgs.ToVersion, jid)
+	if err != nil {
+		return nil, err
+	}
+	keep = append(keep, to)
+	return keep, nil
+}
+
+func (g ReplicationGuaranteeBookmarks) ReceiverPostRecv(ctx context.Context, jid JobID, fs string, toRecvd zfs.FilesystemVersion) (keep []Abstraction, err error) {
+	return receiverPostRecvCommon(ctx, jid, fs, toRecvd)
+}
+
+func (g ReplicationGuaranteeBookmarks) SenderPostRecvConfirmed(ctx context.Context, jid JobID, fs string, to zfs.FilesystemVersion) (keep []Abstraction, err error) {
+	return senderPostRecvConfirmedCommon(ctx, jid, fs, to)
+}
+
 // helper function used by multiple strategies
 func senderPostRecvConfirmedCommon(ctx context.Context, jid JobID, fs string, to zfs.FilesystemVersion) (keep []Abstraction, err error) {
 	log := getLogger(ctx).With(slog.String("toVersion", to.FullPath(fs)))
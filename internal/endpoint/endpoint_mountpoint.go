@@ -0,0 +1,105 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// MountpointCollision is a policy for filesystems received first time, which
+// mountpoint shadows existing content on the receiver.
+type MountpointCollision string
+
+// Note: the constant values are part of the config format!
+const (
+	MountpointCollisionIgnore  MountpointCollision = "ignore"
+	MountpointCollisionNone    MountpointCollision = "none"
+	MountpointCollisionInherit MountpointCollision = "inherit"
+	MountpointCollisionFail    MountpointCollision = "fail"
+)
+
+func (self MountpointCollision) Validate() error {
+	switch self {
+	case "", MountpointCollisionIgnore, MountpointCollisionNone,
+		MountpointCollisionInherit, MountpointCollisionFail:
+		return nil
+	}
+	return fmt.Errorf("%q is not in {ignore,none,inherit,fail}", string(self))
+}
+
+func (self MountpointCollision) Enabled() bool {
+	return self != "" && self != MountpointCollisionIgnore
+}
+
+// Resolve checks mountpoint of fs, received with `zfs recv -u`, and applies
+// this policy, if it collides with existing content. fs is mounted, if it
+// doesn't collide or the collision was resolved.
+func (self MountpointCollision) Resolve(ctx context.Context, log *slog.Logger,
+	p *zfs.DatasetPath,
+) error {
+	fs := p.ToString()
+	props, err := zfs.ZFSGet(ctx, p, []string{"mountpoint", "canmount"})
+	if err != nil {
+		return fmt.Errorf("cannot get mountpoint of %q: %w", fs, err)
+	}
+	mountpoint, canmount := props.Get("mountpoint"), props.Get("canmount")
+	log = log.With(slog.String("mountpoint", mountpoint),
+		slog.String("canmount", canmount),
+		slog.String("policy", string(self)))
+	if !mountable(mountpoint, canmount) {
+		log.Debug("received filesystem is not mountable")
+		return nil
+	}
+
+	collides, err := zfs.MountpointCollides(mountpoint)
+	if err != nil {
+		return fmt.Errorf("cannot check mountpoint %q of %q: %w",
+			mountpoint, fs, err)
+	} else if !collides {
+		return mountReceived(ctx, log, fs, canmount)
+	}
+
+	log.Warn("mountpoint of received filesystem collides with existing path")
+	switch self {
+	case MountpointCollisionNone:
+		err := zfs.ZFSSet(ctx, p, map[string]string{"mountpoint": "none"})
+		if err != nil {
+			return fmt.Errorf("cannot set mountpoint=none on %q: %w", fs, err)
+		}
+		log.Info("set mountpoint=none on received filesystem")
+		return nil
+	case MountpointCollisionInherit:
+		if err := zfs.ZFSInherit(ctx, fs, "mountpoint"); err != nil {
+			return fmt.Errorf("cannot inherit mountpoint of %q: %w", fs, err)
+		}
+		log.Info("inherited mountpoint of received filesystem")
+		return MountpointCollisionFail.Resolve(ctx, log, p)
+	}
+	return fmt.Errorf(
+		"mountpoint %q of received filesystem %q collides with existing path, filesystem left unmounted",
+		mountpoint, fs)
+}
+
+func mountable(mountpoint, canmount string) bool {
+	switch mountpoint {
+	case "", "-", "none", "legacy":
+		return false
+	}
+	return canmount == "on"
+}
+
+func mountReceived(ctx context.Context, log *slog.Logger, fs, canmount string,
+) error {
+	if canmount != "on" {
+		return nil
+	}
+	// Mounting can fail for legitimate reasons, like an encrypted filesystem
+	// without loaded key. zfs recv without -u ignores it too.
+	if err := zfs.ZFSMount(ctx, fs); err != nil {
+		logger.WithError(log, err, "cannot mount received filesystem")
+	}
+	return nil
+}
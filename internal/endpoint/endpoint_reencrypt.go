@@ -0,0 +1,54 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+// ErrReencryptRaw is returned by receivers, which re-encrypt on receive, for
+// raw sends of encrypted filesystems, which can't be re-encrypted.
+var ErrReencryptRaw = errors.New(
+	"re-encryption on receive can't receive raw sends of encrypted filesystems, disable send.encrypted and send.raw on sender")
+
+// sendsEncrypted returns true, if sendArgs send a raw stream of an encrypted
+// filesystem.
+func sendsEncrypted(ctx context.Context, sendArgs *zfs.ZFSSendArgsValidated,
+) (bool, error) {
+	if sendArgs.Encrypted {
+		return true, nil
+	} else if !sendArgs.Raw {
+		return false, nil
+	}
+
+	encrypted, err := zfs.ZFSGetEncryptionEnabled(ctx, sendArgs.FS)
+	if err != nil {
+		return false, fmt.Errorf("check raw send is encrypted: %w", err)
+	}
+	return encrypted, nil
+}
+
+// Reencrypting returns true, if plaintext sends are encrypted on their initial
+// receive.
+func (c *ReceiverConfig) Reencrypting() bool {
+	return len(c.ReencryptProperties) != 0
+}
+
+// reencryptProperties returns override properties of an initial receive,
+// merged with properties, which encrypt it.
+func (c *ReceiverConfig) reencryptProperties(
+	override map[zfsprop.Property]string,
+) map[zfsprop.Property]string {
+	if !c.Reencrypting() {
+		return override
+	}
+	props := make(map[zfsprop.Property]string,
+		len(override)+len(c.ReencryptProperties))
+	maps.Copy(props, override)
+	maps.Copy(props, c.ReencryptProperties)
+	return props
+}
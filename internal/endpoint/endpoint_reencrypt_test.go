@@ -0,0 +1,44 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+func TestReceiverConfig_reencryptProperties(t *testing.T) {
+	override := map[zfsprop.Property]string{"compression": "zstd"}
+	c := ReceiverConfig{}
+	assert.False(t, c.Reencrypting())
+	assert.Equal(t, override, c.reencryptProperties(override))
+
+	c.ReencryptProperties = map[zfsprop.Property]string{
+		"encryption":  "on",
+		"keyformat":   "raw",
+		"keylocation": "file:///etc/zrepl/backup.key",
+	}
+	assert.True(t, c.Reencrypting())
+	assert.Equal(t, map[zfsprop.Property]string{
+		"compression": "zstd",
+		"encryption":  "on",
+		"keyformat":   "raw",
+		"keylocation": "file:///etc/zrepl/backup.key",
+	}, c.reencryptProperties(override))
+	assert.Equal(t, map[zfsprop.Property]string{"compression": "zstd"}, override)
+}
+
+func TestSendsEncrypted(t *testing.T) {
+	var sendArgs zfs.ZFSSendArgsValidated
+	encrypted, err := sendsEncrypted(t.Context(), &sendArgs)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+
+	sendArgs.Encrypted = true
+	encrypted, err = sendsEncrypted(t.Context(), &sendArgs)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+}
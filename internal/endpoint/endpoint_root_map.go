@@ -0,0 +1,128 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// RootMapping receives filesystems of the sender, which have Prefix, below
+// Root instead of RootWithoutClientComponent.
+type RootMapping struct {
+	Prefix *zfs.DatasetPath
+	Root   *zfs.DatasetPath
+}
+
+func (self *RootMapping) copyIn() {
+	self.Prefix = self.Prefix.Copy()
+	self.Root = self.Root.Copy()
+}
+
+func (self *RootMapping) Validate() error {
+	if self.Prefix.Length() <= 0 {
+		return errors.New("Prefix must not be an empty dataset path")
+	} else if self.Root.Length() <= 0 {
+		return errors.New("Root must not be an empty dataset path")
+	}
+	return nil
+}
+
+// rootFor returns root for filesystem fs of the sender, without client
+// component. It's the Root of mapping with the longest matching Prefix or
+// RootWithoutClientComponent, if nothing matches.
+func (c *ReceiverConfig) rootFor(fs *zfs.DatasetPath) *zfs.DatasetPath {
+	var found *RootMapping
+	for i := range c.RootMap {
+		m := &c.RootMap[i]
+		if fs.HasPrefix(m.Prefix) &&
+			(found == nil || m.Prefix.Length() > found.Prefix.Length()) {
+			found = m
+		}
+	}
+
+	if found == nil {
+		return c.RootWithoutClientComponent
+	}
+	return found.Root
+}
+
+// roots returns all distinct roots, without client component, the default one
+// first, including roots of storage classes.
+func (c *ReceiverConfig) roots() []*zfs.DatasetPath {
+	roots := make([]*zfs.DatasetPath, 1,
+		len(c.RootMap)+len(c.StorageClasses)+1)
+	roots[0] = c.RootWithoutClientComponent
+	for i := range c.RootMap {
+		roots = appendRoot(roots, c.RootMap[i].Root)
+	}
+	for i := range c.StorageClasses {
+		roots = appendRoot(roots, c.StorageClasses[i].Root)
+	}
+	return roots
+}
+
+// ownedBy returns true, if filesystem fs of the sender, received below root,
+// belongs to this root. Received filesystems belong to roots of storage
+// classes, but placeholders only to rootFor(fs).
+func (c *ReceiverConfig) ownedBy(root, fs *zfs.DatasetPath, placeholder bool,
+) bool {
+	if c.rootFor(fs).Equal(root) {
+		return true
+	}
+	return !placeholder && c.classRoot(root) != nil
+}
+
+// mapToLocal returns local path of filesystem fs of the sender and its client
+// root.
+func (s *Receiver) mapToLocal(ctx context.Context, fs string,
+) (clientRoot, lp *zfs.DatasetPath, err error) {
+	rootFS, lp, _, err := s.locate(ctx, fs, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.clientRootFromCtx(ctx, rootFS), lp, nil
+}
+
+// listFilesystems lists received filesystems below every root. Filesystems
+// below a root, which isn't the root of them anymore, are skipped. If the same
+// filesystem is listed below multiple roots, the received one wins over
+// placeholders.
+func (s *Receiver) listFilesystems(ctx context.Context,
+) (*pdu.ListFilesystemRes, error) {
+	roots := s.conf.roots()
+	if len(roots) == 1 {
+		return listFilesystemsRecursive(ctx, s.clientRootFromCtx(ctx, roots[0]),
+			false, zfs.PlaceholderPropertyName, receiveResumeToken)
+	}
+
+	var fss []*pdu.Filesystem
+	seen := make(map[string]int)
+	for _, root := range roots {
+		resp, err := listFilesystemsRecursive(ctx,
+			s.clientRootFromCtx(ctx, root), false,
+			zfs.PlaceholderPropertyName, receiveResumeToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, fs := range resp.Filesystems {
+			p, err := zfs.NewDatasetPath(fs.Path)
+			if err != nil {
+				return nil, fmt.Errorf("parse received fs %q: %w", fs.Path, err)
+			} else if !s.conf.ownedBy(root, p, fs.IsPlaceholder) {
+				continue
+			}
+			if i, ok := seen[fs.Path]; ok {
+				if fss[i].IsPlaceholder && !fs.IsPlaceholder {
+					fss[i] = fs
+				}
+				continue
+			}
+			seen[fs.Path] = len(fss)
+			fss = append(fss, fs)
+		}
+	}
+	return &pdu.ListFilesystemRes{Filesystems: fss}, nil
+}
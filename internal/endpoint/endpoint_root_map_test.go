@@ -0,0 +1,96 @@
+package endpoint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func mustDatasetPath(t *testing.T, s string) *zfs.DatasetPath {
+	t.Helper()
+	p, err := zfs.NewDatasetPath(s)
+	require.NoError(t, err)
+	return p
+}
+
+func newTestRootMapConfig(t *testing.T) *ReceiverConfig {
+	return &ReceiverConfig{
+		RootWithoutClientComponent: mustDatasetPath(t, "tank/sink"),
+		AppendClientIdentity:       true,
+		RootMap: []RootMapping{
+			{
+				Prefix: mustDatasetPath(t, "zroot"),
+				Root:   mustDatasetPath(t, "fast/sink"),
+			},
+			{
+				Prefix: mustDatasetPath(t, "zroot/media"),
+				Root:   mustDatasetPath(t, "slow/sink"),
+			},
+			{
+				Prefix: mustDatasetPath(t, "data"),
+				Root:   mustDatasetPath(t, "slow/sink"),
+			},
+		},
+	}
+}
+
+func TestReceiverConfig_rootFor(t *testing.T) {
+	c := newTestRootMapConfig(t)
+	tests := []struct {
+		fs   string
+		root string
+	}{
+		{fs: "zroot", root: "fast/sink"},
+		{fs: "zroot/usr/home", root: "fast/sink"},
+		{fs: "zroot/media", root: "slow/sink"},
+		{fs: "zroot/media/movies", root: "slow/sink"},
+		{fs: "zroot/mediafoo", root: "fast/sink"},
+		{fs: "data/db", root: "slow/sink"},
+		{fs: "other/foo", root: "tank/sink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fs, func(t *testing.T) {
+			assert.Equal(t, tt.root,
+				c.rootFor(mustDatasetPath(t, tt.fs)).ToString())
+		})
+	}
+}
+
+func TestReceiverConfig_roots(t *testing.T) {
+	c := newTestRootMapConfig(t)
+	var roots []string
+	for _, r := range c.roots() {
+		roots = append(roots, r.ToString())
+	}
+	assert.Equal(t, []string{"tank/sink", "fast/sink", "slow/sink"}, roots)
+
+	c.RootMap = nil
+	assert.Len(t, c.roots(), 1)
+}
+
+func TestReceiver_mapToLocal(t *testing.T) {
+	r := NewReceiver(ReceiverConfig{
+		JobID:                      MustMakeJobID("sink"),
+		RootWithoutClientComponent: mustDatasetPath(t, "tank/sink"),
+		AppendClientIdentity:       true,
+		RootMap: []RootMapping{{
+			Prefix: mustDatasetPath(t, "zroot"),
+			Root:   mustDatasetPath(t, "fast/sink"),
+		}},
+		PlaceholderEncryption: PlaceholderCreationEncryptionPropertyUnspecified,
+	}).WithClientIdentity("client")
+
+	clientRoot, lp, err := r.mapToLocal(context.Background(), "zroot/usr")
+	require.NoError(t, err)
+	assert.Equal(t, "fast/sink/client", clientRoot.ToString())
+	assert.Equal(t, "fast/sink/client/zroot/usr", lp.ToString())
+
+	clientRoot, lp, err = r.mapToLocal(context.Background(), "data/db")
+	require.NoError(t, err)
+	assert.Equal(t, "tank/sink/client", clientRoot.ToString())
+	assert.Equal(t, "tank/sink/client/data/db", lp.ToString())
+}
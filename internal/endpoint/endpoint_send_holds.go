@@ -0,0 +1,44 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// releaseSentHolds releases holds of zrepl on snapshot v of fs, received with
+// holds of the sender (zfs send -h), like step holds of the sending job. They
+// belong to the sender and would never be released on the receiver. User holds
+// are kept.
+func releaseSentHolds(ctx context.Context, fs string, v zfs.FilesystemVersion,
+) error {
+	tags, err := zfs.ZFSHolds(ctx, fs, v.Name)
+	if err != nil {
+		return fmt.Errorf("list holds of received snapshot: %w", err)
+	}
+
+	for _, tag := range tags {
+		if !zreplHoldTag(tag) {
+			continue
+		}
+		snap := v.FullPath(fs)
+		err := zfs.ZFSRelease(ctx, tag, snap)
+		audit.Record(ctx, "release", snap, err, "tag", tag)
+		if err != nil {
+			return fmt.Errorf("release sent hold %q of %q: %w", tag, snap, err)
+		}
+		getLogger(ctx).With(slog.String("snap", snap), slog.String("tag", tag)).
+			Info("released hold of sender")
+	}
+	return nil
+}
+
+// zreplHoldTag returns true, if tag is a tag of holds, which zrepl creates.
+func zreplHoldTag(tag string) bool {
+	return strings.HasPrefix(tag, LastReceivedHoldTagNamePrefix) ||
+		stepHoldTagRE.MatchString(tag)
+}
@@ -0,0 +1,14 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZreplHoldTag(t *testing.T) {
+	assert.True(t, zreplHoldTag("zrepl_STEP_J_push"))
+	assert.True(t, zreplHoldTag("zrepl_last_received_J_sink"))
+	assert.False(t, zreplHoldTag("keep"))
+	assert.False(t, zreplHoldTag("zrepl_archive"))
+}
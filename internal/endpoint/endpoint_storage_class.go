@@ -0,0 +1,234 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+// SendStorageClass tags filesystems of the sender, which pass FSF, with storage
+// class Name.
+type SendStorageClass struct {
+	Name string
+	FSF  *filters.DatasetFilter
+}
+
+// storageClasses sets storage class of every filesystem in rfss. User property
+// StorageClassProperty of a filesystem takes precedence over StorageClasses,
+// the first matching class wins.
+func (s *Sender) storageClasses(ctx context.Context, rfss []*pdu.Filesystem,
+) error {
+	if len(s.config.StorageClasses) == 0 && s.config.StorageClassProperty == "" {
+		return nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+
+	for _, fs := range rfss {
+		g.Go(func() error {
+			class, err := s.storageClassOf(ctx, fs.Path)
+			if err != nil {
+				return fmt.Errorf("storage class of %q: %w", fs.Path, err)
+			}
+			fs.StorageClass = class
+			return nil
+		})
+	}
+	return g.Wait() //nolint:wrapcheck // our error
+}
+
+func (s *Sender) storageClassOf(ctx context.Context, fs string) (string,
+	error,
+) {
+	p, err := zfs.NewDatasetPath(fs)
+	if err != nil {
+		return "", err
+	}
+
+	if prop := s.config.StorageClassProperty; prop != "" {
+		props, err := zfs.ZFSGet(ctx, p, []string{prop})
+		if err != nil {
+			return "", fmt.Errorf("get property %q: %w", prop, err)
+		} else if v := props.Get(prop); v != "" && v != "-" {
+			return v, nil
+		}
+	}
+
+	for i := range s.config.StorageClasses {
+		sc := &s.config.StorageClasses[i]
+		if ok, err := sc.FSF.Filter(p); err != nil {
+			return "", fmt.Errorf("filter of class %q: %w", sc.Name, err)
+		} else if ok {
+			return sc.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// StorageClass receives filesystems, which the sender tagged with storage
+// class Name, below Root with properties of the class, in addition to
+// properties of the receiver.
+type StorageClass struct {
+	Name string
+	Root *zfs.DatasetPath
+
+	InheritProperties  []zfsprop.Property
+	OverrideProperties map[zfsprop.Property]string
+}
+
+func (self *StorageClass) copyIn() {
+	self.Root = self.Root.Copy()
+	self.InheritProperties = slices.Clone(self.InheritProperties)
+	self.OverrideProperties = maps.Clone(self.OverrideProperties)
+}
+
+func (self *StorageClass) Validate() error {
+	if self.Name == "" {
+		return errors.New("Name must not be empty")
+	} else if self.Root.Length() <= 0 {
+		return errors.New("Root must not be an empty dataset path")
+	}
+
+	for _, prop := range self.InheritProperties {
+		if err := prop.Validate(); err != nil {
+			return fmt.Errorf("inherit property %q: %w", prop, err)
+		}
+	}
+
+	for prop := range self.OverrideProperties {
+		if err := prop.Validate(); err != nil {
+			return fmt.Errorf("override property %q: %w", prop, err)
+		}
+	}
+	return nil
+}
+
+// storageClass returns storage class with name or nil.
+func (c *ReceiverConfig) storageClass(name string) *StorageClass {
+	if name == "" {
+		return nil
+	}
+	for i := range c.StorageClasses {
+		if sc := &c.StorageClasses[i]; sc.Name == name {
+			return sc
+		}
+	}
+	return nil
+}
+
+// classRoot returns storage class with root or nil.
+func (c *ReceiverConfig) classRoot(root *zfs.DatasetPath) *StorageClass {
+	for i := range c.StorageClasses {
+		if sc := &c.StorageClasses[i]; sc.Root.Equal(root) {
+			return sc
+		}
+	}
+	return nil
+}
+
+// locate returns root, without client component, for filesystem fs of the
+// sender, tagged with storage class, and the class of this root, if any.
+// Received filesystems are never moved between roots, so it's the root,
+// where fs was received already, if received returns true for it. Otherwise
+// it's root of class or rootFor(fs), if the class is empty or unknown.
+func (c *ReceiverConfig) locate(fs *zfs.DatasetPath, class string,
+	received func(root *zfs.DatasetPath) (bool, error),
+) (*zfs.DatasetPath, *StorageClass, error) {
+	if len(c.StorageClasses) == 0 {
+		return c.rootFor(fs), nil, nil
+	}
+
+	defaultRoot := c.rootFor(fs)
+	roots := make([]*zfs.DatasetPath, 0, len(c.StorageClasses)+1)
+	for i := range c.StorageClasses {
+		roots = appendRoot(roots, c.StorageClasses[i].Root)
+	}
+	roots = appendRoot(roots, defaultRoot)
+
+	for _, root := range roots {
+		if ok, err := received(root); err != nil {
+			return nil, nil, err
+		} else if ok {
+			return root, c.classRoot(root), nil
+		}
+	}
+
+	if sc := c.storageClass(class); sc != nil {
+		return sc.Root, sc, nil
+	}
+	return defaultRoot, c.classRoot(defaultRoot), nil
+}
+
+func appendRoot(roots []*zfs.DatasetPath, root *zfs.DatasetPath,
+) []*zfs.DatasetPath {
+	if slices.ContainsFunc(roots, root.Equal) {
+		return roots
+	}
+	return append(roots, root)
+}
+
+// recvProperties returns properties for receiving a filesystem of storage
+// class sc: properties of the receiver, extended and overridden by properties
+// of sc.
+func (c *ReceiverConfig) recvProperties(sc *StorageClass,
+) ([]zfsprop.Property, map[zfsprop.Property]string) {
+	if sc == nil {
+		return c.InheritProperties, c.OverrideProperties
+	}
+
+	inherit := slices.Clone(c.InheritProperties)
+	for _, prop := range sc.InheritProperties {
+		if !slices.Contains(inherit, prop) {
+			inherit = append(inherit, prop)
+		}
+	}
+
+	override := maps.Clone(c.OverrideProperties)
+	if override == nil {
+		override = make(map[zfsprop.Property]string, len(sc.OverrideProperties))
+	}
+	maps.Copy(override, sc.OverrideProperties)
+	return inherit, override
+}
+
+// locate returns root, without client component, and local path of
+// filesystem fs of the sender, tagged with storage class, and the class of this
+// root, if any.
+func (s *Receiver) locate(ctx context.Context, fs, class string,
+) (rootFS, lp *zfs.DatasetPath, sc *StorageClass, err error) {
+	p, err := zfs.NewDatasetPath(fs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rootFS, sc, err = s.conf.locate(p, class,
+		func(root *zfs.DatasetPath) (bool, error) {
+			lp, err := mapToLocal(s.clientRootFromCtx(ctx, root), fs)
+			if err != nil {
+				return false, err
+			}
+			ph, err := zfs.ZFSGetFilesystemPlaceholderState(ctx, lp)
+			if err != nil {
+				return false, fmt.Errorf("cannot get placeholder state of %s: %w",
+					lp.ToString(), err)
+			}
+			return ph.FSExists && !ph.IsPlaceholder, nil
+		})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	lp, err = mapToLocal(s.clientRootFromCtx(ctx, rootFS), fs)
+	return rootFS, lp, sc, err
+}
@@ -0,0 +1,134 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
+)
+
+func newTestStorageClassConfig(t *testing.T) *ReceiverConfig {
+	c := newTestRootMapConfig(t)
+	c.JobID = MustMakeJobID("sink")
+	c.PlaceholderEncryption = PlaceholderCreationEncryptionPropertyUnspecified
+	c.InheritProperties = []zfsprop.Property{"mountpoint"}
+	c.OverrideProperties = map[zfsprop.Property]string{"compression": "lz4"}
+	c.StorageClasses = []StorageClass{
+		{
+			Name: "fast",
+			Root: mustDatasetPath(t, "fast/sink"),
+		},
+		{
+			Name:              "archive",
+			Root:              mustDatasetPath(t, "archive/sink"),
+			InheritProperties: []zfsprop.Property{"mountpoint", "canmount"},
+			OverrideProperties: map[zfsprop.Property]string{
+				"compression": "zstd-19",
+				"recordsize":  "1M",
+			},
+		},
+	}
+	return c
+}
+
+func TestReceiverConfig_locate(t *testing.T) {
+	c := newTestStorageClassConfig(t)
+	tests := []struct {
+		name     string
+		fs       string
+		class    string
+		received string
+		root     string
+		sc       string
+	}{
+		{name: "default", fs: "other/foo", root: "tank/sink"},
+		{name: "root map", fs: "zroot/media", root: "slow/sink"},
+		{
+			name: "class", fs: "other/foo", class: "archive",
+			root: "archive/sink", sc: "archive",
+		},
+		{
+			name: "unknown class", fs: "zroot/media", class: "cold",
+			root: "slow/sink",
+		},
+		{
+			name: "root map of class", fs: "zroot/usr", root: "fast/sink",
+			sc: "fast",
+		},
+		{
+			name: "received in class", fs: "other/foo", class: "fast",
+			received: "archive/sink", root: "archive/sink", sc: "archive",
+		},
+		{
+			name: "received in default", fs: "other/foo", class: "archive",
+			received: "tank/sink", root: "tank/sink",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, sc, err := c.locate(mustDatasetPath(t, tt.fs), tt.class,
+				func(root *zfs.DatasetPath) (bool, error) {
+					return root.ToString() == tt.received, nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, tt.root, root.ToString())
+			if tt.sc == "" {
+				assert.Nil(t, sc)
+			} else {
+				require.NotNil(t, sc)
+				assert.Equal(t, tt.sc, sc.Name)
+			}
+		})
+	}
+}
+
+func TestReceiverConfig_recvProperties(t *testing.T) {
+	c := newTestStorageClassConfig(t)
+
+	inherit, override := c.recvProperties(nil)
+	assert.Equal(t, c.InheritProperties, inherit)
+	assert.Equal(t, c.OverrideProperties, override)
+
+	inherit, override = c.recvProperties(c.storageClass("archive"))
+	assert.Equal(t, []zfsprop.Property{"mountpoint", "canmount"}, inherit)
+	assert.Equal(t, map[zfsprop.Property]string{
+		"compression": "zstd-19",
+		"recordsize":  "1M",
+	}, override)
+	assert.Equal(t, "lz4", c.OverrideProperties["compression"])
+}
+
+func TestReceiverConfig_storageClassRoots(t *testing.T) {
+	c := newTestStorageClassConfig(t)
+	var roots []string
+	for _, r := range c.roots() {
+		roots = append(roots, r.ToString())
+	}
+	assert.Equal(t,
+		[]string{"tank/sink", "fast/sink", "slow/sink", "archive/sink"}, roots)
+
+	archive := mustDatasetPath(t, "archive/sink")
+	assert.True(t, c.ownedBy(archive, mustDatasetPath(t, "zroot/usr"), false))
+	assert.False(t, c.ownedBy(archive, mustDatasetPath(t, "zroot/usr"), true))
+	assert.False(t, c.ownedBy(mustDatasetPath(t, "slow/sink"),
+		mustDatasetPath(t, "zroot/usr"), false))
+}
+
+func TestReceiverConfig_ValidateStorageClasses(t *testing.T) {
+	c := newTestStorageClassConfig(t)
+	require.NoError(t, c.Validate())
+
+	c.StorageClasses = append(c.StorageClasses, StorageClass{
+		Name: "fast",
+		Root: mustDatasetPath(t, "other/sink"),
+	})
+	require.ErrorContains(t, c.Validate(), "duplicate name")
+
+	c = newTestStorageClassConfig(t)
+	c.StorageClasses[0].Root = mustDatasetPath(t, "")
+	require.Error(t, c.Validate())
+}
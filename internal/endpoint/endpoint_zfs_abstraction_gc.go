@@ -0,0 +1,72 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/filters"
+)
+
+// ListStaleSteps returns step holds of completed steps of all filesystems, and
+// step holds and tentative replication cursor bookmarks of jobs, which aren't
+// in jobs. Replication cursors and last-received holds are never returned,
+// because incremental replication depends on them.
+func ListStaleSteps(ctx context.Context, jobs []JobID, concurrency int,
+) ([]Abstraction, error) {
+	f, err := filters.NoFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	q := ListZFSHoldsAndBookmarksQuery{
+		FS: ListZFSHoldsAndBookmarksQueryFilesystemFilter{Filter: f},
+		What: AbstractionTypeSet{
+			AbstractionStepHold:                           true,
+			AbstractionTentativeReplicationCursorBookmark: true,
+			AbstractionReplicationCursorBookmarkV2:        true,
+		},
+		Concurrency: concurrency,
+	}
+	if err := q.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	si, err := ListStale(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return staleSteps(si, jobs), nil
+}
+
+func staleSteps(si *StalenessInfo, jobs []JobID) []Abstraction {
+	exists := make(map[JobID]struct{}, len(jobs))
+	for _, jobID := range jobs {
+		exists[jobID] = struct{}{}
+	}
+
+	orphan := func(a Abstraction) bool {
+		jobID := a.GetJobID()
+		if jobID == nil {
+			return false
+		}
+		_, ok := exists[*jobID]
+		return !ok
+	}
+
+	var stale []Abstraction
+	for _, a := range si.Stale {
+		if a.GetType() == AbstractionStepHold {
+			stale = append(stale, a)
+		}
+	}
+
+	for _, a := range si.Live {
+		switch a.GetType() {
+		case AbstractionStepHold, AbstractionTentativeReplicationCursorBookmark:
+			if orphan(a) {
+				stale = append(stale, a)
+			}
+		}
+	}
+	return stale
+}
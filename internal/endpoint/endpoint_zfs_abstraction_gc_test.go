@@ -0,0 +1,39 @@
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleSteps(t *testing.T) {
+	jobA, jobB := MustMakeJobID("a"), MustMakeJobID("b")
+	hold := func(jobID JobID) Abstraction {
+		return holdBasedAbstraction{
+			Type: AbstractionStepHold, FS: "pool/a", JobID: jobID,
+		}
+	}
+	bookmark := func(typ AbstractionType, jobID JobID) Abstraction {
+		return bookmarkBasedAbstraction{Type: typ, FS: "pool/a", JobID: jobID}
+	}
+
+	si := &StalenessInfo{
+		Stale: []Abstraction{
+			hold(jobA),
+			bookmark(AbstractionReplicationCursorBookmarkV2, jobA),
+			bookmark(AbstractionReplicationCursorBookmarkV2, jobB),
+		},
+		Live: []Abstraction{
+			hold(jobA),
+			hold(jobB),
+			bookmark(AbstractionTentativeReplicationCursorBookmark, jobA),
+			bookmark(AbstractionTentativeReplicationCursorBookmark, jobB),
+			bookmark(AbstractionReplicationCursorBookmarkV2, jobB),
+		},
+	}
+
+	assert.Equal(t, []Abstraction{si.Stale[0], si.Live[1], si.Live[3]},
+		staleSteps(si, []JobID{jobA}))
+	assert.Equal(t, []Abstraction{si.Stale[0]},
+		staleSteps(si, []JobID{jobA, jobB}))
+}
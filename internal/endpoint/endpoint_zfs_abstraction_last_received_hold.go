@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -75,6 +76,7 @@ func CreateLastReceivedHold(ctx context.Context, fs string, to zfs.FilesystemVer
 	// => hold new one before releasing old hold
 
 	err = zfs.ZFSHold(ctx, fs, to, tag)
+	audit.Record(ctx, "hold", to.FullPath(fs), err, "tag", tag)
 	if err != nil {
 		return nil, fmt.Errorf("last-received-hold: hold newly received: %w", err)
 	}
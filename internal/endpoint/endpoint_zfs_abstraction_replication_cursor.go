@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"sort"
 
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -260,7 +261,9 @@ func (c ReplicationCursorV1) String() string {
 }
 
 func (c ReplicationCursorV1) Destroy(ctx context.Context) error {
-	if err := zfs.ZFSDestroyIdempotent(ctx, c.GetFullPath()); err != nil {
+	err := zfs.ZFSDestroyIdempotent(ctx, c.GetFullPath())
+	audit.Record(ctx, "destroy", c.GetFullPath(), err)
+	if err != nil {
 		return fmt.Errorf("destroy %s %s: zfs: %w", c.Type, c.GetFullPath(), err)
 	}
 	return nil
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -46,7 +47,9 @@ func HoldStep(ctx context.Context, fs string, v zfs.FilesystemVersion, jobID Job
 		return nil, fmt.Errorf("step hold tag: %w", err)
 	}
 
-	if err := zfs.ZFSHold(ctx, fs, v, tag); err != nil {
+	err = zfs.ZFSHold(ctx, fs, v, tag)
+	audit.Record(ctx, "hold", v.FullPath(fs), err, "tag", tag)
+	if err != nil {
 		return nil, fmt.Errorf("step hold: zfs: %w", err)
 	}
 
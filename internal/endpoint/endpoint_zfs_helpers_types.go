@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/dsh2dsh/zrepl/internal/endpoint/audit"
 	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
@@ -36,7 +37,9 @@ func (b bookmarkBasedAbstraction) GetFilesystemVersion() zfs.FilesystemVersion {
 }
 
 func (b bookmarkBasedAbstraction) Destroy(ctx context.Context) error {
-	if err := zfs.ZFSDestroyIdempotent(ctx, b.GetFullPath()); err != nil {
+	err := zfs.ZFSDestroyIdempotent(ctx, b.GetFullPath())
+	audit.Record(ctx, "destroy", b.GetFullPath(), err)
+	if err != nil {
 		return fmt.Errorf("destroy %s: zfs: %w", b, err)
 	}
 	return nil
@@ -71,7 +74,9 @@ func (h holdBasedAbstraction) GetFilesystemVersion() zfs.FilesystemVersion {
 }
 
 func (h holdBasedAbstraction) Destroy(ctx context.Context) error {
-	if err := zfs.ZFSRelease(ctx, h.Tag, h.GetFullPath()); err != nil {
+	err := zfs.ZFSRelease(ctx, h.Tag, h.GetFullPath())
+	audit.Record(ctx, "release", h.GetFullPath(), err, "tag", h.Tag)
+	if err != nil {
 		return fmt.Errorf("release %s: zfs: %w", h, err)
 	}
 	return nil
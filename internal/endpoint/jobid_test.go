@@ -0,0 +1,28 @@
+package endpoint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestMakeJobID_sameAsConfig(t *testing.T) {
+	names := []string{
+		"foo", "foo-bar_baz.1:2 3", "", "foo/bar", "foo@bar", "foo#bar", "föo",
+		".", "..",
+	}
+	for n := 190; n <= 240; n++ {
+		names = append(names, strings.Repeat("a", n))
+	}
+
+	for _, name := range names {
+		_, err := MakeJobID(name)
+		configErr := config.ValidateJobID(name)
+		assert.Equal(t, err == nil, configErr == nil,
+			"name=%q (len=%d): endpoint=%v, config=%v",
+			name, len(name), err, configErr)
+	}
+}
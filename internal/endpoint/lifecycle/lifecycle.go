@@ -0,0 +1,60 @@
+// Package lifecycle keeps append-only journals of zrepl actions on snapshots,
+// like created, replicated, received and destroyed, one journal per dataset.
+// They answer what happened to a snapshot, without searching logs.
+package lifecycle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	Created    = "created"
+	Replicated = "replicated"
+	Received   = "received"
+	Marked     = "marked"
+	Destroyed  = "destroyed"
+)
+
+// Entry of a journal.
+type Entry struct {
+	Time     time.Time         `json:"time"`
+	Job      string            `json:"job"`
+	Event    string            `json:"event"`
+	Snapshot string            `json:"snapshot"`
+	Details  map[string]string `json:"details,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+const maxEntrySize = 1 << 20
+
+// Read reads entries of a journal from r. If snapshot isn't empty, it returns
+// only entries of this snapshot.
+func Read(r io.Reader, snapshot string) ([]Entry, error) {
+	snapshot = strings.TrimPrefix(snapshot, "@")
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxEntrySize)
+	for line := 1; s.Scan(); line++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: parse entry: %w", line, err)
+		}
+		if snapshot == "" || e.Snapshot == snapshot {
+			entries = append(entries, e)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read lifecycle journal: %w", err)
+	}
+	return entries, nil
+}
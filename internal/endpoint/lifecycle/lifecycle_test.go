@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+func TestRecord(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, SetDir(dir))
+	defer func() { require.NoError(t, SetDir("")) }()
+
+	ctx := zfscmd.WithJobID(context.Background(), "snap")
+	Record(ctx, Created, "pool/a", "zrepl_1", nil)
+	ctx = zfscmd.WithJobID(context.Background(), "push")
+	Record(ctx, Replicated, "pool/a", "@zrepl_2", nil, "from", "zrepl_1")
+	Record(WithReason(ctx, "keep_sender: grid"), Destroyed, "pool/a", "zrepl_1",
+		errors.New("dataset is busy"))
+	Record(ctx, Created, "pool/b", "zrepl_1", nil)
+
+	f, err := os.Open(Filename(dir, "pool/a"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, filepath.Join(dir, "pool%2Fa.jsonl"), f.Name())
+
+	entries, err := Read(f, "@zrepl_1")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "snap", entries[0].Job)
+	assert.Equal(t, Created, entries[0].Event)
+	assert.Equal(t, "zrepl_1", entries[0].Snapshot)
+	assert.Empty(t, entries[0].Details)
+	assert.Empty(t, entries[0].Error)
+
+	assert.Equal(t, "push", entries[1].Job)
+	assert.Equal(t, Destroyed, entries[1].Event)
+	assert.Equal(t, map[string]string{"reason": "keep_sender: grid"},
+		entries[1].Details)
+	assert.Equal(t, "dataset is busy", entries[1].Error)
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	entries, err = Read(f, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "zrepl_2", entries[1].Snapshot)
+	assert.Equal(t, map[string]string{"from": "zrepl_1"}, entries[1].Details)
+}
+
+func TestRecord_disabled(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, SetDir(""))
+	Record(context.Background(), Created, "pool/a", "zrepl_1", nil)
+
+	_, err := os.Stat(Filename(dir, "pool/a"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRead_invalid(t *testing.T) {
+	_, err := Read(strings.NewReader("{}\n\nfoo\n"), "")
+	require.ErrorContains(t, err, "line 3")
+}
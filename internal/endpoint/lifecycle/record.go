@@ -0,0 +1,116 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/daemon/logging"
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+var journals = struct {
+	mtx sync.Mutex
+	dir string
+}{}
+
+// SetDir sets directory of journals. Empty dir disables journals.
+func SetDir(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create lifecycle dir: %w", err)
+		}
+	}
+	journals.mtx.Lock()
+	journals.dir = dir
+	journals.mtx.Unlock()
+	return nil
+}
+
+// Filename returns path of the journal of dataset fs in directory dir.
+func Filename(dir, fs string) string {
+	return filepath.Join(dir, url.PathEscape(fs)+".jsonl")
+}
+
+type contextKey int
+
+const contextKeyReason contextKey = iota
+
+// WithReason returns ctx, which adds reason to details of every entry recorded
+// with it.
+func WithReason(ctx context.Context, reason string) context.Context {
+	if reason == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKeyReason, reason)
+}
+
+// Record appends event on snapshot of dataset fs with its result err to the
+// journal of fs, if journals are enabled. details are pairs of keys and
+// values. snapshot is a name of the snapshot, with or without leading "@".
+func Record(ctx context.Context, event, fs, snapshot string, err error,
+	details ...string,
+) {
+	e := Entry{
+		Time:     time.Now(),
+		Job:      zfscmd.GetJobID(ctx),
+		Event:    event,
+		Snapshot: strings.TrimPrefix(snapshot, "@"),
+	}
+	reason, _ := ctx.Value(contextKeyReason).(string)
+	if len(details) > 0 || reason != "" {
+		e.Details = make(map[string]string, len(details)/2+1)
+		for i := 0; i+1 < len(details); i += 2 {
+			e.Details[details[i]] = details[i+1]
+		}
+		if reason != "" {
+			e.Details["reason"] = reason
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if err := appendEntry(fs, &e); err != nil {
+		logger.WithError(
+			logging.GetLogger(ctx, logging.SubsysEndpoint).With(
+				slog.String("event", event),
+				slog.String("snapshot", fs+"@"+snapshot)),
+			err, "failed record lifecycle entry")
+	}
+}
+
+func appendEntry(fs string, e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal lifecycle entry: %w", err)
+	}
+
+	journals.mtx.Lock()
+	defer journals.mtx.Unlock()
+	if journals.dir == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(Filename(journals.dir, fs),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open lifecycle journal: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write lifecycle entry: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close lifecycle journal: %w", err)
+	}
+	return nil
+}
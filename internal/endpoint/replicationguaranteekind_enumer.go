@@ -11,11 +11,13 @@ import (
 const (
 	_ReplicationGuaranteeKindName_0 = "resumabilityincremental"
 	_ReplicationGuaranteeKindName_1 = "none"
+	_ReplicationGuaranteeKindName_2 = "bookmarks"
 )
 
 var (
 	_ReplicationGuaranteeKindIndex_0 = [...]uint8{0, 12, 23}
 	_ReplicationGuaranteeKindIndex_1 = [...]uint8{0, 4}
+	_ReplicationGuaranteeKindIndex_2 = [...]uint8{0, 9}
 )
 
 func (i ReplicationGuaranteeKind) String() string {
@@ -25,17 +27,20 @@ func (i ReplicationGuaranteeKind) String() string {
 		return _ReplicationGuaranteeKindName_0[_ReplicationGuaranteeKindIndex_0[i]:_ReplicationGuaranteeKindIndex_0[i+1]]
 	case i == 4:
 		return _ReplicationGuaranteeKindName_1
+	case i == 8:
+		return _ReplicationGuaranteeKindName_2
 	default:
 		return fmt.Sprintf("ReplicationGuaranteeKind(%d)", i)
 	}
 }
 
-var _ReplicationGuaranteeKindValues = []ReplicationGuaranteeKind{1, 2, 4}
+var _ReplicationGuaranteeKindValues = []ReplicationGuaranteeKind{1, 2, 4, 8}
 
 var _ReplicationGuaranteeKindNameToValueMap = map[string]ReplicationGuaranteeKind{
 	_ReplicationGuaranteeKindName_0[0:12]:  1,
 	_ReplicationGuaranteeKindName_0[12:23]: 2,
 	_ReplicationGuaranteeKindName_1[0:4]:   4,
+	_ReplicationGuaranteeKindName_2[0:9]:   8,
 }
 
 // ReplicationGuaranteeKindString retrieves an enum value from the enum constants string name.
@@ -0,0 +1,248 @@
+// Package jsonschema generates JSON Schema (draft 2020-12) of Go types, as
+// they are marshaled by encoding/json.
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Type is a string or a list of strings.
+	Type   any       `json:"type,omitempty"`
+	Format string    `json:"format,omitempty"`
+	AnyOf  []*Schema `json:"anyOf,omitempty"`
+	AllOf  []*Schema `json:"allOf,omitempty"`
+
+	Default          any      `json:"default,omitempty"`
+	Const            any      `json:"const,omitempty"`
+	Enum             []any    `json:"enum,omitempty"`
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MinItems         *int     `json:"minItems,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Extender is implemented by types with custom MarshalJSON, which add fields
+// to their marshaled struct. JSONSchemaExtend gets schema of the struct and
+// adds missing properties to it.
+type Extender interface {
+	JSONSchemaExtend(s *Schema)
+}
+
+// Reflect returns schema of v with title and definitions of all structs
+// reachable from v.
+func Reflect(title string, v any) *Schema {
+	r := reflector{defs: make(map[string]*Schema)}
+	s := r.reflect(reflect.TypeOf(v))
+	s.Schema = Draft
+	s.Title = title
+	if len(r.defs) > 0 {
+		s.Defs = r.defs
+	}
+	return s
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	durationType  = reflect.TypeFor[time.Duration]()
+	extenderType  = reflect.TypeFor[Extender]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+	textType      = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type reflector struct {
+	defs map[string]*Schema
+}
+
+func (self *reflector) reflect(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Description: "duration in nanoseconds"}
+	case implements(t, extenderType):
+	case implements(t, marshalerType):
+		return &Schema{}
+	case implements(t, textType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Pointer:
+		return self.reflect(t.Elem())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: self.reflect(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: self.reflect(t.Elem())}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: self.reflect(t.Elem()),
+		}
+	case reflect.Struct:
+		return self.reflectStruct(t)
+	}
+	return &Schema{}
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func (self *reflector) reflectStruct(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return self.structSchema(t)
+	}
+
+	name := defName(t)
+	ref := &Schema{Ref: "#/$defs/" + name}
+	if _, ok := self.defs[name]; ok {
+		return ref
+	}
+	// Register it before reflecting fields, because it can be recursive.
+	self.defs[name] = &Schema{}
+	self.defs[name] = self.structSchema(t)
+	return ref
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func defName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return unsafeName.ReplaceAllString(pkg+"."+t.Name(), "_")
+}
+
+func (self *reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	self.addFields(s, t)
+	if implements(t, extenderType) {
+		reflect.New(t).Interface().(Extender).JSONSchemaExtend(s)
+	}
+	return s
+}
+
+// addFields adds fields of struct t to s, like encoding/json marshals them.
+// Fields of embedded structs are added after own fields of t and don't
+// override them.
+func (self *reflector) addFields(s *Schema, t reflect.Type) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+
+		var fs *Schema
+		if hasOption(opts, "string") {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = self.reflect(f.Type)
+		}
+
+		if hasOption(opts, "omitempty") || hasOption(opts, "omitzero") {
+			s.Properties[name] = fs
+			continue
+		}
+		s.Properties[name] = nullable(f.Type, fs)
+		s.Required = append(s.Required, name)
+	}
+
+	for _, t := range embedded {
+		self.addFields(s, t)
+	}
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable returns s, which also allows null, if a value of t can be
+// marshaled as null.
+func nullable(t reflect.Type, s *Schema) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return s
+		}
+	default:
+		return s
+	}
+
+	switch typ := s.Type.(type) {
+	case string:
+		s.Type = []string{typ, "null"}
+		return s
+	case nil:
+		if s.Ref == "" {
+			return s
+		}
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
@@ -0,0 +1,111 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEmbedded struct {
+	Name  string `json:"name"`
+	Inner int    `json:"inner"`
+}
+
+type testNode struct {
+	testEmbedded
+
+	Name     string            `json:"node_name"`
+	Count    uint              `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio,string"`
+	Created  time.Time         `json:"created,omitzero"`
+	Timeout  time.Duration     `json:"timeout"`
+	Children []*testNode       `json:"children"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *testNode         `json:"parent"`
+	Raw      []byte            `json:"raw"`
+	Any      any               `json:"any"`
+	Skipped  string            `json:"-"`
+	NoTag    bool
+
+	unexported int
+}
+
+func TestReflect(t *testing.T) {
+	s := Reflect("node", &testNode{})
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "node", s.Title)
+	assert.Equal(t, "#/$defs/jsonschema.testNode", s.Ref)
+	require.Len(t, s.Defs, 1)
+
+	node := s.Defs["jsonschema.testNode"]
+	require.NotNil(t, node)
+	assert.Equal(t, "object", node.Type)
+	assert.Equal(t, []string{
+		"node_name", "ratio", "timeout", "children", "parent", "raw", "any",
+		"NoTag", "name", "inner",
+	}, node.Required)
+
+	props := node.Properties
+	assert.Len(t, props, 13)
+	assert.Equal(t, &Schema{Type: "string"}, props["node_name"])
+	assert.Equal(t, &Schema{Type: "string"}, props["name"], "embedded")
+	assert.Equal(t, &Schema{Type: "integer"}, props["count"])
+	assert.Equal(t, &Schema{Type: "string"}, props["ratio"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"},
+		props["created"])
+	assert.Equal(t, "integer", props["timeout"].Type)
+	assert.Equal(t, &Schema{
+		Type:  []string{"array", "null"},
+		Items: &Schema{Ref: "#/$defs/jsonschema.testNode"},
+	}, props["children"])
+	assert.Equal(t, &Schema{
+		Type:                 "object",
+		AdditionalProperties: &Schema{Type: "string"},
+	}, props["labels"])
+	assert.Equal(t, &Schema{AnyOf: []*Schema{
+		{Ref: "#/$defs/jsonschema.testNode"},
+		{Type: "null"},
+	}}, props["parent"])
+	assert.Equal(t, &Schema{Type: "string", Format: "byte"}, props["raw"])
+	assert.Equal(t, &Schema{}, props["any"])
+	assert.Equal(t, &Schema{Type: "boolean"}, props["NoTag"])
+	assert.NotContains(t, props, "Skipped")
+	assert.NotContains(t, props, "unexported")
+}
+
+type testExtended struct {
+	Duration time.Duration `json:"-"`
+}
+
+func (self *testExtended) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]float64{"seconds": self.Duration.Seconds()})
+}
+
+func (self *testExtended) JSONSchemaExtend(s *Schema) {
+	s.Properties["seconds"] = &Schema{Type: "number"}
+}
+
+type testMarshaler struct{ v int }
+
+func (self testMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(self.v)
+}
+
+func TestReflect_marshalers(t *testing.T) {
+	s := Reflect("", struct {
+		Extended  testExtended  `json:"extended"`
+		Marshaler testMarshaler `json:"marshaler"`
+	}{})
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, &Schema{Ref: "#/$defs/jsonschema.testExtended"},
+		s.Properties["extended"])
+	assert.Equal(t, &Schema{}, s.Properties["marshaler"])
+	assert.Equal(t, &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"seconds": {Type: "number"}},
+	}, s.Defs["jsonschema.testExtended"])
+}
@@ -0,0 +1,263 @@
+package jsonschema
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// YAMLSchemer is implemented by types with custom UnmarshalYAML, which accept
+// other YAML values, than their Go types, like sizes with units.
+type YAMLSchemer interface {
+	YAMLSchema() *Schema
+}
+
+// YAMLVariants is implemented by types, which unmarshal into one of types,
+// selected by type key of YAML mapping. YAMLVariants returns values of the
+// types by their names.
+type YAMLVariants interface {
+	YAMLVariants() map[string]any
+}
+
+// ReflectYAML returns schema of YAML documents, which unmarshal into v, with
+// title and definitions of all structs reachable from v. Fields are named by
+// yaml tags, default tags set defaults, and validate tags required, oneof, min,
+// max, gt, gte, lt, lte, url and email set constraints.
+func ReflectYAML(title string, v any) *Schema {
+	r := yamlReflector{defs: make(map[string]*Schema)}
+	s := r.reflect(reflect.TypeOf(v))
+	s.Schema = Draft
+	s.Title = title
+	if len(r.defs) > 0 {
+		s.Defs = r.defs
+	}
+	return s
+}
+
+var (
+	yamlSchemerType  = reflect.TypeFor[YAMLSchemer]()
+	yamlVariantsType = reflect.TypeFor[YAMLVariants]()
+)
+
+type yamlReflector struct {
+	defs map[string]*Schema
+}
+
+func (self *yamlReflector) reflect(t reflect.Type) *Schema {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return self.reflect(t.Elem())
+	case implements(t, yamlSchemerType):
+		return reflect.New(t).Interface().(YAMLSchemer).YAMLSchema()
+	case implements(t, yamlVariantsType):
+		return self.reflectDef(t, self.variantsSchema)
+	case t == durationType:
+		return &Schema{Type: "string", Description: "duration, like 30s or 1h30m"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: self.reflect(t.Elem())}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: self.reflect(t.Elem()),
+		}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return self.structSchema(t)
+		}
+		return self.reflectDef(t, self.structSchema)
+	}
+	return &Schema{}
+}
+
+// reflectDef returns reference to definition of named type t, which is created
+// by fn, if it's not defined yet.
+func (self *yamlReflector) reflectDef(t reflect.Type,
+	fn func(t reflect.Type) *Schema,
+) *Schema {
+	name := defName(t)
+	ref := &Schema{Ref: "#/$defs/" + name}
+	if _, ok := self.defs[name]; ok {
+		return ref
+	}
+	// Register it before reflecting, because it can be recursive.
+	self.defs[name] = &Schema{}
+	self.defs[name] = fn(t)
+	return ref
+}
+
+// variantsSchema returns schema, which allows any of variants of t, selected by
+// their type.
+func (self *yamlReflector) variantsSchema(t reflect.Type) *Schema {
+	variants := reflect.New(t).Interface().(YAMLVariants).YAMLVariants()
+	s := &Schema{AnyOf: make([]*Schema, 0, len(variants))}
+	for _, name := range slices.Sorted(maps.Keys(variants)) {
+		s.AnyOf = append(s.AnyOf, &Schema{AllOf: []*Schema{
+			self.reflect(reflect.TypeOf(variants[name])),
+			{
+				Properties: map[string]*Schema{"type": {Const: name}},
+				Required:   []string{"type"},
+			},
+		}})
+	}
+	return s
+}
+
+func (self *yamlReflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	self.addFields(s, t)
+	return s
+}
+
+// addFields adds fields of struct t to s, like YAML unmarshals them. Fields of
+// inlined structs are added, where they are defined.
+func (self *yamlReflector) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if hasOption(opts, "inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				self.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+
+		fs := self.reflect(f.Type)
+		required := applyValidate(fs, f.Tag.Get("validate"))
+		if def, ok := f.Tag.Lookup("default"); ok {
+			fs.Default = parseDefault(fs, def)
+			required = false
+		}
+
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyValidate adds constraints of validate tag to s. Rules after dive apply to
+// items of s. It returns true, if the field is required.
+func applyValidate(s *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	rules := strings.Split(tag, ",")
+	var items []string
+	if i := slices.Index(rules, "dive"); i >= 0 {
+		rules, items = rules[:i], rules[i+1:]
+		if j := slices.Index(items, "endkeys"); j >= 0 {
+			items = items[j+1:]
+		}
+	}
+
+	required := applyRules(s, rules)
+	switch {
+	case len(items) == 0:
+	case s.Items != nil:
+		applyRules(s.Items, items)
+	case s.AdditionalProperties != nil:
+		applyRules(s.AdditionalProperties, items)
+	}
+	return required
+}
+
+func applyRules(s *Schema, rules []string) (required bool) {
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "oneof":
+			s.Enum = enumValues(s, strings.Fields(param))
+		case "url":
+			s.Format = "uri"
+		case "email":
+			s.Format = "email"
+		case "min", "gte":
+			setLimit(s, param, &s.Minimum, true)
+		case "max", "lte":
+			setLimit(s, param, &s.Maximum, false)
+		case "gt":
+			setLimit(s, param, &s.ExclusiveMinimum, false)
+		case "lt":
+			setLimit(s, param, &s.ExclusiveMaximum, false)
+		}
+	}
+	return required
+}
+
+func enumValues(s *Schema, values []string) []any {
+	enum := make([]any, len(values))
+	for i, v := range values {
+		enum[i] = parseDefault(s, v)
+	}
+	return enum
+}
+
+// setLimit sets limit of numbers to param. If minItems is true, it sets
+// minimal number of items of arrays too.
+func setLimit(s *Schema, param string, limit **float64, minItems bool) {
+	switch s.Type {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(param, 64); err == nil {
+			*limit = &f
+		}
+	case "array":
+		if n, err := strconv.Atoi(param); err == nil && minItems {
+			s.MinItems = &n
+		}
+	}
+}
+
+// parseDefault returns value of default tag def, typed like s.
+func parseDefault(s *Schema, def string) any {
+	switch s.Type {
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	case "integer":
+		if n, err := strconv.ParseInt(def, 0, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(def, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
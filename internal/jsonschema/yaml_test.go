@@ -0,0 +1,114 @@
+package jsonschema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testYAMLCommon struct {
+	Type string `yaml:"type" validate:"required"`
+}
+
+type testYAMLFile struct {
+	testYAMLCommon `yaml:",inline"`
+
+	Path string `yaml:"path" validate:"required"`
+}
+
+type testYAMLStdout struct {
+	testYAMLCommon `yaml:",inline"`
+}
+
+type testYAMLOutlet struct{ Ret any }
+
+func (testYAMLOutlet) YAMLVariants() map[string]any {
+	return map[string]any{
+		"file":   new(testYAMLFile),
+		"stdout": new(testYAMLStdout),
+	}
+}
+
+type testYAMLSize uint64
+
+func (testYAMLSize) YAMLSchema() *Schema {
+	return &Schema{Type: []string{"integer", "string"}}
+}
+
+type testYAMLConfig struct {
+	Level    string            `yaml:"level" default:"info" validate:"required,oneof=debug info"`
+	Count    int               `yaml:"count" default:"1" validate:"min=1,max=5"`
+	Enabled  bool              `yaml:"enabled" default:"true"`
+	Timeout  time.Duration     `yaml:"timeout" validate:"gt=0s"`
+	Size     testYAMLSize      `yaml:"size"`
+	URL      string            `yaml:"url" validate:"required_with=Level,omitempty,url"`
+	Outlets  []testYAMLOutlet  `yaml:"outlets" validate:"min=1,dive"`
+	Tags     []string          `yaml:"tags" validate:"dive,oneof=a b"`
+	Env      map[string]string `yaml:"env" validate:"dive,keys,required,endkeys,required"`
+	Skipped  string            `yaml:"-"`
+	NoTag    string
+	internal int
+}
+
+func TestReflectYAML(t *testing.T) {
+	s := ReflectYAML("config", &testYAMLConfig{})
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "config", s.Title)
+	assert.Equal(t, "#/$defs/jsonschema.testYAMLConfig", s.Ref)
+
+	c := s.Defs["jsonschema.testYAMLConfig"]
+	require.NotNil(t, c)
+	assert.Empty(t, c.Required)
+
+	props := c.Properties
+	assert.Len(t, props, 10)
+	assert.Equal(t, &Schema{
+		Type:    "string",
+		Default: "info",
+		Enum:    []any{"debug", "info"},
+	}, props["level"])
+	one, five := 1.0, 5.0
+	assert.Equal(t, &Schema{
+		Type:    "integer",
+		Default: int64(1),
+		Minimum: &one,
+		Maximum: &five,
+	}, props["count"])
+	assert.Equal(t, &Schema{Type: "boolean", Default: true}, props["enabled"])
+	assert.Equal(t, "string", props["timeout"].Type)
+	assert.Nil(t, props["timeout"].ExclusiveMinimum)
+	assert.Equal(t, []string{"integer", "string"}, props["size"].Type)
+	assert.Equal(t, &Schema{Type: "string", Format: "uri"}, props["url"])
+	minItems := 1
+	assert.Equal(t, &Schema{
+		Type:     "array",
+		Items:    &Schema{Ref: "#/$defs/jsonschema.testYAMLOutlet"},
+		MinItems: &minItems,
+	}, props["outlets"])
+	assert.Equal(t, []any{"a", "b"}, props["tags"].Items.Enum)
+	assert.Equal(t, &Schema{Type: "string"}, props["env"].AdditionalProperties)
+	assert.Equal(t, &Schema{Type: "string"}, props["notag"])
+	assert.NotContains(t, props, "Skipped")
+
+	outlet := s.Defs["jsonschema.testYAMLOutlet"]
+	require.NotNil(t, outlet)
+	require.Len(t, outlet.AnyOf, 2)
+	assert.Equal(t, &Schema{AllOf: []*Schema{
+		{Ref: "#/$defs/jsonschema.testYAMLFile"},
+		{
+			Properties: map[string]*Schema{"type": {Const: "file"}},
+			Required:   []string{"type"},
+		},
+	}}, outlet.AnyOf[0])
+
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type": {Type: "string"},
+			"path": {Type: "string"},
+		},
+		Required: []string{"type", "path"},
+	}, s.Defs["jsonschema.testYAMLFile"])
+}
@@ -0,0 +1,163 @@
+package platformtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dsh2dsh/zrepl/internal/cli"
+)
+
+var runArgs struct {
+	scenarios []string
+	exe       string
+	workDir   string
+	poolName  string
+	poolSize  int64
+	timeout   time.Duration
+	keep      bool
+}
+
+var Subcommand = &cli.Subcommand{
+	Use:   "platformtest",
+	Short: "run end-to-end scenarios against disposable file-backed pools",
+
+	SetupSubcommands: func() []*cli.Subcommand {
+		return []*cli.Subcommand{runCmd, listCmd}
+	},
+}
+
+var runCmd = &cli.Subcommand{
+	Use:   "run [--scenario NAME]...",
+	Short: "run scenarios, all by default",
+	Long: `Run scenarios, all by default.
+
+Every scenario creates a pool, backed by a sparse file in its work dir, starts
+zrepl daemon with its own config and control socket, runs full lifecycles of
+jobs and destroys the pool. It must be run as root on a host with ZFS, and it
+never touches other pools.
+`,
+	Example: "  zrepl platformtest run --scenario replication-basic",
+
+	NoRequireConfig: true,
+
+	SetupCobra: func(c *cobra.Command) {
+		c.Args = cobra.ExactArgs(0)
+		f := c.Flags()
+		f.StringArrayVarP(&runArgs.scenarios, "scenario", "s", nil,
+			"name of scenario to run")
+		f.StringVar(&runArgs.exe, "zrepl", "",
+			"zrepl binary to test (default is this binary)")
+		f.StringVar(&runArgs.workDir, "workdir", "",
+			"parent of work dirs of scenarios (default is temp dir)")
+		f.StringVar(&runArgs.poolName, "pool", "zreplplatformtest",
+			"name of pools to create")
+		f.Int64Var(&runArgs.poolSize, "pool-size", 256<<20,
+			"size of pool vdevs in bytes")
+		f.DurationVar(&runArgs.timeout, "timeout", 2*time.Minute,
+			"timeout of every step of scenarios")
+		f.BoolVar(&runArgs.keep, "keep", false,
+			"keep work dirs and pools of failed scenarios")
+	},
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand, args []string,
+	) error {
+		selected, err := SelectScenarios(runArgs.scenarios)
+		if err != nil {
+			return err
+		}
+		return runScenarios(ctx, os.Stdout, selected)
+	},
+}
+
+var listCmd = &cli.Subcommand{
+	Use:   "list",
+	Short: "list scenarios",
+
+	NoRequireConfig: true,
+
+	SetupCobra: func(c *cobra.Command) { c.Args = cobra.ExactArgs(0) },
+
+	Run: func(ctx context.Context, subcommand *cli.Subcommand, args []string,
+	) error {
+		for _, s := range Scenarios() {
+			fmt.Printf("%s\t%s\n", s.Name, s.Description)
+		}
+		return nil
+	},
+}
+
+func runScenarios(ctx context.Context, out io.Writer, selected []*Scenario,
+) error {
+	if os.Geteuid() != 0 {
+		return errors.New("platformtest must be run as root")
+	}
+
+	exe := runArgs.exe
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return fmt.Errorf("find zrepl binary: %w", err)
+		}
+	}
+
+	var failed int
+	for _, s := range selected {
+		fmt.Fprintf(out, "=== RUN %s\n", s.Name)
+		begin := time.Now()
+		if err := runScenario(ctx, out, s, exe); err != nil {
+			failed++
+			fmt.Fprintf(out, "--- FAIL %s (%s): %s\n", s.Name,
+				time.Since(begin).Truncate(time.Millisecond), err)
+			continue
+		}
+		fmt.Fprintf(out, "--- PASS %s (%s)\n", s.Name,
+			time.Since(begin).Truncate(time.Millisecond))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failed, len(selected))
+	}
+	return nil
+}
+
+func runScenario(ctx context.Context, out io.Writer, s *Scenario, exe string,
+) (err error) {
+	dir, err := os.MkdirTemp(runArgs.workDir, "zrepl-"+s.Name+"-")
+	if err != nil {
+		return fmt.Errorf("create work dir: %w", err)
+	}
+
+	env := &Env{Dir: dir, Exe: exe, Timeout: runArgs.timeout, Out: out}
+	env.Logf("work dir %s", dir)
+	env.Pool, err = CreatePool(ctx, runArgs.poolName, dir, runArgs.poolSize)
+	if err != nil {
+		if !runArgs.keep {
+			os.RemoveAll(dir)
+		}
+		return err
+	}
+
+	defer func() {
+		if stopErr := env.StopDaemon(ctx); stopErr != nil && err == nil {
+			err = stopErr
+		}
+		if err != nil && runArgs.keep {
+			env.Logf("keep pool %q and work dir", env.Pool.Name)
+			return
+		}
+		if destroyErr := env.Pool.Destroy(ctx); destroyErr != nil {
+			err = errors.Join(err, destroyErr)
+			return
+		}
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			err = errors.Join(err, fmt.Errorf("remove work dir: %w", rmErr))
+		}
+	}()
+	return s.Run(ctx, env)
+}
@@ -0,0 +1,236 @@
+package platformtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/client/status"
+	"github.com/dsh2dsh/zrepl/internal/daemon/job"
+)
+
+const pollInterval = 100 * time.Millisecond
+
+// Env is environment of a scenario: its work dir, disposable pool and zrepl
+// daemon, built from the binary Exe.
+type Env struct {
+	Dir     string
+	Exe     string
+	Pool    *Pool
+	Timeout time.Duration
+	Out     io.Writer
+
+	config string
+	daemon *exec.Cmd
+	exited chan error
+	status *status.Client
+}
+
+// Logf prints a progress message of the scenario.
+func (self *Env) Logf(format string, args ...any) {
+	fmt.Fprintf(self.Out, "    "+format+"\n", args...)
+}
+
+// ConfigPath returns path of config file of the daemon.
+func (self *Env) ConfigPath() string {
+	return filepath.Join(self.Dir, "zrepl.yml")
+}
+
+// WriteConfig writes config file of the daemon from text/template tmpl. The
+// template gets Pool and Dir of env. Global section with control socket, state
+// file and logging inside of Dir is prepended to it.
+func (self *Env) WriteConfig(tmpl string) error {
+	t, err := template.New("config").Parse(globalConfig + tmpl)
+	if err != nil {
+		return fmt.Errorf("parse config template: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = t.Execute(&b, struct {
+		Pool *Pool
+		Dir  string
+	}{Pool: self.Pool, Dir: self.Dir})
+	if err != nil {
+		return fmt.Errorf("execute config template: %w", err)
+	}
+
+	self.config = self.ConfigPath()
+	if err := os.WriteFile(self.config, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+const globalConfig = `
+global:
+  logging:
+    - type: "file"
+      format: "human"
+      level: "debug"
+      filename: "{{ .Dir }}/zrepl.log"
+  control:
+    sockpath: "{{ .Dir }}/control"
+  state_file: "{{ .Dir }}/state.json"
+`
+
+// StartDaemon starts zrepl daemon with config of env and waits, until it
+// responds on its control socket.
+func (self *Env) StartDaemon(ctx context.Context) error {
+	if self.config == "" {
+		return errors.New("config not written")
+	}
+
+	out, err := os.Create(filepath.Join(self.Dir, "daemon.out"))
+	if err != nil {
+		return fmt.Errorf("create daemon output: %w", err)
+	}
+	defer out.Close()
+
+	cmd := exec.Command(self.Exe, "--config", self.config, "daemon")
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start daemon: %w", err)
+	}
+	self.daemon = cmd
+	self.exited = make(chan error, 1)
+	go func() { self.exited <- cmd.Wait() }()
+
+	self.status, err = status.NewClient("unix",
+		filepath.Join(self.Dir, "control"))
+	if err != nil {
+		return fmt.Errorf("connect to daemon: %w", err)
+	}
+
+	err = self.poll(ctx, func() (bool, error) {
+		_, err := self.status.Version()
+		return err == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for daemon: %w", err)
+	}
+	return nil
+}
+
+// StopDaemon shuts down the daemon and waits, until it exited.
+func (self *Env) StopDaemon(ctx context.Context) error {
+	if self.daemon == nil {
+		return nil
+	}
+	defer func() { self.daemon = nil }()
+
+	if _, err := self.Zrepl(ctx, "signal", "shutdown"); err != nil {
+		self.daemon.Process.Kill()
+		<-self.exited
+		return err
+	}
+
+	select {
+	case err := <-self.exited:
+		if err != nil {
+			return fmt.Errorf("daemon exited: %w", err)
+		}
+	case <-time.After(self.Timeout):
+		self.daemon.Process.Kill()
+		<-self.exited
+		return fmt.Errorf("daemon not exited after %s", self.Timeout)
+	}
+	return nil
+}
+
+// Zrepl runs zrepl command with config of env and returns its output.
+func (self *Env) Zrepl(ctx context.Context, args ...string) (string, error) {
+	args = append([]string{"--config", self.config}, args...)
+	out, err := exec.CommandContext(ctx, self.Exe, args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("zrepl %s: %w: %s",
+			strings.Join(args[2:], " "), err, bytes.TrimSpace(out))
+	}
+	return string(out), nil
+}
+
+// RunJob wakes up active job name and waits, until it finished snapshotting,
+// replication and pruning. It returns error of the job, if any.
+func (self *Env) RunJob(ctx context.Context, name string) error {
+	st, err := self.jobStatus(name)
+	if err != nil {
+		return err
+	}
+	prevStarted := st.StartedAt
+
+	if err := self.status.SignalWakeup(name); err != nil {
+		return fmt.Errorf("wakeup job %q: %w", name, err)
+	}
+
+	var jobErr string
+	err = self.poll(ctx, func() (bool, error) {
+		st, err := self.jobStatus(name)
+		if err != nil {
+			return false, err
+		} else if !st.StartedAt.After(prevStarted) {
+			return false, nil
+		} else if _, running := st.Running(); running {
+			return false, nil
+		}
+		jobErr = st.Error()
+		return jobErr != "" || st.PruningReceiver != nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for job %q: %w", name, err)
+	} else if jobErr != "" {
+		return fmt.Errorf("job %q failed: %s", name, jobErr)
+	}
+	return nil
+}
+
+func (self *Env) jobStatus(name string) (*job.ActiveSideStatus, error) {
+	s, err := self.status.Status()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	j, ok := s.Jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("job %q not found", name)
+	}
+	st, ok := j.JobSpecific.(*job.ActiveSideStatus)
+	if !ok {
+		return nil, fmt.Errorf("job %q is not an active job: %T", name,
+			j.JobSpecific)
+	}
+	return st, nil
+}
+
+// poll calls fn, until it returns true or error, the daemon exited or Timeout
+// expired.
+func (self *Env) poll(ctx context.Context, fn func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, self.Timeout)
+	defer cancel()
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		if ok, err := fn(); err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case err := <-self.exited:
+			self.exited <- err
+			return fmt.Errorf("daemon exited: %v", err)
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package platformtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// Pool is a disposable zpool, backed by a sparse file, with mountpoints of its
+// datasets below Altroot.
+type Pool struct {
+	Name    string
+	Vdev    string
+	Altroot string
+}
+
+// CreatePool creates pool name, backed by a sparse file of size bytes in dir.
+func CreatePool(ctx context.Context, name, dir string, size int64,
+) (*Pool, error) {
+	p := &Pool{
+		Name:    name,
+		Vdev:    filepath.Join(dir, name+".img"),
+		Altroot: filepath.Join(dir, "mnt"),
+	}
+
+	f, err := os.Create(p.Vdev)
+	if err != nil {
+		return nil, fmt.Errorf("create vdev: %w", err)
+	} else if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("truncate vdev %q: %w", p.Vdev, err)
+	} else if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close vdev %q: %w", p.Vdev, err)
+	}
+
+	_, err = command(ctx, zfs.ZpoolBin, "create", "-f", "-R", p.Altroot,
+		"-O", "mountpoint=/"+name, name, p.Vdev)
+	if err != nil {
+		os.Remove(p.Vdev)
+		return nil, fmt.Errorf("create pool %q: %w", name, err)
+	}
+	return p, nil
+}
+
+// Destroy destroys the pool and removes its vdev.
+func (self *Pool) Destroy(ctx context.Context) error {
+	if _, err := command(ctx, zfs.ZpoolBin, "destroy", "-f", self.Name); err != nil {
+		return fmt.Errorf("destroy pool %q: %w", self.Name, err)
+	} else if err := os.Remove(self.Vdev); err != nil {
+		return fmt.Errorf("remove vdev: %w", err)
+	}
+	return nil
+}
+
+// Path returns full name of dataset ds of the pool.
+func (self *Pool) Path(ds string) string {
+	if ds == "" {
+		return self.Name
+	}
+	return self.Name + "/" + ds
+}
+
+// Mountpoint returns mountpoint of dataset ds of the pool.
+func (self *Pool) Mountpoint(ds string) string {
+	return filepath.Join(self.Altroot, self.Name, ds)
+}
+
+// CreateDatasets creates datasets of the pool with their parents.
+func (self *Pool) CreateDatasets(ctx context.Context, datasets ...string,
+) error {
+	for _, ds := range datasets {
+		_, err := command(ctx, zfs.ZfsBin, "create", "-p", self.Path(ds))
+		if err != nil {
+			return fmt.Errorf("create dataset %q: %w", self.Path(ds), err)
+		}
+	}
+	return nil
+}
+
+// WriteFile writes data into file name of dataset ds of the pool.
+func (self *Pool) WriteFile(ds, name string, data []byte) error {
+	path := filepath.Join(self.Mountpoint(ds), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write %q: %w", path, err)
+	}
+	return nil
+}
+
+// Snapshots returns names of snapshots of dataset fs, without fs, in order of
+// creation.
+func Snapshots(ctx context.Context, fs string) ([]string, error) {
+	out, err := command(ctx, zfs.ZfsBin, "list", "-H", "-p", "-o", "name",
+		"-t", "snapshot", "-s", "createtxg", "-d", "1", fs)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots of %q: %w", fs, err)
+	}
+
+	var names []string
+	for line := range strings.Lines(out) {
+		if _, name, ok := strings.Cut(strings.TrimSpace(line), "@"); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// command runs name with args and returns its stdout.
+func command(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			err = fmt.Errorf("%w: %s", err, s)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
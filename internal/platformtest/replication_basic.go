@@ -0,0 +1,139 @@
+package platformtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+func init() {
+	addScenario(&Scenario{
+		Name: "replication-basic",
+		Description: "snapshot, replicate and prune filesystems by a push job " +
+			"to a local sink, then check them by zrepl monitor",
+		Run: replicationBasic,
+	})
+}
+
+const (
+	basicRuns         = 4
+	basicKeepSender   = 2
+	basicKeepReceiver = 3
+)
+
+const replicationBasicConfig = `
+jobs:
+  - name: "sink"
+    type: "sink"
+    root_fs: "{{ .Pool.Name }}/sink"
+    recv:
+      placeholder:
+        encryption: "inherit"
+
+  - name: "push"
+    type: "push"
+    connect:
+      type: "local"
+      listener_name: "sink"
+      client_identity: "local"
+    datasets:
+      - pattern: "{{ .Pool.Name }}/src"
+        recursive: true
+    snapshotting:
+      type: "cron"
+      cron: "0 0 1 1 *"
+      prefix: "zrepl_"
+      timestamp_format: "iso-8601"
+      timestamp_local: false
+    pruning:
+      keep_sender:
+        - type: "not_replicated"
+        - type: "last_n"
+          count: 2
+          regex: "^zrepl_"
+      keep_receiver:
+        - type: "last_n"
+          count: 3
+          regex: "^zrepl_"
+    monitor:
+      count:
+        - prefix: "zrepl_"
+          critical: 3
+      latest:
+        - prefix: "zrepl_"
+          critical: "1h"
+`
+
+var basicDatasets = []string{"src", "src/a", "src/a/b", "src/c"}
+
+func replicationBasic(ctx context.Context, env *Env) error {
+	if err := env.Pool.CreateDatasets(ctx, basicDatasets...); err != nil {
+		return err
+	}
+	if err := env.WriteConfig(replicationBasicConfig); err != nil {
+		return err
+	}
+
+	env.Logf("start daemon")
+	if err := env.StartDaemon(ctx); err != nil {
+		return err
+	}
+
+	for i := range basicRuns {
+		for _, ds := range basicDatasets {
+			err := env.Pool.WriteFile(ds, fmt.Sprintf("run%d", i),
+				[]byte(ds+"\n"))
+			if err != nil {
+				return err
+			}
+		}
+		env.Logf("run job %q #%d", "push", i+1)
+		if err := env.RunJob(ctx, "push"); err != nil {
+			return err
+		}
+	}
+
+	for _, ds := range basicDatasets {
+		if err := checkBasicDataset(ctx, env, ds); err != nil {
+			return err
+		}
+	}
+
+	env.Logf("monitor")
+	if _, err := env.Zrepl(ctx, "monitor", "alive"); err != nil {
+		return err
+	} else if _, err := env.Zrepl(ctx, "monitor", "snapshots", "--job",
+		"push"); err != nil {
+		return err
+	}
+
+	env.Logf("stop daemon")
+	return env.StopDaemon(ctx)
+}
+
+// checkBasicDataset checks snapshots of dataset ds were pruned on both sides
+// and the latest one was replicated.
+func checkBasicDataset(ctx context.Context, env *Env, ds string) error {
+	sent, err := Snapshots(ctx, env.Pool.Path(ds))
+	if err != nil {
+		return err
+	} else if len(sent) != basicKeepSender {
+		return fmt.Errorf("%q: want %d snapshots after pruning, got %d: %v",
+			env.Pool.Path(ds), basicKeepSender, len(sent), sent)
+	}
+
+	received := env.Pool.Path("sink/local/" + env.Pool.Path(ds))
+	recv, err := Snapshots(ctx, received)
+	if err != nil {
+		return err
+	} else if len(recv) != basicKeepReceiver {
+		return fmt.Errorf("%q: want %d snapshots after pruning, got %d: %v",
+			received, basicKeepReceiver, len(recv), recv)
+	} else if !slices.Equal(sent, recv[len(recv)-len(sent):]) {
+		return fmt.Errorf("%q: latest snapshots %v not replicated from %q: %v",
+			received, recv, env.Pool.Path(ds), sent)
+	}
+	env.Logf("%s: %d snapshots, %s: %d snapshots", env.Pool.Path(ds),
+		len(sent), received, len(recv))
+	return nil
+}
@@ -0,0 +1,48 @@
+// Package platformtest runs end-to-end scenarios of zrepl against disposable
+// file-backed pools, so packagers can validate a build on their OS and ZFS.
+package platformtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scenario is an end-to-end test, which runs in its own Env.
+type Scenario struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, env *Env) error
+}
+
+var scenarios []*Scenario
+
+func addScenario(s *Scenario) {
+	scenarios = append(scenarios, s)
+	slices.SortFunc(scenarios, func(a, b *Scenario) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// Scenarios returns all known scenarios, sorted by name.
+func Scenarios() []*Scenario { return slices.Clone(scenarios) }
+
+// SelectScenarios returns scenarios with names, or all scenarios, if names is
+// empty.
+func SelectScenarios(names []string) ([]*Scenario, error) {
+	if len(names) == 0 {
+		return Scenarios(), nil
+	}
+
+	selected := make([]*Scenario, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(scenarios,
+			func(s *Scenario) bool { return s.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown scenario %q", name)
+		}
+		selected = append(selected, scenarios[i])
+	}
+	return selected, nil
+}
@@ -0,0 +1,55 @@
+package platformtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config"
+)
+
+func TestSelectScenarios(t *testing.T) {
+	all, err := SelectScenarios(nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+	assert.Equal(t, Scenarios(), all)
+
+	selected, err := SelectScenarios([]string{"replication-basic"})
+	require.NoError(t, err)
+	require.Len(t, selected, 1)
+	assert.Equal(t, "replication-basic", selected[0].Name)
+
+	_, err = SelectScenarios([]string{"replication-basic", "foo"})
+	require.ErrorContains(t, err, `unknown scenario "foo"`)
+}
+
+func TestEnv_WriteConfig(t *testing.T) {
+	env := &Env{
+		Dir:  t.TempDir(),
+		Pool: &Pool{Name: "zreplplatformtest"},
+	}
+	require.NoError(t, env.WriteConfig(replicationBasicConfig))
+
+	c, err := config.ParseConfig(env.ConfigPath())
+	require.NoError(t, err)
+	assert.Equal(t, env.Dir+"/control", c.Global.Control.SockPath)
+	assert.Equal(t, env.Dir+"/state.json", c.Global.StateFile)
+
+	j, err := c.Job("push")
+	require.NoError(t, err)
+	push := j.Ret.(*config.PushJob)
+	assert.Equal(t, "zreplplatformtest/src", push.Datasets[0].Pattern)
+
+	j, err = c.Job("sink")
+	require.NoError(t, err)
+	assert.Equal(t, "zreplplatformtest/sink", j.Ret.(*config.SinkJob).RootFS)
+}
+
+func TestEnv_pollDaemonExited(t *testing.T) {
+	env := &Env{Timeout: time.Second, exited: make(chan error, 1)}
+	env.exited <- nil
+	err := env.poll(t.Context(), func() (bool, error) { return false, nil })
+	require.ErrorContains(t, err, "daemon exited")
+}
@@ -0,0 +1,36 @@
+package pruning
+
+import (
+	"context"
+	"time"
+)
+
+// KeepMinAge keeps every snapshot younger than age. Added to other keep rules
+// it protects fresh snapshots, whatever other rules decide.
+type KeepMinAge struct {
+	age time.Duration
+	now func() time.Time
+}
+
+var _ KeepRule = (*KeepMinAge)(nil)
+
+func NewKeepMinAge(age time.Duration) *KeepMinAge {
+	return &KeepMinAge{age: age, now: time.Now}
+}
+
+func (self *KeepMinAge) KeepRule(_ context.Context, snaps []Snapshot,
+) (destroyList []Snapshot) {
+	oldest := self.now().Add(-self.age)
+	return filterSnapList(snaps, func(s Snapshot) bool {
+		return !s.Date().After(oldest)
+	})
+}
+
+// WithMinAge returns rules with KeepMinAge added, if age > 0. Without rules
+// nothing is destroyed anyway and it returns rules as is.
+func WithMinAge(rules []KeepRule, age time.Duration) []KeepRule {
+	if age <= 0 || len(rules) == 0 {
+		return rules
+	}
+	return append(rules[:len(rules):len(rules)], NewKeepMinAge(age))
+}
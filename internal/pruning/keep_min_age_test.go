@@ -0,0 +1,52 @@
+package pruning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepMinAge(t *testing.T) {
+	now := time.Now()
+	minAge := NewKeepMinAge(time.Hour)
+	minAge.now = func() time.Time { return now }
+
+	inputs := []Snapshot{
+		stubSnap{name: "1", date: now.Add(-2 * time.Hour)},
+		stubSnap{name: "2", date: now.Add(-time.Hour)},
+		stubSnap{name: "3", date: now.Add(-time.Minute)},
+		stubSnap{name: "4", date: now},
+	}
+
+	tcs := map[string]testCase{
+		"keepsYoung": {
+			inputs:     inputs,
+			rules:      []KeepRule{minAge},
+			expDestroy: map[string]bool{"1": true, "2": true},
+		},
+		"overridesOtherRules": {
+			inputs:     inputs,
+			rules:      []KeepRule{MustKeepRegex("^$", false), minAge},
+			expDestroy: map[string]bool{"1": true, "2": true},
+		},
+		"otherRulesKeep": {
+			inputs:     inputs,
+			rules:      []KeepRule{MustKeepRegex("^1$", false), minAge},
+			expDestroy: map[string]bool{"2": true},
+		},
+	}
+	testTable(tcs, t)
+}
+
+func TestWithMinAge(t *testing.T) {
+	assert.Empty(t, WithMinAge(nil, time.Hour))
+
+	rules := []KeepRule{NewKeepNotReplicated()}
+	assert.Equal(t, rules, WithMinAge(rules, 0))
+
+	withMinAge := WithMinAge(rules, time.Hour)
+	assert.Len(t, withMinAge, 2)
+	assert.IsType(t, (*KeepMinAge)(nil), withMinAge[1])
+	assert.Len(t, rules, 1)
+}
@@ -0,0 +1,44 @@
+package pruning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeepProperty keeps snapshots, which have user property set to value, like
+// zrepl:keep=true.
+type KeepProperty struct {
+	property string
+	value    string
+}
+
+var _ PropertiesRule = (*KeepProperty)(nil)
+
+func NewKeepProperty(property, value string) (*KeepProperty, error) {
+	if !strings.Contains(property, ":") {
+		return nil, fmt.Errorf("not a user property: %q", property)
+	} else if value == "" {
+		return nil, errors.New("property value must not be empty")
+	}
+	return &KeepProperty{property: property, value: value}, nil
+}
+
+func MustKeepProperty(property, value string) *KeepProperty {
+	k, err := NewKeepProperty(property, value)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func (k *KeepProperty) Properties() []string { return []string{k.property} }
+
+func (k *KeepProperty) KeepRule(_ context.Context, snaps []Snapshot,
+) []Snapshot {
+	return filterSnapList(snaps, func(s Snapshot) bool {
+		v, ok := s.Property(k.property)
+		return !ok || v != k.value
+	})
+}
@@ -0,0 +1,61 @@
+package pruning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type propSnap struct {
+	stubSnap
+	props map[string]string
+}
+
+func (s propSnap) Property(name string) (string, bool) {
+	v, ok := s.props[name]
+	return v, ok
+}
+
+func TestKeepProperty(t *testing.T) {
+	k := MustKeepProperty("zrepl:keep", "true")
+	assert.Equal(t, []string{"zrepl:keep"}, k.Properties())
+
+	snaps := []Snapshot{
+		propSnap{
+			stubSnap: stubSnap{name: "pinned"},
+			props:    map[string]string{"zrepl:keep": "true"},
+		},
+		propSnap{
+			stubSnap: stubSnap{name: "unpinned"},
+			props:    map[string]string{"zrepl:keep": "false"},
+		},
+		propSnap{
+			stubSnap: stubSnap{name: "other"},
+			props:    map[string]string{"foo:bar": "true"},
+		},
+		stubSnap{name: "none"},
+	}
+
+	destroy := snapshotList(k.KeepRule(t.Context(), snaps))
+	assert.Equal(t, []string{"unpinned", "other", "none"}, destroy.NameList())
+}
+
+func TestRulesProperties(t *testing.T) {
+	rules := []KeepRule{
+		MustKeepProperty("zrepl:keep", "true"),
+		MustKeepRegex(".*", false),
+		MustKeepProperty("com.example:pin", "yes"),
+		MustKeepProperty("zrepl:keep", "1"),
+	}
+	assert.Equal(t, []string{"zrepl:keep", "com.example:pin"},
+		RulesProperties(rules))
+	assert.Empty(t, RulesProperties(rules[1:2]))
+}
+
+func TestNewKeepProperty_invalid(t *testing.T) {
+	_, err := NewKeepProperty("compression", "true")
+	require.Error(t, err)
+	_, err = NewKeepProperty("zrepl:keep", "")
+	require.Error(t, err)
+}
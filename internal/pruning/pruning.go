@@ -3,6 +3,7 @@ package pruning
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/config"
@@ -16,6 +17,29 @@ type Snapshot interface {
 	Name() string
 	Replicated() bool
 	Date() time.Time
+	// Property returns value of user property name, if it's set.
+	Property(name string) (string, bool)
+}
+
+// PropertiesRule is a KeepRule, which needs values of user properties of
+// snapshots.
+type PropertiesRule interface {
+	KeepRule
+	Properties() []string
+}
+
+// RulesProperties returns names of user properties needed by rules.
+func RulesProperties(rules []KeepRule) (props []string) {
+	for _, r := range rules {
+		if r, ok := r.(PropertiesRule); ok {
+			for _, name := range r.Properties() {
+				if !slices.Contains(props, name) {
+					props = append(props, name)
+				}
+			}
+		}
+	}
+	return props
 }
 
 func RulesFromConfig(in []config.PruningEnum) (rules []KeepRule, err error) {
@@ -39,6 +63,8 @@ func RuleFromConfig(in config.PruningEnum) (KeepRule, error) {
 		return NewKeepRegex(v.Regex, v.Negate)
 	case *config.PruneGrid:
 		return NewKeepGrid(v)
+	case *config.PruneKeepProperty:
+		return NewKeepProperty(v.Property, v.Value)
 	default:
 		return nil, fmt.Errorf("unknown keep rule type %T", v)
 	}
@@ -21,6 +21,8 @@ func (s stubSnap) Replicated() bool { return s.replicated }
 
 func (s stubSnap) Date() time.Time { return s.date }
 
+func (s stubSnap) Property(string) (string, bool) { return "", false }
+
 type testCase struct {
 	inputs     []Snapshot
 	rules      []KeepRule
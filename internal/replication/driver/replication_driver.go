@@ -146,8 +146,10 @@ type Step interface {
 }
 
 type fs struct {
-	fs     FS
-	prefix string
+	fs       FS
+	prefix   string
+	blackout func(time.Time) time.Time
+	paused   func() <-chan struct{}
 
 	l *chainlock.L
 
@@ -192,6 +194,27 @@ type Config struct {
 	MaxAttempts              int           `validate:"eq=-1|gt=0"`
 	ReconnectHardFailTimeout time.Duration `validate:"gt=0"`
 	Prefix                   string
+
+	// Retry intervals between attempts. Every next interval is
+	// RetryMultiplier times longer, but not longer than RetryMaxInterval, if
+	// it's not zero.
+	RetryInterval    time.Duration `validate:"min=0"`
+	RetryMaxInterval time.Duration `validate:"min=0"`
+	RetryMultiplier  float64       `validate:"omitempty,gte=1"`
+
+	// Blackout returns the end of blackout window, which contains given time,
+	// or zero time. Steps don't start during blackout windows. Can be nil.
+	Blackout func(time.Time) time.Time
+
+	// Paused returns a channel, which is closed when replication resumed, or
+	// nil if replication isn't paused. Steps don't start while replication is
+	// paused. Can be nil.
+	Paused func() <-chan struct{}
+
+	// Concurrency returns current number of parallel steps, which can change
+	// while replication runs. If not nil, it's used instead of
+	// StepQueueConcurrency.
+	Concurrency func() int
 }
 
 func (c Config) Validate() error {
@@ -227,6 +250,7 @@ func Do(ctx context.Context, config Config, planner Planner) (ReportFunc,
 
 		var prev *attempt
 		mainLog := log
+		retryInterval := config.RetryInterval
 		for ano := 0; ano < config.MaxAttempts; ano++ {
 			log := mainLog.With(slog.Int("attempt_number", ano))
 			log.Debug("start attempt")
@@ -295,6 +319,12 @@ func Do(ctx context.Context, config Config, planner Planner) (ReportFunc,
 				if connectErr == nil {
 					// same level as 'begin with reconnect' message above
 					log.Error("reconnect successful")
+					if retryInterval > 0 && ano+1 < config.MaxAttempts {
+						log.With(slog.Duration("interval", retryInterval)).
+							Info("wait before next attempt")
+						run.l.DropWhile(func() { sleepCtx(graceful, retryInterval) })
+						retryInterval = config.nextRetryInterval(retryInterval)
+					}
 					continue
 				} else {
 					run.waitReconnectError = newTimedError(connectErr, connectErrTime)
@@ -328,6 +358,25 @@ func Do(ctx context.Context, config Config, planner Planner) (ReportFunc,
 	return report, wait
 }
 
+func (c *Config) nextRetryInterval(d time.Duration) time.Duration {
+	if c.RetryMultiplier > 1 {
+		d = time.Duration(float64(d) * c.RetryMultiplier)
+	}
+	if c.RetryMaxInterval > 0 {
+		d = min(d, c.RetryMaxInterval)
+	}
+	return d
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
 func getLog(ctx context.Context) *slog.Logger {
 	return logging.GetLogger(ctx, logging.SubsysReplication)
 }
@@ -366,6 +415,8 @@ func (a *attempt) doGlobalPlanning(ctx context.Context, prev *attempt,
 		fs := &fs{
 			fs:        pfs,
 			prefix:    a.config.Prefix,
+			blackout:  a.config.Blackout,
+			paused:    a.config.Paused,
 			l:         a.l,
 			blockedOn: report.FsBlockedOnNothing,
 		}
@@ -465,7 +516,11 @@ func (a *attempt) doGlobalPlanning(ctx context.Context, prev *attempt,
 func (a *attempt) doFilesystems(ctx context.Context, prevs map[*fs]*fs) {
 	defer a.l.Lock().Unlock()
 	stepQueue := newStepQueue()
-	defer stepQueue.Start(a.config.StepQueueConcurrency)()
+	if a.config.Concurrency != nil {
+		defer stepQueue.StartDynamic(a.config.Concurrency, time.Second)()
+	} else {
+		defer stepQueue.Start(a.config.StepQueueConcurrency)()
+	}
 
 	var fssesDone sync.WaitGroup
 	for _, f := range a.fss {
@@ -598,6 +653,13 @@ func (f *fs) do(ctx context.Context, pq *stepQueue, prev *fs) {
 	// now we are done planning (f.planned.steps won't change from now on)
 	f.planning.done = true
 
+	// fast path: nothing to replicate, so there is no need to wait for parents
+	// and our children can see right now, that we are present on receiver.
+	if len(f.planned.steps) == 0 {
+		f.debug("no steps planned, skip waiting for parents")
+		return
+	}
+
 	// wait for parents' initial replication
 	f.blockedOn = report.FsBlockedOnParentInitialRepl
 	parents := make([]string, 0, len(f.initialRepOrd.parents))
@@ -702,6 +764,10 @@ func (f *fs) do(ctx context.Context, pq *stepQueue, prev *fs) {
 	for i, s := range f.planned.steps {
 		// lock must not be held while executing step in order for reporting to work
 		f.l.DropWhile(func() {
+			// pause during blackout windows, current step is already finished
+			f.waitBlackout(graceful)
+			// pause until replication resumed
+			f.waitPaused(graceful)
 			// wait for parallel replication
 			targetDate := s.step.TargetDate()
 			f.l.HoldWhile(func() { f.blockedOn = report.FsBlockedOnReplStepQueue })
@@ -733,6 +799,50 @@ func (f *fs) do(ctx context.Context, pq *stepQueue, prev *fs) {
 	}
 }
 
+// waitBlackout waits, while current time is inside of a blackout window.
+//
+// caller must not hold lock l
+func (f *fs) waitBlackout(ctx context.Context) {
+	if f.blackout == nil {
+		return
+	}
+
+	for {
+		until := f.blackout(time.Now())
+		if until.IsZero() {
+			return
+		}
+		f.debug("pause until end of blackout window at %s", until)
+		f.l.HoldWhile(func() { f.blockedOn = report.FsBlockedOnBlackout })
+		if sleepCtx(ctx, time.Until(until)); ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// waitPaused waits, while replication is paused.
+//
+// caller must not hold lock l
+func (f *fs) waitPaused(ctx context.Context) {
+	if f.paused == nil {
+		return
+	}
+
+	for {
+		resumed := f.paused()
+		if resumed == nil {
+			return
+		}
+		f.debug("pause until replication resumed")
+		f.l.HoldWhile(func() { f.blockedOn = report.FsBlockedOnPaused })
+		select {
+		case <-ctx.Done():
+			return
+		case <-resumed:
+		}
+	}
+}
+
 // caller must hold lock l
 func (r *run) report() *report.Report {
 	report := &report.Report{
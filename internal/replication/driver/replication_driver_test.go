@@ -15,6 +15,7 @@ import (
 	jsondiffformatter "github.com/yudai/gojsondiff/formatter"
 
 	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/util/chainlock"
 )
 
 type mockPlanner struct {
@@ -213,3 +214,61 @@ func TestReplication(t *testing.T) {
 		t.Logf("\t%s", step)
 	}
 }
+
+func TestConfig_nextRetryInterval(t *testing.T) {
+	c := Config{RetryMultiplier: 2, RetryMaxInterval: 5 * time.Minute}
+	d := time.Minute
+	var got []time.Duration
+	for range 4 {
+		d = c.nextRetryInterval(d)
+		got = append(got, d)
+	}
+	assert.Equal(t, []time.Duration{
+		2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute,
+	}, got)
+
+	c = Config{}
+	assert.Equal(t, time.Minute, c.nextRetryInterval(time.Minute))
+}
+
+func TestFs_waitPaused(t *testing.T) {
+	resumed := make(chan struct{})
+	var paused atomic.Bool
+	paused.Store(true)
+	f := &fs{
+		fs: &mockFS{name: "zroot/one"},
+		l:  chainlock.New(),
+		paused: func() <-chan struct{} {
+			if paused.Load() {
+				return resumed
+			}
+			return nil
+		},
+		blockedOn: report.FsBlockedOnNothing,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		f.waitPaused(t.Context())
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		defer f.l.Lock().Unlock()
+		return f.blockedOn == report.FsBlockedOnPaused
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("waitPaused returned while paused")
+	default:
+	}
+
+	paused.Store(false)
+	close(resumed)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitPaused not returned after resume")
+	}
+}
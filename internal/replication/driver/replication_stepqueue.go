@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dsh2dsh/zrepl/internal/util/chainlock"
+	"github.com/dsh2dsh/zrepl/internal/util/pressure"
 )
 
 type stepQueueRec struct {
@@ -70,6 +71,20 @@ func (q *stepQueue) Start(concurrency int) (done func()) {
 	if concurrency < 1 {
 		panic("concurrency must be >= 1")
 	}
+	return q.start(func() int { return concurrency }, 0)
+}
+
+// StartDynamic is like Start, but concurrency can change while the queue is
+// running. The queue checks it after every completed step and every recheck
+// interval.
+func (q *stepQueue) StartDynamic(concurrency func() int,
+	recheck time.Duration,
+) (done func()) {
+	return q.start(func() int { return max(concurrency(), 1) }, recheck)
+}
+
+func (q *stepQueue) start(concurrency func() int, recheck time.Duration,
+) (done func()) {
 	// l protects pending and queueItems
 	l := chainlock.New()
 	pendingCond := l.NewCond()
@@ -81,10 +96,23 @@ func (q *stepQueue) Start(concurrency int) (done func()) {
 	stopped := false
 	active := 0
 	go func() { // "stopper" goroutine
-		<-q.stop
-		defer l.Lock().Unlock()
-		stopped = true
-		pendingCond.Broadcast()
+		var tick <-chan time.Time
+		if recheck > 0 {
+			t := time.NewTicker(recheck)
+			defer t.Stop()
+			tick = t.C
+		}
+		for {
+			select {
+			case <-q.stop:
+				defer l.Lock().Unlock()
+				stopped = true
+				pendingCond.Broadcast()
+				return
+			case <-tick:
+				l.HoldWhile(pendingCond.Broadcast)
+			}
+		}
 	}()
 	go func() { // "reqs" goroutine
 		for {
@@ -116,7 +144,8 @@ func (q *stepQueue) Start(concurrency int) (done func()) {
 		defer l.Lock().Unlock()
 		for {
 
-			for !stopped && (active >= concurrency || pending.Len() == 0) {
+			for !stopped && (active >= pressure.Concurrency(concurrency()) ||
+				pending.Len() == 0) {
 				pendingCond.Wait()
 			}
 			if stopped {
@@ -169,3 +169,37 @@ func TestPqConcurrent(t *testing.T) {
 		assert.Less(t, math.Abs(deltaFromIdeal), 0.05)
 	}
 }
+
+func TestPqDynamicConcurrency(t *testing.T) {
+	ctx := t.Context()
+	var concurrency atomic.Int32
+	concurrency.Store(1)
+
+	q := newStepQueue()
+	defer q.StartDynamic(func() int { return int(concurrency.Load()) },
+		10*time.Millisecond)()
+
+	first := q.WaitReady(ctx, "1", time.Unix(1, 0))
+	defer first()
+
+	secondReady := make(chan struct{})
+	go func() {
+		defer q.WaitReady(ctx, "2", time.Unix(2, 0))()
+		close(secondReady)
+	}()
+
+	select {
+	case <-secondReady:
+		t.Fatal("second step must wait, while the first one is active")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the first step is still active, but the second one must start after the
+	// recheck interval
+	concurrency.Store(2)
+	select {
+	case <-secondReady:
+	case <-time.After(time.Second):
+		t.Fatal("second step must start after concurrency increased")
+	}
+}
@@ -1,5 +1,7 @@
 package pdu
 
+import "time"
+
 type ListFilesystemRes struct {
 	Filesystems []*Filesystem `json:"Filesystems,omitempty"`
 }
@@ -19,6 +21,10 @@ type Filesystem struct {
 	Replicate  bool   `json:"Replicate,omitempty"`
 	Exclude    string `json:"Exclude,omitempty"`
 	Replicated bool   `json:"Replicated,omitempty"`
+
+	// StorageClass, if not empty, is storage class of the filesystem on sender,
+	// which receivers map to their roots.
+	StorageClass string `json:"StorageClass,omitempty"`
 }
 
 func (x *Filesystem) GetPath() string {
@@ -44,6 +50,8 @@ func (x *Filesystem) GetIsPlaceholder() bool {
 
 type ListFilesystemVersionsReq struct {
 	Filesystem string `json:"Filesystem,omitempty"`
+	// User properties, which values return with every version.
+	Properties []string `json:"Properties,omitempty"`
 }
 
 func (x *ListFilesystemVersionsReq) GetFilesystem() string {
@@ -70,6 +78,8 @@ type FilesystemVersion struct {
 	Guid      uint64                        `json:"Guid,omitempty"`
 	CreateTXG uint64                        `json:"CreateTXG,omitempty"`
 	Creation  string                        `json:"Creation,omitempty"` // RFC 3339
+
+	Properties map[string]string `json:"Properties,omitempty"`
 }
 
 type FilesystemVersion_VersionType int32
@@ -199,6 +209,7 @@ const (
 	ReplicationGuaranteeKind_GuaranteeResumability           ReplicationGuaranteeKind = 1
 	ReplicationGuaranteeKind_GuaranteeIncrementalReplication ReplicationGuaranteeKind = 2
 	ReplicationGuaranteeKind_GuaranteeNothing                ReplicationGuaranteeKind = 3
+	ReplicationGuaranteeKind_GuaranteeBookmarks              ReplicationGuaranteeKind = 4
 )
 
 // Enum value maps for ReplicationGuaranteeKind.
@@ -208,12 +219,14 @@ var (
 		1: "GuaranteeResumability",
 		2: "GuaranteeIncrementalReplication",
 		3: "GuaranteeNothing",
+		4: "GuaranteeBookmarks",
 	}
 	ReplicationGuaranteeKind_value = map[string]int32{
 		"GuaranteeInvalid":                0,
 		"GuaranteeResumability":           1,
 		"GuaranteeIncrementalReplication": 2,
 		"GuaranteeNothing":                3,
+		"GuaranteeBookmarks":              4,
 	}
 )
 
@@ -228,6 +241,11 @@ type SendRes struct {
 	// Expected stream size determined by dry run, not exact.
 	// 0 indicates that for the given SendReq, no size estimate could be made.
 	ExpectedSize uint64 `json:"ExpectedSize,omitempty"`
+	// Encrypted is true, if the stream is a raw send of an encrypted
+	// filesystem.
+	Encrypted bool `json:"Encrypted,omitempty"`
+	// Holds is true, if the stream includes user holds of snapshots.
+	Holds bool `json:"Holds,omitempty"`
 }
 
 func (x *SendRes) GetUsedResumeToken() bool {
@@ -255,13 +273,54 @@ func (x *SendCompletedReq) GetOriginalReq() *SendReq {
 	return nil
 }
 
+// AdoptReq asks the receiver to adopt snapshot To of Filesystem, which was
+// received by other means, like manual zfs send and recv.
+type AdoptReq struct {
+	Filesystem        string             `json:"Filesystem,omitempty"`
+	To                *FilesystemVersion `json:"To,omitempty"`
+	ReplicationConfig *ReplicationConfig `json:"ReplicationConfig,omitempty"`
+}
+
+func (x *AdoptReq) GetFilesystem() string {
+	if x != nil {
+		return x.Filesystem
+	}
+	return ""
+}
+
+func (x *AdoptReq) GetTo() *FilesystemVersion {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *AdoptReq) GetReplicationConfig() *ReplicationConfig {
+	if x != nil {
+		return x.ReplicationConfig
+	}
+	return nil
+}
+
 type DestroySnapshotsReq struct {
 	Filesystems []DestroySnapshots `json:"Filesystems,omitempty"`
+	// DestroyDelay, if positive, marks snapshots for destroy and destroys them
+	// only after the delay.
+	DestroyDelay time.Duration `json:"DestroyDelay,omitempty"`
+	// ReleaseHolds is a regular expression. Holds with matching tags are
+	// released from held snapshots, if it makes them destroyable.
+	ReleaseHolds string `json:"ReleaseHolds,omitempty"`
+	// Reason describes why snapshots are destroyed, like keep rules of the
+	// pruner. It's recorded into lifecycle journals.
+	Reason string `json:"Reason,omitempty"`
 }
 
 type DestroySnapshots struct {
 	Filesystem string   `json:"Filesystem,omitempty"`
 	Snapshots  []string `json:"Snapshots,omitempty"`
+	// Bookmarks are names of zrepl bookmarks without '#', which sender destroys
+	// after snapshots.
+	Bookmarks []string `json:"Bookmarks,omitempty"`
 
 	localPath string
 }
@@ -299,10 +358,22 @@ type DestroyedSnapshots struct {
 type DestroySnapshotRes struct {
 	Name  string `json:"Name,omitempty"`
 	Error string `json:"Error,omitempty"`
+	// Held are snapshots, which weren't destroyed, because of user holds. Error
+	// is empty, if it's the only reason.
+	Held []HeldSnapshot `json:"Held,omitempty"`
+}
+
+type HeldSnapshot struct {
+	Name string   `json:"Name,omitempty"`
+	Tags []string `json:"Tags,omitempty"`
 }
 
 type ReplicationCursorReq struct {
 	Filesystem string `json:"Filesystem,omitempty"`
+	// If not empty, requests cursors of all these filesystems at once and
+	// Filesystem is ignored. Results are returned in
+	// [ReplicationCursorRes.Results].
+	Filesystems []string `json:"Filesystems,omitempty"`
 }
 
 func (x *ReplicationCursorReq) GetFilesystem() string {
@@ -312,8 +383,17 @@ func (x *ReplicationCursorReq) GetFilesystem() string {
 	return ""
 }
 
+func (x *ReplicationCursorReq) GetFilesystems() []string {
+	if x != nil {
+		return x.Filesystems
+	}
+	return nil
+}
+
 type ReplicationCursorRes struct {
 	Result *ReplicationCursorRes_Result `json:"Result,omitempty"`
+	// Results of batched request by filesystem.
+	Results map[string]*ReplicationCursorRes_Result `json:"Results,omitempty"`
 }
 
 type ReplicationCursorRes_Result struct {
@@ -336,6 +416,17 @@ func (x *ReplicationCursorRes) GetNotexist() bool {
 	return x.GetResult().Notexist
 }
 
+// Lookup returns result of batched request for filesystem fs. It returns nil
+// if the response has no result for fs.
+func (x *ReplicationCursorRes) Lookup(fs string) *ReplicationCursorRes {
+	if x == nil {
+		return nil
+	} else if r, ok := x.Results[fs]; ok && r != nil {
+		return &ReplicationCursorRes{Result: r}
+	}
+	return nil
+}
+
 type ReceiveReq struct {
 	Filesystem string             `json:"Filesystem,omitempty"`
 	To         *FilesystemVersion `json:"To,omitempty"`
@@ -343,6 +434,24 @@ type ReceiveReq struct {
 	// zfs recv of the stream in the request
 	ClearResumeToken  bool               `json:"ClearResumeToken,omitempty"`
 	ReplicationConfig *ReplicationConfig `json:"ReplicationConfig,omitempty"`
+
+	// StreamSession, if not empty, journals the stream on receiver, so a broken
+	// request can be continued by the next request with the same session,
+	// which sends the stream again from StreamOffset.
+	StreamSession string `json:"StreamSession,omitempty"`
+	StreamOffset  int64  `json:"StreamOffset,omitempty"`
+
+	// StorageClass is storage class of Filesystem on sender, which selects root
+	// of the receiver for filesystems received first time.
+	StorageClass string `json:"StorageClass,omitempty"`
+
+	// ExpectedSize is estimated size of the stream, zero if unknown.
+	ExpectedSize uint64 `json:"ExpectedSize,omitempty"`
+	// Encrypted is true, if the stream is a raw send of an encrypted
+	// filesystem.
+	Encrypted bool `json:"Encrypted,omitempty"`
+	// Holds is true, if the stream includes user holds of snapshots.
+	Holds bool `json:"Holds,omitempty"`
 }
 
 func (x *ReceiveReq) GetFilesystem() string {
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
@@ -17,7 +18,6 @@ import (
 	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
 	"github.com/dsh2dsh/zrepl/internal/replication/report"
-	"github.com/dsh2dsh/zrepl/internal/zfs"
 )
 
 // Endpoint represents one side of the replication.
@@ -48,19 +48,39 @@ type Receiver interface {
 	// Receive sends r and sendStream (the latter containing a ZFS send stream)
 	// to the parent github.com/dsh2dsh/zrepl/replication.Endpoint.
 	Receive(ctx context.Context, req *pdu.ReceiveReq, receive io.ReadCloser) error
+	// Adopt makes snapshot of a filesystem, received by other means, the
+	// latest received snapshot of the job.
+	Adopt(ctx context.Context, req *pdu.AdoptReq) error
 }
 
 type Planner struct {
 	sender   Sender
 	receiver Receiver
 	policy   PlannerPolicy
+	skip     func(fs string) bool
+	memory   *PlanningMemory
 
 	promSecsPerState    *prometheus.HistogramVec // labels: state
 	promBytesReplicated *prometheus.CounterVec   // labels: filesystem
+	stepMetrics         *StepMetrics
 }
 
 func (p *Planner) Recursive() bool { return p.policy.Recursive() }
 
+// WithSkip configures p to exclude every sender filesystem, for which skip
+// returns true, from planning.
+func (p *Planner) WithSkip(skip func(fs string) bool) *Planner {
+	p.skip = skip
+	return p
+}
+
+// WithStepMetrics configures p to observe every successfully replicated step
+// with m.
+func (p *Planner) WithStepMetrics(m *StepMetrics) *Planner {
+	p.stepMetrics = m
+	return p
+}
+
 func (p *Planner) Plan(ctx context.Context) ([]driver.FS, error) {
 	fss, err := p.doPlanning(ctx)
 	if err != nil {
@@ -124,6 +144,12 @@ type Filesystem struct {
 	Path                 string             // compat
 	receiverFS, senderFS *pdu.Filesystem    // receiverFS may be nil, senderFS never nil
 	promBytesReplicated  prometheus.Counter // compat
+	stepMetrics          *StepMetrics
+
+	// memory is shared by all filesystems of the planner and planningMemory is
+	// estimated memory of this filesystem, which is reported.
+	memory         *PlanningMemory
+	planningMemory atomic.Int64
 
 	sendReplicate bool
 	sendExclude   string
@@ -157,7 +183,10 @@ func (f *Filesystem) PlanFS(ctx context.Context, prefix string) ([]driver.Step,
 }
 
 func (f *Filesystem) ReportInfo() *report.FilesystemInfo {
-	return &report.FilesystemInfo{Name: f.Path} // FIXME compat name
+	return &report.FilesystemInfo{
+		Name:           f.Path, // FIXME compat name
+		PlanningMemory: f.planningMemory.Load(),
+	}
 }
 
 // caller must ensure policy.Validate() == nil
@@ -169,6 +198,7 @@ func NewPlanner(secsPerState *prometheus.HistogramVec, bytesReplicated *promethe
 		sender:              sender,
 		receiver:            receiver,
 		policy:              policy,
+		memory:              newPlanningMemory(policy.PlanningMemory),
 		promSecsPerState:    secsPerState,
 		promBytesReplicated: bytesReplicated,
 	}
@@ -259,23 +289,30 @@ func (p *Planner) doPlanning(ctx context.Context) ([]*Filesystem, error) {
 	if err := g.Wait(); err != nil {
 		return nil, err //nolint:wrapcheck // our error
 	}
-	return p.mergeFilesystems(src, dst), nil
+	return p.mergeFilesystems(log, src, dst), nil
 }
 
-func (p *Planner) mergeFilesystems(src, dst *pdu.ListFilesystemRes,
+func (p *Planner) mergeFilesystems(log *slog.Logger,
+	src, dst *pdu.ListFilesystemRes,
 ) []*Filesystem {
 	merged := make([]*Filesystem, 0, len(src.Filesystems))
 	for _, senderFS := range src.Filesystems {
 		if p.Recursive() && senderFS.Replicated {
 			continue
+		} else if p.skip != nil && p.skip(senderFS.Path) {
+			log.With(slog.String("filesystem", senderFS.Path)).
+				Info("skip filesystem by request")
+			continue
 		}
 
 		fs := &Filesystem{
-			sender:   p.sender,
-			receiver: p.receiver,
-			policy:   p.policy,
-			Path:     senderFS.Path,
-			senderFS: senderFS,
+			sender:      p.sender,
+			receiver:    p.receiver,
+			policy:      p.policy,
+			stepMetrics: p.stepMetrics,
+			memory:      p.memory,
+			Path:        senderFS.Path,
+			senderFS:    senderFS,
 
 			sendReplicate: p.Recursive() && senderFS.Replicate,
 			sendExclude:   senderFS.Exclude,
@@ -341,24 +378,21 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 		rfsvs = []*pdu.FilesystemVersion{}
 	}
 
-	var resumeToken *zfs.ResumeToken
-	var resumeTokenRaw string
-	if fs.receiverFS != nil && fs.receiverFS.ResumeToken != "" {
-		resumeTokenRaw = fs.receiverFS.ResumeToken // shadow
-		log.With(slog.String("receiverFS.ResumeToken", resumeTokenRaw)).
-			Debug("decode receiver fs resume token")
-		resumeToken, err = zfs.ParseResumeToken(ctx, resumeTokenRaw) // shadow
-		if err != nil {
-			// TODO in theory, we could do replication without resume token, but that
-			// would mean that we need to discard the resumable state on the
-			// receiver's side. Would be easy by setting UsedResumeToken=false in the
-			// RecvReq ...
-			//
-			// FIXME / CHECK semantics UsedResumeToken if SendReq.ResumeToken == ""
-			logger.WithError(log, err, "cannot decode resume token, aborting")
-			return nil, err
-		}
-		log.With(slog.Any("token", resumeToken)).Debug("decode resume token")
+	sfsvs, rfsvs, err = fs.limitVersions(log, sfsvs, rfsvs)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	sfsvs, rfsvs, memory, err := fs.acquireMemory(ctx, log, sfsvs, rfsvs)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.memory.Release(memory)
+
+	resumeStep, err := fs.resumeStep(ctx, log, sfsvs)
+	if err != nil {
+		return nil, err
 	}
 
 	// build the list of replication steps
@@ -379,39 +413,9 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 	//   that's actually equivalent to simply cutting off earlier versions from
 	//   rfsvs and sfsvs
 	var steps []*Step
-	if resumeToken != nil {
+	if resumeStep != nil {
 		sfsvs := SortVersionListByCreateTXGThenBookmarkLTSnapshot(sfsvs)
 
-		var from, to *pdu.FilesystemVersion
-		for s := range slices.Values(sfsvs) {
-			if resumeToken.HasFromGUID && s.Guid == resumeToken.FromGUID {
-				if from != nil && from.Type == pdu.FilesystemVersion_Snapshot {
-					// prefer snapshots over bookmarks for size estimation
-				} else {
-					from = s
-				}
-			}
-			if resumeToken.HasToGUID && s.Guid == resumeToken.ToGUID &&
-				s.Type == pdu.FilesystemVersion_Snapshot {
-				// `to` must always be a snapshot
-				to = s
-			}
-		}
-
-		if to == nil {
-			return nil, fmt.Errorf(
-				"resume token `toguid` = %v not found on sender (`toname` = %q)",
-				resumeToken.ToGUID, resumeToken.ToName)
-		} else if from == to {
-			return nil, errors.New(
-				"resume token `fromguid` and `toguid` match same version on sener")
-		}
-
-		// `from` may be nil, `to` is no nil, encryption matches good to go this one
-		// step!
-		resumeStep := NewStep(fs, from, to)
-		resumeStep.resumeToken = resumeTokenRaw
-
 		// By definition, the resume token _must_ be the receiver's most recent
 		// version, if they have any don't bother checking, zfs recv will produce an
 		// error if above assumption is wrong thus, subsequent steps are just
@@ -419,9 +423,12 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 		steps = makeSteps(fs, prefix, resumeStep, slices.DeleteFunc(sfsvs,
 			func(s *pdu.FilesystemVersion) bool {
 				return s.Type != pdu.FilesystemVersion_Snapshot
-			}))
-	} else { // resumeToken == nil
+			}), fs.policy.Intermediates)
+	} else { // resumeStep == nil
 		path, conflict := IncrementalPath(rfsvs, sfsvs)
+		if conflict != nil {
+			path, conflict = fs.resolveForeign(ctx, prefix, conflict)
+		}
 		if conflict != nil {
 			updPath, updConflict := tryAutoresolveConflict(conflict,
 				*fs.policy.ConflictResolution)
@@ -441,7 +448,10 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 				"len(path) must be two for incremental repl, and initial repl must start with nil, got path[0]=%#v",
 				path[0]))
 		case len(path) > 1:
-			steps = makeSteps(fs, prefix, nil, path)
+			if fs.bookmarksOnly() {
+				path = preferBookmarkFrom(path, sfsvs)
+			}
+			steps = makeSteps(fs, prefix, nil, path, fs.policy.Intermediates)
 		}
 	}
 
@@ -449,8 +459,11 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 		log.Info("planning determined that no replication steps are required")
 		return steps, nil
 	}
-	log.With(slog.Int("steps", len(steps))).Debug(
-		"planning determined replication required")
+	fs.accountSteps(steps)
+	log.With(
+		slog.Int("steps", len(steps)),
+		slog.Int64("memory", fs.planningMemory.Load()),
+	).Debug("planning determined replication required")
 
 	log.Debug("compute send size estimate")
 	if err := fs.updateSizeEstimates(ctx, steps); err != nil {
@@ -460,6 +473,60 @@ func (fs *Filesystem) doPlanning(ctx context.Context, prefix string) ([]*Step,
 	return steps, nil
 }
 
+// limitVersions applies configured versions limit to sender and receiver
+// versions, because foreign tools can create so many snapshots, that planning
+// takes too much time and memory.
+func (fs *Filesystem) limitVersions(log *slog.Logger,
+	sfsvs, rfsvs []*pdu.FilesystemVersion,
+) ([]*pdu.FilesystemVersion, []*pdu.FilesystemVersion, error) {
+	limit := &fs.policy.VersionsLimit
+	n := max(len(sfsvs), len(rfsvs))
+	if !limit.Exceeded(n) {
+		return sfsvs, rfsvs, nil
+	}
+
+	log = log.With(
+		slog.Int("sender_versions", len(sfsvs)),
+		slog.Int("receiver_versions", len(rfsvs)),
+		slog.Int("max", limit.Max))
+
+	switch limit.Action {
+	case VersionsLimitSkip:
+		return nil, nil, fmt.Errorf(
+			"skip filesystem: %d versions exceed limit of %d", n, limit.Max)
+	case VersionsLimitTruncate:
+		log.With(slog.Int("keep", limit.Keep)).
+			Warn("versions limit exceeded, use only most recent versions for planning")
+		sfsvs = SortVersionListByCreateTXGThenBookmarkLTSnapshot(sfsvs)
+		rfsvs = SortVersionListByCreateTXGThenBookmarkLTSnapshot(rfsvs)
+		base := commonBase(sfsvs, rfsvs)
+		sfsvs = limit.Truncate(sfsvs, base)
+		rfsvs = limit.Truncate(rfsvs, base)
+	default:
+		log.Warn("versions limit exceeded")
+	}
+	return sfsvs, rfsvs, nil
+}
+
+// commonBase returns the newest receiver snapshot, which sender has a version
+// of with the same GUID, like IncrementalPath chooses incremental source.
+// It returns nil, if sender and receiver have nothing in common. sfsvs and
+// rfsvs must be sorted by createtxg.
+func commonBase(sfsvs, rfsvs []*pdu.FilesystemVersion) *pdu.FilesystemVersion {
+	guids := make(map[uint64]struct{}, len(sfsvs))
+	for _, v := range sfsvs {
+		guids[v.GetGuid()] = struct{}{}
+	}
+	for _, v := range slices.Backward(rfsvs) {
+		if v.Type != pdu.FilesystemVersion_Snapshot {
+			continue
+		} else if _, ok := guids[v.GetGuid()]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
 func (fs *Filesystem) listBothVersions(ctx context.Context,
 ) (resps [2]*pdu.ListFilesystemVersionsRes, err error) {
 	req := pdu.ListFilesystemVersionsReq{Filesystem: fs.Path}
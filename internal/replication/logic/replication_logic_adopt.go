@@ -0,0 +1,85 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+// Adopt adopts receiver filesystems, seeded by other means, like manual zfs
+// send and recv. Every filesystem is verified first, like Verify does, and
+// only filesystems without errors are adopted: the latest common snapshot
+// becomes the replication cursor on sender side and the last received snapshot
+// on receiver side, so next replication will be incremental from it. With
+// dryRun nothing changes, only verification happens.
+func (p *Planner) Adopt(ctx context.Context, dryRun bool) *report.VerifyReport {
+	log := getLogger(ctx).With(slog.Bool("dry_run", dryRun))
+	log.Info("start adoption")
+
+	r := &report.VerifyReport{StartAt: time.Now()}
+	defer func() { r.FinishAt = time.Now() }()
+
+	fss, err := p.verifyFilesystems(ctx)
+	if err != nil {
+		logger.WithError(log, err, "error listing filesystems")
+		r.Err = err.Error()
+		return r
+	}
+
+	r.Filesystems = make([]*report.VerifyFilesystemReport, 0, len(fss))
+	for _, fs := range fss {
+		if ctx.Err() != nil {
+			r.Err = context.Cause(ctx).Error()
+			break
+		}
+		fsr, latest := fs.verifyLatest(ctx)
+		r.Filesystems = append(r.Filesystems, fsr)
+		l := log.With(slog.String("filesystem", fsr.Name))
+		if fsr.Failed() {
+			l.With(slog.Any("errors", fsr.Errors)).Error("verification failed")
+			continue
+		} else if dryRun {
+			continue
+		}
+		if err := fs.adopt(ctx, latest); err != nil {
+			logger.WithError(l, err, "adoption failed")
+			fsr.Fail(err.Error())
+			continue
+		}
+		fsr.Adopted = true
+		l.With(slog.String("snapshot", latest.GetName())).Info("adopted")
+	}
+
+	log.With(slog.Int("filesystems", len(r.Filesystems)),
+		slog.Int("failed", r.Failed())).Info("finished adoption")
+	return r
+}
+
+func (fs *Filesystem) adopt(ctx context.Context, to *pdu.FilesystemVersion,
+) error {
+	err := fs.receiver.Adopt(ctx, &pdu.AdoptReq{
+		Filesystem:        fs.Path,
+		To:                to,
+		ReplicationConfig: fs.policy.ReplicationConfig,
+	})
+	if err != nil {
+		return fmt.Errorf("receiver: %w", err)
+	}
+
+	err = fs.sender.SendCompleted(ctx, &pdu.SendCompletedReq{
+		OriginalReq: &pdu.SendReq{
+			Filesystem:        fs.Path,
+			To:                to,
+			ReplicationConfig: fs.policy.ReplicationConfig,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package logic
+
+import (
+	"slices"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+// bookmarksOnly returns true, if incremental replication protects sender
+// versions by bookmarks only.
+func (fs *Filesystem) bookmarksOnly() bool {
+	return fs.policy.ReplicationConfig.Protection.GetIncremental() ==
+		pdu.ReplicationGuaranteeKind_GuaranteeBookmarks
+}
+
+// preferBookmarkFrom replaces `from` of incremental path by sender bookmark
+// with the same GUID, if it exists. Replication from the bookmark doesn't
+// depend on the snapshot, so sender pruning can destroy it any time.
+func preferBookmarkFrom(path, sfsvs []*pdu.FilesystemVersion,
+) []*pdu.FilesystemVersion {
+	if len(path) < 2 || path[0] == nil ||
+		path[0].Type == pdu.FilesystemVersion_Bookmark {
+		return path
+	}
+
+	from := path[0]
+	i := slices.IndexFunc(sfsvs, func(v *pdu.FilesystemVersion) bool {
+		return v.Type == pdu.FilesystemVersion_Bookmark && v.Guid == from.Guid
+	})
+	if i < 0 {
+		return path
+	}
+
+	path = slices.Clone(path)
+	path[0] = sfsvs[i]
+	return path
+}
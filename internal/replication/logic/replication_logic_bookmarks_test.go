@@ -0,0 +1,38 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func TestPreferBookmarkFrom(t *testing.T) {
+	snap1 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Snapshot, Name: "zrepl_1", Guid: 1,
+	}
+	book1 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Bookmark, Name: "zrepl_1", Guid: 1,
+	}
+	snap2 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Snapshot, Name: "zrepl_2", Guid: 2,
+	}
+	snap3 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Snapshot, Name: "zrepl_3", Guid: 3,
+	}
+
+	path := []*pdu.FilesystemVersion{snap1, snap2, snap3}
+	got := preferBookmarkFrom(path, []*pdu.FilesystemVersion{
+		book1, snap1, snap2, snap3,
+	})
+	assert.Equal(t, []*pdu.FilesystemVersion{book1, snap2, snap3}, got)
+	assert.Same(t, snap1, path[0], "path must not be modified")
+
+	got = preferBookmarkFrom(path, path)
+	assert.Equal(t, path, got)
+
+	// initial replication has no `from`
+	initial := []*pdu.FilesystemVersion{nil, snap1}
+	assert.Equal(t, initial, preferBookmarkFrom(initial, path))
+}
@@ -0,0 +1,106 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+// resolveForeign resolves diverged conflict, caused by foreign receiver
+// snapshots, according to configured policy. It drops foreign snapshots from
+// receiver versions, destroying them on the receiver, if configured, and
+// returns new incremental path from the latest common snapshot and nil
+// conflict, if the conflict was resolved, or unchanged conflict otherwise. Nil
+// path with nil conflict means nothing to replicate.
+func (fs *Filesystem) resolveForeign(ctx context.Context, prefix string,
+	conflict error,
+) ([]*pdu.FilesystemVersion, error) {
+	action := fs.policy.ConflictResolution.ForeignSnapshots
+	if action == ForeignSnapshotsFail {
+		return nil, conflict
+	}
+
+	diverged, ok := errors.AsType[*ConflictDiverged](conflict)
+	if !ok {
+		return nil, conflict
+	} else if prefix == "" {
+		getLogger(ctx).With(slog.String("filesystem", fs.Path)).
+			Warn("can't recognize foreign snapshots without replication prefix")
+		return nil, conflict
+	}
+
+	foreign := foreignSnapshots(diverged.ReceiverOnly, prefix)
+	if len(foreign) == 0 {
+		return nil, conflict
+	}
+
+	log := getLogger(ctx).With(slog.String("filesystem", fs.Path),
+		slog.String("common", diverged.CommonAncestor.GetName()),
+		slog.Any("foreign", foreign))
+
+	switch action {
+	case ForeignSnapshotsIgnore:
+		// zfs recv accepts incremental stream from an older snapshot, if only
+		// snapshots were created after it.
+		log.Warn("receiver has foreign snapshots, ignore them")
+	case ForeignSnapshotsDestroy:
+		log.Warn("receiver has foreign snapshots, destroy them")
+		if err := fs.destroyReceiverSnapshots(ctx, foreign); err != nil {
+			return nil, err
+		}
+	}
+
+	rfsvs := slices.DeleteFunc(slices.Clone(diverged.SortedReceiverVersions),
+		func(v *pdu.FilesystemVersion) bool {
+			return slices.Contains(foreign, v.GetName())
+		})
+	return IncrementalPath(rfsvs, diverged.SortedSenderVersions)
+}
+
+// foreignSnapshots returns names of receiver only snapshots, if all of them
+// are foreign, i.e. don't have prefix of zrepl snapshots. It returns nil, if at
+// least one of them has the prefix, because it's a real conflict.
+func foreignSnapshots(receiverOnly []*pdu.FilesystemVersion, prefix string,
+) []string {
+	names := make([]string, 0, len(receiverOnly))
+	for _, v := range receiverOnly {
+		if v.GetType() != pdu.FilesystemVersion_Snapshot {
+			continue
+		} else if strings.HasPrefix(v.GetName(), prefix) {
+			return nil
+		}
+		names = append(names, v.GetName())
+	}
+	return names
+}
+
+func (fs *Filesystem) destroyReceiverSnapshots(ctx context.Context,
+	names []string,
+) error {
+	resp, err := fs.receiver.DestroySnapshots(ctx, &pdu.DestroySnapshotsReq{
+		Filesystems: []pdu.DestroySnapshots{
+			{Filesystem: fs.Path, Snapshots: names},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("destroy foreign snapshots: %w", err)
+	}
+
+	for _, destroyed := range resp.Filesystems {
+		if destroyed.Error != "" {
+			return fmt.Errorf("destroy foreign snapshots: %s", destroyed.Error)
+		}
+		for _, r := range destroyed.Results {
+			if r.Error != "" {
+				return fmt.Errorf("destroy foreign snapshot %q: %s", r.Name, r.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func TestForeignSnapshots(t *testing.T) {
+	snap := func(name string) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type: pdu.FilesystemVersion_Snapshot,
+			Name: name,
+		}
+	}
+	bookmark := func(name string) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type: pdu.FilesystemVersion_Bookmark,
+			Name: name,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		receiverOnly []*pdu.FilesystemVersion
+		want         []string
+	}{
+		{
+			name: "all foreign",
+			receiverOnly: []*pdu.FilesystemVersion{
+				snap("autosnap_1"), bookmark("zrepl_1"), snap("manual"),
+			},
+			want: []string{"autosnap_1", "manual"},
+		},
+		{
+			name: "with zrepl snapshot",
+			receiverOnly: []*pdu.FilesystemVersion{
+				snap("autosnap_1"), snap("zrepl_2"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := foreignSnapshots(tt.receiverOnly, "zrepl_")
+			if tt.want == nil {
+				assert.Nil(t, got)
+			} else {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilesystem_resolveForeign_interleaved(t *testing.T) {
+	snap := func(name string, guid uint64) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type:      pdu.FilesystemVersion_Snapshot,
+			Name:      name,
+			Guid:      guid,
+			CreateTXG: guid,
+		}
+	}
+
+	sender := []*pdu.FilesystemVersion{
+		snap("zrepl_1", 1), snap("zrepl_2", 3), snap("zrepl_3", 5),
+		snap("zrepl_4", 6),
+	}
+	// foreign snapshots are interleaved with zrepl snapshots, and adopted_1 is
+	// a foreign name of a sender snapshot.
+	receiver := []*pdu.FilesystemVersion{
+		snap("zrepl_1", 1), snap("autosnap_1", 2), snap("zrepl_2", 3),
+		snap("autosnap_2", 4), snap("adopted_1", 5), snap("autosnap_3", 7),
+	}
+
+	tests := []struct {
+		name     string
+		action   ForeignSnapshotsAction
+		prefix   string
+		wantPath []string
+	}{
+		{
+			name:     "ignore",
+			action:   ForeignSnapshotsIgnore,
+			prefix:   "zrepl_",
+			wantPath: []string{"zrepl_3", "zrepl_4"},
+		},
+		{
+			name:   "fail",
+			action: ForeignSnapshotsFail,
+			prefix: "zrepl_",
+		},
+		{
+			name:   "without prefix",
+			action: ForeignSnapshotsIgnore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, conflict := IncrementalPath(receiver, sender)
+			var diverged *ConflictDiverged
+			require.ErrorAs(t, conflict, &diverged)
+
+			fs := &Filesystem{policy: PlannerPolicy{
+				ConflictResolution: &ConflictResolution{ForeignSnapshots: tt.action},
+			}}
+			path, err := fs.resolveForeign(t.Context(), tt.prefix, conflict)
+			if tt.wantPath == nil {
+				require.ErrorIs(t, err, conflict)
+				assert.Nil(t, path)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, len(path))
+			for i, v := range path {
+				names[i] = v.GetName()
+			}
+			assert.Equal(t, tt.wantPath, names)
+		})
+	}
+}
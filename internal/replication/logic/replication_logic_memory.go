@@ -0,0 +1,130 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"unsafe"
+
+	"golang.org/x/sync/semaphore"
+
+	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+// sizeofVersion is the memory of one version without its strings and
+// properties, including the pointer to it in a version list.
+const sizeofVersion = int64(unsafe.Sizeof(pdu.FilesystemVersion{}) +
+	unsafe.Sizeof((*pdu.FilesystemVersion)(nil)))
+
+// sizeofStep is the memory of one planned step without its versions, which
+// are shared with version lists.
+const sizeofStep = int64(unsafe.Sizeof(Step{}) + unsafe.Sizeof((*Step)(nil)))
+
+// versionsMemory estimates memory of version list fsvs.
+func versionsMemory(fsvs []*pdu.FilesystemVersion) (n int64) {
+	for _, v := range fsvs {
+		n += sizeofVersion + int64(len(v.Name)+len(v.Creation))
+		for k, v := range v.Properties {
+			n += int64(len(k) + len(v))
+		}
+	}
+	return n
+}
+
+// newPlanningMemory returns PlanningMemory, which caps memory of version lists
+// of all filesystems planned in parallel by limit. Zero limit means no cap.
+func newPlanningMemory(limit int64) *PlanningMemory {
+	self := &PlanningMemory{limit: limit}
+	if limit > 0 {
+		self.sem = semaphore.NewWeighted(limit)
+	}
+	return self
+}
+
+// PlanningMemory caps estimated memory of the planner.
+type PlanningMemory struct {
+	limit int64
+	sem   *semaphore.Weighted
+}
+
+// Acquire waits until n bytes are available. n must not exceed the limit.
+func (self *PlanningMemory) Acquire(ctx context.Context, n int64) error {
+	if self.sem == nil {
+		return nil
+	} else if err := self.sem.Acquire(ctx, n); err != nil {
+		return fmt.Errorf("acquire %d bytes of planning memory: %w", n, err)
+	}
+	return nil
+}
+
+// Release returns n bytes acquired before.
+func (self *PlanningMemory) Release(n int64) {
+	if self.sem != nil {
+		self.sem.Release(n)
+	}
+}
+
+// Exceeded returns true, if n bytes will never fit into the limit.
+func (self *PlanningMemory) Exceeded(n int64) bool {
+	return self.limit > 0 && n > self.limit
+}
+
+// Limit returns the limit of memory. Zero means no limit.
+func (self *PlanningMemory) Limit() int64 { return self.limit }
+
+// fitVersions returns the most recent versions of sfsvs and rfsvs, which fit
+// into limit together. Every list gets a half of the limit.
+func fitVersions(sfsvs, rfsvs []*pdu.FilesystemVersion, limit int64,
+) ([]*pdu.FilesystemVersion, []*pdu.FilesystemVersion) {
+	if len(rfsvs) == 0 {
+		return fitVersionList(sfsvs, limit), rfsvs
+	}
+	return fitVersionList(sfsvs, limit/2), fitVersionList(rfsvs, limit/2)
+}
+
+func fitVersionList(fsvs []*pdu.FilesystemVersion, limit int64,
+) []*pdu.FilesystemVersion {
+	fsvs = SortVersionListByCreateTXGThenBookmarkLTSnapshot(fsvs)
+	var n int64
+	for i := len(fsvs) - 1; i >= 0; i-- {
+		n += versionsMemory(fsvs[i : i+1])
+		if n > limit {
+			return fsvs[i+1:]
+		}
+	}
+	return fsvs
+}
+
+// acquireMemory accounts memory of version lists sfsvs and rfsvs and returns
+// how much it acquired. If the lists can't fit into the limit, it truncates
+// them to the most recent versions, which fit.
+func (fs *Filesystem) acquireMemory(ctx context.Context, log *slog.Logger,
+	sfsvs, rfsvs []*pdu.FilesystemVersion,
+) ([]*pdu.FilesystemVersion, []*pdu.FilesystemVersion, int64, error) {
+	n := versionsMemory(sfsvs) + versionsMemory(rfsvs)
+	if fs.memory.Exceeded(n) {
+		limit := fs.memory.Limit()
+		log.With(
+			slog.Int("sender_versions", len(sfsvs)),
+			slog.Int("receiver_versions", len(rfsvs)),
+			slog.Int64("memory", n),
+			slog.Int64("limit", limit),
+		).Warn("planning memory exceeded, use only most recent versions for planning")
+		sfsvs, rfsvs = fitVersions(sfsvs, rfsvs, limit)
+		n = versionsMemory(sfsvs) + versionsMemory(rfsvs)
+	}
+
+	if err := fs.memory.Acquire(ctx, n); err != nil {
+		return nil, nil, 0, err
+	}
+	fs.planningMemory.Store(n)
+	return sfsvs, rfsvs, n, nil
+}
+
+// accountSteps adds memory of steps to the memory of fs, which is reported.
+// It doesn't acquire it from the limit, because steps use much less memory
+// than version lists.
+func (fs *Filesystem) accountSteps(steps []*Step) {
+	fs.planningMemory.Add(int64(len(steps)) * sizeofStep)
+}
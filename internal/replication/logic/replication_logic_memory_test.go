@@ -0,0 +1,85 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func testVersions(n int) []*pdu.FilesystemVersion {
+	fsvs := make([]*pdu.FilesystemVersion, n)
+	for i := range fsvs {
+		fsvs[i] = &pdu.FilesystemVersion{
+			Type:      pdu.FilesystemVersion_Snapshot,
+			Name:      fmt.Sprintf("zrepl_%04d", i),
+			CreateTXG: uint64(i + 1),
+		}
+	}
+	return fsvs
+}
+
+func TestVersionsMemory(t *testing.T) {
+	assert.Zero(t, versionsMemory(nil))
+
+	v := &pdu.FilesystemVersion{
+		Name:       "foo",
+		Creation:   "bar",
+		Properties: map[string]string{"a": "bc"},
+	}
+	assert.Equal(t, sizeofVersion+9,
+		versionsMemory([]*pdu.FilesystemVersion{v}))
+}
+
+func TestFitVersions(t *testing.T) {
+	sfsvs, rfsvs := testVersions(100), testVersions(50)
+	limit := versionsMemory(sfsvs[:20]) + versionsMemory(rfsvs[:20])
+
+	s, r := fitVersions(sfsvs, rfsvs, limit)
+	assert.Equal(t, sfsvs[80:], s)
+	assert.Equal(t, rfsvs[30:], r)
+	assert.LessOrEqual(t, versionsMemory(s)+versionsMemory(r), limit)
+
+	s, r = fitVersions(sfsvs, nil, limit)
+	assert.Equal(t, sfsvs[60:], s)
+	assert.Empty(t, r)
+
+	s, _ = fitVersions(sfsvs, nil, versionsMemory(sfsvs))
+	assert.Equal(t, sfsvs, s)
+}
+
+func TestFilesystem_acquireMemory(t *testing.T) {
+	sfsvs, rfsvs := testVersions(100), testVersions(100)
+	limit := versionsMemory(sfsvs)
+	fs := &Filesystem{memory: newPlanningMemory(limit)}
+
+	s, r, n, err := fs.acquireMemory(t.Context(), slog.Default(), sfsvs, rfsvs)
+	require.NoError(t, err)
+	assert.Len(t, s, 50)
+	assert.Len(t, r, 50)
+	assert.Equal(t, versionsMemory(s)+versionsMemory(r), n)
+	assert.Equal(t, n, fs.ReportInfo().PlanningMemory)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, _, _, err = fs.acquireMemory(ctx, slog.Default(), sfsvs[:1], nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	fs.memory.Release(n)
+	_, _, n, err = fs.acquireMemory(t.Context(), slog.Default(), sfsvs[:1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, versionsMemory(sfsvs[:1]), n)
+}
+
+func TestPlanningMemory_noLimit(t *testing.T) {
+	m := newPlanningMemory(0)
+	assert.False(t, m.Exceeded(1<<40))
+	require.NoError(t, m.Acquire(t.Context(), 1<<40))
+	m.Release(1 << 40)
+}
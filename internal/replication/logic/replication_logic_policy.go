@@ -38,6 +38,38 @@ func InitialReplicationAutoResolutionFromConfig(in string) (InitialReplicationAu
 
 type ConflictResolution struct {
 	InitialReplication InitialReplicationAutoResolution
+	ForeignSnapshots   ForeignSnapshotsAction
+
+	// AbortInvalidResumeToken allows to abort resumable state of the receiver,
+	// if its resume token can't be used anymore, instead of failing
+	// replication.
+	AbortInvalidResumeToken bool
+}
+
+// ForeignSnapshotsAction defines what to do, if the receiver has snapshots,
+// newer than the latest common snapshot, which don't exist on sender and
+// weren't created by zrepl.
+type ForeignSnapshotsAction int
+
+const (
+	ForeignSnapshotsFail ForeignSnapshotsAction = iota
+	ForeignSnapshotsIgnore
+	ForeignSnapshotsDestroy
+)
+
+func ForeignSnapshotsActionFromConfig(in string) (ForeignSnapshotsAction,
+	error,
+) {
+	switch in {
+	case "", "fail":
+		return ForeignSnapshotsFail, nil
+	case "ignore":
+		return ForeignSnapshotsIgnore, nil
+	case "destroy":
+		return ForeignSnapshotsDestroy, nil
+	}
+	return ForeignSnapshotsFail, fmt.Errorf("%q is not in {fail,ignore,destroy}",
+		in)
 }
 
 func (c *ConflictResolution) Validate() error {
@@ -53,14 +85,28 @@ func ConflictResolutionFromConfig(in *config.ConflictResolution) (*ConflictResol
 		return nil, fmt.Errorf("field `initial_replication` is invalid: %q is not one of %v", in.InitialReplication, InitialReplicationAutoResolutionValues())
 	}
 
+	foreignSnapshots, err := ForeignSnapshotsActionFromConfig(
+		in.ForeignSnapshots)
+	if err != nil {
+		return nil, fmt.Errorf("field `foreign_snapshots` is invalid: %w", err)
+	}
+
 	return &ConflictResolution{
 		InitialReplication: initialReplication,
+		ForeignSnapshots:   foreignSnapshots,
+
+		AbortInvalidResumeToken: in.InvalidResumeToken == "abort",
 	}, nil
 }
 
 type PlannerPolicy struct {
 	ConflictResolution *ConflictResolution    `validate:"required"`
 	ReplicationConfig  *pdu.ReplicationConfig `validate:"required"`
+	VersionsLimit      VersionsLimit
+	Intermediates      Intermediates
+	// PlanningMemory caps estimated memory of version lists of filesystems
+	// planned in parallel. Zero means no cap.
+	PlanningMemory int64 `validate:"gte=0"`
 }
 
 func (self *PlannerPolicy) Validate() error {
@@ -103,7 +149,98 @@ func pduReplicationGuaranteeKindFromConfig(in string) (k pdu.ReplicationGuarante
 		return pdu.ReplicationGuaranteeKind_GuaranteeIncrementalReplication, nil
 	case "guarantee_resumability":
 		return pdu.ReplicationGuaranteeKind_GuaranteeResumability, nil
+	case "guarantee_bookmarks":
+		return pdu.ReplicationGuaranteeKind_GuaranteeBookmarks, nil
+	default:
+		return k, fmt.Errorf("%q is not in guarantee_{nothing,incremental,resumability,bookmarks}", in)
+	}
+}
+
+// Intermediates selects how the planner replicates intermediate snapshots
+// between the latest common and the latest snapshot.
+type Intermediates int
+
+const (
+	// IntermediatesConsolidated replicates all intermediate snapshots in one
+	// step, using zfs send -I.
+	IntermediatesConsolidated Intermediates = iota
+	// IntermediatesStepwise replicates every intermediate snapshot in its own
+	// step, using zfs send -i.
+	IntermediatesStepwise
+)
+
+func IntermediatesFromConfig(in string) (Intermediates, error) {
+	switch in {
+	case "consolidated":
+		return IntermediatesConsolidated, nil
+	case "stepwise":
+		return IntermediatesStepwise, nil
 	default:
-		return k, fmt.Errorf("%q is not in guarantee_{nothing,incremental,resumability}", in)
+		return 0, fmt.Errorf("%q is not in {consolidated,stepwise}", in)
+	}
+}
+
+type VersionsLimitAction int
+
+const (
+	VersionsLimitWarn VersionsLimitAction = iota
+	VersionsLimitSkip
+	VersionsLimitTruncate
+)
+
+// VersionsLimit limits the number of filesystem versions the planner works
+// with. Zero Max means no limit.
+type VersionsLimit struct {
+	Max    int
+	Action VersionsLimitAction
+	Keep   int
+}
+
+func VersionsLimitFromConfig(in *config.ReplicationOptionsVersionsLimit,
+) (l VersionsLimit, _ error) {
+	l.Max, l.Keep = int(in.Max), int(in.Keep)
+	switch in.Action {
+	case "warn":
+		l.Action = VersionsLimitWarn
+	case "skip":
+		l.Action = VersionsLimitSkip
+	case "truncate":
+		l.Action = VersionsLimitTruncate
+		if l.Max > 0 && l.Keep > l.Max {
+			return l, fmt.Errorf("field 'keep' must not exceed 'max': %d > %d",
+				l.Keep, l.Max)
+		}
+	default:
+		return l, fmt.Errorf("%q is not in {warn,skip,truncate}", in.Action)
+	}
+	return l, nil
+}
+
+func (self *VersionsLimit) Exceeded(n int) bool {
+	return self.Max > 0 && n > self.Max
+}
+
+// Truncate returns only the Keep most recent versions of fsvs and older
+// versions with the same GUID as base, if it isn't nil, so the common base of
+// sender and receiver is never truncated and incremental replication continues
+// from it. fsvs must be sorted by createtxg.
+func (self *VersionsLimit) Truncate(fsvs []*pdu.FilesystemVersion,
+	base *pdu.FilesystemVersion,
+) []*pdu.FilesystemVersion {
+	if len(fsvs) <= self.Keep {
+		return fsvs
+	}
+
+	n := len(fsvs) - self.Keep
+	if base == nil {
+		return fsvs[n:]
+	}
+
+	truncated := make([]*pdu.FilesystemVersion, 0, self.Keep+2)
+	for _, v := range fsvs[:n] {
+		if v.GetGuid() == base.GetGuid() {
+			truncated = append(truncated, v)
+		}
 	}
+	return append(truncated, fsvs[n:]...)
 }
@@ -0,0 +1,140 @@
+package logic
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+)
+
+func TestFilesystem_limitVersions(t *testing.T) {
+	versions := func(n int) []*pdu.FilesystemVersion {
+		fsvs := make([]*pdu.FilesystemVersion, n)
+		for i := range fsvs {
+			fsvs[i] = &pdu.FilesystemVersion{
+				Type:      pdu.FilesystemVersion_Snapshot,
+				Guid:      uint64(i + 1),
+				CreateTXG: uint64(i + 1),
+			}
+		}
+		return fsvs
+	}
+
+	tests := []struct {
+		name    string
+		limit   VersionsLimit
+		sender  int
+		recv    int
+		wantS   int
+		wantR   int
+		wantErr bool
+	}{
+		{
+			name:   "no limit",
+			sender: 100, recv: 100,
+			wantS: 100, wantR: 100,
+		},
+		{
+			name:   "not exceeded",
+			limit:  VersionsLimit{Max: 10, Action: VersionsLimitSkip},
+			sender: 10, recv: 5,
+			wantS: 10, wantR: 5,
+		},
+		{
+			name:   "warn",
+			limit:  VersionsLimit{Max: 10, Action: VersionsLimitWarn},
+			sender: 11, recv: 5,
+			wantS: 11, wantR: 5,
+		},
+		{
+			name:   "skip",
+			limit:  VersionsLimit{Max: 10, Action: VersionsLimitSkip},
+			sender: 5, recv: 11,
+			wantErr: true,
+		},
+		{
+			name:   "truncate",
+			limit:  VersionsLimit{Max: 10, Action: VersionsLimitTruncate, Keep: 3},
+			sender: 11, recv: 2,
+			wantS: 4, wantR: 2,
+		},
+		{
+			name:   "truncate without common base",
+			limit:  VersionsLimit{Max: 10, Action: VersionsLimitTruncate, Keep: 3},
+			sender: 11, recv: 0,
+			wantS: 3, wantR: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &Filesystem{policy: PlannerPolicy{VersionsLimit: tt.limit}}
+			sfsvs, rfsvs, err := fs.limitVersions(slog.Default(),
+				versions(tt.sender), versions(tt.recv))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, sfsvs, tt.wantS)
+			assert.Len(t, rfsvs, tt.wantR)
+			if tt.limit.Action == VersionsLimitTruncate {
+				assert.Equal(t, uint64(tt.sender), sfsvs[len(sfsvs)-1].CreateTXG)
+			}
+		})
+	}
+}
+
+func TestFilesystem_limitVersions_flood(t *testing.T) {
+	snapshot := func(guid uint64) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type:      pdu.FilesystemVersion_Snapshot,
+			Name:      fmt.Sprintf("snap%d", guid),
+			Guid:      guid,
+			CreateTXG: guid,
+		}
+	}
+
+	// a foreign tool flooded the sender with snapshots after the last
+	// replicated one, which is the common base now.
+	const base, flood = 5, 10_000
+	var sfsvs, rfsvs []*pdu.FilesystemVersion
+	for guid := uint64(1); guid <= base+flood; guid++ {
+		sfsvs = append(sfsvs, snapshot(guid))
+		if guid <= base {
+			rfsvs = append(rfsvs, snapshot(guid))
+		}
+	}
+	sfsvs = append(sfsvs, &pdu.FilesystemVersion{
+		Type:      pdu.FilesystemVersion_Bookmark,
+		Name:      "snap5",
+		Guid:      base,
+		CreateTXG: base,
+	})
+
+	fs := &Filesystem{policy: PlannerPolicy{VersionsLimit: VersionsLimit{
+		Max: 100, Action: VersionsLimitTruncate, Keep: 3,
+	}}}
+	sfsvs, rfsvs, err := fs.limitVersions(slog.Default(), sfsvs, rfsvs)
+	require.NoError(t, err)
+
+	guids := func(fsvs []*pdu.FilesystemVersion) (r []uint64) {
+		for _, v := range fsvs {
+			r = append(r, v.Guid)
+		}
+		return r
+	}
+	assert.Equal(t, []uint64{base, base, base + flood - 2, base + flood - 1,
+		base + flood}, guids(sfsvs))
+	assert.Equal(t, []uint64{base - 2, base - 1, base}, guids(rfsvs))
+
+	path, conflict := IncrementalPath(rfsvs, sfsvs)
+	require.NoError(t, conflict)
+	assert.Equal(t, []uint64{base, base + flood - 2, base + flood - 1,
+		base + flood}, guids(path))
+}
@@ -0,0 +1,100 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+// errInvalidResumeToken marks resume tokens, which can't be used anymore,
+// because they are corrupt or reference versions, which don't exist on sender.
+var errInvalidResumeToken = errors.New("invalid resume token")
+
+// resumeStep returns the step, encoded in the resume token of the receiver, or
+// nil if the receiver has no resume token. If the resume token is invalid and
+// configured policy allows it, it returns nil step too, so the planner starts
+// over and the receiver aborts its resumable state before receiving.
+func (fs *Filesystem) resumeStep(ctx context.Context, log *slog.Logger,
+	sfsvs []*pdu.FilesystemVersion,
+) (*Step, error) {
+	if fs.receiverFS == nil || fs.receiverFS.ResumeToken == "" {
+		return nil, nil
+	}
+
+	tokenRaw := fs.receiverFS.ResumeToken
+	log.With(slog.String("receiverFS.ResumeToken", tokenRaw)).
+		Debug("decode receiver fs resume token")
+	step, err := fs.decodeResumeStep(ctx, log, tokenRaw, sfsvs)
+	if err == nil {
+		return step, nil
+	} else if !errors.Is(err, errInvalidResumeToken) ||
+		!fs.policy.ConflictResolution.AbortInvalidResumeToken {
+		logger.WithError(log, err, "cannot use resume token, aborting")
+		return nil, err
+	}
+
+	logger.WithError(log, err, "").Warn(
+		"abort resumable state of receiver and start over")
+	return nil, nil
+}
+
+func (fs *Filesystem) decodeResumeStep(ctx context.Context, log *slog.Logger,
+	tokenRaw string, sfsvs []*pdu.FilesystemVersion,
+) (*Step, error) {
+	token, err := zfs.ParseResumeToken(ctx, tokenRaw)
+	if err != nil {
+		if errors.Is(err, zfs.ResumeTokenCorruptError) {
+			return nil, fmt.Errorf("%w: %w", errInvalidResumeToken, err)
+		}
+		return nil, fmt.Errorf("cannot decode resume token: %w", err)
+	}
+	log.With(slog.Any("token", token)).Debug("decode resume token")
+
+	from, to, err := resumeVersions(token, sfsvs)
+	if err != nil {
+		return nil, err
+	}
+
+	// `from` may be nil, `to` is no nil, encryption matches good to go this one
+	// step!
+	step := NewStep(fs, from, to)
+	step.resumeToken = tokenRaw
+	return step, nil
+}
+
+// resumeVersions returns versions of sender, encoded in token. `from` is nil
+// for full sends, `to` is never nil.
+func resumeVersions(token *zfs.ResumeToken, sfsvs []*pdu.FilesystemVersion,
+) (from, to *pdu.FilesystemVersion, err error) {
+	for s := range slices.Values(sfsvs) {
+		if token.HasFromGUID && s.Guid == token.FromGUID {
+			if from != nil && from.Type == pdu.FilesystemVersion_Snapshot {
+				// prefer snapshots over bookmarks for size estimation
+			} else {
+				from = s
+			}
+		}
+		if token.HasToGUID && s.Guid == token.ToGUID &&
+			s.Type == pdu.FilesystemVersion_Snapshot {
+			// `to` must always be a snapshot
+			to = s
+		}
+	}
+
+	switch {
+	case to == nil:
+		return nil, nil, fmt.Errorf(
+			"%w: `toguid` = %v not found on sender (`toname` = %q)",
+			errInvalidResumeToken, token.ToGUID, token.ToName)
+	case from == to:
+		return nil, nil, errors.New(
+			"resume token `fromguid` and `toguid` match same version on sender")
+	}
+	return from, to, nil
+}
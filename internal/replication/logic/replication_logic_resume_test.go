@@ -0,0 +1,50 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/zfs"
+)
+
+func TestResumeVersions(t *testing.T) {
+	snap1 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Snapshot, Name: "zrepl_1", Guid: 1,
+	}
+	book1 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Bookmark, Name: "zrepl_1", Guid: 1,
+	}
+	snap2 := &pdu.FilesystemVersion{
+		Type: pdu.FilesystemVersion_Snapshot, Name: "zrepl_2", Guid: 2,
+	}
+
+	from, to, err := resumeVersions(&zfs.ResumeToken{
+		HasFromGUID: true, FromGUID: 1, HasToGUID: true, ToGUID: 2,
+	}, []*pdu.FilesystemVersion{book1, snap1, snap2})
+	require.NoError(t, err)
+	assert.Same(t, snap1, from)
+	assert.Same(t, snap2, to)
+
+	from, to, err = resumeVersions(&zfs.ResumeToken{
+		HasToGUID: true, ToGUID: 2,
+	}, []*pdu.FilesystemVersion{snap1, snap2})
+	require.NoError(t, err)
+	assert.Nil(t, from)
+	assert.Same(t, snap2, to)
+
+	_, _, err = resumeVersions(&zfs.ResumeToken{
+		HasFromGUID: true, FromGUID: 1, HasToGUID: true, ToGUID: 2,
+		ToName: "zroot/foo@zrepl_2",
+	}, []*pdu.FilesystemVersion{snap1})
+	require.ErrorIs(t, err, errInvalidResumeToken)
+	assert.ErrorContains(t, err, "zroot/foo@zrepl_2")
+
+	_, _, err = resumeVersions(&zfs.ResumeToken{
+		HasFromGUID: true, FromGUID: 2, HasToGUID: true, ToGUID: 2,
+	}, []*pdu.FilesystemVersion{snap1, snap2})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errInvalidResumeToken)
+}
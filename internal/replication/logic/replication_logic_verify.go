@@ -0,0 +1,159 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/logger"
+	. "github.com/dsh2dsh/zrepl/internal/replication/logic/diff"
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+// Verify compares snapshots of every sender filesystem with snapshots of the
+// receiver. Snapshots with the same name must have the same GUID on both sides
+// and must be in the same order. Every filesystem must have at least one
+// common snapshot.
+func (p *Planner) Verify(ctx context.Context) *report.VerifyReport {
+	log := getLogger(ctx)
+	log.Info("start verification")
+
+	r := &report.VerifyReport{StartAt: time.Now()}
+	defer func() { r.FinishAt = time.Now() }()
+
+	fss, err := p.verifyFilesystems(ctx)
+	if err != nil {
+		logger.WithError(log, err, "error listing filesystems")
+		r.Err = err.Error()
+		return r
+	}
+
+	r.Filesystems = make([]*report.VerifyFilesystemReport, 0, len(fss))
+	for _, fs := range fss {
+		if ctx.Err() != nil {
+			r.Err = context.Cause(ctx).Error()
+			break
+		}
+		fsr := fs.verify(ctx)
+		r.Filesystems = append(r.Filesystems, fsr)
+		if fsr.Failed() {
+			log.With(slog.String("filesystem", fsr.Name),
+				slog.Any("errors", fsr.Errors)).Error("verification failed")
+		}
+	}
+
+	log.With(slog.Int("filesystems", len(r.Filesystems)),
+		slog.Int("failed", r.Failed())).Info("finished verification")
+	return r
+}
+
+func (p *Planner) verifyFilesystems(ctx context.Context) ([]*Filesystem,
+	error,
+) {
+	src, err := p.sender.ListFilesystems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sender: %w", err)
+	}
+
+	dst, err := p.receiver.ListFilesystems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("receiver: %w", err)
+	}
+
+	fss := make([]*Filesystem, 0, len(src.Filesystems))
+	for _, senderFS := range src.Filesystems {
+		if senderFS.IsPlaceholder {
+			continue
+		}
+		fs := &Filesystem{
+			sender:   p.sender,
+			receiver: p.receiver,
+			policy:   p.policy,
+			Path:     senderFS.Path,
+			senderFS: senderFS,
+		}
+		for _, receiverFS := range dst.Filesystems {
+			if receiverFS.Path == senderFS.Path {
+				fs.receiverFS = receiverFS
+				break
+			}
+		}
+		fss = append(fss, fs)
+	}
+	return fss, nil
+}
+
+func (fs *Filesystem) verify(ctx context.Context) *report.VerifyFilesystemReport {
+	r, _ := fs.verifyLatest(ctx)
+	return r
+}
+
+// verifyLatest verifies fs and returns its latest common snapshot, as seen on
+// sender side.
+func (fs *Filesystem) verifyLatest(ctx context.Context) (
+	*report.VerifyFilesystemReport, *pdu.FilesystemVersion,
+) {
+	r := &report.VerifyFilesystemReport{Name: fs.Path}
+	if !fs.needReceiverVersions() {
+		r.Fail("filesystem doesn't exist on receiver")
+		return r, nil
+	}
+
+	resps, err := fs.listBothVersions(ctx)
+	if err != nil {
+		r.Fail(err.Error())
+		return r, nil
+	}
+	latest := verifyVersions(resps[0].GetVersions(), resps[1].GetVersions(), r)
+	return r, latest
+}
+
+func verifyVersions(sfsvs, rfsvs []*pdu.FilesystemVersion,
+	r *report.VerifyFilesystemReport,
+) *pdu.FilesystemVersion {
+	senderSnaps := make(map[string]*pdu.FilesystemVersion, len(sfsvs))
+	for _, v := range sfsvs {
+		if v.GetType() == pdu.FilesystemVersion_Snapshot {
+			senderSnaps[v.GetName()] = v
+		}
+	}
+
+	var latest *pdu.FilesystemVersion
+	var received int
+	for _, rv := range SortVersionListByCreateTXGThenBookmarkLTSnapshot(rfsvs) {
+		if rv.GetType() != pdu.FilesystemVersion_Snapshot {
+			continue
+		}
+		received++
+		sv, ok := senderSnaps[rv.GetName()]
+		if !ok {
+			continue
+		} else if sv.GetGuid() != rv.GetGuid() {
+			r.Fail(fmt.Sprintf("snapshot %q: GUID mismatch: sender %d, receiver %d",
+				rv.GetName(), sv.GetGuid(), rv.GetGuid()))
+			continue
+		} else if latest != nil && sv.GetCreateTXG() <= latest.GetCreateTXG() {
+			r.Fail(fmt.Sprintf("snapshot %q: order differs from sender",
+				rv.GetName()))
+		}
+		latest = sv
+		r.Common++
+	}
+
+	switch {
+	case received == 0:
+		r.Fail("receiver doesn't have any snapshots")
+	case latest == nil:
+		r.Fail("no common snapshot")
+	default:
+		r.LatestCommon = latest.GetName()
+		for _, sv := range senderSnaps {
+			if sv.GetCreateTXG() > latest.GetCreateTXG() {
+				r.Behind++
+			}
+		}
+	}
+	return latest
+}
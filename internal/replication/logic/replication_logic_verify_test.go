@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
+	"github.com/dsh2dsh/zrepl/internal/replication/report"
+)
+
+func TestVerifyVersions(t *testing.T) {
+	snap := func(name string, guid, txg uint64) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type:      pdu.FilesystemVersion_Snapshot,
+			Name:      name,
+			Guid:      guid,
+			CreateTXG: txg,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		sender     []*pdu.FilesystemVersion
+		receiver   []*pdu.FilesystemVersion
+		wantCommon int
+		wantLatest string
+		wantBehind int
+		wantErrors int
+	}{
+		{
+			name:       "in sync",
+			sender:     []*pdu.FilesystemVersion{snap("a", 1, 10), snap("b", 2, 20)},
+			receiver:   []*pdu.FilesystemVersion{snap("a", 1, 5), snap("b", 2, 6)},
+			wantCommon: 2,
+			wantLatest: "b",
+		},
+		{
+			name: "behind and pruned",
+			sender: []*pdu.FilesystemVersion{
+				snap("b", 2, 20), snap("c", 3, 30), snap("d", 4, 40),
+			},
+			receiver:   []*pdu.FilesystemVersion{snap("a", 1, 5), snap("b", 2, 6)},
+			wantCommon: 1,
+			wantLatest: "b",
+			wantBehind: 2,
+		},
+		{
+			name:       "guid mismatch",
+			sender:     []*pdu.FilesystemVersion{snap("a", 1, 10), snap("b", 2, 20)},
+			receiver:   []*pdu.FilesystemVersion{snap("a", 1, 5), snap("b", 3, 6)},
+			wantCommon: 1,
+			wantLatest: "a",
+			wantBehind: 1,
+			wantErrors: 1,
+		},
+		{
+			name:       "order differs",
+			sender:     []*pdu.FilesystemVersion{snap("a", 1, 20), snap("b", 2, 10)},
+			receiver:   []*pdu.FilesystemVersion{snap("a", 1, 5), snap("b", 2, 6)},
+			wantCommon: 2,
+			wantLatest: "b",
+			wantBehind: 1,
+			wantErrors: 1,
+		},
+		{
+			name:       "no common",
+			sender:     []*pdu.FilesystemVersion{snap("b", 2, 20)},
+			receiver:   []*pdu.FilesystemVersion{snap("a", 1, 5)},
+			wantErrors: 1,
+		},
+		{
+			name:       "empty receiver",
+			sender:     []*pdu.FilesystemVersion{snap("a", 1, 10)},
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &report.VerifyFilesystemReport{}
+			latest := verifyVersions(tt.sender, tt.receiver, r)
+			assert.Equal(t, tt.wantLatest, latest.GetName())
+			assert.Equal(t, tt.wantCommon, r.Common)
+			assert.Equal(t, tt.wantLatest, r.LatestCommon)
+			assert.Equal(t, tt.wantBehind, r.Behind)
+			assert.Len(t, r.Errors, tt.wantErrors)
+		})
+	}
+}
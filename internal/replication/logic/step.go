@@ -14,6 +14,7 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/replication/driver"
 	"github.com/dsh2dsh/zrepl/internal/replication/logic/pdu"
 	"github.com/dsh2dsh/zrepl/internal/replication/report"
+	"github.com/dsh2dsh/zrepl/internal/util/bandwidth"
 	"github.com/dsh2dsh/zrepl/internal/util/bytecounter"
 	"github.com/dsh2dsh/zrepl/internal/util/chainlock"
 )
@@ -30,6 +31,12 @@ type Step struct {
 	// => concurrent read of that pointer from Step.ReportInfo must be protected
 	byteCounter    *bytecounter.ReadCloser
 	byteCounterMtx chainlock.L
+	// begin and elapsed are set by Step.doReplication and protected by
+	// byteCounterMtx too.
+	begin   time.Time
+	elapsed time.Duration
+	// throttle accounts time the step waited for bandwidth limit.
+	throttle bandwidth.Stats
 }
 
 func NewStep(fs *Filesystem, from, to *pdu.FilesystemVersion) *Step {
@@ -65,6 +72,10 @@ func (self *Step) ReportInfo() *report.StepInfo {
 	if self.byteCounter != nil {
 		byteCounter = self.byteCounter.Count()
 	}
+	elapsed := self.elapsed
+	if elapsed == 0 && !self.begin.IsZero() {
+		elapsed = time.Since(self.begin)
+	}
 	self.byteCounterMtx.Unlock()
 
 	from := ""
@@ -77,6 +88,8 @@ func (self *Step) ReportInfo() *report.StepInfo {
 		Resumed:         self.resumeToken != "",
 		BytesExpected:   self.expectedSize,
 		BytesReplicated: byteCounter,
+		Elapsed:         elapsed,
+		Throttled:       self.throttle.Throttled(),
 	}
 }
 
@@ -99,13 +112,20 @@ func (self *Step) buildSendRequest() pdu.SendReq {
 
 func (self *Step) doReplication(ctx context.Context) error {
 	sr := self.buildSendRequest()
-	if err := self.sendRecv(ctx, &sr); err != nil {
+	self.byteCounterMtx.Lock()
+	self.begin = time.Now()
+	self.byteCounterMtx.Unlock()
+
+	err := self.sendRecv(bandwidth.WithStats(ctx, &self.throttle), &sr)
+	elapsed := self.finished()
+	if err != nil {
 		return err
 	}
+	self.parent.stepMetrics.observe(self.bytesReplicated(), elapsed)
 
 	log := getLogger(ctx).With(slog.String("filesystem", self.parent.Path))
 	log.Debug("tell sender replication completed")
-	err := self.Sender().SendCompleted(ctx,
+	err = self.Sender().SendCompleted(ctx,
 		&pdu.SendCompletedReq{OriginalReq: &sr})
 	if err != nil {
 		logger.WithError(log, err,
@@ -136,7 +156,8 @@ func (self *Step) sendRecv(ctx context.Context, sr *pdu.SendReq) error {
 	defer jsonclient.BodyClose(stream)
 
 	// Install a byte counter to track progress + for status report
-	byteCountingStream := bytecounter.NewReadCloser(stream)
+	byteCountingStream := bytecounter.NewReadCloser(
+		bandwidth.LimiterFrom(ctx).Reader(ctx, stream))
 	self.WithByteCounter(byteCountingStream)
 	defer func() {
 		defer self.byteCounterMtx.Lock().Unlock()
@@ -150,6 +171,10 @@ func (self *Step) sendRecv(ctx context.Context, sr *pdu.SendReq) error {
 		To:                sr.GetTo(),
 		ClearResumeToken:  !sres.UsedResumeToken,
 		ReplicationConfig: self.parent.policy.ReplicationConfig,
+		StorageClass:      self.parent.senderFS.StorageClass,
+		ExpectedSize:      self.expectedSize,
+		Encrypted:         sres.Encrypted,
+		Holds:             sres.Holds,
 	}
 
 	log.Debug("initiate receive request")
@@ -169,6 +194,21 @@ func (self *Step) sendRecv(ctx context.Context, sr *pdu.SendReq) error {
 	return nil
 }
 
+// finished records and returns elapsed duration of the step.
+func (self *Step) finished() time.Duration {
+	defer self.byteCounterMtx.Lock().Unlock()
+	self.elapsed = time.Since(self.begin)
+	return self.elapsed
+}
+
+func (self *Step) bytesReplicated() uint64 {
+	defer self.byteCounterMtx.Lock().Unlock()
+	if self.byteCounter == nil {
+		return 0
+	}
+	return self.byteCounter.Count()
+}
+
 func (self *Step) WithByteCounter(r *bytecounter.ReadCloser) *Step {
 	self.byteCounterMtx.Lock()
 	self.byteCounter = r
@@ -188,8 +228,9 @@ func (self *Step) String() string {
 }
 
 func makeSteps(fs *Filesystem, prefix string, resume *Step,
-	snaps []*pdu.FilesystemVersion,
+	snaps []*pdu.FilesystemVersion, intermediates Intermediates,
 ) []*Step {
+	stepwise := intermediates == IntermediatesStepwise
 	steps := make([]*Step, 0, 2)
 	if resume != nil {
 		steps = append(steps, resume)
@@ -216,6 +257,9 @@ func makeSteps(fs *Filesystem, prefix string, resume *Step,
 		case last.from.Type == pdu.FilesystemVersion_Bookmark:
 			// first step after bookmark
 			// s.from == last.to and has prefix. s.to has unknown prefix.
+			fallthrough
+		case stepwise: // send -i s.to
+			// every snapshot in its own step
 			steps, last = append(steps, s), s
 			// for next steps last.to has unknown prefix
 		case prefix == "": // send -I latest snapshot
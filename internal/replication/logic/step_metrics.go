@@ -0,0 +1,30 @@
+package logic
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StepMetrics observes every successfully replicated step. Any of its
+// observers may be nil.
+type StepMetrics struct {
+	Bytes      prometheus.Observer // bytes replicated by the step
+	Duration   prometheus.Observer // seconds spent by the step
+	Throughput prometheus.Observer // bytes per second
+}
+
+func (self *StepMetrics) observe(bytes uint64, d time.Duration) {
+	if self == nil {
+		return
+	}
+	if self.Bytes != nil {
+		self.Bytes.Observe(float64(bytes))
+	}
+	if self.Duration != nil {
+		self.Duration.Observe(d.Seconds())
+	}
+	if self.Throughput != nil && d > 0 {
+		self.Throughput.Observe(float64(bytes) / d.Seconds())
+	}
+}
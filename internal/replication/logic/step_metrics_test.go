@@ -0,0 +1,31 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObserver []float64
+
+func (self *testObserver) Observe(v float64) { *self = append(*self, v) }
+
+func TestStepMetrics_observe(t *testing.T) {
+	var bytes, secs, throughput testObserver
+	m := &StepMetrics{Bytes: &bytes, Duration: &secs, Throughput: &throughput}
+
+	m.observe(1000, 2*time.Second)
+	assert.Equal(t, testObserver{1000}, bytes)
+	assert.Equal(t, testObserver{2}, secs)
+	assert.Equal(t, testObserver{500}, throughput)
+
+	m.observe(1000, 0)
+	assert.Equal(t, testObserver{1000, 1000}, bytes)
+	assert.Equal(t, testObserver{2, 0}, secs)
+	assert.Equal(t, testObserver{500}, throughput, "no throughput without duration")
+
+	var nilMetrics *StepMetrics
+	assert.NotPanics(t, func() { nilMetrics.observe(1000, time.Second) })
+	assert.NotPanics(t, func() { (&StepMetrics{}).observe(1000, time.Second) })
+}
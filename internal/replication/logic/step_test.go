@@ -751,7 +751,56 @@ func Test_makeSteps(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := makeSteps(nil, tt.prefix, tt.resume, tt.snaps)
+			got := makeSteps(nil, tt.prefix, tt.resume, tt.snaps,
+				IntermediatesConsolidated)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_makeSteps_stepwise(t *testing.T) {
+	snap := func(name string) *pdu.FilesystemVersion {
+		return &pdu.FilesystemVersion{
+			Type: pdu.FilesystemVersion_Snapshot,
+			Name: name,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		snaps  []*pdu.FilesystemVersion
+		want   []*Step
+	}{
+		{
+			name: "without prefix",
+			snaps: []*pdu.FilesystemVersion{
+				snap("zrepl_1"), snap("zrepl_2"), snap("zrepl_3"), snap("zrepl_4"),
+			},
+			want: []*Step{
+				{from: snap("zrepl_1"), to: snap("zrepl_2")},
+				{from: snap("zrepl_2"), to: snap("zrepl_3")},
+				{from: snap("zrepl_3"), to: snap("zrepl_4")},
+			},
+		},
+		{
+			name:   "with prefix skips aliens",
+			prefix: "zrepl_",
+			snaps: []*pdu.FilesystemVersion{
+				snap("zrepl_1"), snap("zrepl_2"), snap("foo"), snap("zrepl_3"),
+				snap("zrepl_4"),
+			},
+			want: []*Step{
+				{from: snap("zrepl_1"), to: snap("zrepl_2")},
+				{from: snap("zrepl_2"), to: snap("zrepl_3")},
+				{from: snap("zrepl_3"), to: snap("zrepl_4")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := makeSteps(nil, tt.prefix, nil, tt.snaps, IntermediatesStepwise)
 			assert.Equal(t, tt.want, got)
 		})
 	}
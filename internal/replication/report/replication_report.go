@@ -71,6 +71,8 @@ const (
 	FsBlockedOnPlanningStepQueue FsBlockedOn = "plan-queue"
 	FsBlockedOnParentInitialRepl FsBlockedOn = "parent-initial-repl"
 	FsBlockedOnReplStepQueue     FsBlockedOn = "repl-queue"
+	FsBlockedOnBlackout          FsBlockedOn = "blackout"
+	FsBlockedOnPaused            FsBlockedOn = "paused"
 )
 
 type FilesystemReport struct {
@@ -93,6 +95,9 @@ type FilesystemReport struct {
 
 type FilesystemInfo struct {
 	Name string
+	// PlanningMemory is estimated memory of version lists and steps used for
+	// planning.
+	PlanningMemory int64 `json:",omitempty"`
 }
 
 type StepReport struct {
@@ -104,6 +109,18 @@ type StepInfo struct {
 	Resumed         bool
 	BytesExpected   uint64
 	BytesReplicated uint64
+	// Elapsed is duration of the step, so far if it's still running.
+	Elapsed time.Duration `json:",omitempty"`
+	// Throttled is time the step waited for bandwidth limit.
+	Throttled time.Duration `json:",omitempty"`
+}
+
+// Rate returns achieved rate of the step in bytes per second.
+func (self *StepInfo) Rate() uint64 {
+	if self.Elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(self.BytesReplicated) / self.Elapsed.Seconds())
 }
 
 func (self *AttemptReport) BytesSum() (expected, replicated uint64,
@@ -234,6 +251,29 @@ func (r *Report) GetFailedFilesystemsCountInLatestAttempt() int {
 	}
 }
 
+// SucceededFilesystems returns names of filesystems, which were replicated
+// without errors by the latest replication attempt.
+func (r *Report) SucceededFilesystems() []string {
+	if len(r.Attempts) == 0 {
+		return nil
+	}
+
+	a := r.Attempts[len(r.Attempts)-1]
+	switch a.State {
+	case AttemptDone, AttemptFanOutError:
+	default:
+		return nil
+	}
+
+	names := make([]string, 0, len(a.Filesystems))
+	for _, f := range a.Filesystems {
+		if f.State == FilesystemDone {
+			names = append(names, f.Info.Name)
+		}
+	}
+	return names
+}
+
 func (r *Report) Error() string {
 	if r.WaitReconnectError != nil {
 		return r.WaitReconnectError.Error()
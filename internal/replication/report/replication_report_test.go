@@ -0,0 +1,82 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_SucceededFilesystems(t *testing.T) {
+	fs := func(name string, state FilesystemState) *FilesystemReport {
+		return &FilesystemReport{Info: &FilesystemInfo{Name: name}, State: state}
+	}
+
+	tests := []struct {
+		name     string
+		attempts []*AttemptReport
+		expected []string
+	}{
+		{
+			name: "no attempts",
+		},
+		{
+			name:     "planning error",
+			attempts: []*AttemptReport{{State: AttemptPlanningError}},
+		},
+		{
+			name: "running",
+			attempts: []*AttemptReport{{
+				State:       AttemptFanOutFSs,
+				Filesystems: []*FilesystemReport{fs("pool/a", FilesystemDone)},
+			}},
+		},
+		{
+			name: "done",
+			attempts: []*AttemptReport{{
+				State: AttemptDone,
+				Filesystems: []*FilesystemReport{
+					fs("pool/a", FilesystemDone),
+					fs("pool/b", FilesystemDone),
+				},
+			}},
+			expected: []string{"pool/a", "pool/b"},
+		},
+		{
+			name: "latest attempt with errors",
+			attempts: []*AttemptReport{
+				{
+					State:       AttemptDone,
+					Filesystems: []*FilesystemReport{fs("pool/c", FilesystemDone)},
+				},
+				{
+					State: AttemptFanOutError,
+					Filesystems: []*FilesystemReport{
+						fs("pool/a", FilesystemDone),
+						fs("pool/b", FilesystemSteppingErrored),
+					},
+				},
+			},
+			expected: []string{"pool/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Report{Attempts: tt.attempts}
+			names := r.SucceededFilesystems()
+			if len(tt.expected) == 0 {
+				assert.Empty(t, names)
+			} else {
+				assert.Equal(t, tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestStepInfo_Rate(t *testing.T) {
+	info := StepInfo{BytesReplicated: 10 << 20}
+	assert.Zero(t, info.Rate())
+	info.Elapsed = 4 * time.Second
+	assert.Equal(t, uint64(10<<20/4), info.Rate())
+}
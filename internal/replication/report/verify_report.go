@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// VerifyReport describes results of comparison of sender and receiver after
+// replication.
+type VerifyReport struct {
+	JobID             string `json:",omitempty"`
+	StartAt, FinishAt time.Time
+	Err               string
+	Filesystems       []*VerifyFilesystemReport
+}
+
+var _, _ = json.Marshal(&VerifyReport{})
+
+type VerifyFilesystemReport struct {
+	Name string
+	// The most recent snapshot, which exists on both sides with the same GUID.
+	LatestCommon string
+	// Number of snapshots, which exist on both sides with the same GUID.
+	Common int
+	// Number of sender snapshots, which are newer than LatestCommon.
+	Behind int
+	// True if LatestCommon has been adopted by the job on both sides.
+	Adopted bool `json:",omitempty"`
+	Errors  []string
+}
+
+func (self *VerifyReport) Error() string {
+	if self.Err != "" {
+		return self.Err
+	}
+	for _, fs := range self.Filesystems {
+		if len(fs.Errors) > 0 {
+			return fs.Name + ": " + fs.Errors[0]
+		}
+	}
+	return ""
+}
+
+// Failed returns number of filesystems, which failed verification, or -1 if
+// verification failed before enumerating filesystems.
+func (self *VerifyReport) Failed() int {
+	if self.Err != "" {
+		return -1
+	}
+	var n int
+	for _, fs := range self.Filesystems {
+		if len(fs.Errors) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (self *VerifyReport) Running() (d time.Duration, ok bool) {
+	if self.StartAt.IsZero() {
+		return 0, false
+	} else if self.FinishAt.IsZero() {
+		return time.Since(self.StartAt), true
+	}
+	return self.FinishAt.Sub(self.StartAt), false
+}
+
+func (self *VerifyFilesystemReport) Failed() bool { return len(self.Errors) > 0 }
+
+// Fail records an error of this filesystem.
+func (self *VerifyFilesystemReport) Fail(err string) {
+	self.Errors = append(self.Errors, err)
+}
+
+func (self *VerifyReport) Filesystem(name string) *VerifyFilesystemReport {
+	for _, fs := range self.Filesystems {
+		if fs.Name == name {
+			return fs
+		}
+	}
+	return nil
+}
+
+// CheckSizes compares dry run size estimates of completed steps from the latest
+// attempt of r with replicated bytes. It fails a filesystem, if the relative
+// difference is more than tolerance. Resumed steps and steps without size
+// estimate are skipped.
+func (self *VerifyReport) CheckSizes(r *Report, tolerance float64) {
+	if r == nil || len(r.Attempts) == 0 {
+		return
+	}
+
+	attempt := r.Attempts[len(r.Attempts)-1]
+	for _, fs := range attempt.Filesystems {
+		vfs := self.Filesystem(fs.Info.Name)
+		if vfs == nil {
+			continue
+		}
+		for i, step := range fs.Steps {
+			if fs.State != FilesystemDone && i >= fs.CurrentStep {
+				break
+			}
+			info := step.Info
+			if info.Resumed || info.BytesExpected == 0 {
+				continue
+			}
+			expected := float64(info.BytesExpected)
+			diff := math.Abs(float64(info.BytesReplicated) - expected)
+			if diff > expected*tolerance {
+				vfs.Fail(fmt.Sprintf(
+					"step %q -> %q: replicated %d bytes, dry run estimated %d bytes",
+					info.From, info.To, info.BytesReplicated, info.BytesExpected))
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package tlsconf
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// CheckKeyPair parses certificate cert and its private key, which can be paths
+// or PEM content, and checks the key matches the certificate and every
+// certificate of the chain is valid at now. It returns warnings about
+// certificates, which expire within expireWarn, and if the certificate isn't
+// valid for host. Empty host or unspecified IP address aren't checked.
+func CheckKeyPair(cert, key, host string, now time.Time,
+	expireWarn time.Duration,
+) ([]string, error) {
+	pair, err := LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for i, der := range pair.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate #%d: %w", i, err)
+		}
+		w, err := checkValidity(c, now, expireWarn)
+		if err != nil {
+			return nil, err
+		} else if w != "" {
+			warnings = append(warnings, w)
+		}
+		if i == 0 && !unspecifiedHost(host) {
+			if err := c.VerifyHostname(host); err != nil {
+				warnings = append(warnings, err.Error())
+			}
+		}
+	}
+	return warnings, nil
+}
+
+func unspecifiedHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// CheckCA parses CA certificates ca, which can be a path or PEM content, and
+// checks every certificate is a CA certificate and valid at now. It returns
+// warnings about certificates, which expire within expireWarn.
+func CheckCA(ca string, now time.Time, expireWarn time.Duration) ([]string,
+	error,
+) {
+	b, err := ReadPEM(ca)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	var found bool
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		} else if block.Type != "CERTIFICATE" {
+			continue
+		}
+		found = true
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		} else if !c.IsCA {
+			warnings = append(warnings, fmt.Sprintf(
+				"certificate %q is not a CA certificate", c.Subject))
+		}
+		w, err := checkValidity(c, now, expireWarn)
+		if err != nil {
+			return nil, err
+		} else if w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+
+	if !found {
+		return nil, errors.New("no certificates found")
+	}
+	return warnings, nil
+}
+
+// checkValidity returns error if c isn't valid at now, or warning if it expires
+// within expireWarn.
+func checkValidity(c *x509.Certificate, now time.Time,
+	expireWarn time.Duration,
+) (string, error) {
+	switch {
+	case now.Before(c.NotBefore):
+		return "", fmt.Errorf("certificate %q is not valid before %s",
+			c.Subject, c.NotBefore.Format(time.RFC3339))
+	case now.After(c.NotAfter):
+		return "", fmt.Errorf("certificate %q expired at %s",
+			c.Subject, c.NotAfter.Format(time.RFC3339))
+	case c.NotAfter.Sub(now) < expireWarn:
+		return fmt.Sprintf("certificate %q expires at %s (in %s)",
+			c.Subject, c.NotAfter.Format(time.RFC3339),
+			c.NotAfter.Sub(now).Truncate(time.Minute)), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,110 @@
+package tlsconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert, key string
+}
+
+func newTestCert(t *testing.T, notBefore, notAfter time.Time, isCA bool,
+) testCert {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "backup.example.com"},
+		DNSNames:              []string{"backup.example.com"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey,
+		priv)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+
+	return testCert{
+		cert: string(pem.EncodeToMemory(
+			&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		key: string(pem.EncodeToMemory(
+			&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func TestCheckKeyPair(t *testing.T) {
+	now := time.Now()
+	const expireWarn = 30 * 24 * time.Hour
+	valid := newTestCert(t, now.Add(-time.Hour), now.Add(365*24*time.Hour), false)
+
+	w, err := CheckKeyPair(valid.cert, valid.key, "backup.example.com", now,
+		expireWarn)
+	require.NoError(t, err)
+	assert.Empty(t, w)
+
+	w, err = CheckKeyPair(valid.cert, valid.key, "0.0.0.0", now, expireWarn)
+	require.NoError(t, err)
+	assert.Empty(t, w)
+
+	w, err = CheckKeyPair(valid.cert, valid.key, "other.example.com", now,
+		expireWarn)
+	require.NoError(t, err)
+	require.Len(t, w, 1)
+	assert.Contains(t, w[0], "other.example.com")
+
+	w, err = CheckKeyPair(valid.cert+valid.key, "", "", now, expireWarn)
+	require.NoError(t, err, "combined cert and key")
+	assert.Empty(t, w)
+
+	other := newTestCert(t, now.Add(-time.Hour), now.Add(time.Hour), false)
+	_, err = CheckKeyPair(valid.cert, other.key, "", now, expireWarn)
+	require.Error(t, err, "key doesn't match")
+
+	w, err = CheckKeyPair(other.cert, other.key, "", now, expireWarn)
+	require.NoError(t, err)
+	require.Len(t, w, 1)
+	assert.Contains(t, w[0], "expires at")
+
+	expired := newTestCert(t, now.Add(-2*time.Hour), now.Add(-time.Hour), false)
+	_, err = CheckKeyPair(expired.cert, expired.key, "", now, expireWarn)
+	require.ErrorContains(t, err, "expired")
+
+	future := newTestCert(t, now.Add(time.Hour), now.Add(2*time.Hour), false)
+	_, err = CheckKeyPair(future.cert, future.key, "", now, expireWarn)
+	require.ErrorContains(t, err, "not valid before")
+}
+
+func TestCheckCA(t *testing.T) {
+	now := time.Now()
+	const expireWarn = 30 * 24 * time.Hour
+	ca := newTestCert(t, now.Add(-time.Hour), now.Add(365*24*time.Hour), true)
+	leaf := newTestCert(t, now.Add(-time.Hour), now.Add(365*24*time.Hour), false)
+
+	w, err := CheckCA(ca.cert, now, expireWarn)
+	require.NoError(t, err)
+	assert.Empty(t, w)
+
+	w, err = CheckCA(ca.cert+leaf.cert, now, expireWarn)
+	require.NoError(t, err)
+	require.Len(t, w, 1)
+	assert.Contains(t, w[0], "not a CA certificate")
+
+	_, err = CheckCA(leaf.key, now, expireWarn)
+	require.ErrorContains(t, err, "no certificates found")
+}
@@ -7,13 +7,61 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
+// IsPEM returns true if s is PEM content, embedded into config by
+// !include_raw, instead of a path to a file.
+func IsPEM(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN ")
+}
+
+// ReadPEM returns s itself if it's PEM content, or content of file s.
+func ReadPEM(s string) ([]byte, error) {
+	if IsPEM(s) {
+		return []byte(s), nil
+	}
+	b, err := os.ReadFile(s)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %w", s, err)
+	}
+	return b, nil
+}
+
+// Describe returns s for logging: a path to file or a placeholder for PEM
+// content, because it can contain a private key.
+func Describe(s string) string {
+	if IsPEM(s) {
+		return "<inline PEM>"
+	}
+	return s
+}
+
+// LoadX509KeyPair is like [tls.LoadX509KeyPair], but cert and key can be PEM
+// content too. Empty key means cert contains the private key too.
+func LoadX509KeyPair(cert, key string) (tls.Certificate, error) {
+	certPEM, err := ReadPEM(cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM := certPEM
+	if key != "" {
+		if keyPEM, err = ReadPEM(key); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	c, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse key pair: %w", err)
+	}
+	return c, nil
+}
+
 func ParseCAFile(certfile string) (*x509.CertPool, error) {
 	pool := x509.NewCertPool()
-	pem, err := os.ReadFile(certfile)
+	pem, err := ReadPEM(certfile)
 	if err != nil {
-		return nil, fmt.Errorf("reading %q: %w", certfile, err)
+		return nil, err
 	}
 	if !pool.AppendCertsFromPEM(pem) {
 		return nil, errors.New("PEM parsing error")
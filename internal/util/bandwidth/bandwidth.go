@@ -0,0 +1,258 @@
+// Package bandwidth implements a token bucket, which limits bandwidth of
+// streams sharing it.
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBurst is max burst of limiters, configured without it, unless their
+// rate is lower.
+const DefaultBurst = 1 << 20
+
+// NewLimiter returns Limiter, which allows sustained rate bytes per second and
+// max burst bytes at once, shared by all its readers. Zero burst means
+// [DefaultBurst] or rate, if it's lower. Zero rate means no limit and nil
+// Limiter is returned.
+func NewLimiter(rate, burst uint64) *Limiter {
+	if rate == 0 {
+		return nil
+	} else if burst == 0 {
+		burst = min(rate, DefaultBurst)
+	}
+	return &Limiter{
+		rate:   float64(rate),
+		burst:  int(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// RateFunc returns rate in bytes per second at t. Zero rate means no limit.
+type RateFunc func(t time.Time) uint64
+
+// NewScheduledLimiter returns Limiter, which allows rate returned by schedule
+// at the time of every transfer, so the rate changes during long transfers.
+// Zero burst means [DefaultBurst] or current rate, if it's lower.
+func NewScheduledLimiter(schedule RateFunc, burst uint64) *Limiter {
+	now := time.Now()
+	l := &Limiter{schedule: schedule, maxBurst: burst, last: now}
+	l.setRate(schedule(now))
+	return l
+}
+
+// Limiter is a token bucket. It's full initially, so the first burst bytes pass
+// without waiting, and it refills by rate bytes per second. Every transfer of n
+// bytes takes n tokens and waits, while the bucket is in debt, so the average
+// rate of any long enough transfer is rate, regardless of sizes of its chunks.
+type Limiter struct {
+	schedule RateFunc
+	maxBurst uint64
+
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// Wait blocks until n bytes can be transferred or ctx done. The bytes and time
+// it blocked are accounted in [Stats] of ctx, if any.
+func (self *Limiter) Wait(ctx context.Context, n int) error {
+	d := self.reserve(n)
+	stats := StatsFrom(ctx)
+	stats.add(n, 0)
+	if d <= 0 {
+		return nil
+	}
+
+	started := time.Now()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	defer func() { stats.add(0, time.Since(started)) }()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+	}
+	return nil
+}
+
+func (self *Limiter) reserve(n int) time.Duration {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	now := time.Now()
+	if self.schedule != nil {
+		self.setRate(self.schedule(now))
+	}
+	if self.rate == 0 {
+		self.last = now
+		return 0
+	}
+
+	self.tokens = min(float64(self.burst),
+		self.tokens+now.Sub(self.last).Seconds()*self.rate)
+	self.last = now
+
+	self.tokens -= float64(n)
+	if self.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-self.tokens / self.rate * float64(time.Second))
+}
+
+// setRate changes rate and burst, if rate is different.
+func (self *Limiter) setRate(rate uint64) {
+	if float64(rate) == self.rate {
+		return
+	}
+	unlimited := self.rate == 0
+	self.rate = float64(rate)
+	if rate == 0 {
+		self.burst = 0
+		return
+	}
+
+	burst := self.maxBurst
+	if burst == 0 {
+		burst = min(rate, DefaultBurst)
+	}
+	self.burst = int(burst)
+	if unlimited {
+		// the bucket refilled, while it wasn't limited
+		self.tokens = float64(self.burst)
+	} else {
+		self.tokens = min(self.tokens, float64(self.burst))
+	}
+}
+
+// chunkSize returns max bytes of one transfer or zero for no limit.
+func (self *Limiter) chunkSize() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.burst
+}
+
+// Reader wraps r, limiting its reads by self. It returns r as is, if self is
+// nil.
+func (self *Limiter) Reader(ctx context.Context, r io.ReadCloser,
+) io.ReadCloser {
+	if self == nil {
+		return r
+	}
+	return &reader{ReadCloser: r, ctx: ctx, limiter: self}
+}
+
+type reader struct {
+	io.ReadCloser
+
+	ctx     context.Context
+	limiter *Limiter
+}
+
+func (self *reader) Read(p []byte) (int, error) {
+	if n := self.limiter.chunkSize(); n > 0 && len(p) > n {
+		p = p[:n]
+	}
+
+	n, err := self.ReadCloser.Read(p)
+	if n > 0 {
+		if err := self.limiter.Wait(self.ctx, n); err != nil {
+			return n, err
+		}
+	}
+	return n, err //nolint:wrapcheck // not needed
+}
+
+// splicer is zfs.Splicer.
+type splicer interface {
+	SplicePipe() *os.File
+	Spliced(n int64)
+}
+
+// SplicePipe returns pipe of wrapped stream, if it can be spliced. Spliced
+// bytes are limited by Spliced.
+func (self *reader) SplicePipe() *os.File {
+	if s, ok := self.ReadCloser.(splicer); ok {
+		return s.SplicePipe()
+	}
+	return nil
+}
+
+// Spliced waits, until n spliced bytes fit into the limit, so the splicing is
+// limited too.
+func (self *reader) Spliced(n int64) {
+	_ = self.limiter.Wait(self.ctx, int(n))
+	if s, ok := self.ReadCloser.(splicer); ok {
+		s.Spliced(n)
+	}
+}
+
+type ctxKeyLimiter struct{}
+
+// WithLimiter returns ctx, which limits streams of replication steps by l.
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, ctxKeyLimiter{}, l)
+}
+
+// LimiterFrom returns [Limiter] of ctx or nil.
+func LimiterFrom(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(ctxKeyLimiter{}).(*Limiter)
+	return l
+}
+
+type ctxKeyStats struct{}
+
+// WithStats returns ctx, which accounts transfers in s, while they're limited
+// by any Limiter.
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, ctxKeyStats{}, s)
+}
+
+// StatsFrom returns [Stats] of ctx or nil.
+func StatsFrom(ctx context.Context) *Stats {
+	s, _ := ctx.Value(ctxKeyStats{}).(*Stats)
+	return s
+}
+
+// Stats accounts bytes and throttle time of limited transfers. Nil Stats
+// accounts nothing.
+type Stats struct {
+	bytes     atomic.Uint64
+	throttled atomic.Int64
+}
+
+func (self *Stats) add(n int, throttled time.Duration) {
+	if self == nil {
+		return
+	}
+	if n > 0 {
+		self.bytes.Add(uint64(n))
+	}
+	if throttled > 0 {
+		self.throttled.Add(int64(throttled))
+	}
+}
+
+// Bytes returns number of bytes transferred.
+func (self *Stats) Bytes() uint64 {
+	if self == nil {
+		return 0
+	}
+	return self.bytes.Load()
+}
+
+// Throttled returns time transfers waited for tokens.
+func (self *Stats) Throttled() time.Duration {
+	if self == nil {
+		return 0
+	}
+	return time.Duration(self.throttled.Load())
+}
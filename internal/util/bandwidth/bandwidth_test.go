@@ -0,0 +1,150 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiter_zero(t *testing.T) {
+	assert.Nil(t, NewLimiter(0, 0))
+
+	var l *Limiter
+	r := io.NopCloser(bytes.NewReader(nil))
+	assert.Equal(t, r, l.Reader(t.Context(), r))
+}
+
+func TestLimiter_Reader(t *testing.T) {
+	const rate = 64 << 10
+	l := NewLimiter(rate, 0)
+	b := make([]byte, rate*3/2)
+
+	started := time.Now()
+	r := l.Reader(t.Context(), io.NopCloser(bytes.NewReader(b)))
+	n, err := io.Copy(io.Discard, r)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(b)), n)
+	// the first second is burst, the rest is limited
+	assert.GreaterOrEqual(t, time.Since(started), 400*time.Millisecond)
+}
+
+type testSplicer struct {
+	io.ReadCloser
+	spliced int64
+}
+
+func (self *testSplicer) SplicePipe() *os.File { return os.Stdin }
+
+func (self *testSplicer) Spliced(n int64) { self.spliced += n }
+
+func TestLimiter_Reader_spliced(t *testing.T) {
+	const rate = 64 << 10
+	l := NewLimiter(rate, 0)
+	s := &testSplicer{ReadCloser: io.NopCloser(bytes.NewReader(nil))}
+	r := l.Reader(t.Context(), s).(splicer)
+	assert.Same(t, os.Stdin, r.SplicePipe())
+
+	started := time.Now()
+	r.Spliced(rate * 3 / 2)
+	assert.Equal(t, int64(rate*3/2), s.spliced)
+	// the first second is burst, the rest is limited
+	assert.GreaterOrEqual(t, time.Since(started), 400*time.Millisecond)
+
+	r = l.Reader(t.Context(), io.NopCloser(bytes.NewReader(nil))).(splicer)
+	assert.Nil(t, r.SplicePipe())
+}
+
+func TestLimiter_Wait_canceled(t *testing.T) {
+	l := NewLimiter(1, 0)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.NoError(t, l.Wait(ctx, 1))
+	require.ErrorIs(t, l.Wait(ctx, 10), context.Canceled)
+}
+
+func TestNewLimiter_burst(t *testing.T) {
+	assert.Equal(t, 64<<10, NewLimiter(64<<10, 0).burst)
+	assert.Equal(t, DefaultBurst, NewLimiter(10<<20, 0).burst)
+	assert.Equal(t, 4<<10, NewLimiter(10<<20, 4<<10).burst)
+}
+
+// chunkReader returns no more than size bytes per Read.
+type chunkReader struct {
+	io.Reader
+	size int
+}
+
+func (self *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > self.size {
+		p = p[:self.size]
+	}
+	return self.Reader.Read(p) //nolint:wrapcheck // not needed
+}
+
+func TestLimiter_accuracy(t *testing.T) {
+	const (
+		rate  = 1 << 20
+		burst = 64 << 10
+		size  = burst + rate/2
+	)
+
+	for _, chunk := range []int{512, 4 << 10, 32 << 10, 256 << 10} {
+		t.Run(strconv.Itoa(chunk), func(t *testing.T) {
+			t.Parallel()
+			l := NewLimiter(rate, burst)
+			var stats Stats
+			ctx := WithStats(t.Context(), &stats)
+			r := l.Reader(ctx, io.NopCloser(&chunkReader{
+				Reader: bytes.NewReader(make([]byte, size)),
+				size:   chunk,
+			}))
+
+			started := time.Now()
+			n, err := io.Copy(io.Discard, r)
+			elapsed := time.Since(started)
+			require.NoError(t, err)
+			assert.Equal(t, int64(size), n)
+			assert.Equal(t, uint64(size), stats.Bytes())
+
+			// the burst passes at once, the rest is limited by rate
+			achieved := float64(size-burst) / elapsed.Seconds()
+			assert.InEpsilon(t, rate, achieved, 0.05)
+			assert.InEpsilon(t, elapsed, stats.Throttled(), 0.05)
+		})
+	}
+}
+
+func TestStats_nil(t *testing.T) {
+	var stats *Stats
+	stats.add(1, time.Second)
+	assert.Zero(t, stats.Bytes())
+	assert.Zero(t, stats.Throttled())
+	assert.Nil(t, StatsFrom(t.Context()))
+}
+
+func TestNewScheduledLimiter(t *testing.T) {
+	var rate atomic.Uint64
+	l := NewScheduledLimiter(func(time.Time) uint64 { return rate.Load() }, 0)
+	assert.Zero(t, l.chunkSize())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.NoError(t, l.Wait(ctx, 100<<20), "unlimited")
+
+	rate.Store(64 << 10)
+	require.NoError(t, l.Wait(ctx, 1))
+	assert.Equal(t, 64<<10, l.chunkSize())
+	require.ErrorIs(t, l.Wait(ctx, 1<<20), context.Canceled)
+
+	rate.Store(0)
+	require.NoError(t, l.Wait(ctx, 100<<20), "unlimited again")
+	assert.Zero(t, l.chunkSize())
+}
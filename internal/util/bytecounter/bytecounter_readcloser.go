@@ -2,6 +2,7 @@ package bytecounter
 
 import (
 	"io"
+	"os"
 	"sync/atomic"
 )
 
@@ -27,3 +28,25 @@ func (self *ReadCloser) Read(p []byte) (int, error) {
 }
 
 func (self *ReadCloser) Count() uint64 { return self.count.Load() }
+
+// splicer is zfs.Splicer.
+type splicer interface {
+	SplicePipe() *os.File
+	Spliced(n int64)
+}
+
+// SplicePipe returns pipe of wrapped stream, if it can be spliced, so spliced
+// bytes are counted too.
+func (self *ReadCloser) SplicePipe() *os.File {
+	if s, ok := self.ReadCloser.(splicer); ok {
+		return s.SplicePipe()
+	}
+	return nil
+}
+
+func (self *ReadCloser) Spliced(n int64) {
+	self.count.Add(uint64(n))
+	if s, ok := self.ReadCloser.(splicer); ok {
+		s.Spliced(n)
+	}
+}
@@ -0,0 +1,69 @@
+// Package httppost sends requests to notification services and webhooks and
+// checks their responses.
+package httppost
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrBody limits response body, included into errors.
+const maxErrBody = 512
+
+// Post sends body with headers to url and expects 2xx response. ctx can be
+// canceled already, like when the job was stopped, but the receiver must know
+// about it, so only timeout limits the request.
+func Post(ctx context.Context, client *http.Client, timeout time.Duration,
+	url string, body []byte, headers map[string]string,
+) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	_, err = Do(client, req, nil)
+	return err
+}
+
+// Do sends req by client and returns status of the response. It returns an
+// error, if expected returns false for the status, or it isn't 2xx, if
+// expected is nil. The error includes beginning of the response body.
+func Do(client *http.Client, req *http.Request, expected func(status int) bool,
+) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if expected == nil {
+		expected = Is2xx
+	}
+	if expected(resp.StatusCode) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrBody))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if s := strings.TrimSpace(string(b)); s != "" {
+		return resp.StatusCode, fmt.Errorf("unexpected status %q: %s",
+			resp.Status, s)
+	}
+	return resp.StatusCode, fmt.Errorf("unexpected status %q", resp.Status)
+}
+
+// Is2xx returns true, if status is successful.
+func Is2xx(status int) bool { return status >= 200 && status < 300 }
@@ -0,0 +1,69 @@
+package httppost
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPost(t *testing.T) {
+	var gotBody, gotHeader string
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			gotBody, gotHeader = string(b), r.Header.Get("X-Test")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(" failed \n"))
+		}))
+	t.Cleanup(srv.Close)
+
+	ctx := t.Context()
+	require.NoError(t, Post(ctx, srv.Client(), time.Minute, srv.URL,
+		[]byte("body"), map[string]string{"X-Test": "value"}))
+	assert.Equal(t, "body", gotBody)
+	assert.Equal(t, "value", gotHeader)
+
+	status = http.StatusServiceUnavailable
+	err := Post(ctx, srv.Client(), time.Minute, srv.URL, nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, `unexpected status "503 Service Unavailable": failed`,
+		err.Error())
+}
+
+func TestPost_canceled(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { called = true }))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.NoError(t, Post(ctx, srv.Client(), time.Minute, srv.URL, nil, nil))
+	assert.True(t, called)
+}
+
+func TestDo_expected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL,
+		nil)
+	require.NoError(t, err)
+	status, err := Do(srv.Client(), req,
+		func(status int) bool { return status == http.StatusOK })
+	require.Error(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, `unexpected status "202 Accepted"`, err.Error())
+}
@@ -0,0 +1,135 @@
+// Package journal resumes a stream, which was broken by a connection blip, at
+// the transport layer, without aborting zfs send and zfs recv.
+//
+// Sender reads the stream through Replay, which keeps the last bytes sent. If
+// the transfer breaks, sender reconnects and sends the stream again, starting
+// from the oldest kept byte. Receiver feeds the stream into zfs recv through
+// Session, which journals the offset of the stream, skips bytes it already
+// has and continues with new ones.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// NewSessionID returns a random ID of a new stream session.
+func NewSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewReplay returns Replay, which reads r and keeps the last size bytes read
+// from it.
+func NewReplay(r io.Reader, size int) *Replay {
+	return &Replay{r: r, ring: make([]byte, size)}
+}
+
+// Replay reads a stream and keeps the last bytes read, so they can be read
+// again, after a broken transfer.
+type Replay struct {
+	r    io.Reader
+	ring []byte
+
+	mu    sync.Mutex
+	total int64
+	err   error
+	last  *ReplayReader
+}
+
+// Rewind closes the previous reader and returns a new reader, which starts
+// from the oldest kept byte. It waits for a Read of the previous reader, which
+// is in progress.
+func (self *Replay) Rewind() *ReplayReader {
+	if self.last != nil {
+		self.last.Close()
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	start := self.start()
+	self.last = &ReplayReader{replay: self, start: start, offset: start}
+	return self.last
+}
+
+func (self *Replay) start() int64 {
+	return max(0, self.total-int64(len(self.ring)))
+}
+
+func (self *Replay) read(r *ReplayReader, p []byte) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if r.closed.Load() {
+		return 0, os.ErrClosed
+	} else if r.offset < self.start() {
+		return 0, fmt.Errorf("journal: offset %d is not kept anymore", r.offset)
+	}
+
+	if r.offset < self.total {
+		n := self.replay(r.offset, p)
+		r.offset += int64(n)
+		return n, nil
+	} else if self.err != nil {
+		return 0, self.err
+	}
+
+	n, err := self.r.Read(p)
+	self.keep(p[:n])
+	r.offset += int64(n)
+	if err != nil {
+		self.err = err
+	}
+	return n, err //nolint:wrapcheck // not needed
+}
+
+// replay copies to p kept bytes starting from offset.
+func (self *Replay) replay(offset int64, p []byte) int {
+	size := int64(len(self.ring))
+	p = p[:min(int64(len(p)), self.total-offset)]
+	i := int(offset % size)
+	n := copy(p, self.ring[i:])
+	if n < len(p) {
+		n += copy(p[n:], self.ring)
+	}
+	return n
+}
+
+func (self *Replay) keep(b []byte) {
+	self.total += int64(len(b))
+	if len(self.ring) == 0 {
+		return
+	} else if len(b) > len(self.ring) {
+		b = b[len(b)-len(self.ring):]
+	}
+	i := int((self.total - int64(len(b))) % int64(len(self.ring)))
+	n := copy(self.ring[i:], b)
+	copy(self.ring, b[n:])
+}
+
+// ReplayReader reads the stream of Replay from Offset.
+type ReplayReader struct {
+	replay *Replay
+	start  int64
+	offset int64
+	closed atomic.Bool
+}
+
+// Offset returns the offset of the stream, this reader started from.
+func (self *ReplayReader) Offset() int64 { return self.start }
+
+func (self *ReplayReader) Read(p []byte) (int, error) {
+	return self.replay.read(self, p)
+}
+
+// Close makes every next Read fail. It doesn't close the stream.
+func (self *ReplayReader) Close() error {
+	self.closed.Store(true)
+	return nil
+}
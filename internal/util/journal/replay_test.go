@@ -0,0 +1,50 @@
+package journal
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_Rewind(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	replay := NewReplay(bytes.NewReader(data), 64)
+
+	r := replay.Rewind()
+	assert.Equal(t, int64(0), r.Offset())
+	b := make([]byte, 100)
+	_, err := io.ReadFull(r, b)
+	require.NoError(t, err)
+	assert.Equal(t, data[:100], b)
+
+	r2 := replay.Rewind()
+	assert.Equal(t, int64(100-64), r2.Offset())
+	_, err = r.Read(b)
+	require.Error(t, err)
+
+	got, err := io.ReadAll(iotest.HalfReader(r2))
+	require.NoError(t, err)
+	assert.Equal(t, data[r2.Offset():], got)
+
+	r3 := replay.Rewind()
+	assert.Equal(t, int64(len(data)-64), r3.Offset())
+	got, err = io.ReadAll(r3)
+	require.NoError(t, err)
+	assert.Equal(t, data[r3.Offset():], got)
+}
+
+func TestReplay_keepLarge(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	replay := NewReplay(bytes.NewReader(data), 16)
+	got, err := io.ReadAll(replay.Rewind())
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	got, err = io.ReadAll(replay.Rewind())
+	require.NoError(t, err)
+	assert.Equal(t, data[len(data)-16:], got)
+}
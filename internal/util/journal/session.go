@@ -0,0 +1,280 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const chunkSize = 256 << 10
+
+var chunkPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, chunkSize)
+		return &b
+	},
+}
+
+// NewSessions returns Sessions, which wait up to grace for a broken stream to
+// be continued.
+func NewSessions(grace time.Duration) *Sessions {
+	return &Sessions{items: make(map[string]*Session), grace: grace}
+}
+
+// Sessions are stream sessions of a receiver.
+type Sessions struct {
+	mu    sync.Mutex
+	items map[string]*Session
+	grace time.Duration
+}
+
+// Receive feeds r into receive through session id. The first request of the
+// session starts receive, every next request continues the stream from
+// offset, after the previous request broke. Every request waits until
+// receive finished and returns its result.
+//
+// receive runs with ctx of the first request, which isn't canceled, when the
+// first request is gone.
+func (self *Sessions) Receive(ctx context.Context, id string, offset int64,
+	r io.ReadCloser, receive func(context.Context, io.ReadCloser) error,
+) error {
+	s, created, err := self.attach(id, offset, r)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		go func() {
+			s.finish(receive(context.WithoutCancel(ctx), s))
+			time.AfterFunc(self.grace, func() { self.remove(id, s) })
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-s.done:
+	}
+	return s.err
+}
+
+func (self *Sessions) attach(id string, offset int64, r io.ReadCloser,
+) (*Session, bool, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if s, ok := self.items[id]; ok {
+		s.attach(offset, r)
+		return s, false, nil
+	} else if offset != 0 {
+		return nil, false, fmt.Errorf(
+			"journal: unknown stream session %q at offset %d", id, offset)
+	}
+
+	s := newSession(self.grace)
+	s.attach(offset, r)
+	self.items[id] = s
+	return s, true, nil
+}
+
+func (self *Sessions) remove(id string, s *Session) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.items[id] == s {
+		delete(self.items, id)
+	}
+}
+
+// Len returns number of sessions.
+func (self *Sessions) Len() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return len(self.items)
+}
+
+func newSession(grace time.Duration) *Session {
+	return &Session{
+		grace:    grace,
+		chunks:   make(chan chunk, 4),
+		attached: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Session is a stream, which can be continued by another request, after the
+// current one broke. It journals the offset of the stream and skips bytes it
+// already has.
+type Session struct {
+	grace    time.Duration
+	chunks   chan chunk
+	attached chan struct{}
+
+	mu  sync.Mutex
+	cur *body
+
+	// owned by Read
+	offset     int64
+	buf        *[]byte
+	pending    []byte
+	eof        bool
+	broken     error
+	brokenBody *body
+	deadline   time.Time
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	doneOnce sync.Once
+	done     chan struct{}
+	err      error
+}
+
+type body struct {
+	r      io.ReadCloser
+	offset int64
+}
+
+type chunk struct {
+	body   *body
+	offset int64
+	buf    *[]byte
+	n      int
+	err    error
+}
+
+func (self *Session) attach(offset int64, r io.ReadCloser) {
+	select {
+	case <-self.closed:
+		return
+	default:
+	}
+
+	b := &body{r: r, offset: offset}
+	self.mu.Lock()
+	self.cur = b
+	self.mu.Unlock()
+
+	select {
+	case self.attached <- struct{}{}:
+	default:
+	}
+	go self.pump(b)
+}
+
+func (self *Session) current() *body {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.cur
+}
+
+func (self *Session) pump(b *body) {
+	offset := b.offset
+	for {
+		buf := chunkPool.Get().(*[]byte)
+		n, err := b.r.Read(*buf)
+		c := chunk{body: b, offset: offset, buf: buf, n: n, err: err}
+		select {
+		case self.chunks <- c:
+		case <-self.closed:
+			chunkPool.Put(buf)
+			return
+		}
+		offset += int64(n)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (self *Session) Read(p []byte) (int, error) {
+	for len(self.pending) == 0 {
+		if self.buf != nil {
+			chunkPool.Put(self.buf)
+			self.buf = nil
+		}
+		if self.eof {
+			return 0, io.EOF
+		} else if err := self.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, self.pending)
+	self.pending = self.pending[n:]
+	return n, nil
+}
+
+func (self *Session) next() error {
+	if self.broken != nil && self.brokenBody != self.current() {
+		// continued by another request
+		self.broken, self.brokenBody = nil, nil
+	}
+
+	var timeout <-chan time.Time
+	if self.broken != nil {
+		t := time.NewTimer(time.Until(self.deadline))
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case c := <-self.chunks:
+		return self.accept(c)
+	case <-self.attached:
+	case <-timeout:
+		return fmt.Errorf("journal: stream not continued in %s: %w",
+			self.grace, self.broken)
+	case <-self.closed:
+		return errors.New("journal: session closed")
+	}
+	return nil
+}
+
+// accept takes bytes from c, which this session doesn't have yet.
+func (self *Session) accept(c chunk) error {
+	if c.body != self.current() {
+		// a chunk from the broken request
+		chunkPool.Put(c.buf)
+		return nil
+	} else if c.offset > self.offset {
+		chunkPool.Put(c.buf)
+		return fmt.Errorf("journal: stream continued at offset %d, expected %d",
+			c.offset, self.offset)
+	}
+
+	if skip := self.offset - c.offset; skip < int64(c.n) {
+		self.buf = c.buf
+		self.pending = (*c.buf)[skip:c.n]
+		self.offset += int64(len(self.pending))
+	} else {
+		chunkPool.Put(c.buf)
+	}
+
+	switch {
+	case c.err == io.EOF:
+		self.eof = true
+	case c.err != nil:
+		self.broken, self.brokenBody = c.err, c.body
+		self.deadline = time.Now().Add(self.grace)
+	}
+	return nil
+}
+
+// Close stops reading of the stream. Requests of the session keep waiting for
+// the result of the receive.
+func (self *Session) Close() error {
+	self.closeOnce.Do(func() { close(self.closed) })
+	return nil
+}
+
+func (self *Session) finish(err error) {
+	self.Close()
+	self.doneOnce.Do(func() {
+		self.err = err
+		close(self.done)
+	})
+}
@@ -0,0 +1,93 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_Receive(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	sessions := NewSessions(time.Second)
+	ctx := t.Context()
+
+	var got bytes.Buffer
+	received := make(chan struct{})
+	receive := func(ctx context.Context, r io.ReadCloser) error {
+		defer close(received)
+		defer r.Close()
+		_, err := io.Copy(&got, r)
+		return err
+	}
+
+	broken := errors.New("connection reset")
+	first := io.NopCloser(io.MultiReader(bytes.NewReader(data[:300_000]),
+		iotest.ErrReader(broken)))
+	firstDone := make(chan error, 1)
+	go func() {
+		firstDone <- sessions.Receive(ctx, "foo", 0, first, receive)
+	}()
+
+	// wait until the first request broke
+	require.Eventually(t, func() bool { return sessions.Len() == 1 },
+		time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	const offset = 200_000
+	second := io.NopCloser(bytes.NewReader(data[offset:]))
+	require.NoError(t, sessions.Receive(ctx, "foo", offset, second, receive))
+	require.NoError(t, <-firstDone)
+	<-received
+	assert.Equal(t, data, got.Bytes())
+}
+
+func TestSessions_Receive_notContinued(t *testing.T) {
+	sessions := NewSessions(10 * time.Millisecond)
+	broken := errors.New("connection reset")
+	r := io.NopCloser(io.MultiReader(bytes.NewReader([]byte("foo")),
+		iotest.ErrReader(broken)))
+	err := sessions.Receive(t.Context(), "foo", 0, r,
+		func(ctx context.Context, r io.ReadCloser) error {
+			_, err := io.Copy(io.Discard, r)
+			return err
+		})
+	require.ErrorIs(t, err, broken)
+}
+
+func TestSessions_Receive_unknown(t *testing.T) {
+	sessions := NewSessions(time.Second)
+	err := sessions.Receive(t.Context(), "foo", 10,
+		io.NopCloser(bytes.NewReader(nil)),
+		func(ctx context.Context, r io.ReadCloser) error { return nil })
+	require.Error(t, err)
+}
+
+func TestSessions_Receive_gap(t *testing.T) {
+	sessions := NewSessions(time.Second)
+	broken := errors.New("connection reset")
+	first := io.NopCloser(io.MultiReader(bytes.NewReader([]byte("foo")),
+		iotest.ErrReader(broken)))
+
+	started := make(chan struct{})
+	go func() {
+		_ = sessions.Receive(t.Context(), "foo", 0, first,
+			func(ctx context.Context, r io.ReadCloser) error {
+				close(started)
+				_, err := io.Copy(io.Discard, r)
+				return err
+			})
+	}()
+	<-started
+	time.Sleep(10 * time.Millisecond)
+
+	err := sessions.Receive(t.Context(), "foo", 10,
+		io.NopCloser(bytes.NewReader([]byte("bar"))), nil)
+	require.ErrorContains(t, err, "expected 3")
+}
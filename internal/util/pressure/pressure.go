@@ -0,0 +1,22 @@
+// Package pressure shares resource pressure state of the daemon, so components
+// can shed their concurrency.
+package pressure
+
+import "sync/atomic"
+
+var high atomic.Bool
+
+// High returns true, if the daemon is under resource pressure and must reduce
+// its concurrency.
+func High() bool { return high.Load() }
+
+// Set changes pressure state and returns previous state.
+func Set(v bool) bool { return high.Swap(v) }
+
+// Concurrency returns n, or 1 under resource pressure.
+func Concurrency(n int) int {
+	if n > 1 && High() {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,12 @@
+//go:build linux
+
+package qos
+
+import "golang.org/x/sys/unix"
+
+const markSupported = true
+
+//nolint:wrapcheck // wrapped by caller
+func setMark(fd int, mark uint32) error {
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_MARK, int(mark))
+}
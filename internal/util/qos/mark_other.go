@@ -0,0 +1,11 @@
+//go:build !linux
+
+package qos
+
+import "errors"
+
+const markSupported = false
+
+func setMark(int, uint32) error {
+	return errors.New("not supported on this platform")
+}
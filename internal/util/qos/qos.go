@@ -0,0 +1,148 @@
+// Package qos marks sockets with DSCP/TOS and SO_MARK, so network policies can
+// shape and route traffic of them.
+package qos
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// dscpClasses are names of DSCP classes from RFC 2474, RFC 2597, RFC 3246 and
+// RFC 8622.
+var dscpClasses = map[string]uint8{
+	"cs0": 0, "cs1": 8, "cs2": 16, "cs3": 24,
+	"cs4": 32, "cs5": 40, "cs6": 48, "cs7": 56,
+	"af11": 10, "af12": 12, "af13": 14,
+	"af21": 18, "af22": 20, "af23": 22,
+	"af31": 26, "af32": 28, "af33": 30,
+	"af41": 34, "af42": 36, "af43": 38,
+	"ef": 46, "va": 44, "le": 1,
+}
+
+// ParseDSCP returns TOS byte of DSCP s, which is a name of DSCP class, like
+// "af21" or "ef", or its number from 0 to 63.
+func ParseDSCP(s string) (uint8, error) {
+	if dscp, ok := dscpClasses[strings.ToLower(s)]; ok {
+		return dscp << 2, nil
+	}
+	dscp, err := strconv.ParseUint(s, 0, 6)
+	if err != nil {
+		return 0, fmt.Errorf("invalid DSCP %q: not a class name or number 0-63",
+			s)
+	}
+	return uint8(dscp) << 2, nil
+}
+
+// Options are marks of sockets. Zero value of any of them keeps default of the
+// system.
+type Options struct {
+	// TOS is IP_TOS for IPv4 and IPV6_TCLASS for IPv6 sockets.
+	TOS uint8
+	// Mark is SO_MARK of sockets. It's supported on Linux only.
+	Mark uint32
+}
+
+// Validate returns an error, if o can't be applied on this platform.
+func (o Options) Validate() error {
+	if o.Mark != 0 && !markSupported {
+		return errors.New("SO_MARK is supported on Linux only")
+	}
+	return nil
+}
+
+func (o Options) Empty() bool { return o == Options{} }
+
+// Control marks sockets of a net.Dialer. Sockets of networks other than TCP,
+// like unix sockets, are left as is.
+func (o Options) Control(network, _ string, c syscall.RawConn) error {
+	if o.Empty() || !strings.HasPrefix(network, "tcp") {
+		return nil
+	}
+	return o.control(c)
+}
+
+// Apply marks TCP connection conn, or connection wrapped by it, like
+// *tls.Conn. Other connections are left as is.
+func (o Options) Apply(conn net.Conn) error {
+	if o.Empty() {
+		return nil
+	}
+	for {
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		conn = wrapper.NetConn()
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	c, err := tcpConn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("raw connection: %w", err)
+	}
+	return o.control(c)
+}
+
+func (o Options) control(c syscall.RawConn) error {
+	var err error
+	ctrlErr := c.Control(func(fd uintptr) { err = o.set(int(fd)) })
+	if ctrlErr != nil {
+		return fmt.Errorf("control socket: %w", ctrlErr)
+	}
+	return err
+}
+
+func (o Options) set(fd int) error {
+	if o.TOS != 0 {
+		if err := setTOS(fd, int(o.TOS)); err != nil {
+			return err
+		}
+	}
+	if o.Mark != 0 {
+		if err := setMark(fd, o.Mark); err != nil {
+			return fmt.Errorf("set SO_MARK %d: %w", o.Mark, err)
+		}
+	}
+	return nil
+}
+
+// setTOS sets IP_TOS or IPV6_TCLASS, depending on address family of socket
+// fd. IPv4 connections accepted by dual-stack listeners have IPv6 sockets with
+// IPv4-mapped addresses, which need IP_TOS.
+func setTOS(fd, tos int) error {
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		return fmt.Errorf("getsockname: %w", err)
+	}
+
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return setIPTOS(fd, tos)
+	case *unix.SockaddrInet6:
+		if net.IP(sa.Addr[:]).To4() != nil {
+			return setIPTOS(fd, tos)
+		}
+		err = unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos)
+		if err != nil {
+			return fmt.Errorf("set IPV6_TCLASS %#x: %w", tos, err)
+		}
+	}
+	return nil
+}
+
+func setIPTOS(fd, tos int) error {
+	err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_TOS, tos)
+	if err != nil {
+		return fmt.Errorf("set IP_TOS %#x: %w", tos, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package qos
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseDSCP(t *testing.T) {
+	tests := []struct {
+		dscp    string
+		want    uint8
+		wantErr bool
+	}{
+		{dscp: "ef", want: 0xb8},
+		{dscp: "AF21", want: 0x48},
+		{dscp: "cs1", want: 0x20},
+		{dscp: "le", want: 0x04},
+		{dscp: "46", want: 0xb8},
+		{dscp: "0x2e", want: 0xb8},
+		{dscp: "0"},
+		{dscp: "64", wantErr: true},
+		{dscp: "af5", wantErr: true},
+		{dscp: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dscp, func(t *testing.T) {
+			tos, err := ParseDSCP(tt.dscp)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tos)
+		})
+	}
+}
+
+func TestOptions_Apply(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := l.Accept()
+		accepted <- conn
+	}()
+
+	opts := Options{TOS: 0x48}
+	d := net.Dialer{Control: opts.Control}
+	conn, err := d.Dial("tcp4", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, 0x48, socketTOS(t, conn))
+
+	server := <-accepted
+	require.NotNil(t, server)
+	defer server.Close()
+	require.NoError(t, Options{TOS: 0xb8}.Apply(server))
+	assert.Equal(t, 0xb8, socketTOS(t, server))
+}
+
+func socketTOS(t *testing.T, conn net.Conn) int {
+	t.Helper()
+	c, err := conn.(syscall.Conn).SyscallConn()
+	require.NoError(t, err)
+
+	var tos int
+	require.NoError(t, c.Control(func(fd uintptr) {
+		tos, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS)
+	}))
+	require.NoError(t, err)
+	return tos
+}
+
+func TestOptions_Validate(t *testing.T) {
+	require.NoError(t, Options{TOS: 0x48}.Validate())
+	err := Options{Mark: 7}.Validate()
+	if markSupported {
+		require.NoError(t, err)
+	} else {
+		require.Error(t, err)
+	}
+}
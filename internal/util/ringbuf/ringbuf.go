@@ -0,0 +1,117 @@
+// Package ringbuf implements an in-memory ring buffer between a producer and
+// a consumer of a stream, which smooths out bursts of both.
+package ringbuf
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// NewReader returns Reader, which reads r ahead from a goroutine into a ring
+// buffer of size bytes, while the consumer is busy with previous data.
+func NewReader(r io.ReadCloser, size int) *Reader {
+	self := &Reader{r: r, buf: make([]byte, size)}
+	self.cond = sync.NewCond(&self.mu)
+	go self.fill()
+	return self
+}
+
+// Reader reads data of the ring buffer, filled by a goroutine from the
+// underlying reader. Read error of the underlying reader is returned after all
+// buffered data.
+type Reader struct {
+	r   io.ReadCloser
+	buf []byte
+
+	mu     sync.Mutex
+	cond   *sync.Cond // signaled, when data added, space freed or closed
+	start  int        // start of buffered data
+	n      int        // length of buffered data
+	err    error
+	closed bool
+}
+
+func (self *Reader) fill() {
+	for {
+		p := self.waitSpace()
+		if p == nil {
+			return
+		}
+
+		// Nobody touches free space, so read it without the lock.
+		n, err := self.r.Read(p)
+		self.mu.Lock()
+		self.n += n
+		if err != nil {
+			self.err = err
+		}
+		self.cond.Broadcast()
+		self.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitSpace blocks until the buffer has free space and returns continuous
+// part of it, or nil if the reader is closed.
+func (self *Reader) waitSpace() []byte {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for self.n == len(self.buf) && !self.closed {
+		self.cond.Wait()
+	}
+
+	switch {
+	case self.closed:
+		return nil
+	case self.n == 0:
+		// empty buffer, read as much as possible at once
+		self.start = 0
+	}
+
+	end := self.start + self.n
+	if end < len(self.buf) {
+		return self.buf[end:]
+	}
+	end -= len(self.buf)
+	return self.buf[end:self.start]
+}
+
+func (self *Reader) Read(p []byte) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for self.n == 0 && self.err == nil && !self.closed {
+		self.cond.Wait()
+	}
+
+	switch {
+	case self.closed:
+		return 0, os.ErrClosed
+	case self.n == 0:
+		return 0, self.err
+	}
+
+	end := min(self.start+self.n, len(self.buf))
+	n := copy(p, self.buf[self.start:end])
+	self.start += n
+	if self.start == len(self.buf) {
+		self.start = 0
+	}
+	self.n -= n
+	self.cond.Broadcast()
+	return n, nil
+}
+
+// Close closes the underlying reader, which unblocks the goroutine, if it's
+// waiting on Read.
+func (self *Reader) Close() error {
+	self.mu.Lock()
+	self.closed = true
+	self.cond.Broadcast()
+	self.mu.Unlock()
+	return self.r.Close() //nolint:wrapcheck // not needed
+}
@@ -0,0 +1,69 @@
+package ringbuf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	r := NewReader(io.NopCloser(iotest.HalfReader(bytes.NewReader(data))), 64)
+	b, err := io.ReadAll(iotest.HalfReader(r))
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+
+	n, err := r.Read(make([]byte, 1))
+	assert.Zero(t, n)
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, r.Close())
+}
+
+func TestReader_readAhead(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+
+	r := NewReader(pr, 100)
+	_, err = pw.Write(bytes.Repeat([]byte("a"), 100))
+	require.NoError(t, err)
+	// the buffer is full now, but the writer isn't blocked by the consumer
+	_, err = pw.Write(bytes.Repeat([]byte("b"), 10))
+	require.NoError(t, err)
+	require.NoError(t, pw.Close())
+
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.n == len(r.buf)
+	}, time.Second, time.Millisecond)
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, append(bytes.Repeat([]byte("a"), 100),
+		bytes.Repeat([]byte("b"), 10)...), b)
+	require.NoError(t, r.Close())
+}
+
+func TestReader_error(t *testing.T) {
+	r := NewReader(io.NopCloser(iotest.ErrReader(iotest.ErrTimeout)), 64)
+	_, err := io.ReadAll(r)
+	require.ErrorIs(t, err, iotest.ErrTimeout)
+	require.NoError(t, r.Close())
+}
+
+func TestReader_Close(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pw.Close()
+
+	r := NewReader(pr, 64)
+	require.NoError(t, r.Close())
+	_, err = r.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
@@ -0,0 +1,90 @@
+// Package timeoutio fails streams, which don't progress for some time, instead
+// of waiting for them forever.
+package timeoutio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrReadTimeout  = errors.New("stream read timeout")
+	ErrWriteTimeout = errors.New("stream write timeout")
+)
+
+// NewReader returns reader, which calls cancel with [ErrReadTimeout], if a Read
+// of r blocks longer than d, like a read of a stalled network stream. Zero d
+// returns r as is.
+func NewReader(r io.ReadCloser, d time.Duration,
+	cancel context.CancelCauseFunc,
+) io.ReadCloser {
+	if d <= 0 {
+		return r
+	}
+	return newReader(r, d, cancel, ErrReadTimeout, true)
+}
+
+// NewSource returns reader, which calls cancel with [ErrWriteTimeout], if its
+// consumer doesn't come back for next Read in d after previous Read, like a
+// stream, which can't be written into a stalled network. Zero d returns r as
+// is.
+func NewSource(r io.ReadCloser, d time.Duration,
+	cancel context.CancelCauseFunc,
+) io.ReadCloser {
+	if d <= 0 {
+		return r
+	}
+	return newReader(r, d, cancel, ErrWriteTimeout, false)
+}
+
+func newReader(r io.ReadCloser, d time.Duration,
+	cancel context.CancelCauseFunc, err error, inRead bool,
+) *reader {
+	self := &reader{ReadCloser: r, d: d, err: err, inRead: inRead}
+	self.timer = time.AfterFunc(d, func() {
+		self.fired.Store(true)
+		cancel(err)
+	})
+	if inRead {
+		self.timer.Stop()
+	}
+	return self
+}
+
+type reader struct {
+	io.ReadCloser
+
+	d      time.Duration
+	err    error
+	inRead bool // the timer runs inside of Read or between them
+	timer  *time.Timer
+	fired  atomic.Bool
+}
+
+func (self *reader) Read(p []byte) (int, error) {
+	if self.inRead {
+		self.timer.Reset(self.d)
+	} else {
+		self.timer.Stop()
+	}
+
+	n, err := self.ReadCloser.Read(p)
+	switch {
+	case self.fired.Load():
+		return n, fmt.Errorf("%w: no progress in %s", self.err, self.d)
+	case self.inRead:
+		self.timer.Stop()
+	case err == nil:
+		self.timer.Reset(self.d)
+	}
+	return n, err //nolint:wrapcheck // not needed
+}
+
+func (self *reader) Close() error {
+	self.timer.Stop()
+	return self.ReadCloser.Close() //nolint:wrapcheck // not needed
+}
@@ -0,0 +1,71 @@
+package timeoutio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReader_zero(t *testing.T) {
+	r := io.NopCloser(bytes.NewReader(nil))
+	assert.Equal(t, r, NewReader(r, 0, nil))
+	assert.Equal(t, r, NewSource(r, 0, nil))
+}
+
+func TestNewReader(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pw.Close()
+
+	ctx, cancel := context.WithCancelCause(t.Context())
+	r := NewReader(pr, 50*time.Millisecond, func(cause error) {
+		cancel(cause)
+		pr.Close()
+	})
+	defer r.Close()
+
+	_, err = pw.Write([]byte("foo"))
+	require.NoError(t, err)
+	b := make([]byte, 3)
+	_, err = io.ReadFull(r, b)
+	require.NoError(t, err)
+
+	// slow consumer doesn't fail the stream
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ctx.Err())
+
+	_, err = r.Read(b)
+	require.ErrorIs(t, err, ErrReadTimeout)
+	require.ErrorIs(t, context.Cause(ctx), ErrReadTimeout)
+}
+
+func TestNewSource(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(t.Context())
+	r := NewSource(io.NopCloser(bytes.NewReader([]byte("foobar"))),
+		50*time.Millisecond, cancel)
+	defer r.Close()
+
+	b := make([]byte, 3)
+	_, err := io.ReadFull(r, b)
+	require.NoError(t, err)
+	require.NoError(t, ctx.Err())
+
+	assert.Eventually(t, func() bool { return ctx.Err() != nil },
+		time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, context.Cause(ctx), ErrWriteTimeout)
+}
+
+func TestNewSource_Close(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(t.Context())
+	r := NewSource(io.NopCloser(bytes.NewReader(nil)), 50*time.Millisecond,
+		cancel)
+	require.NoError(t, r.Close())
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ctx.Err())
+}
@@ -0,0 +1,113 @@
+package zfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+const (
+	// BackendExec runs zfs commands.
+	BackendExec = "exec"
+	// BackendLZC calls libzfs_core for snapshot, destroy, hold, release and
+	// bookmark operations, instead of zfs commands. It requires build tag
+	// "lzc".
+	BackendLZC = "lzc"
+)
+
+var errLZCNotCompiled = errors.New(
+	`libzfs_core backend is not compiled in, rebuild with "-tags lzc"`)
+
+// useLZC is true, if BackendLZC is selected.
+var useLZC bool
+
+// SetBackend selects backend of zfs operations: BackendExec or BackendLZC.
+func SetBackend(name string) error {
+	switch name {
+	case "", BackendExec:
+		useLZC = false
+	case BackendLZC:
+		if !lzcCompiled {
+			return errLZCNotCompiled
+		} else if err := lzcInit(); err != nil {
+			return err
+		}
+		useLZC = true
+	default:
+		return fmt.Errorf("unknown zfs backend: %q", name)
+	}
+	return nil
+}
+
+// LZCError is an error of libzfs_core operation Op on dataset Name.
+type LZCError struct {
+	Op    string
+	Name  string
+	Errno syscall.Errno
+}
+
+func (self *LZCError) Error() string {
+	if self.Name == "" {
+		return "lzc " + self.Op + ": " + self.Errno.Error()
+	}
+	return fmt.Sprintf("lzc %s %q: %s", self.Op, self.Name, self.Errno)
+}
+
+func (self *LZCError) Unwrap() error { return self.Errno }
+
+// lzcDoesNotExist returns DatasetDoesNotExist of name, if err is ENOENT, or
+// err.
+func lzcDoesNotExist(err error, name string) error {
+	if errors.Is(err, syscall.ENOENT) {
+		return &DatasetDoesNotExist{Path: name}
+	}
+	return err
+}
+
+// lzcDestroySnapshots destroys snapshots of filesystem arg, like "fs@a,b,c",
+// by libzfs_core. Like ZFSDestroy, it returns DestroySnapshotsError, if some
+// of them can't be destroyed. In this case none of them are destroyed.
+func lzcDestroySnapshots(filesystem, arg string) error {
+	_, names, _ := strings.Cut(arg, "@")
+	snaps := strings.Split(names, ",")
+	for i, name := range snaps {
+		snaps[i] = filesystem + "@" + name
+	}
+
+	err := lzcDestroySnaps(snaps)
+	if err == nil {
+		return nil
+	}
+
+	dserr := DestroySnapshotsError{Filesystem: filesystem}
+	for _, lzcErr := range lzcErrors(err) {
+		_, name, ok := strings.Cut(lzcErr.Name, "@")
+		if !ok {
+			continue
+		}
+		dserr.RawLines = append(dserr.RawLines, lzcErr.Error())
+		dserr.Undestroyable = append(dserr.Undestroyable, name)
+		dserr.Reason = append(dserr.Reason, lzcErr.Errno.Error())
+	}
+	if len(dserr.Undestroyable) == 0 {
+		return lzcDoesNotExist(err, arg)
+	}
+	return &dserr
+}
+
+// lzcErrors returns all LZCError of err.
+func lzcErrors(err error) []*LZCError {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	lzcErrs := make([]*LZCError, 0, len(errs))
+	for _, err := range errs {
+		if lzcErr, ok := errors.AsType[*LZCError](err); ok {
+			lzcErrs = append(lzcErrs, lzcErr)
+		}
+	}
+	return lzcErrs
+}
@@ -0,0 +1,52 @@
+package zfs
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetBackend(t *testing.T) {
+	t.Cleanup(func() { useLZC = false })
+
+	require.NoError(t, SetBackend(BackendExec))
+	assert.False(t, useLZC)
+	require.ErrorContains(t, SetBackend("foo"), "unknown zfs backend")
+
+	if !lzcCompiled {
+		require.ErrorIs(t, SetBackend(BackendLZC), errLZCNotCompiled)
+		assert.False(t, useLZC)
+	}
+}
+
+func TestLZCErrors(t *testing.T) {
+	busy := &LZCError{Op: "destroy", Name: "zroot/a@snap1", Errno: syscall.EBUSY}
+	exist := &LZCError{Op: "destroy", Name: "zroot/a@snap2", Errno: syscall.EEXIST}
+
+	assert.Equal(t, []*LZCError{busy}, lzcErrors(busy))
+	assert.Equal(t, []*LZCError{busy, exist},
+		lzcErrors(errors.Join(busy, errors.New("other"), exist)))
+	assert.Empty(t, lzcErrors(errors.New("other")))
+
+	require.ErrorIs(t, busy, syscall.EBUSY)
+	assert.Equal(t, `lzc destroy "zroot/a@snap1": `+syscall.EBUSY.Error(),
+		busy.Error())
+	assert.Equal(t, "lzc destroy: "+syscall.EBUSY.Error(),
+		(&LZCError{Op: "destroy", Errno: syscall.EBUSY}).Error())
+}
+
+func TestLZCDoesNotExist(t *testing.T) {
+	require.NoError(t, lzcDoesNotExist(nil, "zroot/a"))
+
+	err := lzcDoesNotExist(&LZCError{Op: "snapshot", Errno: syscall.ENOENT},
+		"zroot/a")
+	var notExist *DatasetDoesNotExist
+	require.ErrorAs(t, err, &notExist)
+	assert.Equal(t, "zroot/a", notExist.Path)
+
+	err = &LZCError{Op: "snapshot", Errno: syscall.EBUSY}
+	assert.Same(t, err, lzcDoesNotExist(err, "zroot/a"))
+}
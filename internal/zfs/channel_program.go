@@ -0,0 +1,75 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+// ErrChannelProgramsNotSupported is returned by functions, which run zfs
+// channel programs, if zfs can't run them, like old zfs binary or not root
+// user.
+var ErrChannelProgramsNotSupported = errors.New(
+	"zfs channel programs are not supported")
+
+var channelProgramsUnsupportedRE = regexp.MustCompile(
+	`(?i)unrecognized command|invalid command|permission denied|operation not permitted|not supported`)
+
+// runChannelProgram runs program in pool by `zfs program` with args and
+// returns its output. With jsonOut the output is JSON, like
+// {"return": ...}. It returns error wrapping ErrChannelProgramsNotSupported,
+// if zfs can't run channel programs.
+func runChannelProgram(ctx context.Context, pool string, program []byte,
+	jsonOut bool, args ...string,
+) ([]byte, error) {
+	f, err := os.CreateTemp("", "zrepl-program-*.lua")
+	if err != nil {
+		return nil, fmt.Errorf("zfs program: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(program); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("zfs program: write %q: %w", f.Name(), err)
+	} else if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("zfs program: close %q: %w", f.Name(), err)
+	}
+
+	cmdArgs := make([]string, 0, len(args)+4)
+	cmdArgs = append(cmdArgs, "program")
+	if jsonOut {
+		cmdArgs = append(cmdArgs, "-j")
+	}
+	cmdArgs = append(cmdArgs, pool, f.Name())
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, cmdArgs...)
+	if jsonOut {
+		output, err := cmd.Output()
+		if err != nil {
+			if ee, ok := errors.AsType[*exec.ExitError](err); ok {
+				return nil, channelProgramError(err, ee.Stderr)
+			}
+			return nil, fmt.Errorf("zfs program: %w", err)
+		}
+		return output, nil
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, channelProgramError(err, output)
+	}
+	return output, nil
+}
+
+func channelProgramError(err error, output []byte) error {
+	zfsErr := NewZfsError(err, output)
+	if channelProgramsUnsupportedRE.Match(output) {
+		return fmt.Errorf("%w: %w", ErrChannelProgramsNotSupported, zfsErr)
+	}
+	return zfsErr
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"syscall"
 
 	"github.com/dsh2dsh/zrepl/internal/config/env"
 	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
@@ -40,6 +41,16 @@ func ZFSHold(ctx context.Context, fs string, v FilesystemVersion, tag string,
 	}
 
 	fullPath := v.FullPath(fs)
+	defer versionsCache.Invalidate(fs, false)
+	if useLZC {
+		err := lzcHold(fullPath, tag)
+		if err == nil || errors.Is(err, syscall.EEXIST) {
+			return nil
+		}
+		return fmt.Errorf("cannot hold %q: %w", fullPath,
+			lzcDoesNotExist(err, fullPath))
+	}
+
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, "hold", tag, fullPath).
 		WithLogError(false)
 	output, err := cmd.CombinedOutput()
@@ -90,6 +101,15 @@ func ZFSHolds(ctx context.Context, fs, snap string) ([]string, error) {
 // Idempotent: if the hold doesn't exist, this is not an error
 func ZFSRelease(ctx context.Context, tag, snap string) error {
 	var noSuchTagLines, otherLines []string
+	defer invalidateVersions(snap, false)
+	if useLZC {
+		err := lzcRelease(snap, tag)
+		if err == nil || errors.Is(err, syscall.ESRCH) {
+			return nil
+		}
+		return fmt.Errorf("cannot release hold with tag %q: %w", tag, err)
+	}
+
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, "release", tag, snap).
 		WithLogError(false)
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,217 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+var (
+	jsonSupportedOnce sync.Once
+	jsonSupportedVal  bool
+)
+
+var zfsVersionRE = regexp.MustCompile(`(?m)^zfs-(\d+)\.(\d+)`)
+
+// jsonSupported returns true, if zfs list and zfs get support JSON output by
+// -j, like OpenZFS 2.3 and later. It checks `zfs version` once.
+func jsonSupported() bool {
+	jsonSupportedOnce.Do(func() {
+		if env.Values.ZFSJSONDisable {
+			return
+		}
+		cmd := zfscmd.CommandContext(context.Background(), ZfsBin, "version").
+			WithLogError(false)
+		output, err := cmd.Output()
+		if err != nil {
+			debug("zfs version: %s", err)
+			return
+		}
+		jsonSupportedVal = jsonVersion(output)
+		debug("zfs version: json output supported: %v", jsonSupportedVal)
+	})
+	return jsonSupportedVal
+}
+
+// jsonVersion returns true, if output of `zfs version` is 2.3 or later.
+func jsonVersion(output []byte) bool {
+	m := zfsVersionRE.FindSubmatch(output)
+	if m == nil {
+		return false
+	}
+	major, err := strconv.Atoi(string(m[1]))
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(string(m[2]))
+	if err != nil {
+		return false
+	}
+	return major > 2 || (major == 2 && minor >= 3)
+}
+
+// jsonDataset is a dataset from JSON output of zfs list or zfs get.
+type jsonDataset struct {
+	Name       string                  `json:"name"`
+	Properties map[string]jsonProperty `json:"properties"`
+}
+
+type jsonProperty struct {
+	Value  jsonValue `json:"value"`
+	Source struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	} `json:"source"`
+}
+
+// jsonValue is a value of property, which is a string or a number with
+// --json-int.
+type jsonValue string
+
+func (self *jsonValue) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return fmt.Errorf("unmarshal property value: %w", err)
+		}
+		*self = jsonValue(s)
+		return nil
+	}
+	*self = jsonValue(b)
+	return nil
+}
+
+// Field returns value of property name, like zfs list -H prints it.
+func (self *jsonDataset) Field(name string) string {
+	if p, ok := self.Properties[name]; ok {
+		return string(p.Value)
+	} else if name == "name" {
+		return self.Name
+	}
+	return "-"
+}
+
+// Fields returns values of properties, like zfs list -H prints them.
+func (self *jsonDataset) Fields(properties []string) []string {
+	fields := make([]string, len(properties))
+	for i, name := range properties {
+		fields[i] = self.Field(name)
+	}
+	return fields
+}
+
+// sourceString returns source of property, like zfs get -H prints it.
+func (self *jsonProperty) sourceString() string {
+	switch self.Source.Type {
+	case "NONE":
+		return "-"
+	case "DEFAULT":
+		return "default"
+	case "LOCAL":
+		return "local"
+	case "TEMPORARY":
+		return "temporary"
+	case "INHERITED":
+		return "inherited from " + self.Source.Data
+	case "RECEIVED":
+		return "received"
+	}
+	return self.Source.Type
+}
+
+var errStopDecode = errors.New("stop decode")
+
+// decodeJSONDatasets decodes JSON output of zfs list or zfs get from r and
+// calls fn for every dataset in order of output, until fn returns false.
+func decodeJSONDatasets(r io.Reader,
+	fn func(ds *jsonDataset) (error, bool),
+) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		if errors.Is(err, io.EOF) {
+			// no output, like zfs failed
+			return nil
+		}
+		return err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("decode zfs json: %w", err)
+		} else if key != "datasets" {
+			if err := dec.Decode(new(json.RawMessage)); err != nil {
+				return fmt.Errorf("decode zfs json %v: %w", key, err)
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		for dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return fmt.Errorf("decode zfs json dataset name: %w", err)
+			}
+			var ds jsonDataset
+			if err := dec.Decode(&ds); err != nil {
+				return fmt.Errorf("decode zfs json dataset: %w", err)
+			}
+			if err, ok := fn(&ds); err != nil {
+				return err
+			} else if !ok {
+				return errStopDecode
+			}
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("decode zfs json: %w", err)
+	} else if t != delim {
+		return fmt.Errorf("decode zfs json: expected %q, got %v", delim, t)
+	}
+	return nil
+}
+
+// decodeCmdOutput decodes JSON output of cmd from r, like scanCmdOutput does
+// it for text output.
+func decodeCmdOutput(cmd *zfscmd.Cmd, r io.Reader, stderrBuf *bytes.Buffer,
+	fn func(ds *jsonDataset) (error, bool),
+) error {
+	var fnErr bool
+	err := decodeJSONDatasets(r, func(ds *jsonDataset) (error, bool) {
+		err, ok := fn(ds)
+		fnErr = err != nil
+		return err, ok
+	})
+	if err != nil {
+		_, _ = io.Copy(io.Discard, r)
+	}
+	cmdErr := cmd.Wait()
+
+	switch {
+	case errors.Is(err, errStopDecode):
+		return nil
+	case fnErr:
+	case cmdErr != nil:
+		// output of failed zfs is not interesting
+		return NewZfsError(cmdErr, stderrBuf.Bytes())
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package zfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{output: "zfs-2.3.0-1\nzfs-kmod-2.3.0-1\n", want: true},
+		{output: "zfs-2.4.1-FreeBSD_g1234\nzfs-kmod-2.4.1\n", want: true},
+		{output: "zfs-3.0.0\n", want: true},
+		{output: "zfs-2.2.6-FreeBSD_g33174af15\nzfs-kmod-2.2.6\n"},
+		{output: "zfs-0.8.6-1\n"},
+		{output: "unrecognized command 'version'\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, jsonVersion([]byte(tt.output)), tt.output)
+	}
+}
+
+const testListJSON = `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "zroot/b": {
+      "name": "zroot/b",
+      "type": "FILESYSTEM",
+      "pool": "zroot",
+      "properties": {
+        "guid": {"value": "123", "source": {"type": "NONE", "data": "-"}},
+        "zrepl:note": {"value": "a\tb\nc", "source": {"type": "LOCAL", "data": "-"}}
+      }
+    },
+    "zroot/a": {
+      "name": "zroot/a",
+      "type": "FILESYSTEM",
+      "pool": "zroot",
+      "properties": {
+        "guid": {"value": 456, "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}
+`
+
+func TestDecodeJSONDatasets(t *testing.T) {
+	props := []string{"name", "guid", "zrepl:note"}
+	var got [][]string
+	err := decodeJSONDatasets(strings.NewReader(testListJSON),
+		func(ds *jsonDataset) (error, bool) {
+			got = append(got, ds.Fields(props))
+			return nil, true
+		})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"zroot/b", "123", "a\tb\nc"},
+		{"zroot/a", "456", "-"},
+	}, got)
+
+	got = nil
+	err = decodeJSONDatasets(strings.NewReader(testListJSON),
+		func(ds *jsonDataset) (error, bool) {
+			got = append(got, ds.Fields(props))
+			return nil, false
+		})
+	require.ErrorIs(t, err, errStopDecode)
+	assert.Len(t, got, 1)
+
+	require.NoError(t, decodeJSONDatasets(strings.NewReader(""),
+		func(ds *jsonDataset) (error, bool) { return nil, true }))
+
+	require.Error(t, decodeJSONDatasets(strings.NewReader(`{"datasets": [`),
+		func(ds *jsonDataset) (error, bool) { return nil, true }))
+}
+
+func TestJSONProperty_sourceString(t *testing.T) {
+	tests := []struct {
+		typ, data string
+		want      string
+	}{
+		{typ: "NONE", data: "-", want: "-"},
+		{typ: "DEFAULT", data: "-", want: "default"},
+		{typ: "LOCAL", data: "-", want: "local"},
+		{typ: "TEMPORARY", data: "-", want: "temporary"},
+		{typ: "INHERITED", data: "zroot", want: "inherited from zroot"},
+		{typ: "RECEIVED", data: "-", want: "received"},
+	}
+	for _, tt := range tests {
+		var p jsonProperty
+		p.Source.Type, p.Source.Data = tt.typ, tt.data
+		s := p.sourceString()
+		assert.Equal(t, tt.want, s)
+		_, err := parsePropertySource(s)
+		require.NoError(t, err)
+	}
+}
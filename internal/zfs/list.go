@@ -13,13 +13,22 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
+// NewListCmd returns zfs list of props. Its output is JSON, if zfs supports it,
+// and must be parsed by ListIter.
 func NewListCmd(ctx context.Context, props, zfsArgs []string) *zfscmd.Cmd {
-	args := make([]string, 0, 4+len(zfsArgs))
-	args = append(args, "list", "-H", "-p", "-o", strings.Join(props, ","))
+	args := make([]string, 0, 5+len(zfsArgs))
+	if jsonSupported() {
+		args = append(args, "list", "-j", "-p")
+	} else {
+		args = append(args, "list", "-H", "-p")
+	}
+	args = append(args, "-o", strings.Join(props, ","))
 	args = append(args, zfsArgs...)
 	return zfscmd.CommandContext(ctx, ZfsBin, args...)
 }
 
+// ListIter returns iterator over values of properties of datasets from output
+// of cmd, returned by NewListCmd.
 func ListIter(ctx context.Context, properties []string,
 	notExistHint *DatasetPath, cmd *zfscmd.Cmd,
 ) iter.Seq2[[]string, error] {
@@ -36,16 +45,26 @@ func ListIter(ctx context.Context, properties []string,
 			return
 		}
 
-		err = scanCmdOutput(cmd, stdout, &stderrBuf,
-			func(s string) (error, bool) {
-				fields := strings.SplitN(s, "\t", len(properties)+1)
-				if len(fields) != len(properties) {
-					return fmt.Errorf("unexpected output from zfs list: %q", s), false
-				} else if ctx.Err() != nil {
-					return nil, false
-				}
-				return nil, yield(fields, nil)
-			})
+		if jsonSupported() {
+			err = decodeCmdOutput(cmd, stdout, &stderrBuf,
+				func(ds *jsonDataset) (error, bool) {
+					if ctx.Err() != nil {
+						return nil, false
+					}
+					return nil, yield(ds.Fields(properties), nil)
+				})
+		} else {
+			err = scanCmdOutput(cmd, stdout, &stderrBuf,
+				func(s string) (error, bool) {
+					fields := strings.SplitN(s, "\t", len(properties)+1)
+					if len(fields) != len(properties) {
+						return fmt.Errorf("unexpected output from zfs list: %q", s), false
+					} else if ctx.Err() != nil {
+						return nil, false
+					}
+					return nil, yield(fields, nil)
+				})
+		}
 		if err != nil {
 			if notExistHint != nil {
 				err = maybeDatasetNotExists(cmd, notExistHint.ToString(), err)
@@ -103,6 +122,21 @@ func maybeDatasetNotExists(cmd *zfscmd.Cmd, path string, err error) error {
 	return err
 }
 
+// userProperties returns values of names from fields, without unset
+// properties, which zfs list reports as "-".
+func userProperties(names, fields []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	props := make(map[string]string, len(names))
+	for i, name := range names {
+		if fields[i] != "-" {
+			props[name] = fields[i]
+		}
+	}
+	return props
+}
+
 func listVersions(ctx context.Context, props []string, fs *DatasetPath,
 	cmd *zfscmd.Cmd,
 ) ([]FilesystemVersion, error) {
@@ -123,6 +157,7 @@ func listVersions(ctx context.Context, props []string, fs *DatasetPath,
 		if err != nil {
 			return nil, err
 		}
+		v.Properties = userProperties(props[5:], fields[5:])
 		snaps = append(snaps, v)
 	}
 	return snaps, nil
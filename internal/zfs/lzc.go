@@ -0,0 +1,243 @@
+//go:build lzc && cgo
+
+package zfs
+
+/*
+#cgo LDFLAGS: -lzfs_core -lnvpair
+
+#include <stdint.h>
+#include <stdlib.h>
+
+// Declarations of libzfs_core and libnvpair, so headers of zfs are not
+// required for building.
+typedef struct nvlist nvlist_t;
+typedef struct nvpair nvpair_t;
+
+int libzfs_core_init(void);
+
+int nvlist_alloc(nvlist_t **, unsigned int, int);
+void nvlist_free(nvlist_t *);
+int nvlist_add_boolean(nvlist_t *, const char *);
+int nvlist_add_string(nvlist_t *, const char *, const char *);
+int nvlist_add_nvlist(nvlist_t *, const char *, nvlist_t *);
+nvpair_t *nvlist_next_nvpair(nvlist_t *, nvpair_t *);
+char *nvpair_name(nvpair_t *);
+int nvpair_value_int32(nvpair_t *, int32_t *);
+
+int lzc_snapshot(nvlist_t *, nvlist_t *, nvlist_t **);
+int lzc_destroy_snaps(nvlist_t *, int, nvlist_t **);
+int lzc_hold(nvlist_t *, int, nvlist_t **);
+int lzc_release(nvlist_t *, nvlist_t **);
+int lzc_bookmark(nvlist_t *, nvlist_t **);
+
+#define NV_UNIQUE_NAME 0x1
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"syscall"
+	"unsafe"
+)
+
+const lzcCompiled = true
+
+func lzcInit() error {
+	if rc := C.libzfs_core_init(); rc != 0 {
+		return fmt.Errorf("libzfs_core_init: %w", syscall.Errno(rc))
+	}
+	return nil
+}
+
+// nvlist is an owned nvlist_t of libnvpair.
+type nvlist struct {
+	p *C.nvlist_t
+}
+
+func newNvlist() (*nvlist, error) {
+	var p *C.nvlist_t
+	if rc := C.nvlist_alloc(&p, C.NV_UNIQUE_NAME, 0); rc != 0 {
+		return nil, fmt.Errorf("nvlist_alloc: %w", syscall.Errno(rc))
+	}
+	return &nvlist{p: p}, nil
+}
+
+func (self *nvlist) Free() {
+	if self != nil && self.p != nil {
+		C.nvlist_free(self.p)
+		self.p = nil
+	}
+}
+
+func (self *nvlist) AddBoolean(name string) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	if rc := C.nvlist_add_boolean(self.p, cname); rc != 0 {
+		return fmt.Errorf("nvlist_add_boolean %q: %w", name, syscall.Errno(rc))
+	}
+	return nil
+}
+
+func (self *nvlist) AddString(name, value string) error {
+	cname, cvalue := C.CString(name), C.CString(value)
+	defer C.free(unsafe.Pointer(cname))
+	defer C.free(unsafe.Pointer(cvalue))
+	if rc := C.nvlist_add_string(self.p, cname, cvalue); rc != 0 {
+		return fmt.Errorf("nvlist_add_string %q: %w", name, syscall.Errno(rc))
+	}
+	return nil
+}
+
+// AddNvlist adds a copy of value.
+func (self *nvlist) AddNvlist(name string, value *nvlist) error {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	if rc := C.nvlist_add_nvlist(self.p, cname, value.p); rc != 0 {
+		return fmt.Errorf("nvlist_add_nvlist %q: %w", name, syscall.Errno(rc))
+	}
+	return nil
+}
+
+// errnos returns errors of errlist of lzc functions, like name => errno.
+func errnos(errlist *C.nvlist_t) map[string]syscall.Errno {
+	if errlist == nil {
+		return nil
+	}
+	m := make(map[string]syscall.Errno)
+	for p := C.nvlist_next_nvpair(errlist, nil); p != nil; p = C.nvlist_next_nvpair(errlist, p) {
+		var v C.int32_t
+		if C.nvpair_value_int32(p, &v) == 0 {
+			m[C.GoString(C.nvpair_name(p))] = syscall.Errno(v)
+		}
+	}
+	return m
+}
+
+// lzcResult returns error of lzc function op, which returned rc and errlist.
+func lzcResult(op string, rc C.int, errlist *C.nvlist_t) error {
+	if rc == 0 {
+		return nil
+	}
+	failed := errnos(errlist)
+	if len(failed) == 0 {
+		return &LZCError{Op: op, Errno: syscall.Errno(rc)}
+	}
+	errs := make([]error, 0, len(failed))
+	for _, name := range slices.Sorted(maps.Keys(failed)) {
+		errs = append(errs, &LZCError{Op: op, Name: name, Errno: failed[name]})
+	}
+	return errors.Join(errs...)
+}
+
+func booleans(names []string) (*nvlist, error) {
+	nvl, err := newNvlist()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := nvl.AddBoolean(name); err != nil {
+			nvl.Free()
+			return nil, err
+		}
+	}
+	return nvl, nil
+}
+
+func lzcSnapshot(snaps []string, props map[string]string) error {
+	snapsNvl, err := booleans(snaps)
+	if err != nil {
+		return err
+	}
+	defer snapsNvl.Free()
+
+	var propsNvl *nvlist
+	if len(props) != 0 {
+		if propsNvl, err = newNvlist(); err != nil {
+			return err
+		}
+		defer propsNvl.Free()
+		for k, v := range props {
+			if err := propsNvl.AddString(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	var cprops, errlist *C.nvlist_t
+	if propsNvl != nil {
+		cprops = propsNvl.p
+	}
+	rc := C.lzc_snapshot(snapsNvl.p, cprops, &errlist)
+	defer C.nvlist_free(errlist)
+	return lzcResult("snapshot", rc, errlist)
+}
+
+func lzcDestroySnaps(snaps []string) error {
+	snapsNvl, err := booleans(snaps)
+	if err != nil {
+		return err
+	}
+	defer snapsNvl.Free()
+
+	var errlist *C.nvlist_t
+	rc := C.lzc_destroy_snaps(snapsNvl.p, 0, &errlist)
+	defer C.nvlist_free(errlist)
+	return lzcResult("destroy", rc, errlist)
+}
+
+func lzcHold(snap, tag string) error {
+	holds, err := newNvlist()
+	if err != nil {
+		return err
+	}
+	defer holds.Free()
+	if err := holds.AddString(snap, tag); err != nil {
+		return err
+	}
+
+	var errlist *C.nvlist_t
+	rc := C.lzc_hold(holds.p, -1, &errlist)
+	defer C.nvlist_free(errlist)
+	return lzcResult("hold", rc, errlist)
+}
+
+func lzcRelease(snap, tag string) error {
+	tags, err := booleans([]string{tag})
+	if err != nil {
+		return err
+	}
+	defer tags.Free()
+
+	holds, err := newNvlist()
+	if err != nil {
+		return err
+	}
+	defer holds.Free()
+	if err := holds.AddNvlist(snap, tags); err != nil {
+		return err
+	}
+
+	var errlist *C.nvlist_t
+	rc := C.lzc_release(holds.p, &errlist)
+	defer C.nvlist_free(errlist)
+	return lzcResult("release", rc, errlist)
+}
+
+func lzcBookmark(snap, bookmark string) error {
+	bookmarks, err := newNvlist()
+	if err != nil {
+		return err
+	}
+	defer bookmarks.Free()
+	if err := bookmarks.AddString(bookmark, snap); err != nil {
+		return err
+	}
+
+	var errlist *C.nvlist_t
+	rc := C.lzc_bookmark(bookmarks.p, &errlist)
+	defer C.nvlist_free(errlist)
+	return lzcResult("bookmark", rc, errlist)
+}
@@ -0,0 +1,19 @@
+//go:build !lzc || !cgo
+
+package zfs
+
+const lzcCompiled = false
+
+func lzcInit() error { return errLZCNotCompiled }
+
+func lzcSnapshot(snaps []string, props map[string]string) error {
+	return errLZCNotCompiled
+}
+
+func lzcDestroySnaps(snaps []string) error { return errLZCNotCompiled }
+
+func lzcHold(snap, tag string) error { return errLZCNotCompiled }
+
+func lzcRelease(snap, tag string) error { return errLZCNotCompiled }
+
+func lzcBookmark(snap, bookmark string) error { return errLZCNotCompiled }
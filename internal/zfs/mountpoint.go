@@ -0,0 +1,85 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+func ZFSInherit(ctx context.Context, fs, prop string) error {
+	defer invalidateVersions(fs, false)
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, "inherit", prop, fs)
+	if stdio, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(err, stdio)
+	}
+	return nil
+}
+
+func ZFSMount(ctx context.Context, fs string) error {
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, "mount", fs)
+	if stdio, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(err, stdio)
+	}
+	return nil
+}
+
+// MountpointCollides returns true if mounting something at path would shadow
+// existing content: path is a mount point of another filesystem or a non-empty
+// directory.
+func MountpointCollides(path string) (bool, error) {
+	path = filepath.Clean(path)
+	if path == "/" {
+		return true, nil
+	}
+
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("stat mountpoint: %w", err)
+	} else if !fi.IsDir() {
+		return true, nil
+	}
+
+	if mounted, err := isMountPoint(path, fi); err != nil {
+		return false, err
+	} else if mounted {
+		return true, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("open mountpoint: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); errors.Is(err, io.EOF) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("read mountpoint: %w", err)
+	}
+	return true, nil
+}
+
+func isMountPoint(path string, fi fs.FileInfo) (bool, error) {
+	parent, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false, fmt.Errorf("stat parent of mountpoint: %w", err)
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, nil
+	}
+	parentSt, ok := parent.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, nil
+	}
+	return st.Dev != parentSt.Dev, nil
+}
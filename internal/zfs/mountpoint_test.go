@@ -0,0 +1,38 @@
+package zfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountpointCollides(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.Mkdir(empty, 0o755))
+	nonEmpty := filepath.Join(dir, "non-empty")
+	require.NoError(t, os.Mkdir(nonEmpty, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(nonEmpty, "foo"), nil, 0o644))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/", want: true},
+		{path: filepath.Join(dir, "not-exists")},
+		{path: empty},
+		{path: nonEmpty, want: true},
+		{path: filepath.Join(nonEmpty, "foo"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := MountpointCollides(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	parallelSend parallelLimit
+	parallelRecv parallelLimit
+)
+
+// SetParallel limits number of zfs send and zfs recv processes, which run at
+// the same time. Zero means no limit. It must be called before any zfs send or
+// recv started.
+func SetParallel(send, recv int) {
+	parallelSend.set(send)
+	parallelRecv.set(recv)
+}
+
+type parallelLimit struct {
+	sem *semaphore.Weighted
+}
+
+func (self *parallelLimit) set(n int) {
+	if n > 0 {
+		self.sem = semaphore.NewWeighted(int64(n))
+	} else {
+		self.sem = nil
+	}
+}
+
+// acquire waits for a free slot and returns a func, which releases it. The
+// returned func can be called more than once.
+func (self *parallelLimit) acquire(ctx context.Context, what string,
+) (func(), error) {
+	if self.sem == nil {
+		return func() {}, nil
+	}
+
+	if !self.sem.TryAcquire(1) {
+		debug("%s: wait for parallel limit", what)
+		if err := self.sem.Acquire(ctx, 1); err != nil {
+			return nil, fmt.Errorf("%s: wait for parallel limit: %w", what,
+				context.Cause(ctx))
+		}
+	}
+	return sync.OnceFunc(func() { self.sem.Release(1) }), nil
+}
@@ -0,0 +1,36 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelLimit(t *testing.T) {
+	var limit parallelLimit
+	release, err := limit.acquire(t.Context(), "test")
+	require.NoError(t, err)
+	release()
+
+	limit.set(1)
+	release, err = limit.acquire(t.Context(), "test")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limit.acquire(ctx, "test")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release() // must not release twice
+	release2, err := limit.acquire(t.Context(), "test")
+	require.NoError(t, err)
+	assert.False(t, limit.sem.TryAcquire(1))
+	release2()
+
+	limit.set(0)
+	assert.Nil(t, limit.sem)
+}
@@ -0,0 +1,98 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PropertiesCacheTTL is max age of cached results of zfs get in contexts,
+// returned by WithPropertiesCache. Zero disables the cache.
+var PropertiesCacheTTL time.Duration
+
+type propertiesCacheCtxKey struct{}
+
+// WithPropertiesCache returns ctx, which caches results of zfs get of single
+// datasets for PropertiesCacheTTL, so one replication run doesn't get the same
+// properties again and again. Every mutation made by zrepl invalidates cached
+// properties of the mutated dataset, like it does for versionsCache. It
+// returns ctx as is, if the cache is disabled or ctx already has it.
+func WithPropertiesCache(ctx context.Context) context.Context {
+	if PropertiesCacheTTL <= 0 || propertiesCacheFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, propertiesCacheCtxKey{},
+		newPropertiesCache(PropertiesCacheTTL))
+}
+
+func propertiesCacheFrom(ctx context.Context) *propertiesCache {
+	cache, _ := ctx.Value(propertiesCacheCtxKey{}).(*propertiesCache)
+	return cache
+}
+
+func newPropertiesCache(ttl time.Duration) *propertiesCache {
+	return &propertiesCache{
+		ttl:   ttl,
+		items: make(map[string]propertiesCacheItem),
+	}
+}
+
+type propertiesCache struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	items map[string]propertiesCacheItem
+}
+
+type propertiesCacheItem struct {
+	gen     uint64
+	expires time.Time
+	props   *ZFSProperties
+}
+
+// propertiesCacheKey returns key of props of path with allowedSources.
+func propertiesCacheKey(path string, props []string,
+	allowedSources PropertySource,
+) string {
+	return path + "\x00" + strings.Join(props, ",") + "\x00" +
+		strconv.FormatUint(uint64(allowedSources), 10)
+}
+
+// Load returns cached properties by key of path, if they are not expired and
+// not invalidated. Otherwise it returns current generation of the dataset,
+// which owns path, and it must be passed to Store.
+func (self *propertiesCache) Load(path, key string, now time.Time,
+) (*ZFSProperties, uint64, bool) {
+	fs := path
+	if i := strings.IndexAny(path, "@#"); i != -1 {
+		fs = path[:i]
+	}
+	gen := versionsCache.Generation(fs)
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	item, ok := self.items[key]
+	if !ok || item.gen != gen || !now.Before(item.expires) {
+		if ok {
+			delete(self.items, key)
+		}
+		return nil, gen, false
+	}
+	return item.props, gen, true
+}
+
+// Store saves properties by key for ttl of the cache. gen is a generation,
+// returned by Load.
+func (self *propertiesCache) Store(key string, gen uint64,
+	props *ZFSProperties, now time.Time,
+) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.items[key] = propertiesCacheItem{
+		gen:     gen,
+		expires: now.Add(self.ttl),
+		props:   props,
+	}
+}
@@ -0,0 +1,56 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPropertiesCache(t *testing.T) {
+	oldTTL := PropertiesCacheTTL
+	t.Cleanup(func() { PropertiesCacheTTL = oldTTL })
+
+	ctx := context.Background()
+	PropertiesCacheTTL = 0
+	assert.Nil(t, propertiesCacheFrom(WithPropertiesCache(ctx)))
+
+	PropertiesCacheTTL = time.Minute
+	ctx = WithPropertiesCache(ctx)
+	cache := propertiesCacheFrom(ctx)
+	require.NotNil(t, cache)
+	assert.Same(t, cache, propertiesCacheFrom(WithPropertiesCache(ctx)))
+}
+
+func TestPropertiesCache(t *testing.T) {
+	const path = "zroot/test-properties-cache@snap"
+	cache := newPropertiesCache(time.Minute)
+	key := propertiesCacheKey(path, []string{"guid"}, SourceAny)
+	assert.NotEqual(t, key,
+		propertiesCacheKey(path, []string{"guid"}, SourceLocal))
+
+	now := time.Now()
+	_, gen, ok := cache.Load(path, key, now)
+	require.False(t, ok)
+
+	props := NewZFSProperties(path, 0)
+	cache.Store(key, gen, props, now)
+	cached, _, ok := cache.Load(path, key, now)
+	require.True(t, ok)
+	assert.Same(t, props, cached)
+
+	_, _, ok = cache.Load(path, key, now.Add(time.Minute))
+	assert.False(t, ok, "expired")
+
+	cache.Store(key, gen, props, now)
+	invalidateVersions(path, false)
+	_, newGen, ok := cache.Load(path, key, now)
+	assert.False(t, ok, "invalidated")
+	assert.NotEqual(t, gen, newGen)
+
+	cache.Store(key, gen, props, now)
+	_, _, ok = cache.Load(path, key, now)
+	assert.False(t, ok, "stored with old generation")
+}
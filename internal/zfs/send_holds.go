@@ -0,0 +1,55 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+// ErrSendHoldsNotSupported is returned by validation of send args with Holds,
+// if zfs binary doesn't support `zfs send -h`.
+var ErrSendHoldsNotSupported = errors.New(
+	"zfs binary does not support sending holds (zfs send -h)")
+
+var sendUsageFlagsRE = regexp.MustCompile(`(?m)^\s*send \[-([[:alpha:]]+)\]`)
+
+// sendHolds caches detection of `zfs send -h` support.
+var sendHolds struct {
+	mu        sync.Mutex
+	detected  bool
+	supported bool
+}
+
+// ZFSSendHoldsSupported returns true, if zfs binary supports `zfs send -h`.
+// It's detected once by flags of `zfs send` in usage of zfs binary.
+func ZFSSendHoldsSupported(ctx context.Context) (bool, error) {
+	sendHolds.mu.Lock()
+	defer sendHolds.mu.Unlock()
+	if sendHolds.detected {
+		return sendHolds.supported, nil
+	}
+
+	// zfs send without args prints its usage and exits with non-zero status.
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, "send").WithLogError(false)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if _, ok := errors.AsType[*exec.ExitError](err); !ok {
+			return false, fmt.Errorf("detect zfs send -h: %w", err)
+		}
+	}
+
+	sendHolds.supported = sendFlagsHaveHolds(string(output))
+	sendHolds.detected = true
+	return sendHolds.supported, nil
+}
+
+func sendFlagsHaveHolds(usage string) bool {
+	m := sendUsageFlagsRE.FindStringSubmatch(usage)
+	return m != nil && strings.ContainsRune(m[1], 'h')
+}
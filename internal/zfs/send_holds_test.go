@@ -0,0 +1,25 @@
+package zfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendFlagsHaveHolds(t *testing.T) {
+	assert.True(t, sendFlagsHaveHolds(`missing snapshot argument
+usage:
+	send [-DLPbcehnpsVvw] [-i|-I snapshot]
+	     [-R [-X dataset[,dataset]...]]     <snapshot>
+	send [-DnVvPLecw] [-i snapshot|bookmark] <filesystem|volume|snapshot>
+`))
+	assert.False(t, sendFlagsHaveHolds(`usage:
+	send [-DnPpRvLec] [-[iI] snapshot] <snapshot>
+`))
+	assert.False(t, sendFlagsHaveHolds("zfs: command not found"))
+}
+
+func TestZFSSendFlags_holds(t *testing.T) {
+	flags := ZFSSendFlags{Holds: true}
+	assert.Equal(t, []string{"-h"}, flags.buildSendFlagsUnchecked())
+}
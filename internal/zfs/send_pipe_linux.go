@@ -0,0 +1,31 @@
+//go:build linux
+
+package zfs
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// setPipeSize changes capacity of pipe f using F_SETPIPE_SZ. The kernel rounds
+// size up to a power of two pages and limits it by
+// /proc/sys/fs/pipe-max-size for unprivileged users.
+func setPipeSize(f *os.File, size int) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("syscall conn of %q: %w", f.Name(), err)
+	}
+
+	var fcntlErr error
+	err = rc.Control(func(fd uintptr) {
+		_, fcntlErr = unix.FcntlInt(fd, unix.F_SETPIPE_SZ, size)
+	})
+	if err != nil {
+		return fmt.Errorf("control %q: %w", f.Name(), err)
+	} else if fcntlErr != nil {
+		return fmt.Errorf("fcntl F_SETPIPE_SZ: %w", fcntlErr)
+	}
+	return nil
+}
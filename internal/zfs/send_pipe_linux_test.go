@@ -0,0 +1,29 @@
+package zfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestSetPipeSize(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pr.Close()
+	defer pw.Close()
+
+	size := 256 << 10
+	require.NoError(t, setPipeSize(pr, size))
+
+	rc, err := pr.SyscallConn()
+	require.NoError(t, err)
+	var got int
+	require.NoError(t, rc.Control(func(fd uintptr) {
+		got, err = unix.FcntlInt(fd, unix.F_GETPIPE_SZ, 0)
+	}))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, got, size)
+}
@@ -0,0 +1,150 @@
+package zfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+)
+
+// newSendReader wraps stdout of zfs send pipeline r for reading it with
+// ZREPL_ZFS_SEND_READ_SIZE sized reads, rounded up to the page size.
+//
+// With ZREPL_ZFS_SEND_READAHEAD > 0 a goroutine reads r ahead into so many
+// buffers, while the consumer is busy writing previous buffers to the
+// network. Otherwise small reads of the consumer are coalesced into reads of
+// read size and large reads go to r directly.
+//
+// With ZREPL_ZFS_SEND_PIPE_SIZE > 0 the capacity of the pipe is changed, if r
+// is a pipe and the OS supports it.
+func newSendReader(r io.ReadCloser) io.ReadCloser {
+	if size := env.Values.ZFSSendPipeSize; size > 0 {
+		if f, ok := r.(*os.File); ok {
+			if err := setPipeSize(f, size); err != nil {
+				debug("sendReader: set pipe size %d: %s", size, err)
+			}
+		}
+	}
+
+	size := alignReadSize(env.Values.ZFSSendReadSize)
+	switch {
+	case size <= 0:
+		return r
+	case env.Values.ZFSSendReadahead > 0:
+		return newReadahead(r, size, env.Values.ZFSSendReadahead)
+	}
+	return &bufferedReader{Reader: bufio.NewReaderSize(r, size), r: r}
+}
+
+// SetSendBuffers changes size of reads of zfs send streams to chunk, if it's
+// positive, and reads streams ahead into so many buffers, which fit into
+// poolMax bytes, if it's positive.
+func SetSendBuffers(chunk, poolMax int) {
+	if chunk > 0 {
+		env.Values.ZFSSendReadSize = chunk
+	}
+	if size := alignReadSize(env.Values.ZFSSendReadSize); size > 0 &&
+		poolMax > 0 {
+		env.Values.ZFSSendReadahead = poolMax / size
+	}
+}
+
+func alignReadSize(size int) int {
+	if size <= 0 {
+		return 0
+	}
+	pageSize := os.Getpagesize()
+	return (size + pageSize - 1) / pageSize * pageSize
+}
+
+type bufferedReader struct {
+	*bufio.Reader
+	r io.ReadCloser
+}
+
+func (self *bufferedReader) Close() error { return self.r.Close() } //nolint:wrapcheck // not needed
+
+func newReadahead(r io.ReadCloser, size, n int) *readahead {
+	self := &readahead{
+		r:      r,
+		chunks: make(chan readaheadChunk, n),
+		free:   make(chan []byte, n),
+		done:   make(chan struct{}),
+	}
+	for range n {
+		self.free <- make([]byte, size)
+	}
+	go self.run()
+	return self
+}
+
+// readahead reads r ahead into a fixed number of buffers from a goroutine.
+type readahead struct {
+	r      io.ReadCloser
+	chunks chan readaheadChunk
+	free   chan []byte
+	done   chan struct{}
+
+	buf []byte
+	cur []byte
+	err error
+
+	closeOnce sync.Once
+}
+
+type readaheadChunk struct {
+	buf []byte
+	n   int
+	err error
+}
+
+func (self *readahead) run() {
+	for {
+		var buf []byte
+		select {
+		case buf = <-self.free:
+		case <-self.done:
+			return
+		}
+
+		n, err := self.r.Read(buf)
+		select {
+		case self.chunks <- readaheadChunk{buf: buf, n: n, err: err}:
+		case <-self.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (self *readahead) Read(p []byte) (int, error) {
+	for len(self.cur) == 0 {
+		if self.err != nil {
+			return 0, self.err
+		} else if self.buf != nil {
+			self.free <- self.buf
+			self.buf = nil
+		}
+
+		select {
+		case c := <-self.chunks:
+			self.buf, self.cur, self.err = c.buf, c.buf[:c.n], c.err
+		case <-self.done:
+			return 0, os.ErrClosed
+		}
+	}
+
+	n := copy(p, self.cur)
+	self.cur = self.cur[n:]
+	return n, nil
+}
+
+// Close closes r, which unblocks the goroutine, if it's waiting on Read.
+func (self *readahead) Close() error {
+	self.closeOnce.Do(func() { close(self.done) })
+	return self.r.Close() //nolint:wrapcheck // not needed
+}
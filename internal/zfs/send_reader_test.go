@@ -0,0 +1,73 @@
+package zfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+)
+
+func TestAlignReadSize(t *testing.T) {
+	pageSize := os.Getpagesize()
+	assert.Equal(t, 0, alignReadSize(0))
+	assert.Equal(t, 0, alignReadSize(-1))
+	assert.Equal(t, pageSize, alignReadSize(1))
+	assert.Equal(t, pageSize, alignReadSize(pageSize))
+	assert.Equal(t, 2*pageSize, alignReadSize(pageSize+1))
+}
+
+func TestSetSendBuffers(t *testing.T) {
+	saved := env.Values
+	t.Cleanup(func() { env.Values = saved })
+
+	pageSize := os.Getpagesize()
+	SetSendBuffers(0, 0)
+	assert.Equal(t, saved.ZFSSendReadSize, env.Values.ZFSSendReadSize)
+	assert.Equal(t, saved.ZFSSendReadahead, env.Values.ZFSSendReadahead)
+
+	SetSendBuffers(4*pageSize, 0)
+	assert.Equal(t, 4*pageSize, env.Values.ZFSSendReadSize)
+	assert.Equal(t, saved.ZFSSendReadahead, env.Values.ZFSSendReadahead)
+
+	SetSendBuffers(0, 16*pageSize+1)
+	assert.Equal(t, 4*pageSize, env.Values.ZFSSendReadSize)
+	assert.Equal(t, 4, env.Values.ZFSSendReadahead)
+}
+
+func TestReadahead(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	r := newReadahead(io.NopCloser(iotest.HalfReader(bytes.NewReader(data))),
+		64, 3)
+	b, err := io.ReadAll(iotest.OneByteReader(r))
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+
+	n, err := r.Read(make([]byte, 1))
+	assert.Zero(t, n)
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, r.Close())
+}
+
+func TestReadahead_error(t *testing.T) {
+	r := newReadahead(io.NopCloser(iotest.ErrReader(iotest.ErrTimeout)), 64, 2)
+	_, err := io.ReadAll(r)
+	require.ErrorIs(t, err, iotest.ErrTimeout)
+	require.NoError(t, r.Close())
+}
+
+func TestReadahead_Close(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pw.Close()
+
+	r := newReadahead(pr, 64, 2)
+	require.NoError(t, r.Close())
+	_, err = r.Read(make([]byte, 1))
+	require.Error(t, err)
+}
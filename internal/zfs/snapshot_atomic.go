@@ -0,0 +1,166 @@
+package zfs
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// snapshotAtomicProgram is a channel program, which creates snapshots in one
+// txg.
+//
+//go:embed snapshot_atomic.lua
+var snapshotAtomicProgram []byte
+
+// AtomicSnapshot is a snapshot of FS, created by ZFSSnapshotAtomic.
+type AtomicSnapshot struct {
+	FS        *DatasetPath
+	Name      string
+	Recursive bool
+	Props     map[string]string
+}
+
+func (self *AtomicSnapshot) String() string {
+	return self.FS.ToString() + "@" + self.Name
+}
+
+// ZFSSnapshotAtomic creates snaps of datasets of pool in one txg of the pool
+// by `zfs program`, so they are consistent with each other. Snapshots are
+// checked before creation and none of them are created, if a check fails. But
+// creation itself isn't rolled back: if a snapshot or its property fails
+// anyway, snapshots before it stay created and the returned
+// *SnapshotAtomicError reports, which of snaps were created. It returns error
+// wrapping ErrChannelProgramsNotSupported, if zfs can't run channel programs.
+func ZFSSnapshotAtomic(ctx context.Context, pool string, snaps []AtomicSnapshot,
+) error {
+	args, err := snapshotAtomicArgs(pool, snaps)
+	if err != nil {
+		return fmt.Errorf("zfs program: %w", err)
+	}
+
+	defer func() {
+		for _, s := range snaps {
+			versionsCache.Invalidate(s.FS.ToString(), s.Recursive)
+		}
+	}()
+
+	output, err := runChannelProgram(ctx, pool, snapshotAtomicProgram, true,
+		args...)
+	if err != nil {
+		return err
+	}
+	return parseSnapshotAtomic(output)
+}
+
+// snapshotAtomicArgs returns arguments of snapshotAtomicProgram, which
+// describe snaps. Snapshots must be unique, including descendants of
+// recursive ones.
+func snapshotAtomicArgs(pool string, snaps []AtomicSnapshot) ([]string, error) {
+	args := make([]string, 0, len(snaps))
+	for i := range snaps {
+		s := &snaps[i]
+		fs := s.FS.ToString()
+		if fs != pool && !strings.HasPrefix(fs, pool+"/") {
+			return nil, fmt.Errorf("dataset %q doesn't belong to pool %q", fs,
+				pool)
+		}
+
+		name := s.String()
+		if err := EntityNamecheck(name, EntityTypeSnapshot); err != nil {
+			return nil, err
+		} else if err := snapshotAtomicDuplicate(snaps[:i], s); err != nil {
+			return nil, err
+		} else if s.Recursive {
+			name = "+" + name
+		}
+		args = append(args, name)
+
+		for _, k := range slices.Sorted(maps.Keys(s.Props)) {
+			if !strings.Contains(k, ":") || strings.ContainsAny(k, "=@") ||
+				strings.HasPrefix(k, "-") {
+				return nil, fmt.Errorf("invalid user property %q of %q", k, name)
+			}
+			args = append(args, k+"="+s.Props[k])
+		}
+	}
+	return args, nil
+}
+
+// snapshotAtomicDuplicate returns error, if s and one of prev create the same
+// snapshot, directly or as a descendant of recursive snapshot.
+func snapshotAtomicDuplicate(prev []AtomicSnapshot, s *AtomicSnapshot) error {
+	for i := range prev {
+		p := &prev[i]
+		if p.Name != s.Name {
+			continue
+		}
+		switch {
+		case p.FS.Equal(s.FS),
+			p.Recursive && s.FS.HasPrefix(p.FS),
+			s.Recursive && p.FS.HasPrefix(s.FS):
+			return fmt.Errorf("duplicate snapshot %q and %q", p.String(),
+				s.String())
+		}
+	}
+	return nil
+}
+
+// parseSnapshotAtomic returns error from JSON output of snapshotAtomicProgram,
+// if it failed to create a snapshot.
+func parseSnapshotAtomic(output []byte) error {
+	var out struct {
+		Return struct {
+			Created  map[string]bool `json:"created"`
+			Failed   string          `json:"failed"`
+			Error    int64           `json:"error"`
+			Property string          `json:"property"`
+		} `json:"return"`
+	}
+	if err := json.Unmarshal(output, &out); err != nil {
+		return fmt.Errorf("zfs program: parse output %q: %w", output, err)
+	}
+
+	r := &out.Return
+	if r.Failed == "" {
+		return nil
+	}
+
+	return &SnapshotAtomicError{
+		Snapshot: r.Failed,
+		Property: r.Property,
+		Err:      syscall.Errno(r.Error),
+		Created:  r.Created,
+	}
+}
+
+// SnapshotAtomicError is returned by ZFSSnapshotAtomic, if it failed to create
+// Snapshot or to set its Property, after it created some of snapshots. Created
+// are names of these snapshots, like "fs@name".
+type SnapshotAtomicError struct {
+	Snapshot string
+	Property string
+	Err      error
+	Created  map[string]bool
+}
+
+func (self *SnapshotAtomicError) Error() string {
+	if self.Property != "" {
+		return fmt.Sprintf("zfs program: cannot set property %q of %q: %s",
+			self.Property, self.Snapshot, self.Err)
+	}
+	return fmt.Sprintf("zfs program: cannot create snapshot %q: %s",
+		self.Snapshot, self.Err)
+}
+
+func (self *SnapshotAtomicError) Unwrap() error { return self.Err }
+
+// IsCreated returns true, if s was created with all its descendants and
+// properties.
+func (self *SnapshotAtomicError) IsCreated(s *AtomicSnapshot) bool {
+	return self.Created[s.String()]
+}
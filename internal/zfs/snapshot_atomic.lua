@@ -0,0 +1,95 @@
+-- Creates snapshots, passed as arguments, in one txg of the pool.
+--
+-- Every argument is either a snapshot "fs@name", or a user property
+-- "key=value" of the previous snapshot. Snapshots with "+" prefix are
+-- recursive, like zfs snapshot -r: their descendants get the same snapshot
+-- name and properties.
+--
+-- Changes of the sync phase are not rolled back on errors. So everything is
+-- checked before it and, if a snapshot or a property fails anyway, the
+-- program stops and returns, which arguments were created with all their
+-- descendants and properties, which snapshot failed and its error code:
+--
+--   {created = {["fs@name"] = true, ...}, failed = "fs@name", error = code,
+--    property = "key"}
+--
+-- failed, error and property are nil on success.
+
+local argv = (...)["argv"]
+local snaps = {}
+-- number of snapshots of every argument, which aren't created yet
+local pending = {}
+
+local function add(arg, fs, name, props, recursive)
+  table.insert(snaps, {arg = arg, name = fs .. "@" .. name, props = props})
+  pending[arg] = (pending[arg] or 0) + 1
+  if recursive then
+    for child in zfs.list.children(fs) do
+      add(arg, child, name, props, true)
+    end
+  end
+end
+
+local last = nil
+local function flush()
+  if last ~= nil then
+    local fs, name = string.match(last.snap, "^([^@]+)@(.+)$")
+    if fs == nil then
+      error("invalid snapshot name: " .. last.snap)
+    end
+    add(last.snap, fs, name, last.props, last.recursive)
+  end
+end
+
+for _, arg in ipairs(argv) do
+  local k, v = string.match(arg, "^([^=@]+)=(.*)$")
+  if k ~= nil then
+    if last == nil then
+      error("property without snapshot: " .. arg)
+    end
+    last.props[k] = v
+  else
+    flush()
+    local recursive = string.sub(arg, 1, 1) == "+"
+    if recursive then
+      arg = string.sub(arg, 2)
+    end
+    last = {snap = arg, props = {}, recursive = recursive}
+  end
+end
+flush()
+
+local seen = {}
+for _, s in ipairs(snaps) do
+  if seen[s.name] then
+    error("duplicate snapshot " .. s.name)
+  end
+  seen[s.name] = true
+  local err = zfs.check.snapshot(s.name)
+  if err ~= 0 then
+    error("cannot create snapshot " .. s.name .. ": error " .. err)
+  end
+  if next(s.props) ~= nil and zfs.sync.set_prop == nil then
+    error("setting properties by channel programs is not supported")
+  end
+end
+
+local created = {}
+for _, s in ipairs(snaps) do
+  local err = zfs.sync.snapshot(s.name)
+  if err ~= 0 then
+    return {created = created, failed = s.name, error = err}
+  end
+  for k, v in pairs(s.props) do
+    err = zfs.sync.set_prop(s.name, k, v)
+    if err ~= 0 then
+      return {created = created, failed = s.name, error = err, property = k}
+    end
+  end
+  pending[s.arg] = pending[s.arg] - 1
+  if pending[s.arg] == 0 then
+    created[s.arg] = true
+  end
+end
+
+return {created = created}
@@ -0,0 +1,110 @@
+package zfs
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotAtomicArgs(t *testing.T) {
+	path := func(s string) *DatasetPath {
+		p, err := NewDatasetPath(s)
+		require.NoError(t, err)
+		return p
+	}
+
+	args, err := snapshotAtomicArgs("zroot", []AtomicSnapshot{
+		{FS: path("zroot/db"), Name: "zrepl_1", Props: map[string]string{
+			"zrepl:job": "snap", "com.example:note": "a=b",
+		}},
+		{FS: path("zroot/wal"), Name: "zrepl_1", Recursive: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"zroot/db@zrepl_1", "com.example:note=a=b", "zrepl:job=snap",
+		"+zroot/wal@zrepl_1",
+	}, args)
+
+	_, err = snapshotAtomicArgs("zroot", []AtomicSnapshot{
+		{FS: path("tank/db"), Name: "zrepl_1"},
+	})
+	require.ErrorContains(t, err, "doesn't belong to pool")
+
+	_, err = snapshotAtomicArgs("zroot", []AtomicSnapshot{
+		{FS: path("zroot/db"), Name: "zrepl@1"},
+	})
+	require.Error(t, err)
+
+	_, err = snapshotAtomicArgs("zroot", []AtomicSnapshot{
+		{FS: path("zroot/db"), Name: "zrepl_1", Props: map[string]string{
+			"compression": "off",
+		}},
+	})
+	require.ErrorContains(t, err, "invalid user property")
+
+	duplicates := [][]AtomicSnapshot{
+		{
+			{FS: path("zroot/db"), Name: "zrepl_1"},
+			{FS: path("zroot/db"), Name: "zrepl_1"},
+		},
+		{
+			{FS: path("zroot/db"), Name: "zrepl_1", Recursive: true},
+			{FS: path("zroot/db/wal"), Name: "zrepl_1"},
+		},
+		{
+			{FS: path("zroot/db/wal"), Name: "zrepl_1"},
+			{FS: path("zroot"), Name: "zrepl_1", Recursive: true},
+		},
+	}
+	for _, snaps := range duplicates {
+		_, err = snapshotAtomicArgs("zroot", snaps)
+		require.ErrorContains(t, err, "duplicate snapshot")
+	}
+
+	_, err = snapshotAtomicArgs("zroot", []AtomicSnapshot{
+		{FS: path("zroot/db"), Name: "zrepl_1", Recursive: true},
+		{FS: path("zroot/db/wal"), Name: "zrepl_2"},
+		{FS: path("zroot/dbx"), Name: "zrepl_1"},
+	})
+	require.NoError(t, err)
+}
+
+func TestZFSSnapshotAtomic(t *testing.T) {
+	oldBin := ZfsBin
+	t.Cleanup(func() { ZfsBin = oldBin })
+
+	path := func(s string) *DatasetPath {
+		p, err := NewDatasetPath(s)
+		require.NoError(t, err)
+		return p
+	}
+	snaps := []AtomicSnapshot{
+		{FS: path("zroot/a"), Name: "snap"},
+		{FS: path("zroot/b"), Name: "snap", Recursive: true},
+		{FS: path("zroot/c"), Name: "snap"},
+	}
+
+	ZfsBin = fakeZfs(t, `echo '{"return":{"created":{"zroot/a@snap":true,"zroot/b@snap":true,"zroot/c@snap":true}}}'`)
+	require.NoError(t, ZFSSnapshotAtomic(t.Context(), "zroot", snaps))
+
+	ZfsBin = fakeZfs(t, `echo '{"return":{"created":{"zroot/a@snap":true},"failed":"zroot/b/x@snap","error":28}}'`)
+	err := ZFSSnapshotAtomic(t.Context(), "zroot", snaps)
+	partial, ok := errors.AsType[*SnapshotAtomicError](err)
+	require.True(t, ok, err)
+	require.ErrorIs(t, err, syscall.ENOSPC)
+	assert.Equal(t, "zroot/b/x@snap", partial.Snapshot)
+	assert.True(t, partial.IsCreated(&snaps[0]))
+	assert.False(t, partial.IsCreated(&snaps[1]))
+	assert.False(t, partial.IsCreated(&snaps[2]))
+
+	ZfsBin = fakeZfs(t, `echo '{"return":{"created":{},"failed":"zroot/a@snap","error":28,"property":"zrepl:job"}}'`)
+	err = ZFSSnapshotAtomic(t.Context(), "zroot", snaps)
+	require.ErrorContains(t, err, `cannot set property "zrepl:job"`)
+
+	ZfsBin = fakeZfs(t, `echo "unrecognized command 'program'" 1>&2; exit 2`)
+	err = ZFSSnapshotAtomic(t.Context(), "zroot", snaps)
+	require.ErrorIs(t, err, ErrChannelProgramsNotSupported)
+}
@@ -0,0 +1,61 @@
+package zfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+)
+
+// Splicer is a stream of zfs send, which zfs recv reads without copying it
+// through the daemon, like a stream of local jobs. Wrappers of streams
+// implement it by delegating to the wrapped stream.
+type Splicer interface {
+	// SplicePipe returns stdout pipe of zfs send or nil, if the stream must be
+	// read by Read.
+	SplicePipe() *os.File
+	// Spliced accounts n bytes, spliced from the pipe.
+	Spliced(n int64)
+}
+
+// spliceChunk is how many bytes are spliced between accounting of them.
+const spliceChunk = 1 << 20
+
+// spliceStream returns a pipe, which zfs recv reads stream from, and starts a
+// goroutine, which splices the stream into the pipe and sends the result to
+// returned channel. It returns nil pipe, if stream can't be spliced.
+//
+// On Linux the bytes are moved between pipes by splice(2), without copying
+// them into user space. Other systems copy them, see spliceN.
+func spliceStream(stream io.ReadCloser) (*os.File, <-chan error, error) {
+	s, ok := stream.(Splicer)
+	if !ok || env.Values.ZFSSpliceDisable {
+		return nil, nil, nil
+	}
+	src := s.SplicePipe()
+	if src == nil {
+		return nil, nil, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("create splice pipe: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- splice(w, src, s) }()
+	return r, done, nil
+}
+
+func splice(dst, src *os.File, s Splicer) error {
+	defer dst.Close()
+	for {
+		n, err := spliceN(dst, src, spliceChunk)
+		s.Spliced(n)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("splice stream: %w", err)
+		}
+	}
+}
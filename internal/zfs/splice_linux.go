@@ -0,0 +1,56 @@
+//go:build linux
+
+package zfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// spliceN moves up to n bytes from pipe src into pipe dst by splice(2), without
+// copying them into user space. It returns io.EOF, if src has no more bytes.
+//
+// Both pipes are switched into blocking mode, because splice(2) of
+// non-blocking pipes can't tell, which of them isn't ready.
+func spliceN(dst, src *os.File, n int64) (written int64, err error) {
+	src.Fd()
+	dst.Fd()
+	err = controlFD(src, func(rfd int) error {
+		return controlFD(dst, func(wfd int) error {
+			for written < n {
+				m, err := unix.Splice(rfd, nil, wfd, nil, int(n-written),
+					unix.SPLICE_F_MOVE)
+				switch {
+				case errors.Is(err, unix.EINTR):
+					continue
+				case err != nil:
+					return os.NewSyscallError("splice", err)
+				case m == 0:
+					return io.EOF
+				}
+				written += m
+			}
+			return nil
+		})
+	})
+	return written, err
+}
+
+// controlFD calls fn with file descriptor of f, which isn't closed, until fn
+// returns.
+func controlFD(f *os.File, fn func(fd int) error) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("syscall conn of %q: %w", f.Name(), err)
+	}
+
+	var fnErr error
+	if err := rc.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return fmt.Errorf("control %q: %w", f.Name(), err)
+	}
+	return fnErr
+}
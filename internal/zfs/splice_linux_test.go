@@ -0,0 +1,60 @@
+package zfs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestSpliceN(t *testing.T) {
+	srcR, srcW, err := os.Pipe()
+	require.NoError(t, err)
+	defer srcR.Close()
+	dstR, dstW, err := os.Pipe()
+	require.NoError(t, err)
+	defer dstR.Close()
+	defer dstW.Close()
+
+	_, err = srcW.WriteString("foobar")
+	require.NoError(t, err)
+	require.NoError(t, srcW.Close())
+
+	n, err := spliceN(dstW, srcR, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	n, err = spliceN(dstW, srcR, spliceChunk)
+	require.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, int64(2), n)
+	require.NoError(t, dstW.Close())
+
+	b, err := io.ReadAll(dstR)
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", string(b))
+}
+
+func TestSpliceN_notPipes(t *testing.T) {
+	// splice(2) needs a pipe on one side at least, so an error here proves,
+	// bytes aren't copied through user space.
+	dir := t.TempDir()
+	src, err := os.Create(filepath.Join(dir, "src"))
+	require.NoError(t, err)
+	defer src.Close()
+	_, err = src.WriteString("foobar")
+	require.NoError(t, err)
+	_, err = src.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	dst, err := os.Create(filepath.Join(dir, "dst"))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	n, err := spliceN(dst, src, spliceChunk)
+	require.ErrorIs(t, err, unix.EINVAL)
+	assert.Zero(t, n)
+}
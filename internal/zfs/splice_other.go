@@ -0,0 +1,14 @@
+//go:build !linux
+
+package zfs
+
+import (
+	"io"
+	"os"
+)
+
+// spliceN copies up to n bytes from pipe src into pipe dst, because splice(2)
+// is supported on Linux only. It returns io.EOF, if src has no more bytes.
+func spliceN(dst, src *os.File, n int64) (int64, error) {
+	return io.CopyN(dst, src, n) //nolint:wrapcheck // io.EOF must not be wrapped
+}
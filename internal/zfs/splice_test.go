@@ -0,0 +1,74 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsh2dsh/zrepl/internal/util/bytecounter"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+func newTestSpliceStream(t *testing.T, name string, args ...string,
+) *SendStream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	cmd := zfscmd.CommandContext(ctx, name, args...)
+	var stderrBuf bytes.Buffer
+	pipeReader, err := cmd.PipeTo(nil, nil, &stderrBuf)
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	stream := NewSendStream(cmd, newSendReader(pipeReader), &stderrBuf, cancel)
+	stream.pipe, _ = pipeReader.(*os.File)
+	stream.testMode = true
+	return stream
+}
+
+func TestSpliceStream(t *testing.T) {
+	want, err := exec.Command("seq", "300000").Output()
+	require.NoError(t, err)
+
+	stream := newTestSpliceStream(t, "seq", "300000")
+	counter := bytecounter.NewReadCloser(stream)
+	r, spliced, err := spliceStream(counter)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, <-spliced)
+	assert.Equal(t, want, b)
+	assert.Equal(t, uint64(len(want)), counter.Count())
+	require.NoError(t, stream.Close())
+}
+
+func TestSpliceStream_notSplicer(t *testing.T) {
+	r, spliced, err := spliceStream(io.NopCloser(bytes.NewReader(nil)))
+	require.NoError(t, err)
+	assert.Nil(t, r)
+	assert.Nil(t, spliced)
+}
+
+func TestSendStream_SplicePipe(t *testing.T) {
+	stream := newTestSpliceStream(t, "echo", "foobar")
+	assert.NotNil(t, stream.SplicePipe())
+
+	_, err := stream.Read(make([]byte, 1))
+	require.NoError(t, err)
+	assert.Nil(t, stream.SplicePipe(), "stream must not be spliced after Read")
+
+	_, err = io.Copy(io.Discard, stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	assert.Nil(t, stream.SplicePipe())
+}
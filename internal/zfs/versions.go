@@ -9,6 +9,9 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dsh2dsh/zrepl/internal/config/env"
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
 
 type VersionType string
@@ -97,6 +100,10 @@ type FilesystemVersion struct {
 
 	// userrefs field (snapshots only)
 	UserRefs OptionUint64
+
+	// Values of user properties requested by
+	// ListFilesystemVersionsOptions.Properties. Unset properties are missing.
+	Properties map[string]string
 }
 
 type OptionUint64 struct {
@@ -231,6 +238,9 @@ type ListFilesystemVersionsOptions struct {
 	// which types should be returned
 	// nil or len(0) means any prefix matches
 	Types VersionTypeSet
+
+	// user properties listed together with every version
+	Properties []string
 }
 
 func (o *ListFilesystemVersionsOptions) typesFlagArgs() string {
@@ -256,24 +266,18 @@ func ZFSListFilesystemVersions(ctx context.Context, fs *DatasetPath,
 		prom.ZFSListFilesystemVersionDuration.WithLabelValues(fs.ToString()))
 	defer promTimer.ObserveDuration()
 
-	props := []string{"name", "guid", "createtxg", "creation", "userrefs"}
+	props := append([]string{"name", "guid", "createtxg", "creation", "userrefs"},
+		options.Properties...)
 	cmd := NewListCmd(ctx, props, []string{
 		"-r", "-d", "1",
 		"-t", options.typesFlagArgs(),
 		"-s", "createtxg", fs.ToString(),
 	})
 
-	v, err, _ := sg.Do(cmd.String(), func() (any, error) {
-		snaps, err := listVersions(ctx, props, fs, cmd)
-		if err != nil {
-			return nil, err
-		}
-		return &snaps, nil
-	})
+	allSnaps, err := listVersionsCached(ctx, props, fs, cmd)
 	if err != nil {
-		return nil, err //nolint:wrapcheck // already wrapped
+		return nil, err
 	}
-	allSnaps := v.(*[]FilesystemVersion)
 
 	snaps := []FilesystemVersion{}
 	for _, s := range *allSnaps {
@@ -284,6 +288,32 @@ func ZFSListFilesystemVersions(ctx context.Context, fs *DatasetPath,
 	return snaps, nil
 }
 
+func listVersionsCached(ctx context.Context, props []string, fs *DatasetPath,
+	cmd *zfscmd.Cmd,
+) (*[]FilesystemVersion, error) {
+	name, key := fs.ToString(), cmd.String()
+	cached, gen, ok := versionsCache.Load(name, key, time.Now())
+	if ok {
+		return cached, nil
+	}
+
+	// Don't join a listing, which started before the last invalidation.
+	sgKey := key + "\x00" + strconv.FormatUint(gen, 10)
+	v, err, _ := sg.Do(sgKey, func() (any, error) {
+		snaps, err := listVersions(ctx, props, fs, cmd)
+		if err != nil {
+			return nil, err
+		}
+		versionsCache.Store(name, key, gen, &snaps, time.Now(),
+			env.Values.ZFSListCacheTTL)
+		return &snaps, nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+	return v.(*[]FilesystemVersion), nil
+}
+
 func ZFSGetFilesystemVersion(ctx context.Context, ds string,
 ) (v FilesystemVersion, err error) {
 	props, err := zfsGet(ctx, ds,
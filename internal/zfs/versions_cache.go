@@ -0,0 +1,128 @@
+package zfs
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionsCache keeps results of ZFSListFilesystemVersions for a short time,
+// so jobs woken up by the same cron tick list snapshots of overlapping datasets
+// only once. Every mutation made by zrepl invalidates listings of the mutated
+// dataset.
+var versionsCache = newListCache()
+
+func newListCache() *listCache {
+	return &listCache{
+		gens:  make(map[string]uint64),
+		items: make(map[string]map[string]listCacheItem),
+	}
+}
+
+type listCache struct {
+	mu sync.Mutex
+	// gens counts invalidations of every dataset, which was listed at least
+	// once. A listing started before an invalidation is not stored.
+	gens  map[string]uint64
+	items map[string]map[string]listCacheItem
+}
+
+type listCacheItem struct {
+	gen      uint64
+	expires  time.Time
+	versions *[]FilesystemVersion
+}
+
+// Load returns cached versions of fs listed by key, if they are not expired
+// yet. Otherwise it returns current generation of fs, which must be passed to
+// Store.
+func (self *listCache) Load(fs, key string, now time.Time,
+) (*[]FilesystemVersion, uint64, bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	gen, ok := self.gens[fs]
+	if !ok {
+		self.gens[fs] = gen
+	}
+
+	item, ok := self.items[fs][key]
+	if !ok || item.gen != gen || !now.Before(item.expires) {
+		return nil, gen, false
+	}
+	return item.versions, gen, true
+}
+
+// Generation returns current generation of fs, which changes on every
+// invalidation of fs.
+func (self *listCache) Generation(fs string) uint64 {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	gen, ok := self.gens[fs]
+	if !ok {
+		self.gens[fs] = gen
+	}
+	return gen
+}
+
+// Store saves versions of fs listed by key for ttl, unless fs was invalidated
+// after gen returned by Load.
+func (self *listCache) Store(fs, key string, gen uint64,
+	versions *[]FilesystemVersion, now time.Time, ttl time.Duration,
+) {
+	if ttl <= 0 {
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.gens[fs] != gen {
+		return
+	}
+
+	items, ok := self.items[fs]
+	if !ok {
+		items = make(map[string]listCacheItem)
+		self.items[fs] = items
+	}
+	items[key] = listCacheItem{
+		gen:      gen,
+		expires:  now.Add(ttl),
+		versions: versions,
+	}
+}
+
+// Invalidate drops cached listings of fs and, if recursive, of all its
+// children.
+func (self *listCache) Invalidate(fs string, recursive bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if _, ok := self.gens[fs]; ok {
+		self.gens[fs]++
+		delete(self.items, fs)
+	}
+
+	if !recursive {
+		return
+	}
+
+	prefix := fs + "/"
+	for name := range self.gens {
+		if strings.HasPrefix(name, prefix) {
+			self.gens[name]++
+			delete(self.items, name)
+		}
+	}
+}
+
+// invalidateVersions drops cached listings of the dataset, which owns
+// snapshot, bookmark or filesystem path.
+func invalidateVersions(path string, recursive bool) {
+	if i := strings.IndexAny(path, "@#"); i != -1 {
+		path = path[:i]
+	}
+	versionsCache.Invalidate(path, recursive)
+}
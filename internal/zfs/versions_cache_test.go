@@ -0,0 +1,108 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCache(t *testing.T) {
+	const fs, key = "zroot/foo", "zfs list zroot/foo"
+	cache := newListCache()
+	now := time.Now()
+	versions := &[]FilesystemVersion{{Name: "a", Type: Snapshot}}
+
+	_, gen, ok := cache.Load(fs, key, now)
+	require.False(t, ok)
+	cache.Store(fs, key, gen, versions, now, time.Second)
+
+	got, _, ok := cache.Load(fs, key, now)
+	require.True(t, ok)
+	assert.Same(t, versions, got)
+
+	_, _, ok = cache.Load(fs, "zfs list zroot/foo -t snapshot", now)
+	assert.False(t, ok)
+
+	_, _, ok = cache.Load(fs, key, now.Add(time.Second))
+	assert.False(t, ok, "expired")
+}
+
+func TestListCache_disabled(t *testing.T) {
+	const fs, key = "zroot/foo", "zfs list zroot/foo"
+	cache := newListCache()
+	now := time.Now()
+
+	_, gen, _ := cache.Load(fs, key, now)
+	cache.Store(fs, key, gen, &[]FilesystemVersion{}, now, 0)
+	_, _, ok := cache.Load(fs, key, now)
+	assert.False(t, ok)
+}
+
+func TestListCache_Invalidate(t *testing.T) {
+	const key = "zfs list"
+	cache := newListCache()
+	now := time.Now()
+
+	store := func(fs string) {
+		_, gen, _ := cache.Load(fs, key, now)
+		cache.Store(fs, key, gen, &[]FilesystemVersion{}, now, time.Minute)
+	}
+	cached := func(fs string) bool {
+		_, _, ok := cache.Load(fs, key, now)
+		return ok
+	}
+
+	for _, fs := range []string{"zroot", "zroot/foo", "zroot/foo/bar", "zroot/foobar"} {
+		store(fs)
+		require.True(t, cached(fs))
+	}
+
+	cache.Invalidate("zroot/foo", false)
+	assert.True(t, cached("zroot"))
+	assert.False(t, cached("zroot/foo"))
+	assert.True(t, cached("zroot/foo/bar"))
+
+	store("zroot/foo")
+	cache.Invalidate("zroot/foo", true)
+	assert.True(t, cached("zroot"))
+	assert.False(t, cached("zroot/foo"))
+	assert.False(t, cached("zroot/foo/bar"))
+	assert.True(t, cached("zroot/foobar"))
+
+	cache.Invalidate("zroot/unknown", true)
+	assert.True(t, cached("zroot"))
+}
+
+func TestListCache_invalidatedWhileListing(t *testing.T) {
+	const fs, key = "zroot/foo", "zfs list zroot/foo"
+	cache := newListCache()
+	now := time.Now()
+
+	_, gen, ok := cache.Load(fs, key, now)
+	require.False(t, ok)
+	cache.Invalidate(fs, false)
+	cache.Store(fs, key, gen, &[]FilesystemVersion{}, now, time.Minute)
+
+	_, newGen, ok := cache.Load(fs, key, now)
+	assert.False(t, ok, "stale listing must not be stored")
+	assert.NotEqual(t, gen, newGen)
+}
+
+func TestInvalidateVersions(t *testing.T) {
+	const key = "zfs list"
+	now := time.Now()
+	saved := versionsCache
+	versionsCache = newListCache()
+	t.Cleanup(func() { versionsCache = saved })
+
+	for _, path := range []string{"zroot/foo@snap", "zroot/foo#book"} {
+		_, gen, _ := versionsCache.Load("zroot/foo", key, now)
+		versionsCache.Store("zroot/foo", key, gen, &[]FilesystemVersion{}, now,
+			time.Minute)
+		invalidateVersions(path, false)
+		_, _, ok := versionsCache.Load("zroot/foo", key, now)
+		assert.False(t, ok, path)
+	}
+}
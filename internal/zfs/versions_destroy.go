@@ -59,6 +59,9 @@ func doDestroy(ctx context.Context, fs string, reqs []DestroySnapOp) {
 			validated = append(validated, r)
 		}
 	}
+	if DestroyChannelProgram && len(validated) > 1 {
+		validated = doDestroyProgram(ctx, fs, validated)
+	}
 	doDestroyBatched(ctx, fs, validated)
 }
 
@@ -0,0 +1,16 @@
+-- Destroys snapshots, passed as arguments, like zfs destroy fs@a,b,c.
+--
+-- Snapshots, which can't be destroyed, like held or cloned ones, are skipped.
+-- It returns table of them with their error codes.
+
+local argv = (...)["argv"]
+local failed = {}
+
+for _, snap in ipairs(argv) do
+  local err = zfs.sync.destroy(snap)
+  if err ~= 0 then
+    failed[snap] = err
+  end
+end
+
+return failed
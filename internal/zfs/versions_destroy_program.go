@@ -0,0 +1,105 @@
+package zfs
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// destroyProgram is a channel program, which destroys snapshots in the kernel.
+//
+//go:embed versions_destroy.lua
+var destroyProgram []byte
+
+var (
+	// DestroyChannelProgram enables destroying of snapshots by zfs channel
+	// program in ZFSDestroyFilesystemVersions, instead of `zfs destroy
+	// fs@a,b,c`.
+	DestroyChannelProgram bool
+	// DestroyChunk is max number of snapshots, destroyed by one channel
+	// program.
+	DestroyChunk = 1000
+)
+
+// doDestroyProgram destroys fsbatch by channel programs in chunks of
+// DestroyChunk snapshots and returns snapshots, which weren't tried, because a
+// channel program failed. They should be destroyed by `zfs destroy`.
+func doDestroyProgram(ctx context.Context, fs string, fsbatch []*DestroySnapOp,
+) []*DestroySnapOp {
+	var remaining []*DestroySnapOp
+	for len(fsbatch) > 0 {
+		n := min(len(fsbatch), max(DestroyChunk, 1))
+		batch := fsbatch[:n]
+		fsbatch = fsbatch[n:]
+
+		err := tryDestroyProgram(ctx, fs, batch)
+		if errors.Is(err, ErrChannelProgramsNotSupported) {
+			debug("batch destroy: channel programs not supported: %s", err)
+			return append(append(remaining, batch...), fsbatch...)
+		} else if err != nil {
+			debug("batch destroy: channel program failed: %s", err)
+			remaining = append(remaining, batch...)
+		}
+	}
+	return remaining
+}
+
+// tryDestroyProgram destroys batch by one channel program and sets errors of
+// snapshots, which can't be destroyed, like held or cloned ones.
+func tryDestroyProgram(ctx context.Context, fs string, batch []*DestroySnapOp,
+) error {
+	pool, _, _ := strings.Cut(fs, "/")
+	names := make([]string, len(batch))
+	for i, r := range batch {
+		names[i] = fs + "@" + r.Name
+	}
+
+	promTimer := prometheus.NewTimer(
+		prom.ZFSDestroyDuration.WithLabelValues("snapshot", fs))
+	defer promTimer.ObserveDuration()
+	defer invalidateVersions(fs, false)
+
+	output, err := runChannelProgram(ctx, pool, destroyProgram, true, names...)
+	if err != nil {
+		return err
+	}
+	failed, err := parseDestroyProgram(output)
+	if err != nil {
+		return err
+	}
+
+	for i, r := range batch {
+		if code, ok := failed[names[i]]; ok {
+			r.Err = destroyProgramError(names[i], code)
+		} else {
+			r.Err = nil
+		}
+	}
+	return nil
+}
+
+// parseDestroyProgram returns snapshots with error codes from JSON output of
+// destroyProgram.
+func parseDestroyProgram(output []byte) (map[string]int64, error) {
+	var out struct {
+		Return map[string]int64 `json:"return"`
+	}
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("zfs program: parse output %q: %w", output, err)
+	}
+	return out.Return, nil
+}
+
+func destroyProgramError(name string, code int64) error {
+	if errno := syscall.Errno(code); errno == syscall.ENOENT {
+		return &DatasetDoesNotExist{Path: name}
+	} else {
+		return fmt.Errorf("zfs program: destroy %q: %w", name, errno)
+	}
+}
@@ -0,0 +1,76 @@
+package zfs
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDestroyProgram(t *testing.T) {
+	failed, err := parseDestroyProgram([]byte(
+		`{"return":{"zroot/a@snap1":16,"zroot/a@snap2":2}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"zroot/a@snap1": 16, "zroot/a@snap2": 2,
+	}, failed)
+
+	failed, err = parseDestroyProgram([]byte(`{"return":{}}`))
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+
+	_, err = parseDestroyProgram([]byte("Channel program fully executed"))
+	require.Error(t, err)
+}
+
+func TestDestroyProgramError(t *testing.T) {
+	err := destroyProgramError("zroot/a@snap1", int64(syscall.ENOENT))
+	var notExist *DatasetDoesNotExist
+	require.ErrorAs(t, err, &notExist)
+	assert.Equal(t, "zroot/a@snap1", notExist.Path)
+
+	err = destroyProgramError("zroot/a@snap1", int64(syscall.EBUSY))
+	require.ErrorIs(t, err, syscall.EBUSY)
+}
+
+func TestDoDestroyProgram(t *testing.T) {
+	oldBin, oldChunk := ZfsBin, DestroyChunk
+	t.Cleanup(func() { ZfsBin, DestroyChunk = oldBin, oldChunk })
+	DestroyChunk = 2
+
+	newOps := func() []*DestroySnapOp {
+		return []*DestroySnapOp{
+			{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"},
+		}
+	}
+
+	ZfsBin = fakeZfs(t, `echo '{"return":{"zroot/fs@b":16}}'`)
+	ops := newOps()
+	remaining := doDestroyProgram(t.Context(), "zroot/fs", ops)
+	assert.Empty(t, remaining)
+	for _, r := range ops {
+		if r.Name == "b" {
+			require.ErrorIs(t, r.Err, syscall.EBUSY)
+		} else {
+			require.NoError(t, r.Err, r.Name)
+		}
+	}
+
+	ZfsBin = fakeZfs(t, `echo "error: this is a mock" 1>&2; exit 1`)
+	ops = newOps()
+	assert.Equal(t, ops, doDestroyProgram(t.Context(), "zroot/fs", ops))
+
+	ZfsBin = fakeZfs(t, `echo "unrecognized command 'program'" 1>&2; exit 2`)
+	ops = newOps()
+	assert.Equal(t, ops, doDestroyProgram(t.Context(), "zroot/fs", ops))
+}
+
+func fakeZfs(t *testing.T, script string) string {
+	name := filepath.Join(t.TempDir(), "zfs")
+	require.NoError(t, os.WriteFile(name, []byte("#!/bin/sh\n"+script+"\n"),
+		0o755))
+	return name
+}
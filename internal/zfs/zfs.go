@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"maps"
 	"os"
 	"os/exec"
 	"regexp"
@@ -16,11 +17,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/singleflight"
 
 	"github.com/dsh2dsh/zrepl/internal/logger"
+	"github.com/dsh2dsh/zrepl/internal/util/ringbuf"
 	zfsprop "github.com/dsh2dsh/zrepl/internal/zfs/property"
 	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
 )
@@ -142,8 +146,12 @@ type SendStream struct {
 	stderrBuf    *bytes.Buffer
 	cancel       context.CancelFunc
 
+	// pipe is stdout of zfs send, which can be spliced, if it isn't buffered.
+	pipe *os.File
+
 	mtx      sync.Mutex
 	state    sendStreamState
+	read     bool
 	exitErr  *ZFSError
 	testMode bool
 }
@@ -159,6 +167,7 @@ func (s *SendStream) Read(p []byte) (int, error) {
 		panic("unreachable")
 	}
 
+	s.read = true
 	n, err := s.stdoutReader.Read(p)
 	if err == nil {
 		return n, err
@@ -183,6 +192,21 @@ func (s *SendStream) Read(p []byte) (int, error) {
 	return n, err
 }
 
+var _ Splicer = (*SendStream)(nil)
+
+// SplicePipe returns stdout pipe of zfs send, if the stream wasn't read yet and
+// isn't buffered.
+func (s *SendStream) SplicePipe() *os.File {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.state != sendStreamOpen || s.read {
+		return nil
+	}
+	return s.pipe
+}
+
+func (s *SendStream) Spliced(int64) {}
+
 func (s *SendStream) Close() error {
 	debug("sendStream: close called")
 	s.mtx.Lock()
@@ -313,6 +337,10 @@ type ZFSSendArgsUnvalidated struct {
 
 	FS       string
 	From, To *ZFSSendArgVersion // From may be nil
+
+	// Buffer is size of in-memory ring buffer between zfs send and the reader
+	// of the stream. Zero means no buffer.
+	Buffer int
 }
 
 type ZFSSendArgsValidated struct {
@@ -331,6 +359,7 @@ type ZFSSendFlags struct {
 	Compressed       bool
 	EmbeddedData     bool
 	Saved            bool
+	Holds            bool
 	Multi            bool
 	Replicate        bool
 	Exclude          string
@@ -423,6 +452,12 @@ func (a ZFSSendArgsUnvalidated) Validate(ctx context.Context,
 
 	if err := a.ZFSSendFlags.Validate(); err != nil {
 		return v, newGenericValidationError(a, fmt.Errorf("send flags invalid: %w", err))
+	} else if a.Holds && a.ResumeToken == "" {
+		if ok, err := ZFSSendHoldsSupported(ctx); err != nil {
+			return v, newGenericValidationError(a, err)
+		} else if !ok {
+			return v, newGenericValidationError(a, ErrSendHoldsNotSupported)
+		}
 	}
 
 	valCtx := &zfsSendArgsValidationContext{}
@@ -500,6 +535,10 @@ func (self *ZFSSendFlags) buildSendFlagsUnchecked() []string {
 		args = append(args, "-S")
 	}
 
+	if self.Holds {
+		args = append(args, "-h")
+	}
+
 	if self.Replicate {
 		args = append(args, "-R")
 	}
@@ -701,7 +740,16 @@ func ZFSSend(
 	args = append(args, "send")
 	args = append(args, sargs...)
 
-	ctx, cancel := context.WithCancel(ctx)
+	release, err := parallelSend.acquire(ctx, "zfs send")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelCtx := context.WithCancel(ctx)
+	cancel := func() {
+		cancelCtx()
+		release()
+	}
 	cmd := zfscmd.New(ctx).WithPipeLen(len(pipeCmds)).
 		WithCommand(ZfsBin, args).
 		WithEnv(env)
@@ -715,7 +763,14 @@ func ZFSSend(
 		cancel()
 		return nil, fmt.Errorf("cannot start zfs send command: %w", err)
 	}
-	return NewSendStream(cmd, pipeReader, stderrBuf, cancel), nil
+	if sendArgs.Buffer > 0 {
+		return NewSendStream(cmd, ringbuf.NewReader(pipeReader, sendArgs.Buffer),
+			stderrBuf, cancel), nil
+	}
+
+	stream := NewSendStream(cmd, newSendReader(pipeReader), stderrBuf, cancel)
+	stream.pipe, _ = pipeReader.(*os.File)
+	return stream, nil
 }
 
 type DrySendType string
@@ -862,14 +917,20 @@ type RecvOptions struct {
 	RollbackAndForceRecv bool
 	// Set -s flag used for resumable send & recv
 	SavePartialRecvState bool
+	// Set -u flag, the received filesystem is not mounted
+	NoMount bool
 
 	InheritProperties  []zfsprop.Property
 	OverrideProperties map[zfsprop.Property]string
+
+	// Buffer is size of in-memory ring buffer between the stream and zfs recv.
+	// Zero means no buffer.
+	Buffer int
 }
 
 func (self *RecvOptions) buildRecvFlags() []string {
 	args := make([]string, 0,
-		2+len(self.InheritProperties)*2+len(self.OverrideProperties)*2)
+		3+len(self.InheritProperties)*2+len(self.OverrideProperties)*2)
 
 	if self.RollbackAndForceRecv {
 		args = append(args, "-F")
@@ -879,6 +940,10 @@ func (self *RecvOptions) buildRecvFlags() []string {
 		args = append(args, "-s")
 	}
 
+	if self.NoMount {
+		args = append(args, "-u")
+	}
+
 	if len(self.InheritProperties) != 0 {
 		for _, prop := range self.InheritProperties {
 			args = append(args, "-x", string(prop))
@@ -897,6 +962,9 @@ func ZFSRecv(
 	ctx context.Context, fs string, v *ZFSSendArgVersion, stream io.ReadCloser,
 	opts RecvOptions, pipeCmds ...[]string,
 ) error {
+	if opts.Buffer > 0 {
+		stream = ringbuf.NewReader(stream, opts.Buffer)
+	}
 	defer stream.Close()
 	if err := v.ValidateInMemory(fs); err != nil {
 		return fmt.Errorf("invalid version: %w", err)
@@ -918,11 +986,19 @@ func ZFSRecv(
 		}
 	}
 
+	release, err := parallelRecv.acquire(ctx, "zfs recv")
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	recvFlags := opts.buildRecvFlags()
 	args := make([]string, 0, len(recvFlags)+2)
 	args = append(args, "recv")
 	args = append(args, recvFlags...)
 	args = append(args, fs)
+
+	defer versionsCache.Invalidate(fs, false)
 	cmd := zfscmd.New(ctx).WithPipeLen(len(pipeCmds)).
 		WithCommand(ZfsBin, args).
 		WithEnv(map[string]string{"ZREPL_RECV_FS": fs})
@@ -934,12 +1010,27 @@ func ZFSRecv(
 	//  cannot receive new filesystem stream: invalid backup stream
 	var stderr bytes.Buffer
 
-	if err := cmd.PipeFrom(pipeCmds, stream, &stderr, &stderr); err != nil {
+	// local streams go from zfs send to zfs recv without copying them through
+	// the daemon
+	stdin := stream
+	pipe, spliced, err := spliceStream(stream)
+	if err != nil {
+		return err
+	} else if pipe != nil {
+		defer pipe.Close()
+		stdin = pipe
+	}
+
+	if err := cmd.PipeFrom(pipeCmds, stdin, &stderr, &stderr); err != nil {
 		return err
 	}
 
 	if err := cmd.Start(); err != nil {
 		return err
+	} else if pipe != nil {
+		// zfs recv has its own copy of the pipe. Close ours, so splicing fails
+		// with EPIPE, if zfs recv exits early.
+		pipe.Close()
 	}
 
 	pid := cmd.Process().Pid
@@ -954,6 +1045,10 @@ func ZFSRecv(
 		debug("wait err: %T %s", err, err)
 		// almost always more interesting info. NOTE: do not wrap!
 		return err
+	} else if spliced != nil {
+		if err := <-spliced; err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -1096,6 +1191,7 @@ func ZFSRecvClearResumeToken(ctx context.Context, fs string) error {
 		return err
 	}
 
+	defer versionsCache.Invalidate(fs, false)
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, "recv", "-A", fs).
 		WithLogError(false)
 	o, err := cmd.CombinedOutput()
@@ -1162,6 +1258,7 @@ func (p *ZFSProperties) DatasetPath() (*DatasetPath, error) {
 }
 
 func zfsSet(ctx context.Context, path string, props map[string]string) error {
+	defer invalidateVersions(path, false)
 	args := make([]string, 0, len(props)+2)
 	args = append(args, "set")
 
@@ -1186,10 +1283,45 @@ func ZFSSet(ctx context.Context, fs *DatasetPath, props map[string]string) error
 	return zfsSet(ctx, fs.ToString(), props)
 }
 
+// ZFSSetPath sets props of a filesystem, snapshot or bookmark path.
+func ZFSSetPath(ctx context.Context, path string, props map[string]string,
+) error {
+	return zfsSet(ctx, path, props)
+}
+
 func ZFSGet(ctx context.Context, fs *DatasetPath, props []string) (*ZFSProperties, error) {
 	return zfsGet(ctx, fs.ToString(), props, SourceAny)
 }
 
+// ZFSGetWrittenSince returns property written@snapshot of fs: bytes written to
+// fs since snapshot was created.
+func ZFSGetWrittenSince(ctx context.Context, fs, snapshot string,
+) (_ uint64, err error) {
+	defer func(e *error) {
+		if *e != nil {
+			*e = fmt.Errorf("zfs get written fs=%q snapshot=%q: %w",
+				fs, snapshot, *e)
+		}
+	}(&err)
+	if err := validateZFSFilesystem(fs); err != nil {
+		return 0, err
+	} else if err := EntityNamecheck(fs+"@"+snapshot, EntityTypeSnapshot); err != nil {
+		return 0, err
+	}
+
+	prop := "written@" + snapshot
+	props, err := zfsGet(ctx, fs, []string{prop}, SourceAny)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(props.Get(prop), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s %q: %w", prop, props.Get(prop), err)
+	}
+	return n, nil
+}
+
 // The returned error includes requested filesystem and version as quoted strings in its error message
 func ZFSGetGUID(ctx context.Context, fs, version string) (_ uint64, err error) {
 	defer func(e *error) {
@@ -1351,15 +1483,31 @@ func ZFSGetRecursive(ctx context.Context, path string, depth int,
 	allowedPrefixes := allowedSources.zfsGetSourceFieldPrefixes()
 
 	var i int
-	err = scanCmdOutput(cmd, stdout, &stderrBuf,
-		func(s string) (error, bool) {
-			err := parsePropsByFs(i, s, propsByFS, allowedPrefixes)
-			if err != nil {
-				return err, false
-			}
-			i++
-			return nil, true
-		})
+	if jsonSupported() {
+		err = decodeCmdOutput(cmd, stdout, &stderrBuf,
+			func(ds *jsonDataset) (error, bool) {
+				for _, prop := range slices.Sorted(maps.Keys(ds.Properties)) {
+					p := ds.Properties[prop]
+					err := addPropByFs(i, ds.Name, prop, string(p.Value),
+						p.sourceString(), propsByFS, allowedPrefixes)
+					if err != nil {
+						return err, false
+					}
+				}
+				i++
+				return nil, true
+			})
+	} else {
+		err = scanCmdOutput(cmd, stdout, &stderrBuf,
+			func(s string) (error, bool) {
+				err := parsePropsByFs(i, s, propsByFS, allowedPrefixes)
+				if err != nil {
+					return err, false
+				}
+				i++
+				return nil, true
+			})
+	}
 	if err != nil {
 		return nil, maybeDatasetNotExists(cmd, path, err)
 	}
@@ -1372,7 +1520,11 @@ func zfsGetArgs(path string, depth int, dstypes []string,
 	props []string,
 ) []string {
 	args := make([]string, 0, 10)
-	args = append(args, "get", "-Hp", "-o", "name,property,value,source")
+	if jsonSupported() {
+		args = append(args, "get", "-j", "-p")
+	} else {
+		args = append(args, "get", "-Hp", "-o", "name,property,value,source")
+	}
 
 	if depth != 0 {
 		args = append(args, "-r")
@@ -1395,8 +1547,13 @@ func parsePropsByFs(order int, s string, propsByFs map[string]*ZFSProperties,
 		return fmt.Errorf(
 			"zfs get did not return name,property,value,source tuples: %q", s)
 	}
-	fs, prop, value, srcStr := fields[0], fields[1], fields[2], fields[3]
+	return addPropByFs(order, fields[0], fields[1], fields[2], fields[3],
+		propsByFs, prefixes)
+}
 
+func addPropByFs(order int, fs, prop, value, srcStr string,
+	propsByFs map[string]*ZFSProperties, prefixes []string,
+) error {
 	if i := slices.IndexFunc(prefixes, func(p string) bool {
 		return strings.HasPrefix(srcStr, p)
 	}); i < 0 {
@@ -1434,6 +1591,27 @@ func validatePropsByFs(propsByFS map[string]*ZFSProperties, props []string,
 
 func zfsGet(ctx context.Context, path string, props []string,
 	allowedSources PropertySource,
+) (*ZFSProperties, error) {
+	cache := propertiesCacheFrom(ctx)
+	if cache == nil {
+		return zfsGetUncached(ctx, path, props, allowedSources)
+	}
+
+	key := propertiesCacheKey(path, props, allowedSources)
+	cached, gen, ok := cache.Load(path, key, time.Now())
+	if ok {
+		return cached, nil
+	}
+	res, err := zfsGetUncached(ctx, path, props, allowedSources)
+	if err != nil {
+		return nil, err
+	}
+	cache.Store(key, gen, res, time.Now())
+	return res, nil
+}
+
+func zfsGetUncached(ctx context.Context, path string, props []string,
+	allowedSources PropertySource,
 ) (*ZFSProperties, error) {
 	propMap, err := ZFSGetRecursive(ctx, path, 0, nil, props, allowedSources)
 	switch {
@@ -1537,6 +1715,11 @@ func ZFSDestroy(ctx context.Context, arg string) error {
 	defer prometheus.NewTimer(
 		prom.ZFSDestroyDuration.WithLabelValues(dstype, filesystem))
 
+	defer invalidateVersions(arg, dstype == "filesystem")
+	if useLZC && dstype == "snapshot" {
+		return lzcDestroySnapshots(filesystem, arg)
+	}
+
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, "destroy", arg)
 	if stdio, err := cmd.CombinedOutput(); err != nil {
 		if destroyOneOrMoreSnapshotsNoneExistedErrorRegexp.Match(stdio) {
@@ -1570,8 +1753,66 @@ func ZFSDestroyIdempotent(ctx context.Context, path string) error {
 	return nil
 }
 
+// ZFSDestroyRecursive destroys filesystem fs with all its children and
+// snapshots. It's not an error, if fs doesn't exist.
+func ZFSDestroyRecursive(ctx context.Context, fs string) error {
+	if err := EntityNamecheck(fs, EntityTypeFilesystem); err != nil {
+		return fmt.Errorf("zfs destroy: %w", err)
+	}
+
+	defer invalidateVersions(fs, true)
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, "destroy", "-r", fs)
+	if stdio, err := cmd.CombinedOutput(); err != nil {
+		if tryDatasetDoesNotExist(fs, stdio) != nil {
+			return nil
+		}
+		return NewZfsError(err, stdio)
+	}
+	return nil
+}
+
+// ZFSCreateParents creates filesystem fs and all its missing parents with
+// properties props. It's not an error, if fs already exists.
+func ZFSCreateParents(ctx context.Context, fs string, props map[string]string,
+) error {
+	if err := EntityNamecheck(fs, EntityTypeFilesystem); err != nil {
+		return fmt.Errorf("zfs create: %w", err)
+	}
+
+	args := make([]string, 0, 3+len(props)*2)
+	args = append(args, "create", "-p")
+	for _, k := range slices.Sorted(maps.Keys(props)) {
+		args = append(args, "-o", k+"="+props[k])
+	}
+	args = append(args, fs)
+
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, args...)
+	if stdio, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(err, stdio)
+	}
+	return nil
+}
+
+// ZFSClone creates filesystem fs, with all its missing parents, as a clone of
+// snapshot.
+func ZFSClone(ctx context.Context, snapshot, fs string) error {
+	if err := EntityNamecheck(snapshot, EntityTypeSnapshot); err != nil {
+		return fmt.Errorf("zfs clone: %w", err)
+	} else if err := EntityNamecheck(fs, EntityTypeFilesystem); err != nil {
+		return fmt.Errorf("zfs clone: %w", err)
+	}
+
+	cmd := zfscmd.CommandContext(ctx, ZfsBin, "clone", "-p", snapshot, fs)
+	if stdio, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(err, stdio)
+	}
+	return nil
+}
+
+// ZFSSnapshot creates snapshot name of fs with properties props, which are set
+// atomically with creation of the snapshot.
 func ZFSSnapshot(ctx context.Context, fs *DatasetPath, name string,
-	recursive bool,
+	recursive bool, props map[string]string,
 ) error {
 	promTimer := prometheus.NewTimer(
 		prom.ZFSSnapshotDuration.WithLabelValues(fs.ToString()))
@@ -1582,13 +1823,24 @@ func ZFSSnapshot(ctx context.Context, fs *DatasetPath, name string,
 		return fmt.Errorf("zfs snapshot: %w", err)
 	}
 
-	args := make([]string, 0, 3)
+	args := make([]string, 0, 3+2*len(props))
 	args = append(args, "snapshot")
 	if recursive {
 		args = append(args, "-r")
 	}
+	for _, k := range slices.Sorted(maps.Keys(props)) {
+		args = append(args, "-o", k+"="+props[k])
+	}
 	args = append(args, snapname)
 
+	defer versionsCache.Invalidate(fs.ToString(), recursive)
+	if useLZC && !recursive {
+		// libzfs_core doesn't snapshot descendants, so recursive snapshots are
+		// created by zfs snapshot -r.
+		return lzcDoesNotExist(lzcSnapshot([]string{snapname}, props),
+			fs.ToString())
+	}
+
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, args...)
 	if stdio, err := cmd.CombinedOutput(); err != nil {
 		return NewZfsError(err, stdio)
@@ -1664,6 +1916,15 @@ func ZFSBookmark(ctx context.Context, fs string, v FilesystemVersion,
 		return bm, err
 	}
 
+	defer versionsCache.Invalidate(fs, false)
+	if useLZC {
+		err := lzcBookmark(snapname, bookmarkname)
+		if errors.Is(err, syscall.EEXIST) {
+			return bookmarkExists(ctx, fs, v, bm, err.Error())
+		}
+		return bm, lzcDoesNotExist(err, snapname)
+	}
+
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, "bookmark", snapname, bookmarkname)
 	if stdio, err := cmd.CombinedOutput(); err != nil {
 		ddne := tryDatasetDoesNotExist(snapname, stdio)
@@ -1671,31 +1932,38 @@ func ZFSBookmark(ctx context.Context, fs string, v FilesystemVersion,
 		case ddne != nil:
 			return bm, ddne
 		case zfsBookmarkExistsRegex.Match(stdio):
-			// check if this was idempotent
-			bookGuid, err := ZFSGetGUID(ctx, fs, "#"+bookmark)
-			switch {
-			case err != nil:
-				// guid error expressive enough
-				return bm, fmt.Errorf(
-					"bookmark: idempotency check for bookmark creation: %w", err)
-			case v.Guid == bookGuid:
-				debug("bookmark: %q %q was idempotent: {snap,book}guid %d == %d",
-					snapname, bookmarkname, v.Guid, bookGuid)
-				return bm, nil
-			}
-			return bm, &BookmarkExists{
-				fs:             fs,
-				bookmarkOrigin: v.ToSendArgVersion(),
-				bookmark:       bookmark,
-				zfsMsg:         string(stdio),
-				bookGuid:       bookGuid,
-			}
+			return bookmarkExists(ctx, fs, v, bm, string(stdio))
 		}
 		return bm, NewZfsError(err, stdio)
 	}
 	return bm, nil
 }
 
+// bookmarkExists checks if existing bookmark bm of v was created
+// idempotently.
+func bookmarkExists(ctx context.Context, fs string, v, bm FilesystemVersion,
+	zfsMsg string,
+) (FilesystemVersion, error) {
+	bookGuid, err := ZFSGetGUID(ctx, fs, "#"+bm.Name)
+	switch {
+	case err != nil:
+		// guid error expressive enough
+		return bm, fmt.Errorf(
+			"bookmark: idempotency check for bookmark creation: %w", err)
+	case v.Guid == bookGuid:
+		debug("bookmark: %q %q was idempotent: {snap,book}guid %d == %d",
+			v.FullPath(fs), fs+"#"+bm.Name, v.Guid, bookGuid)
+		return bm, nil
+	}
+	return bm, &BookmarkExists{
+		fs:             fs,
+		bookmarkOrigin: v.ToSendArgVersion(),
+		bookmark:       bm.Name,
+		zfsMsg:         zfsMsg,
+		bookGuid:       bookGuid,
+	}
+}
+
 func ZFSRollback(ctx context.Context, fs *DatasetPath,
 	snapshot FilesystemVersion, rollbackArgs ...string,
 ) error {
@@ -1709,6 +1977,7 @@ func ZFSRollback(ctx context.Context, fs *DatasetPath,
 	args = append(args, rollbackArgs...)
 	args = append(args, snapabs)
 
+	defer versionsCache.Invalidate(fs.ToString(), false)
 	cmd := zfscmd.CommandContext(ctx, ZfsBin, args...)
 	if stdio, err := cmd.CombinedOutput(); err != nil {
 		return NewZfsError(err, stdio)
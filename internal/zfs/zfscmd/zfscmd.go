@@ -52,8 +52,7 @@ func (c *Cmd) WithCommand(name string, args []string) *Cmd {
 }
 
 func (c *Cmd) WithEnv(env map[string]string) *Cmd {
-	c.env = make([]string, 0, len(os.Environ())+len(env))
-	c.env = append(c.env, os.Environ()...)
+	c.env = make([]string, 0, len(env))
 	for k, v := range env {
 		c.env = append(c.env, k+"="+v)
 	}
@@ -191,8 +190,9 @@ func (c *Cmd) Wait() (err error) {
 
 func (c *Cmd) startPre() {
 	startPreLogging(c, time.Now())
+	env := c.buildEnv()
 	for _, cmd := range c.cmds {
-		cmd.Env = c.env
+		cmd.Env = env
 	}
 }
 
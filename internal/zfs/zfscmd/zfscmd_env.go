@@ -0,0 +1,53 @@
+package zfscmd
+
+import (
+	"context"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+)
+
+var jobEnv = struct {
+	mtx sync.RWMutex
+	env map[string][]string
+}{env: make(map[string][]string)}
+
+// SetJobEnv configures extra environment variables for every command, executed
+// with jobID in its context. Empty env removes them.
+func SetJobEnv(jobID string, env map[string]string) {
+	jobEnv.mtx.Lock()
+	defer jobEnv.mtx.Unlock()
+	if len(env) == 0 {
+		delete(jobEnv.env, jobID)
+		return
+	}
+
+	l := make([]string, 0, len(env))
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		l = append(l, k+"="+env[k])
+	}
+	jobEnv.env[jobID] = l
+}
+
+func getJobEnv(ctx context.Context) []string {
+	jobEnv.mtx.RLock()
+	defer jobEnv.mtx.RUnlock()
+	return jobEnv.env[GetJobID(ctx)]
+}
+
+// buildEnv returns environment of the command: environment of the daemon, then
+// environment of the job and then environment of the command. Later values
+// override earlier ones.
+func (c *Cmd) buildEnv() []string {
+	jobEnv := getJobEnv(c.ctx)
+	if len(jobEnv) == 0 && len(c.env) == 0 {
+		return nil
+	}
+
+	environ := os.Environ()
+	env := make([]string, 0, len(environ)+len(jobEnv)+len(c.env))
+	env = append(env, environ...)
+	env = append(env, jobEnv...)
+	return append(env, c.env...)
+}
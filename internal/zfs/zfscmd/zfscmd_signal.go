@@ -0,0 +1,32 @@
+package zfscmd
+
+import (
+	"errors"
+	"os"
+)
+
+// SignalJob sends sig to every active command of job jobID, including all
+// commands of its pipes, and returns number of signaled processes.
+func SignalJob(jobID string, sig os.Signal) (n int, err error) {
+	active.mtx.RLock()
+	defer active.mtx.RUnlock()
+	for c := range active.cmds {
+		if GetJobID(c.ctx) != jobID {
+			continue
+		}
+		c.mtx.RLock()
+		for _, cmd := range c.cmds {
+			if cmd.Process == nil {
+				continue
+			} else if err2 := cmd.Process.Signal(sig); err2 != nil {
+				if !errors.Is(err2, os.ErrProcessDone) {
+					err = errors.Join(err, err2)
+				}
+				continue
+			}
+			n++
+		}
+		c.mtx.RUnlock()
+	}
+	return n, err
+}
@@ -3,6 +3,7 @@ package zfscmd
 import (
 	"bytes"
 	"slices"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,26 @@ func TestCmd_WithEnv(t *testing.T) {
 	}
 }
 
+func TestSetJobEnv(t *testing.T) {
+	const jobID = "TestSetJobEnv"
+	SetJobEnv(jobID, map[string]string{"FOO": "JOB", "BAR": "JOB"})
+	defer SetJobEnv(jobID, nil)
+
+	echoCmd := []string{"sh", "-c", "echo -n $FOO $BAR"}
+	ctx := WithJobID(t.Context(), jobID)
+	cmd := CommandContext(ctx, echoCmd[0], echoCmd[1:]...).
+		WithEnv(map[string]string{"FOO": "CMD"})
+	var output bytes.Buffer
+	cmd.setStdio(Stdio{Stdout: &output, Stderr: &output})
+
+	require.NoError(t, cmd.Start())
+	require.NoError(t, cmd.Wait())
+	assert.Equal(t, "CMD JOB", output.String())
+
+	SetJobEnv(jobID, nil)
+	assert.Empty(t, getJobEnv(ctx))
+}
+
 func TestCmd_WithPipeLen(t *testing.T) {
 	cmd := New(t.Context())
 	assert.Equal(t, 10, cap(cmd.WithPipeLen(9).cmds))
@@ -41,3 +62,20 @@ func TestCmd_WithPipeLen(t *testing.T) {
 	assert.Len(t, cmd.cmds, 1)
 	assert.Equal(t, 10, cap(cmd.cmds))
 }
+
+func TestSignalJob(t *testing.T) {
+	const jobID = "TestSignalJob"
+	ctx := WithJobID(t.Context(), jobID)
+	cmd := CommandContext(ctx, "sleep", "10")
+	cmd.setStdio(Stdio{})
+	require.NoError(t, cmd.Start())
+
+	n, err := SignalJob("TestSignalJob_other", syscall.SIGTERM)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = SignalJob(jobID, syscall.SIGTERM)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Error(t, cmd.Wait())
+}
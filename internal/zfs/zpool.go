@@ -0,0 +1,50 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+// ZpoolImported returns true, if pool is imported.
+func ZpoolImported(ctx context.Context, pool string) (bool, error) {
+	cmd := zfscmd.CommandContext(ctx, ZpoolBin, "list", "-H", "-o", "name").
+		WithLogError(false)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, NewZfsError(fmt.Errorf("zpool list failed: %w", err), nil)
+	}
+
+	for name := range bytes.Lines(output) {
+		if string(bytes.TrimSpace(name)) == pool {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ZpoolImport imports pool, using additional args of zpool import, like
+// -d dir.
+func ZpoolImport(ctx context.Context, pool string, args ...string) error {
+	cmdArgs := make([]string, 0, len(args)+2)
+	cmdArgs = append(cmdArgs, "import")
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, pool)
+
+	cmd := zfscmd.CommandContext(ctx, ZpoolBin, cmdArgs...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(fmt.Errorf("zpool import failed: %w", err), output)
+	}
+	return nil
+}
+
+// ZpoolExport exports pool.
+func ZpoolExport(ctx context.Context, pool string) error {
+	cmd := zfscmd.CommandContext(ctx, ZpoolBin, "export", pool)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return NewZfsError(fmt.Errorf("zpool export failed: %w", err), output)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsh2dsh/zrepl/internal/zfs/zfscmd"
+)
+
+var ZpoolBin string = "zpool"
+
+// ZpoolLatency samples I/O of pools during one second and returns the highest
+// average total wait time of reads and writes across all pools.
+func ZpoolLatency(ctx context.Context, pools []string) (time.Duration, error) {
+	args := make([]string, 0, len(pools)+5)
+	args = append(args, "iostat", "-Hply")
+	args = append(args, pools...)
+	args = append(args, "1", "1")
+
+	cmd := zfscmd.CommandContext(ctx, ZpoolBin, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, NewZfsError(fmt.Errorf("zpool iostat failed: %w", err), output)
+	}
+	return parseZpoolLatency(output)
+}
+
+// parseZpoolLatency parses output of zpool iostat -Hpl. Columns are:
+//
+//	pool alloc free rops wops rbw wbw total_wait_r total_wait_w ...
+//
+// Wait times are in nanoseconds or "-", if there was no I/O.
+func parseZpoolLatency(output []byte) (time.Duration, error) {
+	const totalWaitRead, totalWaitWrite = 7, 8
+
+	var latency uint64
+	scan := bufio.NewScanner(bytes.NewReader(output))
+	for scan.Scan() {
+		line := scan.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) <= totalWaitWrite {
+			return 0, fmt.Errorf("unexpected zpool iostat line: %q", line)
+		}
+		for _, s := range fields[totalWaitRead : totalWaitWrite+1] {
+			if s == "-" {
+				continue
+			}
+			n, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse wait time %q: %w", s, err)
+			}
+			latency = max(latency, n)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return 0, fmt.Errorf("scan zpool iostat output: %w", err)
+	}
+	return time.Duration(latency), nil
+}
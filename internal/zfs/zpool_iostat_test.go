@@ -0,0 +1,27 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseZpoolLatency(t *testing.T) {
+	output := "zroot\t10\t20\t1\t2\t3\t4\t1500000\t2500000\t1\t1\t-\t-\t-\t-\t-\t-\n" +
+		"tank\t10\t20\t0\t0\t0\t0\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\n"
+	latency, err := parseZpoolLatency([]byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, 2500*time.Microsecond, latency)
+
+	latency, err = parseZpoolLatency(nil)
+	require.NoError(t, err)
+	assert.Zero(t, latency)
+
+	_, err = parseZpoolLatency([]byte("zroot\t10\n"))
+	require.Error(t, err)
+
+	_, err = parseZpoolLatency([]byte("zroot\t1\t2\t3\t4\t5\t6\tx\t1\n"))
+	require.Error(t, err)
+}
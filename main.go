@@ -7,18 +7,36 @@ import (
 	"github.com/dsh2dsh/zrepl/internal/client/monitor"
 	"github.com/dsh2dsh/zrepl/internal/client/status"
 	"github.com/dsh2dsh/zrepl/internal/daemon"
+	"github.com/dsh2dsh/zrepl/internal/platformtest"
 )
 
 func init() {
 	cli.AddSubcommand(daemon.DaemonCmd)
 	cli.AddSubcommand(status.Subcommand)
 	cli.AddSubcommand(client.SignalCmd)
+	cli.AddSubcommand(client.VerifyCmd)
+	cli.AddSubcommand(client.SkipCmd)
+	cli.AddSubcommand(client.PauseCmd)
+	cli.AddSubcommand(client.ResumeCmd)
+	cli.AddSubcommand(client.BwlimitCmd)
+	cli.AddSubcommand(client.AdoptCmd)
+	cli.AddSubcommand(client.AuditCmd)
+	cli.AddSubcommand(client.LifecycleCmd)
+	cli.AddSubcommand(client.JobCmd)
 	cli.AddSubcommand(client.ConfigcheckCmd)
+	cli.AddSubcommand(client.ConfigCmd)
 	cli.AddSubcommand(client.VersionCmd)
+	cli.AddSubcommand(client.SchemaCmd)
+	cli.AddSubcommand(client.UndoPruneCmd)
+	cli.AddSubcommand(client.AbortResumeCmd)
+	cli.AddSubcommand(client.RehearseCmd)
+	cli.AddSubcommand(client.PruneCmd)
 	cli.AddSubcommand(client.TestCmd)
 	cli.AddSubcommand(client.MigrateCmd)
 	cli.AddSubcommand(client.ZFSAbstractionsCmd)
+	cli.AddSubcommand(client.HoldsCmd)
 	cli.AddSubcommand(monitor.Subcommand)
+	cli.AddSubcommand(platformtest.Subcommand)
 }
 
 func main() {